@@ -0,0 +1,61 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/url"
+	"reflect"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// adminConfigSummary returns the part of cfg shown on the admin dashboard.
+// It must not copy credentials, and keeps only the host of endpoints, as
+// their URLs may carry secrets.
+func adminConfigSummary(cfg *config.Config) schema.AdminConfigResponse {
+	summary := schema.AdminConfigResponse{
+		BackendAPI:   cfg.Engine.BackendAPI,
+		SessionStore: cfg.SessionStore.Type,
+		FileStore:    cfg.FileStore.Type,
+		VectorStore:  cfg.VectorStore.Type,
+		Embedding:    cfg.Embedding.Type,
+		WebSearch:    cfg.WebSearch.Provider,
+		Moderation:   cfg.Moderation.Provider,
+		EventBus:     cfg.EventBus.Provider,
+		LogLevel:     cfg.Logging.Level,
+		Features:     []string{},
+	}
+	if u, err := url.Parse(cfg.Engine.ModelEndpoint); err == nil {
+		summary.ModelHost = u.Host
+	}
+	for _, f := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"websocket", cfg.WebSocket.Enabled},
+		{"grpc", cfg.GRPC.Enabled},
+		{"extproc", cfg.ExtProc.Enabled},
+		{"tls", cfg.Server.TLS.CertFile != "" || cfg.GRPC.TLS.CertFile != "" || cfg.ExtProc.TLS.CertFile != ""},
+		{"compression", cfg.Server.Compression.Enabled},
+		{"response_cache", cfg.Engine.ResponseCache.Enabled},
+		{"recording", cfg.Engine.Recording.Enabled},
+		{"shadow_traffic", cfg.Engine.Shadow.ModelEndpoint != ""},
+		{"admission_control", cfg.Engine.Admission.MaxConcurrent > 0 || cfg.Engine.Admission.MaxConcurrentPerTenant > 0 || cfg.Engine.Admission.MaxConcurrentPerKey > 0},
+		{"conversation_titles", !cfg.Engine.ConversationTitles.Disabled},
+		{"web_fetch", cfg.WebFetch.Enabled},
+		{"image_fetch", cfg.ImageFetch.Enabled},
+		{"egress", !reflect.DeepEqual(cfg.Egress, config.EgressConfig{})},
+		{"hooks", len(cfg.Hooks) > 0},
+		{"provenance", cfg.Provenance.Enabled},
+		{"audit", cfg.Audit.Enabled},
+		{"payload_logging", cfg.Logging.Payloads.Enabled},
+		{"playground", cfg.Playground.Enabled},
+	} {
+		if f.enabled {
+			summary.Features = append(summary.Features, f.name)
+		}
+	}
+	return summary
+}
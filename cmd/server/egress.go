@@ -0,0 +1,54 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/egress"
+)
+
+// egressPolicies returns the egress policies of cfg, or nil when cfg sets
+// none, leaving tool requests unrestricted.
+func egressPolicies(cfg config.EgressConfig) (*egress.Set, error) {
+	if reflect.DeepEqual(cfg, config.EgressConfig{}) {
+		return nil, nil
+	}
+	global, err := egressPolicy(cfg.EgressPolicyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("egress: %w", err)
+	}
+	connectors := make(map[string]egress.Policy, len(cfg.Connectors))
+	for id, c := range cfg.Connectors {
+		if connectors[id], err = egressPolicy(c); err != nil {
+			return nil, fmt.Errorf("egress.connectors.%s: %w", id, err)
+		}
+	}
+	return egress.NewSet(global, connectors), nil
+}
+
+// egressPolicy converts a configured policy.
+func egressPolicy(cfg config.EgressPolicyConfig) (egress.Policy, error) {
+	p := egress.Policy{
+		AllowedHosts:         cfg.AllowedHosts,
+		BlockPrivateNetworks: cfg.BlockPrivateNetworks,
+		MaxResponseBytes:     cfg.MaxResponseBytes,
+		Timeout:              cfg.Timeout,
+		InsecureSkipVerify:   cfg.InsecureSkipVerify,
+	}
+	if cfg.AllowedCIDRs != nil {
+		p.AllowedCIDRs = make([]netip.Prefix, 0, len(cfg.AllowedCIDRs))
+	}
+	for _, s := range cfg.AllowedCIDRs {
+		prefix, err := egress.ParsePrefix(s)
+		if err != nil {
+			return egress.Policy{}, fmt.Errorf("allowed_cidrs: %w", err)
+		}
+		p.AllowedCIDRs = append(p.AllowedCIDRs, prefix)
+	}
+	return p, nil
+}
@@ -8,27 +8,49 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	extprocAdapter "github.com/leseb/openresponses-gw/pkg/adapters/extproc"
+	grpcAdapter "github.com/leseb/openresponses-gw/pkg/adapters/grpc"
+	websocketAdapter "github.com/leseb/openresponses-gw/pkg/adapters/websocket"
+	"github.com/leseb/openresponses-gw/pkg/audit"
+	"github.com/leseb/openresponses-gw/pkg/compression"
 	"github.com/leseb/openresponses-gw/pkg/core/api"
 	"github.com/leseb/openresponses-gw/pkg/core/config"
 	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
 	"github.com/leseb/openresponses-gw/pkg/core/services"
 	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/cors"
+	"github.com/leseb/openresponses-gw/pkg/egress"
+	"github.com/leseb/openresponses-gw/pkg/embedding/local"
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
 	"github.com/leseb/openresponses-gw/pkg/filestore"
 	"github.com/leseb/openresponses-gw/pkg/handlers"
+	"github.com/leseb/openresponses-gw/pkg/health"
+	"github.com/leseb/openresponses-gw/pkg/mcp"
+	"github.com/leseb/openresponses-gw/pkg/moderation"
 	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/openapi"
+	"github.com/leseb/openresponses-gw/pkg/secrets"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
 	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+	"github.com/leseb/openresponses-gw/pkg/webfetch"
 	"github.com/leseb/openresponses-gw/pkg/websearch"
 
 	// Blank imports register provider implementations via init().
 	// Remove any of these to exclude the provider from the binary.
+	_ "github.com/leseb/openresponses-gw/pkg/eventbus/kafka"
+	_ "github.com/leseb/openresponses-gw/pkg/eventbus/nats"
 	_ "github.com/leseb/openresponses-gw/pkg/filestore/filesystem"
 	_ "github.com/leseb/openresponses-gw/pkg/filestore/memory"
 	_ "github.com/leseb/openresponses-gw/pkg/filestore/s3"
@@ -76,11 +98,17 @@ var (
 // @tag.description			Extended - Vector store and embeddings
 // @tag.name					Connectors
 // @tag.description			Extended - MCP connector management
+// @tag.name					Admin
+// @tag.description			Extended - Maintenance operations
 func main() {
 	// Parse command-line flags
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
 	port := flag.Int("port", 0, "HTTP port to listen on (overrides config)")
 	version := flag.Bool("version", false, "Print version and exit")
+	validateConfig := flag.Bool("validate-config", false, "Check the configuration file, print any errors and exit")
+	reencrypt := flag.Bool("reencrypt", false, "Re-encrypt stored payloads with session_store.encryption_key and exit")
+	printOpenAPI := flag.Bool("print-openapi", false, "Print the OpenAPI document served at /openapi.json and exit")
+	watchConfig := flag.Duration("watch-config", 0, "Reload the configuration file when it changes, checking at this interval (0: reload on SIGHUP only)")
 	flag.Parse()
 
 	// Print version
@@ -89,6 +117,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Print the OpenAPI document, for client generators
+	if *printOpenAPI {
+		spec, err := openapi.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "OpenAPI document: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(append(spec, '\n'))
+		os.Exit(0)
+	}
+
+	// Check the configuration
+	if *validateConfig {
+		os.Exit(checkConfig(*configPath, os.Stdout))
+	}
+
 	// Initialize logger
 	logger := logging.New(logging.Config{
 		Level:  "info",
@@ -104,6 +148,16 @@ func main() {
 		// If config file doesn't exist, use defaults
 		logger.Warn("Failed to load config, using defaults", "error", err)
 		cfg = config.Default()
+		if err := cfg.ResolveSecrets(); err != nil {
+			logger.Error("Failed to resolve secrets", "error", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.Logging.Level != "info" || cfg.Logging.Format != "json" {
+		logger = logging.New(logging.Config{
+			Level:  cfg.Logging.Level,
+			Format: cfg.Logging.Format,
+		})
 	}
 
 	// Override ports from flags
@@ -112,8 +166,13 @@ func main() {
 	}
 	// Initialize session store via provider registry
 	initCtx := context.Background()
+	encryptionKeys := cfg.SessionStore.EncryptionKey
+	if encryptionKeys != "" && len(cfg.SessionStore.PreviousEncryptionKeys) > 0 {
+		encryptionKeys += "," + strings.Join(cfg.SessionStore.PreviousEncryptionKeys, ",")
+	}
 	store, err := state.Providers.New(initCtx, cfg.SessionStore.Type, map[string]string{
-		"dsn": cfg.SessionStore.DSN,
+		"dsn":             cfg.SessionStore.DSN,
+		"encryption_keys": encryptionKeys,
 	})
 	if err != nil {
 		logger.Error("Failed to initialize session store", "error", err)
@@ -122,11 +181,42 @@ func main() {
 	if closer, ok := store.(io.Closer); ok {
 		defer closer.Close()
 	}
-	logger.Info("Initialized session store", "type", cfg.SessionStore.Type)
+	logger.Info("Initialized session store", "type", cfg.SessionStore.Type, "encryption", cfg.SessionStore.EncryptionKey != "")
+
+	// Rewrite payloads stored in plain JSON or with a previous key
+	if *reencrypt {
+		encrypter, ok := store.(state.PayloadEncrypter)
+		if !ok || cfg.SessionStore.EncryptionKey == "" {
+			logger.Error("Re-encryption needs a session store with session_store.encryption_key set", "type", cfg.SessionStore.Type)
+			os.Exit(1)
+		}
+		n, err := encrypter.ReencryptPayloads(initCtx)
+		if err != nil {
+			logger.Error("Failed to re-encrypt payloads", "error", err, "rewritten", n)
+			os.Exit(1)
+		}
+		logger.Info("Re-encrypted payloads", "rewritten", n)
+		return
+	}
 
 	// Initialize connectors store (needed by engine for MCP tool support)
-	connectorsStore := memory.NewConnectorsStore()
-	logger.Info("Initialized connectors store")
+	var connectorsStore *memory.ConnectorsStore
+	if cfg.Connectors.EncryptionKey != "" {
+		key, err := secrets.ParseKey(cfg.Connectors.EncryptionKey)
+		if err != nil {
+			logger.Error("Invalid connectors encryption key", "error", err)
+			os.Exit(1)
+		}
+		connectorsStore, err = memory.NewConnectorsStoreWithKey(key)
+		if err != nil {
+			logger.Error("Failed to initialize connectors store", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Initialized connectors store", "encryption_key", "configured")
+	} else {
+		connectorsStore = memory.NewConnectorsStore()
+		logger.Info("Initialized connectors store", "encryption_key", "ephemeral")
+	}
 
 	// Initialize prompts store
 	promptsStore := memory.NewPromptsStore()
@@ -134,11 +224,16 @@ func main() {
 
 	// Initialize files store via provider registry
 	filesStore, err := filestore.Providers.New(initCtx, cfg.FileStore.Type, map[string]string{
-		"base_dir": cfg.FileStore.BaseDir,
-		"bucket":   cfg.FileStore.S3Bucket,
-		"region":   cfg.FileStore.S3Region,
-		"prefix":   cfg.FileStore.S3Prefix,
-		"endpoint": cfg.FileStore.S3Endpoint,
+		"base_dir":               cfg.FileStore.BaseDir,
+		"bucket":                 cfg.FileStore.S3Bucket,
+		"region":                 cfg.FileStore.S3Region,
+		"prefix":                 cfg.FileStore.S3Prefix,
+		"endpoint":               cfg.FileStore.S3Endpoint,
+		"access_key_id":          cfg.FileStore.S3AccessKeyID,
+		"secret_access_key":      cfg.FileStore.S3SecretAccessKey,
+		"server_side_encryption": cfg.FileStore.S3ServerSideEncryption,
+		"kms_key_id":             cfg.FileStore.S3KMSKeyID,
+		"presign_expiry":         cfg.FileStore.S3PresignExpiry.String(),
 	})
 	if err != nil {
 		logger.Error("Failed to initialize file store", "error", err)
@@ -152,15 +247,15 @@ func main() {
 	logger.Info("Initialized vector stores store")
 
 	// Initialize embedding client (optional)
-	var embedder api.EmbeddingClient
-	if cfg.Embedding.Endpoint != "" {
-		embedder = api.NewOpenAIEmbeddingClient(
-			cfg.Embedding.Endpoint,
-			cfg.Embedding.APIKey,
-			cfg.Embedding.Model,
-			cfg.Embedding.Dimensions,
-		)
-		logger.Info("Initialized embedding client", "endpoint", cfg.Embedding.Endpoint, "model", cfg.Embedding.Model)
+	var embedder services.Embedder
+	if cfg.Embedding.Endpoint != "" || cfg.Embedding.Type == "local" {
+		embedder, err = newEmbedder(cfg.Embedding.EmbedderConfig, cfg.Embedding.RuntimeLibrary)
+		if err != nil {
+			logger.Error("Failed to initialize embedding client", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Initialized embedding client", "type", cfg.Embedding.Type, "endpoint", cfg.Embedding.Endpoint,
+			"model", embedder.Model, "dimensions", embedder.Dimensions)
 	}
 
 	// Initialize vector store backend via provider registry
@@ -177,20 +272,50 @@ func main() {
 	// Initialize vector store service (nil if embedding not configured)
 	vectorStoreService := services.NewVectorStoreService(filesStore, embedder, vsBackend)
 	if vectorStoreService != nil {
+		for _, name := range slices.Sorted(maps.Keys(cfg.Embedding.Providers)) {
+			p := cfg.Embedding.Providers[name]
+			e, err := newEmbedder(p, cfg.Embedding.RuntimeLibrary)
+			if err != nil {
+				logger.Error("Failed to initialize embedding provider", "name", name, "error", err)
+				os.Exit(1)
+			}
+			e.Name = name
+			vectorStoreService.AddEmbedder(e)
+			logger.Info("Initialized embedding provider", "name", name, "type", p.Type, "endpoint", p.Endpoint,
+				"model", e.Model, "dimensions", e.Dimensions)
+		}
+		if cfg.Embedding.Type == "openai" {
+			vectorStoreService.SetEmbedderFactory(func(model string, dimensions int) api.EmbeddingClient {
+				return api.NewOpenAIEmbeddingClient(cfg.Embedding.Endpoint, cfg.Embedding.APIKey, model, dimensions)
+			})
+		}
 		logger.Info("Initialized vector store service")
 	}
 
+	// Restrict the outbound requests of tools (optional)
+	egressSet, err := egressPolicies(cfg.Egress)
+	if err != nil {
+		logger.Error("Failed to initialize egress policies", "error", err)
+		os.Exit(1)
+	}
+	if egressSet != nil {
+		logger.Info("Enabled egress policies", "allowed_hosts", cfg.Egress.AllowedHosts,
+			"allowed_cidrs", cfg.Egress.AllowedCIDRs, "connector_policies", len(cfg.Egress.Connectors))
+	}
+
 	// Initialize web search provider via registry (optional)
-	var webSearchProvider engine.WebSearcher
-	if cfg.WebSearch.Provider != "" && cfg.WebSearch.APIKey != "" {
-		wsProvider, wsErr := websearch.Providers.New(initCtx, cfg.WebSearch.Provider, map[string]string{
-			"api_key": cfg.WebSearch.APIKey,
-		})
+	var (
+		webSearchProvider engine.WebSearcher
+		webSearch         *webSearchAdapter
+	)
+	if cfg.WebSearch.Provider != "" {
+		wsProvider, wsErr := newWebSearchProvider(initCtx, cfg.WebSearch, egressSet)
 		if wsErr != nil {
 			logger.Error("Failed to initialize web search provider", "error", wsErr)
 			os.Exit(1)
 		}
-		webSearchProvider = &webSearchAdapter{provider: wsProvider}
+		webSearch = &webSearchAdapter{provider: wsProvider}
+		webSearchProvider = webSearch
 		logger.Info("Initialized web search provider", "provider", cfg.WebSearch.Provider)
 	}
 
@@ -205,20 +330,342 @@ func main() {
 		os.Exit(1)
 	}
 	logger.Info("Initialized engine")
+	if egressSet != nil {
+		eng.SetEgress(egressSet)
+	}
+
+	// Let input parts reference uploaded files, such as videos, by ID
+	eng.SetFileStore(filesStore)
+
+	// Title conversations from their first turn (opt-out)
+	if titles := cfg.Engine.ConversationTitles; !titles.Disabled {
+		eng.SetConversationTitles(titles.Model, titles.MaxLength)
+		logger.Info("Initialized conversation titles", "model", titles.Model)
+	}
+
+	// Fetch remote input images for backends without internet access (optional)
+	if cfg.ImageFetch.Enabled {
+		opts := webfetch.ImageOptions{
+			Options: webfetch.Options{
+				Timeout:              cfg.ImageFetch.Timeout,
+				MaxBytes:             cfg.ImageFetch.MaxBytes,
+				AllowPrivateNetworks: cfg.ImageFetch.AllowPrivateNetworks,
+			},
+			CacheBytes: cfg.ImageFetch.CacheBytes,
+			CacheTTL:   cfg.ImageFetch.CacheTTL,
+		}
+		if len(cfg.ImageFetch.AllowedDomains) > 0 || len(cfg.ImageFetch.BlockedDomains) > 0 {
+			opts.AllowURL = websearch.NewDomainFilter(nil, cfg.ImageFetch.AllowedDomains, cfg.ImageFetch.BlockedDomains).Allows
+		}
+		eng.SetImageFetcher(webfetch.NewImageFetcher(opts))
+		logger.Info("Initialized image fetching")
+	}
+
+	// Initialize the fetch_url tool (optional, needs web search)
+	if cfg.WebFetch.Enabled && webSearch != nil {
+		opts := webfetch.Options{
+			Timeout:              cfg.WebFetch.Timeout,
+			MaxBytes:             cfg.WebFetch.MaxBytes,
+			MaxChars:             cfg.WebFetch.MaxChars,
+			UserAgent:            cfg.WebFetch.UserAgent,
+			IgnoreRobots:         cfg.WebFetch.IgnoreRobots,
+			AllowPrivateNetworks: cfg.WebFetch.AllowPrivateNetworks,
+		}
+		if len(cfg.WebSearch.AllowedDomains) > 0 || len(cfg.WebSearch.BlockedDomains) > 0 {
+			opts.AllowURL = websearch.NewDomainFilter(nil, cfg.WebSearch.AllowedDomains, cfg.WebSearch.BlockedDomains).Allows
+		}
+		if egressSet != nil {
+			policy := egressSet.Policy("")
+			opts.Egress = &policy
+		}
+		eng.SetURLFetcher(webfetch.New(opts))
+		logger.Info("Initialized fetch_url tool")
+	}
+
+	// Initialize request/response hooks (optional)
+	if len(cfg.Hooks) > 0 {
+		chain := hooks.NewChain()
+		for _, hc := range cfg.Hooks {
+			onRequest, onResponse := len(hc.Stages) == 0, len(hc.Stages) == 0
+			for _, stage := range hc.Stages {
+				switch stage {
+				case "request":
+					onRequest = true
+				case "response":
+					onResponse = true
+				default:
+					logger.Error("Invalid hook stage", "hook", hc.Name, "stage", stage)
+					os.Exit(1)
+				}
+			}
+			if err := chain.Add(hooks.NewHTTPHook(hooks.HTTPHookOptions{
+				Name:       hc.Name,
+				URL:        hc.URL,
+				OnRequest:  onRequest,
+				OnResponse: onResponse,
+				FailOpen:   hc.FailOpen,
+				Timeout:    hc.Timeout,
+			})); err != nil {
+				logger.Error("Failed to register hook", "hook", hc.Name, "error", err)
+				os.Exit(1)
+			}
+		}
+		eng.SetHooks(chain)
+		logger.Info("Initialized hooks", "count", len(cfg.Hooks))
+	}
+
+	// Initialize content moderation via registry (optional)
+	if cfg.Moderation.Provider != "" {
+		modProvider, modErr := moderation.Providers.New(initCtx, cfg.Moderation.Provider, map[string]string{
+			"base_url": cfg.Moderation.BaseURL,
+			"api_key":  cfg.Moderation.APIKey,
+			"model":    cfg.Moderation.Model,
+		})
+		if modErr != nil {
+			logger.Error("Failed to initialize moderation provider", "error", modErr)
+			os.Exit(1)
+		}
+		screenInput, screenOutput := len(cfg.Moderation.Stages) == 0, len(cfg.Moderation.Stages) == 0
+		for _, stage := range cfg.Moderation.Stages {
+			switch stage {
+			case "input":
+				screenInput = true
+			case "output":
+				screenOutput = true
+			default:
+				logger.Error("Invalid moderation stage", "stage", stage)
+				os.Exit(1)
+			}
+		}
+		eng.SetModerator(&moderationAdapter{provider: modProvider, thresholds: cfg.Moderation.Thresholds},
+			screenInput, screenOutput, cfg.Moderation.RefusalMessage)
+		logger.Info("Initialized content moderation", "provider", cfg.Moderation.Provider, "input", screenInput, "output", screenOutput)
+	}
+
+	// Initialize feature flags
+	flagRules := make(map[string]featureflags.Rule, len(cfg.FeatureFlags.Flags))
+	for name, fc := range cfg.FeatureFlags.Flags {
+		flagRules[name] = featureflags.Rule{Enabled: fc.Enabled, Percentage: fc.Percentage, Tenants: fc.Tenants}
+	}
+	features, err := featureflags.New(flagRules)
+	if err != nil {
+		logger.Error("Invalid feature flag configuration", "error", err)
+		os.Exit(1)
+	}
+	eng.SetFeatureFlags(features)
+	if len(flagRules) > 0 {
+		logger.Info("Initialized feature flags", "flags", len(flagRules), "tenant_header", cfg.FeatureFlags.TenantHeader)
+	}
+
+	// Initialize output provenance (optional)
+	if cfg.Provenance.Enabled {
+		eng.SetProvenance(Version)
+		logger.Info("Initialized output provenance")
+	}
+
+	// Initialize stdio MCP servers (optional)
+	var stdioServers *mcp.StdioManager
+	if len(cfg.Connectors.Stdio.AllowedCommands) > 0 {
+		stdioServers = mcp.NewStdioManager(mcp.StdioManagerOptions{
+			AllowedCommands: cfg.Connectors.Stdio.AllowedCommands,
+			MaxConcurrency:  cfg.Connectors.Stdio.MaxConcurrency,
+			IdleTimeout:     cfg.Connectors.Stdio.IdleTimeout,
+		})
+		defer stdioServers.Close()
+		eng.SetStdioManager(stdioServers)
+		logger.Info("Enabled stdio MCP connectors", "allowed_commands", cfg.Connectors.Stdio.AllowedCommands)
+	}
 
 	// Initialize HTTP adapter
 	handler := handlers.New(eng, logger, promptsStore, filesStore, vectorStoresStore, connectorsStore, vectorStoreService)
+	if stdioServers != nil {
+		handler.SetStdioManager(stdioServers)
+	}
+	handler.SetFeatureFlags(features, cfg.FeatureFlags.TenantHeader)
+	handler.SetMaxRequestBytes(cfg.Server.MaxRequestBytes)
+	handler.SetStrictValidation(cfg.Server.StrictValidation)
+	if cfg.Playground.Enabled {
+		handler.EnablePlayground()
+		logger.Warn("Developer playground enabled at /playground; do not expose it publicly")
+	}
+	if cfg.AdminUI.Enabled {
+		handler.EnableAdminUI(Version, adminConfigSummary(cfg))
+		logger.Warn("Admin dashboard enabled at /admin; it has no access control, do not expose it publicly")
+	}
+
+	// Initialize dependency health checks for /healthz and /readyz
+	checker := health.New(health.Options{Timeout: cfg.Health.Timeout, CacheTTL: cfg.Health.CacheTTL})
+	checker.Add("session_store", health.Ping(store))
+	checker.Add("file_store", health.Ping(filesStore))
+	checker.Add("vector_backend", health.Ping(vsBackend))
+	if cfg.Embedding.Type == "openai" && cfg.Embedding.Endpoint != "" {
+		checker.Add("embedding", health.HTTPCheck(nil, health.ModelsURL(cfg.Embedding.Endpoint), cfg.Embedding.APIKey))
+	}
+	if cfg.Engine.ModelEndpoint != "" {
+		checker.Add("model_backend", health.HTTPCheck(nil, health.ModelsURL(cfg.Engine.ModelEndpoint), cfg.Engine.APIKey))
+	}
+	handler.SetHealthChecker(checker)
+
+	// Audit log of mutating operations (optional)
+	var auditLog state.AuditLog
+	if cfg.Audit.Enabled {
+		switch cfg.Audit.Sink {
+		case "file":
+			fileLog, err := audit.OpenFile(cfg.Audit.Path)
+			if err != nil {
+				logger.Error("Failed to open audit log", "error", err)
+				os.Exit(1)
+			}
+			defer fileLog.Close()
+			auditLog = fileLog
+		default:
+			var ok bool
+			if auditLog, ok = store.(state.AuditLog); !ok {
+				logger.Error("Session store cannot hold the audit log; set audit.sink to file", "type", cfg.SessionStore.Type)
+				os.Exit(1)
+			}
+		}
+		handler.SetAuditLog(auditLog)
+		logger.Info("Audit logging enabled", "sink", cfg.Audit.Sink)
+	}
+
+	// Response events published to a message broker (optional), through
+	// the session store outbox
+	var eventRelay *services.EventRelay
+	if cfg.EventBus.Provider != "" {
+		outbox, ok := store.(state.EventOutbox)
+		if !ok {
+			logger.Error("Session store cannot hold the event outbox", "type", cfg.SessionStore.Type)
+			os.Exit(1)
+		}
+		params := maps.Clone(cfg.EventBus.Params)
+		if params == nil {
+			params = make(map[string]string, 1)
+		}
+		params["brokers"] = strings.Join(cfg.EventBus.Brokers, ",")
+		publisher, err := eventbus.Providers.New(initCtx, cfg.EventBus.Provider, params)
+		if err != nil {
+			logger.Error("Failed to initialize event bus", "provider", cfg.EventBus.Provider, "error", err)
+			os.Exit(1)
+		}
+		defer publisher.Close()
+		eventRelay = services.NewEventRelay(outbox, publisher, services.EventRelayOptions{
+			Topics:        eventbus.Topics{Responses: cfg.EventBus.Topics.Responses, Usage: cfg.EventBus.Topics.Usage},
+			Serialization: cfg.EventBus.Serialization,
+			Source:        cfg.EventBus.Source,
+			BatchSize:     cfg.EventBus.BatchSize,
+			Lease:         cfg.EventBus.Lease,
+		})
+		eng.SetResponseEvents(true)
+		logger.Info("Initialized event bus", "provider", cfg.EventBus.Provider, "serialization", cfg.EventBus.Serialization)
+	}
 	logger.Info("Initialized request handlers")
 
+	// Initialize orphan garbage collector
+	gc := services.NewGarbageCollector(filesStore, vectorStoresStore, vsBackend)
+	gcDefaults := services.GCOptions{MinAge: cfg.GC.MinAge, IncludeFiles: cfg.GC.IncludeFiles}
+	handler.SetGarbageCollector(gc, gcDefaults)
+
+	// Data deletion by tenant or metadata
+	handler.SetDataEraser(services.NewDataEraser(eng.Store(), filesStore, vectorStoresStore, vsBackend))
+
+	// File retention: expiry is reported on files, and expired files are reaped
+	retention := services.RetentionPolicy(cfg.FileStore.Retention)
+	handler.SetFileRetention(retention)
+
+	// Session store retention: expired sessions, and responses and
+	// conversations past their TTL, are deleted in batches
+	var sessionReaper *services.SessionReaper
+	if expirer, ok := store.(state.Expirer); ok {
+		sessionReaper = services.NewSessionReaper(expirer, services.SessionRetention{
+			Responses:     cfg.SessionStore.Retention.Responses,
+			Conversations: cfg.SessionStore.Retention.Conversations,
+		}, cfg.SessionStore.Retention.BatchSize)
+		handler.SetSessionReaper(sessionReaper)
+	}
+
+	// Request/response body logging (optional), active while the level is debug
+	var appHandler http.Handler = handler
+	if cfg.Logging.Payloads.Enabled {
+		appHandler = logger.PayloadHandler(handler, logging.PayloadOptions{
+			MaxBytes:           cfg.Logging.Payloads.MaxBytes,
+			RedactFields:       cfg.Logging.Payloads.RedactFields,
+			RedactHeaders:      cfg.Logging.Payloads.RedactHeaders,
+			RedactMetadataKeys: cfg.Logging.Payloads.RedactMetadataKeys,
+		})
+		logger.Info("Payload logging enabled; bodies are logged while logging.level is debug", "level", cfg.Logging.Level)
+	}
+
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Periodic garbage collection (optional)
+	if cfg.GC.Interval > 0 {
+		go runGarbageCollector(ctx, gc, gcDefaults, cfg.GC.Interval, logger)
+		logger.Info("Started garbage collector", "interval", cfg.GC.Interval, "min_age", cfg.GC.MinAge, "include_files", cfg.GC.IncludeFiles)
+	}
+
+	// Periodic reaping of expired files (when a retention policy is set)
+	if len(retention) > 0 {
+		reaper := services.NewFileReaper(filesStore, vectorStoresStore, vsBackend, retention)
+		go runFileReaper(ctx, reaper, cfg.FileStore.RetentionInterval, logger)
+		logger.Info("Started file reaper", "interval", cfg.FileStore.RetentionInterval, "retention", cfg.FileStore.Retention)
+	}
+	if sessionReaper != nil {
+		go runSessionReaper(ctx, sessionReaper, cfg.SessionStore.Retention.Interval, logger)
+		logger.Info("Started session store reaper", "interval", cfg.SessionStore.Retention.Interval,
+			"responses", cfg.SessionStore.Retention.Responses, "conversations", cfg.SessionStore.Retention.Conversations)
+	}
+	if eventRelay != nil {
+		go runEventRelay(ctx, eventRelay, cfg.EventBus.PollInterval, logger)
+		logger.Info("Started event relay", "interval", cfg.EventBus.PollInterval,
+			"responses_topic", cfg.EventBus.Topics.Responses, "usage_topic", cfg.EventBus.Topics.Usage)
+	}
+
+	// Reload the reloadable settings on SIGHUP or when the file changes
+	configReloader := &reloader{path: *configPath, current: cfg, logger: logger, engine: eng, webSearch: webSearch, egress: egressSet}
+	go configReloader.run(ctx, *watchConfig)
+
+	// Responses gRPC service (optional), alongside either mode
+	var grpcServer *grpcAdapter.Server
+	if cfg.GRPC.Enabled {
+		grpcTLS, err := listenerTLS("grpc", cfg.GRPC.TLS, logger)
+		if err != nil {
+			logger.Error("Failed to configure gRPC TLS", "error", err)
+			os.Exit(1)
+		}
+		grpcServer = grpcAdapter.NewServer(eng, logger, grpcAdapter.Options{
+			TenantHeader: cfg.FeatureFlags.TenantHeader,
+			TLS:          grpcTLS,
+			Audit:        auditLog,
+		})
+		grpcAddr := fmt.Sprintf("%s:%d", cfg.GRPC.Host, cfg.GRPC.Port)
+		go func() {
+			if err := grpcServer.Start(grpcAddr); err != nil {
+				logger.Error("Responses gRPC server error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	var srv *http.Server
 
 	if cfg.ExtProc.Enabled {
 		// ExtProc mode: gRPC server only, no HTTP listener
-		extprocServer := extprocAdapter.NewServer(handler, logger)
+		extprocOpts := extprocAdapter.Options{
+			StreamRequestBody: cfg.ExtProc.StreamRequestBody,
+		}
+		extprocOpts.TLS, err = listenerTLS("extproc", cfg.ExtProc.TLS, logger)
+		if err != nil {
+			logger.Error("Failed to configure ExtProc TLS", "error", err)
+			os.Exit(1)
+		}
+		if cfg.ExtProc.Mode == "passthrough" {
+			// Envoy forwards responses requests to the backend itself
+			extprocOpts.Passthrough = handler.PrepareBackendRequest
+		}
+		extprocServer := extprocAdapter.NewServer(appHandler, logger, extprocOpts)
 		grpcAddr := fmt.Sprintf("%s:%d", cfg.ExtProc.Host, cfg.ExtProc.Port)
 		go func() {
 			if err := extprocServer.Start(grpcAddr); err != nil {
@@ -229,20 +676,75 @@ func main() {
 
 		<-ctx.Done()
 		logger.Info("Shutdown signal received")
+		drainResponses(handler, grpcServer, cfg.Server.ShutdownGracePeriod, logger)
 		extprocServer.Stop()
 	} else {
 		// Standalone mode: HTTP server
 		httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+		httpHandler := appHandler
+		if cfg.Server.Compression.Enabled {
+			httpHandler, err = compression.Handler(appHandler, compression.Options{
+				MinSize: cfg.Server.Compression.MinSize,
+				Level:   cfg.Server.Compression.Level,
+			})
+			if err != nil {
+				logger.Error("Failed to configure compression", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("HTTP compression enabled", "min_size", cfg.Server.Compression.MinSize, "level", cfg.Server.Compression.Level)
+		}
+		if cfg.Server.CORS.Enabled {
+			// Outermost, so preflight requests are answered before anything else
+			exposed := cfg.Server.CORS.ExposedHeaders
+			if len(exposed) == 0 {
+				exposed = handlers.ResponseHeaders
+			}
+			httpHandler, err = cors.Handler(httpHandler, cors.Options{
+				AllowedOrigins:   cfg.Server.CORS.AllowedOrigins,
+				AllowedMethods:   cfg.Server.CORS.AllowedMethods,
+				AllowedHeaders:   cfg.Server.CORS.AllowedHeaders,
+				ExposedHeaders:   exposed,
+				AllowCredentials: cfg.Server.CORS.AllowCredentials,
+				MaxAge:           cfg.Server.CORS.MaxAge,
+			})
+			if err != nil {
+				logger.Error("Failed to configure CORS", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("CORS enabled", "allowed_origins", cfg.Server.CORS.AllowedOrigins, "allow_credentials", cfg.Server.CORS.AllowCredentials)
+		}
+		if cfg.WebSocket.Enabled {
+			// Mounted outside compression: the connection is hijacked
+			mux := http.NewServeMux()
+			mux.Handle("/", httpHandler)
+			mux.Handle("GET "+websocketAdapter.Path, websocketAdapter.NewServer(appHandler, logger, websocketAdapter.Options{
+				AllowedOrigins:  cfg.WebSocket.AllowedOrigins,
+				MaxMessageBytes: cfg.WebSocket.MaxMessageBytes,
+			}))
+			httpHandler = mux
+			logger.Info("WebSocket adapter enabled", "path", websocketAdapter.Path)
+		}
+		httpTLS, err := listenerTLS("http", cfg.Server.TLS, logger)
+		if err != nil {
+			logger.Error("Failed to configure HTTP TLS", "error", err)
+			os.Exit(1)
+		}
 		srv = &http.Server{
 			Addr:         httpAddr,
-			Handler:      handler,
+			Handler:      httpHandler,
+			TLSConfig:    httpTLS,
 			ReadTimeout:  cfg.Server.Timeout,
 			WriteTimeout: cfg.Server.Timeout,
 			IdleTimeout:  120 * time.Second,
 		}
 		go func() {
-			logger.Info("HTTP server listening", "address", httpAddr)
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Info("HTTP server listening", "address", httpAddr, "tls", httpTLS != nil)
+			serve := srv.ListenAndServe
+			if httpTLS != nil {
+				// The certificate comes from TLSConfig, which reloads it
+				serve = func() error { return srv.ListenAndServeTLS("", "") }
+			}
+			if err := serve(); err != nil && err != http.ErrServerClosed {
 				logger.Error("HTTP server error", "error", err)
 				os.Exit(1)
 			}
@@ -250,6 +752,7 @@ func main() {
 
 		<-ctx.Done()
 		logger.Info("Shutdown signal received")
+		drainResponses(handler, grpcServer, cfg.Server.ShutdownGracePeriod, logger)
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -261,13 +764,196 @@ func main() {
 	logger.Info("Server stopped gracefully")
 }
 
-// webSearchAdapter adapts websearch.Provider to engine.WebSearcher.
+// drainResponses stops accepting new responses and lets in-flight ones,
+// including SSE streams and gRPC calls, finish within the grace period before they are
+// interrupted and saved as incomplete.
+func drainResponses(handler *handlers.Handler, grpcServer *grpcAdapter.Server, gracePeriod time.Duration, logger *logging.Logger) {
+	logger.Info("Draining in-flight responses", "grace_period", gracePeriod)
+	drainCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	// gRPC calls bypass the HTTP handlers: drain them concurrently
+	var wg sync.WaitGroup
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			grpcServer.Stop(drainCtx)
+		}()
+	}
+	err := handler.Drain(drainCtx)
+	wg.Wait()
+	if err != nil {
+		logger.Error("Failed to drain in-flight responses", "error", err)
+		return
+	}
+	logger.Info("In-flight responses drained")
+}
+
+// runGarbageCollector removes orphans every interval until ctx is done.
+func runGarbageCollector(ctx context.Context, gc *services.GarbageCollector, opts services.GCOptions, interval time.Duration, logger *logging.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := gc.Run(ctx, opts)
+			if err != nil {
+				logger.Error("Garbage collection failed", "error", err)
+				continue
+			}
+			if len(report.Orphans) > 0 {
+				logger.Info("Garbage collection completed", "found", len(report.Orphans), "removed", report.Removed())
+			}
+		}
+	}
+}
+
+// runFileReaper deletes expired files every interval until ctx is done.
+func runFileReaper(ctx context.Context, reaper *services.FileReaper, interval time.Duration, logger *logging.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reaped, err := reaper.Run(ctx, now)
+			if err != nil {
+				logger.Error("File reaping failed", "error", err)
+			}
+			for _, rf := range reaped {
+				if rf.Error != "" {
+					logger.Error("Failed to delete expired file", "file_id", rf.FileID, "purpose", rf.Purpose, "error", rf.Error)
+				} else {
+					logger.Info("Deleted expired file", "file_id", rf.FileID, "purpose", rf.Purpose, "vector_stores", rf.VectorStoreIDs)
+				}
+			}
+		}
+	}
+}
+
+// runSessionReaper deletes expired session store data every interval until
+// ctx is done.
+func runSessionReaper(ctx context.Context, reaper *services.SessionReaper, interval time.Duration, logger *logging.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := reaper.Run(ctx, now)
+			if err != nil {
+				logger.Error("Session store reaping failed", "error", err)
+			}
+			if len(deleted) > 0 {
+				logger.Info("Deleted expired session store data",
+					"sessions", deleted[state.ExpiredSessions],
+					"responses", deleted[state.ExpiredResponses],
+					"conversations", deleted[state.ExpiredConversations])
+			}
+		}
+	}
+}
+
+// runEventRelay publishes the events of the outbox every interval until
+// ctx is done.
+func runEventRelay(ctx context.Context, relay *services.EventRelay, interval time.Duration, logger *logging.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := relay.Run(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("Event publication failed", "error", err)
+			}
+			if published > 0 {
+				logger.Debug("Published response events", "events", published)
+			}
+		}
+	}
+}
+
+// newEmbedder creates the embedder described by cfg. The dimensions of
+// local embedders are those of their model.
+func newEmbedder(cfg config.EmbedderConfig, runtimeLibrary string) (services.Embedder, error) {
+	e := services.Embedder{Model: cfg.Model, Dimensions: cfg.Dimensions}
+	if cfg.Type == "local" {
+		client, err := local.New(local.Options{
+			ModelPath:      cfg.ModelPath,
+			RuntimeLibrary: runtimeLibrary,
+			MaxTokens:      cfg.MaxTokens,
+			Dimensions:     cfg.Dimensions,
+		})
+		if err != nil {
+			return e, fmt.Errorf("load local model %s: %w", cfg.ModelPath, err)
+		}
+		e.Client = client
+		e.Dimensions = client.Dimensions()
+		return e, nil
+	}
+	e.Client = api.NewOpenAIEmbeddingClient(cfg.Endpoint, cfg.APIKey, cfg.Model, cfg.Dimensions)
+	return e, nil
+}
+
+// newWebSearchProvider creates the configured web search provider, behind
+// its domain filter and result cache. If policies is non-nil, the provider
+// follows the global egress policy.
+func newWebSearchProvider(ctx context.Context, cfg config.WebSearchConfig, policies *egress.Set) (websearch.Provider, error) {
+	p, err := websearch.Providers.New(ctx, cfg.Provider, webSearchParams(cfg))
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := p.(websearch.HTTPClientSetter); ok && policies != nil {
+		s.SetHTTPClient(policies.Client(""))
+	}
+	if len(cfg.AllowedDomains) > 0 || len(cfg.BlockedDomains) > 0 {
+		p = websearch.NewDomainFilter(p, cfg.AllowedDomains, cfg.BlockedDomains)
+	}
+	if cfg.CacheTTL > 0 {
+		p = websearch.NewCachingProvider(p, cfg.CacheTTL, cfg.CacheSize)
+	}
+	return p, nil
+}
+
+// webSearchParams returns the registry parameters of the web search
+// provider.
+func webSearchParams(cfg config.WebSearchConfig) map[string]string {
+	return map[string]string{
+		"api_key":   cfg.APIKey,
+		"base_url":  cfg.BaseURL,
+		"engine_id": cfg.EngineID,
+		"freshness": cfg.Freshness,
+		"language":  cfg.Language,
+		"country":   cfg.Country,
+		"sites":     strings.Join(cfg.Sites, ","),
+	}
+}
+
+// webSearchAdapter adapts websearch.Provider to engine.WebSearcher. The
+// provider can be replaced on config reload.
 type webSearchAdapter struct {
+	mu       sync.RWMutex
 	provider websearch.Provider
 }
 
+func (a *webSearchAdapter) setProvider(p websearch.Provider) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.provider = p
+}
+
 func (a *webSearchAdapter) Search(ctx context.Context, query string, maxResults int) ([]engine.WebSearchResult, error) {
-	results, err := a.provider.Search(ctx, query, maxResults)
+	a.mu.RLock()
+	provider := a.provider
+	a.mu.RUnlock()
+	results, err := provider.Search(ctx, query, maxResults)
 	if err != nil {
 		return nil, err
 	}
@@ -281,3 +967,18 @@ func (a *webSearchAdapter) Search(ctx context.Context, query string, maxResults
 	}
 	return out, nil
 }
+
+// moderationAdapter adapts moderation.Provider to engine.Moderator,
+// applying the configured per-category thresholds.
+type moderationAdapter struct {
+	provider   moderation.Provider
+	thresholds map[string]float64
+}
+
+func (a *moderationAdapter) Check(ctx context.Context, text string) ([]string, error) {
+	result, err := a.provider.Moderate(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return moderation.Violations(result, a.thresholds), nil
+}
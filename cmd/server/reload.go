@@ -0,0 +1,214 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/egress"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/moderation"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+	"github.com/leseb/openresponses-gw/pkg/websearch"
+)
+
+// checkConfig validates the configuration file at path, including the
+// providers it names, and reports the result to out. It returns the
+// process exit code.
+func checkConfig(path string, out io.Writer) int {
+	cfg, err := config.Check(path)
+	if cfg == nil {
+		fmt.Fprintf(out, "%s: %v\n", path, err)
+		return 1
+	}
+
+	errs := []error{err}
+	for _, p := range []struct {
+		field, name string
+		available   []string
+	}{
+		{"session_store.type", cfg.SessionStore.Type, state.Providers.Available()},
+		{"file_store.type", cfg.FileStore.Type, filestore.Providers.Available()},
+		{"vector_store.type", cfg.VectorStore.Type, vectorstore.Providers.Available()},
+		{"web_search.provider", cfg.WebSearch.Provider, websearch.Providers.Available()},
+		{"moderation.provider", cfg.Moderation.Provider, moderation.Providers.Available()},
+	} {
+		if p.name != "" && !slices.Contains(p.available, p.name) {
+			errs = append(errs, fmt.Errorf("%s: unknown provider %q (available: %v)", p.field, p.name, p.available))
+		}
+	}
+	flagRules := make(map[string]featureflags.Rule, len(cfg.FeatureFlags.Flags))
+	for name, fc := range cfg.FeatureFlags.Flags {
+		flagRules[name] = featureflags.Rule{Enabled: fc.Enabled, Percentage: fc.Percentage, Tenants: fc.Tenants}
+	}
+	if _, err := featureflags.New(flagRules); err != nil {
+		errs = append(errs, fmt.Errorf("feature_flags: %w", err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintf(out, "%s is invalid:\n%v\n", path, err)
+		return 1
+	}
+	fmt.Fprintf(out, "%s is valid\n", path)
+	return 0
+}
+
+// reloader re-reads the configuration file on SIGHUP, or when it changes,
+// and applies the settings that can change while the gateway runs: the
+// log level, the admission limits, the model aliases and the web search
+// provider and API key. Other changes are reported and need a restart.
+type reloader struct {
+	path      string
+	current   *config.Config
+	logger    *logging.Logger
+	engine    reloadableEngine
+	webSearch *webSearchAdapter // nil when web search was disabled at startup
+	egress    *egress.Set       // nil when no egress policy is configured
+}
+
+// reloadableEngine is the part of the engine a reload changes.
+type reloadableEngine interface {
+	SetAdmissionLimits(cfg config.AdmissionConfig) error
+	SetModelAliases(aliases map[string]string)
+}
+
+var _ reloadableEngine = (*engine.Engine)(nil)
+
+// run reloads the configuration until ctx is done. When watchInterval is
+// positive, the file is also checked for changes at that interval.
+func (r *reloader) run(ctx context.Context, watchInterval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	modTime := r.modTime()
+	if watchInterval > 0 {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading config", "path", r.path)
+			r.reload(ctx)
+		case <-tick:
+			// Compared for equality: ConfigMap updates can move it backwards
+			if t := r.modTime(); !t.Equal(modTime) {
+				modTime = t
+				r.logger.Info("Config file changed, reloading config", "path", r.path)
+				r.reload(ctx)
+			}
+		}
+	}
+}
+
+func (r *reloader) modTime() time.Time {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// reload loads and validates the configuration file and applies it. An
+// invalid file is logged and leaves the running configuration unchanged.
+func (r *reloader) reload(ctx context.Context) {
+	cfg, err := config.Load(r.path)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		r.logger.Error("Config reload failed, keeping the current config", "error", err)
+		return
+	}
+
+	if cfg.Logging.Level != r.current.Logging.Level {
+		r.logger.SetLevel(cfg.Logging.Level)
+		r.logger.Info("Log level changed", "level", cfg.Logging.Level)
+	}
+
+	if limits := admissionLimits(cfg); !reflect.DeepEqual(limits, admissionLimits(r.current)) {
+		if err := r.engine.SetAdmissionLimits(limits); err != nil {
+			r.logger.Warn("Admission limits not reloaded; restart to apply them", "error", err)
+			cfg.Engine.Admission = r.current.Engine.Admission
+		} else {
+			r.logger.Info("Admission limits reloaded", "max_concurrent", limits.MaxConcurrent, "max_queue", limits.MaxQueue)
+		}
+	}
+
+	if !reflect.DeepEqual(cfg.Engine.ModelAliases, r.current.Engine.ModelAliases) {
+		r.engine.SetModelAliases(cfg.Engine.ModelAliases)
+		r.logger.Info("Model aliases reloaded", "aliases", len(cfg.Engine.ModelAliases))
+	}
+
+	if !reflect.DeepEqual(cfg.WebSearch, r.current.WebSearch) {
+		switch {
+		case r.webSearch == nil:
+			r.logger.Warn("Web search was disabled at startup; restart to enable it")
+			cfg.WebSearch = r.current.WebSearch
+		case cfg.WebSearch.Provider == "":
+			r.logger.Warn("Web search cannot be disabled without a restart")
+			cfg.WebSearch = r.current.WebSearch
+		default:
+			provider, err := newWebSearchProvider(ctx, cfg.WebSearch, r.egress)
+			if err != nil {
+				r.logger.Error("Failed to reload web search provider", "error", err)
+				cfg.WebSearch = r.current.WebSearch
+				break
+			}
+			r.webSearch.setProvider(provider)
+			r.logger.Info("Web search provider reloaded", "provider", cfg.WebSearch.Provider)
+		}
+	}
+
+	if !reflect.DeepEqual(withoutReloadable(cfg), withoutReloadable(r.current)) {
+		r.logger.Warn("Config changes other than logging.level, the engine.admission limits, engine.model_aliases and web_search need a restart to apply")
+	}
+	// Keep the running values of the settings that were not applied
+	reloaded := *r.current
+	reloaded.Logging.Level = cfg.Logging.Level
+	reloaded.Engine.Admission = admissionLimits(cfg)
+	reloaded.Engine.Admission.TierPriorities = r.current.Engine.Admission.TierPriorities
+	reloaded.Engine.ModelAliases = cfg.Engine.ModelAliases
+	reloaded.WebSearch = cfg.WebSearch
+	r.current = &reloaded
+}
+
+// admissionLimits returns the admission settings of cfg that reload
+// applies: all but the tier priorities.
+func admissionLimits(cfg *config.Config) config.AdmissionConfig {
+	limits := cfg.Engine.Admission
+	limits.TierPriorities = nil
+	return limits
+}
+
+// withoutReloadable returns a copy of cfg without the settings reload
+// applies.
+func withoutReloadable(cfg *config.Config) config.Config {
+	c := *cfg
+	c.Logging.Level = ""
+	c.Engine.Admission = config.AdmissionConfig{TierPriorities: cfg.Engine.Admission.TierPriorities}
+	c.Engine.ModelAliases = nil
+	c.WebSearch = config.WebSearchConfig{}
+	return c
+}
@@ -0,0 +1,197 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+)
+
+type fakeReloadEngine struct {
+	admissionErr error
+	limits       []config.AdmissionConfig
+	aliases      []map[string]string
+}
+
+func (e *fakeReloadEngine) SetAdmissionLimits(cfg config.AdmissionConfig) error {
+	if e.admissionErr != nil {
+		return e.admissionErr
+	}
+	e.limits = append(e.limits, cfg)
+	return nil
+}
+
+func (e *fakeReloadEngine) SetModelAliases(aliases map[string]string) {
+	e.aliases = append(e.aliases, aliases)
+}
+
+const reloadBaseConfig = `
+server:
+  port: 8080
+engine:
+  model_endpoint: http://localhost:8000/v1
+  admission:
+    max_concurrent: 4
+    tier_priorities:
+      priority: 10
+  model_aliases:
+    fast: m-small
+logging:
+  level: info
+`
+
+const reloadChangedConfig = `
+server:
+  port: 9090
+engine:
+  model_endpoint: http://localhost:8000/v1
+  admission:
+    max_concurrent: 8
+    max_queue: 16
+    tier_priorities:
+      priority: 20
+  model_aliases:
+    fast: m-large
+    smart: m-reasoning
+logging:
+  level: debug
+`
+
+// newTestReloader returns a reloader of a configuration file holding
+// reloadBaseConfig, and its log.
+func newTestReloader(t *testing.T, eng reloadableEngine) (*reloader, *bytes.Buffer) {
+	t.Helper()
+	for _, name := range []string{"OPENAI_API_ENDPOINT", "BACKEND_API", "LOG_LEVEL", "ADMISSION_MAX_CONCURRENT", "ADMISSION_MAX_QUEUE"} {
+		t.Setenv(name, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(reloadBaseConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var log bytes.Buffer
+	logger := logging.New(logging.Config{Level: cfg.Logging.Level, Output: &log})
+	return &reloader{path: path, current: cfg, logger: logger, engine: eng}, &log
+}
+
+func writeReloadConfig(t *testing.T, r *reloader, data string) {
+	t.Helper()
+	if err := os.WriteFile(r.path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	eng := &fakeReloadEngine{}
+	r, log := newTestReloader(t, eng)
+	ctx := context.Background()
+
+	writeReloadConfig(t, r, reloadChangedConfig)
+	r.reload(ctx)
+
+	// Reloadable settings are applied
+	if !r.logger.Enabled(ctx, slog.LevelDebug) {
+		t.Error("log level was not changed to debug")
+	}
+	wantLimits := config.AdmissionConfig{MaxConcurrent: 8, MaxQueue: 16, QueueTimeout: r.current.Engine.Admission.QueueTimeout}
+	if len(eng.limits) != 1 || !reflect.DeepEqual(eng.limits[0], wantLimits) {
+		t.Errorf("admission limits set = %+v, want %+v", eng.limits, wantLimits)
+	}
+	wantAliases := map[string]string{"fast": "m-large", "smart": "m-reasoning"}
+	if len(eng.aliases) != 1 || !reflect.DeepEqual(eng.aliases[0], wantAliases) {
+		t.Errorf("model aliases set = %v, want %v", eng.aliases, wantAliases)
+	}
+	if got := r.current; got.Logging.Level != "debug" || got.Engine.Admission.MaxConcurrent != 8 ||
+		!reflect.DeepEqual(got.Engine.ModelAliases, wantAliases) {
+		t.Errorf("current config = level %q, admission %+v, aliases %v", got.Logging.Level, got.Engine.Admission, got.Engine.ModelAliases)
+	}
+
+	// Others keep their running values
+	if got := r.current; got.Server.Port != 8080 || got.Engine.Admission.TierPriorities["priority"] != 10 {
+		t.Errorf("current config = port %d, tier priorities %v, want the startup values", got.Server.Port, got.Engine.Admission.TierPriorities)
+	}
+	if !strings.Contains(log.String(), "need a restart") {
+		t.Errorf("log does not report the changes needing a restart:\n%s", log.String())
+	}
+
+	// Reloading an unchanged file applies nothing
+	log.Reset()
+	r.reload(ctx)
+	if len(eng.limits) != 1 || len(eng.aliases) != 1 {
+		t.Errorf("unchanged reload set limits %d times and aliases %d times, want once", len(eng.limits), len(eng.aliases))
+	}
+}
+
+func TestReload_Invalid(t *testing.T) {
+	eng := &fakeReloadEngine{}
+	r, log := newTestReloader(t, eng)
+	current := r.current
+
+	writeReloadConfig(t, r, strings.Replace(reloadChangedConfig, "max_queue: 16", "max_queue: -1", 1))
+	r.reload(context.Background())
+	if r.current != current || len(eng.limits) != 0 || len(eng.aliases) != 0 || r.logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("an invalid config was applied")
+	}
+	if !strings.Contains(log.String(), "Config reload failed") {
+		t.Errorf("log does not report the failure:\n%s", log.String())
+	}
+}
+
+func TestReload_AdmissionDisabledAtStartup(t *testing.T) {
+	eng := &fakeReloadEngine{admissionErr: errors.New("admission control was disabled at startup")}
+	r, _ := newTestReloader(t, eng)
+
+	writeReloadConfig(t, r, reloadChangedConfig)
+	r.reload(context.Background())
+	if got := r.current.Engine.Admission; got.MaxConcurrent != 4 || got.MaxQueue != 0 {
+		t.Errorf("admission = %+v, want the startup limits kept", got)
+	}
+	if len(eng.aliases) != 1 || r.current.Logging.Level != "debug" {
+		t.Error("the other reloadable settings were not applied")
+	}
+}
+
+// The file is reloaded when it changes
+func TestReloader_Watch(t *testing.T) {
+	eng := &fakeReloadEngine{}
+	r, _ := newTestReloader(t, eng)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.run(ctx, 5*time.Millisecond)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	writeReloadConfig(t, r, reloadChangedConfig)
+	// Move the modification time, in case the file system's is coarse
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(r.path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if r.logger.Enabled(ctx, slog.LevelDebug) {
+			break
+		}
+	}
+	cancel()
+	<-done
+	if !r.logger.Enabled(ctx, slog.LevelDebug) {
+		t.Error("the changed file was not reloaded")
+	}
+}
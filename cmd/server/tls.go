@@ -0,0 +1,39 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/tls"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/servertls"
+)
+
+// listenerTLS returns the TLS configuration of the named listener, or nil
+// when cfg sets no certificate.
+func listenerTLS(name string, cfg config.TLSConfig, logger *logging.Logger) (*tls.Config, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+	tlsConfig, err := servertls.Config(servertls.Options{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		ClientCAFile:   cfg.ClientCAFile,
+		AllowedSANs:    cfg.AllowedSANs,
+		ReloadInterval: cfg.ReloadInterval,
+		OnReload: func(err error) {
+			if err != nil {
+				logger.Warn("Failed to reload TLS files; keeping the previous ones", "listener", name, "error", err)
+				return
+			}
+			logger.Info("Reloaded TLS files", "listener", name)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("TLS enabled", "listener", name, "cert_file", cfg.CertFile, "client_auth", cfg.ClientCAFile != "", "allowed_sans", cfg.AllowedSANs)
+	return tlsConfig, nil
+}
@@ -0,0 +1,103 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command ssecheck checks the event ordering of a /v1/responses stream.
+//
+// It either sends a streamed request to a running gateway:
+//
+//	ssecheck -url http://localhost:8080/v1 -model Qwen/Qwen3-0.6B -input "Hello"
+//	ssecheck -url http://localhost:8080/v1 -request request.json
+//
+// or reads a captured stream:
+//
+//	curl -sN http://localhost:8080/v1/responses -d @request.json | ssecheck -file -
+//
+// It prints every violation and exits with status 1 if there is any.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/ssecheck"
+)
+
+func main() {
+	baseURL := flag.String("url", envOr("OPENRESPONSES_BASE_URL", "http://localhost:8080/v1"), "Base URL of the gateway API")
+	apiKey := flag.String("api-key", os.Getenv("OPENRESPONSES_API_KEY"), "API key sent as a bearer token")
+	model := flag.String("model", envOr("OPENRESPONSES_MODEL", "Qwen/Qwen3-0.6B"), "Model of the request")
+	input := flag.String("input", "Say hello in one word.", "Input of the request")
+	requestFile := flag.String("request", "", "JSON file with the request body (overrides -model and -input)")
+	streamFile := flag.String("file", "", `Captured SSE stream to check instead of sending a request ("-": stdin)`)
+	timeout := flag.Duration("timeout", 2*time.Minute, "Timeout of the request")
+	verbose := flag.Bool("v", false, "Print the type of every event")
+	flag.Parse()
+
+	events, err := readEvents(*streamFile, *requestFile, *baseURL, *apiKey, *model, *input, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssecheck: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *verbose {
+		for i, event := range events {
+			fmt.Printf("%4d %s\n", i, event.Type())
+		}
+	}
+
+	errs := ssecheck.Validate(events)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(errs) > 0 {
+		fmt.Printf("%d events, %d violations\n", len(events), len(errs))
+		os.Exit(1)
+	}
+	fmt.Printf("%d events, stream is conformant\n", len(events))
+}
+
+// readEvents reads the captured stream if streamFile is set, and otherwise
+// streams the request from the gateway.
+func readEvents(streamFile, requestFile, baseURL, apiKey, model, input string, timeout time.Duration) ([]ssecheck.Event, error) {
+	if streamFile != "" {
+		var r io.Reader = os.Stdin
+		if streamFile != "-" {
+			f, err := os.Open(streamFile)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		return ssecheck.Read(r)
+	}
+
+	body := map[string]interface{}{"model": model, "input": input}
+	if requestFile != "" {
+		data, err := os.ReadFile(requestFile)
+		if err != nil {
+			return nil, err
+		}
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("%s: %w", requestFile, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return ssecheck.Stream(ctx, http.DefaultClient, baseURL, apiKey, body)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
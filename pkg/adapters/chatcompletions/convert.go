@@ -0,0 +1,439 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package chatcompletions serves the OpenAI Chat Completions API on top of
+// the engine. Requests are converted to Responses API requests, so they get
+// conversations, MCP tools and file_search like any other response, and the
+// results are converted back to chat completions and chunks.
+package chatcompletions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// Path is the route the adapter is mounted on.
+const Path = "/v1/chat/completions"
+
+// Request is a Chat Completions request. Besides the OpenAI fields, it
+// accepts the Responses API tools (file_search, mcp, web_search) and the
+// gateway's conversation fields.
+type Request struct {
+	api.ChatCompletionRequest
+
+	// Function tools or Responses API tools
+	Tools []json.RawMessage `json:"tools,omitempty"`
+
+	MaxCompletionTokens *int              `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      *ResponseFormat   `json:"response_format,omitempty"`
+	ReasoningEffort     *string           `json:"reasoning_effort,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	Store               *bool             `json:"store,omitempty"`
+
+	// Continue a conversation or an earlier response (gateway extensions)
+	Conversation       *string `json:"conversation,omitempty"`
+	PreviousResponseID *string `json:"previous_response_id,omitempty"`
+}
+
+// ResponseFormat selects plain text or JSON output.
+type ResponseFormat struct {
+	Type string `json:"type"` // "text", "json_object", "json_schema"
+}
+
+// ToRequest converts a Chat Completions request to a Responses API request.
+func ToRequest(in *Request) (*schema.ResponseRequest, error) {
+	if len(in.Messages) == 0 {
+		return nil, fmt.Errorf("messages is required")
+	}
+	req := &schema.ResponseRequest{
+		Temperature:        in.Temperature,
+		TopP:               in.TopP,
+		MaxOutputTokens:    in.MaxTokens,
+		FrequencyPenalty:   in.FrequencyPenalty,
+		PresencePenalty:    in.PresencePenalty,
+		ParallelToolCalls:  in.ParallelToolCalls,
+		TopLogprobs:        in.TopLogprobs,
+		Seed:               in.Seed,
+		Stop:               in.Stop,
+		PromptCacheKey:     in.PromptCacheKey,
+		Metadata:           in.Metadata,
+		Store:              in.Store,
+		Stream:             in.Stream,
+		Conversation:       in.Conversation,
+		PreviousResponseID: in.PreviousResponseID,
+		CandidateCount:     in.N,
+	}
+	if in.Model != "" {
+		req.Model = &in.Model
+	}
+	if in.MaxCompletionTokens != nil {
+		req.MaxOutputTokens = in.MaxCompletionTokens
+	}
+	if in.Logprobs != nil && *in.Logprobs {
+		req.Include = append(req.Include, "message.output_text.logprobs")
+	}
+	if in.ResponseFormat != nil {
+		req.Text = &schema.TextField{Format: schema.TextFormat{Type: in.ResponseFormat.Type}}
+	}
+	if in.ReasoningEffort != nil {
+		req.Reasoning = &schema.ReasoningParam{Type: "default", Effort: in.ReasoningEffort}
+	}
+
+	input, err := toInput(in.Messages)
+	if err != nil {
+		return nil, err
+	}
+	req.Input = input
+
+	for i, raw := range in.Tools {
+		tool, err := toTool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tools[%d]: %w", i, err)
+		}
+		req.Tools = append(req.Tools, tool)
+	}
+	req.ToolChoice = toToolChoice(in.ToolChoice)
+	return req, nil
+}
+
+// toInput converts messages to Responses API input items. Assistant tool
+// calls become function_call items and tool messages function_call_output
+// items.
+func toInput(messages []api.ChatCompletionMsg) ([]interface{}, error) {
+	var items []interface{}
+	for i, msg := range messages {
+		switch msg.Role {
+		case "system", "developer", "user", "assistant":
+			content, err := toContent(msg.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid messages[%d]: %w", i, err)
+			}
+			if content != nil {
+				items = append(items, map[string]interface{}{
+					"type":    "message",
+					"role":    msg.Role,
+					"content": content,
+				})
+			}
+			for _, tc := range msg.ToolCalls {
+				items = append(items, map[string]interface{}{
+					"type":      "function_call",
+					"call_id":   tc.ID,
+					"name":      tc.Function.Name,
+					"arguments": tc.Function.Arguments,
+				})
+			}
+		case "tool":
+			output, err := toText(msg.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid messages[%d]: %w", i, err)
+			}
+			items = append(items, map[string]interface{}{
+				"type":    "function_call_output",
+				"call_id": msg.ToolCallID,
+				"output":  output,
+			})
+		default:
+			return nil, fmt.Errorf("invalid messages[%d]: unsupported role %q", i, msg.Role)
+		}
+	}
+	return items, nil
+}
+
+// toContent converts message content, a string or an array of parts, to
+// the content of a message item. It returns nil for empty content.
+func toContent(content interface{}) (interface{}, error) {
+	switch v := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return v, nil
+	case []interface{}:
+		parts := make([]interface{}, 0, len(v))
+		for _, p := range v {
+			var part api.ChatCompletionContentPart
+			if err := remarshal(p, &part); err != nil {
+				return nil, err
+			}
+			switch part.Type {
+			case "text":
+				parts = append(parts, map[string]interface{}{"type": "input_text", "text": part.Text})
+			case "image_url":
+				if part.ImageURL == nil {
+					return nil, fmt.Errorf("image_url part without image_url")
+				}
+				parts = append(parts, map[string]interface{}{
+					"type":      "input_image",
+					"image_url": part.ImageURL.URL,
+					"detail":    part.ImageURL.Detail,
+				})
+			case "file":
+				if part.File == nil {
+					return nil, fmt.Errorf("file part without file")
+				}
+				parts = append(parts, map[string]interface{}{
+					"type":      "input_file",
+					"file_id":   part.File.FileID,
+					"file_data": part.File.FileData,
+					"filename":  part.File.Filename,
+				})
+			case "video_url":
+				if part.VideoURL == nil {
+					return nil, fmt.Errorf("video_url part without video_url")
+				}
+				parts = append(parts, map[string]interface{}{"type": "input_video", "video_url": part.VideoURL.URL})
+			case "input_audio":
+				if part.InputAudio == nil {
+					return nil, fmt.Errorf("input_audio part without input_audio")
+				}
+				parts = append(parts, map[string]interface{}{
+					"type": "input_audio",
+					"input_audio": map[string]interface{}{
+						"data":   part.InputAudio.Data,
+						"format": part.InputAudio.Format,
+					},
+				})
+			default:
+				return nil, fmt.Errorf("unsupported content part type %q", part.Type)
+			}
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("content must be a string or an array of parts")
+	}
+}
+
+// toText returns the text of a tool message, whose content is a string or
+// an array of text parts.
+func toText(content interface{}) (string, error) {
+	switch v := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []interface{}:
+		var parts []api.ChatCompletionContentPart
+		if err := remarshal(v, &parts); err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		for _, part := range parts {
+			if part.Type != "text" {
+				return "", fmt.Errorf("unsupported content part type %q in tool message", part.Type)
+			}
+			b.WriteString(part.Text)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("content must be a string or an array of parts")
+	}
+}
+
+// toTool converts a tool definition. Function tools use the nested Chat
+// Completions shape; the other types are Responses API tools and are
+// decoded as such.
+func toTool(raw json.RawMessage) (schema.ResponsesToolParam, error) {
+	var tool schema.ResponsesToolParam
+	var head struct {
+		Type     string                          `json:"type"`
+		Function *api.ChatCompletionToolFunction `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return tool, err
+	}
+	if head.Type != "function" {
+		err := json.Unmarshal(raw, &tool)
+		return tool, err
+	}
+	if head.Function == nil || head.Function.Name == "" {
+		return tool, fmt.Errorf("function tool without a function name")
+	}
+	tool = schema.ResponsesToolParam{
+		Type:       "function",
+		Name:       head.Function.Name,
+		Parameters: head.Function.Parameters,
+		Strict:     head.Function.Strict,
+	}
+	if head.Function.Description != "" {
+		tool.Description = &head.Function.Description
+	}
+	return tool, nil
+}
+
+// toToolChoice converts {"type":"function","function":{"name":...}} to the
+// flat Responses API shape. String choices are the same in both APIs.
+func toToolChoice(choice interface{}) interface{} {
+	m, ok := choice.(map[string]interface{})
+	if !ok {
+		return choice
+	}
+	if fn, ok := m["function"].(map[string]interface{}); ok {
+		return map[string]interface{}{"type": "function", "name": fn["name"]}
+	}
+	return choice
+}
+
+// FromResponse converts a response to a chat completion. Each candidate is
+// a choice; function calls become tool calls of the choice they belong to.
+func FromResponse(resp *schema.Response) *api.ChatCompletionResponse {
+	out := &api.ChatCompletionResponse{
+		ID:          resp.ID,
+		Object:      "chat.completion",
+		Model:       resp.Model,
+		Created:     resp.CreatedAt,
+		Choices:     []api.ChatCompletionChoice{},
+		Usage:       fromUsage(resp.Usage),
+		ServiceTier: resp.ServiceTier,
+	}
+
+	choices := make(map[int]*api.ChatCompletionChoice)
+	var order []int
+	choice := func(item schema.ItemField) *api.ChatCompletionChoice {
+		index := 0
+		if item.CandidateIndex != nil {
+			index = *item.CandidateIndex
+		}
+		c, ok := choices[index]
+		if !ok {
+			c = &api.ChatCompletionChoice{Index: index, Message: api.ChatCompletionChoiceMsg{Role: "assistant"}}
+			choices[index] = c
+			order = append(order, index)
+		}
+		return c
+	}
+
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			c := choice(item)
+			var text strings.Builder
+			if c.Message.Content != nil {
+				text.WriteString(*c.Message.Content)
+			}
+			for _, part := range item.Content {
+				switch {
+				case part.Type == "output_text" && part.Text != nil:
+					text.WriteString(*part.Text)
+					if lp := fromLogprobs(part.Logprobs); lp != nil {
+						if c.Logprobs == nil {
+							c.Logprobs = &api.ChatCompletionLogprobs{}
+						}
+						c.Logprobs.Content = append(c.Logprobs.Content, lp.Content...)
+					}
+				case part.Type == "refusal" && part.Refusal != nil:
+					text.WriteString(*part.Refusal)
+				case part.Type == "output_audio":
+					c.Message.Audio = &api.ChatCompletionAudio{
+						Data:       deref(part.Data),
+						Transcript: deref(part.Transcript),
+					}
+				}
+			}
+			content := text.String()
+			c.Message.Content = &content
+		case "function_call":
+			c := choice(item)
+			c.Message.ToolCalls = append(c.Message.ToolCalls, api.ChatCompletionToolCall{
+				ID:   deref(item.CallID),
+				Type: "function",
+				Function: api.ChatCompletionToolCallFunction{
+					Name:      deref(item.Name),
+					Arguments: deref(item.Arguments),
+				},
+			})
+		}
+	}
+
+	if len(order) == 0 {
+		// No text and no tool call, e.g. a failed response
+		empty := ""
+		choices[0] = &api.ChatCompletionChoice{Message: api.ChatCompletionChoiceMsg{Role: "assistant", Content: &empty}}
+		order = append(order, 0)
+	}
+	for _, index := range order {
+		c := choices[index]
+		c.FinishReason = finishReason(resp, len(c.Message.ToolCalls) > 0)
+		out.Choices = append(out.Choices, *c)
+	}
+	return out
+}
+
+// finishReason returns the finish reason of a choice of resp.
+func finishReason(resp *schema.Response, toolCalls bool) string {
+	if resp.Status == "incomplete" && resp.IncompleteDetails != nil {
+		switch resp.IncompleteDetails.Reason {
+		case "max_output_tokens":
+			return "length"
+		case "content_filter":
+			return "content_filter"
+		}
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// fromUsage converts token usage, which may be nil.
+func fromUsage(u *schema.UsageField) *api.ChatCompletionUsage {
+	if u == nil {
+		return nil
+	}
+	usage := &api.ChatCompletionUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.InputTokensDetails.CachedTokens > 0 || u.InputTokensDetails.AudioTokens > 0 {
+		usage.PromptTokensDetails = &api.ChatPromptTokensDetails{
+			CachedTokens: u.InputTokensDetails.CachedTokens,
+			AudioTokens:  u.InputTokensDetails.AudioTokens,
+		}
+	}
+	if u.OutputTokensDetails.ReasoningTokens > 0 || u.OutputTokensDetails.AudioTokens > 0 {
+		usage.CompletionTokensDetails = &api.ChatCompletionTokensDetails{
+			ReasoningTokens: u.OutputTokensDetails.ReasoningTokens,
+			AudioTokens:     u.OutputTokensDetails.AudioTokens,
+		}
+	}
+	return usage
+}
+
+// fromLogprobs converts the logprobs of an output_text part, which have the
+// same shape as the Chat Completions token logprobs.
+func fromLogprobs(logprobs []interface{}) *api.ChatCompletionLogprobs {
+	if len(logprobs) == 0 {
+		return nil
+	}
+	var out api.ChatCompletionLogprobs
+	if err := remarshal(logprobs, &out.Content); err != nil {
+		return nil
+	}
+	return &out
+}
+
+// remarshal decodes a value decoded from JSON as interface{} into dst.
+func remarshal(v interface{}, dst interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
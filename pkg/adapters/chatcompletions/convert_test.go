@@ -0,0 +1,245 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package chatcompletions
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+func decodeRequest(t *testing.T, body string) *schema.ResponseRequest {
+	t.Helper()
+	var in Request
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	req, err := ToRequest(&in)
+	if err != nil {
+		t.Fatalf("ToRequest: %v", err)
+	}
+	return req
+}
+
+func TestToRequest(t *testing.T) {
+	req := decodeRequest(t, `{
+		"model": "m",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "what is this?"},
+				{"type": "image_url", "image_url": {"url": "https://x/cat.png", "detail": "low"}},
+				{"type": "input_audio", "input_audio": {"data": "UklGRg==", "format": "wav"}}
+			]},
+			{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "a cat"}
+		],
+		"tools": [
+			{"type": "function", "function": {"name": "lookup", "description": "Look up", "parameters": {"type": "object"}}},
+			{"type": "file_search", "vector_store_ids": ["vs_1"]}
+		],
+		"tool_choice": {"type": "function", "function": {"name": "lookup"}},
+		"max_tokens": 10,
+		"max_completion_tokens": 20,
+		"n": 2,
+		"logprobs": true,
+		"response_format": {"type": "json_object"},
+		"reasoning_effort": "low",
+		"conversation": "conv_1"
+	}`)
+
+	wantInput := []interface{}{
+		map[string]interface{}{"type": "message", "role": "system", "content": "be brief"},
+		map[string]interface{}{"type": "message", "role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "input_text", "text": "what is this?"},
+			map[string]interface{}{"type": "input_image", "image_url": "https://x/cat.png", "detail": "low"},
+			map[string]interface{}{"type": "input_audio", "input_audio": map[string]interface{}{"data": "UklGRg==", "format": "wav"}},
+		}},
+		map[string]interface{}{"type": "function_call", "call_id": "call_1", "name": "lookup", "arguments": "{}"},
+		map[string]interface{}{"type": "function_call_output", "call_id": "call_1", "output": "a cat"},
+	}
+	if !reflect.DeepEqual(req.Input, wantInput) {
+		t.Errorf("Input = %#v", req.Input)
+	}
+	if len(req.Tools) != 2 || req.Tools[0].Name != "lookup" || *req.Tools[0].Description != "Look up" ||
+		req.Tools[1].Type != "file_search" || req.Tools[1].VectorStoreIDs[0] != "vs_1" {
+		t.Errorf("Tools = %+v", req.Tools)
+	}
+	if want := map[string]interface{}{"type": "function", "name": "lookup"}; !reflect.DeepEqual(req.ToolChoice, want) {
+		t.Errorf("ToolChoice = %v", req.ToolChoice)
+	}
+	if *req.Model != "m" || *req.MaxOutputTokens != 20 || *req.CandidateCount != 2 || *req.Conversation != "conv_1" {
+		t.Errorf("parameters not mapped: %+v", req)
+	}
+	if req.Text.Format.Type != "json_object" || *req.Reasoning.Effort != "low" {
+		t.Errorf("Text = %+v, Reasoning = %+v", req.Text, req.Reasoning)
+	}
+	if !reflect.DeepEqual(req.Include, []string{"message.output_text.logprobs"}) {
+		t.Errorf("Include = %v", req.Include)
+	}
+}
+
+func TestToRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no messages", `{"model": "m", "messages": []}`},
+		{"unknown role", `{"messages": [{"role": "narrator", "content": "hi"}]}`},
+		{"unknown part", `{"messages": [{"role": "user", "content": [{"type": "audio"}]}]}`},
+		{"function without name", `{"messages": [{"role": "user", "content": "hi"}], "tools": [{"type": "function", "function": {}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in Request
+			if err := json.Unmarshal([]byte(tt.body), &in); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if _, err := ToRequest(&in); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func TestFromResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		resp schema.Response
+		want []api.ChatCompletionChoice
+	}{
+		{
+			name: "text",
+			resp: schema.Response{Status: "completed", Output: []schema.ItemField{
+				{Type: "reasoning"},
+				{Type: "message", Content: []schema.ContentPart{{Type: "output_text", Text: strPtr("hello")}}},
+			}},
+			want: []api.ChatCompletionChoice{
+				{Message: api.ChatCompletionChoiceMsg{Role: "assistant", Content: strPtr("hello")}, FinishReason: "stop"},
+			},
+		},
+		{
+			name: "tool calls",
+			resp: schema.Response{Status: "completed", Output: []schema.ItemField{
+				{Type: "function_call", CallID: strPtr("call_1"), Name: strPtr("lookup"), Arguments: strPtr(`{"q":1}`)},
+			}},
+			want: []api.ChatCompletionChoice{
+				{Message: api.ChatCompletionChoiceMsg{Role: "assistant", ToolCalls: []api.ChatCompletionToolCall{
+					{ID: "call_1", Type: "function", Function: api.ChatCompletionToolCallFunction{Name: "lookup", Arguments: `{"q":1}`}},
+				}}, FinishReason: "tool_calls"},
+			},
+		},
+		{
+			name: "candidates cut off",
+			resp: schema.Response{
+				Status:            "incomplete",
+				IncompleteDetails: &schema.IncompleteDetailsField{Reason: "max_output_tokens"},
+				Output: []schema.ItemField{
+					{Type: "message", CandidateIndex: intPtr(0), Content: []schema.ContentPart{{Type: "output_text", Text: strPtr("a")}}},
+					{Type: "message", CandidateIndex: intPtr(1), Content: []schema.ContentPart{{Type: "output_text", Text: strPtr("b")}}},
+				},
+			},
+			want: []api.ChatCompletionChoice{
+				{Index: 0, Message: api.ChatCompletionChoiceMsg{Role: "assistant", Content: strPtr("a")}, FinishReason: "length"},
+				{Index: 1, Message: api.ChatCompletionChoiceMsg{Role: "assistant", Content: strPtr("b")}, FinishReason: "length"},
+			},
+		},
+		{
+			name: "audio",
+			resp: schema.Response{Status: "completed", Output: []schema.ItemField{
+				{Type: "message", Content: []schema.ContentPart{{Type: "output_audio", Data: strPtr("UklGRg=="), Transcript: strPtr("hello")}}},
+			}},
+			want: []api.ChatCompletionChoice{
+				{Message: api.ChatCompletionChoiceMsg{
+					Role: "assistant", Content: strPtr(""),
+					Audio: &api.ChatCompletionAudio{Data: "UklGRg==", Transcript: "hello"},
+				}, FinishReason: "stop"},
+			},
+		},
+		{
+			name: "no output",
+			resp: schema.Response{Status: "completed"},
+			want: []api.ChatCompletionChoice{
+				{Message: api.ChatCompletionChoiceMsg{Role: "assistant", Content: strPtr("")}, FinishReason: "stop"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.resp.ID = "resp_1"
+			got := FromResponse(&tt.resp)
+			if got.ID != "resp_1" || got.Object != "chat.completion" {
+				t.Errorf("ID = %q, Object = %q", got.ID, got.Object)
+			}
+			if !reflect.DeepEqual(got.Choices, tt.want) {
+				gotJSON, _ := json.Marshal(got.Choices)
+				t.Errorf("Choices = %s", gotJSON)
+			}
+		})
+	}
+}
+
+func TestStreamConverter(t *testing.T) {
+	callID, name := "call_1", "lookup"
+	resp := schema.Response{ID: "resp_1", Model: "m", Status: "completed",
+		Usage: &schema.UsageField{InputTokens: 3, OutputTokens: 2, TotalTokens: 5}}
+	events := []interface{}{
+		&schema.ResponseCreatedStreamingEvent{Type: "response.created", Response: resp},
+		&schema.ResponseOutputItemAddedStreamingEvent{Type: "response.output_item.added", OutputIndex: 0,
+			Item: schema.ItemField{Type: "message"}},
+		&schema.ResponseOutputTextDeltaStreamingEvent{Type: "response.output_text.delta", OutputIndex: 0, Delta: "hi"},
+		&schema.ResponseOutputItemAddedStreamingEvent{Type: "response.output_item.added", OutputIndex: 1,
+			Item: schema.ItemField{Type: "function_call", CallID: &callID, Name: &name}},
+		&schema.ResponseFunctionCallArgumentsDeltaStreamingEvent{Type: "response.function_call_arguments.delta", OutputIndex: 1, Delta: "{}"},
+		&schema.ResponseCompletedStreamingEvent{Type: "response.completed", Response: resp},
+	}
+
+	conv := NewStreamConverter(true)
+	var got []string
+	for _, event := range events {
+		chunks, errField := conv.Convert(event)
+		if errField != nil {
+			t.Fatalf("unexpected error %+v", errField)
+		}
+		for _, chunk := range chunks {
+			if chunk.ID != "resp_1" || chunk.Model != "m" || chunk.Object != "chat.completion.chunk" {
+				t.Errorf("chunk header = %+v", chunk)
+			}
+			data, _ := json.Marshal(struct {
+				Choices []api.ChatCompletionChunkChoice `json:"choices"`
+				Usage   *api.ChatCompletionUsage        `json:"usage,omitempty"`
+			}{chunk.Choices, chunk.Usage})
+			got = append(got, string(data))
+		}
+	}
+
+	want := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"hi"}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chunks =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestStreamConverter_Failed(t *testing.T) {
+	conv := NewStreamConverter(false)
+	chunks, errField := conv.Convert(&schema.ResponseFailedStreamingEvent{Type: "response.failed", Response: schema.Response{
+		Error: &schema.ErrorField{Type: "server_error", Message: "backend down"},
+	}})
+	if len(chunks) != 0 || errField == nil || errField.Message != "backend down" {
+		t.Errorf("Convert = %v, %+v", chunks, errField)
+	}
+}
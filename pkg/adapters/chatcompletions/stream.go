@@ -0,0 +1,177 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package chatcompletions
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// StreamConverter converts the streaming events of a response to chat
+// completion chunks. Events are decoded from their JSON form, so events
+// forwarded as-is from the backend are converted too.
+type StreamConverter struct {
+	includeUsage bool
+
+	id      string
+	model   string
+	created int64
+
+	// Choice and tool call index of each output item
+	choices   map[int]int
+	toolCalls map[int]int
+	// Tool calls per choice, and choices that sent their first chunk
+	calls   map[int]int
+	started map[int]bool
+}
+
+// NewStreamConverter creates a converter for a request. includeUsage adds
+// a last chunk with the token usage and no choices.
+func NewStreamConverter(includeUsage bool) *StreamConverter {
+	return &StreamConverter{
+		includeUsage: includeUsage,
+		choices:      make(map[int]int),
+		toolCalls:    make(map[int]int),
+		calls:        make(map[int]int),
+		started:      make(map[int]bool),
+	}
+}
+
+// streamEvent holds the fields of the events the converter uses.
+type streamEvent struct {
+	Type        string             `json:"type"`
+	OutputIndex int                `json:"output_index"`
+	Delta       string             `json:"delta"`
+	Item        *schema.ItemField  `json:"item"`
+	Response    *schema.Response   `json:"response"`
+	Error       *schema.ErrorField `json:"error"`
+}
+
+// Convert returns the chunks for event. It returns an error field when the
+// response failed; the stream ends after it.
+func (c *StreamConverter) Convert(event interface{}) ([]*api.ChatCompletionChunk, *schema.ErrorField) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil
+	}
+	var ev streamEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, nil
+	}
+
+	switch ev.Type {
+	case "response.created":
+		if ev.Response != nil {
+			c.id, c.model, c.created = ev.Response.ID, ev.Response.Model, ev.Response.CreatedAt
+		}
+	case "response.output_item.added":
+		if ev.Item == nil {
+			return nil, nil
+		}
+		index := 0
+		if ev.Item.CandidateIndex != nil {
+			index = *ev.Item.CandidateIndex
+		}
+		c.choices[ev.OutputIndex] = index
+		if ev.Item.Type != "function_call" {
+			return nil, nil
+		}
+		call := c.calls[index]
+		c.calls[index]++
+		c.toolCalls[ev.OutputIndex] = call
+		return c.delta(index, api.ChatCompletionChunkDelta{ToolCalls: []api.ChatCompletionToolCall{{
+			Index: &call,
+			ID:    deref(ev.Item.CallID),
+			Type:  "function",
+			Function: api.ChatCompletionToolCallFunction{
+				Name: deref(ev.Item.Name),
+			},
+		}}}), nil
+	case "response.output_text.delta", "response.refusal.delta":
+		if ev.Delta == "" {
+			return nil, nil
+		}
+		return c.delta(c.choices[ev.OutputIndex], api.ChatCompletionChunkDelta{Content: &ev.Delta}), nil
+	case "response.function_call_arguments.delta":
+		call, ok := c.toolCalls[ev.OutputIndex]
+		if !ok || ev.Delta == "" {
+			return nil, nil
+		}
+		return c.delta(c.choices[ev.OutputIndex], api.ChatCompletionChunkDelta{ToolCalls: []api.ChatCompletionToolCall{{
+			Index:    &call,
+			Function: api.ChatCompletionToolCallFunction{Arguments: ev.Delta},
+		}}}), nil
+	case "response.completed", "response.incomplete":
+		return c.finish(ev.Response), nil
+	case "response.failed":
+		if ev.Response != nil && ev.Response.Error != nil {
+			return nil, ev.Response.Error
+		}
+		return nil, &schema.ErrorField{Type: "server_error", Message: "response failed"}
+	case "error":
+		if ev.Error != nil {
+			return nil, ev.Error
+		}
+		return nil, &schema.ErrorField{Type: "server_error", Message: "response failed"}
+	}
+	return nil, nil
+}
+
+// delta returns the chunks carrying delta for a choice, preceded by the
+// chunk with the assistant role if it is the first of the choice.
+func (c *StreamConverter) delta(index int, delta api.ChatCompletionChunkDelta) []*api.ChatCompletionChunk {
+	var chunks []*api.ChatCompletionChunk
+	if !c.started[index] {
+		c.started[index] = true
+		empty := ""
+		chunks = append(chunks, c.chunk(api.ChatCompletionChunkChoice{
+			Index: index,
+			Delta: api.ChatCompletionChunkDelta{Role: "assistant", Content: &empty},
+		}))
+	}
+	return append(chunks, c.chunk(api.ChatCompletionChunkChoice{Index: index, Delta: delta}))
+}
+
+// finish returns the chunks with the finish reason of each choice, and the
+// usage chunk if it was requested.
+func (c *StreamConverter) finish(resp *schema.Response) []*api.ChatCompletionChunk {
+	if resp == nil {
+		resp = &schema.Response{Status: "completed"}
+	}
+	var chunks []*api.ChatCompletionChunk
+	if len(c.started) == 0 {
+		// No output: send the role so the client still sees a message
+		chunks = c.delta(0, api.ChatCompletionChunkDelta{})[:1]
+	}
+	for _, index := range slices.Sorted(maps.Keys(c.started)) {
+		reason := finishReason(resp, c.calls[index] > 0)
+		chunks = append(chunks, c.chunk(api.ChatCompletionChunkChoice{Index: index, FinishReason: &reason}))
+	}
+	if c.includeUsage {
+		chunk := c.chunk()
+		chunk.Usage = fromUsage(resp.Usage)
+		if chunk.Usage == nil {
+			chunk.Usage = &api.ChatCompletionUsage{}
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func (c *StreamConverter) chunk(choices ...api.ChatCompletionChunkChoice) *api.ChatCompletionChunk {
+	if choices == nil {
+		choices = []api.ChatCompletionChunkChoice{}
+	}
+	return &api.ChatCompletionChunk{
+		ID:      c.id,
+		Object:  "chat.completion.chunk",
+		Model:   c.model,
+		Created: c.created,
+		Choices: choices,
+	}
+}
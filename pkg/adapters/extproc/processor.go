@@ -6,12 +6,15 @@ package extproc
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	filterv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_proc/v3"
 	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
 )
@@ -22,11 +25,33 @@ import (
 type Processor struct {
 	extprocv3.UnimplementedExternalProcessorServer
 	handler http.Handler
+	opts    Options
+}
+
+// Options configures a Processor.
+type Options struct {
+	// StreamRequestBody passes request bodies to the handler as Envoy
+	// streams them, instead of asking Envoy to buffer them first. Envoy's
+	// ext_proc filter must then use request_body_mode FULL_DUPLEX_STREAMED
+	// and request_trailer_mode SEND.
+	StreamRequestBody bool
+
+	// Passthrough, when set, turns POST /v1/responses into a routing step:
+	// instead of answering, the processor sends it to Passthrough and has
+	// Envoy forward the request it returns to the upstream cluster, with
+	// its path, headers and body. Passthrough returns nil after writing an
+	// error to w, which is sent back to the client. Other routes are still
+	// answered by the handler.
+	Passthrough func(w http.ResponseWriter, r *http.Request) *http.Request
+
+	// TLS, when set, has the server accept Envoy's connections over TLS
+	// with this configuration.
+	TLS *tls.Config
 }
 
 // NewProcessor creates a new ExtProc processor that delegates to the given handler.
-func NewProcessor(handler http.Handler) *Processor {
-	return &Processor{handler: handler}
+func NewProcessor(handler http.Handler, opts Options) *Processor {
+	return &Processor{handler: handler, opts: opts}
 }
 
 // Process handles the bidirectional gRPC stream from Envoy.
@@ -50,11 +75,19 @@ func (p *Processor) Process(stream extprocv3.ExternalProcessor_ProcessServer) er
 				return p.handle(stream, reqHeaders, nil)
 			}
 
+			// Forwarded bodies are rewritten as a whole, so Envoy buffers them
+			if p.opts.StreamRequestBody && !p.passthrough(reqHeaders) {
+				return p.handleStreamed(stream, reqHeaders)
+			}
+
 			if err := stream.Send(requestBodyBuffered()); err != nil {
 				return fmt.Errorf("requesting body: %w", err)
 			}
 
 		case *extprocv3.ProcessingRequest_RequestBody:
+			if p.passthrough(reqHeaders) {
+				return p.forward(stream, reqHeaders, v.RequestBody.GetBody())
+			}
 			return p.handle(stream, reqHeaders, v.RequestBody.GetBody())
 
 		default:
@@ -76,6 +109,105 @@ func (p *Processor) handle(stream extprocv3.ExternalProcessor_ProcessServer, hea
 	return w.finish()
 }
 
+// passthrough reports whether a request is forwarded upstream rather than
+// answered by the handler.
+func (p *Processor) passthrough(headers *extprocv3.HttpHeaders) bool {
+	if p.opts.Passthrough == nil {
+		return false
+	}
+	var method, path string
+	for _, h := range headers.GetHeaders().GetHeaders() {
+		switch h.Key {
+		case ":method":
+			method = headerValue(h)
+		case ":path":
+			path, _, _ = strings.Cut(headerValue(h), "?")
+		}
+	}
+	return method == http.MethodPost && (path == "/v1/responses" || path == "/responses")
+}
+
+// forward rewrites a request with the Passthrough function and lets Envoy
+// send the result upstream. If Passthrough refuses the request, its error
+// response is sent to the client instead.
+func (p *Processor) forward(stream extprocv3.ExternalProcessor_ProcessServer, headers *extprocv3.HttpHeaders, body []byte) error {
+	httpReq, err := buildHTTPRequest(stream.Context(), headers, body)
+	if err != nil {
+		return stream.Send(errorResponse(400, "invalid_request", err.Error()))
+	}
+
+	w := newResponseWriter(stream)
+	upstream := p.opts.Passthrough(w, httpReq)
+	if upstream == nil {
+		return w.finish()
+	}
+
+	upstreamBody, err := io.ReadAll(upstream.Body)
+	if err != nil {
+		return stream.Send(errorResponse(500, "processing_error", err.Error()))
+	}
+	return stream.Send(replaceRequestMsg(upstream.URL.RequestURI(), upstream.Header, upstreamBody))
+}
+
+// handleStreamed runs the handler as soon as the request headers arrive,
+// feeding it the body chunks Envoy streams. Writing a chunk blocks until the
+// handler reads it, so a slow handler slows Envoy, and through it the
+// client, instead of the body piling up in memory.
+func (p *Processor) handleStreamed(stream extprocv3.ExternalProcessor_ProcessServer, headers *extprocv3.HttpHeaders) error {
+	httpReq, err := buildHTTPRequest(stream.Context(), headers, nil)
+	if err != nil {
+		return stream.Send(errorResponse(400, "invalid_request", err.Error()))
+	}
+	body, bodyWriter := io.Pipe()
+	httpReq.Body = body
+	httpReq.ContentLength = -1
+	if n, err := strconv.ParseInt(httpReq.Header.Get("Content-Length"), 10, 64); err == nil {
+		httpReq.ContentLength = n
+	}
+	httpReq.Trailer = make(http.Header)
+
+	go receiveBody(stream, bodyWriter, httpReq.Trailer)
+
+	w := newResponseWriter(stream)
+	p.handler.ServeHTTP(w, httpReq)
+	// Unblock receiveBody if the handler did not read the whole body
+	body.Close()
+	return w.finish()
+}
+
+// receiveBody writes the request body chunks received from Envoy to w until
+// the body ends, with end_of_stream or with trailers, which are added to
+// trailer.
+func receiveBody(stream extprocv3.ExternalProcessor_ProcessServer, w *io.PipeWriter, trailer http.Header) {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			w.CloseWithError(io.ErrUnexpectedEOF)
+			return
+		}
+
+		switch v := req.Request.(type) {
+		case *extprocv3.ProcessingRequest_RequestBody:
+			if len(v.RequestBody.GetBody()) > 0 {
+				if _, err := w.Write(v.RequestBody.GetBody()); err != nil {
+					return // the handler is done with the body
+				}
+			}
+			if v.RequestBody.GetEndOfStream() {
+				w.Close()
+				return
+			}
+
+		case *extprocv3.ProcessingRequest_RequestTrailers:
+			for _, h := range v.RequestTrailers.GetTrailers().GetHeaders() {
+				trailer.Add(h.Key, headerValue(h))
+			}
+			w.Close()
+			return
+		}
+	}
+}
+
 // buildHTTPRequest reconstructs an http.Request from ExtProc headers and body.
 func buildHTTPRequest(ctx context.Context, headers *extprocv3.HttpHeaders, body []byte) (*http.Request, error) {
 	var method, path, authority string
@@ -83,10 +215,7 @@ func buildHTTPRequest(ctx context.Context, headers *extprocv3.HttpHeaders, body
 
 	if headers != nil && headers.Headers != nil {
 		for _, h := range headers.Headers.Headers {
-			val := string(h.RawValue)
-			if val == "" {
-				val = h.Value
-			}
+			val := headerValue(h)
 			switch h.Key {
 			case ":method":
 				method = val
@@ -133,6 +262,15 @@ func buildHTTPRequest(ctx context.Context, headers *extprocv3.HttpHeaders, body
 	return req, nil
 }
 
+// headerValue returns the value of an Envoy header, which is set in either
+// RawValue or Value.
+func headerValue(h *corev3.HeaderValue) string {
+	if len(h.RawValue) > 0 {
+		return string(h.RawValue)
+	}
+	return h.Value
+}
+
 // requestBodyBuffered tells Envoy to buffer the full request body and send it.
 func requestBodyBuffered() *extprocv3.ProcessingResponse {
 	return &extprocv3.ProcessingResponse{
@@ -145,15 +283,26 @@ func requestBodyBuffered() *extprocv3.ProcessingResponse {
 	}
 }
 
+// immediateBodyLimit is the largest body sent as a single ImmediateResponse.
+// Larger bodies, such as file downloads, are streamed in chunks of about
+// this size instead of being held in memory and in Envoy's buffers.
+const immediateBodyLimit = 64 << 10
+
 // responseWriter adapts http.ResponseWriter to ExtProc responses.
 // For SSE streaming (Content-Type: text/event-stream), it sends headers via
-// StreamedImmediateResponse and pipes each Flush() as a body chunk.
-// For all other responses, it buffers and sends an ImmediateResponse.
+// StreamedImmediateResponse and pipes each Flush() as a body chunk. Other
+// responses are buffered and sent as an ImmediateResponse, unless they are
+// flushed, exceed immediateBodyLimit or have trailers, in which case they
+// are streamed too.
+//
+// Sends block while Envoy's flow-control window is full, so a slow client
+// slows the handler down rather than the response piling up in memory.
 type responseWriter struct {
 	stream      extprocv3.ExternalProcessor_ProcessServer
 	header      http.Header
 	status      int
 	isSSE       bool
+	streaming   bool // headers sent with StreamedImmediateResponse
 	wroteHeader bool
 	buf         bytes.Buffer
 	sendErr     error
@@ -177,13 +326,10 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	}
 	w.wroteHeader = true
 	w.status = statusCode
-	w.isSSE = w.header.Get("Content-Type") == "text/event-stream"
+	w.isSSE = strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
 
 	if w.isSSE {
-		hdrs := w.headerMap()
-		if err := w.stream.Send(streamHeadersMsg(statusCode, hdrs)); err != nil {
-			w.sendErr = err
-		}
+		w.startStream()
 	}
 }
 
@@ -194,46 +340,111 @@ func (w *responseWriter) Write(data []byte) (int, error) {
 	if !w.wroteHeader {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.buf.Write(data)
+	n, _ := w.buf.Write(data)
+	if w.buf.Len() > immediateBodyLimit {
+		w.Flush()
+	}
+	return n, w.sendErr
 }
 
-// Flush sends buffered SSE data as a StreamedImmediateResponse body chunk.
+// Flush sends buffered data as a StreamedImmediateResponse body chunk.
 func (w *responseWriter) Flush() {
-	if w.sendErr != nil || !w.isSSE || w.buf.Len() == 0 {
-		return
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
 	}
-	if err := w.stream.Send(streamBodyMsg(w.buf.Bytes(), false)); err != nil {
-		w.sendErr = err
+	w.startStream()
+	if w.sendErr != nil || w.buf.Len() == 0 {
+		return
 	}
+	w.send(streamBodyMsg(w.buf.Bytes(), false))
 	w.buf.Reset()
 }
 
-// finish completes the ExtProc response. For SSE, sends end_of_stream.
-// For non-SSE, sends the buffered body as an ImmediateResponse.
+// startStream sends the headers as a StreamedImmediateResponse, once.
+func (w *responseWriter) startStream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	w.send(streamHeadersMsg(w.status, w.headerMap()))
+}
+
+func (w *responseWriter) send(msg *extprocv3.ProcessingResponse) {
+	if w.sendErr != nil {
+		return
+	}
+	w.sendErr = w.stream.Send(msg)
+}
+
+// finish completes the ExtProc response. Streamed responses end with
+// end_of_stream, or with their trailers. Others are sent as an
+// ImmediateResponse.
 func (w *responseWriter) finish() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	trailers := w.trailerMap()
+	if len(trailers) > 0 {
+		// ImmediateResponse has no trailers
+		w.startStream()
+	}
 	if w.sendErr != nil {
 		return w.sendErr
 	}
 
-	if w.isSSE {
-		if w.buf.Len() > 0 {
-			if err := w.stream.Send(streamBodyMsg(w.buf.Bytes(), false)); err != nil {
-				return err
-			}
-		}
-		return w.stream.Send(streamBodyMsg(nil, true))
+	if !w.streaming {
+		return w.stream.Send(immediateResponseMsg(w.status, w.headerMap(), w.buf.Bytes()))
 	}
 
-	if !w.wroteHeader {
-		w.WriteHeader(http.StatusOK)
+	if len(trailers) == 0 {
+		w.send(streamBodyMsg(w.buf.Bytes(), true))
+		return w.sendErr
+	}
+	if w.buf.Len() > 0 {
+		w.send(streamBodyMsg(w.buf.Bytes(), false))
 	}
-	return w.stream.Send(immediateResponseMsg(w.status, w.headerMap(), w.buf.Bytes()))
+	w.send(streamTrailersMsg(trailers))
+	return w.sendErr
 }
 
+// headerMap returns the response headers, without trailers.
 func (w *responseWriter) headerMap() map[string]string {
+	declared := w.declaredTrailers()
 	hdrs := make(map[string]string, len(w.header))
 	for k := range w.header {
+		if k == "Trailer" || declared[k] || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
 		hdrs[strings.ToLower(k)] = w.header.Get(k)
 	}
 	return hdrs
 }
+
+// trailerMap returns the trailers set by the handler: headers declared in
+// the Trailer header, and headers prefixed with http.TrailerPrefix.
+func (w *responseWriter) trailerMap() map[string]string {
+	trailers := make(map[string]string)
+	for k := range w.header {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			trailers[strings.ToLower(name)] = w.header.Get(k)
+		}
+	}
+	for k := range w.declaredTrailers() {
+		if v := w.header.Get(k); v != "" {
+			trailers[strings.ToLower(k)] = v
+		}
+	}
+	return trailers
+}
+
+func (w *responseWriter) declaredTrailers() map[string]bool {
+	declared := make(map[string]bool)
+	for _, v := range w.header.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				declared[http.CanonicalHeaderKey(k)] = true
+			}
+		}
+	}
+	return declared
+}
@@ -112,7 +112,7 @@ func testHandler() http.Handler {
 }
 
 func TestProcess_GET_ImmediateResponse(t *testing.T) {
-	p := NewProcessor(testHandler())
+	p := NewProcessor(testHandler(), Options{})
 	stream := newMockStream(context.Background(),
 		makeHeaders("/health", "GET", true),
 	)
@@ -138,7 +138,7 @@ func TestProcess_GET_ImmediateResponse(t *testing.T) {
 }
 
 func TestProcess_POST_NonStreaming_ImmediateResponse(t *testing.T) {
-	p := NewProcessor(testHandler())
+	p := NewProcessor(testHandler(), Options{})
 	stream := newMockStream(context.Background(),
 		makeHeaders("/v1/responses", "POST", false),
 		makeBody(`{"model":"test","input":"hi"}`),
@@ -170,7 +170,7 @@ func TestProcess_POST_NonStreaming_ImmediateResponse(t *testing.T) {
 }
 
 func TestProcess_POST_Streaming_StreamedImmediateResponse(t *testing.T) {
-	p := NewProcessor(testHandler())
+	p := NewProcessor(testHandler(), Options{})
 	stream := newMockStream(context.Background(),
 		makeHeaders("/v1/responses", "POST", false),
 		makeBody(`{"model":"test","input":"hi","stream":true}`),
@@ -230,7 +230,7 @@ func TestProcess_POST_Streaming_StreamedImmediateResponse(t *testing.T) {
 }
 
 func TestProcess_404_ImmediateResponse(t *testing.T) {
-	p := NewProcessor(testHandler())
+	p := NewProcessor(testHandler(), Options{})
 	stream := newMockStream(context.Background(),
 		makeHeaders("/nonexistent", "GET", true),
 	)
@@ -259,7 +259,7 @@ func TestProcess_HeadersPreserved(t *testing.T) {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
-	}))
+	}), Options{})
 
 	stream := newMockStream(context.Background(),
 		&extprocv3.ProcessingRequest{
@@ -290,3 +290,230 @@ func TestProcess_HeadersPreserved(t *testing.T) {
 		t.Fatalf("expected 200 (auth passed), got %d", imm.Status.Code)
 	}
 }
+
+func makeBodyChunk(body string, endOfStream bool) *extprocv3.ProcessingRequest {
+	return &extprocv3.ProcessingRequest{
+		Request: &extprocv3.ProcessingRequest_RequestBody{
+			RequestBody: &extprocv3.HttpBody{
+				Body:        []byte(body),
+				EndOfStream: endOfStream,
+			},
+		},
+	}
+}
+
+func makeTrailers(key, value string) *extprocv3.ProcessingRequest {
+	return &extprocv3.ProcessingRequest{
+		Request: &extprocv3.ProcessingRequest_RequestTrailers{
+			RequestTrailers: &extprocv3.HttpTrailers{
+				Trailers: &corev3.HeaderMap{
+					Headers: []*corev3.HeaderValue{{Key: key, RawValue: []byte(value)}},
+				},
+			},
+		},
+	}
+}
+
+func TestProcess_StreamRequestBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		requests    []*extprocv3.ProcessingRequest
+		wantBody    string
+		wantTrailer string
+	}{
+		{
+			name: "end of stream",
+			requests: []*extprocv3.ProcessingRequest{
+				makeHeaders("/echo", "POST", false),
+				makeBodyChunk(`{"input":`, false),
+				makeBodyChunk(`"hi"}`, true),
+			},
+			wantBody: `{"input":"hi"}`,
+		},
+		{
+			name: "trailers",
+			requests: []*extprocv3.ProcessingRequest{
+				makeHeaders("/echo", "POST", false),
+				makeBodyChunk(`{"input":"hi"}`, false),
+				makeTrailers("x-checksum", "abc"),
+			},
+			wantBody:    `{"input":"hi"}`,
+			wantTrailer: "abc",
+		},
+		{
+			name: "truncated",
+			requests: []*extprocv3.ProcessingRequest{
+				makeHeaders("/echo", "POST", false),
+				makeBodyChunk(`{"input":`, false),
+			},
+			wantBody: "error: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, err := io.ReadAll(r.Body)
+				if err != nil {
+					fmt.Fprintf(w, "error: %v", err)
+					return
+				}
+				w.Header().Set("X-Trailer", r.Trailer.Get("X-Checksum"))
+				w.Write(data)
+			}), Options{StreamRequestBody: true})
+			stream := newMockStream(context.Background(), tt.requests...)
+
+			if err := p.Process(stream); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(stream.responses) != 1 {
+				t.Fatalf("expected only the ImmediateResponse, got %d responses", len(stream.responses))
+			}
+			imm := stream.responses[0].GetImmediateResponse()
+			if imm == nil {
+				t.Fatal("expected ImmediateResponse")
+			}
+			if string(imm.Body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", imm.Body, tt.wantBody)
+			}
+			for _, h := range imm.GetHeaders().GetSetHeaders() {
+				if h.Header.Key == "x-trailer" && string(h.Header.RawValue) != tt.wantTrailer {
+					t.Errorf("request trailer = %q, want %q", h.Header.RawValue, tt.wantTrailer)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess_LargeResponseStreamed(t *testing.T) {
+	large := strings.Repeat("x", 3*immediateBodyLimit)
+	p := NewProcessor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		for i := 0; i < len(large); i += 1024 {
+			io.WriteString(w, large[i:i+1024])
+		}
+	}), Options{})
+	stream := newMockStream(context.Background(), makeHeaders("/files/f/content", "GET", true))
+
+	if err := p.Process(stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stream.responses[0].GetStreamedImmediateResponse().GetHeadersResponse() == nil {
+		t.Fatal("expected streamed headers first")
+	}
+
+	var body strings.Builder
+	for _, resp := range stream.responses[1:] {
+		br := resp.GetStreamedImmediateResponse().GetBodyResponse()
+		if br == nil {
+			t.Fatalf("expected body chunk, got %v", resp)
+		}
+		if len(br.Body) > immediateBodyLimit+1024 {
+			t.Errorf("chunk of %d bytes exceeds the limit", len(br.Body))
+		}
+		body.Write(br.Body)
+	}
+	if body.Len() != len(large) {
+		t.Errorf("streamed %d bytes, want %d", body.Len(), len(large))
+	}
+	if last := stream.responses[len(stream.responses)-1]; !last.GetStreamedImmediateResponse().GetBodyResponse().GetEndOfStream() {
+		t.Error("expected end_of_stream on the last chunk")
+	}
+}
+
+func TestProcess_ResponseTrailers(t *testing.T) {
+	p := NewProcessor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Trailer", "X-Status")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "data: {}\n\n")
+		w.(http.Flusher).Flush()
+		w.Header().Set("X-Status", "done")
+	}), Options{})
+	stream := newMockStream(context.Background(), makeHeaders("/v1/responses", "GET", true))
+
+	if err := p.Process(stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, h := range stream.responses[0].GetStreamedImmediateResponse().GetHeadersResponse().GetHeaders().GetHeaders() {
+		if h.Key == "trailer" || h.Key == "x-status" {
+			t.Errorf("trailer sent as header %q", h.Key)
+		}
+	}
+	last := stream.responses[len(stream.responses)-1].GetStreamedImmediateResponse().GetTrailersResponse()
+	if last == nil || len(last.Headers) != 1 || last.Headers[0].Key != "x-status" || string(last.Headers[0].RawValue) != "done" {
+		t.Fatalf("expected x-status trailer to end the stream, got %v", last)
+	}
+}
+
+func TestProcess_Passthrough(t *testing.T) {
+	passthrough := func(w http.ResponseWriter, r *http.Request) *http.Request {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"type":"invalid_request","message":"model is required"}}`)
+			return nil
+		}
+		upstream, _ := http.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"test","messages":[]}`))
+		upstream.Header.Set("Authorization", "Bearer sk-test")
+		return upstream
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int // 0 when the request is forwarded
+	}{
+		{"forwarded", "/v1/responses", `{"model":"test","input":"hi"}`, 0},
+		{"refused", "/v1/responses", `{"input":"hi"}`, 400},
+		{"other routes answered", "/v1/conversations", `{}`, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(testHandler(), Options{StreamRequestBody: true, Passthrough: passthrough})
+			stream := newMockStream(context.Background(),
+				makeHeaders(tt.path, "POST", false),
+				makeBody(tt.body),
+			)
+			if err := p.Process(stream); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantStatus != 0 {
+				last := stream.responses[len(stream.responses)-1].GetImmediateResponse()
+				if last == nil || int(last.Status.Code) != tt.wantStatus {
+					t.Fatalf("expected ImmediateResponse with status %d, got %v", tt.wantStatus, stream.responses)
+				}
+				return
+			}
+
+			if len(stream.responses) != 2 || stream.responses[0].ModeOverride == nil {
+				t.Fatalf("expected body buffering then a body response, got %v", stream.responses)
+			}
+			common := stream.responses[1].GetRequestBody().GetResponse()
+			if common == nil {
+				t.Fatal("expected RequestBody response")
+			}
+			if got := string(common.GetBodyMutation().GetBody()); got != `{"model":"test","messages":[]}` {
+				t.Errorf("body = %s", got)
+			}
+			headers := make(map[string]string)
+			for _, h := range common.GetHeaderMutation().GetSetHeaders() {
+				headers[h.Header.Key] = string(h.Header.RawValue)
+			}
+			want := map[string]string{
+				":path":          "/v1/chat/completions",
+				"authorization":  "Bearer sk-test",
+				"content-length": "30",
+			}
+			for k, v := range want {
+				if headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, headers[k], v)
+				}
+			}
+		})
+	}
+}
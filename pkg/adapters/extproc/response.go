@@ -4,12 +4,16 @@
 package extproc
 
 import (
-	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
 	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 )
 
 func makeHeader(key, value string) *corev3.HeaderValueOption {
@@ -83,16 +87,31 @@ func streamBodyMsg(data []byte, endOfStream bool) *extprocv3.ProcessingResponse
 	}
 }
 
-func errorResponse(statusCode int, errType, message string) *extprocv3.ProcessingResponse {
-	body, _ := json.Marshal(map[string]interface{}{
-		"error": map[string]string{
-			"type":    errType,
-			"message": message,
+func streamTrailersMsg(trailers map[string]string) *extprocv3.ProcessingResponse {
+	hdrs := make([]*corev3.HeaderValue, 0, len(trailers))
+	for k, v := range trailers {
+		hdrs = append(hdrs, &corev3.HeaderValue{
+			Key:      k,
+			RawValue: []byte(v),
+		})
+	}
+	return &extprocv3.ProcessingResponse{
+		Response: &extprocv3.ProcessingResponse_StreamedImmediateResponse{
+			StreamedImmediateResponse: &extprocv3.StreamedImmediateResponse{
+				Response: &extprocv3.StreamedImmediateResponse_TrailersResponse{
+					TrailersResponse: &corev3.HeaderMap{
+						Headers: hdrs,
+					},
+				},
+			},
 		},
-	})
+	}
+}
+
+func errorResponse(statusCode int, code, message string) *extprocv3.ProcessingResponse {
 	return immediateResponseMsg(statusCode, map[string]string{
 		"content-type": "application/json",
-	}, body)
+	}, apierror.New(statusCode, code, message).Body())
 }
 
 func passthroughResponse() *extprocv3.ProcessingResponse {
@@ -102,3 +121,31 @@ func passthroughResponse() *extprocv3.ProcessingResponse {
 		},
 	}
 }
+
+// replaceRequestMsg answers a buffered request body by replacing the
+// request's path, headers and body, and letting Envoy route it upstream.
+func replaceRequestMsg(path string, headers http.Header, body []byte) *extprocv3.ProcessingResponse {
+	hdrs := []*corev3.HeaderValueOption{
+		makeHeader(":path", path),
+		makeHeader("content-length", strconv.Itoa(len(body))),
+	}
+	for k := range headers {
+		hdrs = append(hdrs, makeHeader(strings.ToLower(k), headers.Get(k)))
+	}
+	return &extprocv3.ProcessingResponse{
+		Response: &extprocv3.ProcessingResponse_RequestBody{
+			RequestBody: &extprocv3.BodyResponse{
+				Response: &extprocv3.CommonResponse{
+					HeaderMutation: &extprocv3.HeaderMutation{
+						SetHeaders: hdrs,
+					},
+					BodyMutation: &extprocv3.BodyMutation{
+						Mutation: &extprocv3.BodyMutation_Body{Body: body},
+					},
+					// The new path may select another route
+					ClearRouteCache: true,
+				},
+			},
+		},
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	extprocv3 "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
@@ -25,9 +26,13 @@ type Server struct {
 
 // NewServer creates a new ExtProc gRPC server that delegates all
 // request handling to the given http.Handler.
-func NewServer(handler http.Handler, logger *logging.Logger) *Server {
-	gs := grpc.NewServer()
-	processor := NewProcessor(handler)
+func NewServer(handler http.Handler, logger *logging.Logger, opts Options) *Server {
+	var serverOpts []grpc.ServerOption
+	if opts.TLS != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(opts.TLS)))
+	}
+	gs := grpc.NewServer(serverOpts...)
+	processor := NewProcessor(handler, opts)
 	extprocv3.RegisterExternalProcessorServer(gs, processor)
 
 	healthSrv := health.NewServer()
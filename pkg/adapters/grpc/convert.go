@@ -0,0 +1,271 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/leseb/openresponses-gw/pkg/adapters/grpc/responsespb"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// toRequest converts a CreateResponseRequest to the engine's request type.
+func toRequest(in *responsespb.CreateResponseRequest) (*schema.ResponseRequest, error) {
+	req := &schema.ResponseRequest{
+		Model:              in.Model,
+		PreviousResponseID: in.PreviousResponseId,
+		Conversation:       in.Conversation,
+		Include:            in.Include,
+		Metadata:           in.Metadata,
+		Instructions:       in.Instructions,
+		Temperature:        in.Temperature,
+		TopP:               in.TopP,
+		MaxOutputTokens:    intPtr(in.MaxOutputTokens),
+		MaxToolCalls:       intPtr(in.MaxToolCalls),
+		FrequencyPenalty:   in.FrequencyPenalty,
+		PresencePenalty:    in.PresencePenalty,
+		Truncation:         in.Truncation,
+		ParallelToolCalls:  in.ParallelToolCalls,
+		TopLogprobs:        intPtr(in.TopLogprobs),
+		ServiceTier:        in.ServiceTier,
+		Store:              in.Store,
+		ExternalID:         in.ExternalId,
+		MaxDurationSeconds: intPtr(in.MaxDurationSeconds),
+		MaxBackendCalls:    intPtr(in.MaxBackendCalls),
+		MaxTotalTokens:     intPtr(in.MaxTotalTokens),
+		InstructionsMerge:  in.InstructionsMerge,
+	}
+	if in.Seed != nil {
+		seed := int(*in.Seed)
+		req.Seed = &seed
+	}
+	if in.Input != nil {
+		req.Input = in.Input.AsInterface()
+	}
+	if in.ToolChoice != nil {
+		req.ToolChoice = in.ToolChoice.AsInterface()
+	}
+	if in.Stop != nil {
+		req.Stop = in.Stop.AsInterface()
+	}
+
+	// Union types are decoded from their JSON shape, as over HTTP
+	for _, f := range []struct {
+		name string
+		msg  proto.Message
+		dst  any
+	}{
+		{"reasoning", in.Reasoning, &req.Reasoning},
+		{"text", in.Text, &req.Text},
+		{"prompt", in.Prompt, &req.Prompt},
+	} {
+		if err := decodeStruct(f.msg, f.dst); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+	}
+	req.Tools = make([]schema.ResponsesToolParam, len(in.Tools))
+	for i, tool := range in.Tools {
+		if err := decodeStruct(tool, &req.Tools[i]); err != nil {
+			return nil, fmt.Errorf("invalid tools[%d]: %w", i, err)
+		}
+	}
+	if len(req.Tools) == 0 {
+		req.Tools = nil
+	}
+	return req, nil
+}
+
+// toResponse converts a response to its protobuf form.
+func toResponse(in *schema.Response) (*responsespb.Response, error) {
+	out := &responsespb.Response{
+		Id:                          in.ID,
+		Object:                      in.Object,
+		CreatedAt:                   in.CreatedAt,
+		CompletedAt:                 in.CompletedAt,
+		Model:                       in.Model,
+		Status:                      in.Status,
+		Metadata:                    in.Metadata,
+		PreviousResponseId:          in.PreviousResponseID,
+		Conversation:                in.Conversation,
+		Instructions:                in.Instructions,
+		Temperature:                 in.Temperature,
+		TopP:                        in.TopP,
+		MaxOutputTokens:             int32Ptr(in.MaxOutputTokens),
+		MaxToolCalls:                int32Ptr(in.MaxToolCalls),
+		FrequencyPenalty:            in.FrequencyPenalty,
+		PresencePenalty:             in.PresencePenalty,
+		Truncation:                  in.Truncation,
+		ParallelToolCalls:           in.ParallelToolCalls,
+		TopLogprobs:                 int32(in.TopLogprobs),
+		ServiceTier:                 in.ServiceTier,
+		Store:                       in.Store,
+		ExternalId:                  in.ExternalID,
+		MaxDurationSeconds:          int32Ptr(in.MaxDurationSeconds),
+		MaxBackendCalls:             int32Ptr(in.MaxBackendCalls),
+		MaxTotalTokens:              int32Ptr(in.MaxTotalTokens),
+		InstructionsMerge:           in.InstructionsMerge,
+		EffectiveInstructionsSha256: in.EffectiveInstructionsHash,
+	}
+	if u := in.Usage; u != nil {
+		out.Usage = &responsespb.Usage{
+			InputTokens:  int32(u.InputTokens),
+			OutputTokens: int32(u.OutputTokens),
+			TotalTokens:  int32(u.TotalTokens),
+			InputTokensDetails: &responsespb.InputTokensDetails{
+				CachedTokens: int32(u.InputTokensDetails.CachedTokens),
+				AudioTokens:  int32(u.InputTokensDetails.AudioTokens),
+				TextTokens:   int32(u.InputTokensDetails.TextTokens),
+				ImageTokens:  int32(u.InputTokensDetails.ImageTokens),
+			},
+			OutputTokensDetails: &responsespb.OutputTokensDetails{
+				ReasoningTokens: int32(u.OutputTokensDetails.ReasoningTokens),
+				AudioTokens:     int32(u.OutputTokensDetails.AudioTokens),
+				TextTokens:      int32(u.OutputTokensDetails.TextTokens),
+			},
+		}
+	}
+	if e := in.Error; e != nil {
+		out.Error = &responsespb.Error{Type: e.Type, Code: e.Code, Message: e.Message, Param: e.Param}
+	}
+	if d := in.IncompleteDetails; d != nil {
+		out.IncompleteDetails = &responsespb.IncompleteDetails{Reason: d.Reason}
+	}
+	if p := in.Provenance; p != nil {
+		out.Provenance = &responsespb.Provenance{
+			GatewayVersion:        p.GatewayVersion,
+			Model:                 p.Model,
+			BackendEndpointSha256: p.BackendEndpointSHA256,
+			GeneratedAt:           p.GeneratedAt,
+			ContentSha256:         p.ContentSHA256,
+			Watermarked:           p.Watermarked,
+		}
+	}
+
+	var err error
+	if out.Output, err = encodeStructs(in.Output); err != nil {
+		return nil, fmt.Errorf("encode output: %w", err)
+	}
+	if out.Tools, err = encodeStructs(in.Tools); err != nil {
+		return nil, fmt.Errorf("encode tools: %w", err)
+	}
+	if in.ToolChoice != nil {
+		if out.ToolChoice, err = encodeValue(in.ToolChoice); err != nil {
+			return nil, fmt.Errorf("encode tool_choice: %w", err)
+		}
+	}
+	if in.Reasoning != nil {
+		if out.Reasoning, err = encodeStruct(in.Reasoning); err != nil {
+			return nil, fmt.Errorf("encode reasoning: %w", err)
+		}
+	}
+	if out.Text, err = encodeStruct(in.Text); err != nil {
+		return nil, fmt.Errorf("encode text: %w", err)
+	}
+	return out, nil
+}
+
+// toEvent converts a streaming event to its protobuf form. The response
+// carried by lifecycle events is converted to a Response; the other fields
+// are kept in data.
+func toEvent(event any) (*responsespb.ResponseEvent, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	out := &responsespb.ResponseEvent{Type: schema.ExtractEventType(event)}
+	if raw, ok := fields["sequence_number"]; ok {
+		json.Unmarshal(raw, &out.SequenceNumber)
+		delete(fields, "sequence_number")
+	}
+	delete(fields, "type")
+	if raw, ok := fields["response"]; ok {
+		var resp schema.Response
+		if json.Unmarshal(raw, &resp) == nil && resp.ID != "" {
+			if out.Response, err = toResponse(&resp); err != nil {
+				return nil, err
+			}
+			delete(fields, "response")
+		}
+	}
+	if out.Data, err = encodeStruct(fields); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeStruct decodes the JSON form of msg into dst. A nil msg leaves dst
+// untouched.
+func decodeStruct(msg proto.Message, dst any) error {
+	if s, ok := msg.(*structpb.Struct); !ok || s == nil {
+		return nil
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// encodeStruct encodes the JSON form of v as a Struct.
+func encodeStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func encodeStructs[T any](items []T) ([]*structpb.Struct, error) {
+	out := make([]*structpb.Struct, 0, len(items))
+	for _, item := range items {
+		s, err := encodeStruct(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func encodeValue(v any) (*structpb.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	val := &structpb.Value{}
+	if err := protojson.Unmarshal(data, val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+func intPtr(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	i := int(*v)
+	return &i
+}
+
+func int32Ptr(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	i := int32(*v)
+	return &i
+}
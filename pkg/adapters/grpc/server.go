@@ -0,0 +1,334 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package grpc exposes the Responses API as a gRPC service, defined in
+// responsespb/responses.proto, for internal services that would rather not
+// speak JSON and SSE. Unlike the ExtProc adapter, it calls the engine
+// directly instead of going through the HTTP handlers.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/leseb/openresponses-gw/pkg/adapters/grpc/responsespb"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// interruptTimeout bounds how long Stop waits for interrupted responses to
+// be saved once the grace period has expired.
+const interruptTimeout = 5 * time.Second
+
+// Engine is the part of the engine the service uses.
+type Engine interface {
+	ProcessRequest(ctx context.Context, req *schema.ResponseRequest) (*schema.Response, error)
+	ProcessRequestStream(ctx context.Context, req *schema.ResponseRequest) (<-chan interface{}, error)
+	GetResponse(ctx context.Context, responseID string) (*schema.Response, error)
+	Interrupt()
+	NewID(prefix string) string
+}
+
+var _ Engine = (*engine.Engine)(nil)
+
+// Options configures the service.
+type Options struct {
+	// TenantHeader is the metadata key identifying the tenant used to
+	// evaluate feature flags, as the HTTP header of the same name.
+	TenantHeader string
+
+	// TLS, when set, serves the service over TLS with this configuration.
+	TLS *tls.Config
+
+	// Audit, when set, records the responses created through the service,
+	// as the HTTP handlers record POST /v1/responses.
+	Audit state.AuditLog
+}
+
+// Server serves the Responses gRPC service.
+type Server struct {
+	responsespb.UnimplementedResponsesServiceServer
+
+	engine     Engine
+	logger     *logging.Logger
+	opts       Options
+	grpcServer *gogrpc.Server
+}
+
+// NewServer creates a gRPC server for the Responses service.
+func NewServer(eng Engine, logger *logging.Logger, opts Options) *Server {
+	opts.TenantHeader = strings.ToLower(opts.TenantHeader)
+	var serverOpts []gogrpc.ServerOption
+	if opts.TLS != nil {
+		serverOpts = append(serverOpts, gogrpc.Creds(credentials.NewTLS(opts.TLS)))
+	}
+	s := &Server{
+		engine:     eng,
+		logger:     logger,
+		opts:       opts,
+		grpcServer: gogrpc.NewServer(serverOpts...),
+	}
+	responsespb.RegisterResponsesServiceServer(s.grpcServer, s)
+
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(s.grpcServer, healthSrv)
+	healthSrv.SetServingStatus(responsespb.ResponsesService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return s
+}
+
+// Start begins listening on the given address. Blocks until stopped.
+func (s *Server) Start(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.logger.Info("Responses gRPC server listening", "address", addr)
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop refuses new calls and waits for in-flight ones until ctx expires.
+// Responses still running then are interrupted, and end incomplete.
+func (s *Server) Stop(ctx context.Context) {
+	s.logger.Info("Stopping Responses gRPC server")
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	s.logger.Warn("Grace period expired, interrupting gRPC responses")
+	s.engine.Interrupt()
+	select {
+	case <-done:
+	case <-time.After(interruptTimeout):
+		s.grpcServer.Stop()
+	}
+}
+
+// CreateResponse implements responsespb.ResponsesServiceServer
+func (s *Server) CreateResponse(ctx context.Context, in *responsespb.CreateResponseRequest) (out *responsespb.Response, err error) {
+	ctx = s.context(ctx)
+	defer func() { s.audit(ctx, out.GetId(), err) }()
+	req, err := s.request(in)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.engine.ProcessRequest(ctx, req)
+	if err != nil {
+		return nil, s.processingError(ctx, err)
+	}
+	out, err = toResponse(resp)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// StreamResponse implements responsespb.ResponsesServiceServer
+func (s *Server) StreamResponse(in *responsespb.CreateResponseRequest, stream gogrpc.ServerStreamingServer[responsespb.ResponseEvent]) (err error) {
+	ctx := s.context(stream.Context())
+	var responseID string
+	defer func() { s.audit(ctx, responseID, err) }()
+	req, err := s.request(in)
+	if err != nil {
+		return err
+	}
+	req.Stream = true
+	events, err := s.engine.ProcessRequestStream(ctx, req)
+	if err != nil {
+		return s.processingError(ctx, err)
+	}
+
+	// Keep reading after a failed send so the engine finishes and saves
+	// the response; its context is canceled with the stream's
+	var sendErr error
+	for event := range events {
+		if created, ok := event.(*schema.ResponseCreatedStreamingEvent); ok {
+			responseID = created.Response.ID
+		}
+		if sendErr != nil {
+			continue
+		}
+		out, err := toEvent(event)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to convert event", "error", err)
+			continue
+		}
+		sendErr = stream.Send(out)
+	}
+	return sendErr
+}
+
+// GetResponse implements responsespb.ResponsesServiceServer
+func (s *Server) GetResponse(ctx context.Context, in *responsespb.GetResponseRequest) (*responsespb.Response, error) {
+	if in.GetResponseId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "response_id is required")
+	}
+	resp, err := s.engine.GetResponse(ctx, in.GetResponseId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	out, err := toResponse(resp)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// request converts and validates a request.
+func (s *Server) request(in *responsespb.CreateResponseRequest) (*schema.ResponseRequest, error) {
+	req, err := toRequest(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return req, nil
+}
+
+// context attaches the request ID of the call, the tenant named in its
+// metadata and the fingerprint of its API key. The request ID is taken
+// from the x-request-id metadata, or generated, and returned as a header.
+func (s *Server) context(ctx context.Context) context.Context {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(requestid.Header)); len(values) > 0 && requestid.Valid(values[0]) {
+		id = values[0]
+	} else {
+		id = requestid.New()
+	}
+	ctx = requestid.WithID(ctx, id)
+	gogrpc.SetHeader(ctx, metadata.Pairs(requestid.Header, id))
+
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		if key, ok := strings.CutPrefix(values[0], "Bearer "); ok && key != "" {
+			ctx = state.WithAPIKey(ctx, state.APIKeyFingerprint(key))
+		}
+	}
+	if s.opts.TenantHeader == "" {
+		return ctx
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, s.opts.TenantHeader); len(values) > 0 && values[0] != "" {
+		return featureflags.WithTenant(ctx, values[0])
+	}
+	return ctx
+}
+
+// audit records a call creating a response in the audit log, if one is
+// configured. err is the error the call returned.
+func (s *Server) audit(ctx context.Context, responseID string, err error) {
+	if s.opts.Audit == nil {
+		return
+	}
+	method, _ := gogrpc.Method(ctx)
+	event := &state.AuditEvent{
+		ID:           s.engine.NewID("audit_"),
+		CreatedAt:    time.Now(),
+		Actor:        state.APIKeyFromContext(ctx),
+		Tenant:       featureflags.TenantFromContext(ctx),
+		Method:       http.MethodPost,
+		Path:         method,
+		Action:       "create",
+		ResourceType: "response",
+		Status:       httpStatus(status.Code(err)),
+	}
+	if err == nil {
+		event.ResourceID = responseID
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		event.RemoteAddr = p.Addr.String()
+	}
+	if err := s.opts.Audit.AppendAuditEvent(context.WithoutCancel(ctx), event); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record audit event", "error", err,
+			"action", event.Action, "resource_type", event.ResourceType, "resource_id", event.ResourceID)
+	}
+}
+
+// processingError maps an engine error to a gRPC status, as the HTTP
+// handler maps it to a status code.
+func (s *Server) processingError(ctx context.Context, err error) error {
+	var promptErr *engine.PromptError
+	if errors.As(err, &promptErr) {
+		return status.Error(codes.InvalidArgument, promptErr.Error())
+	}
+	apiErr, ok := apierror.From(err)
+	if !ok {
+		s.logger.ErrorContext(ctx, "Failed to process request", "error", err)
+		return status.Error(codes.Internal, err.Error())
+	}
+	var rejectErr *hooks.RejectError
+	if errors.As(err, &rejectErr) {
+		s.logger.InfoContext(ctx, "Request rejected by hook", "hook", rejectErr.Hook)
+	}
+	return status.Error(grpcCode(apiErr), apiErr.Message)
+}
+
+// grpcCode returns the gRPC status code of an API error.
+func grpcCode(err *apierror.Error) codes.Code {
+	switch {
+	case err.Code == apierror.CodeRequestRejected:
+		return codes.FailedPrecondition
+	case err.Status == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case err.Status == http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case err.Status == http.StatusConflict:
+		return codes.Aborted
+	case err.Status == http.StatusNotFound:
+		return codes.NotFound
+	case err.Status == http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case err.Status < 500:
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// httpStatus returns the HTTP status matching a gRPC status code, the
+// reverse of grpcCode, for the audit log.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
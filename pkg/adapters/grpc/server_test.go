@@ -0,0 +1,254 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/leseb/openresponses-gw/pkg/adapters/grpc/responsespb"
+	"github.com/leseb/openresponses-gw/pkg/audit"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+type fakeEngine struct {
+	req       *schema.ResponseRequest
+	tenant    string
+	requestID string
+	err       error
+}
+
+func (e *fakeEngine) response() *schema.Response {
+	return &schema.Response{
+		ID:     "resp_1",
+		Object: "response",
+		Model:  *e.req.Model,
+		Status: "completed",
+		Output: []schema.ItemField{{Type: "message", ID: "msg_1"}},
+		Usage:  &schema.UsageField{InputTokens: 3, OutputTokens: 5, TotalTokens: 8},
+		Text:   schema.TextField{},
+	}
+}
+
+func (e *fakeEngine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest) (*schema.Response, error) {
+	e.req, e.tenant, e.requestID = req, featureflags.TenantFromContext(ctx), requestid.FromContext(ctx)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.response(), nil
+}
+
+func (e *fakeEngine) ProcessRequestStream(ctx context.Context, req *schema.ResponseRequest) (<-chan interface{}, error) {
+	e.req = req
+	if e.err != nil {
+		return nil, e.err
+	}
+	events := make(chan interface{}, 3)
+	events <- &schema.ResponseCreatedStreamingEvent{Type: "response.created", SequenceNumber: 0, Response: *e.response()}
+	events <- &schema.ResponseOutputTextDeltaStreamingEvent{Type: "response.output_text.delta", SequenceNumber: 1, ItemID: "msg_1", Delta: "Hi"}
+	events <- &schema.ResponseCompletedStreamingEvent{Type: "response.completed", SequenceNumber: 2, Response: *e.response()}
+	close(events)
+	return events, nil
+}
+
+func (e *fakeEngine) GetResponse(ctx context.Context, id string) (*schema.Response, error) {
+	return nil, errors.New("response not found")
+}
+
+func (e *fakeEngine) Interrupt() {}
+
+func (e *fakeEngine) NewID(prefix string) string { return prefix + "1" }
+
+func newTestClient(t *testing.T, eng Engine) responsespb.ResponsesServiceClient {
+	t.Helper()
+	return newTestClientWithOptions(t, eng, Options{})
+}
+
+func newTestClientWithOptions(t *testing.T, eng Engine, opts Options) responsespb.ResponsesServiceClient {
+	t.Helper()
+	opts.TenantHeader = "X-Tenant-ID"
+	srv := NewServer(eng, logging.New(logging.Config{Level: "error"}), opts)
+	lis := bufconn.Listen(1 << 20)
+	go srv.grpcServer.Serve(lis)
+	t.Cleanup(srv.grpcServer.Stop)
+
+	conn, err := gogrpc.NewClient("passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return responsespb.NewResponsesServiceClient(conn)
+}
+
+func TestCreateResponse(t *testing.T) {
+	tool, _ := structpb.NewStruct(map[string]any{
+		"type":       "function",
+		"name":       "get_weather",
+		"parameters": map[string]any{"type": "object"},
+	})
+	valid := &responsespb.CreateResponseRequest{
+		Model:           proto.String("m"),
+		Input:           structpb.NewStringValue("hello"),
+		Tools:           []*structpb.Struct{tool},
+		MaxOutputTokens: proto.Int32(64),
+	}
+
+	tests := []struct {
+		name     string
+		req      *responsespb.CreateResponseRequest
+		err      error
+		wantCode codes.Code
+	}{
+		{"ok", valid, nil, codes.OK},
+		{"missing model", &responsespb.CreateResponseRequest{Input: structpb.NewStringValue("hello")}, nil, codes.InvalidArgument},
+		{"rejected by hook", valid, &hooks.RejectError{Hook: "h", Message: "no"}, codes.FailedPrecondition},
+		{"engine failure", valid, errors.New("backend down"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := &fakeEngine{err: tt.err}
+			client := newTestClient(t, eng)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme", "x-request-id", "trace-1")
+
+			var header metadata.MD
+			resp, err := client.CreateResponse(ctx, tt.req, gogrpc.Header(&header))
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if eng.req.Input != "hello" || len(eng.req.Tools) != 1 || eng.req.Tools[0].Name != "get_weather" ||
+				eng.req.MaxOutputTokens == nil || *eng.req.MaxOutputTokens != 64 {
+				t.Errorf("engine request = %+v", eng.req)
+			}
+			if eng.tenant != "acme" {
+				t.Errorf("tenant = %q, want acme", eng.tenant)
+			}
+			if got := header.Get("x-request-id"); eng.requestID != "trace-1" || len(got) != 1 || got[0] != "trace-1" {
+				t.Errorf("request ID = %q, header %v, want trace-1", eng.requestID, got)
+			}
+			if resp.GetId() != "resp_1" || resp.GetUsage().GetTotalTokens() != 8 || len(resp.GetOutput()) != 1 ||
+				resp.GetOutput()[0].GetFields()["type"].GetStringValue() != "message" {
+				t.Errorf("response = %v", resp)
+			}
+		})
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	client := newTestClient(t, &fakeEngine{})
+	stream, err := client.StreamResponse(context.Background(), &responsespb.CreateResponseRequest{
+		Model: proto.String("m"),
+		Input: structpb.NewStringValue("hello"),
+	})
+	if err != nil {
+		t.Fatalf("StreamResponse: %v", err)
+	}
+
+	var events []*responsespb.ResponseEvent
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if e := events[0]; e.GetType() != "response.created" || e.GetResponse().GetId() != "resp_1" {
+		t.Errorf("first event = %v", e)
+	}
+	if e := events[1]; e.GetSequenceNumber() != 1 || e.GetData().GetFields()["delta"].GetStringValue() != "Hi" {
+		t.Errorf("delta event = %v", e)
+	}
+	if e := events[2]; e.GetType() != "response.completed" || e.GetResponse().GetStatus() != "completed" {
+		t.Errorf("last event = %v", e)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	log, err := audit.OpenFile(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	client := newTestClientWithOptions(t, &fakeEngine{}, Options{Audit: log})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme", "authorization", "Bearer sk-test")
+	req := &responsespb.CreateResponseRequest{Model: proto.String("m"), Input: structpb.NewStringValue("hello")}
+
+	if _, err := client.CreateResponse(ctx, req); err != nil {
+		t.Fatalf("CreateResponse: %v", err)
+	}
+	stream, err := client.StreamResponse(ctx, req)
+	if err != nil {
+		t.Fatalf("StreamResponse: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	if _, err := client.CreateResponse(ctx, &responsespb.CreateResponseRequest{Input: structpb.NewStringValue("hello")}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("CreateResponse without model: %v", err)
+	}
+
+	events, _, err := log.ListAuditEvents(context.Background(), state.AuditFilter{}, "", 10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d audit events, want 3", len(events))
+	}
+	// Newest first
+	want := []struct {
+		path       string
+		resourceID string
+		status     int
+	}{
+		{"/openresponses.v1.ResponsesService/CreateResponse", "", 400},
+		{"/openresponses.v1.ResponsesService/StreamResponse", "resp_1", 200},
+		{"/openresponses.v1.ResponsesService/CreateResponse", "resp_1", 200},
+	}
+	for i, e := range events {
+		if e.Path != want[i].path || e.ResourceID != want[i].resourceID || e.Status != want[i].status ||
+			e.Action != "create" || e.ResourceType != "response" || e.Tenant != "acme" || e.Actor != state.APIKeyFingerprint("sk-test") {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestGetResponse_NotFound(t *testing.T) {
+	client := newTestClient(t, &fakeEngine{})
+	_, err := client.GetResponse(context.Background(), &responsespb.GetResponseRequest{ResponseId: "resp_x"})
+	if got := status.Code(err); got != codes.NotFound {
+		t.Errorf("code = %v, want NotFound", got)
+	}
+}
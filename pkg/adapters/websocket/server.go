@@ -0,0 +1,102 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package websocket exposes the Responses API over WebSocket, for clients
+// whose proxies do not pass server-sent events through.
+//
+// Each text frame sent by the client is a JSON message:
+//
+//	{"type": "response.create", "response": {...}}
+//	{"type": "response.cancel", "response_id": "resp_..."}
+//	{"type": "response.tool_outputs", "response_id": "resp_...", "outputs": [{"call_id": "...", "output": "..."}]}
+//
+// The server replies with the same streaming events as POST /v1/responses
+// with stream=true, one event per text frame. Several responses may run
+// concurrently on a connection; their events carry the response ID.
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	ws "golang.org/x/net/websocket"
+)
+
+// Path is the route the adapter is usually mounted on.
+const Path = "/v1/responses/ws"
+
+// DefaultMaxMessageBytes is the default limit on client message size.
+const DefaultMaxMessageBytes = 16 << 20 // 16 MiB
+
+// Options configures the adapter. Zero values select the defaults.
+type Options struct {
+	// AllowedOrigins lists the Origin header values accepted during the
+	// handshake. Empty allows any origin, and clients that send none.
+	AllowedOrigins []string
+	// MaxMessageBytes bounds the size of a client message.
+	MaxMessageBytes int
+}
+
+// Server upgrades HTTP requests to WebSocket connections and runs the
+// Responses API requests they carry through an http.Handler, as the ExtProc
+// adapter does.
+type Server struct {
+	handler http.Handler
+	logger  *logging.Logger
+	opts    Options
+}
+
+// NewServer creates a WebSocket adapter that delegates to handler.
+func NewServer(handler http.Handler, logger *logging.Logger, opts Options) *Server {
+	if opts.MaxMessageBytes <= 0 {
+		opts.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	return &Server{handler: handler, logger: logger, opts: opts}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		writeError(w, http.StatusBadRequest, "WebSocket upgrade required")
+		return
+	}
+	if _, ok := w.(http.Hijacker); !ok {
+		writeError(w, http.StatusInternalServerError, "WebSocket not supported by this connection")
+		return
+	}
+
+	srv := ws.Server{
+		Handshake: s.checkOrigin,
+		Handler: func(conn *ws.Conn) {
+			conn.MaxPayloadBytes = s.opts.MaxMessageBytes
+			// Clear the HTTP server's read and write timeouts, which
+			// outlive the hijack
+			conn.SetDeadline(time.Time{})
+			s.logger.Info("WebSocket connection opened", "remote_addr", r.RemoteAddr)
+			newSession(s, conn).run()
+			s.logger.Info("WebSocket connection closed", "remote_addr", r.RemoteAddr)
+		},
+	}
+	srv.ServeHTTP(w, r)
+}
+
+// checkOrigin rejects handshakes from origins that are not allowed.
+func (s *Server) checkOrigin(_ *ws.Config, r *http.Request) error {
+	if len(s.opts.AllowedOrigins) == 0 {
+		return nil
+	}
+	origin := r.Header.Get("Origin")
+	if !slices.Contains(s.opts.AllowedOrigins, origin) {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	apierror.Write(w, apierror.New(status, apierror.CodeInvalidRequest, message))
+}
@@ -0,0 +1,209 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	ws "golang.org/x/net/websocket"
+)
+
+// fakeHandler streams response.created, then waits for the request to be
+// cancelled when its input is "wait", and ends with response.completed.
+func fakeHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input              json.RawMessage `json:"input"`
+			Stream             bool            `json:"stream"`
+			PreviousResponseID string          `json:"previous_response_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if string(req.Input) == `"fail"` {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"type":"invalid_request","message":"model is required"}}`)
+			return
+		}
+		if !req.Stream {
+			t.Error("stream not forced on")
+		}
+
+		id := "resp_1"
+		if req.PreviousResponseID != "" {
+			id = "resp_2"
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":%q}}\n\n", id)
+		w.(http.Flusher).Flush()
+
+		status := "completed"
+		if string(req.Input) == `"wait"` {
+			select {
+			case <-engine.Cancelled(r.Context()):
+				status = "incomplete"
+			case <-time.After(5 * time.Second):
+				t.Error("response not cancelled")
+			}
+		}
+		fmt.Fprintf(w, "event: response.%s\n", status)
+		fmt.Fprintf(w, "data: {\"type\":\"response.%s\",\"response\":{\"id\":%q,\"previous_response_id\":%q}}\n\n",
+			status, id, req.PreviousResponseID)
+	}
+}
+
+func dial(t *testing.T, srv *httptest.Server, origin string) *ws.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + Path
+	conn, err := ws.Dial(url, "", origin)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type frame struct {
+	Type     string `json:"type"`
+	Response struct {
+		ID                 string `json:"id"`
+		PreviousResponseID string `json:"previous_response_id"`
+	} `json:"response"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func receive(t *testing.T, conn *ws.Conn) frame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var f frame
+	if err := ws.JSON.Receive(conn, &f); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return f
+}
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	logger := logging.New(logging.Config{Level: "error"})
+	srv := httptest.NewServer(NewServer(fakeHandler(t), logger, opts))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServer_Messages(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     []string // frame types, or error types
+	}{
+		{
+			name:     "create",
+			messages: []string{`{"type":"response.create","response":{"input":"hi"}}`},
+			want:     []string{"response.created", "response.completed"},
+		},
+		{
+			name:     "handler error",
+			messages: []string{`{"type":"response.create","response":{"input":"fail"}}`},
+			want:     []string{"error:invalid_request"},
+		},
+		{
+			name:     "unknown type",
+			messages: []string{`{"type":"response.update"}`},
+			want:     []string{"error:invalid_request"},
+		},
+		{
+			name:     "invalid json",
+			messages: []string{`{`},
+			want:     []string{"error:invalid_request"},
+		},
+		{
+			name:     "cancel unknown response",
+			messages: []string{`{"type":"response.cancel","response_id":"resp_x"}`},
+			want:     []string{"error:invalid_request"},
+		},
+		{
+			name:     "tool outputs for unknown response",
+			messages: []string{`{"type":"response.tool_outputs","response_id":"resp_x","outputs":[{"call_id":"c1","output":"1"}]}`},
+			want:     []string{"error:invalid_request"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dial(t, newTestServer(t, Options{}), "http://localhost")
+			for _, msg := range tt.messages {
+				ws.Message.Send(conn, msg)
+			}
+			for _, want := range tt.want {
+				f := receive(t, conn)
+				got := f.Type
+				if f.Type == "error" {
+					got = "error:" + f.Error.Type
+				}
+				if got != want {
+					t.Errorf("frame = %q (%+v), want %q", got, f, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Cancel(t *testing.T) {
+	conn := dial(t, newTestServer(t, Options{}), "http://localhost")
+
+	ws.Message.Send(conn, `{"type":"response.create","response":{"input":"wait"}}`)
+	if f := receive(t, conn); f.Type != "response.created" {
+		t.Fatalf("frame = %+v, want response.created", f)
+	}
+	ws.Message.Send(conn, `{"type":"response.cancel","response_id":"resp_1"}`)
+	if f := receive(t, conn); f.Type != "response.incomplete" {
+		t.Errorf("frame = %+v, want response.incomplete", f)
+	}
+}
+
+func TestServer_ToolOutputs(t *testing.T) {
+	conn := dial(t, newTestServer(t, Options{}), "http://localhost")
+
+	ws.Message.Send(conn, `{"type":"response.create","response":{"input":"hi"}}`)
+	receive(t, conn)
+	receive(t, conn)
+
+	ws.Message.Send(conn, `{"type":"response.tool_outputs","response_id":"resp_1","outputs":[{"call_id":"c1","output":"42"}]}`)
+	receive(t, conn)
+	f := receive(t, conn)
+	if f.Type != "response.completed" || f.Response.PreviousResponseID != "resp_1" {
+		t.Errorf("frame = %+v, want response.completed following resp_1", f)
+	}
+}
+
+func TestServer_Handshake(t *testing.T) {
+	srv := newTestServer(t, Options{AllowedOrigins: []string{"https://app.example.com"}})
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + Path
+	if _, err := ws.Dial(url, "", "https://evil.example.com"); err == nil {
+		t.Error("expected a disallowed origin to be rejected")
+	}
+	dial(t, srv, "https://app.example.com")
+
+	resp, err := http.Get(srv.URL + Path)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	ws "golang.org/x/net/websocket"
+)
+
+// Client message types.
+const (
+	msgCreate      = "response.create"
+	msgCancel      = "response.cancel"
+	msgToolOutputs = "response.tool_outputs"
+)
+
+// maxRemembered bounds how many requests a session keeps for
+// response.tool_outputs.
+const maxRemembered = 100
+
+// clientMessage is a message received from the client.
+type clientMessage struct {
+	Type       string          `json:"type"`
+	Response   json.RawMessage `json:"response,omitempty"`    // response.create
+	ResponseID string          `json:"response_id,omitempty"` // response.cancel, response.tool_outputs
+	Outputs    []toolOutput    `json:"outputs,omitempty"`     // response.tool_outputs
+}
+
+// toolOutput is the result of a function call made by the client.
+type toolOutput struct {
+	CallID string `json:"call_id"`
+	Output string `json:"output"`
+}
+
+// session serves one WebSocket connection.
+type session struct {
+	srv  *Server
+	conn *ws.Conn
+	ctx  context.Context
+
+	writeMu sync.Mutex // serializes frames
+
+	mu       sync.Mutex
+	runs     map[*run]struct{}                     // in-flight responses
+	requests map[string]map[string]json.RawMessage // request fields by response ID
+	order    []string                              // keys of requests, oldest first
+	wg       sync.WaitGroup
+}
+
+// run is an in-flight response.
+type run struct {
+	cancel func()
+	id     string // known once response.created is sent
+}
+
+func newSession(srv *Server, conn *ws.Conn) *session {
+	return &session{
+		srv:      srv,
+		conn:     conn,
+		ctx:      conn.Request().Context(),
+		runs:     make(map[*run]struct{}),
+		requests: make(map[string]map[string]json.RawMessage),
+	}
+}
+
+// run reads client messages until the connection closes. Responses still
+// running then are cancelled, so that their partial output is saved.
+func (s *session) run() {
+	defer func() {
+		s.cancel("")
+		s.wg.Wait()
+	}()
+
+	for {
+		var data []byte
+		if err := ws.Message.Receive(s.conn, &data); err != nil {
+			if errors.Is(err, ws.ErrFrameTooLarge) {
+				s.sendError("invalid_request", fmt.Sprintf("message exceeds %d bytes", s.srv.opts.MaxMessageBytes))
+				continue
+			}
+			if !errors.Is(err, io.EOF) {
+				s.srv.logger.Warn("WebSocket read failed", "error", err)
+			}
+			return
+		}
+		s.handle(data)
+	}
+}
+
+func (s *session) handle(data []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.sendError("invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+
+	switch msg.Type {
+	case msgCreate:
+		if len(msg.Response) == 0 {
+			s.sendError("invalid_request", "response is required")
+			return
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(msg.Response, &fields); err != nil || fields == nil {
+			s.sendError("invalid_request", "response must be a JSON object")
+			return
+		}
+		s.start(fields)
+
+	case msgCancel:
+		if !s.cancel(msg.ResponseID) {
+			s.sendError("invalid_request", fmt.Sprintf("no in-flight response %q", msg.ResponseID))
+		}
+
+	case msgToolOutputs:
+		fields, err := s.toolOutputsRequest(msg)
+		if err != nil {
+			s.sendError("invalid_request", err.Error())
+			return
+		}
+		s.start(fields)
+
+	default:
+		s.sendError("invalid_request", fmt.Sprintf("unsupported message type %q", msg.Type))
+	}
+}
+
+// start runs a streaming response request through the HTTP handler.
+func (s *session) start(fields map[string]json.RawMessage) {
+	fields["stream"] = json.RawMessage("true")
+	body, err := json.Marshal(fields)
+	if err != nil {
+		s.sendError("invalid_request", err.Error())
+		return
+	}
+
+	ctx, cancel := engine.WithCancel(s.ctx)
+	rn := &run{cancel: cancel}
+	s.mu.Lock()
+	s.runs[rn] = struct{}{}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.runs, rn)
+			s.mu.Unlock()
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/responses", bytes.NewReader(body))
+		if err != nil {
+			s.sendError("server_error", err.Error())
+			return
+		}
+		s.copyHeaders(req)
+
+		w := &eventWriter{s: s, rn: rn, fields: fields, header: make(http.Header)}
+		s.srv.handler.ServeHTTP(w, req)
+		w.finish()
+	}()
+}
+
+// copyHeaders passes the handshake's headers (authentication, tenant, ...)
+// on to a response request.
+func (s *session) copyHeaders(req *http.Request) {
+	hs := s.conn.Request()
+	for name, values := range hs.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Connection", "Upgrade", "Content-Length", "Content-Type", "Accept", "Accept-Encoding",
+			"Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol":
+			continue
+		}
+		req.Header[name] = slices.Clone(values)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.RemoteAddr = hs.RemoteAddr
+}
+
+// cancel cancels the in-flight response with the given ID, or all of them
+// if id is empty. It reports whether a response was found.
+func (s *session) cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for rn := range s.runs {
+		if id == "" || rn.id == id {
+			rn.cancel()
+			found = true
+		}
+	}
+	return found || id == ""
+}
+
+// remember keeps the request that created a response, so that tool outputs
+// can continue it.
+func (s *session) remember(id string, fields map[string]json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[id] = fields
+	s.order = append(s.order, id)
+	if len(s.order) > maxRemembered {
+		delete(s.requests, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// toolOutputsRequest builds the request that continues a response with the
+// client's function call outputs, reusing the model, tools and other
+// parameters of the request that created it.
+func (s *session) toolOutputsRequest(msg clientMessage) (map[string]json.RawMessage, error) {
+	if msg.ResponseID == "" {
+		return nil, errors.New("response_id is required")
+	}
+	if len(msg.Outputs) == 0 {
+		return nil, errors.New("outputs is required")
+	}
+
+	s.mu.Lock()
+	prev, ok := s.requests[msg.ResponseID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown response %q: tool outputs must follow a response created on this connection", msg.ResponseID)
+	}
+
+	items := make([]map[string]string, 0, len(msg.Outputs))
+	for _, out := range msg.Outputs {
+		if out.CallID == "" {
+			return nil, errors.New("outputs[].call_id is required")
+		}
+		items = append(items, map[string]string{
+			"type":    "function_call_output",
+			"call_id": out.CallID,
+			"output":  out.Output,
+		})
+	}
+	input, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := maps.Clone(prev)
+	fields["input"] = input
+	// A conversation already carries the previous turn
+	if _, ok := fields["conversation"]; !ok {
+		fields["previous_response_id"], _ = json.Marshal(msg.ResponseID)
+	}
+	return fields, nil
+}
+
+// send writes a text frame. Errors mean the client is gone; the read loop
+// notices and cleans up.
+func (s *session) send(data []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	ws.Message.Send(s.conn, string(data))
+}
+
+func (s *session) sendError(errType, message string) {
+	s.sendErrorField(schema.ErrorField{Type: errType, Message: message})
+}
+
+// sendErrorField sends an error frame carrying an error object.
+func (s *session) sendErrorField(field schema.ErrorField) {
+	data, _ := json.Marshal(&schema.ErrorStreamingEvent{
+		Type:  "error",
+		Error: field,
+	})
+	s.send(data)
+}
@@ -0,0 +1,130 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// eventWriter is the http.ResponseWriter a response request is served
+// with. It turns the handler's server-sent events into text frames.
+type eventWriter struct {
+	s      *session
+	rn     *run
+	fields map[string]json.RawMessage // the request, remembered on response.created
+
+	header http.Header
+	status int
+	sse    bool
+	buf    bytes.Buffer
+}
+
+// Header implements http.ResponseWriter
+func (w *eventWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *eventWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	w.sse = status == http.StatusOK && strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+}
+
+// Write implements http.ResponseWriter
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.buf.Write(p)
+	if w.sse {
+		w.flushEvents()
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher
+func (w *eventWriter) Flush() {}
+
+// flushEvents sends every complete event in the buffer.
+func (w *eventWriter) flushEvents() {
+	for {
+		block, _, ok := bytes.Cut(w.buf.Bytes(), []byte("\n\n"))
+		if !ok {
+			return
+		}
+		w.sendEvent(block)
+		w.buf.Next(len(block) + 2)
+	}
+}
+
+// sendEvent sends the data of an SSE event block.
+func (w *eventWriter) sendEvent(block []byte) {
+	var data []byte
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		if d, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			data = append(data, bytes.TrimPrefix(d, []byte(" "))...)
+		}
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	var event struct {
+		Type     string `json:"type"`
+		Response struct {
+			ID string `json:"id"`
+		} `json:"response"`
+	}
+	if json.Unmarshal(data, &event) != nil || event.Type == "" {
+		// The handler reports a failure to start streaming as an error
+		// envelope, which is not a streaming event
+		w.s.sendErrorField(errorField(data, http.StatusInternalServerError))
+		return
+	}
+
+	if event.Type == "response.created" && event.Response.ID != "" {
+		w.s.mu.Lock()
+		w.rn.id = event.Response.ID
+		w.s.mu.Unlock()
+		w.s.remember(event.Response.ID, w.fields)
+	}
+	w.s.send(data)
+}
+
+// finish sends what the handler wrote that was not an event stream, such as
+// a JSON error, as an error frame.
+func (w *eventWriter) finish() {
+	if w.sse {
+		w.flushEvents()
+		return
+	}
+	if w.status == 0 {
+		return
+	}
+
+	w.s.sendErrorField(errorField(w.buf.Bytes(), w.status))
+}
+
+// errorField extracts the error object of an error envelope, or makes one
+// of the error status and text the handler wrote instead.
+func errorField(data []byte, status int) schema.ErrorField {
+	var body struct {
+		Error schema.ErrorField `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		return body.Error
+	}
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return schema.ErrorField{Type: apierror.TypeFor(status), Message: message}
+}
@@ -0,0 +1,125 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit keeps the audit log in an append-only file, for deployments
+// whose session store cannot hold it.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// ensure FileLog satisfies state.AuditLog
+var _ state.AuditLog = (*FileLog)(nil)
+
+// FileLog is an audit log stored as JSON lines, one event per line, in the
+// order they were recorded. Lines are only ever appended.
+type FileLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// record is the JSON form of an event in the file.
+type record struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Actor        string    `json:"actor,omitempty"`
+	Tenant       string    `json:"tenant,omitempty"`
+	RemoteAddr   string    `json:"remote_addr,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id,omitempty"`
+	Status       int       `json:"status"`
+}
+
+// OpenFile opens the audit log at path, creating it if needed.
+func OpenFile(path string) (*FileLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &FileLog{path: path, f: f}, nil
+}
+
+// Close closes the file.
+func (l *FileLog) Close() error {
+	return l.f.Close()
+}
+
+// AppendAuditEvent implements state.AuditLog.
+func (l *FileLog) AppendAuditEvent(_ context.Context, event *state.AuditEvent) error {
+	line, err := json.Marshal(record(*event))
+	if err != nil {
+		return fmt.Errorf("marshal audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(line); err != nil {
+		return fmt.Errorf("append audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents implements state.AuditLog. The file is scanned on every
+// call.
+func (l *FileLog) ListAuditEvents(_ context.Context, filter state.AuditFilter, after string, limit int) ([]*state.AuditEvent, bool, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	l.mu.Lock()
+	f, err := os.Open(l.path)
+	l.mu.Unlock()
+	if err != nil {
+		return nil, false, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var all []*state.AuditEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A line cut short by a crash; the next ones are still valid
+			continue
+		}
+		e := state.AuditEvent(r)
+		all = append(all, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("read audit log: %w", err)
+	}
+
+	// Newest first, resuming past the after cursor
+	var events []*state.AuditEvent
+	skipping := after != ""
+	for i := len(all) - 1; i >= 0; i-- {
+		e := all[i]
+		if skipping {
+			skipping = e.ID != after
+			continue
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		if len(events) == limit {
+			return events, true, nil
+		}
+		events = append(events, e)
+	}
+	return events, false, nil
+}
@@ -0,0 +1,81 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestFileLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	for i, e := range []*state.AuditEvent{
+		{ID: "audit_1", Actor: "key_a", Action: "create", ResourceType: "file", ResourceID: "file_1", Status: 200},
+		{ID: "audit_2", Actor: "key_b", Action: "delete", ResourceType: "file", ResourceID: "file_1", Status: 200},
+		{ID: "audit_3", Actor: "key_a", Action: "create", ResourceType: "prompt", ResourceID: "pmpt_1", Status: 200},
+	} {
+		e.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := l.AppendAuditEvent(ctx, e); err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+	}
+	l.Close()
+
+	// Events survive reopening, and a truncated last line is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"audit_4","crea`)
+	f.Close()
+	l, err = OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer l.Close()
+
+	tests := []struct {
+		name    string
+		filter  state.AuditFilter
+		after   string
+		limit   int
+		want    []string
+		hasMore bool
+	}{
+		{name: "all newest first", want: []string{"audit_3", "audit_2", "audit_1"}},
+		{name: "by actor", filter: state.AuditFilter{Actor: "key_a"}, want: []string{"audit_3", "audit_1"}},
+		{name: "by resource", filter: state.AuditFilter{ResourceType: "file", ResourceID: "file_1"}, want: []string{"audit_2", "audit_1"}},
+		{name: "created before", filter: state.AuditFilter{CreatedBefore: base.Add(90 * time.Second)}, want: []string{"audit_2", "audit_1"}},
+		{name: "first page", limit: 2, want: []string{"audit_3", "audit_2"}, hasMore: true},
+		{name: "next page", after: "audit_2", limit: 2, want: []string{"audit_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasMore, err := l.ListAuditEvents(ctx, tt.filter, tt.after, tt.limit)
+			if err != nil {
+				t.Fatalf("ListAuditEvents: %v", err)
+			}
+			var ids []string
+			for _, e := range got {
+				ids = append(ids, e.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) || hasMore != tt.hasMore {
+				t.Errorf("got %v (has_more %v), want %v (has_more %v)", ids, hasMore, tt.want, tt.hasMore)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package compression implements HTTP middleware that compresses responses
+// with gzip or deflate and decompresses request bodies.
+//
+// Only textual content types (JSON, text, XML, YAML, ...) are compressed.
+// Server-sent event streams are never compressed, so that each event reaches
+// the client as soon as it is flushed.
+package compression
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// Default option values.
+const (
+	DefaultMinSize             = 1024
+	DefaultMaxDecompressedSize = 1 << 30 // 1 GiB
+)
+
+// Options configures the middleware. Zero values select the defaults.
+type Options struct {
+	// MinSize is the smallest response body, in bytes, that is compressed.
+	MinSize int
+	// Level is the gzip/deflate compression level, from 1 (fastest) to 9
+	// (best). Zero selects the library default.
+	Level int
+	// MaxDecompressedSize bounds the size of a decompressed request body.
+	MaxDecompressedSize int64
+}
+
+// Handler wraps next with response compression and request decompression.
+func Handler(next http.Handler, opts Options) (http.Handler, error) {
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultMinSize
+	}
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	if opts.Level < gzip.HuffmanOnly || opts.Level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", opts.Level)
+	}
+
+	level := opts.Level
+	return &handler{
+		next: next,
+		opts: opts,
+		gzipPool: sync.Pool{New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		deflatePool: sync.Pool{New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+	}, nil
+}
+
+type handler struct {
+	next        http.Handler
+	opts        Options
+	gzipPool    sync.Pool
+	deflatePool sync.Pool
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, err := h.decompressRequest(w, r); err != nil {
+		apierror.Write(w, apierror.New(status, apierror.CodeInvalidRequest, err.Error()))
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiate(r.Header.Get("Accept-Encoding"))
+	if encoding == "" || r.Method == http.MethodHead {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &responseWriter{ResponseWriter: w, h: h, encoding: encoding}
+	defer cw.close()
+	h.next.ServeHTTP(cw, r)
+}
+
+// decompressRequest replaces a compressed request body with its
+// decompressed content. On failure it returns the HTTP status to reply with.
+func (h *handler) decompressRequest(w http.ResponseWriter, r *http.Request) (int, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	var body io.ReadCloser
+	switch encoding {
+	case "", "identity":
+		return 0, nil
+	case encodingGzip, "x-gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid gzip request body: %w", err)
+		}
+		body = zr
+	case encodingDeflate:
+		zr, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid deflate request body: %w", err)
+		}
+		body = zr
+	default:
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+
+	r.Body = http.MaxBytesReader(w, body, h.opts.MaxDecompressedSize)
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return 0, nil
+}
+
+// negotiate returns the preferred encoding accepted by the client, or "".
+func negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range []string{encodingGzip, encodingDeflate} {
+		weight, ok := q[enc]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = enc, weight
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of contentType is worth
+// compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/javascript",
+		"application/xml", "application/yaml", "application/x-yaml":
+		return true
+	}
+	return false
+}
+
+// compressor is implemented by gzip.Writer and zlib.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// responseWriter buffers the start of the body until it knows whether the
+// response is worth compressing: the content type must be compressible and
+// the body at least MinSize bytes long (or flushed before reaching it).
+type responseWriter struct {
+	http.ResponseWriter
+	h        *handler
+	encoding string
+
+	status  int
+	decided bool
+	buf     []byte
+	enc     compressor
+}
+
+func (cw *responseWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.decide(false)
+		return
+	}
+	if n, err := strconv.Atoi(cw.Header().Get("Content-Length")); err == nil && n < cw.h.opts.MinSize {
+		cw.decide(false)
+	}
+}
+
+func (cw *responseWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.h.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered data to the client. A response flushed before its
+// body reached MinSize is still compressed if its content type allows.
+func (cw *responseWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(true)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets handlers take over the connection, as with the underlying
+// writer.
+func (cw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (cw *responseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the headers and the buffered body, compressing if want is
+// set and the response qualifies.
+func (cw *responseWriter) decide(want bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// Sniff now: net/http would otherwise sniff the compressed bytes
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if want && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.enc = cw.h.getCompressor(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close finishes the response once the wrapped handler returns.
+func (cw *responseWriter) close() {
+	if !cw.decided && cw.status != 0 {
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.h.putCompressor(cw.encoding, cw.enc)
+		cw.enc = nil
+	}
+}
+
+func (h *handler) getCompressor(encoding string, w io.Writer) compressor {
+	pool := &h.gzipPool
+	if encoding == encodingDeflate {
+		pool = &h.deflatePool
+	}
+	c := pool.Get().(compressor)
+	c.Reset(w)
+	return c
+}
+
+func (h *handler) putCompressor(encoding string, c compressor) {
+	c.Reset(io.Discard)
+	if encoding == encodingDeflate {
+		h.deflatePool.Put(c)
+		return
+	}
+	h.gzipPool.Put(c)
+}
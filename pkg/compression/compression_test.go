@@ -0,0 +1,174 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestHandler(t *testing.T, next http.HandlerFunc) http.Handler {
+	t.Helper()
+	h, err := Handler(next, Options{MinSize: 16})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	return h
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(body)
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	case "deflate":
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		t.Fatalf("open %s body: %v", encoding, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read %s body: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestHandler_Response(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("x", 64) + `"}`
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{"gzip json", "gzip, deflate", "application/json", large, "gzip"},
+		{"deflate preferred", "gzip;q=0.5, deflate", "application/json", large, "deflate"},
+		{"gzip refused", "gzip;q=0, deflate;q=0", "application/json", large, ""},
+		{"wildcard", "*", "text/plain", large, "gzip"},
+		{"no accept-encoding", "", "application/json", large, ""},
+		{"below min size", "gzip", "application/json", `{}`, ""},
+		{"binary content", "gzip", "application/pdf", large, ""},
+		{"sniffed content type", "gzip", "", large, "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(http.StatusOK)
+				half := len(tt.body) / 2
+				io.WriteString(w, tt.body[:half])
+				io.WriteString(w, tt.body[half:])
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := decode(t, tt.wantEncoding, rec.Body.Bytes()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestHandler_EventStreamNotCompressed(t *testing.T) {
+	h := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "event: response.created\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "event: response.completed\ndata: {}\n\n")
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want none", got)
+	}
+	if !rec.Flushed {
+		t.Error("expected the stream to be flushed")
+	}
+	if !strings.Contains(rec.Body.String(), "response.completed") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestHandler_Request(t *testing.T) {
+	compress := func(encoding, s string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser = gzip.NewWriter(&buf)
+		if encoding == "deflate" {
+			w = zlib.NewWriter(&buf)
+		}
+		io.WriteString(w, s)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		wantStatus int
+		wantBody   string
+	}{
+		{"plain", "", []byte(`{"input":"hi"}`), http.StatusOK, `{"input":"hi"}`},
+		{"gzip", "gzip", compress("gzip", `{"input":"hi"}`), http.StatusOK, `{"input":"hi"}`},
+		{"deflate", "deflate", compress("deflate", `{"input":"hi"}`), http.StatusOK, `{"input":"hi"}`},
+		{"corrupt gzip", "gzip", []byte("not gzip"), http.StatusBadRequest, ""},
+		{"unsupported", "br", []byte("x"), http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Encoding") != "" {
+					t.Error("Content-Encoding not removed from the request")
+				}
+				data, _ := io.ReadAll(r.Body)
+				w.Write(data)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandler_InvalidLevel(t *testing.T) {
+	if _, err := Handler(http.NotFoundHandler(), Options{Level: 12}); err == nil {
+		t.Error("expected an error for level 12")
+	}
+}
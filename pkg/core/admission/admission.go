@@ -0,0 +1,255 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package admission limits the number of responses running against the
+// backend at once, globally and per tenant and API key. When every slot is
+// taken, requests wait in a bounded queue ordered by priority, so that a
+// single noisy client cannot exhaust the backend.
+package admission
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limits configures a Controller. Zero means unlimited for the concurrency
+// limits.
+type Limits struct {
+	MaxConcurrent          int           // responses running at once
+	MaxConcurrentPerTenant int           // responses running or queued per tenant
+	MaxConcurrentPerKey    int           // responses running or queued per API key
+	MaxQueue               int           // requests waiting for a slot; 0 rejects at once
+	QueueTimeout           time.Duration // how long a request waits; 0 waits until it is canceled
+}
+
+// Caller identifies who a request is admitted for. Empty fields are not
+// limited.
+type Caller struct {
+	Tenant string
+	APIKey string
+}
+
+// Reasons a request is refused.
+const (
+	ReasonTenantLimit  = "tenant_limit"  // the tenant has too many responses
+	ReasonKeyLimit     = "key_limit"     // the API key has too many responses
+	ReasonQueueFull    = "queue_full"    // every slot is taken and the queue is full
+	ReasonQueueTimeout = "queue_timeout" // no slot freed up within the queue timeout
+)
+
+// Error is returned when a request is not admitted.
+type Error struct {
+	Reason     string
+	QueueDepth int // requests waiting when the request was refused
+}
+
+func (e *Error) Error() string {
+	switch e.Reason {
+	case ReasonTenantLimit:
+		return "too many concurrent requests for this tenant"
+	case ReasonKeyLimit:
+		return "too many concurrent requests for this API key"
+	case ReasonQueueTimeout:
+		return "timed out waiting for a backend slot"
+	default:
+		return fmt.Sprintf("backend saturated: %d requests queued", e.QueueDepth)
+	}
+}
+
+// CallerLimited reports whether the request was refused because of its
+// caller's own limit, rather than because the backend is saturated.
+func (e *Error) CallerLimited() bool {
+	return e.Reason == ReasonTenantLimit || e.Reason == ReasonKeyLimit
+}
+
+// Controller admits requests within its limits. It is safe for concurrent
+// use.
+type Controller struct {
+	limits Limits
+
+	mu      sync.Mutex
+	running int
+	tenants map[string]int
+	keys    map[string]int
+	queue   waitQueue
+	seq     uint64
+}
+
+// New creates a controller.
+func New(limits Limits) *Controller {
+	return &Controller{
+		limits:  limits,
+		tenants: make(map[string]int),
+		keys:    make(map[string]int),
+	}
+}
+
+// SetLimits replaces the limits. Requests already running keep their
+// slots; slots freed by a higher MaxConcurrent go to the queued requests.
+func (c *Controller) SetLimits(limits Limits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limits = limits
+	for len(c.queue) > 0 && (limits.MaxConcurrent <= 0 || c.running < limits.MaxConcurrent) {
+		w := heap.Pop(&c.queue).(*waiter)
+		close(w.ready)
+		c.running++
+	}
+}
+
+// QueueDepth returns the number of requests waiting for a slot.
+func (c *Controller) QueueDepth() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// Acquire waits for a slot for caller. Requests with a higher priority are
+// served first, and requests of the same priority in arrival order. On
+// success the returned function releases the slot; it must be called once
+// the response is done. Refused requests get an *Error, and requests
+// canceled while queued get the context's error.
+func (c *Controller) Acquire(ctx context.Context, caller Caller, priority int) (func(), error) {
+	c.mu.Lock()
+	if n := c.limits.MaxConcurrentPerTenant; n > 0 && caller.Tenant != "" && c.tenants[caller.Tenant] >= n {
+		depth := len(c.queue)
+		c.mu.Unlock()
+		return nil, &Error{Reason: ReasonTenantLimit, QueueDepth: depth}
+	}
+	if n := c.limits.MaxConcurrentPerKey; n > 0 && caller.APIKey != "" && c.keys[caller.APIKey] >= n {
+		depth := len(c.queue)
+		c.mu.Unlock()
+		return nil, &Error{Reason: ReasonKeyLimit, QueueDepth: depth}
+	}
+
+	// A slot is handed straight to the next waiter when it is released, so
+	// a free slot means nobody is queued
+	if c.limits.MaxConcurrent <= 0 || c.running < c.limits.MaxConcurrent {
+		c.running++
+		c.count(caller, 1)
+		c.mu.Unlock()
+		return c.releaser(caller), nil
+	}
+	if len(c.queue) >= c.limits.MaxQueue {
+		depth := len(c.queue)
+		c.mu.Unlock()
+		return nil, &Error{Reason: ReasonQueueFull, QueueDepth: depth}
+	}
+
+	c.seq++
+	w := &waiter{priority: priority, seq: c.seq, ready: make(chan struct{})}
+	heap.Push(&c.queue, w)
+	c.count(caller, 1)
+	queueTimeout := c.limits.QueueTimeout
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if queueTimeout > 0 {
+		timer := time.NewTimer(queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return c.releaser(caller), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = &Error{Reason: ReasonQueueTimeout}
+	}
+
+	c.mu.Lock()
+	if w.index >= 0 {
+		heap.Remove(&c.queue, w.index)
+		c.count(caller, -1)
+		if e, ok := err.(*Error); ok {
+			e.QueueDepth = len(c.queue)
+		}
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
+	// The slot was handed over as the wait ended: pass it on
+	c.releaser(caller)()
+	return nil, err
+}
+
+// releaser returns the function releasing the slot of caller.
+func (c *Controller) releaser(caller Caller) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.count(caller, -1)
+			// Slots above a lowered limit are not handed over
+			if len(c.queue) > 0 && (c.limits.MaxConcurrent <= 0 || c.running <= c.limits.MaxConcurrent) {
+				w := heap.Pop(&c.queue).(*waiter)
+				close(w.ready)
+				return
+			}
+			c.running--
+		})
+	}
+}
+
+// count adds delta to the responses of caller. c.mu must be held.
+func (c *Controller) count(caller Caller, delta int) {
+	if caller.Tenant != "" {
+		if c.tenants[caller.Tenant] += delta; c.tenants[caller.Tenant] <= 0 {
+			delete(c.tenants, caller.Tenant)
+		}
+	}
+	if caller.APIKey != "" {
+		if c.keys[caller.APIKey] += delta; c.keys[caller.APIKey] <= 0 {
+			delete(c.keys, caller.APIKey)
+		}
+	}
+}
+
+// waiter is a queued request. index is its position in the queue, or -1
+// once it was handed a slot.
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// waitQueue is a heap of waiters, highest priority first, then oldest.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
@@ -0,0 +1,206 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until n requests are queued.
+func waitQueued(t *testing.T, c *Controller, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.QueueDepth() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth = %d, want %d", c.QueueDepth(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAcquire_Limits(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		held       []Caller
+		caller     Caller
+		wantReason string
+	}{
+		{
+			name:   "unlimited",
+			limits: Limits{},
+			held:   []Caller{{Tenant: "a"}, {Tenant: "a"}},
+			caller: Caller{Tenant: "a"},
+		},
+		{
+			name:       "tenant limit",
+			limits:     Limits{MaxConcurrentPerTenant: 1},
+			held:       []Caller{{Tenant: "a"}},
+			caller:     Caller{Tenant: "a"},
+			wantReason: ReasonTenantLimit,
+		},
+		{
+			name:   "other tenant",
+			limits: Limits{MaxConcurrentPerTenant: 1},
+			held:   []Caller{{Tenant: "a"}},
+			caller: Caller{Tenant: "b"},
+		},
+		{
+			name:       "key limit",
+			limits:     Limits{MaxConcurrentPerKey: 1},
+			held:       []Caller{{APIKey: "key_1"}},
+			caller:     Caller{Tenant: "b", APIKey: "key_1"},
+			wantReason: ReasonKeyLimit,
+		},
+		{
+			name:       "saturated without queue",
+			limits:     Limits{MaxConcurrent: 1},
+			held:       []Caller{{}},
+			caller:     Caller{},
+			wantReason: ReasonQueueFull,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.limits)
+			for _, caller := range tt.held {
+				if _, err := c.Acquire(context.Background(), caller, 0); err != nil {
+					t.Fatalf("Acquire(%+v) error = %v", caller, err)
+				}
+			}
+			release, err := c.Acquire(context.Background(), tt.caller, 0)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Acquire() error = %v", err)
+				}
+				release()
+				return
+			}
+			var admitErr *Error
+			if !errors.As(err, &admitErr) || admitErr.Reason != tt.wantReason {
+				t.Errorf("Acquire() error = %v, want reason %s", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAcquire_QueueByPriority(t *testing.T) {
+	c := New(Limits{MaxConcurrent: 1, MaxQueue: 2})
+	release, err := c.Acquire(context.Background(), Caller{}, 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	order := make(chan string, 2)
+	queue := func(name string, priority int) {
+		go func() {
+			r, err := c.Acquire(context.Background(), Caller{}, priority)
+			if err != nil {
+				t.Errorf("Acquire(%s) error = %v", name, err)
+				return
+			}
+			order <- name
+			r()
+		}()
+	}
+	queue("low", 0)
+	waitQueued(t, c, 1)
+	queue("high", 2)
+	waitQueued(t, c, 2)
+
+	var admitErr *Error
+	if _, err := c.Acquire(context.Background(), Caller{}, 5); !errors.As(err, &admitErr) || admitErr.Reason != ReasonQueueFull || admitErr.QueueDepth != 2 {
+		t.Errorf("Acquire() on a full queue error = %v", err)
+	}
+
+	release()
+	release() // releasing twice is a no-op
+	if first, second := <-order, <-order; first != "high" || second != "low" {
+		t.Errorf("served %s then %s, want high then low", first, second)
+	}
+
+	// Every slot was returned
+	r, err := c.Acquire(context.Background(), Caller{}, 0)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	r()
+}
+
+func TestAcquire_LeaveQueue(t *testing.T) {
+	c := New(Limits{MaxConcurrent: 1, MaxQueue: 1, MaxConcurrentPerTenant: 2, QueueTimeout: 20 * time.Millisecond})
+	release, err := c.Acquire(context.Background(), Caller{Tenant: "a"}, 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer release()
+
+	var admitErr *Error
+	if _, err := c.Acquire(context.Background(), Caller{Tenant: "a"}, 0); !errors.As(err, &admitErr) || admitErr.Reason != ReasonQueueTimeout {
+		t.Errorf("Acquire() error = %v, want queue timeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Acquire(ctx, Caller{Tenant: "a"}, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire() error = %v, want context.Canceled", err)
+	}
+
+	// Requests that left the queue no longer count against the tenant
+	if c.QueueDepth() != 0 || c.tenants["a"] != 1 {
+		t.Errorf("queue depth = %d, tenant count = %d", c.QueueDepth(), c.tenants["a"])
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	c := New(Limits{MaxConcurrent: 1, MaxQueue: 2})
+	ctx := context.Background()
+	release, err := c.Acquire(ctx, Caller{}, 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// Raising the limit admits the queued requests
+	admitted := make(chan func(), 2)
+	for range 2 {
+		go func() {
+			r, err := c.Acquire(ctx, Caller{}, 0)
+			if err != nil {
+				t.Errorf("Acquire() error = %v", err)
+				r = func() {}
+			}
+			admitted <- r
+		}()
+	}
+	waitQueued(t, c, 2)
+	c.SetLimits(Limits{MaxConcurrent: 3, MaxQueue: 2})
+	second, third := <-admitted, <-admitted
+
+	// Lowering it keeps the running requests; the next one waits for two
+	// of them to end
+	c.SetLimits(Limits{MaxConcurrent: 1, MaxQueue: 1})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r, err := c.Acquire(ctx, Caller{}, 0)
+		if err != nil {
+			t.Errorf("Acquire() error = %v", err)
+			return
+		}
+		r()
+	}()
+	waitQueued(t, c, 1)
+	release()
+	second()
+	select {
+	case <-done:
+		t.Fatal("request admitted above the lowered limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	third()
+	<-done
+}
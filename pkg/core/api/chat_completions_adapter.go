@@ -7,14 +7,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 )
 
 // ChatCompletionsAdapter implements ResponsesAPIClient by calling /v1/chat/completions
@@ -132,8 +134,10 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 	var responseID string
 	var responseModel string
 	var responseCreated int64
-	accumulatedText := make(map[int]string)                    // output_index → text
+	var accumulatedText strings.Builder                        // text of the message item
+	var accumulatedLogprobs []interface{}                      // logprobs of the text tokens
 	accumulatedToolCalls := make(map[int]*accumulatedToolCall) // tool_call index → accumulated data
+	var accumulatedAudio *ChatCompletionAudio                  // audio of the message item, if any
 	var usage *ChatCompletionUsage
 	var finishReason string
 
@@ -142,23 +146,17 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 	toolCallItemIDs := make(map[int]string) // tool_call index → item ID
 
 	for scanner.Scan() {
-		line := scanner.Text()
-
-		if line == "" {
-			continue
-		}
-
-		if !strings.HasPrefix(line, "data: ") {
+		// The chunk is decoded straight from the scanner's buffer
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data: "))
+		if !ok {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
-		if data == "[DONE]" {
+		if string(data) == "[DONE]" {
 			break
 		}
 
 		var chunk ChatCompletionChunk
-		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		if err := json.Unmarshal(data, &chunk); err != nil {
 			continue
 		}
 
@@ -191,29 +189,41 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 				messageItemID = adapterGenerateID("msg_")
 			}
 
-			accumulatedText[outputIndex] += *delta.Content
+			accumulatedText.WriteString(*delta.Content)
 
 			// Emit response.output_text.delta
-			deltaEvt := map[string]interface{}{
-				"type":          "response.output_text.delta",
-				"output_index":  outputIndex,
-				"content_index": 0,
-				"item_id":       messageItemID,
-				"delta":         *delta.Content,
-				"response_id":   responseID,
+			deltaEvt := &deltaEvent{
+				Type:        "response.output_text.delta",
+				OutputIndex: outputIndex,
+				ItemID:      messageItemID,
+				Delta:       *delta.Content,
+				ResponseID:  responseID,
 			}
-			deltaData, _ := json.Marshal(deltaEvt)
-
-			select {
-			case events <- ResponsesStreamEvent{
-				Type: "response.output_text.delta",
-				Data: deltaData,
-			}:
-			case <-ctx.Done():
+			if logprobs := convertChatLogprobs(choice.Logprobs); len(logprobs) > 0 {
+				accumulatedLogprobs = append(accumulatedLogprobs, logprobs...)
+				deltaEvt.Logprobs = logprobs
+			}
+			if !sendDelta(ctx, events, deltaEvt) {
 				return
 			}
 		}
 
+		// Accumulate audio deltas; the audio is returned whole in the final
+		// response
+		if delta.Audio != nil {
+			if messageItemID == "" {
+				messageItemID = adapterGenerateID("msg_")
+			}
+			if accumulatedAudio == nil {
+				accumulatedAudio = &ChatCompletionAudio{}
+			}
+			if delta.Audio.ID != "" {
+				accumulatedAudio.ID = delta.Audio.ID
+			}
+			accumulatedAudio.Data += delta.Audio.Data
+			accumulatedAudio.Transcript += delta.Audio.Transcript
+		}
+
 		// Process tool call deltas
 		for _, tc := range delta.ToolCalls {
 			idx := 0
@@ -233,36 +243,27 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 			if tc.Function.Name != "" {
 				acc.name = tc.Function.Name
 			}
-			acc.arguments += tc.Function.Arguments
+			acc.arguments.WriteString(tc.Function.Arguments)
 
-			// Assign an item ID for this tool call
+			// Assign an item ID and output index for this tool call. Tool
+			// calls start after the message at index 0, if any.
 			if _, ok := toolCallItemIDs[idx]; !ok {
 				toolCallItemIDs[idx] = adapterGenerateID("fc_")
+				acc.outputIndex = idx
+				if messageItemID != "" {
+					acc.outputIndex = idx + 1
+				}
 			}
 
 			// Emit response.function_call_arguments.delta
 			if tc.Function.Arguments != "" {
-				outputIndex := idx + 1 // tool calls start after the message at index 0
-				if messageItemID == "" && len(accumulatedText) == 0 {
-					// No text output, tool calls start at 0
-					outputIndex = idx
-				}
-
-				deltaEvt := map[string]interface{}{
-					"type":         "response.function_call_arguments.delta",
-					"output_index": outputIndex,
-					"item_id":      toolCallItemIDs[idx],
-					"delta":        tc.Function.Arguments,
-					"response_id":  responseID,
-				}
-				deltaData, _ := json.Marshal(deltaEvt)
-
-				select {
-				case events <- ResponsesStreamEvent{
-					Type: "response.function_call_arguments.delta",
-					Data: deltaData,
-				}:
-				case <-ctx.Done():
+				if !sendDelta(ctx, events, &deltaEvent{
+					Type:        "response.function_call_arguments.delta",
+					OutputIndex: acc.outputIndex,
+					ItemID:      toolCallItemIDs[idx],
+					Delta:       tc.Function.Arguments,
+					ResponseID:  responseID,
+				}) {
 					return
 				}
 			}
@@ -277,11 +278,49 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 	// Build the final ResponsesAPIResponse for response.completed
 	finalResp := buildFinalResponse(
 		responseID, responseModel, responseCreated,
-		messageItemID, accumulatedText,
+		messageItemID, accumulatedText.String(), accumulatedLogprobs, accumulatedAudio,
 		toolCallItemIDs, accumulatedToolCalls,
 		usage, finishReason,
 	)
 
+	// Finalize the tool calls with their accumulated arguments; the OpenAI
+	// SDK relies on these events to complete them
+	for _, item := range finalResp.Output {
+		if item.Type != "function_call" {
+			continue
+		}
+		var outputIndex int
+		for idx, acc := range accumulatedToolCalls {
+			if toolCallItemIDs[idx] == item.ID {
+				outputIndex = acc.outputIndex
+			}
+		}
+		argsDone, _ := json.Marshal(map[string]interface{}{
+			"type":         "response.function_call_arguments.done",
+			"output_index": outputIndex,
+			"item_id":      item.ID,
+			"name":         item.Name,
+			"arguments":    item.Arguments,
+			"response_id":  responseID,
+		})
+		itemDone, _ := json.Marshal(map[string]interface{}{
+			"type":         "response.output_item.done",
+			"output_index": outputIndex,
+			"item":         item,
+			"response_id":  responseID,
+		})
+		for _, evt := range []ResponsesStreamEvent{
+			{Type: "response.function_call_arguments.done", Data: argsDone},
+			{Type: "response.output_item.done", Data: itemDone},
+		} {
+			select {
+			case events <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
 	completedEvt := map[string]interface{}{
 		"type":     "response.completed",
 		"response": finalResp,
@@ -298,13 +337,15 @@ func (a *ChatCompletionsAdapter) processSSEStream(
 }
 
 type accumulatedToolCall struct {
-	id        string
-	name      string
-	arguments string
+	id          string
+	name        string
+	arguments   strings.Builder
+	outputIndex int // output_index of the streamed events
 }
 
 func (a *ChatCompletionsAdapter) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
+	requestid.SetHeader(req)
 	if a.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+a.apiKey)
 	}
@@ -322,10 +363,12 @@ func ConvertToChatRequest(req *ResponsesAPIRequest) *ChatCompletionRequest {
 		ParallelToolCalls: req.ParallelToolCalls,
 		Seed:              req.Seed,
 		Stop:              req.Stop,
+		PromptCacheKey:    req.PromptCacheKey,
+		N:                 req.N,
 	}
 
 	// Handle logprobs
-	if req.TopLogprobs != nil {
+	if req.TopLogprobs != nil || slices.Contains(req.Include, "message.output_text.logprobs") {
 		logprobsTrue := true
 		chatReq.Logprobs = &logprobsTrue
 		chatReq.TopLogprobs = req.TopLogprobs
@@ -342,7 +385,7 @@ func ConvertToChatRequest(req *ResponsesAPIRequest) *ChatCompletionRequest {
 
 	// Convert input to messages
 	messages = append(messages, convertInputToMessages(req.Input)...)
-	chatReq.Messages = messages
+	chatReq.Messages = foldLeadingSystem(messages)
 
 	// Convert tools
 	chatReq.Tools = convertToolsToChatTools(req.Tools)
@@ -357,6 +400,30 @@ func ConvertToChatRequest(req *ResponsesAPIRequest) *ChatCompletionRequest {
 	return chatReq
 }
 
+// foldLeadingSystem merges the system messages that open a conversation,
+// the instructions followed by the developer messages mapped to system, into
+// one: chat templates commonly accept a single system message, first. The
+// merged text keeps the instructions ahead of the developer messages. Later
+// developer messages stay in place.
+func foldLeadingSystem(messages []ChatCompletionMsg) []ChatCompletionMsg {
+	n := 0
+	for n < len(messages) && messages[n].Role == "system" {
+		if _, ok := messages[n].Content.(string); !ok {
+			break
+		}
+		n++
+	}
+	if n < 2 {
+		return messages
+	}
+	parts := make([]string, 0, n)
+	for _, msg := range messages[:n] {
+		parts = append(parts, msg.Content.(string))
+	}
+	folded := ChatCompletionMsg{Role: "system", Content: strings.Join(parts, "\n\n")}
+	return append([]ChatCompletionMsg{folded}, messages[n:]...)
+}
+
 // convertInputToMessages converts Responses API input to Chat Completions messages.
 // Input can be a string, or []interface{} of structured items.
 func convertInputToMessages(input interface{}) []ChatCompletionMsg {
@@ -426,17 +493,18 @@ func convertInputItemsToMessages(items []interface{}) []ChatCompletionMsg {
 			})
 
 		case itemType == "message" || (itemType == "" && role != ""):
-			// Flush any pending tool calls before a new message
-			flushToolCalls()
+			// Flush any pending tool calls before a new message. Items that
+			// produce no message must not split a run of parallel tool calls.
 			msg := convertItemToMessage(itemMap, role)
 			if msg != nil {
+				flushToolCalls()
 				messages = append(messages, *msg)
 			}
 
 		default:
 			// Try simple {role, content} format
-			flushToolCalls()
 			if content, ok := itemMap["content"].(string); ok && content != "" {
+				flushToolCalls()
 				if role == "" {
 					role = "user"
 				}
@@ -541,6 +609,41 @@ func convertItemToMessage(item map[string]interface{}, role string) *ChatComplet
 					File: file,
 				})
 			}
+		case "input_audio":
+			hasNonText = true
+			audio := &ChatCompletionInputAudio{}
+			if audioMap, ok := partMap["input_audio"].(map[string]interface{}); ok {
+				audio.Data, _ = audioMap["data"].(string)
+				audio.Format, _ = audioMap["format"].(string)
+			} else {
+				audio.Data, _ = partMap["data"].(string)
+				audio.Format, _ = partMap["format"].(string)
+			}
+			if audio.Data != "" {
+				contentParts = append(contentParts, ChatCompletionContentPart{
+					Type:       "input_audio",
+					InputAudio: audio,
+				})
+			}
+		case "input_video":
+			hasNonText = true
+			var videoURL string
+			switch v := partMap["video_url"].(type) {
+			case string:
+				videoURL = v
+			case map[string]interface{}:
+				videoURL, _ = v["url"].(string)
+			}
+			if videoURL == "" {
+				videoURL, _ = partMap["url"].(string)
+			}
+			// Chat Completions has no file IDs, so unresolved videos are dropped
+			if videoURL != "" {
+				contentParts = append(contentParts, ChatCompletionContentPart{
+					Type:     "video_url",
+					VideoURL: &ChatCompletionVideoURL{URL: videoURL},
+				})
+			}
 		}
 	}
 
@@ -628,6 +731,17 @@ func ConvertFromChatResponse(chatResp *ChatCompletionResponse) *ResponsesAPIResp
 
 	var output []OutputItem
 
+	if len(chatResp.Choices) > 1 {
+		// Candidates sampled with n > 1
+		candidates, incomplete := convertChatCandidates(chatResp.Choices)
+		if incomplete {
+			resp.Status = "incomplete"
+		}
+		resp.Output = candidates
+		resp.Usage = convertChatUsage(chatResp.Usage)
+		return resp
+	}
+
 	if len(chatResp.Choices) > 0 {
 		choice := chatResp.Choices[0]
 
@@ -639,17 +753,29 @@ func ConvertFromChatResponse(chatResp *ChatCompletionResponse) *ResponsesAPIResp
 			resp.Status = "completed"
 		}
 
-		// Convert text content
+		// Convert text and audio content
+		var content []ContentItem
 		if choice.Message.Content != nil && *choice.Message.Content != "" {
+			content = append(content, ContentItem{
+				Type:     "output_text",
+				Text:     *choice.Message.Content,
+				Logprobs: convertChatLogprobs(choice.Logprobs),
+			})
+		}
+		if audio := choice.Message.Audio; audio != nil {
+			content = append(content, ContentItem{
+				Type:       "output_audio",
+				Data:       audio.Data,
+				Transcript: audio.Transcript,
+			})
+		}
+		if len(content) > 0 {
 			output = append(output, OutputItem{
-				Type:   "message",
-				ID:     adapterGenerateID("msg_"),
-				Role:   "assistant",
-				Status: "completed",
-				Content: []ContentItem{{
-					Type: "output_text",
-					Text: *choice.Message.Content,
-				}},
+				Type:    "message",
+				ID:      adapterGenerateID("msg_"),
+				Role:    "assistant",
+				Status:  "completed",
+				Content: content,
 			})
 		}
 
@@ -668,23 +794,106 @@ func ConvertFromChatResponse(chatResp *ChatCompletionResponse) *ResponsesAPIResp
 
 	resp.Output = output
 
-	// Convert usage
-	if chatResp.Usage != nil {
-		resp.Usage = &UsageInfo{
-			InputTokens:  chatResp.Usage.PromptTokens,
-			OutputTokens: chatResp.Usage.CompletionTokens,
-			TotalTokens:  chatResp.Usage.TotalTokens,
+	resp.Usage = convertChatUsage(chatResp.Usage)
+
+	return resp
+}
+
+// convertChatCandidates converts the choices of a request with n > 1 to one
+// message per choice, tagged with the choice index. It reports whether any
+// choice was cut off by the token limit.
+func convertChatCandidates(choices []ChatCompletionChoice) (output []OutputItem, incomplete bool) {
+	for _, c := range choices {
+		if c.FinishReason == "length" {
+			incomplete = true
 		}
+		text := ""
+		if c.Message.Content != nil {
+			text = *c.Message.Content
+		}
+		index := c.Index
+		output = append(output, OutputItem{
+			Type:   "message",
+			ID:     adapterGenerateID("msg_"),
+			Role:   "assistant",
+			Status: "completed",
+			Content: []ContentItem{{
+				Type:     "output_text",
+				Text:     text,
+				Logprobs: convertChatLogprobs(c.Logprobs),
+			}},
+			CandidateIndex: &index,
+		})
 	}
+	return output, incomplete
+}
 
-	return resp
+// convertChatLogprobs converts Chat Completions token logprobs to the
+// Responses API format, or returns nil if there are none. The formats match
+// field for field, except that bytes and top_logprobs are always present.
+func convertChatLogprobs(lp *ChatCompletionLogprobs) []interface{} {
+	if lp == nil || len(lp.Content) == 0 {
+		return nil
+	}
+	bytesOf := func(b []int) []int {
+		if b == nil {
+			return []int{}
+		}
+		return b
+	}
+	out := make([]interface{}, 0, len(lp.Content))
+	for _, tok := range lp.Content {
+		top := make([]interface{}, 0, len(tok.TopLogprobs))
+		for _, alt := range tok.TopLogprobs {
+			top = append(top, map[string]interface{}{
+				"token":   alt.Token,
+				"logprob": alt.Logprob,
+				"bytes":   bytesOf(alt.Bytes),
+			})
+		}
+		out = append(out, map[string]interface{}{
+			"token":        tok.Token,
+			"logprob":      tok.Logprob,
+			"bytes":        bytesOf(tok.Bytes),
+			"top_logprobs": top,
+		})
+	}
+	return out
+}
+
+// convertChatUsage converts Chat Completions usage, including the prompt
+// tokens served from the backend's prefix cache, or returns nil.
+func convertChatUsage(usage *ChatCompletionUsage) *UsageInfo {
+	if usage == nil {
+		return nil
+	}
+	u := &UsageInfo{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		u.InputTokensDetails = &InputTokensDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+			AudioTokens:  usage.PromptTokensDetails.AudioTokens,
+		}
+	}
+	if usage.CompletionTokensDetails != nil {
+		u.OutputTokensDetails = &OutputTokensDetails{
+			ReasoningTokens: usage.CompletionTokensDetails.ReasoningTokens,
+			AudioTokens:     usage.CompletionTokensDetails.AudioTokens,
+		}
+	}
+	return u
 }
 
 // buildFinalResponse constructs the final ResponsesAPIResponse from accumulated stream data.
 func buildFinalResponse(
 	responseID, model string, created int64,
 	messageItemID string,
-	accumulatedText map[int]string,
+	text string,
+	logprobs []interface{},
+	audio *ChatCompletionAudio,
 	toolCallItemIDs map[int]string,
 	accumulatedToolCalls map[int]*accumulatedToolCall,
 	usage *ChatCompletionUsage,
@@ -705,20 +914,32 @@ func buildFinalResponse(
 
 	var output []OutputItem
 
-	// Add text output
-	if text, ok := accumulatedText[0]; ok && text != "" {
+	// Add text and audio output
+	var content []ContentItem
+	if text != "" {
+		content = append(content, ContentItem{
+			Type:     "output_text",
+			Text:     text,
+			Logprobs: logprobs,
+		})
+	}
+	if audio != nil {
+		content = append(content, ContentItem{
+			Type:       "output_audio",
+			Data:       audio.Data,
+			Transcript: audio.Transcript,
+		})
+	}
+	if len(content) > 0 {
 		if messageItemID == "" {
 			messageItemID = adapterGenerateID("msg_")
 		}
 		output = append(output, OutputItem{
-			Type:   "message",
-			ID:     messageItemID,
-			Role:   "assistant",
-			Status: "completed",
-			Content: []ContentItem{{
-				Type: "output_text",
-				Text: text,
-			}},
+			Type:    "message",
+			ID:      messageItemID,
+			Role:    "assistant",
+			Status:  "completed",
+			Content: content,
 		})
 	}
 
@@ -737,21 +958,14 @@ func buildFinalResponse(
 			ID:        itemID,
 			CallID:    tc.id,
 			Name:      tc.name,
-			Arguments: tc.arguments,
+			Arguments: tc.arguments.String(),
 			Status:    "completed",
 		})
 	}
 
 	resp.Output = output
 
-	// Convert usage
-	if usage != nil {
-		resp.Usage = &UsageInfo{
-			InputTokens:  usage.PromptTokens,
-			OutputTokens: usage.CompletionTokens,
-			TotalTokens:  usage.TotalTokens,
-		}
-	}
+	resp.Usage = convertChatUsage(usage)
 
 	if resp.CreatedAt == 0 {
 		resp.CreatedAt = float64(time.Now().Unix())
@@ -760,9 +974,7 @@ func buildFinalResponse(
 	return resp
 }
 
-// adapterGenerateID generates a random ID with the given prefix.
+// adapterGenerateID generates an ID with the given prefix.
 func adapterGenerateID(prefix string) string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return prefix + hex.EncodeToString(b)
+	return ids.Default.NewID(prefix)
 }
@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -44,6 +47,30 @@ func TestConvertToChatRequest_BasicText(t *testing.T) {
 	}
 }
 
+func TestConvertToChatRequest_DeveloperMessages(t *testing.T) {
+	instructions := "You are a helpful assistant."
+	req := &ResponsesAPIRequest{
+		Model:        "gpt-4",
+		Instructions: &instructions,
+		Input: []interface{}{
+			map[string]interface{}{"role": "developer", "content": "Answer in French."},
+			map[string]interface{}{"role": "user", "content": "Hello"},
+			map[string]interface{}{"role": "developer", "content": "Be brief."},
+		},
+	}
+
+	chatReq := ConvertToChatRequest(req)
+
+	want := []ChatCompletionMsg{
+		{Role: "system", Content: "You are a helpful assistant.\n\nAnswer in French."},
+		{Role: "user", Content: "Hello"},
+		{Role: "system", Content: "Be brief."},
+	}
+	if !reflect.DeepEqual(chatReq.Messages, want) {
+		t.Errorf("messages = %+v, want %+v", chatReq.Messages, want)
+	}
+}
+
 func TestConvertToChatRequest_NoInstructions(t *testing.T) {
 	req := &ResponsesAPIRequest{
 		Model: "gpt-4",
@@ -67,6 +94,7 @@ func TestConvertToChatRequest_SamplingParams(t *testing.T) {
 	freqPenalty := 0.5
 	presPenalty := 0.3
 	topLogprobs := 5
+	cacheKey := "pc_123"
 
 	req := &ResponsesAPIRequest{
 		Model:            "gpt-4",
@@ -77,6 +105,7 @@ func TestConvertToChatRequest_SamplingParams(t *testing.T) {
 		FrequencyPenalty: &freqPenalty,
 		PresencePenalty:  &presPenalty,
 		TopLogprobs:      &topLogprobs,
+		PromptCacheKey:   &cacheKey,
 	}
 
 	chatReq := ConvertToChatRequest(req)
@@ -102,6 +131,29 @@ func TestConvertToChatRequest_SamplingParams(t *testing.T) {
 	if chatReq.TopLogprobs == nil || *chatReq.TopLogprobs != 5 {
 		t.Errorf("expected top_logprobs 5, got %v", chatReq.TopLogprobs)
 	}
+	if chatReq.PromptCacheKey == nil || *chatReq.PromptCacheKey != "pc_123" {
+		t.Errorf("expected prompt_cache_key pc_123, got %v", chatReq.PromptCacheKey)
+	}
+}
+
+func TestConvertToChatRequest_LogprobsFromInclude(t *testing.T) {
+	req := &ResponsesAPIRequest{
+		Model:   "gpt-4",
+		Input:   "Hello",
+		Include: []string{"message.output_text.logprobs"},
+	}
+	chatReq := ConvertToChatRequest(req)
+	if chatReq.Logprobs == nil || !*chatReq.Logprobs {
+		t.Error("expected logprobs true")
+	}
+	if chatReq.TopLogprobs != nil {
+		t.Errorf("expected no top_logprobs, got %v", *chatReq.TopLogprobs)
+	}
+
+	req.Include = nil
+	if chatReq := ConvertToChatRequest(req); chatReq.Logprobs != nil {
+		t.Error("expected logprobs unset")
+	}
 }
 
 func TestConvertToChatRequest_Tools(t *testing.T) {
@@ -498,6 +550,72 @@ func TestConvertInputToMessages_FileInput(t *testing.T) {
 	}
 }
 
+func TestConvertInputToMessages_AudioInput(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_text", "text": "Transcribe this."},
+				map[string]interface{}{
+					"type":        "input_audio",
+					"input_audio": map[string]interface{}{"data": "UklGRg==", "format": "wav"},
+				},
+				// Top-level fields are accepted too
+				map[string]interface{}{"type": "input_audio", "data": "SUQz", "format": "mp3"},
+			},
+		},
+	}
+
+	msgs := convertInputToMessages(input)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	parts, ok := msgs[0].Content.([]ChatCompletionContentPart)
+	if !ok {
+		t.Fatalf("expected []ChatCompletionContentPart, got %T", msgs[0].Content)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+	want := []ChatCompletionInputAudio{{Data: "UklGRg==", Format: "wav"}, {Data: "SUQz", Format: "mp3"}}
+	for i, w := range want {
+		part := parts[i+1]
+		if part.Type != "input_audio" || part.InputAudio == nil || *part.InputAudio != w {
+			t.Errorf("part %d = %+v, want input_audio %+v", i+1, part, w)
+		}
+	}
+}
+
+func TestConvertInputToMessages_VideoInput(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_video", "video_url": "https://example.com/a.mp4"},
+				// Unresolved file IDs cannot be sent
+				map[string]interface{}{"type": "input_video", "file_id": "file_clip"},
+			},
+		},
+	}
+
+	msgs := convertInputToMessages(input)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	parts, ok := msgs[0].Content.([]ChatCompletionContentPart)
+	if !ok {
+		t.Fatalf("expected []ChatCompletionContentPart, got %T", msgs[0].Content)
+	}
+	if len(parts) != 1 || parts[0].Type != "video_url" || parts[0].VideoURL == nil {
+		t.Fatalf("expected one video_url part, got %+v", parts)
+	}
+	if parts[0].VideoURL.URL != "https://example.com/a.mp4" {
+		t.Errorf("expected video URL, got %s", parts[0].VideoURL.URL)
+	}
+}
+
 func TestConvertInputToMessages_FileInputByID(t *testing.T) {
 	input := []interface{}{
 		map[string]interface{}{
@@ -674,6 +792,229 @@ func TestConvertFromChatResponse_LengthFinish(t *testing.T) {
 	}
 }
 
+func TestConvertFromChatResponse_CachedTokens(t *testing.T) {
+	content := "hi"
+	chatResp := &ChatCompletionResponse{
+		ID:      "chatcmpl-cached",
+		Model:   "gpt-4",
+		Choices: []ChatCompletionChoice{{FinishReason: "stop", Message: ChatCompletionChoiceMsg{Role: "assistant", Content: &content}}},
+		Usage: &ChatCompletionUsage{
+			PromptTokens:        2048,
+			CompletionTokens:    1,
+			TotalTokens:         2049,
+			PromptTokensDetails: &ChatPromptTokensDetails{CachedTokens: 1920},
+		},
+	}
+
+	resp := ConvertFromChatResponse(chatResp)
+
+	if got := resp.Usage.CachedTokens(); got != 1920 {
+		t.Errorf("expected 1920 cached tokens, got %d", got)
+	}
+
+	chatResp.Usage.PromptTokensDetails = nil
+	if got := ConvertFromChatResponse(chatResp).Usage.CachedTokens(); got != 0 {
+		t.Errorf("expected 0 cached tokens without details, got %d", got)
+	}
+	if got := (*UsageInfo)(nil).CachedTokens(); got != 0 {
+		t.Errorf("expected 0 cached tokens for nil usage, got %d", got)
+	}
+}
+
+func TestConvertFromChatResponse_Logprobs(t *testing.T) {
+	content := "Hi"
+	chatResp := &ChatCompletionResponse{
+		ID:    "chatcmpl-logprobs",
+		Model: "gpt-4",
+		Choices: []ChatCompletionChoice{{
+			FinishReason: "stop",
+			Message:      ChatCompletionChoiceMsg{Role: "assistant", Content: &content},
+			Logprobs: &ChatCompletionLogprobs{Content: []ChatCompletionTokenLogprob{{
+				Token:       "Hi",
+				Logprob:     -0.25,
+				Bytes:       []int{72, 105},
+				TopLogprobs: []ChatCompletionTopLogprob{{Token: "Hi", Logprob: -0.25}, {Token: "Hey", Logprob: -1.5}},
+			}}},
+		}},
+	}
+
+	resp := ConvertFromChatResponse(chatResp)
+
+	logprobs := resp.Output[0].Content[0].Logprobs
+	if len(logprobs) != 1 {
+		t.Fatalf("expected 1 logprob, got %d", len(logprobs))
+	}
+	lp := logprobs[0].(map[string]interface{})
+	if lp["token"] != "Hi" || lp["logprob"] != -0.25 {
+		t.Errorf("unexpected logprob %v", lp)
+	}
+	if top := lp["top_logprobs"].([]interface{}); len(top) != 2 || top[1].(map[string]interface{})["token"] != "Hey" {
+		t.Errorf("unexpected top_logprobs %v", lp["top_logprobs"])
+	}
+
+	chatResp.Choices[0].Logprobs = nil
+	if got := ConvertFromChatResponse(chatResp).Output[0].Content[0].Logprobs; got != nil {
+		t.Errorf("expected no logprobs, got %v", got)
+	}
+}
+
+func TestConvertFromChatResponse_Candidates(t *testing.T) {
+	first, second := "Hi", "Hello"
+	chatResp := &ChatCompletionResponse{
+		ID:    "chatcmpl-n",
+		Model: "gpt-4",
+		Choices: []ChatCompletionChoice{
+			{Index: 0, FinishReason: "stop", Message: ChatCompletionChoiceMsg{Role: "assistant", Content: &first}},
+			{Index: 1, FinishReason: "length", Message: ChatCompletionChoiceMsg{Role: "assistant", Content: &second}},
+		},
+		Usage: &ChatCompletionUsage{PromptTokens: 5, CompletionTokens: 4, TotalTokens: 9},
+	}
+
+	resp := ConvertFromChatResponse(chatResp)
+	if resp.Status != "incomplete" {
+		t.Errorf("expected status incomplete, got %q", resp.Status)
+	}
+	if len(resp.Output) != 2 {
+		t.Fatalf("expected 2 output items, got %d", len(resp.Output))
+	}
+	for i, want := range []string{"Hi", "Hello"} {
+		item := resp.Output[i]
+		if item.CandidateIndex == nil || *item.CandidateIndex != i {
+			t.Errorf("output[%d] candidate index = %v, want %d", i, item.CandidateIndex, i)
+		}
+		if item.Content[0].Text != want {
+			t.Errorf("output[%d] text = %q, want %q", i, item.Content[0].Text, want)
+		}
+	}
+	if resp.Usage == nil || resp.Usage.OutputTokens != 4 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	n := 2
+	if chatReq := ConvertToChatRequest(&ResponsesAPIRequest{Model: "gpt-4", Input: "Hi", N: &n}); chatReq.N == nil || *chatReq.N != 2 {
+		t.Errorf("expected n=2 in chat request, got %v", chatReq.N)
+	}
+}
+
+func TestProcessSSEStream_Logprobs(t *testing.T) {
+	stream := `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hel"},"logprobs":{"content":[{"token":"Hel","logprob":-0.1,"top_logprobs":[]}]}}]}
+
+data: {"id":"c1","choices":[{"index":0,"delta":{"content":"lo"},"logprobs":{"content":[{"token":"lo","logprob":-0.2,"top_logprobs":[{"token":"lo","logprob":-0.2}]}]}}]}
+
+data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+`
+	events := make(chan ResponsesStreamEvent, 10)
+	adapter := NewChatCompletionsAdapter("http://unused", "")
+	adapter.processSSEStream(context.Background(), strings.NewReader(stream), "model", events)
+	close(events)
+
+	var deltaLogprobs int
+	var final ResponsesAPIResponse
+	for evt := range events {
+		switch evt.Type {
+		case "response.output_text.delta":
+			var delta struct {
+				Logprobs []map[string]interface{} `json:"logprobs"`
+			}
+			if err := json.Unmarshal(evt.Data, &delta); err != nil {
+				t.Fatalf("unmarshal delta: %v", err)
+			}
+			deltaLogprobs += len(delta.Logprobs)
+		case "response.completed":
+			var wrapper struct {
+				Response ResponsesAPIResponse `json:"response"`
+			}
+			if err := json.Unmarshal(evt.Data, &wrapper); err != nil {
+				t.Fatalf("unmarshal completed: %v", err)
+			}
+			final = wrapper.Response
+		}
+	}
+
+	if deltaLogprobs != 2 {
+		t.Errorf("expected 2 logprobs across deltas, got %d", deltaLogprobs)
+	}
+	if len(final.Output) != 1 || len(final.Output[0].Content[0].Logprobs) != 2 {
+		t.Fatalf("expected 2 logprobs on the final output text, got %+v", final.Output)
+	}
+	if lp := final.Output[0].Content[0].Logprobs[1].(map[string]interface{}); lp["token"] != "lo" {
+		t.Errorf("unexpected last logprob %v", lp)
+	}
+}
+
+func TestConvertFromChatResponse_Audio(t *testing.T) {
+	chatResp := &ChatCompletionResponse{
+		ID:    "chatcmpl-audio",
+		Model: "gpt-4o-audio-preview",
+		Choices: []ChatCompletionChoice{{FinishReason: "stop", Message: ChatCompletionChoiceMsg{
+			Role:  "assistant",
+			Audio: &ChatCompletionAudio{ID: "audio_1", Data: "UklGRg==", Transcript: "Hello there."},
+		}}},
+		Usage: &ChatCompletionUsage{
+			PromptTokens:            120,
+			CompletionTokens:        80,
+			TotalTokens:             200,
+			PromptTokensDetails:     &ChatPromptTokensDetails{AudioTokens: 100},
+			CompletionTokensDetails: &ChatCompletionTokensDetails{AudioTokens: 70},
+		},
+	}
+
+	resp := ConvertFromChatResponse(chatResp)
+
+	if len(resp.Output) != 1 || len(resp.Output[0].Content) != 1 {
+		t.Fatalf("expected one message with one part, got %+v", resp.Output)
+	}
+	part := resp.Output[0].Content[0]
+	if part.Type != "output_audio" || part.Data != "UklGRg==" || part.Transcript != "Hello there." {
+		t.Errorf("unexpected audio part %+v", part)
+	}
+	if got := resp.Usage.InputAudioTokens(); got != 100 {
+		t.Errorf("expected 100 input audio tokens, got %d", got)
+	}
+	if got := resp.Usage.OutputAudioTokens(); got != 70 {
+		t.Errorf("expected 70 output audio tokens, got %d", got)
+	}
+}
+
+func TestProcessSSEStream_Audio(t *testing.T) {
+	stream := `data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","audio":{"id":"audio_1","transcript":"Hel"}}}]}
+
+data: {"id":"c1","choices":[{"index":0,"delta":{"audio":{"data":"UklG","transcript":"lo"}}}]}
+
+data: {"id":"c1","choices":[{"index":0,"delta":{"audio":{"data":"Rg=="}},"finish_reason":"stop"}]}
+
+data: [DONE]
+`
+	events := make(chan ResponsesStreamEvent, 10)
+	adapter := NewChatCompletionsAdapter("http://unused", "")
+	adapter.processSSEStream(context.Background(), strings.NewReader(stream), "model", events)
+	close(events)
+
+	var final ResponsesAPIResponse
+	for evt := range events {
+		if evt.Type != "response.completed" {
+			continue
+		}
+		var wrapper struct {
+			Response ResponsesAPIResponse `json:"response"`
+		}
+		if err := json.Unmarshal(evt.Data, &wrapper); err != nil {
+			t.Fatalf("unmarshal completed: %v", err)
+		}
+		final = wrapper.Response
+	}
+
+	if len(final.Output) != 1 || len(final.Output[0].Content) != 1 {
+		t.Fatalf("expected one message with one part, got %+v", final.Output)
+	}
+	part := final.Output[0].Content[0]
+	if part.Type != "output_audio" || part.Data != "UklGRg==" || part.Transcript != "Hello" {
+		t.Errorf("unexpected audio part %+v", part)
+	}
+}
+
 func TestConvertFromChatResponse_MultipleToolCalls(t *testing.T) {
 	chatResp := &ChatCompletionResponse{
 		ID:      "chatcmpl-multi",
@@ -1139,6 +1480,34 @@ func TestCreateResponseStream_ToolCalls(t *testing.T) {
 		t.Errorf("expected 2 function_call_arguments.delta events, got %d", argDeltaCount)
 	}
 
+	// Verify the tool call is finalized before response.completed
+	var types []string
+	for _, evt := range events {
+		types = append(types, evt.Type)
+	}
+	wantTail := []string{"response.function_call_arguments.done", "response.output_item.done", "response.completed"}
+	if len(types) < 3 || !slices.Equal(types[len(types)-3:], wantTail) {
+		t.Fatalf("event types = %v, want them to end with %v", types, wantTail)
+	}
+	var delta, argsDone struct {
+		ItemID      string `json:"item_id"`
+		OutputIndex int    `json:"output_index"`
+		Name        string `json:"name"`
+		Arguments   string `json:"arguments"`
+	}
+	json.Unmarshal(events[len(events)-4].Data, &delta)
+	json.Unmarshal(events[len(events)-3].Data, &argsDone)
+	if argsDone.ItemID != delta.ItemID || argsDone.OutputIndex != 0 || argsDone.Name != "get_weather" || argsDone.Arguments != `{"location":"NYC"}` {
+		t.Errorf("function_call_arguments.done = %+v, delta item %s", argsDone, delta.ItemID)
+	}
+	var itemDone struct {
+		Item OutputItem `json:"item"`
+	}
+	json.Unmarshal(events[len(events)-2].Data, &itemDone)
+	if itemDone.Item.ID != delta.ItemID || itemDone.Item.CallID != "call_abc" || itemDone.Item.Status != "completed" || itemDone.Item.Arguments != `{"location":"NYC"}` {
+		t.Errorf("output_item.done item = %+v", itemDone.Item)
+	}
+
 	// Verify completed event has the tool call
 	var completedEvt *ResponsesStreamEvent
 	for i, evt := range events {
@@ -1280,3 +1649,25 @@ func TestConvertInputToMessages_Nil(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+// BenchmarkProcessSSEStream measures the per-token cost of translating a
+// Chat Completions stream.
+func BenchmarkProcessSSEStream(b *testing.B) {
+	const tokens = 1000
+	var stream strings.Builder
+	for i := 0; i < tokens; i++ {
+		stream.WriteString(`data: {"id":"c1","model":"m","choices":[{"index":0,"delta":{"content":"token "}}]}` + "\n\n")
+	}
+	stream.WriteString(`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\ndata: [DONE]\n\n")
+	data := stream.String()
+	adapter := NewChatCompletionsAdapter("http://unused", "")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		events := make(chan ResponsesStreamEvent, tokens+10)
+		adapter.processSSEStream(context.Background(), strings.NewReader(data), "m", events)
+		close(events)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*tokens), "ns/token")
+}
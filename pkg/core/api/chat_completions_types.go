@@ -21,6 +21,8 @@ type ChatCompletionRequest struct {
 	Seed              *int                 `json:"seed,omitempty"`
 	Stop              interface{}          `json:"stop,omitempty"`
 	StreamOptions     *ChatStreamOptions   `json:"stream_options,omitempty"`
+	PromptCacheKey    *string              `json:"prompt_cache_key,omitempty"`
+	N                 *int                 `json:"n,omitempty"`
 }
 
 // ChatCompletionMsg represents a message in the Chat Completions API.
@@ -33,10 +35,12 @@ type ChatCompletionMsg struct {
 
 // ChatCompletionContentPart represents a content part in a multimodal message.
 type ChatCompletionContentPart struct {
-	Type     string                  `json:"type"` // "text", "image_url", "file"
-	Text     string                  `json:"text,omitempty"`
-	ImageURL *ChatCompletionImageURL `json:"image_url,omitempty"`
-	File     *ChatCompletionFile     `json:"file,omitempty"`
+	Type       string                    `json:"type"` // "text", "image_url", "file", "input_audio", "video_url"
+	Text       string                    `json:"text,omitempty"`
+	ImageURL   *ChatCompletionImageURL   `json:"image_url,omitempty"`
+	File       *ChatCompletionFile       `json:"file,omitempty"`
+	InputAudio *ChatCompletionInputAudio `json:"input_audio,omitempty"`
+	VideoURL   *ChatCompletionVideoURL   `json:"video_url,omitempty"`
 }
 
 // ChatCompletionImageURL represents an image URL in a content part.
@@ -52,6 +56,18 @@ type ChatCompletionFile struct {
 	Filename string `json:"filename,omitempty"`
 }
 
+// ChatCompletionInputAudio represents an audio clip in a content part.
+type ChatCompletionInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// ChatCompletionVideoURL represents a video URL in a content part, as
+// accepted by vLLM for video models.
+type ChatCompletionVideoURL struct {
+	URL string `json:"url"`
+}
+
 // ChatCompletionTool represents a tool definition for Chat Completions.
 type ChatCompletionTool struct {
 	Type     string                     `json:"type"` // "function"
@@ -96,6 +112,7 @@ type ChatCompletionChoice struct {
 	Index        int                     `json:"index"`
 	Message      ChatCompletionChoiceMsg `json:"message"`
 	FinishReason string                  `json:"finish_reason"`
+	Logprobs     *ChatCompletionLogprobs `json:"logprobs,omitempty"`
 }
 
 // ChatCompletionChoiceMsg is the message inside a non-streaming choice.
@@ -103,6 +120,17 @@ type ChatCompletionChoiceMsg struct {
 	Role      string                   `json:"role"`
 	Content   *string                  `json:"content,omitempty"`
 	ToolCalls []ChatCompletionToolCall `json:"tool_calls,omitempty"`
+	Audio     *ChatCompletionAudio     `json:"audio,omitempty"`
+}
+
+// ChatCompletionAudio is the audio of a message, returned when the audio
+// output modality is requested. In streaming chunks each field carries a
+// fragment.
+type ChatCompletionAudio struct {
+	ID         string `json:"id,omitempty"`
+	Data       string `json:"data,omitempty"`       // Base64-encoded audio
+	Transcript string `json:"transcript,omitempty"` // Text of the audio
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
 }
 
 // ChatCompletionChunk represents a streaming chunk from /v1/chat/completions.
@@ -120,6 +148,7 @@ type ChatCompletionChunkChoice struct {
 	Index        int                      `json:"index"`
 	Delta        ChatCompletionChunkDelta `json:"delta"`
 	FinishReason *string                  `json:"finish_reason,omitempty"`
+	Logprobs     *ChatCompletionLogprobs  `json:"logprobs,omitempty"`
 }
 
 // ChatCompletionChunkDelta represents the delta content in a streaming chunk.
@@ -127,13 +156,50 @@ type ChatCompletionChunkDelta struct {
 	Role      string                   `json:"role,omitempty"`
 	Content   *string                  `json:"content,omitempty"`
 	ToolCalls []ChatCompletionToolCall `json:"tool_calls,omitempty"`
+	Audio     *ChatCompletionAudio     `json:"audio,omitempty"`
+}
+
+// ChatCompletionLogprobs holds the log probabilities of the content tokens
+// of a choice or chunk, returned when logprobs are requested.
+type ChatCompletionLogprobs struct {
+	Content []ChatCompletionTokenLogprob `json:"content"`
+}
+
+// ChatCompletionTokenLogprob is the log probability of a sampled token and
+// of the most likely alternatives.
+type ChatCompletionTokenLogprob struct {
+	Token       string                     `json:"token"`
+	Logprob     float64                    `json:"logprob"`
+	Bytes       []int                      `json:"bytes,omitempty"`
+	TopLogprobs []ChatCompletionTopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// ChatCompletionTopLogprob is an alternative token at a position.
+type ChatCompletionTopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // ChatCompletionUsage represents token usage in a Chat Completions response.
 type ChatCompletionUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                          `json:"prompt_tokens"`
+	CompletionTokens        int                          `json:"completion_tokens"`
+	TotalTokens             int                          `json:"total_tokens"`
+	PromptTokensDetails     *ChatPromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *ChatCompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// ChatPromptTokensDetails breaks down the prompt tokens.
+type ChatPromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+	AudioTokens  int `json:"audio_tokens,omitempty"`
+}
+
+// ChatCompletionTokensDetails breaks down the completion tokens.
+type ChatCompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	AudioTokens     int `json:"audio_tokens,omitempty"`
 }
 
 // ChatStreamOptions controls streaming behavior.
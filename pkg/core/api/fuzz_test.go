@@ -0,0 +1,120 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// collectEvents runs fn with a fresh event channel and returns every event
+// it emitted.
+func collectEvents(fn func(events chan<- ResponsesStreamEvent)) []ResponsesStreamEvent {
+	events := make(chan ResponsesStreamEvent)
+	go func() {
+		defer close(events)
+		fn(events)
+	}()
+	var out []ResponsesStreamEvent
+	for evt := range events {
+		out = append(out, evt)
+	}
+	return out
+}
+
+func FuzzReadResponsesSSE(f *testing.F) {
+	f.Add("event: response.output_text.delta\ndata: {\"delta\":\"hi\"}\n\n")
+	f.Add("data: {}\n\ndata: [DONE]\n\ndata: {}\n")
+	f.Add("event: response.completed\n\n\ndata: \n")
+	f.Add(":comment\nevent:x\ndata:{}\n")
+
+	f.Fuzz(func(t *testing.T, stream string) {
+		got := collectEvents(func(events chan<- ResponsesStreamEvent) {
+			readResponsesSSE(context.Background(), strings.NewReader(stream), events)
+		})
+
+		for _, evt := range got {
+			data := string(evt.Data)
+			if data == "[DONE]" {
+				t.Fatalf("[DONE] sentinel forwarded as an event")
+			}
+			if strings.Contains(data, "\n") || strings.Contains(evt.Type, "\n") {
+				t.Fatalf("event spans multiple lines: %+v", evt)
+			}
+			if !strings.Contains(stream, "data: "+data) {
+				t.Fatalf("event data %q not present in stream", data)
+			}
+		}
+	})
+}
+
+func FuzzProcessSSEStream(f *testing.F) {
+	f.Add(`data: {"id":"c1","model":"m","choices":[{"delta":{"content":"Hi"}}]}` + "\n\ndata: [DONE]\n")
+	f.Add(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"f","arguments":"{\"a\":"}}]}}]}` + "\n" +
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]},"finish_reason":"tool_calls"}]}` + "\n")
+	f.Add(`data: {"choices":[{"delta":{"tool_calls":[{"index":-1}]}}]}` + "\n")
+	f.Add("data: not json\n")
+
+	adapter := NewChatCompletionsAdapter("http://localhost/v1", "")
+
+	f.Fuzz(func(t *testing.T, stream string) {
+		got := collectEvents(func(events chan<- ResponsesStreamEvent) {
+			adapter.processSSEStream(context.Background(), strings.NewReader(stream), "model", events)
+		})
+
+		if len(got) == 0 {
+			t.Fatal("expected at least a response.completed event")
+		}
+		for i, evt := range got {
+			if !json.Valid(evt.Data) {
+				t.Fatalf("event %d (%s) has invalid JSON: %s", i, evt.Type, evt.Data)
+			}
+			isLast := i == len(got)-1
+			if (evt.Type == "response.completed") != isLast {
+				t.Fatalf("response.completed must be emitted exactly once, last; got %s at %d/%d", evt.Type, i, len(got))
+			}
+		}
+	})
+}
+
+func FuzzConvertInputItemsToMessages(f *testing.F) {
+	f.Add(`[{"type":"message","role":"user","content":"hi"}]`)
+	f.Add(`[{"type":"function_call","call_id":"c1","name":"f","arguments":"{}"},{"type":"function_call","call_id":"c2","name":"g"},{"type":"function_call_output","call_id":"c1","output":"ok"}]`)
+	f.Add(`[{"role":"developer","content":[{"type":"input_text","text":"x"},{"type":"input_image","image_url":"http://i"}]}]`)
+	f.Add(`[1,"a",null,{"content":"bare"}]`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(input), &items); err != nil {
+			t.Skip()
+		}
+
+		wantCalls := 0
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok && m["type"] == "function_call" {
+				wantCalls++
+			}
+		}
+
+		msgs := convertInputItemsToMessages(items)
+
+		gotCalls := 0
+		for i, msg := range msgs {
+			if msg.Role == "" || msg.Role == "developer" {
+				t.Fatalf("message %d has invalid role %q", i, msg.Role)
+			}
+			if len(msg.ToolCalls) > 0 {
+				if i > 0 && len(msgs[i-1].ToolCalls) > 0 {
+					t.Fatalf("consecutive tool-call messages at %d were not merged", i)
+				}
+				gotCalls += len(msg.ToolCalls)
+			}
+		}
+		if gotCalls != wantCalls {
+			t.Fatalf("got %d tool calls, want %d", gotCalls, wantCalls)
+		}
+	})
+}
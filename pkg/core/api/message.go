@@ -10,20 +10,36 @@ type Message struct {
 	ContentParts []MessageContentPart `json:"content_parts,omitempty"` // Multimodal content parts (takes precedence over Content when non-empty)
 	ToolCalls    []ToolCall           `json:"tool_calls,omitempty"`    // Tool calls (assistant messages)
 	ToolCallID   string               `json:"tool_call_id,omitempty"`  // Tool call ID (tool messages)
+	Reasoning    *Reasoning           `json:"reasoning,omitempty"`     // Reasoning item (assistant messages)
+}
+
+// Reasoning is a reasoning item produced by a reasoning model. It is replayed
+// to the backend on later turns so the model keeps its chain of thought
+// across tool calls.
+type Reasoning struct {
+	ID               string   `json:"id"`
+	Summary          []string `json:"summary,omitempty"`           // Summary texts
+	Text             string   `json:"text,omitempty"`              // Raw reasoning text (vLLM)
+	EncryptedContent string   `json:"encrypted_content,omitempty"` // Opaque reasoning state (OpenAI)
 }
 
 // MessageContentPart represents a content part in a multimodal message.
 type MessageContentPart struct {
-	Type     string           `json:"type"`                // "text", "image_url", "file"
-	Text     string           `json:"text,omitempty"`      // Text content (when Type="text")
-	ImageURL *MessageImageURL `json:"image_url,omitempty"` // Image URL (when Type="image_url")
-	File     *MessageFile     `json:"file,omitempty"`      // File content (when Type="file")
+	Type       string           `json:"type"`                  // "text", "image_url", "file", "input_audio", "video_url"
+	Text       string           `json:"text,omitempty"`        // Text content (when Type="text")
+	ImageURL   *MessageImageURL `json:"image_url,omitempty"`   // Image URL (when Type="image_url")
+	File       *MessageFile     `json:"file,omitempty"`        // File content (when Type="file")
+	InputAudio *MessageAudio    `json:"input_audio,omitempty"` // Audio clip (when Type="input_audio")
+	VideoURL   *MessageVideoURL `json:"video_url,omitempty"`   // Video (when Type="video_url")
 }
 
-// MessageImageURL represents an image URL in a content part.
+// MessageImageURL represents an image in a content part, given by URL or
+// by the ID of an uploaded file, which the engine resolves to a data URL
+// before calling the backend.
 type MessageImageURL struct {
 	URL    string `json:"url"`
 	Detail string `json:"detail,omitempty"` // "auto", "low", "high"
+	FileID string `json:"file_id,omitempty"`
 }
 
 // MessageFile represents a file in a content part.
@@ -33,6 +49,20 @@ type MessageFile struct {
 	Filename string `json:"filename,omitempty"`
 }
 
+// MessageAudio represents an audio clip in a content part.
+type MessageAudio struct {
+	Data   string `json:"data"`   // Base64-encoded audio
+	Format string `json:"format"` // "wav", "mp3"
+}
+
+// MessageVideoURL represents a video in a content part, given by URL or by
+// the ID of an uploaded file, which the engine resolves to a URL before
+// calling the backend.
+type MessageVideoURL struct {
+	URL    string `json:"url,omitempty"`
+	FileID string `json:"file_id,omitempty"`
+}
+
 // ToolCall represents a tool call made by the assistant.
 type ToolCall struct {
 	ID       string           `json:"id"`
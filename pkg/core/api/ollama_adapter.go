@@ -0,0 +1,574 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// maxOllamaImageBytes limits the size of images fetched by URL to send them
+// inline to Ollama.
+const maxOllamaImageBytes = 20 << 20
+
+// OllamaOptions configures an OllamaAdapter.
+type OllamaOptions struct {
+	// AutoPull pulls a model the first time Ollama reports it missing, then
+	// retries the request.
+	AutoPull bool
+	// KeepAlive is how long Ollama keeps the model loaded after a request,
+	// e.g. "10m" or "-1" for ever. Empty uses Ollama's default.
+	KeepAlive string
+}
+
+// OllamaAdapter implements ResponsesAPIClient by calling Ollama's native
+// /api/chat endpoint, which unlike its OpenAI-compatible endpoint supports
+// keep_alive and model pulls. Images are sent inline as base64, as Ollama
+// requires.
+type OllamaAdapter struct {
+	baseURL    string // e.g. "http://localhost:11434"
+	apiKey     string
+	opts       OllamaOptions
+	httpClient *http.Client
+
+	pullMu sync.Mutex // serializes pulls, so a model is pulled once
+	pulled map[string]bool
+}
+
+// NewOllamaAdapter creates a new Ollama adapter. baseURL is the Ollama
+// server; a trailing /v1 is ignored, so the endpoint used with the
+// OpenAI-compatible API works too.
+func NewOllamaAdapter(baseURL, apiKey string, opts OllamaOptions) *OllamaAdapter {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &OllamaAdapter{
+		baseURL:    strings.TrimSuffix(baseURL, "/v1"),
+		apiKey:     apiKey,
+		opts:       opts,
+		httpClient: &http.Client{},
+		pulled:     make(map[string]bool),
+	}
+}
+
+// ollamaChatRequest is the body of POST /api/chat.
+type ollamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []ollamaMessage        `json:"messages"`
+	Tools     []ChatCompletionTool   `json:"tools,omitempty"`
+	Format    json.RawMessage        `json:"format,omitempty"` // "json" or a JSON schema
+	Options   map[string]interface{} `json:"options,omitempty"`
+	Think     *bool                  `json:"think,omitempty"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // base64, without a data: prefix
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // tool messages
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // an object, not a string
+	} `json:"function"`
+}
+
+// ollamaChatResponse is the non-streaming response, and each line of the
+// streaming response.
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// CreateResponse sends a non-streaming request to /api/chat and converts
+// the response back to ResponsesAPIResponse.
+func (a *OllamaAdapter) CreateResponse(ctx context.Context, req *ResponsesAPIRequest) (*ResponsesAPIResponse, error) {
+	ollamaReq, err := a.convertRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.chat(ctx, ollamaReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ollama response: %w", err)
+	}
+	if chatResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chatResp.Error)
+	}
+
+	var acc ollamaAccumulator
+	acc.add(&chatResp)
+	return acc.response(), nil
+}
+
+// CreateResponseStream sends a streaming request to /api/chat and converts
+// the newline-delimited JSON stream into ResponsesStreamEvent events.
+func (a *OllamaAdapter) CreateResponseStream(ctx context.Context, req *ResponsesAPIRequest) (<-chan ResponsesStreamEvent, error) {
+	ollamaReq, err := a.convertRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.chat(ctx, ollamaReq)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ResponsesStreamEvent, 10)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		processOllamaStream(ctx, resp.Body, events)
+	}()
+	return events, nil
+}
+
+// chat posts req to /api/chat. When the model is missing and AutoPull is
+// set, it pulls the model and retries once.
+func (a *OllamaAdapter) chat(ctx context.Context, req *ollamaChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ollama request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := a.post(ctx, "/api/chat", body)
+		if err != nil {
+			return nil, fmt.Errorf("request to backend failed: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound && a.opts.AutoPull && attempt == 0 {
+			if err := a.pull(ctx, req.Model); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, fmt.Errorf("backend returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+}
+
+// pull downloads model, unless it was already pulled by this adapter.
+func (a *OllamaAdapter) pull(ctx context.Context, model string) error {
+	a.pullMu.Lock()
+	defer a.pullMu.Unlock()
+	if a.pulled[model] {
+		return nil
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"model": model, "stream": false})
+	resp, err := a.post(ctx, "/api/pull", body)
+	if err != nil {
+		return fmt.Errorf("pull model %s: %w", model, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(respBody, &result)
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		if result.Error == "" {
+			result.Error = string(respBody)
+		}
+		return fmt.Errorf("pull model %s: status %d: %s", model, resp.StatusCode, result.Error)
+	}
+	a.pulled[model] = true
+	return nil
+}
+
+func (a *OllamaAdapter) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	requestid.SetHeader(httpReq)
+	if a.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+	return a.httpClient.Do(httpReq)
+}
+
+// convertRequest converts a ResponsesAPIRequest to an Ollama chat request,
+// going through the Chat Completions conversion of the input.
+func (a *OllamaAdapter) convertRequest(ctx context.Context, req *ResponsesAPIRequest, stream bool) (*ollamaChatRequest, error) {
+	chatReq := ConvertToChatRequest(req)
+	out := &ollamaChatRequest{
+		Model:     req.Model,
+		Tools:     chatReq.Tools,
+		Stream:    stream,
+		KeepAlive: a.opts.KeepAlive,
+		Options:   make(map[string]interface{}),
+	}
+
+	for name, v := range map[string]interface{}{
+		"temperature":       req.Temperature,
+		"top_p":             req.TopP,
+		"num_predict":       req.MaxOutputTokens,
+		"seed":              req.Seed,
+		"frequency_penalty": req.FrequencyPenalty,
+		"presence_penalty":  req.PresencePenalty,
+	} {
+		switch p := v.(type) {
+		case *float64:
+			if p != nil {
+				out.Options[name] = *p
+			}
+		case *int:
+			if p != nil {
+				out.Options[name] = *p
+			}
+		}
+	}
+	switch stop := req.Stop.(type) {
+	case string:
+		out.Options["stop"] = []string{stop}
+	case []string, []interface{}:
+		out.Options["stop"] = stop
+	}
+	if len(out.Options) == 0 {
+		out.Options = nil
+	}
+
+	out.Format = ollamaFormat(req.Text)
+	if req.Reasoning != nil && req.Reasoning.Effort != nil {
+		think := *req.Reasoning.Effort != "none"
+		out.Think = &think
+	}
+
+	// Tool messages carry the tool name rather than the call ID
+	toolNames := make(map[string]string)
+	for _, msg := range chatReq.Messages {
+		m, err := a.convertMessage(ctx, msg, toolNames)
+		if err != nil {
+			return nil, err
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out, nil
+}
+
+// convertMessage converts a Chat Completions message, fetching the images
+// it references by URL.
+func (a *OllamaAdapter) convertMessage(ctx context.Context, msg ChatCompletionMsg, toolNames map[string]string) (ollamaMessage, error) {
+	out := ollamaMessage{Role: msg.Role}
+	switch content := msg.Content.(type) {
+	case string:
+		out.Content = content
+	case []ChatCompletionContentPart:
+		var text []string
+		for _, part := range content {
+			switch part.Type {
+			case "text":
+				text = append(text, part.Text)
+			case "image_url":
+				image, err := a.loadImage(ctx, part.ImageURL.URL)
+				if err != nil {
+					return out, err
+				}
+				out.Images = append(out.Images, image)
+			}
+			// Ollama has no file, audio or video input, so those parts are dropped
+		}
+		out.Content = strings.Join(text, " ")
+	}
+
+	for _, tc := range msg.ToolCalls {
+		var call ollamaToolCall
+		call.Function.Name = tc.Function.Name
+		call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+		if !json.Valid(call.Function.Arguments) {
+			call.Function.Arguments = json.RawMessage(`{}`)
+		}
+		out.ToolCalls = append(out.ToolCalls, call)
+		toolNames[tc.ID] = tc.Function.Name
+	}
+	if msg.Role == "tool" {
+		out.ToolName = toolNames[msg.ToolCallID]
+	}
+	return out, nil
+}
+
+// loadImage returns the base64 data of an image given as a data: URL or
+// fetched from an http(s) URL.
+func (a *OllamaAdapter) loadImage(ctx context.Context, url string) (string, error) {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		_, data, found := strings.Cut(rest, ";base64,")
+		if !found {
+			return "", errors.New("image data URL must be base64-encoded")
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid image URL: %w", err)
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOllamaImageBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("fetch image: %w", err)
+	}
+	if len(data) > maxOllamaImageBytes {
+		return "", fmt.Errorf("image larger than %d bytes", maxOllamaImageBytes)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// ollamaFormat converts the text format of the request: json_object to
+// "json", and json_schema to its schema.
+func ollamaFormat(text interface{}) json.RawMessage {
+	if text == nil {
+		return nil
+	}
+	data, err := json.Marshal(text)
+	if err != nil {
+		return nil
+	}
+	var field struct {
+		Format struct {
+			Type   string          `json:"type"`
+			Schema json.RawMessage `json:"schema"`
+		} `json:"format"`
+	}
+	if json.Unmarshal(data, &field) != nil {
+		return nil
+	}
+	switch field.Format.Type {
+	case "json_object":
+		return json.RawMessage(`"json"`)
+	case "json_schema":
+		if len(field.Format.Schema) > 0 {
+			return field.Format.Schema
+		}
+		return json.RawMessage(`"json"`)
+	}
+	return nil
+}
+
+// processOllamaStream reads the newline-delimited JSON stream of /api/chat
+// and emits the ResponsesStreamEvent events the engine expects.
+func processOllamaStream(ctx context.Context, body io.Reader, events chan<- ResponsesStreamEvent) {
+	send := func(evtType string, fields map[string]interface{}) bool {
+		fields["type"] = evtType
+		data, _ := json.Marshal(fields)
+		select {
+		case events <- ResponsesStreamEvent{Type: evtType, Data: data}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var acc ollamaAccumulator
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != "" {
+			send("response.failed", map[string]interface{}{
+				"response": map[string]interface{}{
+					"status": "failed",
+					"error":  map[string]string{"type": "server_error", "message": chunk.Error},
+				},
+			})
+			return
+		}
+
+		first := len(acc.calls)
+		acc.add(&chunk)
+		if chunk.Message.Thinking != "" {
+			if !sendDelta(ctx, events, &deltaEvent{
+				Type:        "response.reasoning_text.delta",
+				OutputIndex: acc.reasoningIndex,
+				ItemID:      acc.reasoningID,
+				Delta:       chunk.Message.Thinking,
+			}) {
+				return
+			}
+		}
+		if chunk.Message.Content != "" {
+			if !sendDelta(ctx, events, &deltaEvent{
+				Type:        "response.output_text.delta",
+				OutputIndex: acc.messageIndex,
+				ItemID:      acc.messageID,
+				Delta:       chunk.Message.Content,
+			}) {
+				return
+			}
+		}
+		// Ollama sends each tool call whole
+		for _, call := range acc.calls[first:] {
+			if !sendDelta(ctx, events, &deltaEvent{
+				Type:        "response.function_call_arguments.delta",
+				OutputIndex: call.index,
+				ItemID:      call.item.ID,
+				Delta:       call.item.Arguments,
+			}) {
+				return
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+
+	send("response.completed", map[string]interface{}{"response": acc.response()})
+}
+
+// ollamaAccumulator builds a ResponsesAPIResponse from the chunks of a
+// stream, or from a single non-streaming response. Output items are
+// indexed in the order they first appear.
+type ollamaAccumulator struct {
+	model      string
+	created    time.Time
+	doneReason string
+	usage      *UsageInfo
+	next       int // output index of the next new item
+
+	reasoning      strings.Builder
+	reasoningID    string
+	reasoningIndex int
+	text           strings.Builder
+	messageID      string
+	messageIndex   int
+	calls          []ollamaCall
+}
+
+type ollamaCall struct {
+	index int
+	item  OutputItem
+}
+
+func (acc *ollamaAccumulator) add(chunk *ollamaChatResponse) {
+	if acc.model == "" {
+		acc.model, acc.created = chunk.Model, chunk.CreatedAt
+	}
+	if chunk.Message.Thinking != "" {
+		if acc.reasoningID == "" {
+			acc.reasoningID, acc.reasoningIndex = adapterGenerateID("rs_"), acc.next
+			acc.next++
+		}
+		acc.reasoning.WriteString(chunk.Message.Thinking)
+	}
+	if chunk.Message.Content != "" {
+		if acc.messageID == "" {
+			acc.messageID, acc.messageIndex = adapterGenerateID("msg_"), acc.next
+			acc.next++
+		}
+		acc.text.WriteString(chunk.Message.Content)
+	}
+	for _, tc := range chunk.Message.ToolCalls {
+		args := string(tc.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		acc.calls = append(acc.calls, ollamaCall{index: acc.next, item: OutputItem{
+			Type:      "function_call",
+			ID:        adapterGenerateID("fc_"),
+			CallID:    adapterGenerateID("call_"),
+			Name:      tc.Function.Name,
+			Arguments: args,
+			Status:    "completed",
+		}})
+		acc.next++
+	}
+	if chunk.Done {
+		acc.doneReason = chunk.DoneReason
+		acc.usage = &UsageInfo{
+			InputTokens:  chunk.PromptEvalCount,
+			OutputTokens: chunk.EvalCount,
+			TotalTokens:  chunk.PromptEvalCount + chunk.EvalCount,
+		}
+	}
+}
+
+func (acc *ollamaAccumulator) response() *ResponsesAPIResponse {
+	resp := &ResponsesAPIResponse{
+		ID:        adapterGenerateID("resp_"),
+		Object:    "response",
+		Model:     acc.model,
+		CreatedAt: float64(acc.created.Unix()),
+		Status:    "completed",
+		Usage:     acc.usage,
+	}
+	if acc.created.IsZero() {
+		resp.CreatedAt = float64(time.Now().Unix())
+	}
+	if acc.doneReason == "length" {
+		resp.Status = "incomplete"
+	}
+
+	output := make([]OutputItem, acc.next)
+	if acc.reasoningID != "" {
+		output[acc.reasoningIndex] = OutputItem{
+			Type:    "reasoning",
+			ID:      acc.reasoningID,
+			Status:  "completed",
+			Content: []ContentItem{{Type: "reasoning_text", Text: acc.reasoning.String()}},
+		}
+	}
+	if acc.messageID != "" {
+		output[acc.messageIndex] = OutputItem{
+			Type:    "message",
+			ID:      acc.messageID,
+			Role:    "assistant",
+			Status:  "completed",
+			Content: []ContentItem{{Type: "output_text", Text: acc.text.String()}},
+		}
+	}
+	for _, call := range acc.calls {
+		output[call.index] = call.item
+	}
+	resp.Output = output
+	return resp
+}
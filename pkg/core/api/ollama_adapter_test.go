@@ -0,0 +1,179 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaConvertRequest(t *testing.T) {
+	temp := 0.2
+	maxTokens := 50
+	effort := "high"
+	instructions := "be brief"
+	req := &ResponsesAPIRequest{
+		Model:           "llama3.2",
+		Instructions:    &instructions,
+		Temperature:     &temp,
+		MaxOutputTokens: &maxTokens,
+		Stop:            "END",
+		Reasoning:       &ReasoningParam{Effort: &effort},
+		Text:            map[string]interface{}{"format": map[string]interface{}{"type": "json_object"}},
+		Input: []interface{}{
+			map[string]interface{}{"type": "message", "role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "input_text", "text": "what is this?"},
+				map[string]interface{}{"type": "input_image", "image_url": "data:image/png;base64,iVBORw0KGgo="},
+			}},
+			map[string]interface{}{"type": "function_call", "call_id": "call_1", "name": "lookup", "arguments": `{"q":"cat"}`},
+			map[string]interface{}{"type": "function_call_output", "call_id": "call_1", "output": "a cat"},
+		},
+	}
+
+	a := NewOllamaAdapter("http://localhost:11434/v1/", "", OllamaOptions{KeepAlive: "30m"})
+	if a.baseURL != "http://localhost:11434" {
+		t.Errorf("baseURL = %q", a.baseURL)
+	}
+	got, err := a.convertRequest(context.Background(), req, true)
+	if err != nil {
+		t.Fatalf("convertRequest: %v", err)
+	}
+
+	data, _ := json.Marshal(got)
+	want := `{"model":"llama3.2","messages":[` +
+		`{"role":"system","content":"be brief"},` +
+		`{"role":"user","content":"what is this?","images":["iVBORw0KGgo="]},` +
+		`{"role":"assistant","content":"","tool_calls":[{"function":{"name":"lookup","arguments":{"q":"cat"}}}]},` +
+		`{"role":"tool","content":"a cat","tool_name":"lookup"}],` +
+		`"format":"json","options":{"num_predict":50,"stop":["END"],"temperature":0.2},` +
+		`"think":true,"stream":true,"keep_alive":"30m"}`
+	if string(data) != want {
+		t.Errorf("request =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestOllamaCreateResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"model":"llama3.2","created_at":"2026-01-02T03:04:05Z","done":true,"done_reason":"stop",
+			"message":{"role":"assistant","content":"","thinking":"hmm","tool_calls":[{"function":{"name":"lookup","arguments":{"q":"cat"}}}]},
+			"prompt_eval_count":12,"eval_count":4}`)
+	}))
+	defer srv.Close()
+
+	resp, err := NewOllamaAdapter(srv.URL, "", OllamaOptions{}).CreateResponse(context.Background(), &ResponsesAPIRequest{Model: "llama3.2", Input: "hi"})
+	if err != nil {
+		t.Fatalf("CreateResponse: %v", err)
+	}
+	if resp.Status != "completed" || resp.Model != "llama3.2" || resp.CreatedAt != 1767323045 {
+		t.Errorf("resp = %+v", resp)
+	}
+	if len(resp.Output) != 2 || resp.Output[0].Type != "reasoning" || resp.Output[0].Content[0].Text != "hmm" {
+		t.Fatalf("output = %+v", resp.Output)
+	}
+	if call := resp.Output[1]; call.Type != "function_call" || call.Name != "lookup" || call.Arguments != `{"q":"cat"}` || call.CallID == "" {
+		t.Errorf("function call = %+v", call)
+	}
+	if resp.Usage.InputTokens != 12 || resp.Usage.OutputTokens != 4 || resp.Usage.TotalTokens != 16 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOllamaCreateResponseStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range []string{
+			`{"model":"llama3.2","message":{"role":"assistant","content":"Hel"},"done":false}`,
+			`{"model":"llama3.2","message":{"role":"assistant","content":"lo"},"done":false}`,
+			`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer srv.Close()
+
+	events, err := NewOllamaAdapter(srv.URL, "", OllamaOptions{}).CreateResponseStream(context.Background(), &ResponsesAPIRequest{Model: "llama3.2", Input: "hi"})
+	if err != nil {
+		t.Fatalf("CreateResponseStream: %v", err)
+	}
+	var deltas string
+	var final *ResponsesAPIResponse
+	for evt := range events {
+		switch evt.Type {
+		case "response.output_text.delta":
+			var fields struct {
+				Delta string `json:"delta"`
+			}
+			json.Unmarshal(evt.Data, &fields)
+			deltas += fields.Delta
+		case "response.completed":
+			var wrapper struct {
+				Response ResponsesAPIResponse `json:"response"`
+			}
+			json.Unmarshal(evt.Data, &wrapper)
+			final = &wrapper.Response
+		default:
+			t.Errorf("unexpected event %s", evt.Type)
+		}
+	}
+	if deltas != "Hello" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if final == nil || final.Status != "incomplete" || final.Output[0].Content[0].Text != "Hello" || final.Usage.TotalTokens != 5 {
+		t.Errorf("final = %+v", final)
+	}
+}
+
+func TestOllamaAutoPull(t *testing.T) {
+	tests := []struct {
+		name      string
+		autoPull  bool
+		wantPulls int
+		wantErr   bool
+	}{
+		{name: "pull and retry", autoPull: true, wantPulls: 1},
+		{name: "disabled", autoPull: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/pull":
+					var body struct {
+						Model string `json:"model"`
+					}
+					json.NewDecoder(r.Body).Decode(&body)
+					if body.Model != "llama3.2" {
+						t.Errorf("pulled %q", body.Model)
+					}
+					pulls++
+					fmt.Fprint(w, `{"status":"success"}`)
+				case "/api/chat":
+					if pulls == 0 {
+						w.WriteHeader(http.StatusNotFound)
+						fmt.Fprint(w, `{"error":"model \"llama3.2\" not found, try pulling it first"}`)
+						return
+					}
+					fmt.Fprint(w, `{"model":"llama3.2","message":{"role":"assistant","content":"hi"},"done":true}`)
+				}
+			}))
+			defer srv.Close()
+
+			a := NewOllamaAdapter(srv.URL, "", OllamaOptions{AutoPull: tt.autoPull})
+			_, err := a.CreateResponse(context.Background(), &ResponsesAPIRequest{Model: "llama3.2", Input: "hi"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateResponse error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pulls != tt.wantPulls {
+				t.Errorf("pulls = %d, want %d", pulls, tt.wantPulls)
+			}
+		})
+	}
+}
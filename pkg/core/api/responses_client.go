@@ -39,6 +39,10 @@ type ResponsesAPIRequest struct {
 	TopLogprobs       *int            `json:"top_logprobs,omitempty"`
 	Seed              *int            `json:"seed,omitempty"`
 	Stop              interface{}     `json:"stop,omitempty"`
+	PromptCacheKey    *string         `json:"prompt_cache_key,omitempty"`
+	// N is the number of candidates to sample. Only the chat completions
+	// adapter honors it; other clients must be called once per candidate.
+	N *int `json:"n,omitempty"`
 }
 
 // ToolParam defines a function tool sent to the backend.
@@ -80,6 +84,14 @@ type OutputItem struct {
 	CallID    string        `json:"call_id,omitempty"`
 	Status    string        `json:"status,omitempty"`
 	Output    string        `json:"output,omitempty"`
+
+	// CandidateIndex is set when several candidates were sampled.
+	CandidateIndex *int `json:"candidate_index,omitempty"`
+
+	// Reasoning fields (type="reasoning"). The reasoning text is in
+	// Content as "reasoning_text" parts.
+	Summary          []ContentItem `json:"summary,omitempty"`
+	EncryptedContent string        `json:"encrypted_content,omitempty"`
 }
 
 // ContentItem represents a content element within an output item.
@@ -87,13 +99,68 @@ type ContentItem struct {
 	Type     string        `json:"type"`
 	Text     string        `json:"text,omitempty"`
 	Logprobs []interface{} `json:"logprobs,omitempty"`
+
+	// Audio fields (type="output_audio").
+	Data       string `json:"data,omitempty"`       // Base64-encoded audio
+	Format     string `json:"format,omitempty"`     // "wav", "mp3"
+	Transcript string `json:"transcript,omitempty"` // Text of the audio
 }
 
 // UsageInfo represents token usage from the backend.
 type UsageInfo struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
-	TotalTokens  int `json:"total_tokens"`
+	InputTokens         int                  `json:"input_tokens"`
+	OutputTokens        int                  `json:"output_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	InputTokensDetails  *InputTokensDetails  `json:"input_tokens_details,omitempty"`
+	OutputTokensDetails *OutputTokensDetails `json:"output_tokens_details,omitempty"`
+}
+
+// InputTokensDetails breaks down the input tokens reported by the backend.
+type InputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`          // served from the backend's prompt cache
+	AudioTokens  int `json:"audio_tokens,omitempty"` // spent on input audio
+}
+
+// OutputTokensDetails breaks down the output tokens reported by the backend.
+type OutputTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`       // spent on reasoning items
+	AudioTokens     int `json:"audio_tokens,omitempty"` // spent on output audio
+}
+
+// CachedTokens returns the input tokens served from the backend's prompt
+// cache, or 0 if the backend did not report them.
+func (u *UsageInfo) CachedTokens() int {
+	if u == nil || u.InputTokensDetails == nil {
+		return 0
+	}
+	return u.InputTokensDetails.CachedTokens
+}
+
+// ReasoningTokens returns the output tokens spent on reasoning, or 0 if the
+// backend did not report them.
+func (u *UsageInfo) ReasoningTokens() int {
+	if u == nil || u.OutputTokensDetails == nil {
+		return 0
+	}
+	return u.OutputTokensDetails.ReasoningTokens
+}
+
+// InputAudioTokens returns the input tokens spent on audio, or 0 if the
+// backend did not report them.
+func (u *UsageInfo) InputAudioTokens() int {
+	if u == nil || u.InputTokensDetails == nil {
+		return 0
+	}
+	return u.InputTokensDetails.AudioTokens
+}
+
+// OutputAudioTokens returns the output tokens spent on audio, or 0 if the
+// backend did not report them.
+func (u *UsageInfo) OutputAudioTokens() int {
+	if u == nil || u.OutputTokensDetails == nil {
+		return 0
+	}
+	return u.OutputTokensDetails.AudioTokens
 }
 
 // ResponsesStreamEvent represents a single SSE event from the backend.
@@ -102,3 +169,28 @@ type ResponsesStreamEvent struct {
 	Type string          // SSE event type, e.g. "response.output_text.delta"
 	Data json.RawMessage // raw JSON payload
 }
+
+// deltaEvent is a text, reasoning or function call arguments delta emitted
+// by the adapters. They send one per token, so it is a struct rather than
+// a map to keep encoding it cheap.
+type deltaEvent struct {
+	Type         string        `json:"type"`
+	OutputIndex  int           `json:"output_index"`
+	ContentIndex int           `json:"content_index"`
+	ItemID       string        `json:"item_id"`
+	Delta        string        `json:"delta"`
+	ResponseID   string        `json:"response_id,omitempty"`
+	Logprobs     []interface{} `json:"logprobs,omitempty"`
+}
+
+// sendDelta encodes and sends a delta event. It returns false if ctx is
+// cancelled first.
+func sendDelta(ctx context.Context, events chan<- ResponsesStreamEvent, evt *deltaEvent) bool {
+	data, _ := json.Marshal(evt)
+	select {
+	case events <- ResponsesStreamEvent{Type: evt.Type, Data: data}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
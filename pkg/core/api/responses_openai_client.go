@@ -12,6 +12,8 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 )
 
 // OpenAIResponsesClient implements ResponsesAPIClient using net/http.
@@ -107,53 +109,61 @@ func (c *OpenAIResponsesClient) CreateResponseStream(ctx context.Context, req *R
 		defer close(events)
 		defer resp.Body.Close()
 
-		scanner := bufio.NewScanner(resp.Body)
-		// Increase max token size for large SSE payloads
-		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		readResponsesSSE(ctx, resp.Body, events)
+	}()
 
-		var eventType string
+	return events, nil
+}
 
-		for scanner.Scan() {
-			line := scanner.Text()
+// readResponsesSSE parses a /v1/responses SSE stream and forwards each data
+// line as a ResponsesStreamEvent tagged with the preceding event type. It
+// returns at end of input, on "data: [DONE]", or when ctx is cancelled.
+func readResponsesSSE(ctx context.Context, r io.Reader, events chan<- ResponsesStreamEvent) {
+	scanner := bufio.NewScanner(r)
+	// Increase max token size for large SSE payloads
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var eventType string
+
+	for scanner.Scan() {
+		// Bytes avoids a string per line; only the data that is forwarded
+		// is copied out of the scanner's buffer
+		line := scanner.Bytes()
+
+		// Empty line signals end of an event
+		if len(line) == 0 {
+			eventType = ""
+			continue
+		}
 
-			// Empty line signals end of an event
-			if line == "" {
-				eventType = ""
-				continue
-			}
+		if data, ok := bytes.CutPrefix(line, []byte("event: ")); ok {
+			eventType = string(data)
+			continue
+		}
 
-			if strings.HasPrefix(line, "event: ") {
-				eventType = strings.TrimPrefix(line, "event: ")
-				continue
+		if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+			// [DONE] signals end of stream
+			if string(data) == "[DONE]" {
+				return
 			}
 
-			if strings.HasPrefix(line, "data: ") {
-				data := strings.TrimPrefix(line, "data: ")
-
-				// [DONE] signals end of stream
-				if data == "[DONE]" {
-					return
-				}
-
-				evt := ResponsesStreamEvent{
-					Type: eventType,
-					Data: json.RawMessage(data),
-				}
+			evt := ResponsesStreamEvent{
+				Type: eventType,
+				Data: json.RawMessage(bytes.Clone(data)),
+			}
 
-				select {
-				case events <- evt:
-				case <-ctx.Done():
-					return
-				}
+			select {
+			case events <- evt:
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
-
-	return events, nil
+	}
 }
 
 func (c *OpenAIResponsesClient) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
+	requestid.SetHeader(req)
 	if c.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
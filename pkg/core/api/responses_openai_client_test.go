@@ -11,6 +11,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 )
 
 func TestCreateResponse_Success(t *testing.T) {
@@ -46,6 +48,9 @@ func TestCreateResponse_Success(t *testing.T) {
 		if auth := r.Header.Get("Authorization"); auth != "Bearer test-key" {
 			t.Errorf("expected Authorization Bearer test-key, got %s", auth)
 		}
+		if id := r.Header.Get("X-Request-ID"); id != "req_test" {
+			t.Errorf("expected X-Request-ID req_test, got %s", id)
+		}
 
 		// Verify request body
 		var req ResponsesAPIRequest
@@ -65,7 +70,7 @@ func TestCreateResponse_Success(t *testing.T) {
 	defer srv.Close()
 
 	client := NewOpenAIResponsesClient(srv.URL+"/v1", "test-key")
-	got, err := client.CreateResponse(context.Background(), &ResponsesAPIRequest{
+	got, err := client.CreateResponse(requestid.WithID(context.Background(), "req_test"), &ResponsesAPIRequest{
 		Model: "test-model",
 		Input: "Hello",
 	})
@@ -0,0 +1,228 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package apierror defines the errors returned to clients: the Open
+// Responses error object, its HTTP status, and the mapping of internal
+// failures to them. Every endpoint writes its errors with Write, so that
+// they share one envelope:
+//
+//	{"error": {"type": "...", "code": "...", "param": null, "message": "...", "request_id": "..."}}
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/core/admission"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// Types of errors, as defined by the Open Responses specification. The type
+// is the broad category; the code tells errors of a type apart.
+const (
+	TypeInvalidRequest  = "invalid_request"
+	TypeNotFound        = "not_found"
+	TypeTooManyRequests = "too_many_requests"
+	TypeServerError     = "server_error"
+	TypeModelError      = "model_error"
+)
+
+// Codes of errors with a meaning beyond their endpoint. Handlers use more
+// specific codes, such as "file_not_found", for their own errors.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeInvalidParameter   = "invalid_parameter"
+	CodeRequestTooLarge    = "request_too_large"
+	CodeRequestRejected    = "request_rejected"
+	CodeConversationLocked = "conversation_locked"
+	CodeRateLimitExceeded  = "rate_limit_exceeded"
+	CodeServerOverloaded   = "server_overloaded"
+	CodeServerShuttingDown = "server_shutting_down"
+	CodeNotImplemented     = "not_implemented"
+	CodeServerError        = "server_error"
+	CodeRequestTimeout     = "request_timeout"
+
+	// Failures of the services a response depends on
+	CodeBackendError      = "backend_error"
+	CodeBackendTimeout    = "backend_timeout"
+	CodeConversationError = "conversation_error"
+	CodeModerationError   = "moderation_error"
+	CodeMCPError          = "mcp_error"
+	CodeVectorStoreError  = "vector_store_error"
+	CodeFileStoreError    = "file_store_error"
+	CodeStoreError        = "store_error"
+)
+
+// Error is an error returned to a client.
+type Error struct {
+	Status  int    // HTTP status
+	Type    string // one of the Type constants
+	Code    string
+	Param   string // the request field at fault, if any
+	Message string
+	Err     error // the underlying error, if any
+}
+
+// New returns an error with an HTTP status and the type of that status.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Type: TypeFor(status), Code: code, Message: message}
+}
+
+// Wrap returns an error with an HTTP status, the type of that status and
+// the message of err.
+func Wrap(status int, code string, err error) *Error {
+	return &Error{Status: status, Type: TypeFor(status), Code: code, Message: err.Error(), Err: err}
+}
+
+// Backend returns the error of a failed backend call: backend_timeout if
+// it ran out of time, else backend_error.
+func Backend(err error) *Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Status: http.StatusGatewayTimeout, Type: TypeModelError, Code: CodeBackendTimeout, Message: err.Error(), Err: err}
+	}
+	return &Error{Status: http.StatusBadGateway, Type: TypeModelError, Code: CodeBackendError, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Field returns the error object of e, as set on a failed response or an
+// error event.
+func (e *Error) Field() schema.ErrorField {
+	field := schema.ErrorField{Type: e.Type, Message: e.Message}
+	if e.Code != "" {
+		code := e.Code
+		field.Code = &code
+	}
+	if e.Param != "" {
+		param := e.Param
+		field.Param = &param
+	}
+	return field
+}
+
+// Failed returns the error of a failed response, for endpoints that answer
+// with an error rather than the response: 400 for refused input, 502 or
+// 504 for backend failures, else 500.
+func Failed(field *schema.ErrorField) *Error {
+	e := &Error{Status: http.StatusInternalServerError, Type: field.Type, Message: field.Message}
+	if field.Code != nil {
+		e.Code = *field.Code
+	}
+	if field.Param != nil {
+		e.Param = *field.Param
+	}
+	switch {
+	case e.Code == CodeBackendTimeout:
+		e.Status = http.StatusGatewayTimeout
+	case e.Type == TypeModelError:
+		e.Status = http.StatusBadGateway
+	case e.Type == TypeInvalidRequest || e.Type == "content_filter":
+		e.Status = http.StatusBadRequest
+	}
+	return e
+}
+
+// TypeFor returns the error type of an HTTP status.
+func TypeFor(status int) string {
+	switch {
+	case status == http.StatusNotFound:
+		return TypeNotFound
+	case status == http.StatusTooManyRequests:
+		return TypeTooManyRequests
+	case status >= 500:
+		return TypeServerError
+	default:
+		return TypeInvalidRequest
+	}
+}
+
+// From maps the errors of the core packages to the error returned to the
+// client: request validation and size limits, admission refusals, hook
+// rejections, conversation locks and timeouts. It reports false for other
+// errors, which the caller maps itself.
+func From(err error) (*Error, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	var limitErr *schema.LimitError
+	if errors.As(err, &limitErr) {
+		return &Error{Status: http.StatusRequestEntityTooLarge, Type: TypeInvalidRequest, Code: CodeRequestTooLarge, Param: limitErr.Param, Message: limitErr.Message, Err: err}, true
+	}
+	var validationErr *schema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &Error{Status: http.StatusBadRequest, Type: TypeInvalidRequest, Code: CodeInvalidParameter, Param: validationErr.Param, Message: validationErr.Message, Err: err}, true
+	}
+	var admitErr *admission.Error
+	if errors.As(err, &admitErr) {
+		// Caller limits are the client's to back off from; a full queue
+		// means the whole backend is saturated
+		if admitErr.CallerLimited() {
+			return &Error{Status: http.StatusTooManyRequests, Type: TypeTooManyRequests, Code: CodeRateLimitExceeded, Message: admitErr.Error(), Err: err}, true
+		}
+		return &Error{Status: http.StatusServiceUnavailable, Type: TypeServerError, Code: CodeServerOverloaded, Message: admitErr.Error(), Err: err}, true
+	}
+	var rejectErr *hooks.RejectError
+	if errors.As(err, &rejectErr) {
+		return Wrap(http.StatusBadRequest, CodeRequestRejected, err), true
+	}
+	if errors.Is(err, state.ErrConversationLocked) {
+		return Wrap(http.StatusConflict, CodeConversationLocked, err), true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Wrap(http.StatusGatewayTimeout, CodeRequestTimeout, err), true
+	}
+	return nil, false
+}
+
+// Internal returns the error of an unexpected failure: err mapped by From,
+// else a 500 server_error with the given code.
+func Internal(code string, err error) *Error {
+	if apiErr, ok := From(err); ok {
+		return apiErr
+	}
+	return Wrap(http.StatusInternalServerError, code, err)
+}
+
+// envelope is the body of an error response. Code and param are null when
+// unset, as in the specification.
+type envelope struct {
+	Error struct {
+		Type      string  `json:"type"`
+		Code      *string `json:"code"`
+		Param     *string `json:"param"`
+		Message   string  `json:"message"`
+		RequestID string  `json:"request_id,omitempty"`
+	} `json:"error"`
+}
+
+// Body returns the JSON body of an error response.
+func (e *Error) Body() []byte {
+	return e.BodyWithRequestID("")
+}
+
+// BodyWithRequestID returns the JSON body of an error response to the
+// request with the given ID, which clients report to trace the failure.
+func (e *Error) BodyWithRequestID(requestID string) []byte {
+	var env envelope
+	field := e.Field()
+	env.Error.Type, env.Error.Code, env.Error.Param, env.Error.Message = field.Type, field.Code, field.Param, field.Message
+	env.Error.RequestID = requestID
+	body, _ := json.Marshal(&env)
+	return body
+}
+
+// Write writes e as the response to an HTTP request, with the request ID
+// the response carries in its X-Request-ID header, if any.
+func Write(w http.ResponseWriter, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	w.Write(append(e.BodyWithRequestID(w.Header().Get(requestid.Header)), '\n'))
+}
@@ -0,0 +1,99 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+		code   string
+		param  string
+	}{
+		{"api error", fmt.Errorf("wrapped: %w", New(http.StatusNotFound, "file_not_found", "no file")), http.StatusNotFound, TypeNotFound, "file_not_found", ""},
+		{"limit", &schema.LimitError{Param: "input", Message: "too many items"}, http.StatusRequestEntityTooLarge, TypeInvalidRequest, CodeRequestTooLarge, "input"},
+		{"validation", &schema.ValidationError{Param: "model", Message: "bad model"}, http.StatusBadRequest, TypeInvalidRequest, CodeInvalidParameter, "model"},
+		{"hook", fmt.Errorf("request hook: %w", &hooks.RejectError{Hook: "pii"}), http.StatusBadRequest, TypeInvalidRequest, CodeRequestRejected, ""},
+		{"locked", fmt.Errorf("conversation lock: %w", state.ErrConversationLocked), http.StatusConflict, TypeInvalidRequest, CodeConversationLocked, ""},
+		{"timeout", fmt.Errorf("expand tools: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, TypeServerError, CodeRequestTimeout, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := From(tt.err)
+			if !ok {
+				t.Fatalf("From(%v) not mapped", tt.err)
+			}
+			if got.Status != tt.status || got.Type != tt.typ || got.Code != tt.code || got.Param != tt.param {
+				t.Errorf("From = %d %s %s %q, want %d %s %s %q", got.Status, got.Type, got.Code, got.Param, tt.status, tt.typ, tt.code, tt.param)
+			}
+		})
+	}
+
+	if _, ok := From(errors.New("disk full")); ok {
+		t.Error("From(other error) mapped, want false")
+	}
+	if got := Internal(CodeVectorStoreError, errors.New("disk full")); got.Status != http.StatusInternalServerError || got.Type != TypeServerError || got.Code != CodeVectorStoreError {
+		t.Errorf("Internal = %+v", got)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	if got := Backend(errors.New("connection refused")); got.Status != http.StatusBadGateway || got.Type != TypeModelError || got.Code != CodeBackendError {
+		t.Errorf("Backend(error) = %+v", got)
+	}
+	if got := Backend(fmt.Errorf("call: %w", context.DeadlineExceeded)); got.Status != http.StatusGatewayTimeout || got.Code != CodeBackendTimeout {
+		t.Errorf("Backend(deadline) = %+v", got)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	for _, tt := range []struct {
+		err    *Error
+		status int
+	}{
+		{Backend(errors.New("crashed")), http.StatusBadGateway},
+		{Backend(context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{New(http.StatusBadRequest, "hook_rejected", "rejected"), http.StatusBadRequest},
+		{New(http.StatusInternalServerError, CodeMCPError, "unreachable"), http.StatusInternalServerError},
+	} {
+		field := tt.err.Field()
+		if got := Failed(&field); got.Status != tt.status || got.Code != tt.err.Code || got.Type != tt.err.Type {
+			t.Errorf("Failed(%s) = %d %s %s, want %d", tt.err.Code, got.Status, got.Type, got.Code, tt.status)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, New(http.StatusNotFound, "file_not_found", "File not found"))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	want := `{"error":{"type":"not_found","code":"file_not_found","param":null,"message":"File not found"}}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("X-Request-ID", "req_1")
+	Write(rec, New(http.StatusBadGateway, CodeBackendError, "Backend unavailable"))
+	want = `{"error":{"type":"server_error","code":"backend_error","param":null,"message":"Backend unavailable","request_id":"req_1"}}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envRef matches ${VAR} and ${VAR:-default} references.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces the environment variable references in the scalar
+// values of a parsed YAML document. A reference to an unset variable
+// without a default is an error.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		var errs []error
+		for _, child := range node.Content {
+			errs = append(errs, expandEnv(child))
+		}
+		return errors.Join(errs...)
+	}
+	if !strings.Contains(node.Value, "${") {
+		return nil
+	}
+
+	var missing []string
+	node.Value = envRef.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		m := envRef.FindStringSubmatch(ref)
+		if v, ok := os.LookupEnv(m[1]); ok {
+			return v
+		}
+		if strings.Contains(ref, ":-") {
+			return m[2]
+		}
+		missing = append(missing, m[1])
+		return ""
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("line %d: environment variable %s is not set", node.Line, strings.Join(missing, ", "))
+	}
+	// Let the expanded value be typed again, so "port: ${PORT}" is an int
+	node.Tag = ""
+	node.Style = 0
+	return nil
+}
+
+// ResolveSecrets replaces the secret references in the credential fields
+// (API keys, DSNs, S3 credentials and encryption keys) with the secrets
+// they point to:
+//
+//	file:///run/secrets/openai   contents of the file, without trailing newlines
+//	env://OPENAI_API_KEY         value of the environment variable
+//	vault://secret/data/gw#key   field of a HashiCorp Vault secret, read from
+//	                             VAULT_ADDR with VAULT_TOKEN
+//
+// Other values are kept as they are. Load resolves them; configurations
+// built otherwise must call it before use.
+func (c *Config) ResolveSecrets() error {
+	var errs []error
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"engine.api_key", &c.Engine.APIKey},
+		{"engine.shadow.api_key", &c.Engine.Shadow.APIKey},
+		{"embedding.api_key", &c.Embedding.APIKey},
+		{"web_search.api_key", &c.WebSearch.APIKey},
+		{"moderation.api_key", &c.Moderation.APIKey},
+		{"session_store.dsn", &c.SessionStore.DSN},
+		{"file_store.s3_access_key_id", &c.FileStore.S3AccessKeyID},
+		{"file_store.s3_secret_access_key", &c.FileStore.S3SecretAccessKey},
+		{"connectors.encryption_key", &c.Connectors.EncryptionKey},
+		{"session_store.encryption_key", &c.SessionStore.EncryptionKey},
+	} {
+		v, err := resolveSecret(*f.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+			continue
+		}
+		*f.value = v
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Embedding.Providers)) {
+		p := c.Embedding.Providers[name]
+		v, err := resolveSecret(p.APIKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("embedding.providers.%s.api_key: %w", name, err))
+			continue
+		}
+		p.APIKey = v
+		c.Embedding.Providers[name] = p
+	}
+	for i, ref := range c.SessionStore.PreviousEncryptionKeys {
+		v, err := resolveSecret(ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("session_store.previous_encryption_keys[%d]: %w", i, err))
+			continue
+		}
+		c.SessionStore.PreviousEncryptionKeys[i] = v
+	}
+	return errors.Join(errs...)
+}
+
+// resolveSecret returns the secret a reference points to, or ref itself if
+// it is not a reference.
+func resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "file://"):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, "file://"))
+		if err != nil {
+			return "", fmt.Errorf("read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(ref, "vault://"):
+		return readVaultSecret(strings.TrimPrefix(ref, "vault://"))
+	}
+	return ref, nil
+}
+
+// vaultTimeout bounds a Vault secret read.
+const vaultTimeout = 10 * time.Second
+
+// readVaultSecret reads a field of a Vault secret, given as
+// "<path>#<field>", where path is the API path of the secret, such as
+// "secret/data/gateway" for a KV version 2 engine mounted at secret/.
+func readVaultSecret(ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q (expected vault://<path>#<field>)", ref)
+	}
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN are required for vault references")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	data := body.Data
+	// KV version 2 nests the secret under data.data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	v, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %q", path, field)
+	}
+	return v, nil
+}
@@ -0,0 +1,151 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecode_ExpandEnv(t *testing.T) {
+	t.Setenv("GW_TEST_PORT", "9090")
+	t.Setenv("GW_TEST_HOST", "gw.internal")
+
+	var cfg Config
+	data := "server:\n  host: ${GW_TEST_HOST}\n  port: ${GW_TEST_PORT}\nengine:\n  model_endpoint: ${GW_TEST_UNSET:-http://localhost:8000/v1}\n"
+	if err := decode([]byte(data), &cfg, true); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if cfg.Server.Host != "gw.internal" || cfg.Server.Port != 9090 || cfg.Engine.ModelEndpoint != "http://localhost:8000/v1" {
+		t.Errorf("config = host %q, port %d, endpoint %q", cfg.Server.Host, cfg.Server.Port, cfg.Engine.ModelEndpoint)
+	}
+
+	// Every missing variable is reported, with its line
+	err := decode([]byte("server:\n  host: ${GW_TEST_UNSET}\nengine:\n  api_key: ${GW_TEST_MISSING}\n"), &cfg, true)
+	if err == nil {
+		t.Fatal("decode() error = nil, want missing variables")
+	}
+	for _, want := range []string{"line 2: environment variable GW_TEST_UNSET is not set", "line 4: environment variable GW_TEST_MISSING is not set"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("decode() error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "openai")
+	if err := os.WriteFile(secretFile, []byte("sk-from-file\r\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GW_TEST_DSN", "postgres://gw@db/gw")
+
+	cfg := Config{
+		Engine:       EngineConfig{APIKey: "file://" + secretFile},
+		SessionStore: SessionStoreConfig{DSN: "env://GW_TEST_DSN"},
+		Moderation:   ModerationConfig{APIKey: "s3://bucket/key"}, // not a reference
+	}
+	if err := cfg.ResolveSecrets(); err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if cfg.Engine.APIKey != "sk-from-file" {
+		t.Errorf("engine.api_key = %q, want the file contents without trailing newlines", cfg.Engine.APIKey)
+	}
+	if cfg.SessionStore.DSN != "postgres://gw@db/gw" {
+		t.Errorf("session_store.dsn = %q", cfg.SessionStore.DSN)
+	}
+	if cfg.Moderation.APIKey != "s3://bucket/key" {
+		t.Errorf("moderation.api_key = %q, want it unchanged", cfg.Moderation.APIKey)
+	}
+
+	// Every failure is reported, with its field
+	cfg = Config{
+		Engine:    EngineConfig{APIKey: "env://GW_TEST_UNSET"},
+		WebSearch: WebSearchConfig{APIKey: "file://" + filepath.Join(t.TempDir(), "missing")},
+		SessionStore: SessionStoreConfig{
+			PreviousEncryptionKeys: []string{"key", "vault://secret/data/gw"},
+		},
+	}
+	err := cfg.ResolveSecrets()
+	if err == nil {
+		t.Fatal("ResolveSecrets() error = nil, want failures")
+	}
+	for _, want := range []string{
+		"engine.api_key: environment variable GW_TEST_UNSET is not set",
+		"web_search.api_key: read secret",
+		"session_store.previous_encryption_keys[1]: invalid vault reference",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolveSecrets() error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestReadVaultSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/gw":
+			w.Write([]byte(`{"data": {"data": {"api_key": "sk-from-vault", "port": 1}}}`))
+		case "/v1/kv/gw":
+			w.Write([]byte(`{"data": {"api_key": "sk-from-kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL+"/")
+	t.Setenv("VAULT_TOKEN", "s.token")
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "secret/data/gw#api_key", want: "sk-from-vault"},
+		{ref: "kv/gw#api_key", want: "sk-from-kv1"},
+		{ref: "secret/data/gw#port", wantErr: `has no string field "port"`},
+		{ref: "secret/data/other#api_key", wantErr: "vault returned status 404 for secret/data/other"},
+		{ref: "secret/data/gw", wantErr: "invalid vault reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := readVaultSecret(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("readVaultSecret() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("readVaultSecret() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("wrong token", func(t *testing.T) {
+		t.Setenv("VAULT_TOKEN", "s.wrong")
+		if _, err := readVaultSecret("secret/data/gw#api_key"); err == nil || !strings.Contains(err.Error(), "status 403") {
+			t.Errorf("readVaultSecret() error = %v, want status 403", err)
+		}
+	})
+	t.Run("no token", func(t *testing.T) {
+		t.Setenv("VAULT_TOKEN", "")
+		if _, err := readVaultSecret("secret/data/gw#api_key"); err == nil || !strings.Contains(err.Error(), "VAULT_TOKEN") {
+			t.Errorf("readVaultSecret() error = %v, want VAULT_TOKEN required", err)
+		}
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		t.Setenv("VAULT_ADDR", "http://127.0.0.1:1")
+		if _, err := readVaultSecret("secret/data/gw#api_key"); err == nil || !strings.Contains(err.Error(), "vault request") {
+			t.Errorf("readVaultSecret() error = %v, want a request failure", err)
+		}
+	})
+}
@@ -0,0 +1,372 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/leseb/openresponses-gw/pkg/egress"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/secrets"
+	"github.com/leseb/openresponses-gw/pkg/tokenizer"
+)
+
+// apiKeyFingerprint matches the API key fingerprints of
+// state.APIKeyFingerprint.
+var apiKeyFingerprint = regexp.MustCompile(`^key_[0-9a-f]{16}$`)
+
+// Check loads the configuration file at path like Load, but also rejects
+// unknown fields and validates the result. All problems found are returned
+// together, one per line.
+func Check(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	// Unknown fields are reported with the invalid values
+	strictErr := decode(data, &Config{}, true)
+	var typeErr *yaml.TypeError
+	if strictErr != nil && !errors.As(strictErr, &typeErr) {
+		return nil, fmt.Errorf("failed to parse config: %w", strictErr)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		if strictErr != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", strictErr)
+		}
+		return nil, err
+	}
+	if err := errors.Join(strictErr, cfg.Validate()); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration for values the gateway would reject or
+// misinterpret at startup. Each problem is reported as its own error, named
+// after the YAML field.
+func (c *Config) Validate() error {
+	var v validator
+
+	v.check(c.Engine.ModelEndpoint != "", "engine.model_endpoint", "is required (or set OPENAI_API_ENDPOINT)")
+	if c.Engine.ModelEndpoint != "" {
+		v.url("engine.model_endpoint", c.Engine.ModelEndpoint)
+	}
+	v.oneOf("engine.backend_api", c.Engine.BackendAPI, "responses", "chat_completions", "ollama")
+	if ka := c.Engine.Ollama.KeepAlive; ka != "" {
+		_, durErr := time.ParseDuration(ka)
+		_, intErr := strconv.Atoi(ka)
+		v.check(durErr == nil || intErr == nil, "engine.ollama.keep_alive", fmt.Sprintf("invalid duration %q", ka))
+	}
+	v.check(c.Engine.Loop.MaxDuration >= 0, "engine.loop.max_duration", "must not be negative")
+	v.check(c.Engine.Loop.MaxBackendCalls >= 0, "engine.loop.max_backend_calls", "must not be negative")
+	v.check(c.Engine.Loop.MaxTotalTokens >= 0, "engine.loop.max_total_tokens", "must not be negative")
+	v.check(c.Engine.Loop.RequestTimeout >= 0, "engine.loop.request_timeout", "must not be negative")
+	v.check(c.Engine.Admission.MaxConcurrent >= 0, "engine.admission.max_concurrent", "must not be negative")
+	v.check(c.Engine.Admission.MaxConcurrentPerTenant >= 0, "engine.admission.max_concurrent_per_tenant", "must not be negative")
+	v.check(c.Engine.Admission.MaxConcurrentPerKey >= 0, "engine.admission.max_concurrent_per_key", "must not be negative")
+	v.check(c.Engine.Admission.MaxQueue >= 0, "engine.admission.max_queue", "must not be negative")
+	v.check(c.Engine.Admission.QueueTimeout >= 0, "engine.admission.queue_timeout", "must not be negative")
+	v.oneOf("engine.id_format", c.Engine.IDFormat, ids.Formats...)
+	for _, name := range slices.Sorted(maps.Keys(c.Engine.Models)) {
+		m := c.Engine.Models[name]
+		field := "engine.models." + name
+		for _, p := range []struct {
+			kind   string
+			params ModelParams
+		}{{"defaults", m.Defaults}, {"overrides", m.Overrides}} {
+			f := field + "." + p.kind
+			if t := p.params.Temperature; t != nil {
+				v.check(*t >= 0 && *t <= 2, f+".temperature", "must be between 0 and 2")
+			}
+			if t := p.params.TopP; t != nil {
+				v.check(*t > 0 && *t <= 1, f+".top_p", "must be greater than 0 and at most 1")
+			}
+			if n := p.params.MaxOutputTokens; n != nil {
+				v.check(*n > 0, f+".max_output_tokens", "must be positive")
+			}
+			if p.params.ReasoningEffort != "" {
+				v.oneOf(f+".reasoning_effort", p.params.ReasoningEffort, "low", "medium", "high")
+			}
+		}
+		v.check(m.MaxOutputTokens >= 0, field+".max_output_tokens", "must not be negative")
+	}
+	for _, alias := range slices.Sorted(maps.Keys(c.Engine.ModelAliases)) {
+		model := c.Engine.ModelAliases[alias]
+		field := "engine.model_aliases." + alias
+		v.check(model != "", field, "must name a model")
+		v.check(model != alias, field, "must not point to itself")
+		_, chained := c.Engine.ModelAliases[model]
+		v.check(model == alias || !chained, field, "must point to a model, not another alias")
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Engine.APIKeyInstructions)) {
+		v.check(apiKeyFingerprint.MatchString(key), "engine.api_key_instructions."+key, "must be an API key fingerprint (key_ and 16 hex characters)")
+	}
+	for i, tool := range c.Engine.Tools {
+		v.tool(fmt.Sprintf("engine.tools[%d]", i), tool)
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Engine.APIKeyTools)) {
+		field := "engine.api_key_tools." + key
+		v.check(apiKeyFingerprint.MatchString(key), field, "must be an API key fingerprint (key_ and 16 hex characters)")
+		for i, tool := range c.Engine.APIKeyTools[key] {
+			v.tool(fmt.Sprintf("%s[%d]", field, i), tool)
+		}
+	}
+	v.oneOf("engine.instructions_merge", c.Engine.InstructionsMerge, "replace", "inherit", "prepend", "append")
+	// A separator at the end keeps the prefix from running into the
+	// suffix, so IDs of different prefixes cannot collide
+	v.check(strings.HasSuffix(c.Engine.ResponseIDPrefix, "_"), "engine.response_id_prefix", "must end with \"_\"")
+	v.oneOf("engine.tokenizer.encoding", c.Engine.Tokenizer.Encoding, tokenizer.Encodings...)
+	if enc := c.Engine.Tokenizer.Encoding; enc != "heuristic" && slices.Contains(tokenizer.Encodings, enc) {
+		v.check(c.Engine.Tokenizer.VocabFile != "", "engine.tokenizer.vocab_file", "is required for encoding "+enc)
+	}
+	v.check(c.Engine.RequestLimits.MaxInputItems >= 0, "engine.request_limits.max_input_items", "must not be negative")
+	v.check(c.Engine.RequestLimits.MaxTools >= 0, "engine.request_limits.max_tools", "must not be negative")
+	v.check(c.Engine.ConversationLockTTL > 0, "engine.conversation_lock_ttl", "must be positive")
+	v.check(c.Engine.Streaming.Buffer > 0, "engine.streaming.buffer", "must be positive")
+	v.oneOf("engine.streaming.overflow", c.Engine.Streaming.Overflow, "block", "merge", "drop")
+	v.check(c.Engine.ContextWindow >= 0, "engine.context_window", "must not be negative")
+	v.check(c.Engine.HistoryMaxDepth > 0, "engine.history_max_depth", "must be positive")
+	v.check(c.Engine.MaxInlineFileBytes > 0, "engine.max_inline_file_bytes", "must be positive")
+	v.check(c.Engine.MaxToolOutputBytes > 0, "engine.max_tool_output_bytes", "must be positive")
+	v.check(c.Engine.ConversationTitles.MaxLength > 0, "engine.conversation_titles.max_length", "must be positive")
+	v.oneOf("engine.prompt_cache_key", c.Engine.PromptCacheKey, "none", "prefix")
+	v.check(c.Engine.ResponseCache.TTL >= 0, "engine.response_cache.ttl", "must not be negative")
+	for _, model := range slices.Sorted(maps.Keys(c.Engine.ResponseCache.Models)) {
+		v.check(c.Engine.ResponseCache.Models[model] >= 0, "engine.response_cache.models."+model, "must not be negative")
+	}
+	if c.Engine.Shadow.ModelEndpoint != "" {
+		v.url("engine.shadow.model_endpoint", c.Engine.Shadow.ModelEndpoint)
+		v.oneOf("engine.shadow.backend_api", c.Engine.Shadow.BackendAPI, "responses", "chat_completions", "ollama")
+	}
+	v.check(c.Engine.Shadow.SampleRate > 0 && c.Engine.Shadow.SampleRate <= 1, "engine.shadow.sample_rate", "must be greater than 0 and at most 1")
+	v.check(c.Engine.Shadow.MaxConcurrent > 0, "engine.shadow.max_concurrent", "must be positive")
+	v.check(c.Engine.Shadow.Timeout > 0, "engine.shadow.timeout", "must be positive")
+
+	v.port("server.port", c.Server.Port)
+	v.check(c.Server.Compression.Level >= -2 && c.Server.Compression.Level <= 9, "server.compression.level", "must be between -2 and 9")
+	if c.Server.CORS.Enabled {
+		v.check(len(c.Server.CORS.AllowedOrigins) > 0, "server.cors.allowed_origins", "required when CORS is enabled")
+		v.check(!c.Server.CORS.AllowCredentials || !slices.Contains(c.Server.CORS.AllowedOrigins, "*"),
+			"server.cors.allow_credentials", `cannot be combined with the "*" origin`)
+	}
+	v.tls("server.tls", c.Server.TLS)
+	v.check(c.Server.ShutdownGracePeriod >= 0, "server.shutdown_grace_period", "must not be negative")
+	v.check(c.Server.MaxRequestBytes > 0, "server.max_request_bytes", "must be positive")
+	v.port("extproc.port", c.ExtProc.Port)
+	v.oneOf("extproc.mode", c.ExtProc.Mode, "terminate", "passthrough")
+	// Passthrough returns the backend's reply as-is, which Ollama's native
+	// API cannot be
+	v.check(!(c.ExtProc.Enabled && c.ExtProc.Mode == "passthrough" && c.Engine.BackendAPI == "ollama"),
+		"extproc.mode", "passthrough does not support the ollama backend API")
+	v.tls("extproc.tls", c.ExtProc.TLS)
+	v.port("grpc.port", c.GRPC.Port)
+	v.tls("grpc.tls", c.GRPC.TLS)
+	v.check(c.WebSocket.MaxMessageBytes >= 0, "websocket.max_message_bytes", "must not be negative")
+
+	v.embedder("embedding", c.Embedding.EmbedderConfig)
+	for _, name := range slices.Sorted(maps.Keys(c.Embedding.Providers)) {
+		p := c.Embedding.Providers[name]
+		field := "embedding.providers." + name
+		v.check(name != "default", field, `"default" is the name of the embedding section's embedder`)
+		v.check(p.Model != "", field+".model", "is required")
+		v.embedder(field, p)
+	}
+	if c.VectorStore.Type == "milvus" {
+		v.check(c.VectorStore.MilvusAddress != "", "vector_store.milvus_address", "is required for the milvus vector store")
+	}
+	if c.FileStore.Type == "filesystem" {
+		v.check(c.FileStore.BaseDir != "", "file_store.base_dir", "is required for the filesystem file store")
+	}
+	for _, purpose := range slices.Sorted(maps.Keys(c.FileStore.Retention)) {
+		v.check(c.FileStore.Retention[purpose] > 0, "file_store.retention."+purpose, "must be positive")
+	}
+	v.check(c.FileStore.RetentionInterval > 0, "file_store.retention_interval", "must be positive")
+	if c.FileStore.Type == "s3" {
+		v.check(c.FileStore.S3Bucket != "", "file_store.s3_bucket", "is required for the s3 file store")
+		v.oneOf("file_store.s3_server_side_encryption", c.FileStore.S3ServerSideEncryption, "", "AES256", "aws:kms")
+		v.check(c.FileStore.S3KMSKeyID == "" || c.FileStore.S3ServerSideEncryption == "aws:kms",
+			"file_store.s3_kms_key_id", "requires s3_server_side_encryption aws:kms")
+		v.check(c.FileStore.S3PresignExpiry > 0 && c.FileStore.S3PresignExpiry <= 7*24*time.Hour,
+			"file_store.s3_presign_expiry", "must be between 0 and 168h")
+	}
+
+	if c.SessionStore.EncryptionKey != "" {
+		_, err := secrets.ParseKey(c.SessionStore.EncryptionKey)
+		v.check(err == nil, "session_store.encryption_key", "must be 32 bytes encoded as base64 or hex")
+	}
+	for i, key := range c.SessionStore.PreviousEncryptionKeys {
+		_, err := secrets.ParseKey(key)
+		v.check(err == nil, fmt.Sprintf("session_store.previous_encryption_keys[%d]", i), "must be 32 bytes encoded as base64 or hex")
+	}
+	v.check(c.SessionStore.EncryptionKey != "" || len(c.SessionStore.PreviousEncryptionKeys) == 0,
+		"session_store.previous_encryption_keys", "requires session_store.encryption_key")
+	v.check(c.SessionStore.Retention.Responses >= 0, "session_store.retention.responses", "must not be negative")
+	v.check(c.SessionStore.Retention.Conversations >= 0, "session_store.retention.conversations", "must not be negative")
+	v.check(c.SessionStore.Retention.Interval > 0, "session_store.retention.interval", "must be positive")
+	v.check(c.SessionStore.Retention.BatchSize > 0, "session_store.retention.batch_size", "must be positive")
+
+	switch c.WebSearch.Provider {
+	case "":
+	case "searxng":
+		v.check(c.WebSearch.BaseURL != "", "web_search.base_url", "is required for the searxng provider")
+	default:
+		v.check(c.WebSearch.APIKey != "", "web_search.api_key", "is required when web_search.provider is set")
+		if c.WebSearch.Provider == "google" {
+			v.check(c.WebSearch.EngineID != "", "web_search.engine_id", "is required for the google provider")
+		}
+	}
+	if c.WebSearch.Freshness != "" {
+		v.oneOf("web_search.freshness", c.WebSearch.Freshness, "day", "week", "month", "year")
+	}
+	v.check(c.WebSearch.CacheTTL >= 0, "web_search.cache_ttl", "must not be negative")
+	v.check(c.WebSearch.CacheSize >= 0, "web_search.cache_size", "must not be negative")
+	v.check(!c.WebFetch.Enabled || c.WebSearch.Provider != "", "web_fetch.enabled", "requires web_search.provider")
+	v.egress("egress", c.Egress.EgressPolicyConfig)
+	for _, id := range slices.Sorted(maps.Keys(c.Egress.Connectors)) {
+		v.egress("egress.connectors."+id, c.Egress.Connectors[id])
+	}
+	v.check(c.WebFetch.Timeout >= 0, "web_fetch.timeout", "must not be negative")
+	v.check(c.WebFetch.MaxBytes >= 0, "web_fetch.max_bytes", "must not be negative")
+	v.check(c.WebFetch.MaxChars >= 0, "web_fetch.max_chars", "must not be negative")
+	v.check(c.ImageFetch.Timeout >= 0, "image_fetch.timeout", "must not be negative")
+	v.check(c.ImageFetch.MaxBytes >= 0, "image_fetch.max_bytes", "must not be negative")
+	v.check(c.ImageFetch.CacheTTL >= 0, "image_fetch.cache_ttl", "must not be negative")
+	for _, stage := range c.Moderation.Stages {
+		v.oneOf("moderation.stages", stage, "input", "output")
+	}
+	for i, h := range c.Hooks {
+		field := fmt.Sprintf("hooks[%d]", i)
+		v.check(h.Name != "", field+".name", "is required")
+		v.check(h.URL != "", field+".url", "is required")
+		if h.URL != "" {
+			v.url(field+".url", h.URL)
+		}
+		for _, stage := range h.Stages {
+			v.oneOf(field+".stages", stage, "request", "response")
+		}
+		v.check(h.Timeout >= 0, field+".timeout", "must not be negative")
+	}
+
+	v.check(c.Connectors.Stdio.MaxConcurrency >= 0, "connectors.stdio.max_concurrency", "must not be negative")
+	v.check(c.GC.Interval >= 0, "gc.interval", "must not be negative")
+	for _, name := range slices.Sorted(maps.Keys(c.FeatureFlags.Flags)) {
+		flag := c.FeatureFlags.Flags[name]
+		v.check(flag.Percentage >= 0 && flag.Percentage <= 100, "feature_flags.flags."+name+".percentage", "must be between 0 and 100")
+	}
+	v.check(c.Health.Timeout >= 0, "health.timeout", "must not be negative")
+
+	v.oneOf("logging.level", c.Logging.Level, "debug", "info", "warn", "error")
+	v.oneOf("logging.format", c.Logging.Format, "json", "text")
+	v.check(c.Logging.Payloads.MaxBytes >= 0, "logging.payloads.max_bytes", "must not be negative")
+	for _, pattern := range c.Logging.Payloads.RedactMetadataKeys {
+		_, err := path.Match(pattern, "")
+		v.check(err == nil, "logging.payloads.redact_metadata_keys", fmt.Sprintf("invalid pattern %q", pattern))
+	}
+	v.oneOf("audit.sink", c.Audit.Sink, "session_store", "file")
+	if c.EventBus.Provider != "" {
+		v.check(len(c.EventBus.Brokers) > 0, "event_bus.brokers", "required when a provider is set")
+		v.oneOf("event_bus.serialization", c.EventBus.Serialization, "json", "cloudevents")
+		v.check(c.EventBus.BatchSize > 0, "event_bus.batch_size", "must be positive")
+		v.check(c.EventBus.PollInterval > 0, "event_bus.poll_interval", "must be positive")
+		v.check(c.EventBus.Lease > 0, "event_bus.lease", "must be positive")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects validation errors.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, field, msg string) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", field, msg))
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), field, fmt.Sprintf("invalid value %q (allowed: %v)", value, allowed))
+}
+
+func (v *validator) port(field string, port int) {
+	v.check(port >= 0 && port <= 65535, field, fmt.Sprintf("invalid port %d", port))
+}
+
+func (v *validator) url(field, value string) {
+	u, err := url.Parse(value)
+	v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field,
+		fmt.Sprintf("invalid URL %q (expected http:// or https://)", value))
+}
+
+// tls checks the TLS configuration of a listener under field.
+func (v *validator) tls(field string, t TLSConfig) {
+	v.check((t.CertFile == "") == (t.KeyFile == ""), field+".key_file", "cert_file and key_file must be set together")
+	v.check(t.ClientCAFile == "" || t.CertFile != "", field+".client_ca_file", "requires cert_file")
+	v.check(len(t.AllowedSANs) == 0 || t.ClientCAFile != "", field+".allowed_sans", "requires client_ca_file")
+}
+
+// tool checks a configured tool under field.
+func (v *validator) tool(field string, t ToolConfig) {
+	v.oneOf(field+".type", t.Type, "function", "file_search", "web_search", "mcp")
+	switch t.Type {
+	case "function":
+		v.check(t.Name != "", field+".name", "is required for function tools")
+	case "file_search":
+		v.check(len(t.VectorStoreIDs) > 0, field+".vector_store_ids", "is required for file_search tools")
+		v.check(t.MaxNumResults >= 0, field+".max_num_results", "must not be negative")
+	case "web_search":
+		if t.SearchContextSize != "" {
+			v.oneOf(field+".search_context_size", t.SearchContextSize, "low", "medium", "high")
+		}
+	case "mcp":
+		v.check(t.ServerLabel != "", field+".server_label", "is required for mcp tools")
+	}
+}
+
+// egress checks an egress policy under field.
+func (v *validator) egress(field string, p EgressPolicyConfig) {
+	for i, host := range p.AllowedHosts {
+		_, addrErr := netip.ParseAddr(host)
+		name := host != "" && !strings.ContainsAny(host, "/: ") && !strings.Contains(strings.TrimPrefix(host, "*."), "*")
+		v.check(addrErr == nil || name, fmt.Sprintf("%s.allowed_hosts[%d]", field, i),
+			fmt.Sprintf("invalid host %q (expected an IP address or a host name, optionally starting with \"*.\")", host))
+	}
+	for i, cidr := range p.AllowedCIDRs {
+		_, err := egress.ParsePrefix(cidr)
+		v.check(err == nil, fmt.Sprintf("%s.allowed_cidrs[%d]", field, i), fmt.Sprintf("invalid CIDR %q", cidr))
+	}
+	v.check(p.MaxResponseBytes >= 0, field+".max_response_bytes", "must not be negative")
+	v.check(p.Timeout >= 0, field+".timeout", "must not be negative")
+}
+
+// embedder checks an embedder configuration under field.
+func (v *validator) embedder(field string, e EmbedderConfig) {
+	v.oneOf(field+".type", e.Type, "openai", "local")
+	if e.Endpoint != "" {
+		v.url(field+".endpoint", e.Endpoint)
+	}
+	if e.Type == "local" {
+		v.check(e.ModelPath != "", field+".model_path", "is required for local embedders")
+		v.check(e.Dimensions >= 0, field+".dimensions", "must not be negative")
+		v.check(e.MaxTokens > 0, field+".max_tokens", "must be positive")
+	} else {
+		v.check(e.Dimensions > 0, field+".dimensions", "must be positive")
+	}
+}
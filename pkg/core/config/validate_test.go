@@ -0,0 +1,83 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a configuration file and returns its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("OPENAI_API_ENDPOINT", "")
+	t.Setenv("BACKEND_API", "")
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []string // every line expected in the error, in any order
+		wantCfg bool
+	}{
+		{
+			name:    "valid",
+			data:    "engine:\n  model_endpoint: http://localhost:8000/v1\n",
+			wantCfg: true,
+		},
+		{
+			name: "unknown fields and invalid values together",
+			data: "server:\n  prot: 8080\nengine:\n  backend_api: grpc\n  loop:\n    max_backend_calls: -1\n  modle_aliases: {}\n",
+			want: []string{
+				"line 2: field prot not found",
+				"line 7: field modle_aliases not found",
+				"engine.model_endpoint: is required",
+				"engine.backend_api:",
+				"engine.loop.max_backend_calls: must not be negative",
+			},
+			wantCfg: true,
+		},
+		{
+			name: "invalid type",
+			data: "server:\n  port: http\n  prot: 8080\n",
+			want: []string{"failed to parse config", "line 2: cannot unmarshal", "line 3: field prot not found"},
+		},
+		{
+			name: "invalid YAML",
+			data: "server: [\n",
+			want: []string{"failed to parse config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Check(writeConfig(t, tt.data))
+			if (cfg != nil) != tt.wantCfg {
+				t.Errorf("Check() config = %v, want one: %v", cfg, tt.wantCfg)
+			}
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Check() error = nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Check() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+)
+
+// ActivityEvent is a lifecycle event of a response, as seen live by
+// SubscribeActivity.
+type ActivityEvent struct {
+	Type     string                 `json:"type"` // eventbus event type, e.g. "response.completed"
+	Time     time.Time              `json:"time"`
+	Response eventbus.ResponseEvent `json:"response"`
+}
+
+// activityFeed fans response events out to live subscribers. Subscribers
+// that fall behind miss events rather than slowing requests down.
+type activityFeed struct {
+	mu   sync.Mutex
+	subs map[chan ActivityEvent]struct{}
+}
+
+// active reports whether anyone is subscribed.
+func (f *activityFeed) active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs) > 0
+}
+
+func (f *activityFeed) publish(event ActivityEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscribeActivity returns a channel receiving the lifecycle events of
+// responses as the engine saves them, and a function ending the
+// subscription. Up to buffer events are queued; later ones are dropped
+// until the subscriber catches up.
+func (e *Engine) SubscribeActivity(buffer int) (<-chan ActivityEvent, func()) {
+	ch := make(chan ActivityEvent, buffer)
+	e.activity.mu.Lock()
+	if e.activity.subs == nil {
+		e.activity.subs = make(map[chan ActivityEvent]struct{})
+	}
+	e.activity.subs[ch] = struct{}{}
+	e.activity.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			e.activity.mu.Lock()
+			delete(e.activity.subs, ch)
+			e.activity.mu.Unlock()
+			close(ch)
+		})
+	}
+}
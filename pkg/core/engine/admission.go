@@ -0,0 +1,72 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+
+	"github.com/leseb/openresponses-gw/pkg/core/admission"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+)
+
+// newAdmission returns the admission controller for cfg, or nil when no
+// concurrency limit is set.
+func newAdmission(cfg config.AdmissionConfig) *admission.Controller {
+	if cfg.MaxConcurrent == 0 && cfg.MaxConcurrentPerTenant == 0 && cfg.MaxConcurrentPerKey == 0 {
+		return nil
+	}
+	return admission.New(admissionLimits(cfg))
+}
+
+func admissionLimits(cfg config.AdmissionConfig) admission.Limits {
+	return admission.Limits{
+		MaxConcurrent:          cfg.MaxConcurrent,
+		MaxConcurrentPerTenant: cfg.MaxConcurrentPerTenant,
+		MaxConcurrentPerKey:    cfg.MaxConcurrentPerKey,
+		MaxQueue:               cfg.MaxQueue,
+		QueueTimeout:           cfg.QueueTimeout,
+	}
+}
+
+// SetAdmissionLimits replaces the concurrency limits, for a configuration
+// reload. Tier priorities are kept. Admission control cannot be enabled
+// at runtime if no limit was set at startup.
+func (e *Engine) SetAdmissionLimits(cfg config.AdmissionConfig) error {
+	if e.admission == nil {
+		if newAdmission(cfg) != nil {
+			return errors.New("admission control was disabled at startup")
+		}
+		return nil
+	}
+	e.admission.SetLimits(admissionLimits(cfg))
+	return nil
+}
+
+// admit waits for an admission slot for req, which the response holds for
+// its whole agentic loop. The returned function releases it.
+func (e *Engine) admit(ctx context.Context, req *schema.ResponseRequest) (func(), error) {
+	if e.admission == nil {
+		return func() {}, nil
+	}
+	caller := admission.Caller{
+		Tenant: featureflags.TenantFromContext(ctx),
+		APIKey: state.APIKeyFromContext(ctx),
+	}
+	return e.admission.Acquire(ctx, caller, e.tierPriority(req.ServiceTier))
+}
+
+// tierPriority returns the queue priority of a service tier.
+func (e *Engine) tierPriority(tier *string) int {
+	priorities := e.config.Admission.TierPriorities
+	if tier != nil {
+		if p, ok := priorities[*tier]; ok {
+			return p
+		}
+	}
+	return priorities["default"]
+}
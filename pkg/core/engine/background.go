@@ -0,0 +1,73 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+)
+
+var (
+	errBackgroundDisabled = &apierror.Error{Status: http.StatusBadRequest, Type: apierror.TypeInvalidRequest,
+		Code: apierror.CodeInvalidParameter, Param: "background", Message: "background mode is not enabled"}
+	errBackgroundStream = &apierror.Error{Status: http.StatusBadRequest, Type: apierror.TypeInvalidRequest,
+		Code: apierror.CodeInvalidParameter, Param: "background", Message: "'background' cannot be combined with 'stream'"}
+)
+
+type backgroundKey struct{}
+
+// inBackground reports whether ctx belongs to a background response.
+func inBackground(ctx context.Context) bool {
+	return ctx.Value(backgroundKey{}) != nil
+}
+
+// processBackground starts a response in the background, if the
+// background_mode feature flag is enabled for the tenant. It runs as a
+// streamed response detached from the caller, whose events are discarded,
+// and is returned in_progress once saved; GetResponse returns it when done.
+func (e *Engine) processBackground(ctx context.Context, req *schema.ResponseRequest) (*schema.Response, error) {
+	if !e.features.Enabled(ctx, featureflags.BackgroundMode) {
+		return nil, errBackgroundDisabled
+	}
+	streamReq := *req
+	streamReq.Stream = true
+	ctx = context.WithValue(context.WithoutCancel(ctx), backgroundKey{}, struct{}{})
+	events, err := e.ProcessRequestStream(ctx, &streamReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// The response is saved once created, before the next event is sent,
+	// so it can be retrieved once that event arrives
+	var resp *schema.Response
+	for event := range events {
+		if resp != nil {
+			break
+		}
+		switch ev := event.(type) {
+		case *schema.ResponseCreatedStreamingEvent:
+			resp = &ev.Response
+			continue
+		case *schema.ErrorStreamingEvent:
+			go drain(events)
+			return nil, apierror.Failed(&ev.Error)
+		}
+	}
+	go drain(events)
+	if resp == nil {
+		return nil, errors.New("background response ended before it was created")
+	}
+	return resp, nil
+}
+
+// drain discards the remaining events of a stream.
+func drain(events <-chan interface{}) {
+	for range events {
+	}
+}
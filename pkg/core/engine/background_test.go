@@ -0,0 +1,82 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func TestBackgroundMode(t *testing.T) {
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	flags, err := featureflags.New(map[string]featureflags.Rule{featureflags.BackgroundMode: {Tenants: []string{"acme"}}})
+	if err != nil {
+		t.Fatalf("featureflags.New() error = %v", err)
+	}
+	e := &Engine{
+		config:   &config.EngineConfig{},
+		sessions: store,
+		llm:      &benchBackend{deltas: 3},
+		idGen:    ids.NewSequence(),
+		features: flags,
+	}
+	background := true
+	newRequest := func() *schema.ResponseRequest {
+		return &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi", Background: &background}
+	}
+
+	// Disabled for other tenants
+	ctx := featureflags.WithTenant(context.Background(), "other")
+	_, err = e.ProcessRequest(ctx, newRequest())
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != 400 || apiErr.Param != "background" {
+		t.Fatalf("ProcessRequest() error = %v, want 400 on background", err)
+	}
+
+	// Enabled at runtime
+	if err := flags.Override(featureflags.BackgroundMode, featureflags.Rule{Enabled: true}); err != nil {
+		t.Fatalf("Override() error = %v", err)
+	}
+	resp, err := e.ProcessRequest(ctx, newRequest())
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	if resp.Status != "in_progress" || resp.Background == nil || !*resp.Background {
+		t.Fatalf("response = status %s, background %v, want in_progress in the background", resp.Status, resp.Background)
+	}
+
+	var got *schema.Response
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if got, err = e.GetResponse(ctx, resp.ID); err != nil {
+			t.Fatalf("GetResponse() error = %v", err)
+		}
+		if got.Status != "in_progress" {
+			break
+		}
+	}
+	if got.Status != "completed" || len(got.Output) == 0 {
+		t.Errorf("retrieved response = status %s, %d output items, want completed", got.Status, len(got.Output))
+	}
+
+	// Streaming in the background is not supported
+	req := newRequest()
+	req.Stream = true
+	if _, err := e.ProcessRequestStream(ctx, req); !errors.As(err, &apiErr) || apiErr.Param != "background" {
+		t.Errorf("ProcessRequestStream() error = %v, want 400 on background", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// benchBackend answers every call instantly with the same text, streamed
+// as the given number of deltas.
+type benchBackend struct {
+	deltas int
+}
+
+func (b *benchBackend) CreateResponse(context.Context, *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	text := "Hello from the benchmark backend."
+	return &api.ResponsesAPIResponse{
+		ID:     "resp_backend",
+		Status: "completed",
+		Output: []api.OutputItem{{
+			Type: "message", ID: "msg_backend", Role: "assistant", Status: "completed",
+			Content: []api.ContentItem{{Type: "output_text", Text: text}},
+		}},
+		Usage: &api.UsageInfo{InputTokens: 5, OutputTokens: 6, TotalTokens: 11},
+	}, nil
+}
+
+func (b *benchBackend) CreateResponseStream(ctx context.Context, _ *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	ch := make(chan api.ResponsesStreamEvent, 10)
+	go func() {
+		defer close(ch)
+		send := func(evt map[string]interface{}) bool {
+			data, _ := json.Marshal(evt)
+			select {
+			case ch <- api.ResponsesStreamEvent{Type: evt["type"].(string), Data: data}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for i := 0; i < b.deltas; i++ {
+			if !send(map[string]interface{}{
+				"type": "response.output_text.delta", "output_index": 0, "item_id": "msg_backend", "delta": "token ",
+			}) {
+				return
+			}
+		}
+		send(backendCompleted(backendMessage("msg_backend", "done")))
+	}()
+	return ch, nil
+}
+
+func newBenchEngine(b *testing.B, deltas int) *Engine {
+	b.Helper()
+	store, err := sqlite.New(filepath.Join(b.TempDir(), "sessions.db"))
+	if err != nil {
+		b.Fatalf("sqlite.New() error = %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+	return &Engine{
+		config:   &config.EngineConfig{},
+		sessions: store,
+		llm:      &benchBackend{deltas: deltas},
+		idGen:    ids.NewSequence(),
+	}
+}
+
+// reportLatency reports the p50 and p99 of the durations of the operations.
+func reportLatency(b *testing.B, durations []time.Duration) {
+	b.Helper()
+	if len(durations) == 0 {
+		return
+	}
+	slices.Sort(durations)
+	b.ReportMetric(float64(durations[len(durations)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(durations[len(durations)*99/100].Nanoseconds()), "p99-ns")
+}
+
+func BenchmarkProcessRequest(b *testing.B) {
+	e := newBenchEngine(b, 0)
+	ctx := context.Background()
+	durations := make([]time.Duration, 0, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		if _, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"}); err != nil {
+			b.Fatalf("ProcessRequest() error = %v", err)
+		}
+		durations = append(durations, time.Since(start))
+	}
+	b.StopTimer()
+	reportLatency(b, durations)
+}
+
+// BenchmarkProcessRequestStream measures the streaming path from the
+// backend deltas to the events read by the client, per response and per
+// delta.
+func BenchmarkProcessRequestStream(b *testing.B) {
+	for _, deltas := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("deltas=%d", deltas), func(b *testing.B) {
+			e := newBenchEngine(b, deltas)
+			ctx := context.Background()
+			durations := make([]time.Duration, 0, b.N)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				events, err := e.ProcessRequestStream(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+				if err != nil {
+					b.Fatalf("ProcessRequestStream() error = %v", err)
+				}
+				for range events {
+				}
+				durations = append(durations, time.Since(start))
+			}
+			b.StopTimer()
+			reportLatency(b, durations)
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*deltas), "ns/delta")
+		})
+	}
+}
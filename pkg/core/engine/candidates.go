@@ -0,0 +1,316 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+)
+
+// candidateCount returns the number of candidates a backend request samples.
+func candidateCount(apiReq *api.ResponsesAPIRequest) int {
+	if apiReq.N == nil || *apiReq.N < 1 {
+		return 1
+	}
+	return *apiReq.N
+}
+
+// createResponse calls the backend through the recorder of ctx, if any: the
+// call is recorded, or in replay the recorded response is returned without
+// calling. Calls of responses marked for shadow traffic are also sent to the
+// secondary backend.
+func (e *Engine) createResponse(ctx context.Context, apiReq *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	r := recorderFrom(ctx)
+	if r.replaying() {
+		return r.replayResponse(apiReq)
+	}
+	i := r.startBackend(apiReq)
+	shadow := e.startShadowCall(ctx, apiReq, false)
+	resp, err := e.sampleResponse(ctx, apiReq)
+	shadow.finish(apiReq.Model, resp, 0, err)
+	r.finishBackend(i, resp, nil, err)
+	return resp, err
+}
+
+// sampleResponse calls the backend, sampling apiReq.N candidates. The chat
+// completions adapter samples them in one call with n; other backends get
+// one concurrent call per candidate, merged by mergeCandidates.
+func (e *Engine) sampleResponse(ctx context.Context, apiReq *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	n := candidateCount(apiReq)
+	if n == 1 {
+		return e.llm.CreateResponse(ctx, apiReq)
+	}
+	if _, ok := e.llm.(*api.ChatCompletionsAdapter); ok {
+		return e.llm.CreateResponse(ctx, apiReq)
+	}
+
+	single := *apiReq
+	single.N = nil
+	responses := make([]*api.ResponsesAPIResponse, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = e.llm.CreateResponse(ctx, &single)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeCandidates(responses, nil), nil
+}
+
+// mergeCandidates combines the responses of single-candidate backend calls.
+// Output items are tagged with the index of their candidate and ordered by
+// position, the position of the k-th item of candidate c being
+// positions[{c, k}]; items without a position follow in candidate order.
+// Usage is summed, or nil if any call did not report it. Candidates without
+// a response are skipped.
+func mergeCandidates(responses []*api.ResponsesAPIResponse, positions map[[2]int]int) *api.ResponsesAPIResponse {
+	type positioned struct {
+		pos  int
+		item api.OutputItem
+	}
+	var (
+		merged   *api.ResponsesAPIResponse
+		items    []positioned
+		usage    = &api.UsageInfo{}
+		reported = true
+	)
+	for c, r := range responses {
+		if r == nil {
+			continue
+		}
+		if merged == nil {
+			first := *r
+			merged = &first
+		}
+		if r.Status == "incomplete" {
+			merged.Status = r.Status
+		}
+		for k, item := range r.Output {
+			index := c
+			item.CandidateIndex = &index
+			pos, ok := positions[[2]int{c, k}]
+			if !ok {
+				pos = len(positions) + len(items)
+			}
+			items = append(items, positioned{pos, item})
+		}
+		if r.Usage == nil {
+			reported = false
+			continue
+		}
+		usage.InputTokens += r.Usage.InputTokens
+		usage.OutputTokens += r.Usage.OutputTokens
+		usage.TotalTokens += r.Usage.TotalTokens
+		if cached := r.Usage.CachedTokens(); cached > 0 {
+			if usage.InputTokensDetails == nil {
+				usage.InputTokensDetails = &api.InputTokensDetails{}
+			}
+			usage.InputTokensDetails.CachedTokens += cached
+		}
+		if reasoning := r.Usage.ReasoningTokens(); reasoning > 0 {
+			if usage.OutputTokensDetails == nil {
+				usage.OutputTokensDetails = &api.OutputTokensDetails{}
+			}
+			usage.OutputTokensDetails.ReasoningTokens += reasoning
+		}
+		if audio := r.Usage.InputAudioTokens(); audio > 0 {
+			if usage.InputTokensDetails == nil {
+				usage.InputTokensDetails = &api.InputTokensDetails{}
+			}
+			usage.InputTokensDetails.AudioTokens += audio
+		}
+		if audio := r.Usage.OutputAudioTokens(); audio > 0 {
+			if usage.OutputTokensDetails == nil {
+				usage.OutputTokensDetails = &api.OutputTokensDetails{}
+			}
+			usage.OutputTokensDetails.AudioTokens += audio
+		}
+	}
+	if merged == nil {
+		return nil
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].pos < items[j].pos })
+	merged.Output = make([]api.OutputItem, 0, len(items))
+	for _, p := range items {
+		merged.Output = append(merged.Output, p.item)
+	}
+	merged.Usage = nil
+	if reported {
+		merged.Usage = usage
+	}
+	return merged
+}
+
+// createResponseStream starts streaming from the backend through the
+// recorder of ctx, if any: the streamed events are recorded, or in replay
+// the recorded events are streamed without calling. Calls of responses
+// marked for shadow traffic are also sent to the secondary backend.
+func (e *Engine) createResponseStream(ctx context.Context, apiReq *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	r := recorderFrom(ctx)
+	if r.replaying() {
+		return r.replayStream(apiReq)
+	}
+	i := r.startBackend(apiReq)
+	shadow := e.startShadowCall(ctx, apiReq, true)
+	stream, err := e.sampleResponseStream(ctx, apiReq)
+	if err != nil {
+		shadow.finish(apiReq.Model, nil, 0, err)
+	} else if shadow != nil {
+		stream = shadow.shadowStream(ctx, apiReq.Model, stream)
+	}
+	if err != nil || r == nil {
+		r.finishBackend(i, nil, nil, err)
+		return stream, err
+	}
+	return r.recordStream(ctx, i, stream), nil
+}
+
+// sampleResponseStream starts streaming from the backend. When apiReq.N
+// asks for several candidates, one stream per candidate is started (the
+// chat completions adapter does not stream n choices) and merged by
+// mergeCandidateStreams.
+func (e *Engine) sampleResponseStream(ctx context.Context, apiReq *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	n := candidateCount(apiReq)
+	if n == 1 {
+		return e.llm.CreateResponseStream(ctx, apiReq)
+	}
+
+	single := *apiReq
+	single.N = nil
+	streams := make([]<-chan api.ResponsesStreamEvent, 0, n)
+	for range n {
+		stream, err := e.llm.CreateResponseStream(ctx, &single)
+		if err != nil {
+			// Drain the streams already started so their readers exit
+			for _, s := range streams {
+				go func() {
+					for range s {
+					}
+				}()
+			}
+			return nil, err
+		}
+		streams = append(streams, stream)
+	}
+
+	merged := make(chan api.ResponsesStreamEvent, 10)
+	go mergeCandidateStreams(ctx, streams, merged)
+	return merged, nil
+}
+
+// mergeCandidateStreams forwards the events of concurrent candidate streams
+// to out. Each (candidate, output_index) pair gets a distinct output_index,
+// assigned in the order items first appear. The candidates'
+// response.completed events are replaced by one whose output holds every
+// candidate's items, ordered by their new output_index.
+func mergeCandidateStreams(ctx context.Context, streams []<-chan api.ResponsesStreamEvent, out chan<- api.ResponsesStreamEvent) {
+	defer close(out)
+
+	var (
+		mu        sync.Mutex
+		positions = make(map[[2]int]int)
+		completed = make([]*api.ResponsesAPIResponse, len(streams))
+		wg        sync.WaitGroup
+	)
+	outputIndex := func(candidate, index int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		key := [2]int{candidate, index}
+		pos, ok := positions[key]
+		if !ok {
+			pos = len(positions)
+			positions[key] = pos
+		}
+		return pos
+	}
+
+	for c, stream := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for evt := range stream {
+				if evt.Type == "response.completed" {
+					var wrapper struct {
+						Response api.ResponsesAPIResponse `json:"response"`
+					}
+					if err := json.Unmarshal(evt.Data, &wrapper); err == nil {
+						completed[c] = &wrapper.Response
+					}
+					continue
+				}
+				select {
+				case out <- remapOutputIndex(evt, func(index int) int { return outputIndex(c, index) }):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp := mergeCandidates(completed, positions)
+	if resp == nil {
+		return
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"type":     "response.completed",
+		"response": resp,
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case out <- api.ResponsesStreamEvent{Type: "response.completed", Data: data}:
+	case <-ctx.Done():
+	}
+}
+
+// remapOutputIndex rewrites the output_index of a stream event, if it has
+// one.
+func remapOutputIndex(evt api.ResponsesStreamEvent, remap func(int) int) api.ResponsesStreamEvent {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(evt.Data, &m); err != nil {
+		return evt
+	}
+	raw, ok := m["output_index"]
+	if !ok {
+		return evt
+	}
+	var index int
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return evt
+	}
+	m["output_index"], _ = json.Marshal(remap(index))
+	data, err := json.Marshal(m)
+	if err != nil {
+		return evt
+	}
+	evt.Data = data
+	return evt
+}
+
+// firstCandidate returns the output items of the first candidate, the one
+// kept in the conversation history. Untagged items belong to it.
+func firstCandidate(output []api.OutputItem) []api.OutputItem {
+	var items []api.OutputItem
+	for _, item := range output {
+		if item.CandidateIndex == nil || *item.CandidateIndex == 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
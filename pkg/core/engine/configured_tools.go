@@ -0,0 +1,103 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"slices"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// injectTools adds the configured tools to req: the gateway's, then those
+// of the request's API key. A configured tool replaces the request's tool
+// of the same identity, so that clients cannot redefine it, except for
+// file_search, whose vector stores are added to the request's. Like
+// applyModelParams, it runs before the request is echoed, so responses
+// list the tools the model was offered.
+func (e *Engine) injectTools(ctx context.Context, req *schema.ResponseRequest) {
+	if e.config == nil {
+		return
+	}
+	configured := e.config.Tools
+	if key := state.APIKeyFromContext(ctx); key != "" {
+		configured = append(slices.Clip(configured), e.config.APIKeyTools[key]...)
+	}
+	if len(configured) == 0 {
+		return
+	}
+	// The request's tools may come from a stored prompt template
+	tools := slices.Clone(req.Tools)
+	for _, c := range configured {
+		tools = mergeTool(tools, configuredTool(c))
+	}
+	req.Tools = tools
+}
+
+// toolIdentity returns the key under which a configured tool replaces a
+// request tool: function tools by name, mcp tools by server label, and the
+// other types by type, the engine running one tool of each.
+func toolIdentity(t schema.ResponsesToolParam) string {
+	switch t.Type {
+	case "function":
+		return "function:" + t.Name
+	case "mcp":
+		return "mcp:" + t.ServerLabel
+	}
+	return t.Type
+}
+
+// mergeTool adds t to tools, replacing the tool of the same identity, or
+// adding its vector stores to it for file_search.
+func mergeTool(tools []schema.ResponsesToolParam, t schema.ResponsesToolParam) []schema.ResponsesToolParam {
+	i := slices.IndexFunc(tools, func(r schema.ResponsesToolParam) bool {
+		return toolIdentity(r) == toolIdentity(t)
+	})
+	if i < 0 {
+		return append(tools, t)
+	}
+	if t.Type != "file_search" {
+		tools[i] = t
+		return tools
+	}
+	merged := tools[i]
+	merged.VectorStoreIDs = slices.Clone(merged.VectorStoreIDs)
+	for _, id := range t.VectorStoreIDs {
+		if !slices.Contains(merged.VectorStoreIDs, id) {
+			merged.VectorStoreIDs = append(merged.VectorStoreIDs, id)
+		}
+	}
+	if t.MaxNumResults != nil {
+		merged.MaxNumResults = t.MaxNumResults
+	}
+	tools[i] = merged
+	return tools
+}
+
+// configuredTool returns the request tool of a configured one.
+func configuredTool(c config.ToolConfig) schema.ResponsesToolParam {
+	t := schema.ResponsesToolParam{
+		Type:           c.Type,
+		Name:           c.Name,
+		Parameters:     c.Parameters,
+		Strict:         c.Strict,
+		ServerLabel:    c.ServerLabel,
+		VectorStoreIDs: slices.Clone(c.VectorStoreIDs),
+	}
+	if c.Description != "" {
+		t.Description = &c.Description
+	}
+	if c.AllowedTools != nil {
+		t.AllowedTools = &schema.MCPAllowedTools{ToolNames: slices.Clone(c.AllowedTools)}
+	}
+	if c.SearchContextSize != "" {
+		t.SearchContextSize = &c.SearchContextSize
+	}
+	if c.MaxNumResults > 0 {
+		t.MaxNumResults = &c.MaxNumResults
+	}
+	return t
+}
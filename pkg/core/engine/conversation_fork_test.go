@@ -0,0 +1,51 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func TestProcessRequest_ForkedConversation(t *testing.T) {
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	now := time.Now()
+	if err := store.SaveResponse(ctx, &state.Response{
+		ID: "resp_parent", ConversationID: "conv_parent", Status: "completed", CreatedAt: now,
+		Messages: []state.ConversationMessage{{Role: "user", Content: "first"}, {Role: "assistant", Content: "reply"}},
+	}); err != nil {
+		t.Fatalf("SaveResponse() error = %v", err)
+	}
+	if err := store.CreateConversation(ctx, &state.Conversation{
+		ID: "conv_fork", ForkedFrom: "resp_parent", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateConversation() error = %v", err)
+	}
+	e := &Engine{config: &config.EngineConfig{}, sessions: store, llm: &benchBackend{}, idGen: ids.NewSequence()}
+
+	resp, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "second", Conversation: stringPtr("conv_fork")})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	stored, err := store.GetResponse(ctx, resp.ID)
+	if err != nil {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if len(stored.Messages) < 3 || stored.Messages[0].Content != "first" || stored.Messages[2].Content != "second" {
+		t.Errorf("history of the first turn of the fork = %+v, want the parent's history first", stored.Messages)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// lockConversation holds the conversation the request continues until the
+// returned function is called, so that a concurrent turn on it is refused
+// with state.ErrConversationLocked instead of interleaving its history.
+// New conversations, and stores without locks, need none.
+func (e *Engine) lockConversation(ctx context.Context, req *schema.ResponseRequest, respID string) (func(), error) {
+	locker, ok := e.sessions.(state.ConversationLocker)
+	if !ok || req.Conversation == nil || *req.Conversation == "" {
+		return func() {}, nil
+	}
+	conversationID := *req.Conversation
+	if err := locker.LockConversation(ctx, conversationID, respID, e.conversationLockTTL()); err != nil {
+		return nil, err
+	}
+	return func() {
+		// Unlock even when the client is gone; a failed unlock lapses
+		// with the TTL
+		_ = locker.UnlockConversation(context.WithoutCancel(ctx), conversationID, respID)
+	}, nil
+}
+
+// conversationLockTTL returns how long a request may hold its conversation.
+func (e *Engine) conversationLockTTL() time.Duration {
+	if e.config.ConversationLockTTL > 0 {
+		return e.config.ConversationLockTTL
+	}
+	return 10 * time.Minute
+}
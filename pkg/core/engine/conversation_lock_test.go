@@ -0,0 +1,85 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// gatedBackend holds its streamed responses open until release is closed.
+type gatedBackend struct {
+	benchBackend
+	release chan struct{}
+}
+
+func (b *gatedBackend) CreateResponseStream(ctx context.Context, _ *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	ch := make(chan api.ResponsesStreamEvent, 1)
+	go func() {
+		defer close(ch)
+		select {
+		case <-b.release:
+		case <-ctx.Done():
+			return
+		}
+		data, _ := json.Marshal(backendCompleted(backendMessage("msg_backend", "done")))
+		ch <- api.ResponsesStreamEvent{Type: "response.completed", Data: data}
+	}()
+	return ch, nil
+}
+
+func TestProcessRequest_ConversationLock(t *testing.T) {
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	if err := store.CreateConversation(ctx, &state.Conversation{ID: "conv_1", CreatedAt: time.Now(), UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateConversation() error = %v", err)
+	}
+	backend := &gatedBackend{release: make(chan struct{})}
+	e := &Engine{config: &config.EngineConfig{}, sessions: store, llm: backend, idGen: ids.NewSequence()}
+	turn := func() *schema.ResponseRequest {
+		return &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi", Conversation: stringPtr("conv_1")}
+	}
+
+	events, err := e.ProcessRequestStream(ctx, turn())
+	if err != nil {
+		t.Fatalf("ProcessRequestStream() error = %v", err)
+	}
+
+	// The streamed turn holds the conversation
+	if _, err := e.ProcessRequest(ctx, turn()); !errors.Is(err, state.ErrConversationLocked) {
+		t.Fatalf("concurrent ProcessRequest() error = %v, want ErrConversationLocked", err)
+	}
+	if _, err := e.ProcessRequestStream(ctx, turn()); !errors.Is(err, state.ErrConversationLocked) {
+		t.Fatalf("concurrent ProcessRequestStream() error = %v, want ErrConversationLocked", err)
+	}
+	if _, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"}); err != nil {
+		t.Fatalf("ProcessRequest() on a new conversation error = %v", err)
+	}
+
+	close(backend.release)
+	for range events {
+	}
+	resp, err := e.ProcessRequest(ctx, turn())
+	if err != nil {
+		t.Fatalf("ProcessRequest() after the streamed turn error = %v", err)
+	}
+	if resp.Status != "completed" {
+		t.Errorf("status = %q, want completed", resp.Status)
+	}
+}
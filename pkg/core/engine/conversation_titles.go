@@ -0,0 +1,164 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// TitleMetadataKey is the conversation metadata key generated titles are
+// stored under.
+const TitleMetadataKey = "title"
+
+const (
+	// titleInstructions asks the backend for a title.
+	titleInstructions = "Write a short title, of at most eight words, for the conversation that starts with the exchange below. " +
+		"Use the language of the user. Reply with the title only, without quotes or final punctuation."
+
+	// titleExcerptRunes bounds each side of the exchange sent for a title.
+	titleExcerptRunes = 2000
+
+	// titleTimeout bounds the generation of a title.
+	titleTimeout = 30 * time.Second
+)
+
+// titleConfig holds the settings of conversation title generation.
+type titleConfig struct {
+	model     string // model asked for titles; the turn's when empty
+	maxLength int    // runes kept of a title
+}
+
+// SetConversationTitles enables titles generated for conversations from
+// their first turn, stored as their "title" metadata. Titles are asked of
+// model, or of the model of the turn if empty, and cut to maxLength
+// characters. Session stores that do not implement
+// state.ConversationMetadataAdder get no titles.
+func (e *Engine) SetConversationTitles(model string, maxLength int) {
+	e.titles = &titleConfig{model: model, maxLength: maxLength}
+}
+
+// titleConversation generates the title of conv in the background if resp
+// completed its first turn and it has none. conv is the conversation as it
+// was before the turn.
+func (e *Engine) titleConversation(ctx context.Context, conv *state.Conversation, req *schema.ResponseRequest, resp *schema.Response) {
+	if e.titles == nil || resp.Status != "completed" || len(conv.Messages) > 0 || conv.Metadata[TitleMetadataKey] != "" {
+		return
+	}
+	// Responses chained with previous_response_id get a conversation of
+	// their own, but do not start one
+	if req.PreviousResponseID != nil && *req.PreviousResponseID != "" {
+		return
+	}
+	adder, ok := e.sessions.(state.ConversationMetadataAdder)
+	if !ok {
+		return
+	}
+	input := firstUserText(req.Input)
+	output := strings.Join(outputTextParts(resp.Output), "\n")
+	if input == "" {
+		return
+	}
+	model := e.titles.model
+	if model == "" {
+		model = resp.Model
+	}
+
+	// The title outlives the request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+		defer cancel()
+		title, err := e.generateTitle(ctx, model, input, output)
+		if err != nil || title == "" {
+			return
+		}
+		_, _ = adder.AddConversationMetadata(ctx, conv.ID, TitleMetadataKey, title)
+	}()
+}
+
+// generateTitle asks the backend for the title of a conversation starting
+// with the given exchange.
+func (e *Engine) generateTitle(ctx context.Context, model, input, output string) (string, error) {
+	exchange := "User: " + truncateRunes(input, titleExcerptRunes)
+	if output != "" {
+		exchange += "\n\nAssistant: " + truncateRunes(output, titleExcerptRunes)
+	}
+	instructions := titleInstructions
+	storeFalse := false
+	maxOutputTokens := 32
+	apiResp, err := e.llm.CreateResponse(ctx, &api.ResponsesAPIRequest{
+		Model:           model,
+		Input:           exchange,
+		Instructions:    &instructions,
+		MaxOutputTokens: &maxOutputTokens,
+		Store:           &storeFalse,
+	})
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, item := range apiResp.Output {
+		for _, c := range item.Content {
+			if c.Type == "output_text" {
+				text.WriteString(c.Text)
+			}
+		}
+	}
+	return cleanTitle(text.String(), e.titles.maxLength), nil
+}
+
+// cleanTitle keeps the first line of a generated title, without the
+// quotes, markup and final punctuation models add, cut to maxLength runes.
+func cleanTitle(title string, maxLength int) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	const markup = " \t\"'`*#“”‘’«»"
+	title = strings.Trim(title, markup)
+	title = strings.Trim(strings.TrimPrefix(title, "Title:"), markup)
+	title = strings.TrimSpace(strings.TrimRight(title, ".。"))
+	if maxLength > 0 {
+		if runes := []rune(title); len(runes) > maxLength {
+			title = strings.TrimSpace(string(runes[:maxLength-1])) + "…"
+		}
+	}
+	return title
+}
+
+// firstUserText returns the text of the first user message of an input.
+func firstUserText(input interface{}) string {
+	for _, msg := range extractInputMessages(input) {
+		if msg.Role != "user" {
+			continue
+		}
+		if msg.Content != "" {
+			return msg.Content
+		}
+		var parts []string
+		for _, cp := range msg.ContentParts {
+			if cp.Text != "" {
+				parts = append(parts, cp.Text)
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "\n")
+		}
+	}
+	return ""
+}
+
+// truncateRunes cuts s to at most n runes.
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// titleBackend answers title requests with a quoted title and counts them.
+type titleBackend struct {
+	benchBackend
+	mu     sync.Mutex
+	titles int
+	input  string
+}
+
+func (b *titleBackend) CreateResponse(ctx context.Context, req *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	if req.Instructions == nil || *req.Instructions != titleInstructions {
+		return b.benchBackend.CreateResponse(ctx, req)
+	}
+	b.mu.Lock()
+	b.titles++
+	b.input, _ = req.Input.(string)
+	b.mu.Unlock()
+	return &api.ResponsesAPIResponse{
+		Status: "completed",
+		Output: []api.OutputItem{{
+			Type: "message", Role: "assistant",
+			Content: []api.ContentItem{{Type: "output_text", Text: "\"Greeting the gateway.\"\n"}},
+		}},
+	}, nil
+}
+
+func (b *titleBackend) calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.titles
+}
+
+// waitForTitle polls the conversation until it has a title or a second
+// has passed.
+func waitForTitle(t *testing.T, store state.SessionStore, convID string) string {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		conv, err := store.GetConversation(context.Background(), convID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if title := conv.Metadata[TitleMetadataKey]; title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+func TestProcessRequest_ConversationTitle(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	backend := &titleBackend{}
+	e := &Engine{config: &config.EngineConfig{}, sessions: store, llm: backend, idGen: ids.NewSequence()}
+	e.SetConversationTitles("", 80)
+
+	resp, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hello gateway"})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	if got := waitForTitle(t, store, *resp.Conversation); got != "Greeting the gateway" {
+		t.Fatalf("title = %q, want %q", got, "Greeting the gateway")
+	}
+	backend.mu.Lock()
+	input := backend.input
+	backend.mu.Unlock()
+	if want := "User: hello gateway\n\nAssistant: Hello from the benchmark backend."; input != want {
+		t.Errorf("title input = %q, want %q", input, want)
+	}
+
+	// Later turns of the conversation and chained responses are not titled
+	if _, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "again", Conversation: resp.Conversation}); err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	chained, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "more", PreviousResponseID: &resp.ID})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+
+	// A title set by the client is kept
+	conv := &state.Conversation{ID: "conv_titled", Metadata: map[string]string{TitleMetadataKey: "Mine"}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateConversation(ctx, conv); err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	if _, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi", Conversation: &conv.ID}); err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := backend.calls(); n != 1 {
+		t.Errorf("backend asked for %d titles, want 1", n)
+	}
+	if got, _ := store.GetConversation(ctx, *chained.Conversation); got.Metadata[TitleMetadataKey] != "" {
+		t.Errorf("chained response titled %q", got.Metadata[TitleMetadataKey])
+	}
+	if got, _ := store.GetConversation(ctx, conv.ID); got.Metadata[TitleMetadataKey] != "Mine" {
+		t.Errorf("client title replaced by %q", got.Metadata[TitleMetadataKey])
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in        string
+		maxLength int
+		want      string
+	}{
+		{"Trip to Lisbon", 80, "Trip to Lisbon"},
+		{"  \"Trip to Lisbon.\"\n", 80, "Trip to Lisbon"},
+		{"**Title:** Trip to Lisbon", 80, "Trip to Lisbon"},
+		{"Trip to Lisbon\nA conversation about travel", 80, "Trip to Lisbon"},
+		{"« Voyage à Lisbonne »", 80, "Voyage à Lisbonne"},
+		{"Planning a long trip to Lisbon", 12, "Planning a…"},
+		{"\"\"", 80, ""},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in, tt.maxLength); got != tt.want {
+			t.Errorf("cleanTitle(%q, %d) = %q, want %q", tt.in, tt.maxLength, got, tt.want)
+		}
+	}
+}
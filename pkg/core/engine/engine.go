@@ -4,21 +4,37 @@
 package engine
 
 import (
+	"cmp"
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/admission"
 	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
 	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/egress"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/ids"
 	"github.com/leseb/openresponses-gw/pkg/mcp"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/tokenizer"
 	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+	"github.com/leseb/openresponses-gw/pkg/websearch"
 )
 
 const defaultMaxToolCalls = 10
@@ -47,6 +63,13 @@ type WebSearchResult struct {
 	Snippet string
 }
 
+// Moderator screens text against a content moderation policy.
+// Implemented by an adapter over moderation.Provider.
+type Moderator interface {
+	// Check returns the violated categories; an empty result means the text is allowed.
+	Check(ctx context.Context, text string) ([]string, error)
+}
+
 // PromptResolver resolves stored prompt templates.
 type PromptResolver interface {
 	GetPrompt(ctx context.Context, promptID string) (*memory.Prompt, error)
@@ -57,13 +80,45 @@ type PromptResolver interface {
 // It calls a /v1/responses-compatible backend for inference and adds
 // persistence, conversations, MCP tools, file_search, web_search, and prompts.
 type Engine struct {
-	config       *config.EngineConfig
-	sessions     state.SessionStore
-	llm          api.ResponsesAPIClient
-	connectors   ConnectorLookup // nil-safe: nil means no MCP support
-	vectorSearch VectorSearcher  // nil-safe: nil means no file_search support
-	webSearch    WebSearcher     // nil-safe: nil means no web_search support
-	prompts      PromptResolver  // nil-safe: nil means no prompt resolution
+	config         *config.EngineConfig
+	sessions       state.SessionStore
+	llm            api.ResponsesAPIClient
+	connectors     ConnectorLookup     // nil-safe: nil means no MCP support
+	vectorSearch   VectorSearcher      // nil-safe: nil means no file_search support
+	webSearch      WebSearcher         // nil-safe: nil means no web_search support
+	urlFetch       URLFetcher          // nil-safe: nil means no fetch_url tool
+	files          filestore.FileStore // nil-safe: nil means file_id parts are forwarded as is
+	imageFetch     ImageFetcher        // nil-safe: nil means image URLs are forwarded as is
+	prompts        PromptResolver      // nil-safe: nil means no prompt resolution
+	hooks          *hooks.Chain        // nil-safe: nil means no request/response hooks
+	moderation     *moderationConfig
+	stdioServers   *mcp.StdioManager      // nil-safe: nil means no stdio connectors
+	egress         *egress.Set            // nil-safe: nil means unrestricted connector requests
+	features       *featureflags.Flags    // nil-safe: nil means every flag is off
+	provenance     *provenanceConfig      // nil-safe: nil means no provenance block
+	watermarker    Watermarker            // nil-safe: nil means no watermarking
+	tokens         tokenizer.TokenCounter // nil-safe: nil means the heuristic counter
+	idGen          ids.Generator          // nil-safe: nil means ids.Default
+	responseCache  state.ResponseCache    // nil-safe: nil means no response caching
+	recordings     state.RecordingStore   // nil-safe: nil means no recording or replay
+	shadow         *shadowTraffic         // nil-safe: nil means no shadow traffic
+	aliases        modelAliases
+	admission      *admission.Controller // nil-safe: nil means no admission control
+	titles         *titleConfig          // nil-safe: nil means no conversation titles
+	responseEvents bool                  // record response events in the session store outbox
+	activity       activityFeed
+	streamStats    streamStats
+
+	interrupt     chan struct{} // closed by Interrupt
+	interruptOnce sync.Once
+}
+
+// moderationConfig holds the content moderator and the stages it screens.
+type moderationConfig struct {
+	moderator      Moderator
+	input          bool
+	output         bool
+	refusalMessage string
 }
 
 // New creates a new Engine instance.
@@ -80,43 +135,279 @@ func New(cfg *config.EngineConfig, store state.SessionStore, connectors Connecto
 	if cfg.ModelEndpoint == "" {
 		return nil, fmt.Errorf("model endpoint is required (set OPENAI_API_ENDPOINT)")
 	}
-	var llm api.ResponsesAPIClient
-	if cfg.BackendAPI == "responses" {
-		llm = api.NewOpenAIResponsesClient(cfg.ModelEndpoint, cfg.APIKey)
-	} else {
-		llm = api.NewChatCompletionsAdapter(cfg.ModelEndpoint, cfg.APIKey)
-	}
+	llm := newBackendClient(cfg.BackendAPI, cfg.ModelEndpoint, cfg.APIKey, cfg.Ollama)
 
 	var promptResolver PromptResolver
 	if len(prompts) > 0 {
 		promptResolver = prompts[0]
 	}
 
+	tokens, err := tokenizer.New(cfg.Tokenizer.Encoding, cfg.Tokenizer.VocabFile)
+	if err != nil {
+		return nil, err
+	}
+
+	idGen, err := ids.New(cfg.IDFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseCache state.ResponseCache
+	if cfg.ResponseCache.Enabled {
+		c, ok := store.(state.ResponseCache)
+		if !ok {
+			return nil, fmt.Errorf("session store does not support the response cache")
+		}
+		responseCache = c
+	}
+
+	recordings, _ := store.(state.RecordingStore)
+	if cfg.Recording.Enabled && recordings == nil {
+		return nil, fmt.Errorf("session store does not support recording")
+	}
+
+	var shadow *shadowTraffic
+	if cfg.Shadow.ModelEndpoint != "" {
+		shadowStore, ok := store.(state.ShadowStore)
+		if !ok {
+			return nil, fmt.Errorf("session store does not support shadow traffic")
+		}
+		shadowLLM := newBackendClient(cfg.Shadow.BackendAPI, cfg.Shadow.ModelEndpoint, cfg.Shadow.APIKey, cfg.Ollama)
+		shadow = newShadowTraffic(cfg.Shadow, shadowLLM, shadowStore)
+	}
+
 	return &Engine{
-		config:       cfg,
-		sessions:     store,
-		llm:          llm,
-		connectors:   connectors,
-		vectorSearch: vectorSearch,
-		webSearch:    webSearch,
-		prompts:      promptResolver,
+		config:        cfg,
+		sessions:      store,
+		llm:           llm,
+		connectors:    connectors,
+		vectorSearch:  vectorSearch,
+		webSearch:     webSearch,
+		prompts:       promptResolver,
+		tokens:        tokens,
+		idGen:         idGen,
+		responseCache: responseCache,
+		recordings:    recordings,
+		shadow:        shadow,
+		aliases:       modelAliases{targets: maps.Clone(cfg.ModelAliases)},
+		admission:     newAdmission(cfg.Admission),
+		interrupt:     make(chan struct{}),
 	}, nil
 }
 
+// newBackendClient returns the client of a backend serving the given API:
+// "responses", "ollama", or otherwise chat completions.
+func newBackendClient(backendAPI, endpoint, apiKey string, ollama config.OllamaConfig) api.ResponsesAPIClient {
+	switch backendAPI {
+	case "responses":
+		return api.NewOpenAIResponsesClient(endpoint, apiKey)
+	case "ollama":
+		return api.NewOllamaAdapter(endpoint, apiKey, api.OllamaOptions{
+			AutoPull:  ollama.AutoPull,
+			KeepAlive: ollama.KeepAlive,
+		})
+	default:
+		return api.NewChatCompletionsAdapter(endpoint, apiKey)
+	}
+}
+
+// SetIDGenerator replaces the generator of the IDs the engine and the HTTP
+// handler assign, which defaults to the configured format. Tests can inject
+// an ids.Sequence for deterministic IDs.
+func (e *Engine) SetIDGenerator(g ids.Generator) {
+	e.idGen = g
+}
+
+// NewID returns a new ID with the given prefix.
+func (e *Engine) NewID(prefix string) string {
+	if e.idGen == nil {
+		return ids.Default.NewID(prefix)
+	}
+	return e.idGen.NewID(prefix)
+}
+
 // Store returns the session store
 func (e *Engine) Store() state.SessionStore {
 	return e.sessions
 }
 
+// loopGuard returns the guard for a request's agentic loop, which also
+// stops when the engine is interrupted or the client cancels the response.
+func (e *Engine) loopGuard(ctx context.Context, req *schema.ResponseRequest) *loopGuard {
+	g := newLoopGuard(e.config.Loop, req, time.Now())
+	if deadline := requestDeadline(ctx); !deadline.IsZero() && (g.deadline.IsZero() || deadline.Before(g.deadline)) {
+		g.deadline = deadline
+	}
+	g.interrupt = e.interrupt
+	g.cancel = Cancelled(ctx)
+	return g
+}
+
+// Interrupt stops the agentic loop of every in-flight response, for
+// shutdown. Pending backend and tool calls are canceled, and the responses
+// end as "incomplete" with reason "interrupted", keeping and saving the
+// output produced so far.
+func (e *Engine) Interrupt() {
+	e.interruptOnce.Do(func() {
+		if e.interrupt != nil {
+			close(e.interrupt)
+		}
+	})
+}
+
+// SetHooks installs the request/response hook chain. Hooks run in the order
+// they were added to the chain.
+func (e *Engine) SetHooks(chain *hooks.Chain) {
+	e.hooks = chain
+}
+
+// SetStdioManager enables MCP connectors backed by local stdio servers.
+// Without it, requests that use such a connector fail.
+func (e *Engine) SetStdioManager(m *mcp.StdioManager) {
+	e.stdioServers = m
+}
+
+// SetEgress installs the egress policies enforced on the requests of MCP
+// and HTTP tool connectors.
+func (e *Engine) SetEgress(s *egress.Set) {
+	e.egress = s
+}
+
+// SetFeatureFlags installs the feature flags that gate experimental
+// behaviors. Flags are evaluated for the tenant carried by the request
+// context (see featureflags.WithTenant).
+func (e *Engine) SetFeatureFlags(f *featureflags.Flags) {
+	e.features = f
+}
+
+// Features returns the engine's feature flags, or nil if none are set.
+func (e *Engine) Features() *featureflags.Flags {
+	return e.features
+}
+
+// runResponseHooks applies the response hooks to a finished response. If a
+// hook rejects or fails, the output is discarded and the response is marked
+// as failed so that the rejected content is neither returned nor stored.
+func (e *Engine) runResponseHooks(ctx context.Context, req *schema.ResponseRequest, resp *schema.Response) {
+	err := e.hooks.RunResponse(ctx, req, resp)
+	if err == nil {
+		return
+	}
+	errType, code := apierror.TypeServerError, "hook_error"
+	var rejectErr *hooks.RejectError
+	if errors.As(err, &rejectErr) {
+		errType, code = apierror.TypeInvalidRequest, "hook_rejected"
+	}
+	resp.Output = make([]schema.ItemField, 0)
+	resp.MarkFailed(errType, code, err.Error())
+}
+
+// SetModerator enables content moderation. Input is screened before it is
+// sent to the backend and output before the response is returned; either
+// stage can be disabled. Violations produce a refusal message and a failed
+// response with error type "content_filter".
+func (e *Engine) SetModerator(m Moderator, screenInput, screenOutput bool, refusalMessage string) {
+	if refusalMessage == "" {
+		refusalMessage = defaultRefusalMessage
+	}
+	e.moderation = &moderationConfig{
+		moderator:      m,
+		input:          screenInput,
+		output:         screenOutput,
+		refusalMessage: refusalMessage,
+	}
+}
+
+const defaultRefusalMessage = "I'm sorry, but I can't help with that request."
+
+// moderateInput screens the new input items of a request. It returns the
+// violated categories, or nil if moderation is disabled for input.
+func (e *Engine) moderateInput(ctx context.Context, req *schema.ResponseRequest) ([]string, error) {
+	if e.moderation == nil || !e.moderation.input {
+		return nil, nil
+	}
+	ctx, cancel := withinRequest(ctx)
+	defer cancel()
+	var parts []string
+	for _, msg := range extractInputMessages(req.Input) {
+		if msg.Content != "" {
+			parts = append(parts, msg.Content)
+		}
+		for _, cp := range msg.ContentParts {
+			if cp.Type == "text" && cp.Text != "" {
+				parts = append(parts, cp.Text)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	return e.moderation.moderator.Check(ctx, strings.Join(parts, "\n"))
+}
+
+// moderateOutput screens the text of the final output items. It returns the
+// violated categories, or nil if moderation is disabled for output.
+func (e *Engine) moderateOutput(ctx context.Context, output []schema.ItemField) ([]string, error) {
+	if e.moderation == nil || !e.moderation.output {
+		return nil, nil
+	}
+	parts := outputTextParts(output)
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	return e.moderation.moderator.Check(ctx, strings.Join(parts, "\n"))
+}
+
+// refuse replaces the response output with a refusal message and marks the
+// response as failed. code is "input_flagged" or "output_flagged".
+func (e *Engine) refuse(resp *schema.Response, code string, categories []string) schema.ItemField {
+	item := refusalItem(e.NewID("msg_"), e.moderation.refusalMessage)
+	resp.Output = []schema.ItemField{item}
+	resp.MarkFailed("content_filter", code, fmt.Sprintf("content flagged by moderation: %s", strings.Join(categories, ", ")))
+	return item
+}
+
+// refusalItem builds an assistant message carrying a single refusal part.
+func refusalItem(id, message string) schema.ItemField {
+	role := "assistant"
+	status := "completed"
+	return schema.ItemField{
+		Type:   "message",
+		ID:     id,
+		Role:   &role,
+		Status: &status,
+		Content: []schema.ContentPart{{
+			Type:    "refusal",
+			Refusal: &message,
+		}},
+	}
+}
+
+// PromptError reports a prompt reference that cannot be resolved, such as an
+// unknown prompt or version. It is a client error.
+type PromptError struct {
+	PromptID string
+	Err      error
+}
+
+func (e *PromptError) Error() string {
+	return fmt.Sprintf("prompt %q: %v", e.PromptID, e.Err)
+}
+
+func (e *PromptError) Unwrap() error { return e.Err }
+
 // resolvePromptRef resolves a prompt reference in the request, rendering the
 // template with the provided variables and setting the result as Instructions.
 // Returns an error if both Prompt and Instructions are set.
+// String and input_text variables are substituted inline; input_image and
+// input_file variables are attached as a leading user message and referenced
+// by name in the rendered text.
 func (e *Engine) resolvePromptRef(ctx context.Context, req *schema.ResponseRequest) error {
 	if req.Prompt == nil {
 		return nil
 	}
 	if req.Instructions != nil {
-		return fmt.Errorf("prompt and instructions are mutually exclusive")
+		return &PromptError{PromptID: req.Prompt.ID, Err: fmt.Errorf("prompt and instructions are mutually exclusive")}
 	}
 	if e.prompts == nil {
 		return fmt.Errorf("prompt resolution is not configured")
@@ -130,15 +421,73 @@ func (e *Engine) resolvePromptRef(ctx context.Context, req *schema.ResponseReque
 		prompt, err = e.prompts.GetPrompt(ctx, req.Prompt.ID)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to get prompt %q: %w", req.Prompt.ID, err)
+		return &PromptError{PromptID: req.Prompt.ID, Err: err}
+	}
+
+	textVars := make(map[string]string, len(req.Prompt.Variables))
+	var mediaNames []string
+	for name, v := range req.Prompt.Variables {
+		if v.Type == "input_text" {
+			textVars[name] = v.Text
+		} else {
+			textVars[name] = fmt.Sprintf("[attached %s: %s]", strings.TrimPrefix(v.Type, "input_"), name)
+			mediaNames = append(mediaNames, name)
+		}
 	}
 
-	rendered := memory.RenderPrompt(prompt.Template, req.Prompt.Variables)
+	rendered := memory.RenderPrompt(prompt.Template, textVars)
 	req.Instructions = &rendered
+
+	if len(mediaNames) > 0 {
+		sort.Strings(mediaNames)
+		parts := make([]interface{}, 0, 2*len(mediaNames))
+		for _, name := range mediaNames {
+			parts = append(parts,
+				map[string]interface{}{"type": "input_text", "text": name + ":"},
+				promptVariablePart(req.Prompt.Variables[name]))
+		}
+		attachment := map[string]interface{}{"type": "message", "role": "user", "content": parts}
+		req.Input = prependInputItem(req.Input, attachment)
+	}
 	return nil
 }
 
-// BackendAPI returns the configured backend API mode ("responses" or "chat_completions").
+// promptVariablePart converts a media prompt variable to an input content part.
+func promptVariablePart(v schema.PromptVariable) map[string]interface{} {
+	part := map[string]interface{}{"type": v.Type}
+	if v.Type == "input_image" {
+		part["image_url"] = v.ImageURL
+		if v.Detail != "" {
+			part["detail"] = v.Detail
+		}
+		return part
+	}
+	if v.FileID != "" {
+		part["file_id"] = v.FileID
+	}
+	if v.FileData != "" {
+		part["file_data"] = v.FileData
+	}
+	if v.Filename != "" {
+		part["filename"] = v.Filename
+	}
+	return part
+}
+
+// prependInputItem inserts item before the request input, converting a
+// string input into a user message item.
+func prependInputItem(input interface{}, item map[string]interface{}) []interface{} {
+	switch v := input.(type) {
+	case string:
+		return []interface{}{item, map[string]interface{}{"type": "message", "role": "user", "content": v}}
+	case []interface{}:
+		return append([]interface{}{item}, v...)
+	default:
+		return []interface{}{item}
+	}
+}
+
+// BackendAPI returns the configured backend API mode ("responses", "chat_completions" or "ollama").
 func (e *Engine) BackendAPI() string {
 	return e.config.BackendAPI
 }
@@ -161,6 +510,7 @@ func echoRequestParams(resp *schema.Response, req *schema.ResponseRequest) {
 	}
 	resp.MaxOutputTokens = req.MaxOutputTokens
 	resp.MaxToolCalls = req.MaxToolCalls
+	resp.CandidateCount = req.CandidateCount
 	if req.FrequencyPenalty != nil {
 		resp.FrequencyPenalty = *req.FrequencyPenalty
 	}
@@ -185,10 +535,20 @@ func echoRequestParams(resp *schema.Response, req *schema.ResponseRequest) {
 	// Service tier (echoed from request)
 	resp.ServiceTier = req.ServiceTier
 
+	// Prompt cache key (echoed from request)
+	resp.PromptCacheKey = req.PromptCacheKey
+
 	// Gateway-managed persistence flag
 	if req.Store != nil {
 		resp.Store = *req.Store
 	}
+	resp.Background = req.Background
+
+	resp.ExternalID = req.ExternalID
+	resp.MaxDurationSeconds = req.MaxDurationSeconds
+	resp.MaxBackendCalls = req.MaxBackendCalls
+	resp.MaxTotalTokens = req.MaxTotalTokens
+	resp.InstructionsMerge = req.InstructionsMerge
 }
 
 // extractInputMessages parses the Responses API input field into chat messages
@@ -206,7 +566,7 @@ func extractInputMessages(input interface{}) []api.Message {
 			itemType, _ := itemMap["type"].(string)
 			switch itemType {
 			case "message":
-				role, _ := itemMap["role"].(string)
+				role := inputRole(itemMap)
 				if role == "" {
 					continue
 				}
@@ -224,6 +584,17 @@ func extractInputMessages(input interface{}) []api.Message {
 						ToolCallID: callID,
 					})
 				}
+			case "mcp_call", "file_search_call":
+				// Failed server-side tool calls answer their function_call
+				callID, _ := itemMap["call_id"].(string)
+				errMsg, _ := itemMap["error"].(string)
+				if callID != "" && errMsg != "" {
+					messages = append(messages, api.Message{
+						Role:       "tool",
+						Content:    toolErrorOutput(errMsg),
+						ToolCallID: callID,
+					})
+				}
 			case "function_call":
 				callID, _ := itemMap["call_id"].(string)
 				name, _ := itemMap["name"].(string)
@@ -243,10 +614,14 @@ func extractInputMessages(input interface{}) []api.Message {
 						},
 					})
 				}
+			case "reasoning":
+				if r := extractReasoningFromItem(itemMap); r != nil {
+					messages = append(messages, api.Message{Role: "assistant", Reasoning: r})
+				}
 			default:
 				// Try to extract content for unknown types
 				if content, ok := itemMap["content"].(string); ok && content != "" {
-					role, _ := itemMap["role"].(string)
+					role := inputRole(itemMap)
 					if role == "" {
 						role = "user"
 					}
@@ -263,6 +638,46 @@ func extractInputMessages(input interface{}) []api.Message {
 	}
 }
 
+// inputRole returns the role of an input message item. The system role is
+// reserved for the request's instructions, which outrank every input item,
+// so input system messages are read as developer messages: they keep their
+// place in the conversation and are sent to the backend as such.
+func inputRole(item map[string]interface{}) string {
+	role, _ := item["role"].(string)
+	if role == "system" {
+		return "developer"
+	}
+	return role
+}
+
+// extractReasoningFromItem extracts a reasoning input item, as returned in
+// the output of an earlier response.
+func extractReasoningFromItem(item map[string]interface{}) *api.Reasoning {
+	id, _ := item["id"].(string)
+	if id == "" {
+		return nil
+	}
+	r := &api.Reasoning{ID: id}
+	r.EncryptedContent, _ = item["encrypted_content"].(string)
+	if summary, ok := item["summary"].([]interface{}); ok {
+		for _, part := range summary {
+			if partMap, ok := part.(map[string]interface{}); ok {
+				text, _ := partMap["text"].(string)
+				r.Summary = append(r.Summary, text)
+			}
+		}
+	}
+	if content, ok := item["content"].([]interface{}); ok {
+		for _, part := range content {
+			if partMap, ok := part.(map[string]interface{}); ok {
+				text, _ := partMap["text"].(string)
+				r.Text += text
+			}
+		}
+	}
+	return r
+}
+
 // extractMessageFromItem extracts a Message from a message input item,
 // handling both text-only and multimodal (image/file) content parts.
 func extractMessageFromItem(item map[string]interface{}, role string) *api.Message {
@@ -311,6 +726,12 @@ func extractMessageFromItem(item map[string]interface{}, role string) *api.Messa
 						cp.ImageURL = &api.MessageImageURL{URL: url}
 					}
 				}
+				if cp.ImageURL == nil {
+					// An uploaded image, resolved before the backend call
+					if fileID, ok := partMap["file_id"].(string); ok {
+						cp.ImageURL = &api.MessageImageURL{FileID: fileID}
+					}
+				}
 				if detail, ok := partMap["detail"].(string); ok && cp.ImageURL != nil && cp.ImageURL.Detail == "" {
 					cp.ImageURL.Detail = detail
 				}
@@ -331,6 +752,33 @@ func extractMessageFromItem(item map[string]interface{}, role string) *api.Messa
 				}
 				cp.File = file
 				contentParts = append(contentParts, cp)
+			case "input_audio":
+				hasNonText = true
+				audio := &api.MessageAudio{}
+				// Audio can be nested under "input_audio" as in chat completions, or at top level
+				if audioMap, ok := partMap["input_audio"].(map[string]interface{}); ok {
+					audio.Data, _ = audioMap["data"].(string)
+					audio.Format, _ = audioMap["format"].(string)
+				} else {
+					audio.Data, _ = partMap["data"].(string)
+					audio.Format, _ = partMap["format"].(string)
+				}
+				contentParts = append(contentParts, api.MessageContentPart{Type: "input_audio", InputAudio: audio})
+			case "input_video":
+				hasNonText = true
+				video := &api.MessageVideoURL{}
+				// video_url can be a string or an object, or the video an uploaded file
+				switch v := partMap["video_url"].(type) {
+				case string:
+					video.URL = v
+				case map[string]interface{}:
+					video.URL, _ = v["url"].(string)
+				}
+				if video.URL == "" {
+					video.URL, _ = partMap["url"].(string)
+				}
+				video.FileID, _ = partMap["file_id"].(string)
+				contentParts = append(contentParts, api.MessageContentPart{Type: "video_url", VideoURL: video})
 			}
 		}
 
@@ -424,6 +872,9 @@ func buildResponsesAPIRequest(model string, messages []api.Message, req *schema.
 	apiReq.FrequencyPenalty = req.FrequencyPenalty
 	apiReq.PresencePenalty = req.PresencePenalty
 	apiReq.MaxOutputTokens = req.MaxOutputTokens
+	if req.CandidateCount != nil && *req.CandidateCount > 1 {
+		apiReq.N = req.CandidateCount
+	}
 
 	// Tool choice
 	apiReq.ToolChoice = req.ToolChoice
@@ -450,7 +901,8 @@ func buildResponsesAPIRequest(model string, messages []api.Message, req *schema.
 }
 
 // convertMessagesToResponsesInput converts internal Messages to the Responses
-// API input format. System messages are skipped (handled by the Instructions field).
+// API input format. System messages are skipped (handled by the Instructions
+// field); developer messages are kept in place with their role.
 //
 // vLLM compatibility: vLLM's /v1/responses endpoint only accepts the simple
 // {role, content} format for user and assistant text messages. The structured
@@ -458,7 +910,7 @@ func buildResponsesAPIRequest(model string, messages []api.Message, req *schema.
 // text:"..."}]}) causes vLLM to return 400 errors with Pydantic validation
 // failures in multi-turn conversations. We therefore use the simple chat-style
 // format for plain text messages, and only use the structured format for
-// multimodal content (images, files) and tool calls (function_call,
+// multimodal content (images, files, audio, video) and tool calls (function_call,
 // function_call_output) which require it.
 func convertMessagesToResponsesInput(messages []api.Message) []interface{} {
 	var input []interface{}
@@ -480,10 +932,13 @@ func convertMessagesToResponsesInput(messages []api.Message) []interface{} {
 						})
 					case "image_url":
 						if cp.ImageURL != nil {
-							parts = append(parts, map[string]interface{}{
-								"type":      "input_image",
-								"image_url": cp.ImageURL.URL,
-							})
+							part := map[string]interface{}{"type": "input_image"}
+							if cp.ImageURL.URL != "" {
+								part["image_url"] = cp.ImageURL.URL
+							} else {
+								part["file_id"] = cp.ImageURL.FileID
+							}
+							parts = append(parts, part)
 						}
 					case "file":
 						if cp.File != nil {
@@ -502,6 +957,26 @@ func convertMessagesToResponsesInput(messages []api.Message) []interface{} {
 								"file": fileMap,
 							})
 						}
+					case "video_url":
+						if cp.VideoURL != nil {
+							part := map[string]interface{}{"type": "input_video"}
+							if cp.VideoURL.URL != "" {
+								part["video_url"] = cp.VideoURL.URL
+							} else {
+								part["file_id"] = cp.VideoURL.FileID
+							}
+							parts = append(parts, part)
+						}
+					case "input_audio":
+						if cp.InputAudio != nil {
+							parts = append(parts, map[string]interface{}{
+								"type": "input_audio",
+								"input_audio": map[string]interface{}{
+									"data":   cp.InputAudio.Data,
+									"format": cp.InputAudio.Format,
+								},
+							})
+						}
 					}
 				}
 				input = append(input, map[string]interface{}{
@@ -517,6 +992,9 @@ func convertMessagesToResponsesInput(messages []api.Message) []interface{} {
 				})
 			}
 		case "assistant":
+			if msg.Reasoning != nil {
+				input = append(input, reasoningInputItem(msg.Reasoning))
+			}
 			if len(msg.ToolCalls) > 0 {
 				// Each tool call becomes a separate function_call input item
 				for _, tc := range msg.ToolCalls {
@@ -551,6 +1029,34 @@ func convertMessagesToResponsesInput(messages []api.Message) []interface{} {
 	return input
 }
 
+// reasoningInputItem converts a reasoning message to a Responses API reasoning
+// input item. The backend does not store responses, so the item is only
+// usable if it carries its encrypted content or reasoning text.
+func reasoningInputItem(r *api.Reasoning) map[string]interface{} {
+	summary := make([]map[string]interface{}, 0, len(r.Summary))
+	for _, text := range r.Summary {
+		summary = append(summary, map[string]interface{}{
+			"type": "summary_text",
+			"text": text,
+		})
+	}
+	item := map[string]interface{}{
+		"type":    "reasoning",
+		"id":      r.ID,
+		"summary": summary,
+	}
+	if r.Text != "" {
+		item["content"] = []map[string]interface{}{{
+			"type": "reasoning_text",
+			"text": r.Text,
+		}}
+	}
+	if r.EncryptedContent != "" {
+		item["encrypted_content"] = r.EncryptedContent
+	}
+	return item
+}
+
 // toolCallInfo holds extracted function call information from backend output.
 type toolCallInfo struct {
 	ID        string
@@ -565,8 +1071,12 @@ func parseResponsesOutput(output []api.OutputItem) (textContent string, toolCall
 		switch item.Type {
 		case "message":
 			for _, c := range item.Content {
-				if c.Type == "output_text" || c.Type == "text" {
+				switch c.Type {
+				case "output_text", "text":
 					textContent += c.Text
+				case "output_audio":
+					// The transcript stands for the audio in the history
+					textContent += c.Transcript
 				}
 			}
 		case "function_call":
@@ -595,6 +1105,14 @@ func convertOutputItemsToSchema(items []api.OutputItem) []schema.ItemField {
 			}
 			var content []schema.ContentPart
 			for _, c := range item.Content {
+				if c.Type == "output_audio" {
+					cp := schema.ContentPart{Type: c.Type, Data: &c.Data, Transcript: &c.Transcript}
+					if c.Format != "" {
+						cp.Format = &c.Format
+					}
+					content = append(content, cp)
+					continue
+				}
 				text := c.Text
 				cp := schema.ContentPart{
 					Type: c.Type,
@@ -611,11 +1129,12 @@ func convertOutputItemsToSchema(items []api.OutputItem) []schema.ItemField {
 				content = append(content, cp)
 			}
 			result = append(result, schema.ItemField{
-				Type:    "message",
-				ID:      item.ID,
-				Role:    &role,
-				Status:  &status,
-				Content: content,
+				Type:           "message",
+				ID:             item.ID,
+				Role:           &role,
+				Status:         &status,
+				Content:        content,
+				CandidateIndex: item.CandidateIndex,
 			})
 		case "function_call":
 			name := item.Name
@@ -642,167 +1161,147 @@ func convertOutputItemsToSchema(items []api.OutputItem) []schema.ItemField {
 				CallID: &callID,
 				Output: &output,
 			})
+		case "reasoning":
+			result = append(result, reasoningItemField(item))
 		}
 	}
 	return result
 }
 
-// patchResponseID replaces the response_id field in a raw JSON event
-// with the gateway's own response ID.
-func patchResponseID(data json.RawMessage, newResponseID string) json.RawMessage {
-	var m map[string]json.RawMessage
-	if err := json.Unmarshal(data, &m); err != nil {
-		return data
+// reasoningItemField converts a backend reasoning item to a schema ItemField.
+func reasoningItemField(item api.OutputItem) schema.ItemField {
+	field := schema.ItemField{
+		Type:           "reasoning",
+		ID:             item.ID,
+		Content:        make([]schema.ContentPart, 0, len(item.Content)),
+		Summary:        make([]schema.ContentPart, 0, len(item.Summary)),
+		CandidateIndex: item.CandidateIndex,
 	}
-	if _, ok := m["response_id"]; ok {
-		quoted, _ := json.Marshal(newResponseID)
-		m["response_id"] = quoted
-		patched, err := json.Marshal(m)
-		if err != nil {
-			return data
-		}
-		return patched
-	}
-	return data
-}
-
-// emitOutputItemAddedIfNeeded emits a response.output_item.added event if
-// the given output_index hasn't been announced yet. The OpenAI Python SDK
-// expects this event before any delta events for that output index.
-func emitOutputItemAddedIfNeeded(
-	events chan<- interface{},
-	announced map[int]string,
-	outputIndex int,
-	itemID string,
-	itemType string,
-	seqNum int,
-) int {
-	if _, ok := announced[outputIndex]; ok {
-		return seqNum
-	}
-	if itemID == "" {
-		if itemType == "function_call" {
-			itemID = generateID("fc_")
-		} else {
-			itemID = generateID("msg_")
+	for _, c := range item.Content {
+		text := c.Text
+		field.Content = append(field.Content, schema.ContentPart{Type: c.Type, Text: &text})
+	}
+	for _, s := range item.Summary {
+		text := s.Text
+		field.Summary = append(field.Summary, schema.ContentPart{Type: "summary_text", Text: &text})
+	}
+	if item.EncryptedContent != "" {
+		encrypted := item.EncryptedContent
+		field.EncryptedContent = &encrypted
+	}
+	if item.Status != "" {
+		status := item.Status
+		field.Status = &status
+	}
+	return field
+}
+
+// reasoningItems returns the reasoning items of backend output.
+func reasoningItems(output []api.OutputItem) []api.OutputItem {
+	var items []api.OutputItem
+	for _, item := range output {
+		if item.Type == "reasoning" {
+			items = append(items, item)
 		}
 	}
-	announced[outputIndex] = itemID
+	return items
+}
 
-	role := "assistant"
-	status := "in_progress"
-	item := schema.ItemField{
-		Type:    itemType,
-		ID:      itemID,
-		Content: make([]schema.ContentPart, 0),
-	}
-	if itemType == "message" {
-		item.Role = &role
-		item.Status = &status
-	}
-
-	events <- &schema.ResponseOutputItemAddedStreamingEvent{
-		Type:           "response.output_item.added",
-		SequenceNumber: seqNum,
-		OutputIndex:    outputIndex,
-		Item:           item,
-	}
-	return seqNum + 1
-}
-
-// emitContentPartAddedIfNeeded emits a response.content_part.added event if
-// the given output_index:content_index pair hasn't been announced yet.
-func emitContentPartAddedIfNeeded(
-	events chan<- interface{},
-	announcedParts map[string]bool,
-	announcedOutputs map[int]string,
-	outputIndex int,
-	contentIndex int,
-	seqNum int,
-) int {
-	key := fmt.Sprintf("%d:%d", outputIndex, contentIndex)
-	if announcedParts[key] {
-		return seqNum
-	}
-	announcedParts[key] = true
-	itemID := announcedOutputs[outputIndex]
-	emptyText := ""
-
-	events <- &schema.ResponseContentPartAddedStreamingEvent{
-		Type:           "response.content_part.added",
-		SequenceNumber: seqNum,
-		ItemID:         itemID,
-		OutputIndex:    outputIndex,
-		ContentIndex:   contentIndex,
-		Part: schema.ContentPart{
-			Type:        "output_text",
-			Text:        &emptyText,
-			Annotations: make([]schema.Annotation, 0),
-		},
-	}
-	return seqNum + 1
-}
-
-// buildConversationMessages reconstructs conversation history for multi-turn
-func (e *Engine) buildConversationMessages(ctx context.Context, req *schema.ResponseRequest) ([]api.Message, error) {
+// reasoningMessages returns the reasoning items of backend output as
+// messages, so they are stored with the history and replayed on later
+// turns. Items with neither encrypted content nor reasoning text cannot be
+// resolved by a backend that does not store responses, and are dropped.
+func reasoningMessages(output []api.OutputItem) []api.Message {
 	var messages []api.Message
+	for _, item := range reasoningItems(output) {
+		r := &api.Reasoning{ID: item.ID, EncryptedContent: item.EncryptedContent}
+		for _, s := range item.Summary {
+			r.Summary = append(r.Summary, s.Text)
+		}
+		for _, c := range item.Content {
+			r.Text += c.Text
+		}
+		if r.EncryptedContent == "" && r.Text == "" {
+			continue
+		}
+		messages = append(messages, api.Message{Role: "assistant", Reasoning: r})
+	}
+	return messages
+}
+
+// streamedReasoningItem builds a reasoning item from streamed deltas, for
+// backends that do not send the completed item.
+func streamedReasoningItem(text string, summary []string) schema.ItemField {
+	item := schema.ItemField{
+		Type:    "reasoning",
+		Content: make([]schema.ContentPart, 0, 1),
+		Summary: make([]schema.ContentPart, 0, len(summary)),
+	}
+	if text != "" {
+		item.Content = append(item.Content, schema.ContentPart{Type: "reasoning_text", Text: &text})
+	}
+	for _, s := range summary {
+		item.Summary = append(item.Summary, schema.ContentPart{Type: "summary_text", Text: &s})
+	}
+	return item
+}
+
+// streamedFunctionCall returns the function call item streamed at
+// outputIndex under itemID: the backend's completed item when it has one,
+// else an item built from the streamed arguments.
+func streamedFunctionCall(backendOutput []api.OutputItem, outputIndex int, itemID, arguments string) schema.ItemField {
+	match := slices.IndexFunc(backendOutput, func(o api.OutputItem) bool {
+		return o.Type == "function_call" && o.ID == itemID
+	})
+	if match < 0 && outputIndex < len(backendOutput) && backendOutput[outputIndex].Type == "function_call" {
+		match = outputIndex
+	}
+	status := "completed"
+	if match < 0 {
+		return schema.ItemField{Type: "function_call", ID: itemID, Arguments: &arguments, Status: &status}
+	}
+	item := backendOutput[match]
+	return schema.ItemField{
+		Type:      "function_call",
+		ID:        itemID,
+		CallID:    &item.CallID,
+		Name:      &item.Name,
+		Arguments: &item.Arguments,
+		Status:    &status,
+	}
+}
+
+// buildConversationMessages reconstructs conversation history for multi-turn.
+// The history keeps the system prompt stored by the previous turn, which
+// withInstructions replaces with the instructions of this one. It also returns the ID of the response the history was loaded from, which
+// lets the store keep only the messages added by this turn.
+func (e *Engine) buildConversationMessages(ctx context.Context, req *schema.ResponseRequest) ([]api.Message, string, error) {
+	var (
+		messages []api.Message
+		baseID   string
+	)
 
 	// Load previous conversation if this is a follow-up
 	if req.PreviousResponseID != nil && *req.PreviousResponseID != "" {
-		prevResp, err := e.sessions.GetResponse(ctx, *req.PreviousResponseID)
+		history, id, err := e.responseHistory(ctx, *req.PreviousResponseID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load previous response %s: %w", *req.PreviousResponseID, err)
-		}
-
-		// Load stored messages from previous response
-		for _, m := range prevResp.Messages {
-			msg := api.Message{
-				Role:       m.Role,
-				Content:    m.Content,
-				ToolCallID: m.ToolCallID,
-			}
-			if len(m.ToolCalls) > 0 {
-				for _, tc := range m.ToolCalls {
-					msg.ToolCalls = append(msg.ToolCalls, api.ToolCall{
-						ID:   tc.ID,
-						Type: tc.Type,
-						Function: api.ToolCallFunction{
-							Name:      tc.Name,
-							Arguments: tc.Arguments,
-						},
-					})
-				}
-			}
-			messages = append(messages, msg)
+			return nil, "", fmt.Errorf("failed to load previous response %s: %w", *req.PreviousResponseID, err)
 		}
+		messages, baseID = history, id
 
 		// NOTE: stored messages already include the assistant response
 		// (appended during ProcessRequest before save), so we do NOT
-		// re-process prevResp.Output here to avoid duplicates.
-	}
-
-	// Add instructions as system message
-	if req.Instructions != nil && *req.Instructions != "" {
-		// Prepend system message if not already present
-		hasSystem := false
-		for _, m := range messages {
-			if m.Role == "system" {
-				hasSystem = true
-				break
-			}
-		}
-		if !hasSystem {
-			messages = append([]api.Message{
-				{Role: "system", Content: *req.Instructions},
-			}, messages...)
-		}
+		// re-process the previous output here to avoid duplicates.
 	}
 
 	// Append current input
 	inputMessages := extractInputMessages(req.Input)
+	if err := e.resolveFileInputs(ctx, inputMessages); err != nil {
+		return nil, "", err
+	}
 	messages = append(messages, inputMessages...)
 
-	return messages, nil
+	return messages, baseID, nil
 }
 
 // messagesToConversationMessages converts api.Messages to state.ConversationMessages for storage
@@ -814,6 +1313,14 @@ func messagesToConversationMessages(messages []api.Message) []state.Conversation
 			Content:    m.Content,
 			ToolCallID: m.ToolCallID,
 		}
+		if m.Reasoning != nil {
+			cm.Reasoning = &state.ReasoningRecord{
+				ID:               m.Reasoning.ID,
+				Summary:          m.Reasoning.Summary,
+				Text:             m.Reasoning.Text,
+				EncryptedContent: m.Reasoning.EncryptedContent,
+			}
+		}
 		for _, tc := range m.ToolCalls {
 			cm.ToolCalls = append(cm.ToolCalls, state.ToolCallRecord{
 				ID:        tc.ID,
@@ -827,31 +1334,45 @@ func messagesToConversationMessages(messages []api.Message) []state.Conversation
 	return result
 }
 
-// resolveConversation returns a conversation ID for the request.
+// reasoningFromRecord converts a stored reasoning item back to its message
+// form.
+func reasoningFromRecord(r *state.ReasoningRecord) *api.Reasoning {
+	if r == nil {
+		return nil
+	}
+	return &api.Reasoning{
+		ID:               r.ID,
+		Summary:          r.Summary,
+		Text:             r.Text,
+		EncryptedContent: r.EncryptedContent,
+	}
+}
+
+// resolveConversation returns the conversation of the request.
 // If req.Conversation is set, it validates the conversation exists.
 // Otherwise, it auto-creates a new conversation.
-func (e *Engine) resolveConversation(ctx context.Context, req *schema.ResponseRequest) (string, error) {
+func (e *Engine) resolveConversation(ctx context.Context, req *schema.ResponseRequest) (*state.Conversation, error) {
 	if req.Conversation != nil && *req.Conversation != "" {
 		// Validate existing conversation
-		_, err := e.sessions.GetConversation(ctx, *req.Conversation)
+		conv, err := e.sessions.GetConversation(ctx, *req.Conversation)
 		if err != nil {
-			return "", fmt.Errorf("conversation %s not found", *req.Conversation)
+			return nil, fmt.Errorf("conversation %s not found", *req.Conversation)
 		}
-		return *req.Conversation, nil
+		return conv, nil
 	}
 
 	// Auto-create a new conversation
-	convID := generateID("conv_")
 	conv := &state.Conversation{
-		ID:        convID,
+		ID:        e.NewID("conv_"),
 		Messages:  []state.Message{},
+		Tenant:    featureflags.TenantFromContext(ctx),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 	if err := e.sessions.CreateConversation(ctx, conv); err != nil {
-		return "", fmt.Errorf("failed to create conversation: %w", err)
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
 	}
-	return convID, nil
+	return conv, nil
 }
 
 // findLatestResponseInConversation finds the most recent response in a conversation.
@@ -875,18 +1396,22 @@ func (e *Engine) findLatestResponseInConversation(ctx context.Context, conversat
 	return latest, nil
 }
 
-// appendItemsToConversation adds the current turn's input and output messages to the conversation.
-func (e *Engine) appendItemsToConversation(ctx context.Context, conversationID string, req *schema.ResponseRequest, output []schema.ItemField) error {
+// conversationItems returns the current turn's input and output messages,
+// to be appended to the conversation with the response.
+func (e *Engine) conversationItems(conversationID string, req *schema.ResponseRequest, output []schema.ItemField) []state.Message {
+	if conversationID == "" {
+		return nil
+	}
 	var items []state.Message
 
 	// Add user input messages
 	inputMessages := extractInputMessages(req.Input)
 	for _, m := range inputMessages {
-		if m.Role == "system" {
-			continue // skip system messages
+		if m.Reasoning != nil {
+			continue // skip replayed reasoning
 		}
 		item := state.Message{
-			ID:        generateID("msg_"),
+			ID:        e.NewID("msg_"),
 			Role:      m.Role,
 			Content:   m.Content,
 			CreatedAt: time.Now(),
@@ -897,8 +1422,11 @@ func (e *Engine) appendItemsToConversation(ctx context.Context, conversationID s
 		items = append(items, item)
 	}
 
-	// Add assistant output messages
+	// Add assistant output messages; only the first candidate is kept
 	for _, out := range output {
+		if out.CandidateIndex != nil && *out.CandidateIndex > 0 {
+			continue
+		}
 		switch out.Type {
 		case "message":
 			role := "assistant"
@@ -913,7 +1441,7 @@ func (e *Engine) appendItemsToConversation(ctx context.Context, conversationID s
 			}
 			if content != "" {
 				items = append(items, state.Message{
-					ID:        generateID("msg_"),
+					ID:        e.NewID("msg_"),
 					Role:      role,
 					Content:   content,
 					CreatedAt: time.Now(),
@@ -929,7 +1457,7 @@ func (e *Engine) appendItemsToConversation(ctx context.Context, conversationID s
 				name = *out.Name
 			}
 			items = append(items, state.Message{
-				ID:        generateID("msg_"),
+				ID:        e.NewID("msg_"),
 				Role:      "assistant",
 				Content:   fmt.Sprintf(`{"name":%q,"arguments":%s}`, name, args),
 				Metadata:  map[string]string{"type": "function_call"},
@@ -938,77 +1466,51 @@ func (e *Engine) appendItemsToConversation(ctx context.Context, conversationID s
 		}
 	}
 
-	if len(items) > 0 {
-		return e.sessions.AddConversationItems(ctx, conversationID, items)
-	}
-	return nil
+	return items
 }
 
-// buildConversationMessagesFromConversation builds messages from the latest response in a conversation.
-// This reuses the same mechanism as previous_response_id: load stored Messages + Output from the latest response.
-func (e *Engine) buildConversationMessagesFromConversation(ctx context.Context, conversationID string, req *schema.ResponseRequest) ([]api.Message, error) {
-	var messages []api.Message
+// buildConversationMessagesFromConversation builds messages from the latest response in a conversation,
+// or, for a fork without turns of its own yet, from the response it was forked from.
+// This reuses the same mechanism as previous_response_id: load stored Messages + Output from that response.
+func (e *Engine) buildConversationMessagesFromConversation(ctx context.Context, conv *state.Conversation, req *schema.ResponseRequest) ([]api.Message, string, error) {
+	var (
+		messages []api.Message
+		baseID   string
+	)
 
 	// Find the latest response in the conversation
-	latestResp, err := e.findLatestResponseInConversation(ctx, conversationID)
+	latestResp, err := e.findLatestResponseInConversation(ctx, conv.ID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	if latestResp != nil {
-		// Load stored messages from the latest response (same as previous_response_id logic)
-		for _, m := range latestResp.Messages {
-			msg := api.Message{
-				Role:       m.Role,
-				Content:    m.Content,
-				ToolCallID: m.ToolCallID,
-			}
-			if len(m.ToolCalls) > 0 {
-				for _, tc := range m.ToolCalls {
-					msg.ToolCalls = append(msg.ToolCalls, api.ToolCall{
-						ID:   tc.ID,
-						Type: tc.Type,
-						Function: api.ToolCallFunction{
-							Name:      tc.Name,
-							Arguments: tc.Arguments,
-						},
-					})
-				}
-			}
-			messages = append(messages, msg)
-		}
-
-		// NOTE: stored messages already include the assistant response
-		// (appended during ProcessRequest before save), so we do NOT
-		// re-process latestResp.Output here to avoid duplicates.
+	if latestResp == nil && conv.ForkedFrom != "" {
+		latestResp = &state.Response{ID: conv.ForkedFrom}
 	}
 
-	// Add instructions as system message
-	if req.Instructions != nil && *req.Instructions != "" {
-		hasSystem := false
-		for _, m := range messages {
-			if m.Role == "system" {
-				hasSystem = true
-				break
-			}
-		}
-		if !hasSystem {
-			messages = append([]api.Message{
-				{Role: "system", Content: *req.Instructions},
-			}, messages...)
+	if latestResp != nil {
+		// Listed responses may only carry the messages added by their own
+		// turn; load the full history.
+		history, id, err := e.responseHistory(ctx, latestResp.ID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load response %s: %w", latestResp.ID, err)
 		}
+		messages, baseID = history, id
 	}
 
 	// Append current input
 	inputMessages := extractInputMessages(req.Input)
+	if err := e.resolveFileInputs(ctx, inputMessages); err != nil {
+		return nil, "", err
+	}
 	messages = append(messages, inputMessages...)
 
-	return messages, nil
+	return messages, baseID, nil
 }
 
 // expandMCPTools discovers tools from MCP servers and replaces MCP tool entries
 // with concrete function tool definitions. It returns the expanded tools list
-// and a map from tool name to MCP client for server-side execution.
+// and a map from tool name to MCP client for server-side execution. An
+// http_tool connector is expanded the same way, into its single tool.
 func (e *Engine) expandMCPTools(ctx context.Context, tools []schema.ResponsesToolParam) (
 	[]schema.ResponsesToolParam, map[string]*mcp.Client, error,
 ) {
@@ -1016,6 +1518,8 @@ func (e *Engine) expandMCPTools(ctx context.Context, tools []schema.ResponsesToo
 		// No connector support — pass through all tools unchanged
 		return tools, nil, nil
 	}
+	ctx, cancel := withinRequest(ctx)
+	defer cancel()
 
 	var expanded []schema.ResponsesToolParam
 	mcpToolNames := map[string]*mcp.Client{}
@@ -1032,19 +1536,30 @@ func (e *Engine) expandMCPTools(ctx context.Context, tools []schema.ResponsesToo
 			return nil, nil, fmt.Errorf("mcp connector %q not found: %w", t.ServerLabel, err)
 		}
 
-		// Create MCP client, initialize, and list tools
-		mcpClient := mcp.NewClient(connector.URL)
-		if err := mcpClient.Initialize(ctx); err != nil {
-			return nil, nil, fmt.Errorf("mcp server %q initialize: %w", t.ServerLabel, err)
+		// List the server's tools; a replay lists the recorded ones, as it
+		// replays the tool calls too
+		var (
+			mcpClient *mcp.Client
+			toolInfos []mcp.ToolInfo
+		)
+		if rec := recorderFrom(ctx); rec.replaying() {
+			mcpClient = &mcp.Client{Label: t.ServerLabel, MaxOutputBytes: connector.MaxOutputBytes}
+			toolInfos, err = rec.replayMCPTools(t.ServerLabel)
+		} else {
+			mcpClient, toolInfos, err = e.connectMCP(ctx, t.ServerLabel, connector)
+			if err == nil {
+				rec.recordMCPTools(t.ServerLabel, toolInfos)
+			}
 		}
-
-		toolInfos, err := mcpClient.ListTools(ctx)
 		if err != nil {
-			return nil, nil, fmt.Errorf("mcp server %q list tools: %w", t.ServerLabel, err)
+			return nil, nil, err
 		}
 
-		// Convert each MCP ToolInfo to a function tool
+		// Convert each allowed MCP ToolInfo to a function tool
 		for _, ti := range toolInfos {
+			if !mcpToolAllowed(ti, t.AllowedTools, connector.DeniedTools) {
+				continue
+			}
 			desc := ti.Description
 			expanded = append(expanded, schema.ResponsesToolParam{
 				Type:        "function",
@@ -1059,10 +1574,65 @@ func (e *Engine) expandMCPTools(ctx context.Context, tools []schema.ResponsesToo
 	return expanded, mcpToolNames, nil
 }
 
+// connectMCP creates a client for the MCP server of connector, initializes
+// it and lists its tools.
+func (e *Engine) connectMCP(ctx context.Context, serverLabel string, connector *memory.Connector) (*mcp.Client, []mcp.ToolInfo, error) {
+	var mcpClient *mcp.Client
+	switch {
+	case connector.HTTPTool != nil:
+		mcpClient = mcp.NewHTTPToolClient(*connector.HTTPTool, connector.Auth)
+	case connector.Stdio != nil:
+		if e.stdioServers == nil {
+			return nil, nil, fmt.Errorf("mcp connector %q uses stdio, which is disabled", serverLabel)
+		}
+		server, err := e.stdioServers.Get(connector.ConnectorID, *connector.Stdio)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mcp connector %q: %w", serverLabel, err)
+		}
+		mcpClient = mcp.NewStdioClient(server)
+	default:
+		mcpClient = mcp.NewClient(connector.URL, connector.Auth)
+	}
+	if e.egress != nil && connector.Stdio == nil {
+		mcpClient.SetHTTPClient(e.egress.Client(connector.ConnectorID))
+	}
+	mcpClient.Label = serverLabel
+	mcpClient.MaxOutputBytes = connector.MaxOutputBytes
+	if err := mcpClient.Initialize(ctx); err != nil {
+		return nil, nil, fmt.Errorf("mcp server %q initialize: %w", serverLabel, err)
+	}
+
+	toolInfos, err := mcpClient.ListTools(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mcp server %q list tools: %w", serverLabel, err)
+	}
+	return mcpClient, toolInfos, nil
+}
+
+// mcpToolAllowed reports whether an MCP server tool may be exposed to the
+// model. The connector's denylist always wins over the request's
+// allowed_tools filter.
+func mcpToolAllowed(ti mcp.ToolInfo, allowed *schema.MCPAllowedTools, denied []string) bool {
+	if slices.Contains(denied, ti.Name) {
+		return false
+	}
+	if allowed == nil {
+		return true
+	}
+	if allowed.ToolNames != nil && !slices.Contains(allowed.ToolNames, ti.Name) {
+		return false
+	}
+	if allowed.ReadOnly && (ti.Annotations == nil || !ti.Annotations.ReadOnlyHint) {
+		return false
+	}
+	return true
+}
+
 // fileSearchConfig holds the configuration for a file_search tool.
 type fileSearchConfig struct {
 	VectorStoreIDs []string
 	MaxNumResults  int
+	ScoreThreshold float64 // from ranking_options; results scoring lower are dropped
 }
 
 // expandFileSearchTools replaces file_search tool entries with a synthetic
@@ -1089,9 +1659,11 @@ func (e *Engine) expandFileSearchTools(tools []schema.ResponsesToolParam) (
 		if t.MaxNumResults != nil && *t.MaxNumResults > 0 {
 			maxResults = *t.MaxNumResults
 		}
+		scoreThreshold, _ := t.RankingOptions["score_threshold"].(float64)
 		configs["file_search"] = fileSearchConfig{
 			VectorStoreIDs: t.VectorStoreIDs,
 			MaxNumResults:  maxResults,
+			ScoreThreshold: scoreThreshold,
 		}
 
 		// Replace with a synthetic function tool
@@ -1121,20 +1693,34 @@ func (e *Engine) expandFileSearchTools(tools []schema.ResponsesToolParam) (
 	return expanded, configs
 }
 
-// executeFileSearch runs a file_search tool call against all configured vector stores.
+// executeFileSearch runs a file_search tool call against all configured vector stores,
+// queried concurrently. Results are merged, filtered by the score threshold,
+// de-duplicated and ranked by score, keeping the best MaxNumResults.
 // Returns the formatted text result and the raw search results for annotation tracking.
-func (e *Engine) executeFileSearch(ctx context.Context, cfg fileSearchConfig, query string) (string, []vectorstore.SearchResult) {
-	var allResults []vectorstore.SearchResult
-	for _, vsID := range cfg.VectorStoreIDs {
-		results, err := e.vectorSearch.Search(ctx, vsID, query, cfg.MaxNumResults, "")
-		if err != nil {
-			continue
-		}
-		allResults = append(allResults, results...)
+// Stores that fail are skipped; the call only fails when every store did.
+// The text is truncated to the tool output limit.
+func (e *Engine) executeFileSearch(ctx context.Context, cfg fileSearchConfig, query string) (string, []vectorstore.SearchResult, error) {
+	results := make([][]vectorstore.SearchResult, len(cfg.VectorStoreIDs))
+	errs := make([]error, len(cfg.VectorStoreIDs))
+	var wg sync.WaitGroup
+	for i, vsID := range cfg.VectorStoreIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = e.vectorSearch.Search(ctx, vsID, query, cfg.MaxNumResults, "")
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("vector store %s: %w", vsID, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if len(cfg.VectorStoreIDs) > 0 && !slices.ContainsFunc(errs, func(err error) bool { return err == nil }) {
+		return "", nil, errors.Join(errs...)
 	}
+	allResults := rankSearchResults(slices.Concat(results...), cfg.ScoreThreshold, cfg.MaxNumResults)
 
 	if len(allResults) == 0 {
-		return "No relevant results found.", nil
+		return "No relevant results found.", nil, nil
 	}
 
 	// Format results as text
@@ -1145,12 +1731,47 @@ func (e *Engine) executeFileSearch(ctx context.Context, cfg fileSearchConfig, qu
 		}
 		fmt.Fprintf(&sb, "[File: %s, Score: %.4f]\n%s", r.FileID, r.Score, r.Content)
 	}
-	return sb.String(), allResults
+	return e.truncateToolOutput(sb.String(), 0), allResults, nil
+}
+
+// rankSearchResults drops the results scoring below threshold and the
+// duplicates of a chunk found in several stores, then returns the best
+// limit results, highest score first. Results without a chunk ID are the
+// same chunk when they have the same file and content.
+func rankSearchResults(results []vectorstore.SearchResult, threshold float64, limit int) []vectorstore.SearchResult {
+	type chunkKey struct{ fileID, chunk string }
+	best := make(map[chunkKey]int) // chunk → index in ranked
+	var ranked []vectorstore.SearchResult
+	for _, r := range results {
+		if r.Score < threshold {
+			continue
+		}
+		key := chunkKey{r.FileID, r.ChunkID}
+		if r.ChunkID == "" {
+			key.chunk = r.Content
+		}
+		if i, ok := best[key]; ok {
+			if r.Score > ranked[i].Score {
+				ranked[i] = r
+			}
+			continue
+		}
+		best[key] = len(ranked)
+		ranked = append(ranked, r)
+	}
+	slices.SortStableFunc(ranked, func(a, b vectorstore.SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
 }
 
 // webSearchConfig holds the configuration for a web_search tool.
 type webSearchConfig struct {
 	MaxResults int
+	Options    websearch.Options // from user_location and filters.allowed_domains
 }
 
 // expandWebSearchTools replaces web_search tool entries with a synthetic
@@ -1183,6 +1804,7 @@ func (e *Engine) expandWebSearchTools(tools []schema.ResponsesToolParam) (
 		}
 		configs["web_search"] = webSearchConfig{
 			MaxResults: maxResults,
+			Options:    webSearchOptions(t),
 		}
 
 		// Replace with a synthetic function tool
@@ -1208,13 +1830,36 @@ func (e *Engine) expandWebSearchTools(tools []schema.ResponsesToolParam) (
 	if len(configs) == 0 {
 		return tools, nil
 	}
+	if e.urlFetch != nil {
+		configs[fetchURLToolName] = webSearchConfig{}
+		expanded = append(expanded, fetchURLTool())
+	}
 
 	return expanded, configs
 }
 
+// webSearchOptions returns the search options of a web_search tool: the
+// country of its user_location and the domains of filters.allowed_domains.
+// Freshness and language are left to the provider's configuration.
+func webSearchOptions(t schema.ResponsesToolParam) websearch.Options {
+	var opts websearch.Options
+	if country, ok := t.UserLocation["country"].(string); ok {
+		opts.Country = country
+	}
+	if filters, ok := t.Filters.(map[string]interface{}); ok {
+		domains, _ := filters["allowed_domains"].([]interface{})
+		for _, d := range domains {
+			if domain, ok := d.(string); ok && domain != "" {
+				opts.Sites = append(opts.Sites, domain)
+			}
+		}
+	}
+	return opts
+}
+
 // executeWebSearch runs a web search and formats the results for the LLM.
 func (e *Engine) executeWebSearch(ctx context.Context, cfg webSearchConfig, query string) (string, []WebSearchResult) {
-	results, err := e.webSearch.Search(ctx, query, cfg.MaxResults)
+	results, err := e.webSearch.Search(websearch.WithOptions(ctx, cfg.Options), query, cfg.MaxResults)
 	if err != nil {
 		return fmt.Sprintf("Web search error: %v", err), nil
 	}
@@ -1233,6 +1878,17 @@ func (e *Engine) executeWebSearch(ctx context.Context, cfg webSearchConfig, quer
 	return sb.String(), results
 }
 
+// executeWebSearchTool runs a call of the web_search or fetch_url tool.
+func (e *Engine) executeWebSearchTool(ctx context.Context, cfg webSearchConfig, name, arguments string) (string, []WebSearchResult) {
+	args := parseJSONArgs(arguments)
+	if name == fetchURLToolName {
+		url, _ := args["url"].(string)
+		return e.executeFetchURL(ctx, url)
+	}
+	query, _ := args["query"].(string)
+	return e.executeWebSearch(ctx, cfg, query)
+}
+
 // searchSource represents a citation source from tool execution.
 type searchSource struct {
 	Type     string // "url_citation" or "file_citation"
@@ -1291,24 +1947,173 @@ func attachAnnotations(output []schema.ItemField, sources []searchSource) {
 	}
 }
 
-// parseJSONArgs parses a JSON string into a map for MCP tool call arguments.
-func parseJSONArgs(jsonStr string) map[string]any {
-	var args map[string]any
-	if err := json.Unmarshal([]byte(jsonStr), &args); err != nil {
-		return map[string]any{}
+// parseJSONArgs parses a JSON string into a map for MCP tool call arguments.
+func parseJSONArgs(jsonStr string) map[string]any {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(jsonStr), &args); err != nil {
+		return map[string]any{}
+	}
+	return args
+}
+
+// mcpResultToString converts an MCP tool call result to a string for the LLM.
+func mcpResultToString(result *mcp.ToolCallResult) string {
+	var parts []string
+	for _, block := range result.Content {
+		if block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// responseIDPrefix returns the configured prefix for generated response IDs.
+func (e *Engine) responseIDPrefix() string {
+	if e.config == nil || e.config.ResponseIDPrefix == "" {
+		return "resp_"
+	}
+	return e.config.ResponseIDPrefix
+}
+
+// toolInstructions returns the configured guidance for each server-side tool
+// type present in the request, joined in request order. Tool types that the
+// engine does not execute server-side (or that have no configured guidance)
+// contribute nothing.
+func (e *Engine) toolInstructions(tools []schema.ResponsesToolParam) string {
+	if len(e.config.ToolInstructions) == 0 {
+		return ""
+	}
+
+	seen := make(map[string]bool)
+	var parts []string
+	for _, t := range tools {
+		if seen[t.Type] {
+			continue
+		}
+		switch t.Type {
+		case "mcp":
+			if e.connectors == nil {
+				continue
+			}
+		case "file_search":
+			if e.vectorSearch == nil {
+				continue
+			}
+		case "web_search":
+			if e.webSearch == nil {
+				continue
+			}
+		default:
+			continue
+		}
+		seen[t.Type] = true
+		if guidance := strings.TrimSpace(e.config.ToolInstructions[t.Type]); guidance != "" {
+			parts = append(parts, guidance)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// appendInstructions returns instructions with the addendum appended after a
+// blank line. The original pointer is never modified so the client's
+// instructions are echoed back unchanged.
+func appendInstructions(instructions *string, addendum string) *string {
+	if addendum == "" {
+		return instructions
 	}
-	return args
+	if instructions == nil || *instructions == "" {
+		return &addendum
+	}
+	combined := *instructions + "\n\n" + addendum
+	return &combined
 }
 
-// mcpResultToString converts an MCP tool call result to a string for the LLM.
-func mcpResultToString(result *mcp.ToolCallResult) string {
-	var parts []string
-	for _, block := range result.Content {
-		if block.Text != "" {
-			parts = append(parts, block.Text)
-		}
+// storedInstructions returns the conversation's stored system prompt: the
+// leading system message of the history, or "" if there is none.
+func storedInstructions(messages []api.Message) string {
+	if len(messages) > 0 && messages[0].Role == "system" {
+		return messages[0].Content
+	}
+	return ""
+}
+
+// mergeInstructions combines the request's instructions with the stored
+// system prompt according to req.InstructionsMerge, or the configured
+// default. With replace (the default, as in the OpenAI API) only the
+// request's instructions are sent; inherit keeps the stored prompt when the
+// request omits instructions, and an empty string drops it; prepend and append combine both, falling
+// back to the stored prompt when the request has no instructions.
+func (e *Engine) mergeInstructions(req *schema.ResponseRequest, stored string) *string {
+	mode := schema.InstructionsMergeReplace
+	if req.InstructionsMerge != nil {
+		mode = *req.InstructionsMerge
+	} else if e.config != nil && e.config.InstructionsMerge != "" {
+		mode = e.config.InstructionsMerge
+	}
+	if mode == schema.InstructionsMergeReplace || stored == "" {
+		return req.Instructions
+	}
+	if mode == schema.InstructionsMergeInherit {
+		if req.Instructions == nil {
+			return &stored
+		}
+		return req.Instructions
+	}
+	if req.Instructions == nil || *req.Instructions == "" || *req.Instructions == stored {
+		return &stored
+	}
+	var merged string
+	if mode == schema.InstructionsMergePrepend {
+		merged = *req.Instructions + "\n\n" + stored
+	} else {
+		merged = stored + "\n\n" + *req.Instructions
+	}
+	return &merged
+}
+
+// withInstructions sets the leading system message of messages, which
+// records the system prompt with the history, to the effective instructions
+// of the turn. Without instructions the stored prompt is dropped, so that a
+// later turn does not inherit instructions this one did not use.
+func withInstructions(messages []api.Message, instructions *string) []api.Message {
+	hasSystem := len(messages) > 0 && messages[0].Role == "system"
+	switch {
+	case instructions == nil || *instructions == "":
+		if hasSystem {
+			return messages[1:]
+		}
+	case hasSystem:
+		messages[0] = api.Message{Role: "system", Content: *instructions}
+	default:
+		messages = append([]api.Message{{Role: "system", Content: *instructions}}, messages...)
+	}
+	return messages
+}
+
+// instructionsHash returns the hex SHA-256 of the effective instructions, or
+// nil if no merge strategy was requested.
+func instructionsHash(req *schema.ResponseRequest, instructions *string) *string {
+	if req.InstructionsMerge == nil {
+		return nil
+	}
+	var text string
+	if instructions != nil {
+		text = *instructions
+	}
+	sum := sha256.Sum256([]byte(text))
+	hash := hex.EncodeToString(sum[:])
+	return &hash
+}
+
+// requestLimits returns the configured request size limits.
+func (e *Engine) requestLimits() schema.RequestLimits {
+	if e.config == nil {
+		return schema.RequestLimits{}
+	}
+	return schema.RequestLimits{
+		MaxInputItems: e.config.RequestLimits.MaxInputItems,
+		MaxTools:      e.config.RequestLimits.MaxTools,
 	}
-	return strings.Join(parts, "\n")
 }
 
 // ProcessRequest processes a Responses API request (non-streaming).
@@ -1318,14 +2123,45 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
+	if req.Background != nil && *req.Background {
+		return e.processBackground(ctx, req)
+	}
+	if err := req.CheckLimits(e.requestLimits()); err != nil {
+		return nil, err
+	}
+
+	// 1a. Bound the whole request by its timeout
+	ctx = e.withRequestDeadline(ctx, time.Now())
 
 	// 1b. Resolve prompt template if specified
 	if err := e.resolvePromptRef(ctx, req); err != nil {
 		return nil, fmt.Errorf("prompt resolution: %w", err)
 	}
 
+	// 1c. Run request hooks (may mutate or reject the request)
+	if err := e.hooks.RunRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("request hook: %w", err)
+	}
+	alias := e.resolveModelAlias(req)
+	e.applyModelParams(req)
+	e.injectTools(ctx, req)
+
+	// 1d. Wait for a backend slot
+	release, err := e.admit(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("admission: %w", err)
+	}
+	defer release()
+
 	// 2. Generate response ID
-	respID := generateID("resp_")
+	respID := e.NewID(e.responseIDPrefix())
+
+	// 2b. Hold the conversation for the rest of the turn
+	unlock, err := e.lockConversation(ctx, req, respID)
+	if err != nil {
+		return nil, fmt.Errorf("conversation lock: %w", err)
+	}
+	defer unlock()
 
 	// 3. Create response object
 	model := ""
@@ -1333,27 +2169,51 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 		model = *req.Model
 	}
 	resp := schema.NewResponse(respID, model)
+	resp.ModelAlias = alias
+
+	// 3a. Record the backend calls and tool results of the loop, and
+	// mirror them to the shadow backend if the response is sampled
+	ctx, rec := e.startRecording(ctx, respID, req)
+	defer e.saveRecording(ctx, rec, resp)
+	ctx = e.startShadow(ctx, respID)
 
 	// 4. Resolve conversation (auto-create or validate existing)
-	conversationID, err := e.resolveConversation(ctx, req)
+	conv, err := e.resolveConversation(ctx, req)
 	if err != nil {
-		resp.MarkFailed("api_error", "conversation_error", fmt.Sprintf("failed to resolve conversation: %v", err))
+		resp.MarkFailed(apierror.TypeServerError, apierror.CodeConversationError, fmt.Sprintf("failed to resolve conversation: %v", err))
 		return resp, nil
 	}
+	conversationID := conv.ID
 
 	// 5. Echo ALL request parameters and set conversation
 	echoRequestParams(resp, req)
 	resp.Conversation = &conversationID
 
 	// 6. Build conversation messages (including multi-turn history)
-	var messages []api.Message
+	var (
+		messages []api.Message
+		baseID   string
+	)
 	if req.Conversation != nil && *req.Conversation != "" {
-		messages, err = e.buildConversationMessagesFromConversation(ctx, conversationID, req)
+		messages, baseID, err = e.buildConversationMessagesFromConversation(ctx, conv, req)
 	} else {
-		messages, err = e.buildConversationMessages(ctx, req)
+		messages, baseID, err = e.buildConversationMessages(ctx, req)
 	}
 	if err != nil {
-		resp.MarkFailed("api_error", "conversation_error", fmt.Sprintf("failed to build conversation: %v", err))
+		resp.MarkFailed(apierror.TypeServerError, apierror.CodeConversationError, fmt.Sprintf("failed to build conversation: %v", err))
+		return resp, nil
+	}
+	instructions := e.mergeInstructions(req, storedInstructions(messages))
+	messages = withInstructions(messages, instructions)
+	resp.EffectiveInstructionsHash = instructionsHash(req, instructions)
+	instructions = e.layerInstructions(ctx, req, conv, instructions)
+
+	// 6b. Screen input with the content moderator
+	if violations, modErr := e.moderateInput(ctx, req); modErr != nil {
+		resp.MarkFailed(apierror.TypeServerError, apierror.CodeModerationError, fmt.Sprintf("content moderation failed: %v", modErr))
+		return resp, nil
+	} else if len(violations) > 0 {
+		e.refuse(resp, "input_flagged", violations)
 		return resp, nil
 	}
 
@@ -1364,7 +2224,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 		var expandErr error
 		expandedTools, mcpToolNames, expandErr = e.expandMCPTools(ctx, req.Tools)
 		if expandErr != nil {
-			resp.MarkFailed("api_error", "mcp_error", fmt.Sprintf("failed to expand MCP tools: %v", expandErr))
+			resp.MarkFailed(apierror.TypeServerError, apierror.CodeMCPError, fmt.Sprintf("failed to expand MCP tools: %v", expandErr))
 			return resp, nil
 		}
 	}
@@ -1381,19 +2241,49 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 		expandedTools, webSearchConfigs = e.expandWebSearchTools(expandedTools)
 	}
 
+	// 7d. Tool usage guidance for expanded server-side tools
+	toolGuidance := e.toolInstructions(req.Tools)
+
 	// 8. Agentic loop
 	maxIters := defaultMaxToolCalls
 	if req.MaxToolCalls != nil && *req.MaxToolCalls > 0 {
 		maxIters = *req.MaxToolCalls
 	}
 
+	// 7e. Serve repeated deterministic requests from the response cache;
+	// a hit skips the agentic loop
+	cacheKey, cacheTTL := e.responseCacheKey(ctx, req, model, messages, appendInstructions(instructions, toolGuidance))
+	cached := e.lookupResponseCache(ctx, cacheKey)
+	loopStart := len(messages)
+	if cached != nil {
+		resp.CacheHit = true
+		maxIters = 0
+		messages = append(messages, cached.Messages...)
+	}
+
+	guard := e.loopGuard(ctx, req)
+	loopCtx, cancelLoop := guard.context(ctx)
+	defer cancelLoop()
+
 	accumulatedOutputTokens := 0
+	accumulatedReasoningTokens := 0
+	accumulatedAudioTokens := 0
 	var allOutput []schema.ItemField
+	if cached != nil {
+		allOutput = cached.Output
+	}
 	var allSources []searchSource
 
 	for iter := 0; iter < maxIters; iter++ {
+		if reason := guard.check(time.Now()); reason != "" {
+			resp.MarkIncomplete(reason)
+			break
+		}
+
 		// Build Responses API request
 		apiReq := buildResponsesAPIRequest(model, messages, req, expandedTools, false)
+		apiReq.Instructions = appendInstructions(instructions, toolGuidance)
+		apiReq.PromptCacheKey = e.promptCacheKey(req, apiReq, messages)
 
 		// Adjust token budget if max_output_tokens is set
 		if req.MaxOutputTokens != nil {
@@ -1405,22 +2295,44 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 			apiReq.MaxOutputTokens = &remaining
 		}
 
+		// Count input tokens, truncating to the context window if asked
+		inputTokens := e.fitContext(apiReq, messages)
+		if reason := guard.checkInput(inputTokens); reason != "" {
+			resp.MarkIncomplete(reason)
+			break
+		}
+
 		// Call backend
-		apiResp, err := e.llm.CreateResponse(ctx, apiReq)
+		apiResp, err := e.createResponse(loopCtx, apiReq)
 		if err != nil {
-			resp.MarkFailed("api_error", "llm_error", fmt.Sprintf("failed to call backend: %v", err))
+			if reason := guard.stopped(ctx, loopCtx); reason != "" {
+				resp.MarkIncomplete(reason)
+				break
+			}
+			backendErr := apierror.Backend(err)
+			resp.MarkFailed(backendErr.Type, backendErr.Code, fmt.Sprintf("failed to call backend: %v", err))
 			return resp, nil
 		}
+		if apiResp.Usage == nil {
+			apiResp.Usage = estimateUsage(inputTokens, e.countOutput(apiResp.Output))
+		}
+		guard.record(apiResp.Usage)
 
 		// Track usage
 		if apiResp.Usage != nil {
 			accumulatedOutputTokens += apiResp.Usage.OutputTokens
+			accumulatedReasoningTokens += apiResp.Usage.ReasoningTokens()
+			accumulatedAudioTokens += apiResp.Usage.OutputAudioTokens()
 		}
 
 		// Parse output for tool calls
 		_, toolCalls, hasToolCalls := parseResponsesOutput(apiResp.Output)
 
 		if hasToolCalls {
+			// Keep the reasoning that led to the calls, ahead of them
+			allOutput = append(allOutput, convertOutputItemsToSchema(reasoningItems(apiResp.Output))...)
+			messages = append(messages, reasoningMessages(apiResp.Output)...)
+
 			var clientSideCalls []api.ToolCall
 
 			for _, tc := range toolCalls {
@@ -1430,8 +2342,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 
 				if isMCP {
 					// Execute MCP tool server-side
-					args := parseJSONArgs(tc.Arguments)
-					result, mcpErr := mcpClient.CallTool(ctx, tc.Name, args)
+					result, mcpErr := e.callMCPTool(loopCtx, mcpClient, tc)
 
 					completedStatus := "completed"
 					callID := tc.CallID
@@ -1440,7 +2351,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 
 					allOutput = append(allOutput, schema.ItemField{
 						Type:      "function_call",
-						ID:        generateID("fc_"),
+						ID:        e.NewID("fc_"),
 						CallID:    &callID,
 						Name:      &funcName,
 						Arguments: &funcArgs,
@@ -1449,16 +2360,17 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 
 					var outputStr string
 					if mcpErr != nil {
-						outputStr = fmt.Sprintf("Error calling tool: %v", mcpErr)
+						outputStr = toolErrorOutput(mcpErr.Error())
+						allOutput = append(allOutput, e.failedMCPCall(tc, mcpClient.Label, mcpErr))
 					} else {
-						outputStr = mcpResultToString(result)
+						outputStr = e.truncateToolOutput(mcpResultToString(result), mcpClient.MaxOutputBytes)
+						allOutput = append(allOutput, schema.ItemField{
+							Type:   "function_call_output",
+							ID:     e.NewID("fco_"),
+							CallID: &callID,
+							Output: &outputStr,
+						})
 					}
-					allOutput = append(allOutput, schema.ItemField{
-						Type:   "function_call_output",
-						ID:     generateID("fco_"),
-						CallID: &callID,
-						Output: &outputStr,
-					})
 
 					messages = append(messages, api.Message{
 						Role: "assistant",
@@ -1479,7 +2391,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 				} else if isFileSearch {
 					args := parseJSONArgs(tc.Arguments)
 					query, _ := args["query"].(string)
-					outputStr, fsResults := e.executeFileSearch(ctx, fsCfg, query)
+					outputStr, fsResults, fsErr := e.callFileSearch(loopCtx, fsCfg, tc, query)
 
 					// Collect file_citation sources
 					for _, r := range fsResults {
@@ -1496,18 +2408,23 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 
 					allOutput = append(allOutput, schema.ItemField{
 						Type:      "function_call",
-						ID:        generateID("fc_"),
+						ID:        e.NewID("fc_"),
 						CallID:    &callID,
 						Name:      &funcName,
 						Arguments: &funcArgs,
 						Status:    &completedStatus,
 					})
-					allOutput = append(allOutput, schema.ItemField{
-						Type:   "function_call_output",
-						ID:     generateID("fco_"),
-						CallID: &callID,
-						Output: &outputStr,
-					})
+					if fsErr != nil {
+						outputStr = toolErrorOutput(fsErr.Error())
+						allOutput = append(allOutput, failedFileSearchCall(e.NewID("fs_"), tc, query, fsErr))
+					} else {
+						allOutput = append(allOutput, schema.ItemField{
+							Type:   "function_call_output",
+							ID:     e.NewID("fco_"),
+							CallID: &callID,
+							Output: &outputStr,
+						})
+					}
 
 					messages = append(messages, api.Message{
 						Role: "assistant",
@@ -1526,9 +2443,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 						ToolCallID: tc.CallID,
 					})
 				} else if isWebSearch {
-					args := parseJSONArgs(tc.Arguments)
-					query, _ := args["query"].(string)
-					outputStr, wsResults := e.executeWebSearch(ctx, wsCfg, query)
+					outputStr, wsResults := e.callWebSearchTool(loopCtx, wsCfg, tc)
 
 					// Collect url_citation sources
 					for _, r := range wsResults {
@@ -1546,7 +2461,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 
 					allOutput = append(allOutput, schema.ItemField{
 						Type:      "function_call",
-						ID:        generateID("fc_"),
+						ID:        e.NewID("fc_"),
 						CallID:    &callID,
 						Name:      &funcName,
 						Arguments: &funcArgs,
@@ -1554,7 +2469,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 					})
 					allOutput = append(allOutput, schema.ItemField{
 						Type:   "function_call_output",
-						ID:     generateID("fco_"),
+						ID:     e.NewID("fco_"),
 						CallID: &callID,
 						Output: &outputStr,
 					})
@@ -1583,7 +2498,7 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 					funcArgs := tc.Arguments
 					allOutput = append(allOutput, schema.ItemField{
 						Type:      "function_call",
-						ID:        generateID("fc_"),
+						ID:        e.NewID("fc_"),
 						CallID:    &callID,
 						Name:      &funcName,
 						Arguments: &funcArgs,
@@ -1615,8 +2530,11 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 		backendOutput := convertOutputItemsToSchema(apiResp.Output)
 		allOutput = append(allOutput, backendOutput...)
 
-		// Append assistant message for storage
-		textContent, _, _ := parseResponsesOutput(apiResp.Output)
+		// Append reasoning and assistant messages for storage
+		// Only the first candidate continues the conversation
+		kept := firstCandidate(apiResp.Output)
+		messages = append(messages, reasoningMessages(kept)...)
+		textContent, _, _ := parseResponsesOutput(kept)
 		if textContent != "" {
 			messages = append(messages, api.Message{
 				Role:    "assistant",
@@ -1631,10 +2549,12 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 				OutputTokens: accumulatedOutputTokens,
 				TotalTokens:  apiResp.Usage.InputTokens + accumulatedOutputTokens,
 				InputTokensDetails: schema.InputTokensDetails{
-					CachedTokens: 0,
+					CachedTokens: apiResp.Usage.CachedTokens(),
+					AudioTokens:  apiResp.Usage.InputAudioTokens(),
 				},
 				OutputTokensDetails: schema.OutputTokensDetails{
-					ReasoningTokens: 0,
+					ReasoningTokens: accumulatedReasoningTokens,
+					AudioTokens:     accumulatedAudioTokens,
 				},
 			}
 		}
@@ -1664,31 +2584,62 @@ func (e *Engine) ProcessRequest(ctx context.Context, req *schema.ResponseRequest
 		resp.MarkCompleted()
 	}
 
-	// 12. Save response to state store
+	// 11a. Screen output with the content moderator
+	if resp.Status == "completed" {
+		if violations, modErr := e.moderateOutput(ctx, resp.Output); modErr != nil {
+			resp.Output = make([]schema.ItemField, 0)
+			resp.MarkFailed(apierror.TypeServerError, apierror.CodeModerationError, fmt.Sprintf("content moderation failed: %v", modErr))
+		} else if len(violations) > 0 {
+			e.refuse(resp, "output_flagged", violations)
+		}
+	}
+
+	// 11b. Cache the output of a deterministic request
+	if cacheKey != "" && cached == nil && resp.Status == "completed" {
+		e.storeResponseCache(ctx, cacheKey, cacheTTL, &cachedResponse{Output: resp.Output, Messages: messages[loopStart:]})
+	}
+
+	// 11c. Run response hooks (may rewrite or reject the output)
+	if resp.Status == "completed" {
+		e.runResponseHooks(ctx, req, resp)
+	}
+
+	// 11d. Watermark the output and attach provenance
+	e.applyProvenance(ctx, resp)
+
+	// 12. Save response to state store, appending the turn to the
+	// conversation for the Conversations API in the same transaction so
+	// the two views stay consistent
 	prevRespID := ""
 	if req.PreviousResponseID != nil {
 		prevRespID = *req.PreviousResponseID
 	}
 
-	if err := e.sessions.SaveResponse(ctx, &state.Response{
+	items := e.conversationItems(conversationID, req, resp.Output)
+	if err := e.sessions.SaveResponseWithItems(ctx, &state.Response{
 		ID:                 resp.ID,
 		ConversationID:     conversationID,
 		PreviousResponseID: prevRespID,
+		ExternalID:         externalID(req),
+		Tenant:             featureflags.TenantFromContext(ctx),
+		APIKey:             state.APIKeyFromContext(ctx),
+		RequestID:          requestid.FromContext(ctx),
 		Request:            req,
 		Output:             resp.Output,
 		Status:             resp.Status,
 		Usage:              resp.Usage,
+		Provenance:         resp.Provenance,
 		Messages:           messagesToConversationMessages(messages),
+		MessagesBase:       baseID,
 		CreatedAt:          time.Unix(resp.CreatedAt, 0),
 		CompletedAt:        timePtr(resp.CompletedAt),
-	}); err != nil {
+		Events:             e.eventsFor(ctx, req, resp, conversationID, true),
+	}, items); err != nil {
 		return nil, fmt.Errorf("failed to save response: %w", err)
 	}
 
-	// 13. Append items to conversation for the Conversations API
-	if err := e.appendItemsToConversation(ctx, conversationID, req, allOutput); err != nil {
-		_ = err
-	}
+	// 13. Title a conversation started by this turn
+	e.titleConversation(ctx, conv, req, resp)
 
 	return resp, nil
 }
@@ -1701,48 +2652,85 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
+	if req.Background != nil && *req.Background && !inBackground(ctx) {
+		return nil, errBackgroundStream
+	}
+	if err := req.CheckLimits(e.requestLimits()); err != nil {
+		return nil, err
+	}
+
+	// Bound the whole request by its timeout
+	ctx = e.withRequestDeadline(ctx, time.Now())
 
 	// Resolve prompt template if specified
 	if err := e.resolvePromptRef(ctx, req); err != nil {
 		return nil, fmt.Errorf("prompt resolution: %w", err)
 	}
 
-	events := make(chan interface{}, 10)
+	// Run request hooks (may mutate or reject the request)
+	if err := e.hooks.RunRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("request hook: %w", err)
+	}
+	alias := e.resolveModelAlias(req)
+	e.applyModelParams(req)
+	e.injectTools(ctx, req)
+
+	// Wait for a backend slot, held until the stream ends
+	release, err := e.admit(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("admission: %w", err)
+	}
+
+	// Hold the conversation until the stream ends
+	respID := e.NewID(e.responseIDPrefix())
+	unlock, err := e.lockConversation(ctx, req, respID)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("conversation lock: %w", err)
+	}
+
+	events := make(chan interface{}, e.streamBuffer())
 
 	go func() {
 		defer close(events)
+		defer release()
+		defer unlock()
 
-		respID := generateID("resp_")
 		model := ""
 		if req.Model != nil {
 			model = *req.Model
 		}
 		resp := schema.NewResponse(respID, model)
+		resp.ModelAlias = alias
+
+		// Record the backend calls and tool results of the loop, and
+		// mirror them to the shadow backend if the response is sampled
+		ctx, rec := e.startRecording(ctx, respID, req)
+		defer e.saveRecording(ctx, rec, resp)
+		ctx = e.startShadow(ctx, respID)
 
-		// Track sequence number for events
-		seqNum := 0
+		stream := newEventStream(events, respID)
+		stream.overflow, stream.stats = e.streamOverflow(), &e.streamStats
+		e.streamStats.streams.Add(1)
+		defer stream.flush()
 
 		// Resolve conversation before emitting response.created
-		conversationID, err := e.resolveConversation(ctx, req)
+		conv, err := e.resolveConversation(ctx, req)
 		if err != nil {
-			events <- &schema.ErrorStreamingEvent{
-				Type:  "error",
-				Error: schema.ErrorField{Type: "api_error", Message: fmt.Sprintf("failed to resolve conversation: %v", err)},
-			}
+			stream.fail(apierror.TypeServerError, apierror.CodeConversationError, fmt.Sprintf("failed to resolve conversation: %v", err))
 			return
 		}
+		conversationID := conv.ID
 
 		// Echo ALL request parameters and set conversation
 		echoRequestParams(resp, req)
 		resp.Conversation = &conversationID
 
 		// Send response.created event
-		events <- &schema.ResponseCreatedStreamingEvent{
-			Type:           "response.created",
-			SequenceNumber: seqNum,
-			Response:       *resp,
-		}
-		seqNum++
+		stream.send(&schema.ResponseCreatedStreamingEvent{
+			Type:     "response.created",
+			Response: *resp,
+		})
 
 		// Save response on creation (in_progress)
 		prevRespID := ""
@@ -1753,35 +2741,74 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 			ID:                 resp.ID,
 			ConversationID:     conversationID,
 			PreviousResponseID: prevRespID,
+			ExternalID:         externalID(req),
+			Tenant:             featureflags.TenantFromContext(ctx),
+			APIKey:             state.APIKeyFromContext(ctx),
+			RequestID:          requestid.FromContext(ctx),
 			Request:            req,
 			Output:             resp.Output,
 			Status:             "in_progress",
 			CreatedAt:          time.Unix(resp.CreatedAt, 0),
+			Events:             e.eventsFor(ctx, req, resp, conversationID, true),
 		})
 
 		// Build conversation messages
-		var messages []api.Message
+		var (
+			messages []api.Message
+			baseID   string
+		)
 		if req.Conversation != nil && *req.Conversation != "" {
-			messages, err = e.buildConversationMessagesFromConversation(ctx, conversationID, req)
+			messages, baseID, err = e.buildConversationMessagesFromConversation(ctx, conv, req)
 		} else {
-			messages, err = e.buildConversationMessages(ctx, req)
+			messages, baseID, err = e.buildConversationMessages(ctx, req)
 		}
 		if err != nil {
-			events <- &schema.ErrorStreamingEvent{
-				Type:  "error",
-				Error: schema.ErrorField{Type: "api_error", Message: fmt.Sprintf("failed to build conversation: %v", err)},
-			}
+			stream.fail(apierror.TypeServerError, apierror.CodeConversationError, fmt.Sprintf("failed to build conversation: %v", err))
 			return
 		}
+		instructions := e.mergeInstructions(req, storedInstructions(messages))
+		messages = withInstructions(messages, instructions)
+		resp.EffectiveInstructionsHash = instructionsHash(req, instructions)
+		instructions = e.layerInstructions(ctx, req, conv, instructions)
 
 		// Send response.in_progress event
 		resp.Status = "in_progress"
-		events <- &schema.ResponseInProgressStreamingEvent{
-			Type:           "response.in_progress",
-			SequenceNumber: seqNum,
-			Response:       *resp,
+		stream.send(&schema.ResponseInProgressStreamingEvent{
+			Type:     "response.in_progress",
+			Response: *resp,
+		})
+
+		// Screen input with the content moderator
+		violations, modErr := e.moderateInput(ctx, req)
+		if modErr != nil {
+			stream.fail(apierror.TypeServerError, apierror.CodeModerationError, fmt.Sprintf("content moderation failed: %v", modErr))
+			return
+		}
+		if len(violations) > 0 {
+			item := e.refuse(resp, "input_flagged", violations)
+			stream.refusal(item)
+			stream.send(&schema.ResponseFailedStreamingEvent{
+				Type:     "response.failed",
+				Response: *resp,
+			})
+			_ = e.sessions.SaveResponse(ctx, &state.Response{
+				ID:                 resp.ID,
+				ConversationID:     conversationID,
+				PreviousResponseID: prevRespID,
+				ExternalID:         externalID(req),
+				Tenant:             featureflags.TenantFromContext(ctx),
+				APIKey:             state.APIKeyFromContext(ctx),
+				RequestID:          requestid.FromContext(ctx),
+				Request:            req,
+				Output:             resp.Output,
+				Status:             resp.Status,
+				Messages:           messagesToConversationMessages(messages),
+				MessagesBase:       baseID,
+				CreatedAt:          time.Unix(resp.CreatedAt, 0),
+				Events:             e.eventsFor(ctx, req, resp, conversationID, false),
+			})
+			return
 		}
-		seqNum++
 
 		// Expand MCP tools
 		expandedTools := req.Tools
@@ -1790,10 +2817,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 			var expandErr error
 			expandedTools, mcpToolNames, expandErr = e.expandMCPTools(ctx, req.Tools)
 			if expandErr != nil {
-				events <- &schema.ErrorStreamingEvent{
-					Type:  "error",
-					Error: schema.ErrorField{Type: "api_error", Message: fmt.Sprintf("failed to expand MCP tools: %v", expandErr)},
-				}
+				stream.fail(apierror.TypeServerError, apierror.CodeMCPError, fmt.Sprintf("failed to expand MCP tools: %v", expandErr))
 				return
 			}
 		}
@@ -1810,182 +2834,128 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 			expandedTools, webSearchConfigs = e.expandWebSearchTools(expandedTools)
 		}
 
+		// Tool usage guidance for expanded server-side tools
+		toolGuidance := e.toolInstructions(req.Tools)
+
 		// Agentic loop
 		maxIters := defaultMaxToolCalls
 		if req.MaxToolCalls != nil && *req.MaxToolCalls > 0 {
 			maxIters = *req.MaxToolCalls
 		}
 
+		guard := e.loopGuard(ctx, req)
+		loopCtx, cancelLoop := guard.context(ctx)
+		defer cancelLoop()
+
 		var allOutput []schema.ItemField
 		var allSources []searchSource
+		var usage usageTally
 
 		for iter := 0; iter < maxIters; iter++ {
+			if reason := guard.check(time.Now()); reason != "" {
+				resp.MarkIncomplete(reason)
+				break
+			}
+
 			// Build Responses API request
 			apiReq := buildResponsesAPIRequest(model, messages, req, expandedTools, true)
+			apiReq.Instructions = appendInstructions(instructions, toolGuidance)
+			apiReq.PromptCacheKey = e.promptCacheKey(req, apiReq, messages)
+
+			// Count input tokens, truncating to the context window if asked
+			inputTokens := e.fitContext(apiReq, messages)
+			if reason := guard.checkInput(inputTokens); reason != "" {
+				resp.MarkIncomplete(reason)
+				break
+			}
 
 			// Start streaming from backend
-			streamChan, streamErr := e.llm.CreateResponseStream(ctx, apiReq)
+			streamChan, streamErr := e.createResponseStream(loopCtx, apiReq)
 			if streamErr != nil {
-				events <- &schema.ErrorStreamingEvent{
-					Type:  "error",
-					Error: schema.ErrorField{Type: "api_error", Message: fmt.Sprintf("failed to start streaming: %v", streamErr)},
+				if reason := guard.stopped(ctx, loopCtx); reason != "" {
+					resp.MarkIncomplete(reason)
+					break
 				}
+				backendErr := apierror.Backend(streamErr)
+				stream.fail(backendErr.Type, backendErr.Code, fmt.Sprintf("failed to start streaming: %v", streamErr))
 				return
 			}
 
-			// Track the backend's completed response (from response.completed event)
-			var backendOutput []api.OutputItem
-			var backendUsage *api.UsageInfo
-
-			// Track announced items for proper SSE event sequencing.
-			// The OpenAI SDK expects response.output_item.added and
-			// response.content_part.added before any delta events.
-			// vLLM uses a per-token content_index (0, 1, 2, ...) instead of
-			// the standard content_index=0 for all deltas in one content part.
-			// We normalise: emit our own lifecycle events, rewrite delta
-			// content_index to 0, and skip vLLM's lifecycle events.
-			announcedOutputs := make(map[int]string) // output_index → item_id
-			announcedContent := make(map[int]bool)   // output_index → content_part announced
-			accumulatedText := make(map[int]string)  // output_index → accumulated text
-
-			// Forward backend events to client, skipping lifecycle events
+			// Translate the backend events into the gateway's own: the
+			// backend's lifecycle and done events are dropped, its deltas
+			// are renumbered into the response's output, and every item
+			// it streamed is closed once it is done.
+			backend := newBackendTranslator(e, stream)
 			for evt := range streamChan {
-				switch evt.Type {
-				case "response.created", "response.queued", "response.in_progress":
-					// Skip — we manage lifecycle events ourselves
-					continue
-
-				case "response.completed":
-					// Parse to extract final output and usage
-					var wrapper struct {
-						Response api.ResponsesAPIResponse `json:"response"`
-					}
-					if err := json.Unmarshal(evt.Data, &wrapper); err == nil {
-						backendOutput = wrapper.Response.Output
-						backendUsage = wrapper.Response.Usage
-					}
-					continue
-
-				case "response.failed":
-					events <- &schema.RawStreamingEvent{
-						EventType: evt.Type,
-						RawData:   patchResponseID(evt.Data, respID),
-					}
-					continue
-
-				case "response.output_item.added",
-					"response.output_item.done",
-					"response.content_part.added",
-					"response.content_part.done",
-					"response.output_text.done":
-					// Skip — the gateway emits its own normalised versions
-					continue
-
-				case "response.output_text.delta":
-					var fields struct {
-						OutputIndex int    `json:"output_index"`
-						ItemID      string `json:"item_id"`
-						Delta       string `json:"delta"`
-					}
-					if err := json.Unmarshal(evt.Data, &fields); err == nil {
-						// Emit output_item.added + content_part.added on first delta
-						seqNum = emitOutputItemAddedIfNeeded(events, announcedOutputs, fields.OutputIndex, fields.ItemID, "message", seqNum)
-						if !announcedContent[fields.OutputIndex] {
-							announcedContent[fields.OutputIndex] = true
-							seqNum = emitContentPartAddedIfNeeded(events, make(map[string]bool), announcedOutputs, fields.OutputIndex, 0, seqNum)
-						}
-						accumulatedText[fields.OutputIndex] += fields.Delta
-					}
-
-					// Re-emit delta with normalised content_index=0 and correct sequence_number
-					var m map[string]json.RawMessage
-					if err := json.Unmarshal(evt.Data, &m); err == nil {
-						m["content_index"], _ = json.Marshal(0)
-						m["sequence_number"], _ = json.Marshal(seqNum)
-						seqNum++
-						patched, _ := json.Marshal(m)
-						events <- &schema.RawStreamingEvent{
-							EventType: evt.Type,
-							RawData:   patchResponseID(json.RawMessage(patched), respID),
-						}
-					}
-
-				case "response.function_call_arguments.delta":
-					var fields struct {
-						OutputIndex int    `json:"output_index"`
-						ItemID      string `json:"item_id"`
-					}
-					if err := json.Unmarshal(evt.Data, &fields); err == nil {
-						seqNum = emitOutputItemAddedIfNeeded(events, announcedOutputs, fields.OutputIndex, fields.ItemID, "function_call", seqNum)
-					}
-					events <- &schema.RawStreamingEvent{
-						EventType: evt.Type,
-						RawData:   patchResponseID(evt.Data, respID),
-					}
+				backend.handle(evt)
+			}
+			backend.finish()
+			backendOutput := backend.output
+			backendUsage := backend.usage
+
+			// Calls keep the item ID they were streamed under
+			streamedCallItems := backend.callItemIDs()
+			callItemID := func(callID string) string {
+				if id, ok := streamedCallItems[callID]; ok {
+					return id
+				}
+				return e.NewID("fc_")
+			}
 
-				default:
-					events <- &schema.RawStreamingEvent{
-						EventType: evt.Type,
-						RawData:   patchResponseID(evt.Data, respID),
+			if backendUsage == nil {
+				outputTokens := e.countOutput(backendOutput)
+				if backendOutput == nil {
+					for _, item := range backend.messages() {
+						outputTokens += e.tokenCounter().CountTokens(*item.Content[0].Text)
 					}
 				}
+				backendUsage = estimateUsage(inputTokens, outputTokens)
+			}
+			guard.record(backendUsage)
+			usage.add(backendUsage)
+			if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+				stream.send(&schema.ResponseUsageStreamingEvent{
+					Type:       "response.usage",
+					ResponseID: respID,
+					Iteration:  iter,
+					Usage:      usageField(backendUsage),
+					TotalUsage: usageField(&usage.total),
+				})
 			}
 
-			// Emit done events for text content parts
-			for outputIdx, text := range accumulatedText {
-				itemID := announcedOutputs[outputIdx]
-
-				events <- &schema.ResponseOutputTextDoneStreamingEvent{
-					Type:           "response.output_text.done",
-					SequenceNumber: seqNum,
-					ItemID:         itemID,
-					OutputIndex:    outputIdx,
-					ContentIndex:   0,
-					Text:           text,
-					Logprobs:       make([]interface{}, 0),
-				}
-				seqNum++
-
-				events <- &schema.ResponseContentPartDoneStreamingEvent{
-					Type:           "response.content_part.done",
-					SequenceNumber: seqNum,
-					ItemID:         itemID,
-					OutputIndex:    outputIdx,
-					ContentIndex:   0,
-					Part: schema.ContentPart{
-						Type:        "output_text",
-						Text:        &text,
-						Annotations: make([]schema.Annotation, 0),
-					},
-				}
-				seqNum++
-
-				completedStatus := "completed"
-				role := "assistant"
-				t := text
-				events <- &schema.ResponseOutputItemDoneStreamingEvent{
-					Type:           "response.output_item.done",
-					SequenceNumber: seqNum,
-					OutputIndex:    outputIdx,
-					Item: schema.ItemField{
-						Type: "message",
-						ID:   itemID,
-						Role: &role,
-						Content: []schema.ContentPart{{
-							Type:        "output_text",
-							Text:        &t,
-							Annotations: make([]schema.Annotation, 0),
-						}},
-						Status: &completedStatus,
-					},
+			// The backend stream was cut off by the deadline or an
+			// interruption: keep the text that was already streamed and stop.
+			if reason := guard.stopped(ctx, loopCtx); reason != "" {
+				allOutput = append(allOutput, backend.messages()...)
+				resp.MarkIncomplete(reason)
+				break
+			}
+
+			// The backend failed or stopped early: end the response the
+			// same way, keeping the text that was already streamed
+			if backend.failure != nil {
+				allOutput = append(allOutput, backend.messages()...)
+				resp.MarkFailed(apierror.TypeModelError, backend.failure.code, backend.failure.message)
+				break
+			}
+			if backend.incomplete != "" {
+				if backendOutput != nil {
+					allOutput = append(allOutput, convertOutputItemsToSchema(backendOutput)...)
+				} else {
+					allOutput = append(allOutput, backend.messages()...)
 				}
-				seqNum++
+				resp.MarkIncomplete(backend.incomplete)
+				break
 			}
 
 			// Check for server-side tool calls in the completed output
 			_, toolCalls, hasToolCalls := parseResponsesOutput(backendOutput)
 
 			if hasToolCalls {
+				// Keep the reasoning that led to the calls, ahead of them
+				allOutput = append(allOutput, convertOutputItemsToSchema(reasoningItems(backendOutput))...)
+				messages = append(messages, reasoningMessages(backendOutput)...)
+
 				hasServerSide := false
 				var clientSideCalls []api.ToolCall
 
@@ -1996,8 +2966,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 					if isMCP {
 						hasServerSide = true
-						args := parseJSONArgs(tc.Arguments)
-						result, mcpErr := mcpClient.CallTool(ctx, tc.Name, args)
+						result, mcpErr := e.callMCPTool(loopCtx, mcpClient, tc)
 
 						completedStatus := "completed"
 						callID := tc.CallID
@@ -2006,7 +2975,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 						allOutput = append(allOutput, schema.ItemField{
 							Type:      "function_call",
-							ID:        generateID("fc_"),
+							ID:        callItemID(tc.CallID),
 							CallID:    &callID,
 							Name:      &funcName,
 							Arguments: &funcArgs,
@@ -2015,34 +2984,23 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 						var outputStr string
 						if mcpErr != nil {
-							outputStr = fmt.Sprintf("Error calling tool: %v", mcpErr)
+							outputStr = toolErrorOutput(mcpErr.Error())
+							failedItem := e.failedMCPCall(tc, mcpClient.Label, mcpErr)
+							allOutput = append(allOutput, failedItem)
+							stream.toolCallFailed(failedItem)
 						} else {
-							outputStr = mcpResultToString(result)
-						}
-
-						outputItem := schema.ItemField{
-							Type:   "function_call_output",
-							ID:     generateID("fco_"),
-							CallID: &callID,
-							Output: &outputStr,
-						}
-						allOutput = append(allOutput, outputItem)
+							outputStr = e.truncateToolOutput(mcpResultToString(result), mcpClient.MaxOutputBytes)
+							outputItem := schema.ItemField{
+								Type:   "function_call_output",
+								ID:     e.NewID("fco_"),
+								CallID: &callID,
+								Output: &outputStr,
+							}
+							allOutput = append(allOutput, outputItem)
 
-						// Emit function_call_output events to client
-						events <- &schema.ResponseOutputItemAddedStreamingEvent{
-							Type:           "response.output_item.added",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
+							// Emit function_call_output events to client
+							stream.addDoneItem(outputItem)
 						}
-						seqNum++
-						events <- &schema.ResponseOutputItemDoneStreamingEvent{
-							Type:           "response.output_item.done",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
-						}
-						seqNum++
 
 						messages = append(messages, api.Message{
 							Role: "assistant",
@@ -2063,36 +3021,32 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 					} else if isFileSearch {
 						hasServerSide = true
-						fsItemID := generateID("fs_")
-						fsOutputIndex := len(allOutput)
+						fsItemID := e.NewID("fs_")
+						fsOutputIndex := stream.nextIndex()
 
 						// Emit file_search call lifecycle events
-						events <- &schema.ResponseFileSearchCallInProgressStreamingEvent{
-							Type:           "response.file_search_call.in_progress",
-							SequenceNumber: seqNum,
-							OutputIndex:    fsOutputIndex,
-							ItemID:         fsItemID,
-						}
-						seqNum++
-						events <- &schema.ResponseFileSearchCallSearchingStreamingEvent{
-							Type:           "response.file_search_call.searching",
-							SequenceNumber: seqNum,
-							OutputIndex:    fsOutputIndex,
-							ItemID:         fsItemID,
-						}
-						seqNum++
+						stream.send(&schema.ResponseFileSearchCallInProgressStreamingEvent{
+							Type:        "response.file_search_call.in_progress",
+							OutputIndex: fsOutputIndex,
+							ItemID:      fsItemID,
+						})
+						stream.send(&schema.ResponseFileSearchCallSearchingStreamingEvent{
+							Type:        "response.file_search_call.searching",
+							OutputIndex: fsOutputIndex,
+							ItemID:      fsItemID,
+						})
 
 						args := parseJSONArgs(tc.Arguments)
 						query, _ := args["query"].(string)
-						outputStr, fsResults := e.executeFileSearch(ctx, fsCfg, query)
+						outputStr, fsResults, fsErr := e.callFileSearch(loopCtx, fsCfg, tc, query)
 
-						events <- &schema.ResponseFileSearchCallCompletedStreamingEvent{
-							Type:           "response.file_search_call.completed",
-							SequenceNumber: seqNum,
-							OutputIndex:    fsOutputIndex,
-							ItemID:         fsItemID,
+						if fsErr == nil {
+							stream.send(&schema.ResponseFileSearchCallCompletedStreamingEvent{
+								Type:        "response.file_search_call.completed",
+								OutputIndex: fsOutputIndex,
+								ItemID:      fsItemID,
+							})
 						}
-						seqNum++
 
 						// Collect file_citation sources
 						for _, r := range fsResults {
@@ -2109,35 +3063,29 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 						allOutput = append(allOutput, schema.ItemField{
 							Type:      "function_call",
-							ID:        generateID("fc_"),
+							ID:        callItemID(tc.CallID),
 							CallID:    &callID,
 							Name:      &funcName,
 							Arguments: &funcArgs,
 							Status:    &completedStatus,
 						})
 
-						outputItem := schema.ItemField{
-							Type:   "function_call_output",
-							ID:     generateID("fco_"),
-							CallID: &callID,
-							Output: &outputStr,
-						}
-						allOutput = append(allOutput, outputItem)
+						if fsErr != nil {
+							outputStr = toolErrorOutput(fsErr.Error())
+							failedItem := failedFileSearchCall(fsItemID, tc, query, fsErr)
+							allOutput = append(allOutput, failedItem)
+							stream.toolCallFailed(failedItem)
+						} else {
+							outputItem := schema.ItemField{
+								Type:   "function_call_output",
+								ID:     e.NewID("fco_"),
+								CallID: &callID,
+								Output: &outputStr,
+							}
+							allOutput = append(allOutput, outputItem)
 
-						events <- &schema.ResponseOutputItemAddedStreamingEvent{
-							Type:           "response.output_item.added",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
+							stream.addDoneItem(outputItem)
 						}
-						seqNum++
-						events <- &schema.ResponseOutputItemDoneStreamingEvent{
-							Type:           "response.output_item.done",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
-						}
-						seqNum++
 
 						messages = append(messages, api.Message{
 							Role: "assistant",
@@ -2158,36 +3106,28 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 					} else if isWebSearch {
 						hasServerSide = true
-						wsItemID := generateID("ws_")
-						wsOutputIndex := len(allOutput)
+						wsItemID := e.NewID("ws_")
+						wsOutputIndex := stream.nextIndex()
 
 						// Emit web_search call lifecycle events
-						events <- &schema.ResponseWebSearchCallInProgressStreamingEvent{
-							Type:           "response.web_search_call.in_progress",
-							SequenceNumber: seqNum,
-							OutputIndex:    wsOutputIndex,
-							ItemID:         wsItemID,
-						}
-						seqNum++
-						events <- &schema.ResponseWebSearchCallSearchingStreamingEvent{
-							Type:           "response.web_search_call.searching",
-							SequenceNumber: seqNum,
-							OutputIndex:    wsOutputIndex,
-							ItemID:         wsItemID,
-						}
-						seqNum++
+						stream.send(&schema.ResponseWebSearchCallInProgressStreamingEvent{
+							Type:        "response.web_search_call.in_progress",
+							OutputIndex: wsOutputIndex,
+							ItemID:      wsItemID,
+						})
+						stream.send(&schema.ResponseWebSearchCallSearchingStreamingEvent{
+							Type:        "response.web_search_call.searching",
+							OutputIndex: wsOutputIndex,
+							ItemID:      wsItemID,
+						})
 
-						args := parseJSONArgs(tc.Arguments)
-						query, _ := args["query"].(string)
-						outputStr, wsResults := e.executeWebSearch(ctx, wsCfg, query)
+						outputStr, wsResults := e.callWebSearchTool(loopCtx, wsCfg, tc)
 
-						events <- &schema.ResponseWebSearchCallCompletedStreamingEvent{
-							Type:           "response.web_search_call.completed",
-							SequenceNumber: seqNum,
-							OutputIndex:    wsOutputIndex,
-							ItemID:         wsItemID,
-						}
-						seqNum++
+						stream.send(&schema.ResponseWebSearchCallCompletedStreamingEvent{
+							Type:        "response.web_search_call.completed",
+							OutputIndex: wsOutputIndex,
+							ItemID:      wsItemID,
+						})
 
 						// Collect url_citation sources
 						for _, r := range wsResults {
@@ -2205,7 +3145,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 						allOutput = append(allOutput, schema.ItemField{
 							Type:      "function_call",
-							ID:        generateID("fc_"),
+							ID:        callItemID(tc.CallID),
 							CallID:    &callID,
 							Name:      &funcName,
 							Arguments: &funcArgs,
@@ -2214,26 +3154,13 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 						outputItem := schema.ItemField{
 							Type:   "function_call_output",
-							ID:     generateID("fco_"),
+							ID:     e.NewID("fco_"),
 							CallID: &callID,
 							Output: &outputStr,
 						}
 						allOutput = append(allOutput, outputItem)
 
-						events <- &schema.ResponseOutputItemAddedStreamingEvent{
-							Type:           "response.output_item.added",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
-						}
-						seqNum++
-						events <- &schema.ResponseOutputItemDoneStreamingEvent{
-							Type:           "response.output_item.done",
-							SequenceNumber: seqNum,
-							OutputIndex:    len(allOutput) - 1,
-							Item:           outputItem,
-						}
-						seqNum++
+						stream.addDoneItem(outputItem)
 
 						messages = append(messages, api.Message{
 							Role: "assistant",
@@ -2260,7 +3187,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 						funcArgs := tc.Arguments
 						allOutput = append(allOutput, schema.ItemField{
 							Type:      "function_call",
-							ID:        generateID("fc_"),
+							ID:        callItemID(tc.CallID),
 							CallID:    &callID,
 							Name:      &funcName,
 							Arguments: &funcArgs,
@@ -2290,10 +3217,15 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 						ID:                 resp.ID,
 						ConversationID:     conversationID,
 						PreviousResponseID: prevRespID,
+						ExternalID:         externalID(req),
+						Tenant:             featureflags.TenantFromContext(ctx),
+						APIKey:             state.APIKeyFromContext(ctx),
+						RequestID:          requestid.FromContext(ctx),
 						Request:            req,
 						Output:             allOutput,
 						Status:             "in_progress",
 						Messages:           messagesToConversationMessages(messages),
+						MessagesBase:       baseID,
 						CreatedAt:          time.Unix(resp.CreatedAt, 0),
 					})
 					// All calls were server-side — continue agentic loop
@@ -2308,28 +3240,19 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 				backendSchemaOutput := convertOutputItemsToSchema(backendOutput)
 				allOutput = append(allOutput, backendSchemaOutput...)
 
-				textContent, _, _ := parseResponsesOutput(backendOutput)
+				// Only the first candidate continues the conversation
+				kept := firstCandidate(backendOutput)
+				messages = append(messages, reasoningMessages(kept)...)
+				textContent, _, _ := parseResponsesOutput(kept)
 				if textContent != "" {
 					messages = append(messages, api.Message{
 						Role:    "assistant",
 						Content: textContent,
 					})
 				}
-			}
-
-			// Set usage from backend
-			if backendUsage != nil {
-				resp.Usage = &schema.UsageField{
-					InputTokens:  backendUsage.InputTokens,
-					OutputTokens: backendUsage.OutputTokens,
-					TotalTokens:  backendUsage.TotalTokens,
-					InputTokensDetails: schema.InputTokensDetails{
-						CachedTokens: 0,
-					},
-					OutputTokensDetails: schema.OutputTokensDetails{
-						ReasoningTokens: 0,
-					},
-				}
+			} else {
+				// The backend sent no completed output: keep what it streamed
+				allOutput = append(allOutput, backend.messages()...)
 			}
 
 			break
@@ -2338,9 +3261,10 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 		// Attach annotations from search sources
 		attachAnnotations(allOutput, allSources)
 
-		// Emit annotation streaming events
+		// Emit annotation streaming events for the streamed messages
 		for i := range allOutput {
-			if allOutput[i].Type != "message" {
+			outputIndex, streamed := stream.indexOf(allOutput[i].ID)
+			if allOutput[i].Type != "message" || !streamed {
 				continue
 			}
 			for j := range allOutput[i].Content {
@@ -2349,10 +3273,10 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 					continue
 				}
 				for _, ann := range cp.Annotations {
-					events <- &schema.ResponseOutputTextAnnotationAddedStreamingEvent{
+					stream.send(&schema.ResponseOutputTextAnnotationAddedStreamingEvent{
 						Type:         "response.output_text_annotation.added",
 						ResponseID:   respID,
-						OutputIndex:  i,
+						OutputIndex:  outputIndex,
 						ContentIndex: j,
 						Annotation: schema.ContentPart{
 							Type:        "output_text_annotation",
@@ -2360,8 +3284,7 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 							EndIndex:    &ann.EndIndex,
 							Annotations: []schema.Annotation{ann},
 						},
-					}
-					seqNum++
+					})
 				}
 			}
 		}
@@ -2372,9 +3295,13 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 			resp.Output = make([]schema.ItemField, 0)
 		}
 
-		resp.MarkCompleted()
+		if resp.Status == "in_progress" {
+			resp.MarkCompleted()
+		}
 
-		// Set usage if not already set
+		// Set usage from every backend call of the loop, including those
+		// made before a client-side tool call or an interruption
+		resp.Usage = usage.responseUsage()
 		if resp.Usage == nil {
 			resp.Usage = &schema.UsageField{
 				InputTokensDetails:  schema.InputTokensDetails{},
@@ -2382,29 +3309,71 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 			}
 		}
 
-		// Send response.completed event
-		events <- &schema.ResponseCompletedStreamingEvent{
-			Type:           "response.completed",
-			SequenceNumber: seqNum,
-			Response:       *resp,
+		// Screen output with the content moderator. Deltas have already been
+		// streamed, so a violation appends a refusal item and the final
+		// response carries only the refusal.
+		if violations, modErr := e.moderateOutput(ctx, resp.Output); modErr != nil {
+			resp.Output = make([]schema.ItemField, 0)
+			resp.MarkFailed(apierror.TypeServerError, apierror.CodeModerationError, fmt.Sprintf("content moderation failed: %v", modErr))
+		} else if len(violations) > 0 {
+			item := e.refuse(resp, "output_flagged", violations)
+			stream.refusal(item)
+		}
+
+		// Run response hooks. Deltas have already been streamed, so hooks
+		// can only affect the final response object and what is stored.
+		if resp.Status == "completed" {
+			e.runResponseHooks(ctx, req, resp)
 		}
 
-		// Final save with complete state
-		_ = e.sessions.SaveResponse(ctx, &state.Response{
+		// Watermark the output and attach provenance. As with hooks, the
+		// streamed deltas carry the unmarked text.
+		e.applyProvenance(ctx, resp)
+
+		// Send response.completed (or response.failed if a hook rejected
+		// it, or response.incomplete if a loop limit was reached)
+		switch resp.Status {
+		case "failed":
+			stream.send(&schema.ResponseFailedStreamingEvent{
+				Type:     "response.failed",
+				Response: *resp,
+			})
+		case "incomplete":
+			stream.send(&schema.ResponseIncompleteStreamingEvent{
+				Type:     "response.incomplete",
+				Response: *resp,
+			})
+		default:
+			stream.send(&schema.ResponseCompletedStreamingEvent{
+				Type:     "response.completed",
+				Response: *resp,
+			})
+		}
+
+		// Final save with complete state, appending the turn to the
+		// conversation in the same transaction
+		items := e.conversationItems(conversationID, req, resp.Output)
+		if err := e.sessions.SaveResponseWithItems(ctx, &state.Response{
 			ID:                 resp.ID,
 			ConversationID:     conversationID,
 			PreviousResponseID: prevRespID,
+			ExternalID:         externalID(req),
+			Tenant:             featureflags.TenantFromContext(ctx),
+			APIKey:             state.APIKeyFromContext(ctx),
+			RequestID:          requestid.FromContext(ctx),
 			Request:            req,
 			Output:             resp.Output,
 			Status:             resp.Status,
 			Usage:              resp.Usage,
+			Provenance:         resp.Provenance,
 			Messages:           messagesToConversationMessages(messages),
+			MessagesBase:       baseID,
 			CreatedAt:          time.Unix(resp.CreatedAt, 0),
 			CompletedAt:        timePtr(resp.CompletedAt),
-		})
-
-		// Append items to conversation for the Conversations API
-		_ = e.appendItemsToConversation(ctx, conversationID, req, allOutput)
+			Events:             e.eventsFor(ctx, req, resp, conversationID, false),
+		}, items); err == nil {
+			e.titleConversation(ctx, conv, req, resp)
+		}
 	}()
 
 	return events, nil
@@ -2412,10 +3381,12 @@ func (e *Engine) ProcessRequestStream(ctx context.Context, req *schema.ResponseR
 
 // Helper functions
 
-func generateID(prefix string) string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return prefix + hex.EncodeToString(b)
+// externalID returns the client-supplied external_id of a request, or "".
+func externalID(req *schema.ResponseRequest) string {
+	if req.ExternalID == nil {
+		return ""
+	}
+	return *req.ExternalID
 }
 
 func timePtr(t *int64) *time.Time {
@@ -2515,6 +3486,7 @@ func convertToolsToResponse(reqTools []schema.ResponsesToolParam) []schema.Respo
 			Parameters:        t.Parameters,
 			Strict:            t.Strict,
 			ServerLabel:       t.ServerLabel,
+			AllowedTools:      t.AllowedTools,
 			SearchContextSize: t.SearchContextSize,
 			UserLocation:      t.UserLocation,
 			VectorStoreIDs:    t.VectorStoreIDs,
@@ -2561,6 +3533,7 @@ func (e *Engine) GetResponse(ctx context.Context, responseID string) (*schema.Re
 
 	// Restore Usage from stored state
 	schemaResp.Usage = convertStoredUsage(stateResp.Usage)
+	schemaResp.Provenance = convertStoredProvenance(stateResp.Provenance)
 
 	schemaResp.CreatedAt = stateResp.CreatedAt.Unix()
 	if stateResp.CompletedAt != nil {
@@ -2599,13 +3572,26 @@ func (e *Engine) GetResponse(ctx context.Context, responseID string) (*schema.Re
 		convID := stateResp.ConversationID
 		schemaResp.Conversation = &convID
 	}
+	if stateResp.ExternalID != "" {
+		extID := stateResp.ExternalID
+		schemaResp.ExternalID = &extID
+	}
 
 	return schemaResp, nil
 }
 
+// CountResponses returns the number of stored responses matching filter
+func (e *Engine) CountResponses(ctx context.Context, filter state.ResponseFilter) (int, error) {
+	n, err := e.sessions.CountResponses(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count responses: %w", err)
+	}
+	return n, nil
+}
+
 // ListResponses retrieves a paginated list of responses
-func (e *Engine) ListResponses(ctx context.Context, after, before string, limit int, order, model string) ([]*schema.Response, bool, error) {
-	stateResponses, hasMore, err := e.sessions.ListResponsesPaginated(ctx, after, before, limit, order, model)
+func (e *Engine) ListResponses(ctx context.Context, after, before string, limit int, order string, filter state.ResponseFilter) ([]*schema.Response, bool, error) {
+	stateResponses, hasMore, err := e.sessions.ListResponsesPaginated(ctx, after, before, limit, order, filter)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to list responses: %w", err)
 	}
@@ -2615,8 +3601,8 @@ func (e *Engine) ListResponses(ctx context.Context, after, before string, limit
 	for _, stateResp := range stateResponses {
 		req := convertStoredRequest(stateResp.Request)
 
-		var modelName string
-		if req != nil && req.Model != nil {
+		modelName := stateResp.Model
+		if modelName == "" && req != nil && req.Model != nil {
 			modelName = *req.Model
 		}
 
@@ -2624,6 +3610,10 @@ func (e *Engine) ListResponses(ctx context.Context, after, before string, limit
 		schemaResp.Status = stateResp.Status
 		schemaResp.Output = convertStoredOutput(stateResp.Output)
 		schemaResp.Usage = convertStoredUsage(stateResp.Usage)
+		schemaResp.Provenance = convertStoredProvenance(stateResp.Provenance)
+		if req != nil {
+			schemaResp.Metadata = req.Metadata
+		}
 
 		schemaResp.CreatedAt = stateResp.CreatedAt.Unix()
 		if stateResp.CompletedAt != nil {
@@ -2636,6 +3626,10 @@ func (e *Engine) ListResponses(ctx context.Context, after, before string, limit
 			convID := stateResp.ConversationID
 			schemaResp.Conversation = &convID
 		}
+		if stateResp.ExternalID != "" {
+			extID := stateResp.ExternalID
+			schemaResp.ExternalID = &extID
+		}
 
 		responses = append(responses, schemaResp)
 	}
@@ -2650,12 +3644,3 @@ func (e *Engine) DeleteResponse(ctx context.Context, responseID string) error {
 	}
 	return nil
 }
-
-// GetResponseInputItems retrieves input items for a specific response
-func (e *Engine) GetResponseInputItems(ctx context.Context, responseID string) (interface{}, error) {
-	items, err := e.sessions.GetResponseInputItems(ctx, responseID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get response input items: %w", err)
-	}
-	return items, nil
-}
@@ -5,13 +5,29 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/mcp"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/tokenizer"
 	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+	"github.com/leseb/openresponses-gw/pkg/webfetch"
+	"github.com/leseb/openresponses-gw/pkg/websearch"
 )
 
 // --- Test helpers ---
@@ -102,6 +118,30 @@ func TestExtractInputMessages_MessageItems(t *testing.T) {
 			wantLen:  1,
 			wantRole: "tool",
 		},
+		{
+			name: "developer message",
+			input: []interface{}{
+				map[string]interface{}{
+					"type":    "message",
+					"role":    "developer",
+					"content": "answer in French",
+				},
+			},
+			wantLen:  1,
+			wantRole: "developer",
+		},
+		{
+			name: "system message read as developer",
+			input: []interface{}{
+				map[string]interface{}{
+					"type":    "message",
+					"role":    "system",
+					"content": "answer in French",
+				},
+			},
+			wantLen:  1,
+			wantRole: "developer",
+		},
 	}
 
 	for _, tt := range tests {
@@ -156,6 +196,118 @@ func TestExtractInputMessages_MultimodalContent(t *testing.T) {
 	}
 }
 
+func TestExtractInputMessages_AudioContent(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{
+					"type":        "input_audio",
+					"input_audio": map[string]interface{}{"data": "UklGRg==", "format": "wav"},
+				},
+			},
+		},
+	}
+
+	msgs := extractInputMessages(input)
+	if len(msgs) != 1 || len(msgs[0].ContentParts) != 1 {
+		t.Fatalf("expected 1 message with 1 part, got %+v", msgs)
+	}
+	part := msgs[0].ContentParts[0]
+	if part.Type != "input_audio" || part.InputAudio == nil {
+		t.Fatalf("expected input_audio part, got %+v", part)
+	}
+	if *part.InputAudio != (api.MessageAudio{Data: "UklGRg==", Format: "wav"}) {
+		t.Errorf("unexpected audio %+v", *part.InputAudio)
+	}
+
+	// Forwarded to a Responses backend as an input_audio part
+	converted := convertMessagesToResponsesInput(msgs)
+	parts := converted[0].(map[string]interface{})["content"].([]map[string]interface{})
+	want := map[string]interface{}{"data": "UklGRg==", "format": "wav"}
+	if parts[0]["type"] != "input_audio" || !reflect.DeepEqual(parts[0]["input_audio"], want) {
+		t.Errorf("converted part = %v", parts[0])
+	}
+}
+
+func TestExtractInputMessages_ImageFileID(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_image", "file_id": "file_photo", "detail": "low"},
+			},
+		},
+	}
+
+	msgs := extractInputMessages(input)
+	if len(msgs) != 1 || len(msgs[0].ContentParts) != 1 {
+		t.Fatalf("expected 1 message with 1 part, got %+v", msgs)
+	}
+	if got := msgs[0].ContentParts[0].ImageURL; got == nil || *got != (api.MessageImageURL{FileID: "file_photo", Detail: "low"}) {
+		t.Errorf("image = %+v", got)
+	}
+
+	// Unresolved, the file ID is forwarded to a Responses backend
+	converted := convertMessagesToResponsesInput(msgs)
+	parts := converted[0].(map[string]interface{})["content"].([]map[string]interface{})
+	if parts[0]["type"] != "input_image" || parts[0]["file_id"] != "file_photo" {
+		t.Errorf("converted part = %v", parts[0])
+	}
+}
+
+func TestExtractInputMessages_VideoContent(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_video", "video_url": "https://example.com/a.mp4"},
+				map[string]interface{}{"type": "input_video", "video_url": map[string]interface{}{"url": "https://example.com/b.mp4"}},
+				map[string]interface{}{"type": "input_video", "file_id": "file_clip"},
+			},
+		},
+	}
+
+	msgs := extractInputMessages(input)
+	if len(msgs) != 1 || len(msgs[0].ContentParts) != 3 {
+		t.Fatalf("expected 1 message with 3 parts, got %+v", msgs)
+	}
+	want := []api.MessageVideoURL{{URL: "https://example.com/a.mp4"}, {URL: "https://example.com/b.mp4"}, {FileID: "file_clip"}}
+	for i, w := range want {
+		part := msgs[0].ContentParts[i]
+		if part.Type != "video_url" || part.VideoURL == nil || *part.VideoURL != w {
+			t.Errorf("part %d = %+v, want video %+v", i, part, w)
+		}
+	}
+
+	// Forwarded to a Responses backend as input_video parts
+	converted := convertMessagesToResponsesInput(msgs)
+	parts := converted[0].(map[string]interface{})["content"].([]map[string]interface{})
+	if parts[0]["type"] != "input_video" || parts[0]["video_url"] != "https://example.com/a.mp4" {
+		t.Errorf("converted part 0 = %v", parts[0])
+	}
+	if parts[2]["type"] != "input_video" || parts[2]["file_id"] != "file_clip" {
+		t.Errorf("converted part 2 = %v", parts[2])
+	}
+}
+
+func TestExtractInputMessages_FailedToolCall(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{"type": "function_call", "call_id": "call_1", "name": "search", "arguments": "{}"},
+		map[string]interface{}{"type": "mcp_call", "id": "mcp_1", "call_id": "call_1", "name": "search", "status": "failed", "error": "connection refused"},
+	}
+	msgs := extractInputMessages(input)
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	if msgs[1].Role != "tool" || msgs[1].ToolCallID != "call_1" || msgs[1].Content != "Error calling tool: connection refused" {
+		t.Errorf("message 1 = %+v", msgs[1])
+	}
+}
+
 func TestExtractInputMessages_NonStringNonArray(t *testing.T) {
 	msgs := extractInputMessages(42)
 	if len(msgs) != 1 {
@@ -207,6 +359,22 @@ func TestConvertMessagesToResponsesInput_SystemSkipped(t *testing.T) {
 	}
 }
 
+func TestConvertMessagesToResponsesInput_DeveloperKept(t *testing.T) {
+	messages := []api.Message{
+		{Role: "system", Content: "you are helpful"},
+		{Role: "user", Content: "hello"},
+		{Role: "developer", Content: "answer in French"},
+	}
+	input := convertMessagesToResponsesInput(messages)
+	if len(input) != 2 {
+		t.Fatalf("expected 2 input items, got %d", len(input))
+	}
+	item := input[1].(map[string]interface{})
+	if item["role"] != "developer" || item["content"] != "answer in French" {
+		t.Errorf("expected the developer message in place, got %v", item)
+	}
+}
+
 func TestConvertMessagesToResponsesInput_AssistantWithToolCalls(t *testing.T) {
 	messages := []api.Message{
 		{
@@ -459,6 +627,33 @@ func TestConvertOutputItemsToSchema_Message(t *testing.T) {
 	}
 }
 
+func TestConvertOutputItemsToSchema_OutputAudio(t *testing.T) {
+	items := []api.OutputItem{{
+		Type: "message",
+		ID:   "msg-1",
+		Role: "assistant",
+		Content: []api.ContentItem{
+			{Type: "output_audio", Data: "UklGRg==", Transcript: "hello"},
+		},
+	}}
+	result := convertOutputItemsToSchema(items)
+	if len(result) != 1 || len(result[0].Content) != 1 {
+		t.Fatalf("expected 1 message with 1 part, got %+v", result)
+	}
+	cp := result[0].Content[0]
+	if cp.Type != "output_audio" || cp.Text != nil || cp.Format != nil {
+		t.Errorf("unexpected part %+v", cp)
+	}
+	if *cp.Data != "UklGRg==" || *cp.Transcript != "hello" {
+		t.Errorf("data = %q, transcript = %q", *cp.Data, *cp.Transcript)
+	}
+
+	// The transcript stands for the audio in the history
+	if text, _, _ := parseResponsesOutput(items); text != "hello" {
+		t.Errorf("parseResponsesOutput text = %q, want hello", text)
+	}
+}
+
 func TestConvertOutputItemsToSchema_FunctionCall(t *testing.T) {
 	items := []api.OutputItem{
 		{
@@ -488,6 +683,39 @@ func TestConvertOutputItemsToSchema_FunctionCall(t *testing.T) {
 	}
 }
 
+func TestStreamedFunctionCall(t *testing.T) {
+	backendOutput := []api.OutputItem{
+		{Type: "message", ID: "msg-1"},
+		{Type: "function_call", ID: "fc-1", Name: "search", Arguments: `{"q":"test"}`, CallID: "call-1"},
+	}
+	tests := []struct {
+		name          string
+		outputIndex   int
+		itemID        string
+		wantName      string
+		wantArguments string
+	}{
+		{name: "by item ID", outputIndex: 5, itemID: "fc-1", wantName: "search", wantArguments: `{"q":"test"}`},
+		{name: "by output index", outputIndex: 1, itemID: "fc-gateway", wantName: "search", wantArguments: `{"q":"test"}`},
+		{name: "streamed arguments only", outputIndex: 0, itemID: "fc-gateway", wantArguments: `{"q":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := streamedFunctionCall(backendOutput, tt.outputIndex, tt.itemID, `{"q":`)
+			if item.ID != tt.itemID || *item.Status != "completed" || *item.Arguments != tt.wantArguments {
+				t.Errorf("item = %s %s %q", item.ID, *item.Status, *item.Arguments)
+			}
+			var name string
+			if item.Name != nil {
+				name = *item.Name
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+		})
+	}
+}
+
 func TestConvertOutputItemsToSchema_FunctionCallOutput(t *testing.T) {
 	items := []api.OutputItem{
 		{
@@ -512,6 +740,99 @@ func TestConvertOutputItemsToSchema_FunctionCallOutput(t *testing.T) {
 	}
 }
 
+func TestConvertOutputItemsToSchema_Reasoning(t *testing.T) {
+	items := []api.OutputItem{{
+		Type:             "reasoning",
+		ID:               "rs-1",
+		Summary:          []api.ContentItem{{Type: "summary_text", Text: "Thought about it"}},
+		Content:          []api.ContentItem{{Type: "reasoning_text", Text: "step 1"}},
+		EncryptedContent: "opaque",
+	}}
+	result := convertOutputItemsToSchema(items)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(result))
+	}
+	r := result[0]
+	if r.Type != "reasoning" || r.ID != "rs-1" {
+		t.Errorf("expected reasoning rs-1, got %s %s", r.Type, r.ID)
+	}
+	if len(r.Summary) != 1 || r.Summary[0].Type != "summary_text" || *r.Summary[0].Text != "Thought about it" {
+		t.Errorf("unexpected summary %+v", r.Summary)
+	}
+	if len(r.Content) != 1 || *r.Content[0].Text != "step 1" {
+		t.Errorf("unexpected content %+v", r.Content)
+	}
+	if r.EncryptedContent == nil || *r.EncryptedContent != "opaque" {
+		t.Errorf("expected encrypted content, got %v", r.EncryptedContent)
+	}
+}
+
+// --- reasoning replay tests ---
+
+func TestReasoningMessages(t *testing.T) {
+	output := []api.OutputItem{
+		{Type: "reasoning", ID: "rs-enc", EncryptedContent: "opaque", Summary: []api.ContentItem{{Text: "s1"}, {Text: "s2"}}},
+		{Type: "reasoning", ID: "rs-text", Content: []api.ContentItem{{Text: "think "}, {Text: "more"}}},
+		{Type: "reasoning", ID: "rs-summary-only", Summary: []api.ContentItem{{Text: "s"}}},
+		{Type: "message", ID: "msg-1", Content: []api.ContentItem{{Type: "output_text", Text: "hi"}}},
+	}
+	messages := reasoningMessages(output)
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 replayable reasoning messages, got %d", len(messages))
+	}
+	if r := messages[0].Reasoning; messages[0].Role != "assistant" || r.ID != "rs-enc" || r.EncryptedContent != "opaque" || len(r.Summary) != 2 {
+		t.Errorf("unexpected first message %+v", messages[0])
+	}
+	if r := messages[1].Reasoning; r.ID != "rs-text" || r.Text != "think more" {
+		t.Errorf("unexpected second message %+v", messages[1])
+	}
+}
+
+func TestReasoningInputRoundTrip(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			"type":              "reasoning",
+			"id":                "rs-1",
+			"summary":           []interface{}{map[string]interface{}{"type": "summary_text", "text": "plan"}},
+			"encrypted_content": "opaque",
+		},
+		map[string]interface{}{
+			"type": "function_call", "call_id": "call-1", "name": "search", "arguments": "{}",
+		},
+	}
+	messages := extractInputMessages(input)
+	if len(messages) != 2 || messages[0].Reasoning == nil {
+		t.Fatalf("expected reasoning then function call, got %+v", messages)
+	}
+
+	// Stored and reloaded history keeps the reasoning
+	stored := messagesToConversationMessages(messages)
+	if stored[0].Reasoning == nil || stored[0].Reasoning.EncryptedContent != "opaque" {
+		t.Fatalf("reasoning not stored: %+v", stored[0])
+	}
+	if r := reasoningFromRecord(stored[0].Reasoning); r.ID != "rs-1" || len(r.Summary) != 1 {
+		t.Errorf("reasoning not reloaded: %+v", r)
+	}
+
+	replayed := convertMessagesToResponsesInput(messages)
+	if len(replayed) != 2 {
+		t.Fatalf("expected 2 input items, got %d", len(replayed))
+	}
+	item := replayed[0].(map[string]interface{})
+	if item["type"] != "reasoning" || item["id"] != "rs-1" || item["encrypted_content"] != "opaque" {
+		t.Errorf("unexpected reasoning item %v", item)
+	}
+	if summary := item["summary"].([]map[string]interface{}); len(summary) != 1 || summary[0]["text"] != "plan" {
+		t.Errorf("unexpected summary %v", item["summary"])
+	}
+	if _, ok := item["content"]; ok {
+		t.Errorf("reasoning without text should have no content: %v", item)
+	}
+	if replayed[1].(map[string]interface{})["type"] != "function_call" {
+		t.Errorf("function call should follow its reasoning: %v", replayed[1])
+	}
+}
+
 // --- convertToToolParams tests ---
 
 func TestConvertToToolParams_FunctionToolsOnly(t *testing.T) {
@@ -618,152 +939,983 @@ func TestExpandFileSearchTools_DefaultMaxResults(t *testing.T) {
 	}
 }
 
-// --- generateID tests ---
+func TestExecuteFileSearch_Errors(t *testing.T) {
+	cfg := fileSearchConfig{VectorStoreIDs: []string{"vs-1", "vs-2"}, MaxNumResults: 5}
 
-func TestGenerateID_Format(t *testing.T) {
-	prefixes := []string{"resp_", "conv_", "msg_", "fc_"}
-	for _, prefix := range prefixes {
-		id := generateID(prefix)
-		if !strings.HasPrefix(id, prefix) {
-			t.Errorf("expected prefix %q, got %q", prefix, id)
-		}
-		// hex suffix should be 32 chars (16 bytes * 2)
-		suffix := strings.TrimPrefix(id, prefix)
-		if len(suffix) != 32 {
-			t.Errorf("expected 32 hex chars suffix, got %d: %q", len(suffix), suffix)
-		}
+	e := &Engine{vectorSearch: &dummyVectorSearcher{err: errors.New("index unavailable")}}
+	if _, _, err := e.executeFileSearch(context.Background(), cfg, "q"); err == nil || !strings.Contains(err.Error(), "vs-2") {
+		t.Errorf("executeFileSearch error = %v, want the errors of both stores", err)
+	}
+
+	e = &Engine{vectorSearch: &dummyVectorSearcher{}}
+	if out, _, err := e.executeFileSearch(context.Background(), cfg, "q"); err != nil || out != "No relevant results found." {
+		t.Errorf("executeFileSearch = %q, %v", out, err)
+	}
+}
+
+func TestExecuteFileSearch_Ranking(t *testing.T) {
+	// Both stores return the same chunks, so each must appear once
+	e := &Engine{vectorSearch: &dummyVectorSearcher{results: []vectorstore.SearchResult{
+		{FileID: "file_a", ChunkID: "file_a_chunk_0", Content: "low", Score: 0.2},
+		{FileID: "file_b", ChunkID: "file_b_chunk_3", Content: "best", Score: 0.9},
+		{FileID: "file_a", ChunkID: "file_a_chunk_1", Content: "good", Score: 0.5},
+		{FileID: "file_c", ChunkID: "file_c_chunk_0", Content: "fair", Score: 0.4},
+	}}}
+	cfg := fileSearchConfig{VectorStoreIDs: []string{"vs-1", "vs-2"}, MaxNumResults: 2, ScoreThreshold: 0.3}
+	_, results, err := e.executeFileSearch(context.Background(), cfg, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Content)
+	}
+	if strings.Join(got, ",") != "best,good" {
+		t.Errorf("results = %v, want [best good]", got)
+	}
+}
+
+func TestExpandFileSearchTools_ScoreThreshold(t *testing.T) {
+	e := &Engine{vectorSearch: &dummyVectorSearcher{}}
+	tools := []schema.ResponsesToolParam{
+		{Type: "file_search", VectorStoreIDs: []string{"vs-1"}, RankingOptions: map[string]interface{}{"score_threshold": 0.6}},
+	}
+	_, configs := e.expandFileSearchTools(tools)
+	if configs["file_search"].ScoreThreshold != 0.6 {
+		t.Errorf("expected ScoreThreshold=0.6, got %v", configs["file_search"].ScoreThreshold)
+	}
+}
+
+func TestTruncateToolOutput(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{MaxToolOutputBytes: 10}}
+	if got := e.truncateToolOutput("short", 0); got != "short" {
+		t.Errorf("truncateToolOutput(short) = %q", got)
 	}
+	want := "01234\n...\nvwxyz\n[output truncated: 26 of 36 bytes omitted]"
+	if got := e.truncateToolOutput("0123456789abcdefghijklmnopqrstuvwxyz", 0); got != want {
+		t.Errorf("truncateToolOutput = %q, want %q", got, want)
+	}
+	// A connector's limit overrides the engine's
+	if got := e.truncateToolOutput("0123456789abcdef", 20); got != "0123456789abcdef" {
+		t.Errorf("truncateToolOutput(limit 20) = %q", got)
+	}
+	// Cuts fall on rune boundaries
+	got := e.truncateToolOutput(strings.Repeat("é", 10), 0)
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "éé\n...\n") {
+		t.Errorf("truncateToolOutput(runes) = %q", got)
+	}
+}
 
-	// Uniqueness
-	id1 := generateID("test_")
-	id2 := generateID("test_")
-	if id1 == id2 {
-		t.Error("expected unique IDs, got duplicates")
+func TestFailedToolCallItems(t *testing.T) {
+	e := &Engine{}
+	tc := toolCallInfo{CallID: "call_1", Name: "lookup", Arguments: `{"id":1}`}
+	item := e.failedMCPCall(tc, "crm", errors.New("timeout"))
+	if item.Type != "mcp_call" || !strings.HasPrefix(item.ID, "mcp_") || *item.Status != "failed" || *item.Error != "timeout" || *item.ServerLabel != "crm" || *item.Name != "lookup" {
+		t.Errorf("mcp_call item = %+v", item)
+	}
+
+	item = failedFileSearchCall("fs_1", tc, "q", errors.New("index unavailable"))
+	if item.Type != "file_search_call" || item.ID != "fs_1" || *item.CallID != "call_1" || *item.Status != "failed" || len(item.Queries) != 1 || item.Queries[0] != "q" {
+		t.Errorf("file_search_call item = %+v", item)
 	}
 }
 
-// --- patchResponseID tests ---
+// --- toolInstructions tests ---
+
+func TestToolInstructions(t *testing.T) {
+	cfg := &config.EngineConfig{
+		ToolInstructions: map[string]string{
+			"file_search": "Use file_search for questions about uploaded documents.",
+			"web_search":  "Use web_search for current events.",
+		},
+	}
 
-func TestPatchResponseID(t *testing.T) {
 	tests := []struct {
-		name    string
-		input   string
-		newID   string
-		wantID  string
-		changed bool
+		name   string
+		engine *Engine
+		tools  []schema.ResponsesToolParam
+		want   string
 	}{
 		{
-			name:    "patches existing ID",
-			input:   `{"response_id":"old-id","text":"hello"}`,
-			newID:   "new-id",
-			wantID:  "new-id",
-			changed: true,
+			name:   "no server-side tools",
+			engine: &Engine{config: cfg, vectorSearch: &dummyVectorSearcher{}},
+			tools:  []schema.ResponsesToolParam{{Type: "function", Name: "calc"}},
+			want:   "",
+		},
+		{
+			name:   "file_search expanded",
+			engine: &Engine{config: cfg, vectorSearch: &dummyVectorSearcher{}},
+			tools: []schema.ResponsesToolParam{
+				{Type: "file_search"},
+				{Type: "file_search"},
+			},
+			want: "Use file_search for questions about uploaded documents.",
 		},
 		{
-			name:    "no-op without response_id",
-			input:   `{"text":"hello"}`,
-			newID:   "new-id",
-			changed: false,
+			name:   "file_search not configured",
+			engine: &Engine{config: cfg},
+			tools:  []schema.ResponsesToolParam{{Type: "file_search"}},
+			want:   "",
 		},
 		{
-			name:    "handles invalid JSON",
-			input:   `not json`,
-			newID:   "new-id",
-			changed: false,
+			name:   "mcp without guidance",
+			engine: &Engine{config: cfg, connectors: memory.NewConnectorsStore()},
+			tools:  []schema.ResponsesToolParam{{Type: "mcp", ServerLabel: "x"}},
+			want:   "",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := patchResponseID(json.RawMessage(tt.input), tt.newID)
-
-			if tt.changed {
-				var m map[string]interface{}
-				if err := json.Unmarshal(result, &m); err != nil {
-					t.Fatalf("failed to unmarshal result: %v", err)
-				}
-				if m["response_id"] != tt.wantID {
-					t.Errorf("expected response_id=%q, got %v", tt.wantID, m["response_id"])
-				}
-			} else {
-				if string(result) != tt.input {
-					t.Errorf("expected unchanged input, got %q", string(result))
-				}
+			if got := tt.engine.toolInstructions(tt.tools); got != tt.want {
+				t.Errorf("toolInstructions() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
-// --- parseJSONArgs tests ---
+func TestAppendInstructions(t *testing.T) {
+	if got := appendInstructions(nil, ""); got != nil {
+		t.Errorf("expected nil, got %q", *got)
+	}
+	if got := appendInstructions(nil, "guide"); got == nil || *got != "guide" {
+		t.Errorf("expected %q, got %v", "guide", got)
+	}
+
+	base := "be brief"
+	got := appendInstructions(&base, "guide")
+	if got == nil || *got != "be brief\n\nguide" {
+		t.Errorf("expected combined instructions, got %v", got)
+	}
+	if base != "be brief" {
+		t.Errorf("original instructions were modified: %q", base)
+	}
+}
+
+func TestApplyModelParams(t *testing.T) {
+	cfg := &config.EngineConfig{Models: map[string]config.ModelConfig{
+		"small": {
+			Defaults:        config.ModelParams{Temperature: float64Ptr(0.2), ReasoningEffort: "low"},
+			Overrides:       config.ModelParams{TopP: float64Ptr(0.9)},
+			MaxOutputTokens: 100,
+		},
+		"*": {Defaults: config.ModelParams{MaxOutputTokens: intPtr(50)}},
+	}}
+	e := &Engine{config: cfg}
+	effort := "high"
 
-func TestParseJSONArgs(t *testing.T) {
 	tests := []struct {
-		name  string
-		input string
-		check func(map[string]any) bool
+		name     string
+		req      schema.ResponseRequest
+		wantTemp *float64
+		wantTopP *float64
+		wantMax  *int
+		wantEff  string
 	}{
 		{
-			name:  "valid JSON",
-			input: `{"key":"value","num":42}`,
-			check: func(m map[string]any) bool {
-				return m["key"] == "value" && m["num"] == float64(42)
-			},
+			name:     "defaults fill unset parameters",
+			req:      schema.ResponseRequest{Model: stringPtr("small")},
+			wantTemp: float64Ptr(0.2), wantTopP: float64Ptr(0.9), wantMax: intPtr(100), wantEff: "low",
 		},
 		{
-			name:  "empty string",
-			input: "",
-			check: func(m map[string]any) bool {
-				return len(m) == 0
-			},
+			name: "request values win over defaults",
+			req: schema.ResponseRequest{Model: stringPtr("small"), Temperature: float64Ptr(1), TopP: float64Ptr(0.5),
+				MaxOutputTokens: intPtr(20), Reasoning: &schema.ReasoningParam{Effort: &effort}},
+			wantTemp: float64Ptr(1), wantTopP: float64Ptr(0.9), wantMax: intPtr(20), wantEff: "high",
 		},
 		{
-			name:  "invalid JSON",
-			input: "not json",
-			check: func(m map[string]any) bool {
-				return len(m) == 0
-			},
+			name:     "limit caps max_output_tokens",
+			req:      schema.ResponseRequest{Model: stringPtr("small"), MaxOutputTokens: intPtr(1000)},
+			wantTemp: float64Ptr(0.2), wantTopP: float64Ptr(0.9), wantMax: intPtr(100), wantEff: "low",
+		},
+		{
+			name:    "wildcard entry",
+			req:     schema.ResponseRequest{Model: stringPtr("other")},
+			wantMax: intPtr(50),
 		},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parseJSONArgs(tt.input)
-			if !tt.check(result) {
-				t.Errorf("unexpected result: %v", result)
+			req := tt.req
+			e.applyModelParams(&req)
+			if !reflect.DeepEqual(req.Temperature, tt.wantTemp) || !reflect.DeepEqual(req.TopP, tt.wantTopP) ||
+				!reflect.DeepEqual(req.MaxOutputTokens, tt.wantMax) {
+				t.Errorf("temperature = %v, top_p = %v, max_output_tokens = %v", req.Temperature, req.TopP, req.MaxOutputTokens)
+			}
+			var eff string
+			if req.Reasoning != nil && req.Reasoning.Effort != nil {
+				eff = *req.Reasoning.Effort
+			}
+			if eff != tt.wantEff {
+				t.Errorf("reasoning effort = %q, want %q", eff, tt.wantEff)
 			}
 		})
 	}
+	if effort != "high" {
+		t.Errorf("request reasoning was modified: %q", effort)
+	}
 }
 
-// --- messagesToConversationMessages tests ---
+func TestModelAliases(t *testing.T) {
+	e := &Engine{aliases: modelAliases{targets: map[string]string{"prod-chat": "llama-3.1-70b"}}}
 
-func TestMessagesToConversationMessages(t *testing.T) {
-	messages := []api.Message{
-		{Role: "user", Content: "hello"},
-		{
-			Role: "assistant",
-			ToolCalls: []api.ToolCall{
-				{
-					ID:   "tc-1",
-					Type: "function",
-					Function: api.ToolCallFunction{
-						Name:      "search",
-						Arguments: `{"q":"test"}`,
-					},
-				},
-			},
-		},
-		{Role: "tool", Content: "result", ToolCallID: "tc-1"},
+	req := &schema.ResponseRequest{Model: stringPtr("prod-chat")}
+	if alias := e.resolveModelAlias(req); alias == nil || *alias != "prod-chat" || *req.Model != "llama-3.1-70b" {
+		t.Errorf("resolveModelAlias() = %v, model %q", alias, *req.Model)
 	}
-
-	result := messagesToConversationMessages(messages)
-	if len(result) != 3 {
-		t.Fatalf("expected 3 messages, got %d", len(result))
+	req = &schema.ResponseRequest{Model: stringPtr("llama-3.1-70b")}
+	if alias := e.resolveModelAlias(req); alias != nil || *req.Model != "llama-3.1-70b" {
+		t.Errorf("resolveModelAlias() = %v, model %q", alias, *req.Model)
 	}
 
-	// Check user message
-	if result[0].Role != "user" || result[0].Content != "hello" {
-		t.Errorf("unexpected user message: %+v", result[0])
+	tests := []struct {
+		name    string
+		alias   string
+		model   string
+		wantErr bool
+	}{
+		{"repoint", "prod-chat", "llama-3.1-8b", false},
+		{"new alias", "fast", "llama-3.1-8b", false},
+		{"self", "x", "x", true},
+		{"target is an alias", "other", "prod-chat", true},
+		{"alias is a target", "llama-3.1-8b", "m", true},
+		{"empty model", "y", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.SetModelAlias(tt.alias, tt.model); (err != nil) != tt.wantErr {
+				t.Errorf("SetModelAlias() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	want := []schema.ModelAlias{
+		{Object: "model_alias", Alias: "fast", Model: "llama-3.1-8b"},
+		{Object: "model_alias", Alias: "prod-chat", Model: "llama-3.1-8b"},
+	}
+	if got := e.ModelAliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ModelAliases() = %v", got)
+	}
+	if err := e.DeleteModelAlias("fast"); err != nil {
+		t.Fatalf("DeleteModelAlias() error = %v", err)
+	}
+	if err := e.DeleteModelAlias("fast"); !errors.Is(err, ErrModelAliasNotFound) {
+		t.Errorf("DeleteModelAlias() twice error = %v", err)
+	}
+}
+
+func TestTierPriority(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{Admission: config.AdmissionConfig{
+		TierPriorities: map[string]int{"flex": 0, "default": 1, "priority": 2},
+	}}}
+	tests := []struct {
+		tier *string
+		want int
+	}{
+		{nil, 1},
+		{stringPtr("priority"), 2},
+		{stringPtr("flex"), 0},
+		{stringPtr("unknown"), 1},
+	}
+	for _, tt := range tests {
+		if got := e.tierPriority(tt.tier); got != tt.want {
+			t.Errorf("tierPriority(%v) = %d, want %d", tt.tier, got, tt.want)
+		}
+	}
+}
+
+func TestWebSearchOptions(t *testing.T) {
+	var tool schema.ResponsesToolParam
+	if err := json.Unmarshal([]byte(`{"type": "web_search",
+		"user_location": {"type": "approximate", "country": "FR", "city": "Paris"},
+		"filters": {"allowed_domains": ["lemonde.fr", "lefigaro.fr"]}}`), &tool); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	got := webSearchOptions(tool)
+	want := websearch.Options{Country: "FR", Sites: []string{"lemonde.fr", "lefigaro.fr"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("webSearchOptions() = %+v, want %+v", got, want)
+	}
+	if got := webSearchOptions(schema.ResponsesToolParam{Type: "web_search"}); !reflect.DeepEqual(got, websearch.Options{}) {
+		t.Errorf("webSearchOptions() without options = %+v", got)
+	}
+}
+
+type dummyWebSearcher struct{}
+
+func (dummyWebSearcher) Search(_ context.Context, query string, _ int) ([]WebSearchResult, error) {
+	return []WebSearchResult{{Title: query, URL: "https://example.com"}}, nil
+}
+
+type dummyURLFetcher struct{}
+
+func (dummyURLFetcher) Fetch(_ context.Context, url string) (*webfetch.Page, error) {
+	if url == "https://blocked.example.com" {
+		return nil, webfetch.ErrBlocked
+	}
+	return &webfetch.Page{URL: url, Text: "page text", Truncated: true}, nil
+}
+
+func TestFetchURLTool(t *testing.T) {
+	e := &Engine{webSearch: dummyWebSearcher{}}
+	tools := []schema.ResponsesToolParam{{Type: "web_search"}}
+	if expanded, _ := e.expandWebSearchTools(tools); len(expanded) != 1 {
+		t.Fatalf("expanded %d tools without a fetcher, want 1", len(expanded))
+	}
+
+	e.SetURLFetcher(dummyURLFetcher{})
+	expanded, configs := e.expandWebSearchTools(tools)
+	if len(expanded) != 2 || expanded[1].Name != fetchURLToolName {
+		t.Fatalf("expanded = %+v, want web_search and fetch_url", expanded)
+	}
+	cfg, ok := configs[fetchURLToolName]
+	if !ok {
+		t.Fatal("fetch_url is not executed server-side")
+	}
+
+	tests := []struct {
+		name        string
+		arguments   string
+		wantOutput  string
+		wantResults []WebSearchResult
+	}{
+		{
+			name:        "page",
+			arguments:   `{"url": "https://example.com/a"}`,
+			wantOutput:  "[https://example.com/a](https://example.com/a)\npage text\n[Content truncated]",
+			wantResults: []WebSearchResult{{Title: "https://example.com/a", URL: "https://example.com/a"}},
+		},
+		{
+			name:       "error",
+			arguments:  `{"url": "https://blocked.example.com"}`,
+			wantOutput: "Fetch error: destination is not allowed",
+		},
+		{
+			name:       "missing url",
+			arguments:  `{}`,
+			wantOutput: "Fetch error: url is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, results := e.executeWebSearchTool(context.Background(), cfg, fetchURLToolName, tt.arguments)
+			if output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if !reflect.DeepEqual(results, tt.wantResults) {
+				t.Errorf("results = %+v, want %+v", results, tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestModelInstructions(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{Models: map[string]config.ModelConfig{
+		"m": {InstructionsPrefix: "house rules"},
+	}}}
+	base := "be brief"
+	tests := []struct {
+		name  string
+		model string
+		in    *string
+		want  *string
+	}{
+		{"prefixed", "m", &base, stringPtr("house rules\n\nbe brief")},
+		{"no instructions", "m", nil, stringPtr("house rules")},
+		{"other model", "x", &base, &base},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.modelInstructions(&schema.ResponseRequest{Model: &tt.model}, tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("modelInstructions() = %v, want %q", got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestLayerInstructions(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{
+		SystemPrompt:       "global",
+		APIKeyInstructions: map[string]string{"key_0123456789abcdef": "for this key"},
+		Models:             map[string]config.ModelConfig{"m": {InstructionsPrefix: "for this model"}},
+	}}
+	conv := &state.Conversation{Metadata: map[string]string{ConversationInstructionsKey: "for this conversation"}}
+	keyCtx := state.WithAPIKey(context.Background(), "key_0123456789abcdef")
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		model string
+		conv  *state.Conversation
+		in    *string
+		want  string
+	}{
+		{"all layers", keyCtx, "m", conv, stringPtr("be brief"), "global\n\nfor this key\n\nfor this model\n\nfor this conversation\n\nbe brief"},
+		{"no request instructions", keyCtx, "x", conv, nil, "global\n\nfor this key\n\nfor this conversation"},
+		{"other key", state.WithAPIKey(context.Background(), "key_ffffffffffffffff"), "x", nil, stringPtr("be brief"), "global\n\nbe brief"},
+		{"no key", context.Background(), "x", &state.Conversation{}, nil, "global"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			got := e.layerInstructions(tt.ctx, &schema.ResponseRequest{Model: &tt.model}, tt.conv, in)
+			if got == nil || *got != tt.want {
+				t.Errorf("layerInstructions() = %v, want %q", got, tt.want)
+			}
+			if in != nil && *in != "be brief" {
+				t.Errorf("request instructions modified to %q", *in)
+			}
+		})
+	}
+	if got := (&Engine{config: &config.EngineConfig{}}).layerInstructions(context.Background(), &schema.ResponseRequest{}, nil, nil); got != nil {
+		t.Errorf("layerInstructions() without layers = %q, want nil", *got)
+	}
+}
+
+func TestInjectTools(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{
+		Tools: []config.ToolConfig{
+			{Type: "file_search", VectorStoreIDs: []string{"vs_compliance"}},
+			{Type: "mcp", ServerLabel: "audit-log", AllowedTools: []string{"log"}},
+		},
+		APIKeyTools: map[string][]config.ToolConfig{
+			"key_0123456789abcdef": {{Type: "function", Name: "lookup", Description: "Look up an order"}},
+		},
+	}}
+	keyCtx := state.WithAPIKey(context.Background(), "key_0123456789abcdef")
+	clientStores := []string{"vs_docs"}
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		tools []schema.ResponsesToolParam
+		want  []string // type:identity and vector stores
+	}{
+		{"no request tools", context.Background(), nil,
+			[]string{"file_search [vs_compliance]", "mcp:audit-log"}},
+		{"API key tools", keyCtx, []schema.ResponsesToolParam{{Type: "function", Name: "other"}},
+			[]string{"function:other", "file_search [vs_compliance]", "mcp:audit-log", "function:lookup"}},
+		{"file_search stores merged", context.Background(), []schema.ResponsesToolParam{{Type: "file_search", VectorStoreIDs: clientStores}},
+			[]string{"file_search [vs_docs vs_compliance]", "mcp:audit-log"}},
+		{"same identity replaced", keyCtx, []schema.ResponsesToolParam{
+			{Type: "mcp", ServerLabel: "audit-log", AllowedTools: &schema.MCPAllowedTools{ToolNames: []string{}}},
+			{Type: "function", Name: "lookup"},
+		}, []string{"mcp:audit-log", "function:lookup", "file_search [vs_compliance]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &schema.ResponseRequest{Tools: tt.tools}
+			e.injectTools(tt.ctx, req)
+			var got []string
+			for _, tool := range req.Tools {
+				id := toolIdentity(tool)
+				if tool.Type == "file_search" {
+					id = fmt.Sprintf("%s %v", id, tool.VectorStoreIDs)
+				}
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tools = %q, want %q", got, tt.want)
+			}
+			for _, tool := range req.Tools {
+				switch toolIdentity(tool) {
+				case "mcp:audit-log":
+					if tool.AllowedTools == nil || !reflect.DeepEqual(tool.AllowedTools.ToolNames, []string{"log"}) {
+						t.Errorf("mcp allowed_tools = %v, want the configured ones", tool.AllowedTools)
+					}
+				case "function:lookup":
+					if tool.Description == nil {
+						t.Error("function tool was not replaced by the configured one")
+					}
+				}
+			}
+		})
+	}
+	if !reflect.DeepEqual(clientStores, []string{"vs_docs"}) || len(e.config.Tools[0].VectorStoreIDs) != 1 {
+		t.Errorf("vector stores modified: request %v, config %v", clientStores, e.config.Tools[0].VectorStoreIDs)
+	}
+}
+
+func TestMergeInstructions(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   *string
+		req    *string
+		stored string
+		want   *string
+	}{
+		{"default keeps request", nil, stringPtr("be brief"), "you are a pirate", stringPtr("be brief")},
+		{"replace without request", stringPtr("replace"), nil, "you are a pirate", nil},
+		{"prepend", stringPtr("prepend"), stringPtr("be brief"), "you are a pirate", stringPtr("be brief\n\nyou are a pirate")},
+		{"append", stringPtr("append"), stringPtr("be brief"), "you are a pirate", stringPtr("you are a pirate\n\nbe brief")},
+		{"append without request", stringPtr("append"), nil, "you are a pirate", stringPtr("you are a pirate")},
+		{"append same as stored", stringPtr("append"), stringPtr("you are a pirate"), "you are a pirate", stringPtr("you are a pirate")},
+		{"append without stored", stringPtr("append"), stringPtr("be brief"), "", stringPtr("be brief")},
+		{"inherit without request", stringPtr("inherit"), nil, "you are a pirate", stringPtr("you are a pirate")},
+		{"inherit with request", stringPtr("inherit"), stringPtr("be brief"), "you are a pirate", stringPtr("be brief")},
+	}
+	e := &Engine{config: &config.EngineConfig{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &schema.ResponseRequest{Instructions: tt.req, InstructionsMerge: tt.mode}
+			got := e.mergeInstructions(req, tt.stored)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("mergeInstructions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	inherit := &Engine{config: &config.EngineConfig{InstructionsMerge: "inherit"}}
+	if got := inherit.mergeInstructions(&schema.ResponseRequest{}, "you are a pirate"); got == nil || *got != "you are a pirate" {
+		t.Errorf("mergeInstructions() with inherit configured = %v, want the stored prompt", got)
+	}
+	if got := inherit.mergeInstructions(&schema.ResponseRequest{InstructionsMerge: stringPtr("replace")}, "you are a pirate"); got != nil {
+		t.Errorf("mergeInstructions() with replace requested = %q, want nil", *got)
+	}
+
+	messages := []api.Message{{Role: "system", Content: "you are a pirate"}, {Role: "user", Content: "hi"}}
+	if got := storedInstructions(messages); got != "you are a pirate" {
+		t.Errorf("storedInstructions() = %q", got)
+	}
+	if got := storedInstructions(messages[1:]); got != "" {
+		t.Errorf("storedInstructions() without system message = %q", got)
+	}
+	if got := instructionsHash(&schema.ResponseRequest{}, stringPtr("x")); got != nil {
+		t.Errorf("instructionsHash() without merge strategy = %q, want nil", *got)
+	}
+}
+
+// --- prompt resolution tests ---
+
+func TestResolvePromptRef(t *testing.T) {
+	store := memory.NewPromptsStore()
+	if err := store.CreatePrompt(context.Background(), &memory.Prompt{
+		ID:       "prompt_1",
+		Template: "Help {{name}} with {{topic}}. Refer to {{photo}}.",
+	}); err != nil {
+		t.Fatalf("CreatePrompt: %v", err)
+	}
+	e := &Engine{prompts: store}
+
+	req := &schema.ResponseRequest{
+		Input: "hi",
+		Prompt: &schema.PromptReference{
+			ID: "prompt_1",
+			Variables: map[string]schema.PromptVariable{
+				"name":  {Type: "input_text", Text: "Alice"},
+				"topic": {Type: "input_text", Text: "billing"},
+				"photo": {Type: "input_image", ImageURL: "https://example.com/a.png"},
+			},
+		},
+	}
+	if err := e.resolvePromptRef(context.Background(), req); err != nil {
+		t.Fatalf("resolvePromptRef: %v", err)
+	}
+
+	want := "Help Alice with billing. Refer to [attached image: photo]."
+	if req.Instructions == nil || *req.Instructions != want {
+		t.Errorf("Instructions = %v, want %q", req.Instructions, want)
+	}
+
+	msgs := extractInputMessages(req.Input)
+	if len(msgs) != 2 {
+		t.Fatalf("expected attachment message + original input, got %+v", msgs)
+	}
+	if len(msgs[0].ContentParts) != 2 || msgs[0].ContentParts[1].ImageURL == nil ||
+		msgs[0].ContentParts[1].ImageURL.URL != "https://example.com/a.png" {
+		t.Errorf("unexpected attachment message: %+v", msgs[0])
+	}
+	if msgs[1].Role != "user" || msgs[1].Content != "hi" {
+		t.Errorf("original input not preserved: %+v", msgs[1])
+	}
+}
+
+func TestResolvePromptRef_Errors(t *testing.T) {
+	e := &Engine{prompts: memory.NewPromptsStore()}
+
+	tests := []struct {
+		name string
+		req  *schema.ResponseRequest
+	}{
+		{
+			name: "unknown prompt",
+			req:  &schema.ResponseRequest{Prompt: &schema.PromptReference{ID: "prompt_missing"}},
+		},
+		{
+			name: "instructions also set",
+			req: &schema.ResponseRequest{
+				Instructions: stringPtr("be brief"),
+				Prompt:       &schema.PromptReference{ID: "prompt_1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.resolvePromptRef(context.Background(), tt.req)
+			var promptErr *PromptError
+			if !errors.As(err, &promptErr) {
+				t.Errorf("expected PromptError, got %v", err)
+			}
+		})
+	}
+}
+
+// --- content moderation tests ---
+
+// dummyModerator implements Moderator for testing; it flags any text
+// containing one of the configured words.
+type dummyModerator struct {
+	blocked map[string]string // word → category
+	seen    []string
+}
+
+func (d *dummyModerator) Check(_ context.Context, text string) ([]string, error) {
+	d.seen = append(d.seen, text)
+	var out []string
+	for word, category := range d.blocked {
+		if strings.Contains(text, word) {
+			out = append(out, category)
+		}
+	}
+	return out, nil
+}
+
+func TestModerateInputAndOutput(t *testing.T) {
+	mod := &dummyModerator{blocked: map[string]string{"attack": "violence"}}
+	e := &Engine{}
+	e.SetModerator(mod, true, false, "")
+
+	violations, err := e.moderateInput(context.Background(), &schema.ResponseRequest{Input: "plan an attack"})
+	if err != nil {
+		t.Fatalf("moderateInput: %v", err)
+	}
+	if len(violations) != 1 || violations[0] != "violence" {
+		t.Errorf("violations = %v, want [violence]", violations)
+	}
+
+	// Output screening is disabled, so the moderator must not be called.
+	text := "attack"
+	output := []schema.ItemField{{Type: "message", Content: []schema.ContentPart{{Type: "output_text", Text: &text}}}}
+	if violations, _ := e.moderateOutput(context.Background(), output); violations != nil {
+		t.Errorf("expected no output violations, got %v", violations)
+	}
+	if len(mod.seen) != 1 {
+		t.Errorf("moderator called %d times, want 1", len(mod.seen))
+	}
+}
+
+func TestRefuse(t *testing.T) {
+	e := &Engine{}
+	e.SetModerator(&dummyModerator{}, true, true, "")
+
+	resp := schema.NewResponse("resp_1", "m")
+	item := e.refuse(resp, "output_flagged", []string{"hate", "violence"})
+
+	if resp.Status != "failed" || resp.Error == nil || resp.Error.Type != "content_filter" {
+		t.Fatalf("expected failed content_filter response, got status=%q error=%+v", resp.Status, resp.Error)
+	}
+	if resp.Error.Code == nil || *resp.Error.Code != "output_flagged" {
+		t.Errorf("unexpected error code: %v", resp.Error.Code)
+	}
+	if len(resp.Output) != 1 || resp.Output[0].ID != item.ID {
+		t.Fatalf("expected refusal item as sole output, got %+v", resp.Output)
+	}
+	part := item.Content[0]
+	if part.Type != "refusal" || part.Refusal == nil || *part.Refusal != defaultRefusalMessage {
+		t.Errorf("unexpected refusal part: %+v", part)
+	}
+}
+
+type dummyWatermarker struct {
+	err error
+}
+
+func (w *dummyWatermarker) Watermark(_ context.Context, text string) (string, error) {
+	if w.err != nil {
+		return "", w.err
+	}
+	return text + "\u200b", nil
+}
+
+func TestApplyProvenance(t *testing.T) {
+	tests := []struct {
+		name            string
+		watermarker     Watermarker
+		status          string
+		wantStatus      string
+		wantText        string
+		wantProvenance  bool
+		wantWatermarked bool
+	}{
+		{"provenance only", nil, "completed", "completed", "hello", true, false},
+		{"watermarked", &dummyWatermarker{}, "completed", "completed", "hello\u200b", true, true},
+		{"incomplete response", &dummyWatermarker{}, "incomplete", "incomplete", "hello\u200b", true, true},
+		{"failed response untouched", &dummyWatermarker{}, "failed", "failed", "hello", false, false},
+		{"watermark error", &dummyWatermarker{err: errors.New("boom")}, "completed", "failed", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{config: &config.EngineConfig{ModelEndpoint: "http://backend:8000/v1"}}
+			e.SetProvenance("1.2.3")
+			e.SetWatermarker(tt.watermarker)
+
+			resp := schema.NewResponse("resp_1", "m")
+			resp.Status = tt.status
+			text := "hello"
+			resp.Output = []schema.ItemField{{Type: "message", Content: []schema.ContentPart{{Type: "output_text", Text: &text}}}}
+
+			e.applyProvenance(context.Background(), resp)
+
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if got := strings.Join(outputTextParts(resp.Output), "\n"); got != tt.wantText {
+				t.Errorf("output text = %q, want %q", got, tt.wantText)
+			}
+			if (resp.Provenance != nil) != tt.wantProvenance {
+				t.Fatalf("provenance = %+v, want present=%v", resp.Provenance, tt.wantProvenance)
+			}
+			if resp.Provenance == nil {
+				return
+			}
+			p := resp.Provenance
+			if p.GatewayVersion != "1.2.3" || p.Model != "m" || p.Watermarked != tt.wantWatermarked {
+				t.Errorf("unexpected provenance: %+v", p)
+			}
+			if want := sha256Hex("http://backend:8000/v1"); p.BackendEndpointSHA256 != want {
+				t.Errorf("backend_endpoint_sha256 = %s, want %s", p.BackendEndpointSHA256, want)
+			}
+			if want := sha256Hex(tt.wantText); p.ContentSHA256 != want {
+				t.Errorf("content_sha256 = %s, want %s", p.ContentSHA256, want)
+			}
+		})
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestEventStream_Refusal(t *testing.T) {
+	events := make(chan interface{}, 10)
+	stream := newEventStream(events, "resp_1")
+	stream.seq, stream.items = 5, 2
+	stream.refusal(refusalItem("msg_1", "no"))
+	close(events)
+
+	want := []string{
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.refusal.delta",
+		"response.refusal.done",
+		"response.content_part.done",
+		"response.output_item.done",
+	}
+	var got []string
+	for evt := range events {
+		got = append(got, schema.ExtractEventType(evt))
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if stream.seq != 5+len(want) {
+		t.Errorf("next sequence number = %d, want %d", stream.seq, 5+len(want))
+	}
+	if index, ok := stream.indexOf("msg_1"); !ok || index != 2 {
+		t.Errorf("output index = %d, %v, want 2", index, ok)
+	}
+}
+
+func TestEventStream_ReasoningDone(t *testing.T) {
+	tests := []struct {
+		name string
+		item schema.ItemField
+		want []string
+	}{
+		{
+			name: "text and summary",
+			item: streamedReasoningItem("step 1", []string{"plan", "check"}),
+			want: []string{"response.reasoning.done", "response.reasoning_summary.done", "response.output_item.done"},
+		},
+		{
+			name: "encrypted only",
+			item: reasoningItemField(api.OutputItem{Type: "reasoning", ID: "rs-1", EncryptedContent: "opaque"}),
+			want: []string{"response.output_item.done"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan interface{}, 10)
+			stream := newEventStream(events, "resp_1")
+			stream.seq = 3
+			stream.reasoningDone(tt.item, 0)
+			close(events)
+
+			var got []string
+			for evt := range events {
+				got = append(got, schema.ExtractEventType(evt))
+				if e, ok := evt.(*schema.ResponseReasoningSummaryDoneStreamingEvent); ok && e.Summary != "plan\n\ncheck" {
+					t.Errorf("summary = %q", e.Summary)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			if stream.seq != 3+len(tt.want) {
+				t.Errorf("next sequence number = %d, want %d", stream.seq, 3+len(tt.want))
+			}
+		})
+	}
+}
+
+func TestEventStream_ToolCallFailed(t *testing.T) {
+	events := make(chan interface{}, 10)
+	stream := newEventStream(events, "resp_1")
+	stream.items = 1
+	item := failedFileSearchCall("fs_1", toolCallInfo{CallID: "call_1"}, "q", errors.New("index unavailable"))
+	stream.toolCallFailed(item)
+	close(events)
+
+	want := []string{
+		"response.output_item.added",
+		"response.file_search_call.failed",
+		"response.output_item.done",
+	}
+	var got []string
+	for evt := range events {
+		got = append(got, schema.ExtractEventType(evt))
+		if failed, ok := evt.(*schema.ResponseFileSearchCallFailedStreamingEvent); ok && (failed.ItemID != "fs_1" || failed.OutputIndex != 1) {
+			t.Errorf("failed event = %+v", failed)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+// --- NewID tests ---
+
+func TestNewID_Format(t *testing.T) {
+	e := &Engine{}
+	prefixes := []string{"resp_", "conv_", "msg_", "fc_"}
+	for _, prefix := range prefixes {
+		id := e.NewID(prefix)
+		if !strings.HasPrefix(id, prefix) {
+			t.Errorf("expected prefix %q, got %q", prefix, id)
+		}
+		// ULID suffix should be 26 chars
+		suffix := strings.TrimPrefix(id, prefix)
+		if len(suffix) != 26 {
+			t.Errorf("expected 26-char suffix, got %d: %q", len(suffix), suffix)
+		}
+	}
+
+	// Uniqueness and creation order
+	id1 := e.NewID("test_")
+	id2 := e.NewID("test_")
+	if id1 >= id2 {
+		t.Errorf("expected %q to sort before %q", id1, id2)
+	}
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	e := &Engine{}
+	e.SetIDGenerator(ids.NewSequence())
+	if got := []string{e.NewID("resp_"), e.NewID("resp_")}; got[0] != "resp_00000001" || got[1] != "resp_00000002" {
+		t.Errorf("IDs = %v, want resp_00000001, resp_00000002", got)
+	}
+}
+
+func TestResponseIDPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine *Engine
+		want   string
+	}{
+		{"nil config", &Engine{}, "resp_"},
+		{"empty prefix", &Engine{config: &config.EngineConfig{}}, "resp_"},
+		{"custom prefix", &Engine{config: &config.EngineConfig{ResponseIDPrefix: "resp_gw2_"}}, "resp_gw2_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.engine.responseIDPrefix(); got != tt.want {
+				t.Errorf("responseIDPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// --- parseJSONArgs tests ---
+
+func TestParseJSONArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(map[string]any) bool
+	}{
+		{
+			name:  "valid JSON",
+			input: `{"key":"value","num":42}`,
+			check: func(m map[string]any) bool {
+				return m["key"] == "value" && m["num"] == float64(42)
+			},
+		},
+		{
+			name:  "empty string",
+			input: "",
+			check: func(m map[string]any) bool {
+				return len(m) == 0
+			},
+		},
+		{
+			name:  "invalid JSON",
+			input: "not json",
+			check: func(m map[string]any) bool {
+				return len(m) == 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseJSONArgs(tt.input)
+			if !tt.check(result) {
+				t.Errorf("unexpected result: %v", result)
+			}
+		})
+	}
+}
+
+// --- messagesToConversationMessages tests ---
+
+func TestMessagesToConversationMessages(t *testing.T) {
+	messages := []api.Message{
+		{Role: "user", Content: "hello"},
+		{
+			Role: "assistant",
+			ToolCalls: []api.ToolCall{
+				{
+					ID:   "tc-1",
+					Type: "function",
+					Function: api.ToolCallFunction{
+						Name:      "search",
+						Arguments: `{"q":"test"}`,
+					},
+				},
+			},
+		},
+		{Role: "tool", Content: "result", ToolCallID: "tc-1"},
+	}
+
+	result := messagesToConversationMessages(messages)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(result))
+	}
+
+	// Check user message
+	if result[0].Role != "user" || result[0].Content != "hello" {
+		t.Errorf("unexpected user message: %+v", result[0])
 	}
 
 	// Check assistant with tool calls
@@ -857,6 +2009,7 @@ func TestEchoRequestParams_InferenceAndStoreFields(t *testing.T) {
 		Text:              &schema.TextField{Format: schema.TextFormat{Type: "json_object"}},
 		TopLogprobs:       intPtr(5),
 		Store:             boolPtr(false),
+		ExternalID:        stringPtr("order-42"),
 	}
 
 	resp := schema.NewResponse("resp-test", "test-model")
@@ -877,6 +2030,9 @@ func TestEchoRequestParams_InferenceAndStoreFields(t *testing.T) {
 	if resp.Store != false {
 		t.Errorf("Store: expected false, got %v", resp.Store)
 	}
+	if resp.ExternalID == nil || *resp.ExternalID != "order-42" {
+		t.Errorf("ExternalID: expected %q, got %v", "order-42", resp.ExternalID)
+	}
 }
 
 func TestEchoRequestParams_InferenceAndStoreDefaults(t *testing.T) {
@@ -1028,3 +2184,642 @@ func TestEchoRequestParams_NilOptionals(t *testing.T) {
 		t.Errorf("expected TopP=0, got %v", resp.TopP)
 	}
 }
+
+// --- mcpToolAllowed tests ---
+
+func TestMCPToolAllowed(t *testing.T) {
+	search := mcp.ToolInfo{Name: "search", Annotations: &mcp.ToolAnnotations{ReadOnlyHint: true}}
+	deleteRepo := mcp.ToolInfo{Name: "delete_repo"}
+
+	tests := []struct {
+		name    string
+		tool    mcp.ToolInfo
+		allowed *schema.MCPAllowedTools
+		denied  []string
+		want    bool
+	}{
+		{"no filter", deleteRepo, nil, nil, true},
+		{"denied", deleteRepo, nil, []string{"delete_repo"}, false},
+		{"in allowed list", search, &schema.MCPAllowedTools{ToolNames: []string{"search"}}, nil, true},
+		{"not in allowed list", deleteRepo, &schema.MCPAllowedTools{ToolNames: []string{"search"}}, nil, false},
+		{"empty allowed list", search, &schema.MCPAllowedTools{ToolNames: []string{}}, nil, false},
+		{"denylist wins over allowed list", deleteRepo, &schema.MCPAllowedTools{ToolNames: []string{"delete_repo"}}, []string{"delete_repo"}, false},
+		{"read only tool", search, &schema.MCPAllowedTools{ReadOnly: true}, nil, true},
+		{"read only excludes unannotated", deleteRepo, &schema.MCPAllowedTools{ReadOnly: true}, nil, false},
+		{"read only and names", search, &schema.MCPAllowedTools{ToolNames: []string{"other"}, ReadOnly: true}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mcpToolAllowed(tt.tool, tt.allowed, tt.denied); got != tt.want {
+				t.Errorf("mcpToolAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLoopGuard(t *testing.T) {
+	start := time.Unix(1000, 0)
+	cfg := config.LoopConfig{MaxDuration: time.Minute, MaxBackendCalls: 10}
+
+	tests := []struct {
+		name         string
+		req          schema.ResponseRequest
+		wantDeadline time.Time
+		wantCalls    int
+		wantTokens   int
+	}{
+		{"configured limits", schema.ResponseRequest{}, start.Add(time.Minute), 10, 0},
+		{"request tightens", schema.ResponseRequest{MaxDurationSeconds: intPtr(5), MaxBackendCalls: intPtr(3)}, start.Add(5 * time.Second), 3, 0},
+		{"request cannot loosen", schema.ResponseRequest{MaxDurationSeconds: intPtr(600), MaxBackendCalls: intPtr(50)}, start.Add(time.Minute), 10, 0},
+		{"request sets unlimited limit", schema.ResponseRequest{MaxTotalTokens: intPtr(1000)}, start.Add(time.Minute), 10, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newLoopGuard(cfg, &tt.req, start)
+			if !g.deadline.Equal(tt.wantDeadline) {
+				t.Errorf("deadline = %v, want %v", g.deadline, tt.wantDeadline)
+			}
+			if g.maxBackendCalls != tt.wantCalls {
+				t.Errorf("maxBackendCalls = %d, want %d", g.maxBackendCalls, tt.wantCalls)
+			}
+			if g.maxTotalTokens != tt.wantTokens {
+				t.Errorf("maxTotalTokens = %d, want %d", g.maxTotalTokens, tt.wantTokens)
+			}
+		})
+	}
+
+	if g := newLoopGuard(config.LoopConfig{}, &schema.ResponseRequest{}, start); !g.deadline.IsZero() {
+		t.Errorf("unlimited guard has deadline %v", g.deadline)
+	}
+}
+
+func TestLoopGuardCheck(t *testing.T) {
+	start := time.Unix(1000, 0)
+	g := newLoopGuard(config.LoopConfig{MaxDuration: time.Minute, MaxBackendCalls: 2, MaxTotalTokens: 100}, &schema.ResponseRequest{}, start)
+
+	if reason := g.check(start); reason != "" {
+		t.Fatalf("fresh guard check = %q, want \"\"", reason)
+	}
+
+	g.record(&api.UsageInfo{InputTokens: 40, OutputTokens: 20})
+	if reason := g.check(start); reason != "" {
+		t.Fatalf("check after 60 tokens = %q, want \"\"", reason)
+	}
+
+	g.record(&api.UsageInfo{TotalTokens: 50})
+	if reason := g.check(start); reason != incompleteMaxBackendCalls {
+		t.Errorf("check after 2 calls = %q, want %q", reason, incompleteMaxBackendCalls)
+	}
+	if g.totalTokens != 110 {
+		t.Errorf("totalTokens = %d, want 110", g.totalTokens)
+	}
+
+	g.maxBackendCalls = 0
+	if reason := g.check(start); reason != incompleteMaxTotalTokens {
+		t.Errorf("check over token ceiling = %q, want %q", reason, incompleteMaxTotalTokens)
+	}
+	if reason := g.check(start.Add(time.Minute)); reason != incompleteMaxDuration {
+		t.Errorf("check at deadline = %q, want %q", reason, incompleteMaxDuration)
+	}
+}
+
+func TestLoopGuardStopped(t *testing.T) {
+	ctx := context.Background()
+
+	interrupt := make(chan struct{})
+	g := newLoopGuard(config.LoopConfig{}, &schema.ResponseRequest{}, time.Now())
+	g.interrupt = interrupt
+	loopCtx, cancel := g.context(ctx)
+	defer cancel()
+
+	if reason := g.stopped(ctx, loopCtx); reason != "" {
+		t.Fatalf("stopped before interruption = %q, want \"\"", reason)
+	}
+	close(interrupt)
+	select {
+	case <-loopCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("loop context not canceled on interruption")
+	}
+	if reason := g.stopped(ctx, loopCtx); reason != incompleteInterrupted {
+		t.Errorf("stopped after interruption = %q, want %q", reason, incompleteInterrupted)
+	}
+	if reason := g.check(time.Now()); reason != incompleteInterrupted {
+		t.Errorf("check after interruption = %q, want %q", reason, incompleteInterrupted)
+	}
+
+	g = newLoopGuard(config.LoopConfig{MaxDuration: time.Millisecond}, &schema.ResponseRequest{}, time.Now())
+	loopCtx, cancel = g.context(ctx)
+	defer cancel()
+	<-loopCtx.Done()
+	if reason := g.stopped(ctx, loopCtx); reason != incompleteMaxDuration {
+		t.Errorf("stopped after deadline = %q, want %q", reason, incompleteMaxDuration)
+	}
+
+	reqCtx, cancelResponse := WithCancel(ctx)
+	g = newLoopGuard(config.LoopConfig{}, &schema.ResponseRequest{}, time.Now())
+	g.cancel = Cancelled(reqCtx)
+	loopCtx, cancel = g.context(reqCtx)
+	defer cancel()
+	cancelResponse()
+	<-loopCtx.Done()
+	if reason := g.stopped(reqCtx, loopCtx); reason != incompleteCancelled {
+		t.Errorf("stopped after cancel = %q, want %q", reason, incompleteCancelled)
+	}
+	if Cancelled(ctx) != nil {
+		t.Error("Cancelled of a plain context is not nil")
+	}
+
+	canceled, cancelCaller := context.WithCancel(ctx)
+	g = newLoopGuard(config.LoopConfig{}, &schema.ResponseRequest{}, time.Now())
+	loopCtx, cancel = g.context(canceled)
+	defer cancel()
+	cancelCaller()
+	if reason := g.stopped(canceled, loopCtx); reason != "" {
+		t.Errorf("stopped after caller cancellation = %q, want \"\"", reason)
+	}
+}
+
+func TestPrepareBackendRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		backendAPI string
+		apiKey     string
+		stream     bool
+		wantPath   string
+		wantKeys   []string
+	}{
+		{"responses", "responses", "sk-test", false, "/v1/responses", []string{"model", "input", "instructions"}},
+		{"chat completions", "chat_completions", "", true, "/v1/chat/completions", []string{"model", "messages", "stream_options"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{config: &config.EngineConfig{
+				ModelEndpoint: "http://backend:8000/v1/",
+				APIKey:        tt.apiKey,
+				BackendAPI:    tt.backendAPI,
+			}}
+			req := &schema.ResponseRequest{
+				Model:        stringPtr("m"),
+				Input:        "hi",
+				Instructions: stringPtr("be brief"),
+				Stream:       tt.stream,
+			}
+
+			out, err := e.PrepareBackendRequest(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PrepareBackendRequest: %v", err)
+			}
+			if out.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", out.Path, tt.wantPath)
+			}
+			wantAuth := ""
+			if tt.apiKey != "" {
+				wantAuth = "Bearer " + tt.apiKey
+			}
+			if got := out.Header.Get("Authorization"); got != wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, wantAuth)
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(out.Body, &body); err != nil {
+				t.Fatalf("body: %v", err)
+			}
+			for _, k := range tt.wantKeys {
+				if _, ok := body[k]; !ok {
+					t.Errorf("body has no %q: %s", k, out.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareBackendRequest_InputFlagged(t *testing.T) {
+	e := &Engine{config: &config.EngineConfig{ModelEndpoint: "http://backend:8000/v1"}}
+	e.SetModerator(&dummyModerator{blocked: map[string]string{"attack": "violence"}}, true, false, "")
+
+	_, err := e.PrepareBackendRequest(context.Background(), &schema.ResponseRequest{Model: stringPtr("m"), Input: "plan an attack"})
+	if !errors.Is(err, ErrInputFlagged) {
+		t.Fatalf("err = %v, want ErrInputFlagged", err)
+	}
+}
+
+// wordCounter counts one token per word.
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestLoopGuardCheckInput(t *testing.T) {
+	g := newLoopGuard(config.LoopConfig{MaxTotalTokens: 100}, &schema.ResponseRequest{}, time.Now())
+	g.record(&api.UsageInfo{TotalTokens: 60})
+
+	if reason := g.checkInput(40); reason != "" {
+		t.Errorf("checkInput(40) = %q, want \"\"", reason)
+	}
+	if reason := g.checkInput(41); reason != incompleteMaxTotalTokens {
+		t.Errorf("checkInput(41) = %q, want %q", reason, incompleteMaxTotalTokens)
+	}
+	g.maxTotalTokens = 0
+	if reason := g.checkInput(1000); reason != "" {
+		t.Errorf("checkInput without limit = %q, want \"\"", reason)
+	}
+}
+
+func TestRequestDeadline(t *testing.T) {
+	start := time.Now()
+	e := &Engine{config: &config.EngineConfig{Loop: config.LoopConfig{MaxDuration: time.Hour, RequestTimeout: time.Minute}}}
+
+	ctx := e.withRequestDeadline(context.Background(), start)
+	if got := requestDeadline(ctx); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("deadline = %v, want the configured timeout", got.Sub(start))
+	}
+	// The request deadline tightens the loop's
+	if g := e.loopGuard(ctx, &schema.ResponseRequest{}); !g.deadline.Equal(start.Add(time.Minute)) {
+		t.Errorf("loop deadline = %v, want the request deadline", g.deadline.Sub(start))
+	}
+
+	// A request can lower the timeout, not raise it
+	ctx = e.withRequestDeadline(WithRequestTimeout(context.Background(), time.Second), start)
+	if got := requestDeadline(ctx); got.After(start.Add(2 * time.Second)) {
+		t.Errorf("deadline = %v, want the requested timeout", got.Sub(start))
+	}
+	ctx = e.withRequestDeadline(WithRequestTimeout(context.Background(), time.Hour), start)
+	if got := requestDeadline(ctx); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("deadline = %v, want the configured timeout", got.Sub(start))
+	}
+
+	// Calls before the loop are bounded by the request deadline
+	bounded, cancel := withinRequest(ctx)
+	defer cancel()
+	if got, ok := bounded.Deadline(); !ok || !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("withinRequest deadline = %v, %v", got.Sub(start), ok)
+	}
+
+	// Without a timeout there is no deadline
+	e.config.Loop.RequestTimeout = 0
+	ctx = e.withRequestDeadline(context.Background(), start)
+	if got := requestDeadline(ctx); !got.IsZero() {
+		t.Errorf("deadline = %v, want none", got)
+	}
+	bounded, cancel = withinRequest(ctx)
+	defer cancel()
+	if _, ok := bounded.Deadline(); ok {
+		t.Error("withinRequest set a deadline without a request timeout")
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	e := &Engine{tokens: wordCounter{}}
+
+	msg := api.Message{
+		Role: "user",
+		ContentParts: []api.MessageContentPart{
+			{Type: "text", Text: "what is this"},
+			{Type: "image_url", ImageURL: &api.MessageImageURL{URL: "https://example.com/a.png"}},
+		},
+	}
+	if got, want := e.countMessage(msg), messageOverhead+3+imageTokens; got != want {
+		t.Errorf("countMessage(multimodal) = %d, want %d", got, want)
+	}
+	// Five seconds of 16-bit 24kHz wav, 240000 bytes once decoded
+	audio := api.Message{Role: "user", ContentParts: []api.MessageContentPart{
+		{Type: "input_audio", InputAudio: &api.MessageAudio{Data: strings.Repeat("A", 320000), Format: "wav"}},
+	}}
+	if got, want := e.countMessage(audio), messageOverhead+5*audioTokensPerSecond; got != want {
+		t.Errorf("countMessage(audio) = %d, want %d", got, want)
+	}
+	call := api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "lookup", Arguments: `{"q": "x"}`}}}}
+	if got, want := e.countMessage(call), messageOverhead+3; got != want {
+		t.Errorf("countMessage(tool call) = %d, want %d", got, want)
+	}
+
+	apiReq := &api.ResponsesAPIRequest{Instructions: stringPtr("be brief")}
+	messages := []api.Message{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hello there"}}
+	if got, want := e.countInput(apiReq, messages), messageOverhead+2+messageOverhead+2; got != want {
+		t.Errorf("countInput = %d, want %d", got, want)
+	}
+
+	output := []api.OutputItem{
+		{Type: "message", Content: []api.ContentItem{{Type: "output_text", Text: "hi there friend"}}},
+		{Type: "function_call", Name: "lookup", Arguments: "{}"},
+	}
+	if got := e.countOutput(output); got != 5 {
+		t.Errorf("countOutput = %d, want 5", got)
+	}
+
+	if _, ok := (&Engine{}).tokenCounter().(tokenizer.Heuristic); !ok {
+		t.Error("engine without counter should use the heuristic")
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	// Each message below is messageOverhead+2 = 6 tokens
+	messages := []api.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "first question"},
+		{Role: "assistant", Content: "first answer"},
+		{Role: "user", Content: "second question"},
+		{Role: "assistant", Content: "second answer"},
+		{Role: "user", Content: "third question"},
+	}
+
+	tests := []struct {
+		name       string
+		window     int
+		truncation *string
+		maxOutput  *int
+		wantTokens int
+		wantInput  int // messages sent, -1 when the input is unchanged
+	}{
+		{"no window", 0, stringPtr("auto"), nil, 30, -1},
+		{"truncation disabled", 10, stringPtr("disabled"), nil, 30, -1},
+		{"fits", 30, stringPtr("auto"), nil, 30, -1},
+		{"drops first turn", 29, stringPtr("auto"), nil, 18, 3},
+		{"reserves output", 30, stringPtr("auto"), intPtr(10), 18, 3},
+		{"keeps last turn", 5, stringPtr("auto"), nil, 6, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{config: &config.EngineConfig{ContextWindow: tt.window}, tokens: wordCounter{}}
+			req := &schema.ResponseRequest{Truncation: tt.truncation, MaxOutputTokens: tt.maxOutput}
+			apiReq := buildResponsesAPIRequest("m", messages, req, nil, false)
+			before := len(apiReq.Input.([]interface{}))
+
+			if got := e.fitContext(apiReq, messages); got != tt.wantTokens {
+				t.Errorf("fitContext = %d, want %d", got, tt.wantTokens)
+			}
+			want := tt.wantInput
+			if want < 0 {
+				want = before
+			}
+			if got := len(apiReq.Input.([]interface{})); got != want {
+				t.Errorf("sent %d messages, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPromptCacheKey(t *testing.T) {
+	firstTurn := []api.Message{{Role: "user", Content: "summarize this document"}}
+	laterTurn := append(firstTurn,
+		api.Message{Role: "assistant", Content: "it is about caching"},
+		api.Message{Role: "user", Content: "shorter please"},
+	)
+	keyFor := func(strategy string, req *schema.ResponseRequest, instructions string, messages []api.Message) *string {
+		e := &Engine{config: &config.EngineConfig{PromptCacheKey: strategy}}
+		apiReq := &api.ResponsesAPIRequest{Model: "m", Instructions: stringPtr(instructions)}
+		return e.promptCacheKey(req, apiReq, messages)
+	}
+
+	if key := keyFor("none", &schema.ResponseRequest{}, "be brief", firstTurn); key != nil {
+		t.Errorf("key with strategy none = %q, want nil", *key)
+	}
+	if key := keyFor("none", &schema.ResponseRequest{PromptCacheKey: stringPtr("mine")}, "be brief", firstTurn); key == nil || *key != "mine" {
+		t.Errorf("request key not used: %v", key)
+	}
+
+	first := keyFor("prefix", &schema.ResponseRequest{}, "be brief", firstTurn)
+	if first == nil || !strings.HasPrefix(*first, "pc_") || len(*first) != 35 {
+		t.Fatalf("derived key = %v, want pc_ and 32 hex digits", first)
+	}
+	if later := keyFor("prefix", &schema.ResponseRequest{}, "be brief", laterTurn); *later != *first {
+		t.Errorf("key changed across turns: %q then %q", *first, *later)
+	}
+	if other := keyFor("prefix", &schema.ResponseRequest{}, "be verbose", firstTurn); *other == *first {
+		t.Error("key did not change with the instructions")
+	}
+	if other := keyFor("prefix", &schema.ResponseRequest{}, "be brief", []api.Message{{Role: "user", Content: "hello"}}); *other == *first {
+		t.Error("key did not change with the first turn")
+	}
+}
+
+// mapResponseCache implements state.ResponseCache in memory.
+type mapResponseCache map[string][]byte
+
+func (c mapResponseCache) GetCachedResponse(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := c[key]
+	return value, ok, nil
+}
+
+func (c mapResponseCache) PutCachedResponse(_ context.Context, key string, value []byte, _ time.Time) error {
+	c[key] = value
+	return nil
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	messages := []api.Message{{Role: "user", Content: "2+2?"}}
+	zero := float64Ptr(0)
+	tests := []struct {
+		name    string
+		models  map[string]time.Duration
+		req     *schema.ResponseRequest
+		wantTTL time.Duration // 0 when not cacheable
+	}{
+		{"temperature 0", nil, &schema.ResponseRequest{Temperature: zero}, 10 * time.Minute},
+		{"seed", nil, &schema.ResponseRequest{Seed: intPtr(7)}, 10 * time.Minute},
+		{"sampled", nil, &schema.ResponseRequest{Temperature: float64Ptr(0.7)}, 0},
+		{"no temperature", nil, &schema.ResponseRequest{}, 0},
+		{"function tools", nil, &schema.ResponseRequest{Temperature: zero, Tools: []schema.ResponsesToolParam{{Type: "function", Name: "f"}}}, 10 * time.Minute},
+		{"server-side tools", nil, &schema.ResponseRequest{Temperature: zero, Tools: []schema.ResponsesToolParam{{Type: "web_search"}}}, 0},
+		{"model default ttl", map[string]time.Duration{"m": 0}, &schema.ResponseRequest{Temperature: zero}, 10 * time.Minute},
+		{"model ttl", map[string]time.Duration{"m": time.Hour}, &schema.ResponseRequest{Temperature: zero}, time.Hour},
+		{"model not listed", map[string]time.Duration{"other": 0}, &schema.ResponseRequest{Temperature: zero}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{
+				config:        &config.EngineConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true, TTL: 10 * time.Minute, Models: tt.models}},
+				responseCache: mapResponseCache{},
+			}
+			key, ttl := e.responseCacheKey(context.Background(), tt.req, "m", messages, nil)
+			if ttl != tt.wantTTL {
+				t.Errorf("ttl = %v, want %v", ttl, tt.wantTTL)
+			}
+			if (key != "") != (tt.wantTTL > 0) {
+				t.Errorf("key = %q, want cacheable %v", key, tt.wantTTL > 0)
+			}
+		})
+	}
+
+	// The key covers the input and the tenant
+	e := &Engine{config: &config.EngineConfig{ResponseCache: config.ResponseCacheConfig{Enabled: true, TTL: time.Minute}}, responseCache: mapResponseCache{}}
+	req := &schema.ResponseRequest{Temperature: zero}
+	key, _ := e.responseCacheKey(context.Background(), req, "m", messages, nil)
+	if other, _ := e.responseCacheKey(context.Background(), req, "m", []api.Message{{Role: "user", Content: "3+3?"}}, nil); other == key {
+		t.Error("key did not change with the input")
+	}
+	if other, _ := e.responseCacheKey(featureflags.WithTenant(context.Background(), "acme"), req, "m", messages, nil); other == key {
+		t.Error("key did not change with the tenant")
+	}
+	if other, _ := e.responseCacheKey(context.Background(), req, "m", messages, stringPtr("be brief")); other == key {
+		t.Error("key did not change with the instructions")
+	}
+}
+
+func TestResponseCacheRoundTrip(t *testing.T) {
+	e := &Engine{responseCache: mapResponseCache{}}
+	ctx := context.Background()
+
+	if cached := e.lookupResponseCache(ctx, "k"); cached != nil {
+		t.Fatalf("lookup before store = %+v, want nil", cached)
+	}
+
+	text := "4"
+	e.storeResponseCache(ctx, "k", time.Minute, &cachedResponse{
+		Output:   []schema.ItemField{{Type: "message", ID: "msg_original", Content: []schema.ContentPart{{Type: "output_text", Text: &text}}}},
+		Messages: []api.Message{{Role: "assistant", Content: "4"}},
+	})
+	cached := e.lookupResponseCache(ctx, "k")
+	if cached == nil {
+		t.Fatal("lookup after store = nil")
+	}
+	if len(cached.Output) != 1 || *cached.Output[0].Content[0].Text != "4" {
+		t.Errorf("output = %+v", cached.Output)
+	}
+	if id := cached.Output[0].ID; id == "msg_original" || !strings.HasPrefix(id, "msg_") {
+		t.Errorf("item ID = %q, want a fresh msg_ ID", id)
+	}
+	if len(cached.Messages) != 1 || cached.Messages[0].Content != "4" {
+		t.Errorf("messages = %+v", cached.Messages)
+	}
+	if cached := e.lookupResponseCache(ctx, ""); cached != nil {
+		t.Error("lookup of an uncacheable request should miss")
+	}
+}
+
+func TestMergeCandidates(t *testing.T) {
+	message := func(id, text string) api.OutputItem {
+		return api.OutputItem{Type: "message", ID: id, Role: "assistant", Content: []api.ContentItem{{Type: "output_text", Text: text}}}
+	}
+	responses := []*api.ResponsesAPIResponse{
+		{ID: "a", Status: "completed", Output: []api.OutputItem{message("msg_a", "one")}, Usage: &api.UsageInfo{InputTokens: 3, OutputTokens: 1, TotalTokens: 4}},
+		{ID: "b", Status: "incomplete", Output: []api.OutputItem{message("msg_b", "two")}, Usage: &api.UsageInfo{InputTokens: 3, OutputTokens: 2, TotalTokens: 5}},
+	}
+
+	tests := []struct {
+		name      string
+		positions map[[2]int]int
+		wantIDs   []string
+	}{
+		{"candidate order", nil, []string{"msg_a", "msg_b"}},
+		{"positions", map[[2]int]int{{0, 0}: 1, {1, 0}: 0}, []string{"msg_b", "msg_a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeCandidates(responses, tt.positions)
+			if merged.Status != "incomplete" {
+				t.Errorf("status = %q, want incomplete", merged.Status)
+			}
+			if merged.Usage == nil || merged.Usage.InputTokens != 6 || merged.Usage.OutputTokens != 3 || merged.Usage.TotalTokens != 9 {
+				t.Errorf("usage = %+v", merged.Usage)
+			}
+			if len(merged.Output) != len(tt.wantIDs) {
+				t.Fatalf("got %d items, want %d", len(merged.Output), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				item := merged.Output[i]
+				wantCandidate := map[string]int{"msg_a": 0, "msg_b": 1}[id]
+				if item.ID != id || item.CandidateIndex == nil || *item.CandidateIndex != wantCandidate {
+					t.Errorf("output[%d] = %s candidate %v, want %s candidate %d", i, item.ID, item.CandidateIndex, id, wantCandidate)
+				}
+			}
+		})
+	}
+
+	if first := firstCandidate(mergeCandidates(responses, nil).Output); len(first) != 1 || first[0].ID != "msg_a" {
+		t.Errorf("firstCandidate = %+v", first)
+	}
+	if responses[0].Output[0].CandidateIndex != nil {
+		t.Error("mergeCandidates modified its input")
+	}
+}
+
+func TestMergeCandidateStreams(t *testing.T) {
+	candidate := func(id, text string) <-chan api.ResponsesStreamEvent {
+		ch := make(chan api.ResponsesStreamEvent, 2)
+		delta, _ := json.Marshal(map[string]interface{}{"type": "response.output_text.delta", "output_index": 0, "item_id": id, "delta": text})
+		completed, _ := json.Marshal(map[string]interface{}{
+			"type": "response.completed",
+			"response": api.ResponsesAPIResponse{ID: id, Status: "completed", Output: []api.OutputItem{{
+				Type: "message", ID: id, Role: "assistant", Content: []api.ContentItem{{Type: "output_text", Text: text}},
+			}}},
+		})
+		ch <- api.ResponsesStreamEvent{Type: "response.output_text.delta", Data: delta}
+		ch <- api.ResponsesStreamEvent{Type: "response.completed", Data: completed}
+		close(ch)
+		return ch
+	}
+
+	out := make(chan api.ResponsesStreamEvent, 10)
+	mergeCandidateStreams(context.Background(), []<-chan api.ResponsesStreamEvent{candidate("msg_a", "one"), candidate("msg_b", "two")}, out)
+
+	indexOf := make(map[string]int)
+	var completed *api.ResponsesAPIResponse
+	for evt := range out {
+		switch evt.Type {
+		case "response.output_text.delta":
+			var fields struct {
+				OutputIndex int    `json:"output_index"`
+				ItemID      string `json:"item_id"`
+			}
+			if err := json.Unmarshal(evt.Data, &fields); err != nil {
+				t.Fatalf("unmarshal delta: %v", err)
+			}
+			indexOf[fields.ItemID] = fields.OutputIndex
+		case "response.completed":
+			if completed != nil {
+				t.Fatal("more than one response.completed")
+			}
+			var wrapper struct {
+				Response api.ResponsesAPIResponse `json:"response"`
+			}
+			if err := json.Unmarshal(evt.Data, &wrapper); err != nil {
+				t.Fatalf("unmarshal completed: %v", err)
+			}
+			completed = &wrapper.Response
+		}
+	}
+
+	if len(indexOf) != 2 || indexOf["msg_a"] == indexOf["msg_b"] {
+		t.Fatalf("output indexes not distinct: %v", indexOf)
+	}
+	if completed == nil || len(completed.Output) != 2 {
+		t.Fatalf("completed = %+v", completed)
+	}
+	for i, item := range completed.Output {
+		if indexOf[item.ID] != i {
+			t.Errorf("output[%d] = %s, streamed at index %d", i, item.ID, indexOf[item.ID])
+		}
+	}
+}
+
+func TestUsageTally(t *testing.T) {
+	var tally usageTally
+	if tally.responseUsage() != nil {
+		t.Fatal("expected no usage before any call")
+	}
+
+	tally.add(&api.UsageInfo{InputTokens: 10, OutputTokens: 4, TotalTokens: 14, OutputTokensDetails: &api.OutputTokensDetails{ReasoningTokens: 2}})
+	tally.add(nil)
+	tally.add(&api.UsageInfo{
+		InputTokens: 20, OutputTokens: 5,
+		InputTokensDetails:  &api.InputTokensDetails{CachedTokens: 8, AudioTokens: 6},
+		OutputTokensDetails: &api.OutputTokensDetails{AudioTokens: 3},
+	})
+
+	got := tally.responseUsage()
+	want := schema.UsageField{
+		InputTokens:         20,
+		OutputTokens:        9,
+		TotalTokens:         29,
+		InputTokensDetails:  schema.InputTokensDetails{CachedTokens: 8, AudioTokens: 6},
+		OutputTokensDetails: schema.OutputTokensDetails{ReasoningTokens: 2, AudioTokens: 3},
+	}
+	if *got != want {
+		t.Errorf("responseUsage = %+v, want %+v", *got, want)
+	}
+
+	total := usageField(&tally.total)
+	if total.InputTokens != 30 || total.OutputTokens != 9 || total.TotalTokens != 39 || total.InputTokensDetails.CachedTokens != 8 {
+		t.Errorf("total = %+v", total)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/webfetch"
+)
+
+// fetchURLToolName is the function tool offered next to web_search to read
+// the pages it finds.
+const fetchURLToolName = "fetch_url"
+
+// URLFetcher reads web pages for the fetch_url tool.
+// Implemented by webfetch.Fetcher.
+type URLFetcher interface {
+	Fetch(ctx context.Context, url string) (*webfetch.Page, error)
+}
+
+// SetURLFetcher enables the fetch_url tool. Requests with a web_search tool
+// are then also given a fetch_url function, so the model can read the
+// pages it found without the client's involvement.
+func (e *Engine) SetURLFetcher(f URLFetcher) {
+	e.urlFetch = f
+}
+
+// fetchURLTool returns the synthetic function tool reading a web page.
+func fetchURLTool() schema.ResponsesToolParam {
+	desc := "Fetch a web page, such as a web search result, and return its text content."
+	return schema.ResponsesToolParam{
+		Type:        "function",
+		Name:        fetchURLToolName,
+		Description: &desc,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "The http or https URL of the page to read.",
+				},
+			},
+			"required":             []string{"url"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// executeFetchURL fetches a page and formats it for the LLM. The page is
+// returned as a result so that it can be cited.
+func (e *Engine) executeFetchURL(ctx context.Context, url string) (string, []WebSearchResult) {
+	if url == "" {
+		return "Fetch error: url is required", nil
+	}
+	page, err := e.urlFetch.Fetch(ctx, url)
+	if err != nil {
+		return fmt.Sprintf("Fetch error: %v", err), nil
+	}
+
+	title := page.Title
+	if title == "" {
+		title = page.URL
+	}
+	out := fmt.Sprintf("[%s](%s)\n%s", title, page.URL, page.Text)
+	if page.Truncated {
+		out += "\n[Content truncated]"
+	}
+	return out, []WebSearchResult{{Title: title, URL: page.URL}}
+}
@@ -0,0 +1,169 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+)
+
+// SetFileStore lets input content parts reference uploaded files by ID.
+// Image, file and video parts with a file_id are resolved against the
+// store before the backend call.
+func (e *Engine) SetFileStore(fs filestore.FileStore) {
+	e.files = fs
+}
+
+// resolveFileInputs replaces the file IDs of the content parts of messages
+// with content the backend can read: images and files are inlined, videos
+// are given a signed URL when the store can presign one. Without a file
+// store, the parts are forwarded with their file ID. With an image fetcher,
+// remote images are inlined too.
+func (e *Engine) resolveFileInputs(ctx context.Context, messages []api.Message) error {
+	ctx, cancel := withinRequest(ctx)
+	defer cancel()
+	for i := range messages {
+		for j := range messages[i].ContentParts {
+			part := &messages[i].ContentParts[j]
+			switch {
+			case part.ImageURL != nil && isRemoteURL(part.ImageURL.URL):
+				if e.imageFetch == nil {
+					continue
+				}
+				url, err := e.imageFetch.DataURL(ctx, part.ImageURL.URL)
+				if err != nil {
+					return fmt.Errorf("input image: %w", err)
+				}
+				part.ImageURL.URL = url
+			case e.files == nil:
+				continue
+			case part.ImageURL != nil && part.ImageURL.URL == "" && part.ImageURL.FileID != "":
+				file, err := e.inputFile(ctx, part.ImageURL.FileID)
+				if err != nil {
+					return err
+				}
+				if part.ImageURL.URL, err = e.inlineFile(ctx, file, "image/"); err != nil {
+					return err
+				}
+			case part.File != nil && part.File.FileData == "" && part.File.FileID != "":
+				file, err := e.inputFile(ctx, part.File.FileID)
+				if err != nil {
+					return err
+				}
+				if part.File.FileData, err = e.inlineFile(ctx, file, ""); err != nil {
+					return err
+				}
+				if part.File.Filename == "" {
+					part.File.Filename = file.Filename
+				}
+			case part.VideoURL != nil && part.VideoURL.URL == "" && part.VideoURL.FileID != "":
+				file, err := e.inputFile(ctx, part.VideoURL.FileID)
+				if err != nil {
+					return err
+				}
+				if part.VideoURL.URL, err = e.videoURL(ctx, file); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inputFile returns an uploaded file referenced by an input part. Files of
+// another tenant are reported as not found.
+func (e *Engine) inputFile(ctx context.Context, fileID string) (*filestore.File, error) {
+	file, err := e.files.GetFile(ctx, fileID)
+	if err == nil && file.Tenant != "" && file.Tenant != featureflags.TenantFromContext(ctx) {
+		err = filestore.ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("input file %s: %w", fileID, err)
+	}
+	return file, nil
+}
+
+// videoURL returns a URL to the content of a video: a signed URL when the
+// store can presign one, else a base64 data URL.
+func (e *Engine) videoURL(ctx context.Context, file *filestore.File) (string, error) {
+	if signer, ok := e.files.(filestore.URLSigner); ok && !e.inlineFileInputs() {
+		if err := checkMIMEType(file, fileMIMEType(file, nil), "video/"); err != nil {
+			return "", err
+		}
+		url, _, err := signer.PresignContentURL(ctx, file, 0)
+		if err != nil {
+			return "", fmt.Errorf("input file %s: %w", file.ID, err)
+		}
+		return url, nil
+	}
+	return e.inlineFile(ctx, file, "video/")
+}
+
+// inlineFile returns the content of an uploaded file as a base64 data URL.
+// The file must fit in the configured size limit and, when mimePrefix is
+// set, have a MIME type starting with it.
+func (e *Engine) inlineFile(ctx context.Context, file *filestore.File, mimePrefix string) (string, error) {
+	limit := e.maxInlineFileBytes()
+	if file.Bytes > limit {
+		return "", fmt.Errorf("input file %s is %d bytes, over the %d bytes limit", file.ID, file.Bytes, limit)
+	}
+	content, err := e.files.GetFileContent(ctx, file.ID)
+	if err != nil {
+		return "", fmt.Errorf("input file %s: %w", file.ID, err)
+	}
+	if int64(len(content)) > limit {
+		return "", fmt.Errorf("input file %s is %d bytes, over the %d bytes limit", file.ID, len(content), limit)
+	}
+	mimeType := fileMIMEType(file, content)
+	if err := checkMIMEType(file, mimeType, mimePrefix); err != nil {
+		return "", err
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(content), nil
+}
+
+// fileMIMEType returns the media type of an uploaded file: the one it was
+// uploaded with, else the one of its extension, else the one sniffed from
+// its content (when given).
+func fileMIMEType(file *filestore.File, content []byte) string {
+	candidates := []string{file.MimeType, mime.TypeByExtension(filepath.Ext(file.Filename))}
+	if content != nil {
+		candidates = append(candidates, http.DetectContentType(content))
+	}
+	for _, c := range candidates {
+		if mediaType, _, err := mime.ParseMediaType(c); err == nil && mediaType != "application/octet-stream" {
+			return mediaType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// checkMIMEType returns an error unless mimeType starts with prefix.
+func checkMIMEType(file *filestore.File, mimeType, prefix string) error {
+	if prefix != "" && !strings.HasPrefix(mimeType, prefix) {
+		return fmt.Errorf("input file %s has type %s, want %s*", file.ID, mimeType, prefix)
+	}
+	return nil
+}
+
+// inlineFileInputs reports whether files are always sent as data URLs.
+func (e *Engine) inlineFileInputs() bool {
+	return e.config != nil && e.config.InlineFileInputs
+}
+
+// maxInlineFileBytes returns the size limit of a file sent inline.
+func (e *Engine) maxInlineFileBytes() int64 {
+	if e.config == nil || e.config.MaxInlineFileBytes <= 0 {
+		return 20 << 20
+	}
+	return e.config.MaxInlineFileBytes
+}
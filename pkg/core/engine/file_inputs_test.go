@@ -0,0 +1,156 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/filestore/memory"
+)
+
+// signingStore is a memory file store that presigns URLs.
+type signingStore struct {
+	*memory.Store
+}
+
+func (s signingStore) PresignContentURL(_ context.Context, file *filestore.File, _ time.Duration) (string, time.Time, error) {
+	return "https://files.example.com/" + file.ID + "?sig=x", time.Now().Add(time.Hour), nil
+}
+
+// stubImageFetcher fetches any URL of images.example.com as a PNG.
+type stubImageFetcher struct{}
+
+func (stubImageFetcher) DataURL(_ context.Context, url string) (string, error) {
+	if !strings.HasPrefix(url, "https://images.example.com/") {
+		return "", errors.New("blocked")
+	}
+	return "data:image/png;base64,cG5n", nil
+}
+
+func TestResolveFileInputs(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	for _, f := range []*filestore.File{
+		{ID: "file_clip", Filename: "clip.mp4", MimeType: "video/mp4", Content: []byte("mp4")},
+		{ID: "file_other", Filename: "other.mp4", MimeType: "video/mp4", Content: []byte("mp4"), Tenant: "acme"},
+		{ID: "file_photo", Filename: "photo.png", MimeType: "application/octet-stream", Content: []byte("png")},
+		{ID: "file_doc", Filename: "doc.pdf", MimeType: "application/pdf", Content: []byte("pdf")},
+		{ID: "file_big", Filename: "big.png", MimeType: "image/png", Content: make([]byte, 64)},
+	} {
+		if err := store.CreateFile(ctx, f); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+	video := func(url, fileID string) []api.Message {
+		return []api.Message{{Role: "user", ContentParts: []api.MessageContentPart{
+			{Type: "video_url", VideoURL: &api.MessageVideoURL{URL: url, FileID: fileID}},
+		}}}
+	}
+	resolved := func(t *testing.T, e *Engine, messages []api.Message) string {
+		t.Helper()
+		if err := e.resolveFileInputs(ctx, messages); err != nil {
+			t.Fatalf("resolveFileInputs: %v", err)
+		}
+		return messages[0].ContentParts[0].VideoURL.URL
+	}
+
+	e := &Engine{config: &config.EngineConfig{}, files: store}
+	if got, want := resolved(t, e, video("", "file_clip")), "data:video/mp4;base64,bXA0"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+	if got := resolved(t, e, video("https://example.com/a.mp4", "")); got != "https://example.com/a.mp4" {
+		t.Errorf("URL = %q, want it unchanged", got)
+	}
+
+	// A store that presigns URLs is asked for one, unless inlining is forced
+	e.files = signingStore{store}
+	if got, want := resolved(t, e, video("", "file_clip")), "https://files.example.com/file_clip?sig=x"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+	e.config.InlineFileInputs = true
+	if got := resolved(t, e, video("", "file_clip")); got != "data:video/mp4;base64,bXA0" {
+		t.Errorf("URL = %q, want a data URL", got)
+	}
+
+	// Files of another tenant, and missing files, are not found
+	for _, id := range []string{"file_other", "file_missing"} {
+		if err := e.resolveFileInputs(ctx, video("", id)); !errors.Is(err, filestore.ErrFileNotFound) {
+			t.Errorf("resolveFileInputs(%s) error = %v, want ErrFileNotFound", id, err)
+		}
+	}
+	if err := e.resolveFileInputs(featureflags.WithTenant(ctx, "acme"), video("", "file_other")); err != nil {
+		t.Errorf("resolveFileInputs(own tenant) error = %v", err)
+	}
+
+	// Images and files are inlined, within the size limit and when their
+	// type matches
+	e.config.MaxInlineFileBytes = 32
+	messages := []api.Message{{Role: "user", ContentParts: []api.MessageContentPart{
+		{Type: "image_url", ImageURL: &api.MessageImageURL{FileID: "file_photo"}},
+		{Type: "file", File: &api.MessageFile{FileID: "file_doc"}},
+	}}}
+	if err := e.resolveFileInputs(ctx, messages); err != nil {
+		t.Fatalf("resolveFileInputs: %v", err)
+	}
+	// The type of photo.png comes from its extension
+	if got, want := messages[0].ContentParts[0].ImageURL.URL, "data:image/png;base64,cG5n"; got != want {
+		t.Errorf("image URL = %q, want %q", got, want)
+	}
+	if got := *messages[0].ContentParts[1].File; got != (api.MessageFile{FileID: "file_doc", FileData: "data:application/pdf;base64,cGRm", Filename: "doc.pdf"}) {
+		t.Errorf("file = %+v", got)
+	}
+	for _, part := range []api.MessageContentPart{
+		{Type: "image_url", ImageURL: &api.MessageImageURL{FileID: "file_big"}},
+		{Type: "image_url", ImageURL: &api.MessageImageURL{FileID: "file_doc"}},
+		{Type: "video_url", VideoURL: &api.MessageVideoURL{FileID: "file_photo"}},
+	} {
+		if err := e.resolveFileInputs(ctx, []api.Message{{Role: "user", ContentParts: []api.MessageContentPart{part}}}); err == nil {
+			t.Errorf("resolveFileInputs(%+v) succeeded, want an error", part)
+		}
+	}
+
+	// Without a file store the file ID is forwarded
+	e.files = nil
+	messages = video("", "file_clip")
+	if got := resolved(t, e, messages); got != "" || messages[0].ContentParts[0].VideoURL.FileID != "file_clip" {
+		t.Errorf("video = %+v, want the file ID kept", messages[0].ContentParts[0].VideoURL)
+	}
+}
+
+func TestResolveFileInputs_ImageFetch(t *testing.T) {
+	ctx := context.Background()
+	image := func(url string) []api.Message {
+		return []api.Message{{Role: "user", ContentParts: []api.MessageContentPart{
+			{Type: "image_url", ImageURL: &api.MessageImageURL{URL: url}},
+		}}}
+	}
+
+	// Without an image fetcher, remote images are forwarded as is
+	e := &Engine{config: &config.EngineConfig{}}
+	messages := image("https://images.example.com/cat.png")
+	if err := e.resolveFileInputs(ctx, messages); err != nil || messages[0].ContentParts[0].ImageURL.URL != "https://images.example.com/cat.png" {
+		t.Errorf("image = %+v, %v, want it unchanged", messages[0].ContentParts[0].ImageURL, err)
+	}
+
+	e.SetImageFetcher(stubImageFetcher{})
+	if err := e.resolveFileInputs(ctx, messages); err != nil || messages[0].ContentParts[0].ImageURL.URL != "data:image/png;base64,cG5n" {
+		t.Errorf("image = %+v, %v, want a data URL", messages[0].ContentParts[0].ImageURL, err)
+	}
+	// Data URLs are not fetched
+	messages = image("data:image/gif;base64,R0lGODlh")
+	if err := e.resolveFileInputs(ctx, messages); err != nil || messages[0].ContentParts[0].ImageURL.URL != "data:image/gif;base64,R0lGODlh" {
+		t.Errorf("image = %+v, %v, want it unchanged", messages[0].ContentParts[0].ImageURL, err)
+	}
+	if err := e.resolveFileInputs(ctx, image("https://internal.example.com/x.png")); err == nil {
+		t.Error("resolveFileInputs(blocked image) succeeded, want an error")
+	}
+}
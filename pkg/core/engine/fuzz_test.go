@@ -0,0 +1,43 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func FuzzExtractInputMessages(f *testing.F) {
+	f.Add(`"hello"`)
+	f.Add(`[{"type":"message","role":"user","content":"hi"},{"type":"function_call","call_id":"c1","name":"f","arguments":"{}"},{"type":"function_call_output","call_id":"c1","output":"ok"}]`)
+	f.Add(`[{"type":"message","role":"user","content":[{"type":"input_text","text":"x"},{"type":"input_image","image_url":"data:image/png;base64,AA"},{"type":"input_file","file_id":"f"}]}]`)
+	f.Add(`[{"type":"message"},{"content":""},7,null]`)
+	f.Add(`{"unexpected":"object"}`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(input), &v); err != nil {
+			t.Skip()
+		}
+
+		msgs := extractInputMessages(v)
+
+		if len(msgs) == 0 {
+			t.Fatal("expected at least one message")
+		}
+		if s, ok := v.(string); ok {
+			if len(msgs) != 1 || msgs[0].Role != "user" || msgs[0].Content != s {
+				t.Fatalf("string input should map to one user message, got %+v", msgs)
+			}
+		}
+		for i, msg := range msgs {
+			if msg.Role == "" {
+				t.Fatalf("message %d has empty role", i)
+			}
+			if msg.Role == "tool" && msg.ToolCallID == "" {
+				t.Fatalf("tool message %d has no call ID", i)
+			}
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// responseHistory returns the messages of a thread up to and including the
+// response responseID, along with the ID of the response whose stored
+// history they extend ("" when none does).
+//
+// A response normally stores its whole history. One that does not, such as
+// a turn that failed before the engine built it, is replayed from its
+// request input and its output, and the walk continues along its
+// previous_response_id until a response with a stored history or the start
+// of the thread. The walk stops early at the configured depth, or once the
+// replayed turns exceed the model's context window; older turns are then
+// left out.
+func (e *Engine) responseHistory(ctx context.Context, responseID string) ([]api.Message, string, error) {
+	var (
+		turns   [][]api.Message // newest first
+		baseID  string
+		visited = make(map[string]bool)
+		tokens  int
+	)
+	for id := responseID; id != ""; {
+		if visited[id] {
+			return nil, "", fmt.Errorf("previous_response_id chain of %s loops back to %s", responseID, id)
+		}
+		visited[id] = true
+		if len(visited) > e.historyMaxDepth() {
+			break
+		}
+
+		resp, err := e.sessions.GetResponse(ctx, id)
+		if err != nil {
+			if id == responseID {
+				return nil, "", err
+			}
+			break // the rest of the thread was deleted
+		}
+		if len(resp.Messages) > 0 {
+			turns = append(turns, messagesFromRecords(resp.Messages))
+			if id == responseID {
+				baseID = id
+			}
+			break
+		}
+
+		turn := replayTurn(resp)
+		for _, msg := range turn {
+			tokens += e.countMessage(msg)
+		}
+		if window := e.contextWindow(); window > 0 && tokens > window && len(turns) > 0 {
+			break
+		}
+		turns = append(turns, turn)
+		id = resp.PreviousResponseID
+	}
+
+	var messages []api.Message
+	for _, turn := range slices.Backward(turns) {
+		messages = append(messages, turn...)
+	}
+	return messages, baseID, nil
+}
+
+// replayTurn rebuilds the messages of a response that did not store its
+// history from its request input and the first candidate of its output.
+func replayTurn(resp *state.Response) []api.Message {
+	var messages []api.Message
+	if req := convertStoredRequest(resp.Request); req != nil && req.Input != nil {
+		messages = extractInputMessages(req.Input)
+	}
+
+	var calls []api.ToolCall
+	for _, item := range convertStoredOutput(resp.Output) {
+		if item.CandidateIndex != nil && *item.CandidateIndex > 0 {
+			continue
+		}
+		switch item.Type {
+		case "message":
+			var text strings.Builder
+			for _, part := range item.Content {
+				switch {
+				case part.Text != nil:
+					text.WriteString(*part.Text)
+				case part.Transcript != nil:
+					text.WriteString(*part.Transcript)
+				}
+			}
+			if text.Len() > 0 {
+				messages = append(messages, api.Message{Role: "assistant", Content: text.String()})
+			}
+		case "function_call":
+			if item.Name == nil || item.CallID == nil {
+				continue
+			}
+			arguments := ""
+			if item.Arguments != nil {
+				arguments = *item.Arguments
+			}
+			calls = append(calls, api.ToolCall{
+				ID:       *item.CallID,
+				Type:     "function",
+				Function: api.ToolCallFunction{Name: *item.Name, Arguments: arguments},
+			})
+		}
+	}
+	if len(calls) > 0 {
+		messages = append(messages, api.Message{Role: "assistant", ToolCalls: calls})
+	}
+	return messages
+}
+
+// messagesFromRecords converts stored messages back to their backend form.
+func messagesFromRecords(records []state.ConversationMessage) []api.Message {
+	messages := make([]api.Message, 0, len(records))
+	for _, m := range records {
+		msg := api.Message{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+			Reasoning:  reasoningFromRecord(m.Reasoning),
+		}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, api.ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: api.ToolCallFunction{
+					Name:      tc.Name,
+					Arguments: tc.Arguments,
+				},
+			})
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// historyMaxDepth returns the number of responses a history walk may visit.
+func (e *Engine) historyMaxDepth() int {
+	if e.config == nil || e.config.HistoryMaxDepth <= 0 {
+		return 100
+	}
+	return e.config.HistoryMaxDepth
+}
+
+// contextWindow returns the model's context size in tokens, 0 if unknown.
+func (e *Engine) contextWindow() int {
+	if e.config == nil {
+		return 0
+	}
+	return e.config.ContextWindow
+}
@@ -0,0 +1,105 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// summarize renders messages as role:content lines, tool calls by name.
+func summarize(messages []api.Message) string {
+	var lines []string
+	for _, m := range messages {
+		content := m.Content
+		for _, tc := range m.ToolCalls {
+			content += "call " + tc.Function.Name
+		}
+		lines = append(lines, m.Role+":"+content)
+	}
+	return strings.Join(lines, " ")
+}
+
+func TestResponseHistory(t *testing.T) {
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	now := time.Now()
+	for _, resp := range []*state.Response{
+		{
+			ID: "resp_a", Status: "completed", CreatedAt: now,
+			Messages: []state.ConversationMessage{{Role: "user", Content: "one"}, {Role: "assistant", Content: "A"}},
+		},
+		// Turns that did not store their history
+		{
+			ID: "resp_b", PreviousResponseID: "resp_a", Status: "failed", CreatedAt: now,
+			Request: map[string]interface{}{"input": "two"},
+			Output: []interface{}{map[string]interface{}{
+				"type": "message", "id": "msg_b", "role": "assistant",
+				"content": []interface{}{map[string]interface{}{"type": "output_text", "text": "B"}},
+			}},
+		},
+		{
+			ID: "resp_c", PreviousResponseID: "resp_b", Status: "in_progress", CreatedAt: now,
+			Request: map[string]interface{}{"input": "three"},
+			Output: []interface{}{map[string]interface{}{
+				"type": "function_call", "id": "fc_c", "call_id": "call_c", "name": "lookup", "arguments": "{}",
+			}},
+		},
+		// A corrupt chain
+		{ID: "resp_x", PreviousResponseID: "resp_y", Status: "failed", CreatedAt: now, Request: map[string]interface{}{"input": "x"}},
+		{ID: "resp_y", PreviousResponseID: "resp_x", Status: "failed", CreatedAt: now, Request: map[string]interface{}{"input": "y"}},
+	} {
+		if err := store.SaveResponse(ctx, resp); err != nil {
+			t.Fatalf("SaveResponse(%s) error = %v", resp.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		from     string
+		want     string
+		wantBase string
+		wantErr  bool
+	}{
+		{name: "stored history", from: "resp_a", want: "user:one assistant:A", wantBase: "resp_a"},
+		{name: "replayed turns", from: "resp_c", want: "user:one assistant:A user:two assistant:B user:three assistant:call lookup"},
+		{name: "depth limit", maxDepth: 2, from: "resp_c", want: "user:two assistant:B user:three assistant:call lookup"},
+		{name: "cycle", from: "resp_x", wantErr: true},
+		{name: "missing", from: "resp_missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{config: &config.EngineConfig{HistoryMaxDepth: tt.maxDepth}, sessions: store}
+			messages, base, err := e.responseHistory(ctx, tt.from)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("responseHistory() = %q, want an error", summarize(messages))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("responseHistory() error = %v", err)
+			}
+			if got := summarize(messages); got != tt.want {
+				t.Errorf("responseHistory() = %q, want %q", got, tt.want)
+			}
+			if base != tt.wantBase {
+				t.Errorf("base = %q, want %q", base, tt.wantBase)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"strings"
+)
+
+// ImageFetcher fetches remote images for backends without internet access.
+// Implemented by webfetch.ImageFetcher.
+type ImageFetcher interface {
+	DataURL(ctx context.Context, url string) (string, error)
+}
+
+// SetImageFetcher makes the engine fetch the http and https image URLs of
+// input parts itself and send the images to the backend as data URLs.
+func (e *Engine) SetImageFetcher(f ImageFetcher) {
+	e.imageFetch = f
+}
+
+// isRemoteURL reports whether url is an http or https URL.
+func isRemoteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
@@ -0,0 +1,153 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// ErrInputItemNotFound is returned by ListResponseInputItems for a cursor
+// that is not an input item of the response.
+var ErrInputItemNotFound = errors.New("input item not found")
+
+// ListResponseInputItems returns a page of the input items of a response,
+// in the given order: "asc" lists them as they were sent, "desc" (default)
+// last first. The after and before cursors are item IDs and follow the
+// listing order.
+func (e *Engine) ListResponseInputItems(ctx context.Context, responseID, after, before string, limit int, order string) ([]schema.InputItem, bool, error) {
+	stored, err := e.sessions.GetResponseInputItems(ctx, responseID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get response input items: %w", err)
+	}
+	request, _ := stored.(map[string]interface{})
+	items := inputItems(responseID, request["input"])
+	if order != "asc" {
+		slices.Reverse(items)
+	}
+
+	start, end := 0, len(items)
+	if after != "" {
+		i := slices.IndexFunc(items, func(item schema.InputItem) bool { return item.ID == after })
+		if i < 0 {
+			return nil, false, fmt.Errorf("%w: %s", ErrInputItemNotFound, after)
+		}
+		start = i + 1
+	}
+	if before != "" {
+		i := slices.IndexFunc(items, func(item schema.InputItem) bool { return item.ID == before })
+		if i < 0 {
+			return nil, false, fmt.Errorf("%w: %s", ErrInputItemNotFound, before)
+		}
+		end = i
+	}
+	page := items[start:max(start, end)]
+	hasMore := limit > 0 && len(page) > limit
+	if hasMore {
+		page = page[:limit]
+	}
+	return page, hasMore, nil
+}
+
+// inputItems converts the input of a stored request into typed items.
+func inputItems(responseID string, input interface{}) []schema.InputItem {
+	switch v := input.(type) {
+	case string:
+		return []schema.InputItem{{
+			Type:    "message",
+			ID:      inputItemID(responseID, 0, "message"),
+			Status:  "completed",
+			Role:    "user",
+			Content: []map[string]interface{}{{"type": "input_text", "text": v}},
+		}}
+	case []interface{}:
+		items := make([]schema.InputItem, 0, len(v))
+		for i, raw := range v {
+			if m, ok := raw.(map[string]interface{}); ok {
+				items = append(items, inputItem(responseID, i, m))
+			}
+		}
+		return items
+	default:
+		return []schema.InputItem{}
+	}
+}
+
+// inputItem converts an input item of a stored request. Messages may omit
+// their type, and their content may be a string.
+func inputItem(responseID string, index int, m map[string]interface{}) schema.InputItem {
+	item := schema.InputItem{}
+	item.Type, _ = m["type"].(string)
+	item.ID, _ = m["id"].(string)
+	item.Status, _ = m["status"].(string)
+	item.Role, _ = m["role"].(string)
+	item.CallID, _ = m["call_id"].(string)
+	item.Name, _ = m["name"].(string)
+	item.Arguments, _ = m["arguments"].(string)
+	item.Output = m["output"]
+	item.ServerLabel, _ = m["server_label"].(string)
+	item.Error, _ = m["error"].(string)
+	item.EncryptedContent, _ = m["encrypted_content"].(string)
+	item.Summary = contentParts(m["summary"])
+
+	if item.Type == "" && item.Role != "" {
+		item.Type = "message"
+	}
+	switch content := m["content"].(type) {
+	case string:
+		partType := "input_text"
+		if item.Role == "assistant" {
+			partType = "output_text"
+		}
+		item.Content = []map[string]interface{}{{"type": partType, "text": content}}
+	default:
+		item.Content = contentParts(content)
+	}
+	if item.ID == "" {
+		item.ID = inputItemID(responseID, index, item.Type)
+	}
+	if item.Status == "" {
+		switch item.Type {
+		case "message", "function_call", "function_call_output":
+			item.Status = "completed"
+		}
+	}
+	return item
+}
+
+// contentParts returns the object parts of a content array.
+func contentParts(v interface{}) []map[string]interface{} {
+	arr, _ := v.([]interface{})
+	var parts []map[string]interface{}
+	for _, part := range arr {
+		if m, ok := part.(map[string]interface{}); ok {
+			parts = append(parts, m)
+		}
+	}
+	return parts
+}
+
+// inputItemID derives the ID of an input item sent without one from the
+// response and the item's position, so that it is the same on every
+// listing and can be used as a cursor.
+func inputItemID(responseID string, index int, itemType string) string {
+	prefix := "item_"
+	switch itemType {
+	case "message":
+		prefix = "msg_"
+	case "function_call":
+		prefix = "fc_"
+	case "function_call_output":
+		prefix = "fco_"
+	}
+	sum := sha256.Sum256([]byte(responseID + "/" + strconv.Itoa(index)))
+	return prefix + hex.EncodeToString(sum[:16])
+}
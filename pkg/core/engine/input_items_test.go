@@ -0,0 +1,111 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func TestListResponseInputItems(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	e := &Engine{sessions: store}
+
+	save := func(id string, input interface{}) {
+		t.Helper()
+		if err := store.SaveResponse(ctx, &state.Response{
+			ID: id, Status: "completed", CreatedAt: time.Now(),
+			Request: &schema.ResponseRequest{Model: stringPtr("m"), Input: input},
+		}); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+	save("resp_1", []interface{}{
+		map[string]interface{}{"role": "developer", "content": "be brief"},
+		map[string]interface{}{"type": "message", "role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "input_text", "text": "what is the weather?"},
+			map[string]interface{}{"type": "input_image", "image_url": "https://example.com/a.png"},
+		}},
+		map[string]interface{}{"type": "function_call", "id": "fc_client", "call_id": "call_1", "name": "weather", "arguments": `{"city":"Paris"}`},
+		map[string]interface{}{"type": "function_call_output", "call_id": "call_1", "output": "sunny"},
+	})
+	save("resp_2", "hello")
+
+	asc, hasMore, err := e.ListResponseInputItems(ctx, "resp_1", "", "", 20, "asc")
+	if err != nil || hasMore || len(asc) != 4 {
+		t.Fatalf("ListResponseInputItems = %d items, %v, %v", len(asc), hasMore, err)
+	}
+	if it := asc[0]; it.Type != "message" || it.Role != "developer" || it.Status != "completed" ||
+		len(it.Content) != 1 || it.Content[0]["type"] != "input_text" || it.Content[0]["text"] != "be brief" || !strings.HasPrefix(it.ID, "msg_") {
+		t.Errorf("string content message = %+v", it)
+	}
+	if it := asc[1]; len(it.Content) != 2 || it.Content[1]["type"] != "input_image" {
+		t.Errorf("multimodal message = %+v", it)
+	}
+	if it := asc[2]; it.Type != "function_call" || it.ID != "fc_client" || it.CallID != "call_1" || it.Name != "weather" || it.Content != nil {
+		t.Errorf("function call = %+v", it)
+	}
+	if it := asc[3]; it.Type != "function_call_output" || it.Output != "sunny" || !strings.HasPrefix(it.ID, "fco_") {
+		t.Errorf("function call output = %+v", it)
+	}
+
+	// IDs are the same on every listing, and desc lists the last item first
+	desc, _, err := e.ListResponseInputItems(ctx, "resp_1", "", "", 20, "desc")
+	if err != nil {
+		t.Fatalf("ListResponseInputItems: %v", err)
+	}
+	for i := range desc {
+		if desc[i].ID != asc[len(asc)-1-i].ID {
+			t.Fatalf("desc order = %v, asc = %v", itemIDs(desc), itemIDs(asc))
+		}
+	}
+
+	// Cursors follow the listing order
+	page, hasMore, err := e.ListResponseInputItems(ctx, "resp_1", desc[0].ID, "", 2, "desc")
+	if err != nil || !equalIDs(page, desc[1:3]) || !hasMore {
+		t.Errorf("after %s = %v, %v, %v", desc[0].ID, itemIDs(page), hasMore, err)
+	}
+	page, hasMore, err = e.ListResponseInputItems(ctx, "resp_1", "", asc[2].ID, 20, "asc")
+	if err != nil || !equalIDs(page, asc[:2]) || hasMore {
+		t.Errorf("before %s = %v, %v, %v", asc[2].ID, itemIDs(page), hasMore, err)
+	}
+	if _, _, err := e.ListResponseInputItems(ctx, "resp_1", "msg_unknown", "", 20, "asc"); !errors.Is(err, ErrInputItemNotFound) {
+		t.Errorf("unknown cursor: got %v, want ErrInputItemNotFound", err)
+	}
+
+	// A string input is a user message
+	items, _, err := e.ListResponseInputItems(ctx, "resp_2", "", "", 20, "desc")
+	if err != nil || len(items) != 1 || items[0].Role != "user" || items[0].Content[0]["text"] != "hello" {
+		t.Errorf("string input = %+v, %v", items, err)
+	}
+
+	if _, _, err := e.ListResponseInputItems(ctx, "resp_missing", "", "", 20, "desc"); err == nil {
+		t.Error("expected an error for a missing response")
+	}
+}
+
+func itemIDs(items []schema.InputItem) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.ID
+	}
+	return out
+}
+
+func equalIDs(a, b []schema.InputItem) bool {
+	return strings.Join(itemIDs(a), ",") == strings.Join(itemIDs(b), ",")
+}
@@ -0,0 +1,47 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// ConversationInstructionsKey is the conversation metadata key whose value
+// is sent ahead of the instructions of every turn of the conversation.
+const ConversationInstructionsKey = "instructions"
+
+// layerInstructions returns the instructions sent to the backend. The
+// gateway's system prompt, the instructions of the request's API key, the
+// model's instructions prefix and the conversation's instructions come
+// first, in this order, and the request's own instructions last, so that
+// clients can add to the gateway's instructions but not remove them.
+func (e *Engine) layerInstructions(ctx context.Context, req *schema.ResponseRequest, conv *state.Conversation, instructions *string) *string {
+	if conv != nil {
+		instructions = prependInstructions(conv.Metadata[ConversationInstructionsKey], instructions)
+	}
+	instructions = e.modelInstructions(req, instructions)
+	if e.config == nil {
+		return instructions
+	}
+	if key := state.APIKeyFromContext(ctx); key != "" {
+		instructions = prependInstructions(e.config.APIKeyInstructions[key], instructions)
+	}
+	return prependInstructions(e.config.SystemPrompt, instructions)
+}
+
+// prependInstructions returns instructions with layer prepended before a
+// blank line. Like appendInstructions, it never modifies the original.
+func prependInstructions(layer string, instructions *string) *string {
+	if layer == "" {
+		return instructions
+	}
+	if instructions == nil || *instructions == "" {
+		return &layer
+	}
+	combined := layer + "\n\n" + *instructions
+	return &combined
+}
@@ -0,0 +1,85 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// instructionsBackend records the instructions of the last request.
+type instructionsBackend struct {
+	benchBackend
+	instructions *string
+}
+
+func (b *instructionsBackend) CreateResponse(ctx context.Context, req *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	b.instructions = req.Instructions
+	return b.benchBackend.CreateResponse(ctx, req)
+}
+
+func TestProcessRequest_InstructionsCarryover(t *testing.T) {
+	tests := []struct {
+		name  string
+		merge string // configured default
+		turns []*string
+		want  []string // instructions sent on each turn, "" for none
+	}{
+		{
+			name:  "replace",
+			turns: []*string{stringPtr("be brief"), nil, stringPtr("be verbose"), nil},
+			want:  []string{"be brief", "", "be verbose", ""},
+		},
+		{
+			name:  "inherit",
+			merge: "inherit",
+			turns: []*string{stringPtr("be brief"), nil, stringPtr("be verbose"), nil},
+			want:  []string{"be brief", "be brief", "be verbose", "be verbose"},
+		},
+		{
+			// A turn without instructions drops them for later ones
+			name:  "inherit after replace",
+			merge: "inherit",
+			turns: []*string{stringPtr("be brief"), stringPtr(""), nil},
+			want:  []string{"be brief", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatalf("sqlite.New() error = %v", err)
+			}
+			defer store.Close()
+			backend := &instructionsBackend{}
+			e := &Engine{config: &config.EngineConfig{InstructionsMerge: tt.merge}, sessions: store, llm: backend, idGen: ids.NewSequence()}
+
+			var prev *string
+			for i, instructions := range tt.turns {
+				backend.instructions = nil
+				resp, err := e.ProcessRequest(context.Background(), &schema.ResponseRequest{
+					Model: stringPtr("m"), Input: "hi", Instructions: instructions, PreviousResponseID: prev,
+				})
+				if err != nil {
+					t.Fatalf("turn %d: ProcessRequest() error = %v", i, err)
+				}
+				got := ""
+				if backend.instructions != nil {
+					got = *backend.instructions
+				}
+				if got != tt.want[i] {
+					t.Errorf("turn %d: instructions = %q, want %q", i, got, tt.want[i])
+				}
+				prev = &resp.ID
+			}
+		})
+	}
+}
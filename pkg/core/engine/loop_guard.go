@@ -0,0 +1,234 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// Incomplete reasons reported when a loop guard stops the agentic loop.
+const (
+	incompleteMaxDuration     = "max_duration"
+	incompleteMaxBackendCalls = "max_backend_calls"
+	incompleteMaxTotalTokens  = "max_total_tokens"
+	incompleteInterrupted     = "interrupted"
+	incompleteCancelled       = "cancelled"
+)
+
+type cancelKey struct{}
+
+// cancelSignal is closed when the client cancels a response.
+type cancelSignal struct {
+	done chan struct{}
+	once sync.Once
+}
+
+// WithCancel returns a context for a response that the client may cancel
+// while it runs, and the function that cancels it. Unlike canceling ctx,
+// which abandons the response, cancel stops the agentic loop like
+// Engine.Interrupt: the response ends as "incomplete" with reason
+// "cancelled", and the output produced so far is returned and saved.
+func WithCancel(ctx context.Context) (context.Context, func()) {
+	sig := &cancelSignal{done: make(chan struct{})}
+	return context.WithValue(ctx, cancelKey{}, sig), func() {
+		sig.once.Do(func() { close(sig.done) })
+	}
+}
+
+// Cancelled returns a channel that is closed when the response run under ctx
+// is cancelled, or nil if ctx does not come from WithCancel.
+func Cancelled(ctx context.Context) <-chan struct{} {
+	if sig, ok := ctx.Value(cancelKey{}).(*cancelSignal); ok {
+		return sig.done
+	}
+	return nil
+}
+
+type deadlineKey struct{}
+
+// WithRequestTimeout returns a context for a response that must be done
+// within d from now. It can lower the configured loop.request_timeout but
+// not raise it. A request that runs out of time during the agentic loop
+// ends as "incomplete" with reason "max_duration"; before the loop, while
+// listing MCP tools or fetching inputs, it fails.
+func WithRequestTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, deadlineKey{}, time.Now().Add(d))
+}
+
+// withRequestDeadline records in ctx the deadline of a request that arrived
+// at start: the earlier of the configured request timeout and the one set
+// with WithRequestTimeout.
+func (e *Engine) withRequestDeadline(ctx context.Context, start time.Time) context.Context {
+	deadline := requestDeadline(ctx)
+	if d := e.config.Loop.RequestTimeout; d > 0 && (deadline.IsZero() || start.Add(d).Before(deadline)) {
+		deadline = start.Add(d)
+	}
+	if deadline.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, deadlineKey{}, deadline)
+}
+
+// requestDeadline returns the deadline of the request run under ctx, or the
+// zero time if it has none.
+func requestDeadline(ctx context.Context) time.Time {
+	deadline, _ := ctx.Value(deadlineKey{}).(time.Time)
+	return deadline
+}
+
+// withinRequest returns ctx bounded by the request deadline, for the calls
+// made before the agentic loop, whose guard bounds the calls made in it.
+func withinRequest(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline := requestDeadline(ctx); !deadline.IsZero() {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return ctx, func() {}
+}
+
+// loopGuard bounds the agentic loop by wall-clock time, number of backend
+// calls and total tokens. Zero limits are unlimited.
+type loopGuard struct {
+	deadline        time.Time // zero means no deadline
+	maxBackendCalls int
+	maxTotalTokens  int
+
+	backendCalls int
+	totalTokens  int
+
+	interrupt <-chan struct{} // closed when the engine is interrupted; nil never is
+	cancel    <-chan struct{} // closed when the client cancels; nil never is
+}
+
+// newLoopGuard combines the configured limits with the request's. A request
+// can only tighten a configured limit.
+func newLoopGuard(cfg config.LoopConfig, req *schema.ResponseRequest, start time.Time) *loopGuard {
+	maxDuration := cfg.MaxDuration
+	if req.MaxDurationSeconds != nil {
+		d := time.Duration(*req.MaxDurationSeconds) * time.Second
+		if maxDuration == 0 || d < maxDuration {
+			maxDuration = d
+		}
+	}
+
+	g := &loopGuard{
+		maxBackendCalls: tighterLimit(cfg.MaxBackendCalls, req.MaxBackendCalls),
+		maxTotalTokens:  tighterLimit(cfg.MaxTotalTokens, req.MaxTotalTokens),
+	}
+	if maxDuration > 0 {
+		g.deadline = start.Add(maxDuration)
+	}
+	return g
+}
+
+func tighterLimit(configured int, requested *int) int {
+	if requested != nil && *requested > 0 && (configured == 0 || *requested < configured) {
+		return *requested
+	}
+	return configured
+}
+
+// context returns ctx bounded by the guard's deadline and canceled on
+// interruption, so that a slow backend or tool call is interrupted when the
+// loop runs out of time or the gateway shuts down.
+func (g *loopGuard) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	var (
+		loopCtx context.Context
+		cancel  context.CancelFunc
+	)
+	if g.deadline.IsZero() {
+		loopCtx, cancel = context.WithCancel(ctx)
+	} else {
+		loopCtx, cancel = context.WithDeadline(ctx, g.deadline)
+	}
+	if g.interrupt != nil || g.cancel != nil {
+		go func() {
+			select {
+			case <-g.interrupt:
+				cancel()
+			case <-g.cancel:
+				cancel()
+			case <-loopCtx.Done():
+			}
+		}()
+	}
+	return loopCtx, cancel
+}
+
+// stopReason returns why the loop was stopped from outside, by the client
+// cancelling the response or the engine being interrupted, or "".
+func (g *loopGuard) stopReason() string {
+	select {
+	case <-g.cancel:
+		return incompleteCancelled
+	default:
+	}
+	select {
+	case <-g.interrupt:
+		return incompleteInterrupted
+	default:
+	}
+	return ""
+}
+
+// check returns the reason the loop must stop before making another
+// backend call, or "" if it may continue.
+func (g *loopGuard) check(now time.Time) string {
+	if reason := g.stopReason(); reason != "" {
+		return reason
+	}
+	switch {
+	case !g.deadline.IsZero() && !now.Before(g.deadline):
+		return incompleteMaxDuration
+	case g.maxBackendCalls > 0 && g.backendCalls >= g.maxBackendCalls:
+		return incompleteMaxBackendCalls
+	case g.maxTotalTokens > 0 && g.totalTokens >= g.maxTotalTokens:
+		return incompleteMaxTotalTokens
+	}
+	return ""
+}
+
+// checkInput returns incompleteMaxTotalTokens if a backend call with
+// inputTokens of input would exceed the token limit, or "".
+func (g *loopGuard) checkInput(inputTokens int) string {
+	if g.maxTotalTokens > 0 && g.totalTokens+inputTokens > g.maxTotalTokens {
+		return incompleteMaxTotalTokens
+	}
+	return ""
+}
+
+// record accounts for a finished backend call.
+func (g *loopGuard) record(usage *api.UsageInfo) {
+	g.backendCalls++
+	if usage == nil {
+		return
+	}
+	if usage.TotalTokens > 0 {
+		g.totalTokens += usage.TotalTokens
+	} else {
+		g.totalTokens += usage.InputTokens + usage.OutputTokens
+	}
+}
+
+// stopped returns the reason loopCtx was ended by the guard, because its
+// deadline passed or the response was cancelled or interrupted, or "" if
+// loopCtx is live or the caller's ctx was canceled.
+func (g *loopGuard) stopped(ctx, loopCtx context.Context) string {
+	if ctx.Err() != nil || loopCtx.Err() == nil {
+		return ""
+	}
+	if reason := g.stopReason(); reason != "" {
+		return reason
+	}
+	if errors.Is(loopCtx.Err(), context.DeadlineExceeded) {
+		return incompleteMaxDuration
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// ErrModelAliasNotFound is returned when deleting an alias that does not
+// exist.
+var ErrModelAliasNotFound = errors.New("model alias not found")
+
+// modelAliases maps the model names clients use to the models the backend
+// serves. It is seeded from the configuration and changed at runtime
+// through the admin API.
+type modelAliases struct {
+	mu      sync.RWMutex
+	targets map[string]string
+}
+
+// resolve returns the model an alias points to.
+func (a *modelAliases) resolve(alias string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	model, ok := a.targets[alias]
+	return model, ok
+}
+
+// ModelAliases returns the model aliases, sorted by alias.
+func (e *Engine) ModelAliases() []schema.ModelAlias {
+	e.aliases.mu.RLock()
+	defer e.aliases.mu.RUnlock()
+	out := make([]schema.ModelAlias, 0, len(e.aliases.targets))
+	for _, alias := range slices.Sorted(maps.Keys(e.aliases.targets)) {
+		out = append(out, schema.ModelAlias{Object: "model_alias", Alias: alias, Model: e.aliases.targets[alias]})
+	}
+	return out
+}
+
+// SetModelAlias creates or repoints an alias. Aliases resolve once, so the
+// target cannot itself be an alias, nor the alias a target of another.
+func (e *Engine) SetModelAlias(alias, model string) error {
+	if alias == "" || model == "" {
+		return fmt.Errorf("alias and model are required")
+	}
+	if alias == model {
+		return fmt.Errorf("alias %q cannot point to itself", alias)
+	}
+
+	e.aliases.mu.Lock()
+	defer e.aliases.mu.Unlock()
+	if _, ok := e.aliases.targets[model]; ok {
+		return fmt.Errorf("model %q is itself an alias", model)
+	}
+	for other, target := range e.aliases.targets {
+		if target == alias {
+			return fmt.Errorf("%q is the model of alias %q", alias, other)
+		}
+	}
+	if e.aliases.targets == nil {
+		e.aliases.targets = make(map[string]string)
+	}
+	e.aliases.targets[alias] = model
+	return nil
+}
+
+// SetModelAliases replaces every alias, for a configuration reload. Aliases
+// changed through the admin API are lost.
+func (e *Engine) SetModelAliases(aliases map[string]string) {
+	e.aliases.mu.Lock()
+	defer e.aliases.mu.Unlock()
+	e.aliases.targets = maps.Clone(aliases)
+}
+
+// DeleteModelAlias removes an alias. Requests using it are then sent to the
+// backend with the alias as the model name.
+func (e *Engine) DeleteModelAlias(alias string) error {
+	e.aliases.mu.Lock()
+	defer e.aliases.mu.Unlock()
+	if _, ok := e.aliases.targets[alias]; !ok {
+		return fmt.Errorf("%w: %s", ErrModelAliasNotFound, alias)
+	}
+	delete(e.aliases.targets, alias)
+	return nil
+}
+
+// resolveModelAlias replaces an aliased model of req with its target and
+// returns the alias, or nil if the model is not an alias.
+func (e *Engine) resolveModelAlias(req *schema.ResponseRequest) *string {
+	if req.Model == nil {
+		return nil
+	}
+	model, ok := e.aliases.resolve(*req.Model)
+	if !ok {
+		return nil
+	}
+	alias := *req.Model
+	req.Model = &model
+	return &alias
+}
@@ -0,0 +1,79 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// modelConfig returns the configured parameters of req's model, falling
+// back to the "*" entry.
+func (e *Engine) modelConfig(req *schema.ResponseRequest) (config.ModelConfig, bool) {
+	if e.config == nil || len(e.config.Models) == 0 {
+		return config.ModelConfig{}, false
+	}
+	if req.Model != nil {
+		if m, ok := e.config.Models[*req.Model]; ok {
+			return m, true
+		}
+	}
+	m, ok := e.config.Models["*"]
+	return m, ok
+}
+
+// applyModelParams applies the configured defaults, overrides and limits of
+// the request's model. It runs before the request parameters are echoed, so
+// the response reports the values the backend was called with.
+func (e *Engine) applyModelParams(req *schema.ResponseRequest) {
+	m, ok := e.modelConfig(req)
+	if !ok {
+		return
+	}
+
+	setParams(req, m.Defaults, false)
+	setParams(req, m.Overrides, true)
+
+	if limit := m.MaxOutputTokens; limit > 0 {
+		if req.MaxOutputTokens == nil || *req.MaxOutputTokens > limit {
+			req.MaxOutputTokens = &limit
+		}
+	}
+}
+
+// setParams sets the parameters of p on req: all of them when override is
+// true, only those req leaves unset otherwise.
+func setParams(req *schema.ResponseRequest, p config.ModelParams, override bool) {
+	if p.Temperature != nil && (override || req.Temperature == nil) {
+		v := *p.Temperature
+		req.Temperature = &v
+	}
+	if p.TopP != nil && (override || req.TopP == nil) {
+		v := *p.TopP
+		req.TopP = &v
+	}
+	if p.MaxOutputTokens != nil && (override || req.MaxOutputTokens == nil) {
+		v := *p.MaxOutputTokens
+		req.MaxOutputTokens = &v
+	}
+	if p.ReasoningEffort != "" && (override || req.Reasoning == nil || req.Reasoning.Effort == nil) {
+		reasoning := schema.ReasoningParam{Type: "default"}
+		if req.Reasoning != nil {
+			reasoning = *req.Reasoning
+		}
+		effort := p.ReasoningEffort
+		reasoning.Effort = &effort
+		req.Reasoning = &reasoning
+	}
+}
+
+// modelInstructions prepends the configured instructions prefix of the
+// request's model to instructions.
+func (e *Engine) modelInstructions(req *schema.ResponseRequest, instructions *string) *string {
+	m, ok := e.modelConfig(req)
+	if !ok {
+		return instructions
+	}
+	return prependInstructions(m.InstructionsPrefix, instructions)
+}
@@ -0,0 +1,128 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// ErrInputFlagged is returned by PrepareBackendRequest when the content
+// moderator flags the input.
+var ErrInputFlagged = errors.New("input flagged by content moderation")
+
+// BackendRequest is a request ready to be sent to the model backend by a
+// proxy, for deployments where the gateway does not call the backend itself.
+type BackendRequest struct {
+	Path   string      // URL path on the model endpoint, e.g. "/v1/responses"
+	Header http.Header // headers to set: Content-Type, and Authorization when an API key is configured
+	Body   []byte
+}
+
+// PrepareBackendRequest runs the request side of ProcessRequest (validation,
+// prompt templates, request hooks, conversation history, input moderation
+// and tool expansion) and returns the request the first iteration of the
+// agentic loop would send to the backend.
+//
+// Nothing is stored, and the backend's reply goes to the client as-is: MCP,
+// file_search and web_search tools are offered to the model as function
+// tools, but their calls are returned to the client instead of being run.
+func (e *Engine) PrepareBackendRequest(ctx context.Context, req *schema.ResponseRequest) (*BackendRequest, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := req.CheckLimits(e.requestLimits()); err != nil {
+		return nil, err
+	}
+	if err := e.resolvePromptRef(ctx, req); err != nil {
+		return nil, fmt.Errorf("prompt resolution: %w", err)
+	}
+	if err := e.hooks.RunRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("request hook: %w", err)
+	}
+	e.resolveModelAlias(req)
+	e.applyModelParams(req)
+	e.injectTools(ctx, req)
+
+	var (
+		messages []api.Message
+		conv     *state.Conversation
+		err      error
+	)
+	if req.Conversation != nil && *req.Conversation != "" {
+		if conv, err = e.sessions.GetConversation(ctx, *req.Conversation); err != nil {
+			return nil, fmt.Errorf("conversation %s not found", *req.Conversation)
+		}
+		messages, _, err = e.buildConversationMessagesFromConversation(ctx, conv, req)
+	} else {
+		messages, _, err = e.buildConversationMessages(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build conversation: %w", err)
+	}
+
+	if violations, err := e.moderateInput(ctx, req); err != nil {
+		return nil, fmt.Errorf("content moderation failed: %w", err)
+	} else if len(violations) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInputFlagged, strings.Join(violations, ", "))
+	}
+
+	tools := req.Tools
+	if len(tools) > 0 {
+		if tools, _, err = e.expandMCPTools(ctx, tools); err != nil {
+			return nil, fmt.Errorf("failed to expand MCP tools: %w", err)
+		}
+		tools, _ = e.expandFileSearchTools(tools)
+		tools, _ = e.expandWebSearchTools(tools)
+	}
+
+	model := ""
+	if req.Model != nil {
+		model = *req.Model
+	}
+	apiReq := buildResponsesAPIRequest(model, messages, req, tools, req.Stream)
+	apiReq.Instructions = appendInstructions(e.layerInstructions(ctx, req, conv, e.mergeInstructions(req, storedInstructions(messages))), e.toolInstructions(req.Tools))
+	apiReq.PromptCacheKey = e.promptCacheKey(req, apiReq, messages)
+
+	base, err := url.Parse(e.config.ModelEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model endpoint: %w", err)
+	}
+	out := &BackendRequest{
+		Path:   strings.TrimSuffix(base.Path, "/") + "/responses",
+		Header: make(http.Header),
+	}
+	var body any = apiReq
+	if e.config.BackendAPI == "chat_completions" {
+		chatReq := api.ConvertToChatRequest(apiReq)
+		chatReq.Stream = apiReq.Stream
+		if chatReq.Stream {
+			chatReq.StreamOptions = &api.ChatStreamOptions{IncludeUsage: true}
+		}
+		out.Path = strings.TrimSuffix(base.Path, "/") + "/chat/completions"
+		body = chatReq
+	}
+	if out.Body, err = json.Marshal(body); err != nil {
+		return nil, fmt.Errorf("failed to marshal backend request: %w", err)
+	}
+
+	out.Header.Set("Content-Type", "application/json")
+	if e.config.APIKey != "" {
+		out.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		out.Header.Set(requestid.Header, id)
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// promptCacheKey returns the prompt_cache_key to send with apiReq: the
+// request's own, or one derived from the prompt's stable prefix when the
+// "prefix" strategy is configured, or nil.
+//
+// The derived key hashes what stays the same across the turns of a
+// conversation: the model, the instructions, the tool definitions and the
+// first user message. Backends that route or bucket their prefix cache by
+// this key then see every turn of a conversation together, while unrelated
+// conversations sharing a system prompt spread out.
+func (e *Engine) promptCacheKey(req *schema.ResponseRequest, apiReq *api.ResponsesAPIRequest, messages []api.Message) *string {
+	if req.PromptCacheKey != nil && *req.PromptCacheKey != "" {
+		return req.PromptCacheKey
+	}
+	if e.config == nil || e.config.PromptCacheKey != "prefix" {
+		return nil
+	}
+
+	h := sha256.New()
+	write := func(data []byte) {
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	write([]byte(apiReq.Model))
+	if apiReq.Instructions != nil {
+		write([]byte(*apiReq.Instructions))
+	}
+	if tools, err := json.Marshal(apiReq.Tools); err == nil {
+		write(tools)
+	}
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			if data, err := json.Marshal(msg); err == nil {
+				write(data)
+			}
+			break
+		}
+	}
+	key := "pc_" + hex.EncodeToString(h.Sum(nil))[:32]
+	return &key
+}
@@ -0,0 +1,122 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// Watermarker embeds an invisible watermark in generated text, for
+// deployments that must mark AI-generated content. The visible text must be
+// left unchanged.
+type Watermarker interface {
+	Watermark(ctx context.Context, text string) (string, error)
+}
+
+// provenanceConfig holds the fixed part of the provenance block.
+type provenanceConfig struct {
+	gatewayVersion string
+	endpointHash   string
+}
+
+// SetProvenance attaches a provenance block to every completed or
+// incomplete response, identifying the gateway version, model, backend and
+// output content.
+func (e *Engine) SetProvenance(gatewayVersion string) {
+	sum := sha256.Sum256([]byte(e.config.ModelEndpoint))
+	e.provenance = &provenanceConfig{
+		gatewayVersion: gatewayVersion,
+		endpointHash:   hex.EncodeToString(sum[:]),
+	}
+}
+
+// SetWatermarker installs a watermark hook for output text. It runs on
+// completed and incomplete responses after moderation and response hooks.
+func (e *Engine) SetWatermarker(w Watermarker) {
+	e.watermarker = w
+}
+
+// applyProvenance watermarks the output text and attaches the provenance
+// block. A watermark failure discards the output and fails the response, so
+// that unmarked content is not returned when marking is required.
+func (e *Engine) applyProvenance(ctx context.Context, resp *schema.Response) {
+	if resp.Status != "completed" && resp.Status != "incomplete" {
+		return
+	}
+
+	watermarked := false
+	if e.watermarker != nil {
+		for i := range resp.Output {
+			for j := range resp.Output[i].Content {
+				cp := &resp.Output[i].Content[j]
+				if cp.Type != "output_text" || cp.Text == nil || *cp.Text == "" {
+					continue
+				}
+				marked, err := e.watermarker.Watermark(ctx, *cp.Text)
+				if err != nil {
+					resp.Output = make([]schema.ItemField, 0)
+					resp.MarkFailed(apierror.TypeServerError, "watermark_error", fmt.Sprintf("watermarking failed: %v", err))
+					return
+				}
+				cp.Text = &marked
+				watermarked = true
+			}
+		}
+	}
+
+	if e.provenance == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(strings.Join(outputTextParts(resp.Output), "\n")))
+	resp.Provenance = &schema.ProvenanceField{
+		GatewayVersion:        e.provenance.gatewayVersion,
+		Model:                 resp.Model,
+		BackendEndpointSHA256: e.provenance.endpointHash,
+		GeneratedAt:           time.Now().Unix(),
+		ContentSHA256:         hex.EncodeToString(sum[:]),
+		Watermarked:           watermarked,
+	}
+}
+
+// outputTextParts returns the non-empty output_text parts of output, in order.
+func outputTextParts(output []schema.ItemField) []string {
+	var parts []string
+	for _, item := range output {
+		for _, cp := range item.Content {
+			if cp.Type == "output_text" && cp.Text != nil && *cp.Text != "" {
+				parts = append(parts, *cp.Text)
+			}
+		}
+	}
+	return parts
+}
+
+// convertStoredProvenance converts the stored provenance block, as
+// convertStoredUsage does usage.
+func convertStoredProvenance(stored interface{}) *schema.ProvenanceField {
+	if stored == nil {
+		return nil
+	}
+	if provenance, ok := stored.(*schema.ProvenanceField); ok {
+		return provenance
+	}
+	raw, err := json.Marshal(stored)
+	if err != nil {
+		return nil
+	}
+	var provenance schema.ProvenanceField
+	if err := json.Unmarshal(raw, &provenance); err != nil {
+		return nil
+	}
+	return &provenance
+}
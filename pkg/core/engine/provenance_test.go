@@ -0,0 +1,60 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func TestProvenance_Stored(t *testing.T) {
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	e := &Engine{
+		config:   &config.EngineConfig{ModelEndpoint: "http://backend"},
+		sessions: store,
+		llm:      &benchBackend{deltas: 3},
+		idGen:    ids.NewSequence(),
+	}
+	e.SetProvenance("v1.2.3")
+	ctx := context.Background()
+
+	for _, stream := range []bool{false, true} {
+		req := &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"}
+		var resp *schema.Response
+		if stream {
+			events, err := e.ProcessRequestStream(ctx, req)
+			if err != nil {
+				t.Fatalf("ProcessRequestStream() error = %v", err)
+			}
+			for event := range events {
+				if completed, ok := event.(*schema.ResponseCompletedStreamingEvent); ok {
+					resp = &completed.Response
+				}
+			}
+		} else if resp, err = e.ProcessRequest(ctx, req); err != nil {
+			t.Fatalf("ProcessRequest() error = %v", err)
+		}
+		if resp == nil || resp.Provenance == nil {
+			t.Fatalf("stream=%v: response = %+v, want a provenance block", stream, resp)
+		}
+
+		got, err := e.GetResponse(ctx, resp.ID)
+		if err != nil {
+			t.Fatalf("GetResponse() error = %v", err)
+		}
+		if got.Provenance == nil || *got.Provenance != *resp.Provenance {
+			t.Errorf("stream=%v: retrieved provenance = %+v, want %+v", stream, got.Provenance, resp.Provenance)
+		}
+	}
+}
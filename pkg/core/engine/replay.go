@@ -0,0 +1,455 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/mcp"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// ErrRecordingNotFound is returned by GetRecording for responses that were
+// not recorded.
+var ErrRecordingNotFound = errors.New("recording not found")
+
+// ErrNotReplayable is returned by Replay for recordings it cannot replay.
+var ErrNotReplayable = errors.New("recording cannot be replayed")
+
+// replayOfMetadataKey is the metadata key of replayed responses that holds
+// the ID of the recorded response.
+const replayOfMetadataKey = "replay_of"
+
+// recorder collects the backend calls, MCP tool listings and server-side
+// tool results of the agentic loop of a response, or in replay serves them
+// from a recording. It travels with the context of the loop; a nil
+// recorder records nothing.
+type recorder struct {
+	mu          sync.Mutex
+	recording   schema.ResponseRecording
+	replay      bool
+	backendNext int    // replay: next recorded backend call
+	toolsUsed   []bool // replay: recorded tool calls already served
+	divergences []schema.ReplayDivergence
+}
+
+type recorderKey struct{}
+
+// recorderFrom returns the recorder of ctx, if any.
+func recorderFrom(ctx context.Context) *recorder {
+	r, _ := ctx.Value(recorderKey{}).(*recorder)
+	return r
+}
+
+// replaying reports whether r serves a recording rather than records.
+func (r *recorder) replaying() bool {
+	return r != nil && r.replay
+}
+
+// startRecording returns ctx with a recorder for the response respID to
+// req, if responses are recorded, and the recorder. A replay keeps its own.
+func (e *Engine) startRecording(ctx context.Context, respID string, req *schema.ResponseRequest) (context.Context, *recorder) {
+	if r := recorderFrom(ctx); r != nil {
+		return ctx, r
+	}
+	if e.recordings == nil || !e.config.Recording.Enabled {
+		return ctx, nil
+	}
+	request, err := json.Marshal(req)
+	if err != nil {
+		return ctx, nil
+	}
+	r := &recorder{recording: schema.ResponseRecording{
+		ID:           respID,
+		Object:       "response.recording",
+		Request:      request,
+		BackendCalls: []schema.RecordedBackendCall{},
+		ToolCalls:    []schema.RecordedToolCall{},
+		CreatedAt:    time.Now().Unix(),
+	}}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// saveRecording stores what r recorded for resp. Replays and responses
+// served from the response cache are not stored.
+func (e *Engine) saveRecording(ctx context.Context, r *recorder, resp *schema.Response) {
+	if r == nil || r.replay || resp.CacheHit {
+		return
+	}
+	r.mu.Lock()
+	r.recording.Status = resp.Status
+	data, err := json.Marshal(&r.recording)
+	r.mu.Unlock()
+	if err != nil {
+		return
+	}
+	_ = e.recordings.SaveRecording(context.WithoutCancel(ctx), resp.ID, data)
+}
+
+// GetRecording returns the recording of a response made in record mode.
+func (e *Engine) GetRecording(ctx context.Context, responseID string) (*schema.ResponseRecording, error) {
+	if e.recordings == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRecordingNotFound, responseID)
+	}
+	data, ok, err := e.recordings.GetRecording(ctx, responseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRecordingNotFound, responseID)
+	}
+	var recording schema.ResponseRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("decode recording: %w", err)
+	}
+	return &recording, nil
+}
+
+// Replay runs the agentic loop of a recorded response again, serving the
+// backend calls, MCP tool listings and server-side tool results from the
+// recording, in order, instead of calling the backend or the tools. The
+// replay does not stream and is stored as a new response, with the
+// "replay_of" metadata. Where the loop departs from the recording, such as
+// a backend request that differs from the recorded one, the replay goes on
+// and the difference is reported as a divergence.
+//
+// Responses that continued a conversation are not replayed, as the replay
+// would add to the conversation.
+func (e *Engine) Replay(ctx context.Context, recording *schema.ResponseRecording) (*schema.ResponseReplay, error) {
+	var req schema.ResponseRequest
+	if err := json.Unmarshal(recording.Request, &req); err != nil {
+		return nil, fmt.Errorf("%w: request: %v", ErrNotReplayable, err)
+	}
+	if req.Conversation != nil && *req.Conversation != "" {
+		return nil, fmt.Errorf("%w: it continued conversation %s", ErrNotReplayable, *req.Conversation)
+	}
+	req.Stream = false
+	req.Metadata = maps.Clone(req.Metadata)
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata[replayOfMetadataKey] = recording.ID
+
+	r := &recorder{
+		recording: *recording,
+		replay:    true,
+		toolsUsed: make([]bool, len(recording.ToolCalls)),
+	}
+	resp, err := e.ProcessRequest(context.WithValue(ctx, recorderKey{}, r), &req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if unused := len(r.recording.BackendCalls) - r.backendNext; unused > 0 {
+		r.diverge("backend_call", "%d recorded backend calls were not made", unused)
+	}
+	for i, used := range r.toolsUsed {
+		if !used {
+			tc := r.recording.ToolCalls[i]
+			r.diverge("tool_call", "recorded call %s of tool %s was not made", tc.CallID, tc.Name)
+		}
+	}
+	return &schema.ResponseReplay{
+		Object:      "response.replay",
+		ReplayOf:    recording.ID,
+		Response:    resp,
+		Divergences: append([]schema.ReplayDivergence{}, r.divergences...),
+	}, nil
+}
+
+// diverge reports a divergence of a replay. The caller holds r.mu.
+func (r *recorder) diverge(kind, format string, args ...any) {
+	r.divergences = append(r.divergences, schema.ReplayDivergence{Type: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// --- Backend calls ---
+
+// startBackend records the start of a backend call and returns its index.
+func (r *recorder) startBackend(apiReq *api.ResponsesAPIRequest) int {
+	if r == nil {
+		return 0
+	}
+	request, _ := json.Marshal(apiReq)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording.BackendCalls = append(r.recording.BackendCalls, schema.RecordedBackendCall{Request: request})
+	return len(r.recording.BackendCalls) - 1
+}
+
+// finishBackend records the outcome of backend call i.
+func (r *recorder) finishBackend(i int, resp *api.ResponsesAPIResponse, events []schema.RecordedStreamEvent, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call := &r.recording.BackendCalls[i]
+	if resp != nil {
+		call.Response, _ = json.Marshal(resp)
+	}
+	call.Events = events
+	if err != nil {
+		call.Error = err.Error()
+	}
+}
+
+// recordStream records the events of streaming backend call i as they are
+// forwarded from stream.
+func (r *recorder) recordStream(ctx context.Context, i int, stream <-chan api.ResponsesStreamEvent) <-chan api.ResponsesStreamEvent {
+	out := make(chan api.ResponsesStreamEvent, cap(stream))
+	go func() {
+		defer close(out)
+		var events []schema.RecordedStreamEvent
+		forward := true
+		for evt := range stream {
+			events = append(events, schema.RecordedStreamEvent{Type: evt.Type, Data: evt.Data})
+			if !forward {
+				continue
+			}
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				// Keep recording until the backend stream ends
+				forward = false
+			}
+		}
+		r.finishBackend(i, nil, events, nil)
+	}()
+	return out
+}
+
+// nextBackend returns the next recorded backend call, reporting how
+// apiReq differs from its request.
+func (r *recorder) nextBackend(apiReq *api.ResponsesAPIRequest) (schema.RecordedBackendCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.backendNext + 1
+	if r.backendNext == len(r.recording.BackendCalls) {
+		r.diverge("backend_call", "backend call %d was not recorded", n)
+		return schema.RecordedBackendCall{}, fmt.Errorf("replay: backend call %d was not recorded", n)
+	}
+	call := r.recording.BackendCalls[r.backendNext]
+	r.backendNext++
+	request, _ := json.Marshal(apiReq)
+	if fields := requestDiff(call.Request, request); len(fields) > 0 {
+		r.diverge("backend_request", "backend call %d differs from the recorded request in %v", n, fields)
+	}
+	return call, nil
+}
+
+// replayResponse returns the recorded response of the next backend call.
+// The final response of a streamed call stands for it.
+func (r *recorder) replayResponse(apiReq *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	call, err := r.nextBackend(apiReq)
+	if err != nil {
+		return nil, err
+	}
+	if call.Error != "" {
+		return nil, errors.New(call.Error)
+	}
+	var resp api.ResponsesAPIResponse
+	if len(call.Response) > 0 {
+		if err := json.Unmarshal(call.Response, &resp); err != nil {
+			return nil, fmt.Errorf("replay: recorded response: %w", err)
+		}
+		return &resp, nil
+	}
+	for _, evt := range slices.Backward(call.Events) {
+		if evt.Type != "response.completed" && evt.Type != "response.incomplete" {
+			continue
+		}
+		var wrapper struct {
+			Response api.ResponsesAPIResponse `json:"response"`
+		}
+		if err := json.Unmarshal(evt.Data, &wrapper); err != nil {
+			return nil, fmt.Errorf("replay: recorded %s event: %w", evt.Type, err)
+		}
+		return &wrapper.Response, nil
+	}
+	return nil, fmt.Errorf("replay: recorded stream has no final response")
+}
+
+// replayStream streams the recorded events of the next backend call. A
+// non-streaming call is streamed as its response.completed event.
+func (r *recorder) replayStream(apiReq *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	call, err := r.nextBackend(apiReq)
+	if err != nil {
+		return nil, err
+	}
+	if call.Error != "" {
+		return nil, errors.New(call.Error)
+	}
+	events := call.Events
+	if len(call.Response) > 0 {
+		data, err := json.Marshal(map[string]json.RawMessage{
+			"type":     json.RawMessage(`"response.completed"`),
+			"response": call.Response,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = []schema.RecordedStreamEvent{{Type: "response.completed", Data: data}}
+	}
+	out := make(chan api.ResponsesStreamEvent, len(events))
+	for _, evt := range events {
+		out <- api.ResponsesStreamEvent{Type: evt.Type, Data: evt.Data}
+	}
+	close(out)
+	return out, nil
+}
+
+// requestDiff returns the top-level fields in which two backend requests
+// differ, streaming aside.
+func requestDiff(recorded, replayed json.RawMessage) []string {
+	var a, b map[string]any
+	if json.Unmarshal(recorded, &a) != nil || json.Unmarshal(replayed, &b) != nil {
+		return []string{"request"}
+	}
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	var fields []string
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		if key != "stream" && !reflect.DeepEqual(a[key], b[key]) {
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
+
+// --- MCP tool listings ---
+
+// recordMCPTools records the tools an MCP server listed.
+func (r *recorder) recordMCPTools(serverLabel string, tools []mcp.ToolInfo) {
+	if r == nil {
+		return
+	}
+	data, _ := json.Marshal(tools)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording.MCPTools = append(r.recording.MCPTools, schema.RecordedMCPTools{ServerLabel: serverLabel, Tools: data})
+}
+
+// replayMCPTools returns the tools an MCP server listed when recorded.
+func (r *recorder) replayMCPTools(serverLabel string) ([]mcp.ToolInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, listed := range r.recording.MCPTools {
+		if listed.ServerLabel != serverLabel {
+			continue
+		}
+		var tools []mcp.ToolInfo
+		if err := json.Unmarshal(listed.Tools, &tools); err != nil {
+			return nil, fmt.Errorf("mcp server %q: recorded tools: %w", serverLabel, err)
+		}
+		return tools, nil
+	}
+	r.diverge("mcp_tools", "tools of MCP server %q were not recorded", serverLabel)
+	return nil, fmt.Errorf("mcp server %q: tools were not recorded", serverLabel)
+}
+
+// --- Server-side tool calls ---
+
+// recordTool runs call, the execution of a server-side tool call, through
+// the recorder of ctx: its result is recorded, or in replay the recorded
+// result of the call with the same ID is returned instead.
+func recordTool[T any](ctx context.Context, tc toolCallInfo, call func() (T, error)) (T, error) {
+	r := recorderFrom(ctx)
+	if r.replaying() {
+		return replayTool[T](r, tc)
+	}
+	result, err := call()
+	if r != nil {
+		recorded := schema.RecordedToolCall{CallID: tc.CallID, Name: tc.Name}
+		recorded.Result, _ = json.Marshal(result)
+		if err != nil {
+			recorded.Error = err.Error()
+		}
+		r.mu.Lock()
+		r.recording.ToolCalls = append(r.recording.ToolCalls, recorded)
+		r.mu.Unlock()
+	}
+	return result, err
+}
+
+// replayTool returns the first recorded result of tc not served yet.
+func replayTool[T any](r *recorder, tc toolCallInfo) (T, error) {
+	var result T
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, recorded := range r.recording.ToolCalls {
+		if r.toolsUsed[i] || recorded.CallID != tc.CallID || recorded.Name != tc.Name {
+			continue
+		}
+		r.toolsUsed[i] = true
+		if len(recorded.Result) > 0 {
+			if err := json.Unmarshal(recorded.Result, &result); err != nil {
+				return result, fmt.Errorf("replay: recorded result of tool call %s: %w", tc.CallID, err)
+			}
+		}
+		if recorded.Error != "" {
+			return result, errors.New(recorded.Error)
+		}
+		return result, nil
+	}
+	r.diverge("tool_call", "call %s of tool %s was not recorded", tc.CallID, tc.Name)
+	return result, fmt.Errorf("replay: call %s of tool %s was not recorded", tc.CallID, tc.Name)
+}
+
+// callMCPTool runs an MCP tool call.
+func (e *Engine) callMCPTool(ctx context.Context, client *mcp.Client, tc toolCallInfo) (*mcp.ToolCallResult, error) {
+	return recordTool(ctx, tc, func() (*mcp.ToolCallResult, error) {
+		return client.CallTool(ctx, tc.Name, parseJSONArgs(tc.Arguments))
+	})
+}
+
+// fileSearchOutcome is the recorded result of a file_search call.
+type fileSearchOutcome struct {
+	Output  string                     `json:"output"`
+	Results []vectorstore.SearchResult `json:"results,omitempty"`
+}
+
+// callFileSearch runs a file_search call for query.
+func (e *Engine) callFileSearch(ctx context.Context, cfg fileSearchConfig, tc toolCallInfo, query string) (string, []vectorstore.SearchResult, error) {
+	outcome, err := recordTool(ctx, tc, func() (fileSearchOutcome, error) {
+		output, results, err := e.executeFileSearch(ctx, cfg, query)
+		return fileSearchOutcome{Output: output, Results: results}, err
+	})
+	return outcome.Output, outcome.Results, err
+}
+
+// webSearchOutcome is the recorded result of a web_search or fetch_url
+// call.
+type webSearchOutcome struct {
+	Output  string            `json:"output"`
+	Results []WebSearchResult `json:"results,omitempty"`
+}
+
+// callWebSearchTool runs a web_search or fetch_url call.
+func (e *Engine) callWebSearchTool(ctx context.Context, cfg webSearchConfig, tc toolCallInfo) (string, []WebSearchResult) {
+	outcome, err := recordTool(ctx, tc, func() (webSearchOutcome, error) {
+		output, results := e.executeWebSearchTool(ctx, cfg, tc.Name, tc.Arguments)
+		return webSearchOutcome{Output: output, Results: results}, nil
+	})
+	if err != nil {
+		return toolErrorOutput(err.Error()), nil
+	}
+	return outcome.Output, outcome.Results
+}
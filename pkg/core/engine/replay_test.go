@@ -0,0 +1,219 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// unreachableBackend fails every call, for replays that must not call it.
+type unreachableBackend struct{}
+
+func (unreachableBackend) CreateResponse(context.Context, *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	return nil, errors.New("backend called")
+}
+
+func (unreachableBackend) CreateResponseStream(context.Context, *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	return nil, errors.New("backend called")
+}
+
+func newRecordingEngine(t *testing.T, llm api.ResponsesAPIClient) *Engine {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return &Engine{
+		config:     &config.EngineConfig{Recording: config.RecordingConfig{Enabled: true}},
+		sessions:   store,
+		recordings: store,
+		llm:        llm,
+		idGen:      ids.NewSequence(),
+	}
+}
+
+// outputTexts returns the output_text and function_call_output of output.
+func outputTexts(output []schema.ItemField) []string {
+	var texts []string
+	for _, item := range output {
+		if item.Output != nil {
+			texts = append(texts, *item.Output)
+		}
+		for _, cp := range item.Content {
+			if cp.Type == "output_text" && cp.Text != nil {
+				texts = append(texts, *cp.Text)
+			}
+		}
+	}
+	return texts
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	e := newRecordingEngine(t, &scriptedBackend{streams: [][]map[string]interface{}{
+		{
+			{"type": "response.output_item.added", "output_index": 0, "item": map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_fs", "name": "file_search", "arguments": ""}},
+			{"type": "response.function_call_arguments.delta", "output_index": 0, "item_id": "fc_backend", "delta": `{"query":"pricing"}`},
+			backendCompleted(map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_fs", "name": "file_search", "arguments": `{"query":"pricing"}`}),
+		},
+		{
+			{"type": "response.output_text.delta", "output_index": 0, "item_id": "msg_backend", "delta": "Plans start at $10."},
+			backendCompleted(backendMessage("msg_backend", "Plans start at $10.")),
+		},
+	}})
+	e.vectorSearch = &dummyVectorSearcher{results: []vectorstore.SearchResult{{FileID: "file_1", Content: "Plans start at $10.", Score: 0.9}}}
+	ctx := context.Background()
+
+	events, err := e.ProcessRequestStream(ctx, &schema.ResponseRequest{
+		Model: stringPtr("m"),
+		Input: "hi",
+		Tools: []schema.ResponsesToolParam{{Type: "file_search", VectorStoreIDs: []string{"vs_1"}}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessRequestStream() error = %v", err)
+	}
+	var recorded *schema.Response
+	for event := range events {
+		if completed, ok := event.(*schema.ResponseCompletedStreamingEvent); ok {
+			recorded = &completed.Response
+		}
+	}
+	if recorded == nil {
+		t.Fatal("stream did not complete")
+	}
+
+	recording, err := e.GetRecording(ctx, recorded.ID)
+	if err != nil {
+		t.Fatalf("GetRecording() error = %v", err)
+	}
+	if recording.Status != "completed" || len(recording.BackendCalls) != 2 || len(recording.BackendCalls[0].Events) != 3 {
+		t.Fatalf("recording = %+v, want two streamed backend calls", recording)
+	}
+	if len(recording.ToolCalls) != 1 || recording.ToolCalls[0].CallID != "call_fs" || recording.ToolCalls[0].Name != "file_search" {
+		t.Fatalf("tool calls = %+v", recording.ToolCalls)
+	}
+
+	// The replay calls neither the backend nor the vector store
+	e.llm = unreachableBackend{}
+	e.vectorSearch = &dummyVectorSearcher{err: errors.New("vector store called")}
+	replay, err := e.Replay(ctx, recording)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(replay.Divergences) != 0 {
+		t.Errorf("divergences = %+v, want none", replay.Divergences)
+	}
+	resp := replay.Response
+	if resp.Status != "completed" || resp.ID == recorded.ID || resp.Metadata["replay_of"] != recorded.ID {
+		t.Errorf("replayed response = %s %s %v", resp.ID, resp.Status, resp.Metadata)
+	}
+	if got, want := outputTexts(resp.Output), outputTexts(recorded.Output); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed output = %q, want %q", got, want)
+	}
+
+	// Replays are not recorded themselves
+	if _, err := e.GetRecording(ctx, resp.ID); !errors.Is(err, ErrRecordingNotFound) {
+		t.Errorf("GetRecording(replay) error = %v, want ErrRecordingNotFound", err)
+	}
+}
+
+func TestReplay_Divergences(t *testing.T) {
+	e := newRecordingEngine(t, &benchBackend{})
+	ctx := context.Background()
+	recorded, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	recording, err := e.GetRecording(ctx, recorded.ID)
+	if err != nil {
+		t.Fatalf("GetRecording() error = %v", err)
+	}
+	if len(recording.BackendCalls) != 1 || len(recording.BackendCalls[0].Response) == 0 {
+		t.Fatalf("backend calls = %+v, want one response", recording.BackendCalls)
+	}
+	e.llm = unreachableBackend{}
+
+	tests := []struct {
+		name   string
+		change func(r *schema.ResponseRecording)
+		want   []string // divergence types
+		status string
+	}{
+		{
+			// The recorded response is served all the same
+			name: "changed input",
+			change: func(r *schema.ResponseRecording) {
+				r.Request = json.RawMessage(strings.Replace(string(r.Request), `"hi"`, `"bye"`, 1))
+			},
+			want:   []string{"backend_request"},
+			status: "completed",
+		},
+		{
+			name: "extra backend call",
+			change: func(r *schema.ResponseRecording) {
+				r.BackendCalls = append(r.BackendCalls, r.BackendCalls[0])
+			},
+			want:   []string{"backend_call"},
+			status: "completed",
+		},
+		{
+			name: "missing backend call",
+			change: func(r *schema.ResponseRecording) {
+				r.BackendCalls = nil
+			},
+			want:   []string{"backend_call"},
+			status: "failed",
+		},
+		{
+			name: "unused tool call",
+			change: func(r *schema.ResponseRecording) {
+				r.ToolCalls = []schema.RecordedToolCall{{CallID: "call_1", Name: "web_search"}}
+			},
+			want:   []string{"tool_call"},
+			status: "completed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := *recording
+			changed.BackendCalls = append([]schema.RecordedBackendCall{}, recording.BackendCalls...)
+			tt.change(&changed)
+			replay, err := e.Replay(ctx, &changed)
+			if err != nil {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			var got []string
+			for _, d := range replay.Divergences {
+				got = append(got, d.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("divergences = %+v, want types %v", replay.Divergences, tt.want)
+			}
+			if replay.Response.Status != tt.status {
+				t.Errorf("status = %s, want %s", replay.Response.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestReplay_Conversation(t *testing.T) {
+	e := newRecordingEngine(t, unreachableBackend{})
+	recording := &schema.ResponseRecording{ID: "resp_1", Request: json.RawMessage(`{"model": "m", "input": "hi", "conversation": "conv_1"}`)}
+	if _, err := e.Replay(context.Background(), recording); !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("Replay() error = %v, want ErrNotReplayable", err)
+	}
+}
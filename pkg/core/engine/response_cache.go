@@ -0,0 +1,104 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+)
+
+// cachedResponse is what the response cache stores for a request: the
+// output, and the messages the agentic loop added to the conversation.
+type cachedResponse struct {
+	Output   []schema.ItemField `json:"output"`
+	Messages []api.Message      `json:"messages"`
+}
+
+// responseCacheKey returns the cache key of a request and how long its
+// output may be cached, or "" if the request is not cacheable. Only
+// deterministic requests (temperature 0 or a seed) for a cached model, with
+// no server-side tools, are cacheable. The key hashes the tenant and the
+// backend request the loop would start from, so it covers the conversation
+// history, instructions and every sampling parameter. Replays are not
+// cached, so that they run the loop.
+func (e *Engine) responseCacheKey(ctx context.Context, req *schema.ResponseRequest, model string, messages []api.Message, instructions *string) (string, time.Duration) {
+	if e.responseCache == nil || recorderFrom(ctx).replaying() {
+		return "", 0
+	}
+	if (req.Temperature == nil || *req.Temperature != 0) && req.Seed == nil {
+		return "", 0
+	}
+	for _, t := range req.Tools {
+		if t.Type != "function" {
+			return "", 0
+		}
+	}
+	cfg := e.config.ResponseCache
+	ttl := cfg.TTL
+	if len(cfg.Models) > 0 {
+		modelTTL, ok := cfg.Models[model]
+		if !ok {
+			return "", 0
+		}
+		if modelTTL > 0 {
+			ttl = modelTTL
+		}
+	}
+	if ttl <= 0 {
+		return "", 0
+	}
+
+	apiReq := buildResponsesAPIRequest(model, messages, req, req.Tools, false)
+	apiReq.Instructions = instructions
+	data, err := json.Marshal(struct {
+		Tenant       string                   `json:"tenant"`
+		MaxToolCalls *int                     `json:"max_tool_calls"`
+		Request      *api.ResponsesAPIRequest `json:"request"`
+	}{featureflags.TenantFromContext(ctx), req.MaxToolCalls, apiReq})
+	if err != nil {
+		return "", 0
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), ttl
+}
+
+// lookupResponseCache returns the cached response for key, with fresh item
+// IDs, or nil on a miss. Cache errors and unreadable entries count as
+// misses.
+func (e *Engine) lookupResponseCache(ctx context.Context, key string) *cachedResponse {
+	if key == "" {
+		return nil
+	}
+	data, ok, err := e.responseCache.GetCachedResponse(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	// Items are stored by ID, so each response gets its own
+	for i := range cached.Output {
+		if prefix, _, ok := strings.Cut(cached.Output[i].ID, "_"); ok {
+			cached.Output[i].ID = e.NewID(prefix + "_")
+		}
+	}
+	return &cached
+}
+
+// storeResponseCache caches a completed response under key. Errors are
+// ignored: a failed write only costs a later miss.
+func (e *Engine) storeResponseCache(ctx context.Context, key string, ttl time.Duration, cached *cachedResponse) {
+	if data, err := json.Marshal(cached); err == nil {
+		_ = e.responseCache.PutCachedResponse(ctx, key, data, time.Now().Add(ttl))
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// SetResponseEvents makes the engine record the lifecycle and usage events
+// of responses in the outbox of the session store, with the responses, for
+// a services.EventRelay to publish. Session stores that do not implement
+// state.EventOutbox drop them.
+func (e *Engine) SetResponseEvents(enabled bool) {
+	e.responseEvents = enabled
+}
+
+// eventsFor returns the events to record with a save of resp:
+// response.created if created is set, and once resp finished, its
+// terminal event followed by its usage. The created event describes the
+// response as it started. The events also go to activity subscribers,
+// even when they are not recorded.
+func (e *Engine) eventsFor(ctx context.Context, req *schema.ResponseRequest, resp *schema.Response, conversationID string, created bool) []state.OutboxEvent {
+	live := e.activity.active()
+	if !e.responseEvents && !live {
+		return nil
+	}
+	prevRespID := ""
+	if req.PreviousResponseID != nil {
+		prevRespID = *req.PreviousResponseID
+	}
+	data := eventbus.ResponseEvent{
+		ResponseID:         resp.ID,
+		Status:             resp.Status,
+		Model:              resp.Model,
+		ConversationID:     conversationID,
+		PreviousResponseID: prevRespID,
+		ExternalID:         externalID(req),
+		Tenant:             featureflags.TenantFromContext(ctx),
+		APIKey:             state.APIKeyFromContext(ctx),
+		RequestID:          requestid.FromContext(ctx),
+		Metadata:           resp.Metadata,
+		Usage:              resp.Usage,
+		Error:              resp.Error,
+		IncompleteDetails:  resp.IncompleteDetails,
+		CreatedAt:          resp.CreatedAt,
+		CompletedAt:        resp.CompletedAt,
+	}
+
+	var events []state.OutboxEvent
+	add := func(eventType string, data eventbus.ResponseEvent) {
+		if live {
+			e.activity.publish(ActivityEvent{Type: eventType, Time: time.Now(), Response: data})
+		}
+		if !e.responseEvents {
+			return
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		events = append(events, state.OutboxEvent{
+			ID:         e.NewID("evt_"),
+			Type:       eventType,
+			ResponseID: resp.ID,
+			Data:       raw,
+			CreatedAt:  time.Now(),
+		})
+	}
+	if created {
+		started := data
+		started.Status = "in_progress"
+		started.Usage, started.Error, started.IncompleteDetails, started.CompletedAt = nil, nil, nil, nil
+		add(eventbus.ResponseCreated, started)
+	}
+	var terminal string
+	switch resp.Status {
+	case "completed":
+		terminal = eventbus.ResponseCompleted
+	case "incomplete":
+		terminal = eventbus.ResponseIncomplete
+	case "failed":
+		terminal = eventbus.ResponseFailed
+	default:
+		return events
+	}
+	add(terminal, data)
+	if resp.Usage != nil {
+		add(eventbus.ResponseUsage, data)
+	}
+	return events
+}
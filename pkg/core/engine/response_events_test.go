@@ -0,0 +1,122 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func TestResponseEvents(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	e := &Engine{config: &config.EngineConfig{}, sessions: store, llm: &benchBackend{deltas: 2}, idGen: ids.NewSequence()}
+
+	// Nothing is recorded until enabled
+	if _, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"}); err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	if events, _ := store.ClaimOutboxEvents(ctx, 10, time.Minute); len(events) != 0 {
+		t.Fatalf("recorded %d events while disabled", len(events))
+	}
+	e.SetResponseEvents(true)
+
+	resp, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi", Metadata: map[string]string{"team": "a"}})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	events, err := store.ClaimOutboxEvents(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutboxEvents: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		if event.ResponseID != resp.ID {
+			t.Errorf("%s event of response %s, want %s", event.Type, event.ResponseID, resp.ID)
+		}
+	}
+	if want := []string{eventbus.ResponseCreated, eventbus.ResponseCompleted, eventbus.ResponseUsage}; !slices.Equal(types, want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	var created, completed eventbus.ResponseEvent
+	if err := json.Unmarshal(events[0].Data, &created); err != nil {
+		t.Fatalf("created data: %v", err)
+	}
+	if err := json.Unmarshal(events[1].Data, &completed); err != nil {
+		t.Fatalf("completed data: %v", err)
+	}
+	if created.Status != "in_progress" || created.Usage != nil || created.Model != "m" || created.Metadata["team"] != "a" {
+		t.Errorf("created data = %+v", created)
+	}
+	if completed.Status != "completed" || completed.Usage == nil || completed.Usage.TotalTokens != 11 ||
+		completed.ConversationID != *resp.Conversation || completed.CompletedAt == nil {
+		t.Errorf("completed data = %+v", completed)
+	}
+
+	// A stream records its created event when it starts
+	stream, err := e.ProcessRequestStream(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+	if err != nil {
+		t.Fatalf("ProcessRequestStream() error = %v", err)
+	}
+	for range stream {
+	}
+	events, err = store.ClaimOutboxEvents(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutboxEvents: %v", err)
+	}
+	types = types[:0]
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	if want := []string{eventbus.ResponseCreated, eventbus.ResponseCompleted, eventbus.ResponseUsage}; !slices.Equal(types, want) {
+		t.Errorf("stream event types = %v, want %v", types, want)
+	}
+}
+
+func TestSubscribeActivity(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	defer store.Close()
+	e := &Engine{config: &config.EngineConfig{}, sessions: store, llm: &benchBackend{deltas: 2}, idGen: ids.NewSequence()}
+
+	activity, unsubscribe := e.SubscribeActivity(10)
+	resp, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+	if err != nil {
+		t.Fatalf("ProcessRequest() error = %v", err)
+	}
+	unsubscribe()
+	var types []string
+	for event := range activity {
+		types = append(types, event.Type)
+		if event.Response.ResponseID != resp.ID {
+			t.Errorf("%s event of response %s, want %s", event.Type, event.Response.ResponseID, resp.ID)
+		}
+	}
+	if want := []string{eventbus.ResponseCreated, eventbus.ResponseCompleted, eventbus.ResponseUsage}; !slices.Equal(types, want) {
+		t.Errorf("activity types = %v, want %v", types, want)
+	}
+
+	// Subscribers do not make the engine record events
+	if events, _ := store.ClaimOutboxEvents(ctx, 10, time.Minute); len(events) != 0 {
+		t.Errorf("recorded %d events without SetResponseEvents", len(events))
+	}
+}
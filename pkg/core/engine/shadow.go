@@ -0,0 +1,295 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// shadowTraffic sends the backend calls of a share of responses to a
+// secondary backend as well, in the background, and stores what both
+// backends returned for offline comparison. The secondary backend's output
+// never reaches the client, and its failures and slowness do not affect
+// the response.
+type shadowTraffic struct {
+	llm     api.ResponsesAPIClient
+	store   state.ShadowStore
+	model   string  // model asked of the secondary backend; the request's when empty
+	rate    float64 // share of responses shadowed
+	timeout time.Duration
+	slots   chan struct{} // one per shadow call in flight
+}
+
+func newShadowTraffic(cfg config.ShadowConfig, llm api.ResponsesAPIClient, store state.ShadowStore) *shadowTraffic {
+	return &shadowTraffic{
+		llm:     llm,
+		store:   store,
+		model:   cfg.Model,
+		rate:    cfg.SampleRate,
+		timeout: cfg.Timeout,
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// shadowedResponse numbers the backend calls of a response sent as shadow
+// traffic. It travels with the context of the agentic loop.
+type shadowedResponse struct {
+	id    string
+	calls atomic.Int32
+}
+
+type shadowKey struct{}
+
+// startShadow returns ctx marking the response respID for shadow traffic,
+// if shadow traffic is configured and the response is sampled.
+func (e *Engine) startShadow(ctx context.Context, respID string) context.Context {
+	if e.shadow == nil || rand.Float64() >= e.shadow.rate {
+		return ctx
+	}
+	return context.WithValue(ctx, shadowKey{}, &shadowedResponse{id: respID})
+}
+
+// shadowCall is a backend call sent to the secondary backend. The outcome
+// of the primary call is handed over through primary once known.
+type shadowCall struct {
+	primary chan schema.ShadowOutput
+	start   time.Time
+}
+
+// startShadowCall sends apiReq to the secondary backend in the background
+// if ctx belongs to a response marked for shadow traffic. The call must be
+// finished with the outcome of the primary call; a nil call needs no
+// finishing. Calls sampling several candidates are not shadowed, and calls
+// beyond the concurrency limit are dropped.
+func (e *Engine) startShadowCall(ctx context.Context, apiReq *api.ResponsesAPIRequest, stream bool) *shadowCall {
+	resp, _ := ctx.Value(shadowKey{}).(*shadowedResponse)
+	if resp == nil {
+		return nil
+	}
+	// Dropped calls are counted too, so that indexes match the loop
+	n := int(resp.calls.Add(1)) - 1
+	if candidateCount(apiReq) > 1 {
+		return nil
+	}
+	request, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil
+	}
+	s := e.shadow
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil
+	}
+
+	result := schema.ShadowResult{
+		ID:         e.NewID("shadow_"),
+		Object:     "shadow.result",
+		ResponseID: resp.id,
+		Call:       n,
+		Stream:     stream,
+		Request:    request,
+		CreatedAt:  time.Now().Unix(),
+	}
+	c := &shadowCall{primary: make(chan schema.ShadowOutput, 1), start: time.Now()}
+
+	// The shadow call outlives the request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		result.Shadow = s.call(ctx, request, stream)
+		<-s.slots
+		result.Primary = <-c.primary
+		result.Identical = result.Primary.Status == "completed" && result.Shadow.Status == "completed" &&
+			result.Primary.OutputText == result.Shadow.OutputText
+		data, err := json.Marshal(&result)
+		if err != nil {
+			return
+		}
+		_ = s.store.SaveShadowResult(ctx, &state.ShadowResult{
+			ID:         result.ID,
+			CreatedAt:  time.Unix(result.CreatedAt, 0).UTC(),
+			ResponseID: result.ResponseID,
+			Model:      apiReq.Model,
+			Data:       data,
+		})
+	}()
+	return c
+}
+
+// finish hands the outcome of the primary call over to the shadow call.
+func (c *shadowCall) finish(model string, resp *api.ResponsesAPIResponse, firstToken time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	c.primary <- shadowOutput(model, resp, time.Since(c.start), firstToken, err)
+}
+
+// shadowStream forwards the events of a streaming primary call, finishing
+// c with its outcome once the stream ends.
+func (c *shadowCall) shadowStream(ctx context.Context, model string, stream <-chan api.ResponsesStreamEvent) <-chan api.ResponsesStreamEvent {
+	out := make(chan api.ResponsesStreamEvent, cap(stream))
+	go func() {
+		defer close(out)
+		var consumed streamOutcome
+		forward := true
+		for evt := range stream {
+			consumed.add(evt, c.start)
+			if !forward {
+				continue
+			}
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				// Keep timing the backend until its stream ends
+				forward = false
+			}
+		}
+		resp, err := consumed.result()
+		c.finish(model, resp, consumed.firstToken, err)
+	}()
+	return out
+}
+
+// call sends a backend request to the secondary backend.
+func (s *shadowTraffic) call(ctx context.Context, request []byte, stream bool) schema.ShadowOutput {
+	var apiReq api.ResponsesAPIRequest
+	if err := json.Unmarshal(request, &apiReq); err != nil {
+		return shadowOutput("", nil, 0, 0, err)
+	}
+	if s.model != "" {
+		apiReq.Model = s.model
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if !stream {
+		resp, err := s.llm.CreateResponse(ctx, &apiReq)
+		return shadowOutput(apiReq.Model, resp, time.Since(start), 0, err)
+	}
+	events, err := s.llm.CreateResponseStream(ctx, &apiReq)
+	if err != nil {
+		return shadowOutput(apiReq.Model, nil, time.Since(start), 0, err)
+	}
+	var consumed streamOutcome
+	for evt := range events {
+		consumed.add(evt, start)
+	}
+	resp, err := consumed.result()
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return shadowOutput(apiReq.Model, resp, time.Since(start), consumed.firstToken, err)
+}
+
+// streamOutcome follows a backend stream for its final response and the
+// time to its first delta.
+type streamOutcome struct {
+	firstToken time.Duration
+	final      *api.ResponsesAPIResponse
+	err        error
+}
+
+func (o *streamOutcome) add(evt api.ResponsesStreamEvent, start time.Time) {
+	if o.firstToken == 0 && strings.HasSuffix(evt.Type, ".delta") {
+		o.firstToken = max(time.Since(start), time.Nanosecond)
+	}
+	switch evt.Type {
+	case "response.completed", "response.incomplete", "response.failed", "error":
+	default:
+		return
+	}
+	var ev backendEvent
+	if err := json.Unmarshal(evt.Data, &ev); err != nil {
+		o.err = err
+		return
+	}
+	if evt.Type == "error" {
+		o.err = errors.New(ev.Message)
+		return
+	}
+	o.final = &ev.Response
+}
+
+// result returns the final response of the stream, or why there is none.
+func (o *streamOutcome) result() (*api.ResponsesAPIResponse, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	if o.final == nil {
+		return nil, errors.New("stream ended without a final response")
+	}
+	return o.final, nil
+}
+
+// shadowOutput summarizes what a backend returned for a call.
+func shadowOutput(model string, resp *api.ResponsesAPIResponse, latency, firstToken time.Duration, err error) schema.ShadowOutput {
+	out := schema.ShadowOutput{Model: model, Status: "failed", LatencyMs: latency.Milliseconds()}
+	if firstToken > 0 {
+		ms := firstToken.Milliseconds()
+		out.FirstTokenMs = &ms
+	}
+	if err != nil {
+		msg := err.Error()
+		out.Error = &msg
+		return out
+	}
+	out.Status = resp.Status
+	if resp.Model != "" {
+		out.Model = resp.Model
+	}
+	out.Output, _ = json.Marshal(resp.Output)
+	var text strings.Builder
+	for _, item := range resp.Output {
+		for _, c := range item.Content {
+			if c.Type == "output_text" {
+				text.WriteString(c.Text)
+			}
+		}
+	}
+	out.OutputText = text.String()
+	if resp.Usage != nil {
+		out.InputTokens = resp.Usage.InputTokens
+		out.OutputTokens = resp.Usage.OutputTokens
+	}
+	return out
+}
+
+// ErrShadowUnsupported is returned by ListShadowResults when the session
+// store cannot hold shadow results.
+var ErrShadowUnsupported = errors.New("session store does not support shadow traffic")
+
+// ListShadowResults returns the stored results of shadow traffic, newest
+// first. after is the ID of the last result of the previous page.
+func (e *Engine) ListShadowResults(ctx context.Context, filter state.ShadowFilter, after string, limit int) ([]schema.ShadowResult, bool, error) {
+	store, ok := e.sessions.(state.ShadowStore)
+	if !ok {
+		return nil, false, ErrShadowUnsupported
+	}
+	stored, hasMore, err := store.ListShadowResults(ctx, filter, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	results := make([]schema.ShadowResult, 0, len(stored))
+	for _, r := range stored {
+		var result schema.ShadowResult
+		if err := json.Unmarshal(r.Data, &result); err != nil {
+			return nil, false, fmt.Errorf("decode shadow result %s: %w", r.ID, err)
+		}
+		results = append(results, result)
+	}
+	return results, hasMore, nil
+}
@@ -0,0 +1,156 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func newShadowEngine(t *testing.T, primary, shadow api.ResponsesAPIClient, maxConcurrent int) *Engine {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	cfg := config.ShadowConfig{Model: "m-next", SampleRate: 1, MaxConcurrent: maxConcurrent, Timeout: time.Second}
+	return &Engine{
+		config:   &config.EngineConfig{},
+		sessions: store,
+		llm:      primary,
+		idGen:    ids.NewSequence(),
+		shadow:   newShadowTraffic(cfg, shadow, store),
+	}
+}
+
+// waitForShadowResults polls the shadow results of a response until there
+// are want of them or a second has passed.
+func waitForShadowResults(t *testing.T, e *Engine, responseID string, want int) []schema.ShadowResult {
+	t.Helper()
+	var results []schema.ShadowResult
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		var err error
+		results, _, err = e.ListShadowResults(context.Background(), state.ShadowFilter{ResponseID: responseID}, "", 10)
+		if err != nil {
+			t.Fatalf("ListShadowResults() error = %v", err)
+		}
+		if len(results) >= want {
+			break
+		}
+	}
+	return results
+}
+
+func TestShadowTraffic(t *testing.T) {
+	tests := []struct {
+		name          string
+		stream        bool
+		shadow        api.ResponsesAPIClient
+		wantStatus    string
+		wantIdentical bool
+	}{
+		{
+			name:          "non-streaming",
+			shadow:        &benchBackend{},
+			wantStatus:    "completed",
+			wantIdentical: true,
+		},
+		{
+			name:          "streaming",
+			stream:        true,
+			shadow:        &benchBackend{deltas: 3},
+			wantStatus:    "completed",
+			wantIdentical: true,
+		},
+		{
+			// The response does not depend on the secondary backend
+			name:       "failing shadow backend",
+			stream:     true,
+			shadow:     unreachableBackend{},
+			wantStatus: "failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newShadowEngine(t, &benchBackend{deltas: 3}, tt.shadow, 4)
+			ctx := context.Background()
+			req := &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"}
+
+			var resp *schema.Response
+			if tt.stream {
+				events, err := e.ProcessRequestStream(ctx, req)
+				if err != nil {
+					t.Fatalf("ProcessRequestStream() error = %v", err)
+				}
+				for event := range events {
+					if completed, ok := event.(*schema.ResponseCompletedStreamingEvent); ok {
+						resp = &completed.Response
+					}
+				}
+				if resp == nil {
+					t.Fatal("stream did not complete")
+				}
+			} else {
+				var err error
+				if resp, err = e.ProcessRequest(ctx, req); err != nil {
+					t.Fatalf("ProcessRequest() error = %v", err)
+				}
+			}
+			if resp.Status != "completed" {
+				t.Fatalf("response status = %s, want completed", resp.Status)
+			}
+
+			results := waitForShadowResults(t, e, resp.ID, 1)
+			if len(results) != 1 {
+				t.Fatalf("shadow results = %+v, want one", results)
+			}
+			got := results[0]
+			if got.Call != 0 || got.Stream != tt.stream || len(got.Request) == 0 {
+				t.Errorf("shadow result = call %d, stream %v, request %s", got.Call, got.Stream, got.Request)
+			}
+			if got.Primary.Status != "completed" || got.Primary.Model != "m" || got.Primary.OutputText == "" {
+				t.Errorf("primary = %+v", got.Primary)
+			}
+			if got.Shadow.Status != tt.wantStatus || got.Shadow.Model != "m-next" {
+				t.Errorf("shadow = %+v, want status %s of m-next", got.Shadow, tt.wantStatus)
+			}
+			if (got.Shadow.Error != nil) != (tt.wantStatus == "failed") {
+				t.Errorf("shadow error = %v", got.Shadow.Error)
+			}
+			if tt.stream && got.Primary.FirstTokenMs == nil {
+				t.Error("primary first_token_ms is not set for a streamed call")
+			}
+			if got.Identical != tt.wantIdentical {
+				t.Errorf("identical = %v, want %v", got.Identical, tt.wantIdentical)
+			}
+		})
+	}
+}
+
+func TestShadowTraffic_Dropped(t *testing.T) {
+	e := newShadowEngine(t, &benchBackend{}, &benchBackend{}, 1)
+	ctx := context.Background()
+
+	// A call beyond the concurrency limit is not shadowed
+	e.shadow.slots <- struct{}{}
+	resp, err := e.ProcessRequest(ctx, &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+	if err != nil || resp.Status != "completed" {
+		t.Fatalf("ProcessRequest() = %v, %v", resp, err)
+	}
+	<-e.shadow.slots
+	time.Sleep(50 * time.Millisecond)
+	if results := waitForShadowResults(t, e, resp.ID, 0); len(results) != 0 {
+		t.Errorf("shadow results = %+v, want none", results)
+	}
+}
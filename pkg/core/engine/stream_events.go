@@ -0,0 +1,726 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// eventStream sends the events of a streamed response to the client. Every
+// event gets the next sequence_number and every output item the next
+// output_index, so that the numbering stays contiguous across the backend
+// calls of the agentic loop, as the OpenAI SDK expects.
+//
+// When the client reads slower than the backend writes, the overflow policy
+// decides what happens to deltas that find the channel full: "block" waits,
+// "merge" holds them and appends the following deltas of the same item,
+// "drop" discards them. Deltas are only numbered once they are sent.
+type eventStream struct {
+	out      chan<- interface{}
+	respID   string
+	seq      int
+	items    int            // output items announced so far
+	indexes  map[string]int // item ID → output_index
+	overflow string
+	pending  interface{} // merged delta not sent yet
+	stats    *streamStats
+	stalled  bool // a send of this stream has blocked
+}
+
+func newEventStream(out chan<- interface{}, respID string) *eventStream {
+	return &eventStream{out: out, respID: respID, indexes: make(map[string]int), stats: &streamStats{}}
+}
+
+// send numbers and sends an event, after the pending merged delta.
+func (s *eventStream) send(event interface{}) {
+	s.flush()
+	s.push(event)
+}
+
+// sendDelta sends a text, reasoning or arguments delta according to the
+// overflow policy.
+func (s *eventStream) sendDelta(event interface{}) {
+	switch s.overflow {
+	case "merge":
+		if s.pending != nil {
+			if mergeDelta(s.pending, event) {
+				s.stats.merged.Add(1)
+			} else {
+				s.flush()
+				s.pending = event
+			}
+		} else {
+			s.pending = event
+		}
+		if s.tryPush(s.pending) {
+			s.pending = nil
+		}
+	case "drop":
+		if !s.tryPush(event) {
+			s.stats.dropped.Add(1)
+		}
+	default:
+		s.send(event)
+	}
+}
+
+// flush sends the pending merged delta, waiting for the client.
+func (s *eventStream) flush() {
+	if s.pending != nil {
+		s.push(s.pending)
+		s.pending = nil
+	}
+}
+
+// push numbers and sends an event, waiting for the client if the channel
+// is full.
+func (s *eventStream) push(event interface{}) {
+	if s.tryPush(event) {
+		return
+	}
+	if !s.stalled {
+		s.stalled = true
+		s.stats.stalledStreams.Add(1)
+	}
+	s.stats.stalls.Add(1)
+	start := time.Now()
+	schema.SetSequenceNumber(event, s.seq)
+	s.seq++
+	s.out <- event
+	s.stats.blocked.Add(int64(time.Since(start)))
+}
+
+// tryPush numbers and sends an event if the channel has room.
+func (s *eventStream) tryPush(event interface{}) bool {
+	schema.SetSequenceNumber(event, s.seq)
+	select {
+	case s.out <- event:
+		s.seq++
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeDelta appends the delta of src to dst when both are deltas of the
+// same type for the same item.
+func mergeDelta(dst, src interface{}) bool {
+	switch d := dst.(type) {
+	case *schema.ResponseOutputTextDeltaStreamingEvent:
+		e, ok := src.(*schema.ResponseOutputTextDeltaStreamingEvent)
+		if !ok || e.ItemID != d.ItemID || e.ContentIndex != d.ContentIndex {
+			return false
+		}
+		d.Delta += e.Delta
+		d.Logprobs = append(d.Logprobs, e.Logprobs...)
+	case *schema.ResponseRefusalDeltaStreamingEvent:
+		e, ok := src.(*schema.ResponseRefusalDeltaStreamingEvent)
+		if !ok || e.ItemID != d.ItemID || e.ContentIndex != d.ContentIndex {
+			return false
+		}
+		d.Delta += e.Delta
+	case *schema.ResponseReasoningDeltaStreamingEvent:
+		e, ok := src.(*schema.ResponseReasoningDeltaStreamingEvent)
+		if !ok || e.ItemID != d.ItemID {
+			return false
+		}
+		d.Delta += e.Delta
+	case *schema.ResponseReasoningSummaryDeltaStreamingEvent:
+		e, ok := src.(*schema.ResponseReasoningSummaryDeltaStreamingEvent)
+		if !ok || e.ItemID != d.ItemID {
+			return false
+		}
+		d.Delta += e.Delta
+	case *schema.ResponseFunctionCallArgumentsDeltaStreamingEvent:
+		e, ok := src.(*schema.ResponseFunctionCallArgumentsDeltaStreamingEvent)
+		if !ok || e.ItemID != d.ItemID {
+			return false
+		}
+		d.Delta += e.Delta
+	default:
+		return false
+	}
+	return true
+}
+
+// streamStats counts how often streams waited for slow clients since the
+// gateway started.
+type streamStats struct {
+	streams        atomic.Int64
+	stalledStreams atomic.Int64
+	stalls         atomic.Int64
+	blocked        atomic.Int64 // nanoseconds
+	merged         atomic.Int64
+	dropped        atomic.Int64
+}
+
+// StreamStats reports the backpressure of streamed responses since the
+// gateway started.
+func (e *Engine) StreamStats() schema.StreamStatsResponse {
+	return schema.StreamStatsResponse{
+		Object:         "stream_stats",
+		Overflow:       e.streamOverflow(),
+		Buffer:         e.streamBuffer(),
+		Streams:        e.streamStats.streams.Load(),
+		StalledStreams: e.streamStats.stalledStreams.Load(),
+		Stalls:         e.streamStats.stalls.Load(),
+		BlockedMS:      time.Duration(e.streamStats.blocked.Load()).Milliseconds(),
+		MergedDeltas:   e.streamStats.merged.Load(),
+		DroppedDeltas:  e.streamStats.dropped.Load(),
+	}
+}
+
+// streamBuffer returns the number of events buffered per stream.
+func (e *Engine) streamBuffer() int {
+	if e.config.Streaming.Buffer > 0 {
+		return e.config.Streaming.Buffer
+	}
+	return 10
+}
+
+// streamOverflow returns the overflow policy of streams.
+func (e *Engine) streamOverflow() string {
+	if e.config.Streaming.Overflow != "" {
+		return e.config.Streaming.Overflow
+	}
+	return "block"
+}
+
+// addItem announces an output item with response.output_item.added and
+// returns its output_index.
+func (s *eventStream) addItem(item schema.ItemField) int {
+	index := s.items
+	s.items++
+	s.indexes[item.ID] = index
+	s.send(&schema.ResponseOutputItemAddedStreamingEvent{
+		Type:        "response.output_item.added",
+		OutputIndex: index,
+		Item:        item,
+	})
+	return index
+}
+
+// nextIndex returns the output_index the next announced item will get.
+func (s *eventStream) nextIndex() int {
+	return s.items
+}
+
+// indexOf returns the output_index of an announced item.
+func (s *eventStream) indexOf(itemID string) (int, bool) {
+	index, ok := s.indexes[itemID]
+	return index, ok
+}
+
+// fail sends an error event.
+func (s *eventStream) fail(errType, code, message string) {
+	s.send(&schema.ErrorStreamingEvent{
+		Type:  "error",
+		Error: schema.ErrorField{Type: errType, Code: &code, Message: message},
+	})
+}
+
+// addDoneItem announces an item that is already complete, such as a tool
+// output: output_item.added immediately followed by output_item.done.
+func (s *eventStream) addDoneItem(item schema.ItemField) {
+	index := s.addItem(item)
+	s.itemDone(item, index)
+}
+
+// toolCallFailed streams the item of a failed server-side tool call:
+// output_item.added, the failed event of its type and output_item.done.
+func (s *eventStream) toolCallFailed(item schema.ItemField) {
+	index := s.addItem(item)
+	switch item.Type {
+	case "mcp_call":
+		s.send(&schema.ResponseMCPCallFailedStreamingEvent{
+			Type:        "response.mcp_call.failed",
+			OutputIndex: index,
+			ItemID:      item.ID,
+		})
+	case "file_search_call":
+		s.send(&schema.ResponseFileSearchCallFailedStreamingEvent{
+			Type:        "response.file_search_call.failed",
+			OutputIndex: index,
+			ItemID:      item.ID,
+		})
+	}
+	s.itemDone(item, index)
+}
+
+// itemDone sends response.output_item.done.
+func (s *eventStream) itemDone(item schema.ItemField, index int) {
+	s.send(&schema.ResponseOutputItemDoneStreamingEvent{
+		Type:        "response.output_item.done",
+		OutputIndex: index,
+		Item:        item,
+	})
+}
+
+// refusal streams a refusal message item: output_item.added,
+// content_part.added, refusal.delta, refusal.done, content_part.done and
+// output_item.done.
+func (s *eventStream) refusal(item schema.ItemField) {
+	refusal := *item.Content[0].Refusal
+	emptyRefusal := ""
+	inProgress := "in_progress"
+
+	added := item
+	added.Status = &inProgress
+	added.Content = make([]schema.ContentPart, 0)
+	index := s.addItem(added)
+
+	s.send(&schema.ResponseContentPartAddedStreamingEvent{
+		Type:        "response.content_part.added",
+		ItemID:      item.ID,
+		OutputIndex: index,
+		Part:        schema.ContentPart{Type: "refusal", Refusal: &emptyRefusal},
+	})
+	s.send(&schema.ResponseRefusalDeltaStreamingEvent{
+		Type:        "response.refusal.delta",
+		ResponseID:  s.respID,
+		ItemID:      item.ID,
+		OutputIndex: index,
+		Delta:       refusal,
+	})
+	s.send(&schema.ResponseRefusalDoneStreamingEvent{
+		Type:        "response.refusal.done",
+		ResponseID:  s.respID,
+		ItemID:      item.ID,
+		OutputIndex: index,
+		Refusal:     refusal,
+	})
+	s.send(&schema.ResponseContentPartDoneStreamingEvent{
+		Type:        "response.content_part.done",
+		ItemID:      item.ID,
+		OutputIndex: index,
+		Part:        item.Content[0],
+	})
+	s.itemDone(item, index)
+}
+
+// reasoningDone sends response.reasoning.done and
+// response.reasoning_summary.done for a streamed reasoning item, when it
+// has text and a summary, followed by its response.output_item.done.
+func (s *eventStream) reasoningDone(item schema.ItemField, index int) {
+	var text string
+	for _, part := range item.Content {
+		if part.Text != nil {
+			text += *part.Text
+		}
+	}
+	if text != "" {
+		s.send(&schema.ResponseReasoningDoneStreamingEvent{
+			Type:        "response.reasoning.done",
+			ResponseID:  s.respID,
+			ItemID:      item.ID,
+			OutputIndex: index,
+			Reasoning:   text,
+		})
+	}
+
+	var summary []string
+	for _, part := range item.Summary {
+		if part.Text != nil {
+			summary = append(summary, *part.Text)
+		}
+	}
+	if len(summary) > 0 {
+		s.send(&schema.ResponseReasoningSummaryDoneStreamingEvent{
+			Type:        "response.reasoning_summary.done",
+			ResponseID:  s.respID,
+			ItemID:      item.ID,
+			OutputIndex: index,
+			Summary:     strings.Join(summary, "\n\n"),
+		})
+	}
+	s.itemDone(item, index)
+}
+
+// functionCallDone sends response.function_call_arguments.done for a
+// streamed function call item followed by its response.output_item.done.
+func (s *eventStream) functionCallDone(item schema.ItemField, index int) {
+	done := &schema.ResponseFunctionCallArgumentsDoneStreamingEvent{
+		Type:        "response.function_call_arguments.done",
+		ResponseID:  s.respID,
+		ItemID:      item.ID,
+		OutputIndex: index,
+	}
+	if item.Name != nil {
+		done.Name = *item.Name
+	}
+	if item.Arguments != nil {
+		done.Arguments = *item.Arguments
+	}
+	s.send(done)
+	s.itemDone(item, index)
+}
+
+// backendEvent holds the fields of the backend streaming events the gateway
+// reads. Each event only sets the fields of its type.
+type backendEvent struct {
+	OutputIndex  int                      `json:"output_index"`
+	ItemID       string                   `json:"item_id"`
+	SummaryIndex int                      `json:"summary_index"`
+	Delta        string                   `json:"delta"`
+	Logprobs     []interface{}            `json:"logprobs"`
+	Item         api.OutputItem           `json:"item"`
+	Response     api.ResponsesAPIResponse `json:"response"`
+
+	// Error events
+	Code    *string `json:"code"`
+	Message string  `json:"message"`
+}
+
+// streamedItem is an output item of the backend announced to the client.
+type streamedItem struct {
+	id    string
+	typ   string
+	index int // output_index in the gateway's stream
+
+	// Message content: a single output_text or refusal part
+	partType string
+	text     strings.Builder
+	logprobs []interface{}
+
+	// Reasoning
+	reasoning strings.Builder
+	summary   []string
+
+	// Function call
+	arguments strings.Builder
+}
+
+// backendTranslator turns the events of one backend stream into the
+// gateway's own typed events. Backends number output items per call and
+// differ in the lifecycle events they send, so the gateway parses the
+// deltas, announces each item itself, and closes every item it announced
+// once the backend is done, in output order.
+type backendTranslator struct {
+	e      *Engine
+	stream *eventStream
+
+	items    map[int]*streamedItem  // backend output_index → item
+	declared map[int]api.OutputItem // items the backend announced, by backend output_index
+	ev       backendEvent           // the event being handled, reused to save an allocation per delta
+
+	// Set from the backend's terminal event
+	output     []api.OutputItem
+	usage      *api.UsageInfo
+	incomplete string // incomplete_details.reason
+	failure    *streamFailure
+}
+
+// streamFailure is the error a backend stream ended with.
+type streamFailure struct {
+	code    string
+	message string
+}
+
+func newBackendTranslator(e *Engine, stream *eventStream) *backendTranslator {
+	return &backendTranslator{
+		e:        e,
+		stream:   stream,
+		items:    make(map[int]*streamedItem),
+		declared: make(map[int]api.OutputItem),
+	}
+}
+
+// handle translates a backend event. Events the gateway does not know are
+// dropped rather than forwarded, so that clients only see events in the
+// gateway's numbering.
+func (t *backendTranslator) handle(evt api.ResponsesStreamEvent) {
+	ev := &t.ev
+	*ev = backendEvent{}
+	if err := json.Unmarshal(evt.Data, ev); err != nil {
+		return
+	}
+
+	switch evt.Type {
+	case "response.completed":
+		t.output = ev.Response.Output
+		t.usage = ev.Response.Usage
+
+	case "response.incomplete":
+		t.output = ev.Response.Output
+		t.usage = ev.Response.Usage
+		t.incomplete = "max_output_tokens"
+		if details, ok := ev.Response.IncompleteDetails.(map[string]interface{}); ok {
+			if reason, ok := details["reason"].(string); ok && reason != "" {
+				t.incomplete = reason
+			}
+		}
+
+	case "response.failed":
+		t.failure = &streamFailure{code: apierror.CodeBackendError, message: "backend response failed"}
+		if details, ok := ev.Response.Error.(map[string]interface{}); ok {
+			if code, ok := details["code"].(string); ok && code != "" {
+				t.failure.code = code
+			}
+			if message, ok := details["message"].(string); ok && message != "" {
+				t.failure.message = message
+			}
+		}
+
+	case "error":
+		t.failure = &streamFailure{code: apierror.CodeBackendError, message: ev.Message}
+		if ev.Code != nil && *ev.Code != "" {
+			t.failure.code = *ev.Code
+		}
+
+	case "response.output_item.added", "response.output_item.done":
+		// Only used to name the function calls the gateway announces
+		t.declared[ev.OutputIndex] = ev.Item
+
+	case "response.output_text.delta", "response.refusal.delta":
+		partType := "output_text"
+		if evt.Type == "response.refusal.delta" {
+			partType = "refusal"
+		}
+		item := t.announce(ev.OutputIndex, ev.ItemID, "message")
+		if item.partType == "" {
+			item.partType = partType
+			empty := ""
+			part := schema.ContentPart{Type: partType, Refusal: &empty}
+			if partType == "output_text" {
+				part = schema.ContentPart{Type: partType, Text: &empty, Annotations: make([]schema.Annotation, 0)}
+			}
+			t.stream.send(&schema.ResponseContentPartAddedStreamingEvent{
+				Type:        "response.content_part.added",
+				ItemID:      item.id,
+				OutputIndex: item.index,
+				Part:        part,
+			})
+		}
+		item.text.WriteString(ev.Delta)
+		if partType == "refusal" {
+			t.stream.sendDelta(&schema.ResponseRefusalDeltaStreamingEvent{
+				Type:        evt.Type,
+				ResponseID:  t.stream.respID,
+				ItemID:      item.id,
+				OutputIndex: item.index,
+				Delta:       ev.Delta,
+			})
+			return
+		}
+		item.logprobs = append(item.logprobs, ev.Logprobs...)
+		logprobs := ev.Logprobs
+		if logprobs == nil {
+			logprobs = make([]interface{}, 0)
+		}
+		t.stream.sendDelta(&schema.ResponseOutputTextDeltaStreamingEvent{
+			Type:        evt.Type,
+			ItemID:      item.id,
+			OutputIndex: item.index,
+			Delta:       ev.Delta,
+			Logprobs:    logprobs,
+		})
+
+	case "response.reasoning_text.delta", "response.reasoning.delta":
+		item := t.announce(ev.OutputIndex, ev.ItemID, "reasoning")
+		item.reasoning.WriteString(ev.Delta)
+		t.stream.sendDelta(&schema.ResponseReasoningDeltaStreamingEvent{
+			Type:        "response.reasoning.delta",
+			ResponseID:  t.stream.respID,
+			ItemID:      item.id,
+			OutputIndex: item.index,
+			Delta:       ev.Delta,
+		})
+
+	case "response.reasoning_summary_text.delta":
+		if ev.SummaryIndex < 0 {
+			return
+		}
+		item := t.announce(ev.OutputIndex, ev.ItemID, "reasoning")
+		for len(item.summary) <= ev.SummaryIndex {
+			item.summary = append(item.summary, "")
+		}
+		item.summary[ev.SummaryIndex] += ev.Delta
+		t.stream.sendDelta(&schema.ResponseReasoningSummaryDeltaStreamingEvent{
+			Type:        "response.reasoning_summary.delta",
+			ResponseID:  t.stream.respID,
+			ItemID:      item.id,
+			OutputIndex: item.index,
+			Delta:       ev.Delta,
+		})
+
+	case "response.function_call_arguments.delta":
+		item := t.announce(ev.OutputIndex, ev.ItemID, "function_call")
+		item.arguments.WriteString(ev.Delta)
+		t.stream.sendDelta(&schema.ResponseFunctionCallArgumentsDeltaStreamingEvent{
+			Type:        evt.Type,
+			ResponseID:  t.stream.respID,
+			ItemID:      item.id,
+			OutputIndex: item.index,
+			Delta:       ev.Delta,
+		})
+	}
+}
+
+// announce returns the item at the backend's outputIndex, announcing it
+// with response.output_item.added the first time.
+func (t *backendTranslator) announce(outputIndex int, itemID, typ string) *streamedItem {
+	if item, ok := t.items[outputIndex]; ok {
+		return item
+	}
+	if itemID == "" {
+		switch typ {
+		case "function_call":
+			itemID = t.e.NewID("fc_")
+		case "reasoning":
+			itemID = t.e.NewID("rs_")
+		default:
+			itemID = t.e.NewID("msg_")
+		}
+	}
+
+	added := schema.ItemField{Type: typ, ID: itemID}
+	switch typ {
+	case "message":
+		role, status := "assistant", "in_progress"
+		added.Role, added.Status = &role, &status
+		added.Content = make([]schema.ContentPart, 0)
+	case "reasoning":
+		added.Content = make([]schema.ContentPart, 0)
+		added.Summary = make([]schema.ContentPart, 0)
+	case "function_call":
+		status, arguments := "in_progress", ""
+		added.Status, added.Arguments = &status, &arguments
+		if declared := t.declared[outputIndex]; declared.Type == "function_call" {
+			added.Name, added.CallID = &declared.Name, &declared.CallID
+		}
+	}
+
+	item := &streamedItem{id: itemID, typ: typ}
+	item.index = t.stream.addItem(added)
+	t.items[outputIndex] = item
+	return item
+}
+
+// finish closes every item of the backend call, in the backend's output
+// order. Reasoning items and function calls the backend returned without
+// streaming them are announced first.
+func (t *backendTranslator) finish() {
+	for i, out := range t.output {
+		if out.Type == "reasoning" || out.Type == "function_call" {
+			t.declared[i] = out
+			t.announce(i, out.ID, out.Type)
+		}
+	}
+
+	for _, outputIndex := range slices.Sorted(maps.Keys(t.items)) {
+		item := t.items[outputIndex]
+		var out *api.OutputItem
+		if outputIndex < len(t.output) && t.output[outputIndex].Type == item.typ {
+			out = &t.output[outputIndex]
+		}
+
+		switch item.typ {
+		case "message":
+			msg := item.message()
+			if out != nil {
+				msg.CandidateIndex = out.CandidateIndex
+			}
+			part := msg.Content[0]
+			if item.partType == "refusal" {
+				t.stream.send(&schema.ResponseRefusalDoneStreamingEvent{
+					Type:        "response.refusal.done",
+					ResponseID:  t.stream.respID,
+					ItemID:      item.id,
+					OutputIndex: item.index,
+					Refusal:     item.text.String(),
+				})
+			} else {
+				t.stream.send(&schema.ResponseOutputTextDoneStreamingEvent{
+					Type:        "response.output_text.done",
+					ItemID:      item.id,
+					OutputIndex: item.index,
+					Text:        item.text.String(),
+					Logprobs:    part.Logprobs,
+				})
+			}
+			t.stream.send(&schema.ResponseContentPartDoneStreamingEvent{
+				Type:        "response.content_part.done",
+				ItemID:      item.id,
+				OutputIndex: item.index,
+				Part:        part,
+			})
+			t.stream.itemDone(msg, item.index)
+
+		case "reasoning":
+			var reasoning schema.ItemField
+			if out != nil {
+				reasoning = reasoningItemField(*out)
+			} else {
+				reasoning = streamedReasoningItem(item.reasoning.String(), item.summary)
+			}
+			reasoning.ID = item.id
+			t.stream.reasoningDone(reasoning, item.index)
+
+		case "function_call":
+			t.stream.functionCallDone(streamedFunctionCall(t.output, outputIndex, item.id, item.arguments.String()), item.index)
+		}
+	}
+}
+
+// messages returns the message items streamed so far, in output order.
+func (t *backendTranslator) messages() []schema.ItemField {
+	var out []schema.ItemField
+	for _, outputIndex := range slices.Sorted(maps.Keys(t.items)) {
+		if item := t.items[outputIndex]; item.typ == "message" {
+			out = append(out, item.message())
+		}
+	}
+	return out
+}
+
+// callItemIDs returns the item IDs the function calls were streamed under,
+// by call ID.
+func (t *backendTranslator) callItemIDs() map[string]string {
+	ids := make(map[string]string)
+	for outputIndex, item := range t.items {
+		if item.typ != "function_call" {
+			continue
+		}
+		call := streamedFunctionCall(t.output, outputIndex, item.id, item.arguments.String())
+		if call.CallID != nil {
+			ids[*call.CallID] = item.id
+		}
+	}
+	return ids
+}
+
+// message returns the completed message item of the streamed text.
+func (item *streamedItem) message() schema.ItemField {
+	role, status := "assistant", "completed"
+	text := item.text.String()
+	part := schema.ContentPart{Type: "refusal", Refusal: &text}
+	if item.partType != "refusal" {
+		logprobs := item.logprobs
+		if logprobs == nil {
+			logprobs = make([]interface{}, 0)
+		}
+		part = schema.ContentPart{
+			Type:        "output_text",
+			Text:        &text,
+			Annotations: make([]schema.Annotation, 0),
+			Logprobs:    logprobs,
+		}
+	}
+	return schema.ItemField{
+		Type:    "message",
+		ID:      item.id,
+		Role:    &role,
+		Status:  &status,
+		Content: []schema.ContentPart{part},
+	}
+}
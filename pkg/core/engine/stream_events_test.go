@@ -0,0 +1,382 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+	"github.com/leseb/openresponses-gw/pkg/ssecheck"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// scriptedBackend replays one scripted event stream per backend call.
+type scriptedBackend struct {
+	streams [][]map[string]interface{}
+}
+
+func (b *scriptedBackend) CreateResponse(context.Context, *api.ResponsesAPIRequest) (*api.ResponsesAPIResponse, error) {
+	return nil, fmt.Errorf("not scripted")
+}
+
+func (b *scriptedBackend) CreateResponseStream(context.Context, *api.ResponsesAPIRequest) (<-chan api.ResponsesStreamEvent, error) {
+	if len(b.streams) == 0 {
+		return nil, fmt.Errorf("no more scripted streams")
+	}
+	script := b.streams[0]
+	b.streams = b.streams[1:]
+
+	ch := make(chan api.ResponsesStreamEvent, len(script))
+	for _, evt := range script {
+		data, _ := json.Marshal(evt)
+		ch <- api.ResponsesStreamEvent{Type: evt["type"].(string), Data: data}
+	}
+	close(ch)
+	return ch, nil
+}
+
+// backendMessage is a completed backend message item.
+func backendMessage(id, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "message", "id": id, "role": "assistant", "status": "completed",
+		"content": []interface{}{map[string]interface{}{"type": "output_text", "text": text}},
+	}
+}
+
+// backendCompleted is the backend's response.completed event.
+func backendCompleted(output ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "response.completed",
+		"response": map[string]interface{}{
+			"id": "resp_backend", "status": "completed", "output": output,
+			"usage": map[string]interface{}{"input_tokens": 5, "output_tokens": 3, "total_tokens": 8},
+		},
+	}
+}
+
+func TestProcessRequestStream_Conformance(t *testing.T) {
+	tests := []struct {
+		name    string
+		tools   []schema.ResponsesToolParam
+		streams [][]map[string]interface{}
+	}{
+		{
+			// vLLM numbers each delta with its own content_index and
+			// sends its own lifecycle events and sequence numbers
+			name: "text",
+			streams: [][]map[string]interface{}{{
+				{"type": "response.created", "sequence_number": 0, "response": map[string]interface{}{"id": "resp_backend", "status": "in_progress"}},
+				{"type": "response.in_progress", "sequence_number": 1, "response": map[string]interface{}{"id": "resp_backend", "status": "in_progress"}},
+				{"type": "response.output_item.added", "sequence_number": 2, "output_index": 0, "item": map[string]interface{}{"type": "message", "id": "msg_backend", "role": "assistant", "content": []interface{}{}}},
+				{"type": "response.content_part.added", "sequence_number": 3, "output_index": 0, "content_index": 0, "item_id": "msg_backend", "part": map[string]interface{}{"type": "output_text", "text": ""}},
+				{"type": "response.output_text.delta", "sequence_number": 4, "output_index": 0, "content_index": 0, "item_id": "msg_backend", "delta": "Hello", "response_id": "resp_backend"},
+				{"type": "response.output_text.delta", "sequence_number": 5, "output_index": 0, "content_index": 1, "item_id": "msg_backend", "delta": ", world", "response_id": "resp_backend"},
+				{"type": "response.vendor.debug", "sequence_number": 6, "info": "dropped"},
+				{"type": "response.output_text.done", "sequence_number": 7, "output_index": 0, "content_index": 0, "item_id": "msg_backend", "text": "Hello, world"},
+				{"type": "response.content_part.done", "sequence_number": 8, "output_index": 0, "content_index": 0, "item_id": "msg_backend"},
+				{"type": "response.output_item.done", "sequence_number": 9, "output_index": 0, "item": backendMessage("msg_backend", "Hello, world")},
+				backendCompleted(backendMessage("msg_backend", "Hello, world")),
+			}},
+		},
+		{
+			name:  "reasoning and client tool call",
+			tools: []schema.ResponsesToolParam{{Type: "function", Name: "get_weather"}},
+			streams: [][]map[string]interface{}{{
+				{"type": "response.reasoning_text.delta", "output_index": 0, "item_id": "rs_backend", "delta": "The user wants the weather."},
+				{"type": "response.output_item.added", "output_index": 1, "item": map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_1", "name": "get_weather", "arguments": ""}},
+				{"type": "response.function_call_arguments.delta", "output_index": 1, "item_id": "fc_backend", "delta": `{"city":`},
+				{"type": "response.function_call_arguments.delta", "output_index": 1, "item_id": "fc_backend", "delta": `"Paris"}`},
+				{"type": "response.function_call_arguments.done", "output_index": 1, "item_id": "fc_backend", "arguments": `{"city":"Paris"}`},
+				backendCompleted(
+					map[string]interface{}{"type": "reasoning", "id": "rs_backend", "content": []interface{}{map[string]interface{}{"type": "reasoning_text", "text": "The user wants the weather."}}},
+					map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_1", "name": "get_weather", "arguments": `{"city":"Paris"}`, "status": "completed"},
+				),
+			}},
+		},
+		{
+			// Both backend calls number their items from 0; the gateway
+			// numbers them across the whole response
+			name:  "file search loop",
+			tools: []schema.ResponsesToolParam{{Type: "file_search", VectorStoreIDs: []string{"vs_1"}}},
+			streams: [][]map[string]interface{}{
+				{
+					{"type": "response.output_item.added", "output_index": 0, "item": map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_fs", "name": "file_search", "arguments": ""}},
+					{"type": "response.function_call_arguments.delta", "output_index": 0, "item_id": "fc_backend", "delta": `{"query":"pricing"}`},
+					backendCompleted(map[string]interface{}{"type": "function_call", "id": "fc_backend", "call_id": "call_fs", "name": "file_search", "arguments": `{"query":"pricing"}`}),
+				},
+				{
+					{"type": "response.output_text.delta", "output_index": 0, "item_id": "msg_backend", "delta": "Plans start at $10."},
+					backendCompleted(backendMessage("msg_backend", "Plans start at $10.")),
+				},
+			},
+		},
+		{
+			name: "backend failure",
+			streams: [][]map[string]interface{}{{
+				{"type": "response.output_text.delta", "output_index": 0, "item_id": "msg_backend", "delta": "Partial"},
+				{"type": "response.failed", "response": map[string]interface{}{"id": "resp_backend", "status": "failed", "error": map[string]interface{}{"code": "server_error", "message": "model crashed"}}},
+			}},
+		},
+		{
+			name: "backend refusal",
+			streams: [][]map[string]interface{}{{
+				{"type": "response.refusal.delta", "output_index": 0, "item_id": "msg_backend", "delta": "I can't help with that."},
+				backendCompleted(),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatalf("sqlite.New() error = %v", err)
+			}
+			e := &Engine{
+				config:       &config.EngineConfig{},
+				sessions:     store,
+				llm:          &scriptedBackend{streams: tt.streams},
+				vectorSearch: &dummyVectorSearcher{results: []vectorstore.SearchResult{{FileID: "file_1", Content: "Plans start at $10.", Score: 0.9}}},
+				idGen:        ids.NewSequence(),
+			}
+
+			events, err := e.ProcessRequestStream(context.Background(), &schema.ResponseRequest{
+				Model: stringPtr("m"),
+				Input: "hi",
+				Tools: tt.tools,
+			})
+			if err != nil {
+				t.Fatalf("ProcessRequestStream() error = %v", err)
+			}
+			var got []map[string]interface{}
+			var stream []ssecheck.Event
+			for event := range events {
+				fields := normalizeEvent(t, event)
+				got = append(got, fields)
+				stream = append(stream, ssecheck.Event{Name: schema.ExtractEventType(event), Fields: fields})
+			}
+
+			for _, err := range ssecheck.Validate(stream) {
+				t.Error(err)
+			}
+			checkGolden(t, filepath.Join("testdata", "stream", strings.ReplaceAll(tt.name, " ", "_")+".golden"), got)
+		})
+	}
+}
+
+// normalizeEvent returns the JSON fields of a streaming event, without the
+// timestamps that change on every run.
+func normalizeEvent(t *testing.T, event interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal %T: %v", event, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	if resp, ok := fields["response"].(map[string]interface{}); ok {
+		delete(resp, "created_at")
+		delete(resp, "completed_at")
+	}
+	return fields
+}
+
+// checkGolden compares the events with a golden file, one SSE event per
+// block, rewriting the file when -update is set.
+func checkGolden(t *testing.T, path string, events []map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, event := range events {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", event["type"], data)
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("events differ from %s (run with -update to accept)\ngot:\n%s", path, buf.Bytes())
+	}
+}
+
+// textDelta is an output_text delta of msg_1.
+func textDelta(delta string) *schema.ResponseOutputTextDeltaStreamingEvent {
+	return &schema.ResponseOutputTextDeltaStreamingEvent{
+		Type: "response.output_text.delta", ItemID: "msg_1", Delta: delta, Logprobs: make([]interface{}, 0),
+	}
+}
+
+func TestEventStream_Overflow(t *testing.T) {
+	tests := []struct {
+		overflow    string
+		wantDeltas  []string
+		wantMerged  int64
+		wantDropped int64
+	}{
+		// "c" finds the channel full
+		{overflow: "merge", wantDeltas: []string{"a", "b", "cd"}, wantMerged: 1},
+		{overflow: "drop", wantDeltas: []string{"a", "b"}, wantDropped: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			events := make(chan interface{}, 3)
+			stream := newEventStream(events, "resp_1")
+			stream.overflow = tt.overflow
+			stream.send(&schema.ResponseCreatedStreamingEvent{Type: "response.created"})
+			for _, delta := range []string{"a", "b", "c", "d"} {
+				stream.sendDelta(textDelta(delta))
+			}
+
+			// The client catches up; the done event is sent after the
+			// held delta
+			go func() {
+				stream.send(&schema.ResponseCompletedStreamingEvent{Type: "response.completed"})
+				close(events)
+			}()
+			var deltas []string
+			seq := 0
+			for event := range events {
+				data, _ := json.Marshal(event)
+				var fields struct {
+					SequenceNumber int    `json:"sequence_number"`
+					Delta          string `json:"delta"`
+					Type           string `json:"type"`
+				}
+				json.Unmarshal(data, &fields)
+				if fields.SequenceNumber != seq {
+					t.Errorf("%s: sequence_number %d, want %d", fields.Type, fields.SequenceNumber, seq)
+				}
+				seq++
+				if fields.Type == "response.output_text.delta" {
+					deltas = append(deltas, fields.Delta)
+				}
+			}
+			if strings.Join(deltas, "|") != strings.Join(tt.wantDeltas, "|") {
+				t.Errorf("deltas = %q, want %q", deltas, tt.wantDeltas)
+			}
+			if got := stream.stats.merged.Load(); got != tt.wantMerged {
+				t.Errorf("merged = %d, want %d", got, tt.wantMerged)
+			}
+			if got := stream.stats.dropped.Load(); got != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestEventStream_Stall(t *testing.T) {
+	events := make(chan interface{}, 1)
+	stream := newEventStream(events, "resp_1")
+	stream.send(&schema.ResponseCreatedStreamingEvent{Type: "response.created"})
+	go func() {
+		stream.sendDelta(textDelta("a"))
+		stream.sendDelta(textDelta("b"))
+		close(events)
+	}()
+
+	// Read only once the sender has found the channel full
+	for stream.stats.stalls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("got %d events, want 3: blocking streams keep every delta", n)
+	}
+	if got := stream.stats.stalledStreams.Load(); got != 1 {
+		t.Errorf("stalled streams = %d, want 1", got)
+	}
+	if got := stream.stats.stalls.Load(); got < 1 {
+		t.Errorf("stalls = %d, want at least 1", got)
+	}
+}
+
+// TestProcessRequestStream_SlowClient streams many deltas through a one
+// event buffer to a slow client: every overflow policy must keep the stream
+// conformant and the final text complete.
+func TestProcessRequestStream_SlowClient(t *testing.T) {
+	script := []map[string]interface{}{}
+	var text strings.Builder
+	for i := 0; i < 50; i++ {
+		delta := fmt.Sprintf("w%d ", i)
+		text.WriteString(delta)
+		script = append(script, map[string]interface{}{
+			"type": "response.output_text.delta", "output_index": 0, "item_id": "msg_backend", "delta": delta,
+		})
+	}
+	script = append(script, backendCompleted(backendMessage("msg_backend", text.String())))
+
+	for _, overflow := range []string{"block", "merge", "drop"} {
+		t.Run(overflow, func(t *testing.T) {
+			store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatalf("sqlite.New() error = %v", err)
+			}
+			defer store.Close()
+			e := &Engine{
+				config:   &config.EngineConfig{Streaming: config.StreamingConfig{Buffer: 1, Overflow: overflow}},
+				sessions: store,
+				llm:      &scriptedBackend{streams: [][]map[string]interface{}{script}},
+				idGen:    ids.NewSequence(),
+			}
+
+			events, err := e.ProcessRequestStream(context.Background(), &schema.ResponseRequest{Model: stringPtr("m"), Input: "hi"})
+			if err != nil {
+				t.Fatalf("ProcessRequestStream() error = %v", err)
+			}
+			var sse bytes.Buffer
+			var done string
+			for event := range events {
+				time.Sleep(100 * time.Microsecond)
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(&sse, "event: %s\ndata: %s\n\n", schema.ExtractEventType(event), data)
+				if d, ok := event.(*schema.ResponseOutputTextDoneStreamingEvent); ok {
+					done = d.Text
+				}
+			}
+
+			parsed, err := ssecheck.Read(&sse)
+			if err != nil {
+				t.Fatalf("ssecheck.Read() error = %v", err)
+			}
+			for _, err := range ssecheck.Validate(parsed) {
+				t.Error(err)
+			}
+			if done != text.String() {
+				t.Errorf("output_text.done = %q, want %q", done, text.String())
+			}
+			if got := e.StreamStats().Streams; got != 1 {
+				t.Errorf("streams = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"encoding/json"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/tokenizer"
+)
+
+const (
+	// messageOverhead approximates the tokens a chat template adds around
+	// each message (role and delimiters).
+	messageOverhead = 4
+	// imageTokens approximates an image, file or video input, whose real cost
+	// depends on the model and is not visible in the request.
+	imageTokens = 765
+	// audioTokensPerSecond approximates the tokens of a second of input
+	// audio, whose length is estimated from the size of the clip.
+	audioTokensPerSecond = 10
+)
+
+// SetTokenCounter replaces the counter used to estimate input and output
+// tokens, which defaults to the configured tokenizer.
+func (e *Engine) SetTokenCounter(c tokenizer.TokenCounter) {
+	e.tokens = c
+}
+
+// tokenCounter returns the engine's counter, or the heuristic one for
+// engines built without New.
+func (e *Engine) tokenCounter() tokenizer.TokenCounter {
+	if e.tokens == nil {
+		return tokenizer.Heuristic{}
+	}
+	return e.tokens
+}
+
+// countMessage estimates the tokens of a message.
+func (e *Engine) countMessage(msg api.Message) int {
+	c := e.tokenCounter()
+	n := messageOverhead
+	if len(msg.ContentParts) > 0 {
+		for _, part := range msg.ContentParts {
+			switch {
+			case part.Type == "text":
+				n += c.CountTokens(part.Text)
+			case part.ImageURL != nil, part.File != nil, part.VideoURL != nil:
+				n += imageTokens
+			case part.InputAudio != nil:
+				n += audioTokens(part.InputAudio)
+			}
+		}
+	} else {
+		n += c.CountTokens(msg.Content)
+	}
+	for _, tc := range msg.ToolCalls {
+		n += c.CountTokens(tc.Function.Name) + c.CountTokens(tc.Function.Arguments)
+	}
+	if msg.Reasoning != nil {
+		n += c.CountTokens(msg.Reasoning.Text)
+		for _, s := range msg.Reasoning.Summary {
+			n += c.CountTokens(s)
+		}
+	}
+	return n
+}
+
+// audioTokens estimates the tokens of an audio clip from its duration,
+// itself estimated from the size of the base64 data: 16-bit 24kHz mono for
+// wav and pcm16, 128kbps for compressed formats.
+func audioTokens(audio *api.MessageAudio) int {
+	bytesPerSecond := 16000
+	switch audio.Format {
+	case "wav", "pcm16":
+		bytesPerSecond = 48000
+	}
+	size := len(audio.Data) * 3 / 4
+	return max(1, size*audioTokensPerSecond/bytesPerSecond)
+}
+
+// countInput estimates the input tokens of a backend request: its
+// instructions, tool definitions and messages.
+func (e *Engine) countInput(apiReq *api.ResponsesAPIRequest, messages []api.Message) int {
+	n := e.countFixed(apiReq)
+	for _, msg := range messages {
+		if msg.Role != "system" {
+			n += e.countMessage(msg)
+		}
+	}
+	return n
+}
+
+// countFixed estimates the tokens of a request that truncation cannot drop:
+// instructions and tool definitions.
+func (e *Engine) countFixed(apiReq *api.ResponsesAPIRequest) int {
+	c := e.tokenCounter()
+	n := 0
+	if apiReq.Instructions != nil {
+		n += messageOverhead + c.CountTokens(*apiReq.Instructions)
+	}
+	if len(apiReq.Tools) > 0 {
+		if data, err := json.Marshal(apiReq.Tools); err == nil {
+			n += c.CountTokens(string(data))
+		}
+	}
+	return n
+}
+
+// countOutput estimates the tokens of backend output items.
+func (e *Engine) countOutput(output []api.OutputItem) int {
+	c := e.tokenCounter()
+	n := 0
+	for _, item := range output {
+		for _, content := range item.Content {
+			n += c.CountTokens(content.Text)
+		}
+		for _, summary := range item.Summary {
+			n += c.CountTokens(summary.Text)
+		}
+		n += c.CountTokens(item.Name) + c.CountTokens(item.Arguments)
+	}
+	return n
+}
+
+// estimateUsage returns usage for a backend call that did not report it.
+func estimateUsage(inputTokens, outputTokens int) *api.UsageInfo {
+	return &api.UsageInfo{
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		TotalTokens:  inputTokens + outputTokens,
+	}
+}
+
+// fitContext counts the input tokens of apiReq, built from messages. When
+// the engine knows the model's context window and the request asks for
+// truncation "auto", it first drops the oldest turns until the input and
+// the requested output fit, replacing apiReq.Input. A turn runs from a user
+// message to the next one; the last turn is never dropped, so a request
+// that cannot fit is sent as is. It returns the input token count.
+func (e *Engine) fitContext(apiReq *api.ResponsesAPIRequest, messages []api.Message) int {
+	input := e.countInput(apiReq, messages)
+	window := 0
+	if e.config != nil {
+		window = e.config.ContextWindow
+	}
+	if window <= 0 || apiReq.Truncation == nil || *apiReq.Truncation != "auto" {
+		return input
+	}
+
+	budget := window
+	if apiReq.MaxOutputTokens != nil {
+		budget -= *apiReq.MaxOutputTokens
+	}
+	if input <= budget {
+		return input
+	}
+
+	// System and developer messages are kept; the rest is dropped a turn
+	// at a time from the front.
+	var kept, turns []api.Message
+	for _, msg := range messages {
+		if msg.Role == "system" || msg.Role == "developer" {
+			kept = append(kept, msg)
+		} else {
+			turns = append(turns, msg)
+		}
+	}
+	for input > budget {
+		next := -1
+		for i := 1; i < len(turns); i++ {
+			if turns[i].Role == "user" {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		for _, msg := range turns[:next] {
+			input -= e.countMessage(msg)
+		}
+		turns = turns[next:]
+	}
+
+	apiReq.Input = convertMessagesToResponsesInput(append(kept, turns...))
+	return input
+}
@@ -0,0 +1,49 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import "github.com/leseb/openresponses-gw/pkg/core/schema"
+
+// Server-side tool calls that fail are reported to the client as an item of
+// the tool's type with status "failed" and the error, in place of the
+// function_call_output a successful call gets, so that clients can tell a
+// tool failure from tool output. The model is still given the error as the
+// call's output, and again when a client sends the failed item back as
+// input.
+
+// toolErrorOutput returns the output the model is given for a failed call.
+func toolErrorOutput(message string) string {
+	return "Error calling tool: " + message
+}
+
+// failedMCPCall returns the mcp_call item of a failed MCP tool call.
+func (e *Engine) failedMCPCall(tc toolCallInfo, serverLabel string, err error) schema.ItemField {
+	item := failedToolCall("mcp_call", e.NewID("mcp_"), tc, err)
+	item.Name = &tc.Name
+	item.Arguments = &tc.Arguments
+	if serverLabel != "" {
+		item.ServerLabel = &serverLabel
+	}
+	return item
+}
+
+// failedFileSearchCall returns the file_search_call item of a failed search.
+func failedFileSearchCall(id string, tc toolCallInfo, query string, err error) schema.ItemField {
+	item := failedToolCall("file_search_call", id, tc, err)
+	item.Queries = []string{query}
+	return item
+}
+
+func failedToolCall(itemType, id string, tc toolCallInfo, err error) schema.ItemField {
+	failed := "failed"
+	callID := tc.CallID
+	message := err.Error()
+	return schema.ItemField{
+		Type:   itemType,
+		ID:     id,
+		CallID: &callID,
+		Status: &failed,
+		Error:  &message,
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncateToolOutput bounds the output of a server-side tool call given to
+// the model to limit bytes, or to the engine's limit when limit is 0.
+// Longer outputs keep their head and tail, cut at rune boundaries, and end
+// with a note of how much was left out.
+func (e *Engine) truncateToolOutput(output string, limit int) string {
+	if limit <= 0 {
+		limit = e.maxToolOutputBytes()
+	}
+	if len(output) <= limit {
+		return output
+	}
+	head := limit / 2
+	for head > 0 && !utf8.RuneStart(output[head]) {
+		head--
+	}
+	tail := len(output) - (limit - head)
+	for tail < len(output) && !utf8.RuneStart(output[tail]) {
+		tail++
+	}
+	omitted := tail - head
+	return fmt.Sprintf("%s\n...\n%s\n[output truncated: %d of %d bytes omitted]", output[:head], output[tail:], omitted, len(output))
+}
+
+// maxToolOutputBytes returns the size limit of the tool output given to
+// the model.
+func (e *Engine) maxToolOutputBytes() int {
+	if e.config == nil || e.config.MaxToolOutputBytes <= 0 {
+		return 64 << 10
+	}
+	return e.config.MaxToolOutputBytes
+}
@@ -0,0 +1,88 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// usageTally accumulates the usage of the backend calls of an agentic loop.
+type usageTally struct {
+	last  *api.UsageInfo
+	total api.UsageInfo
+}
+
+// add records the usage of one backend call.
+func (t *usageTally) add(usage *api.UsageInfo) {
+	if usage == nil {
+		return
+	}
+	t.last = usage
+	t.total.InputTokens += usage.InputTokens
+	t.total.OutputTokens += usage.OutputTokens
+	if usage.TotalTokens > 0 {
+		t.total.TotalTokens += usage.TotalTokens
+	} else {
+		t.total.TotalTokens += usage.InputTokens + usage.OutputTokens
+	}
+	if cached := usage.CachedTokens(); cached > 0 {
+		if t.total.InputTokensDetails == nil {
+			t.total.InputTokensDetails = &api.InputTokensDetails{}
+		}
+		t.total.InputTokensDetails.CachedTokens += cached
+	}
+	if reasoning := usage.ReasoningTokens(); reasoning > 0 {
+		if t.total.OutputTokensDetails == nil {
+			t.total.OutputTokensDetails = &api.OutputTokensDetails{}
+		}
+		t.total.OutputTokensDetails.ReasoningTokens += reasoning
+	}
+	if audio := usage.OutputAudioTokens(); audio > 0 {
+		if t.total.OutputTokensDetails == nil {
+			t.total.OutputTokensDetails = &api.OutputTokensDetails{}
+		}
+		t.total.OutputTokensDetails.AudioTokens += audio
+	}
+}
+
+// responseUsage returns the usage reported on the final response, or nil if
+// no call was recorded. As for non-streaming requests, input tokens are
+// those of the last call, whose input holds the whole conversation, and
+// output tokens are summed over every call.
+func (t *usageTally) responseUsage() *schema.UsageField {
+	if t.last == nil {
+		return nil
+	}
+	return &schema.UsageField{
+		InputTokens:  t.last.InputTokens,
+		OutputTokens: t.total.OutputTokens,
+		TotalTokens:  t.last.InputTokens + t.total.OutputTokens,
+		InputTokensDetails: schema.InputTokensDetails{
+			CachedTokens: t.last.CachedTokens(),
+			AudioTokens:  t.last.InputAudioTokens(),
+		},
+		OutputTokensDetails: schema.OutputTokensDetails{
+			ReasoningTokens: t.total.ReasoningTokens(),
+			AudioTokens:     t.total.OutputAudioTokens(),
+		},
+	}
+}
+
+// usageField converts backend usage to the schema format.
+func usageField(usage *api.UsageInfo) schema.UsageField {
+	return schema.UsageField{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+		InputTokensDetails: schema.InputTokensDetails{
+			CachedTokens: usage.CachedTokens(),
+			AudioTokens:  usage.InputAudioTokens(),
+		},
+		OutputTokensDetails: schema.OutputTokensDetails{
+			ReasoningTokens: usage.ReasoningTokens(),
+			AudioTokens:     usage.OutputAudioTokens(),
+		},
+	}
+}
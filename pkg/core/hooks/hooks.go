@@ -0,0 +1,109 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hooks provides the engine's request/response extension points.
+//
+// Hooks run in registration order. A request hook may mutate the incoming
+// request or reject it; a response hook may rewrite the final response
+// (e.g. redact PII or add annotations) before it is stored and returned.
+// This lets deployments enforce policy without forking the engine.
+package hooks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// RequestHook runs before a request is sent to the backend.
+type RequestHook interface {
+	// OnRequest may modify req in place. Returning a *RejectError refuses
+	// the request; any other error aborts it as an internal failure.
+	OnRequest(ctx context.Context, req *schema.ResponseRequest) error
+}
+
+// ResponseHook runs after the engine has produced the final response and
+// before it is persisted and returned to the client.
+type ResponseHook interface {
+	// OnResponse may modify resp in place. Returning a *RejectError marks
+	// the response as failed with the rejection message.
+	OnResponse(ctx context.Context, req *schema.ResponseRequest, resp *schema.Response) error
+}
+
+// RejectError is returned by a hook to refuse a request or response on
+// policy grounds.
+type RejectError struct {
+	Hook    string // name of the hook that rejected
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	if e.Hook == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("rejected by %s: %s", e.Hook, e.Message)
+}
+
+// Chain is an ordered list of request and response hooks.
+// The zero value is an empty chain and a nil *Chain is safe to use.
+type Chain struct {
+	request  []RequestHook
+	response []ResponseHook
+}
+
+// NewChain creates an empty hook chain.
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// Add registers a hook. h must implement RequestHook, ResponseHook, or both.
+func (c *Chain) Add(h interface{}) error {
+	added := false
+	if rh, ok := h.(RequestHook); ok {
+		c.request = append(c.request, rh)
+		added = true
+	}
+	if rh, ok := h.(ResponseHook); ok {
+		c.response = append(c.response, rh)
+		added = true
+	}
+	if !added {
+		return fmt.Errorf("hook %T implements neither RequestHook nor ResponseHook", h)
+	}
+	return nil
+}
+
+// Len returns the total number of registered hooks.
+func (c *Chain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.request) + len(c.response)
+}
+
+// RunRequest runs all request hooks in order, stopping at the first error.
+func (c *Chain) RunRequest(ctx context.Context, req *schema.ResponseRequest) error {
+	if c == nil {
+		return nil
+	}
+	for _, h := range c.request {
+		if err := h.OnRequest(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunResponse runs all response hooks in order, stopping at the first error.
+func (c *Chain) RunResponse(ctx context.Context, req *schema.ResponseRequest, resp *schema.Response) error {
+	if c == nil {
+		return nil
+	}
+	for _, h := range c.response {
+		if err := h.OnResponse(ctx, req, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+type recordingHook struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (h *recordingHook) OnRequest(_ context.Context, _ *schema.ResponseRequest) error {
+	*h.calls = append(*h.calls, h.name)
+	return h.err
+}
+
+func TestChainRunRequest(t *testing.T) {
+	var calls []string
+	chain := NewChain()
+	for _, h := range []*recordingHook{
+		{name: "first", calls: &calls},
+		{name: "second", calls: &calls, err: &RejectError{Hook: "second", Message: "no"}},
+		{name: "third", calls: &calls},
+	} {
+		if err := chain.Add(h); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	err := chain.RunRequest(context.Background(), &schema.ResponseRequest{})
+	var rejectErr *RejectError
+	if !errors.As(err, &rejectErr) {
+		t.Fatalf("expected RejectError, got %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("calls = %v, want [first second]", calls)
+	}
+}
+
+func TestChainAddInvalid(t *testing.T) {
+	if err := NewChain().Add("not a hook"); err == nil {
+		t.Error("expected error for value that implements no hook interface")
+	}
+}
+
+func TestChainNil(t *testing.T) {
+	var chain *Chain
+	if chain.Len() != 0 {
+		t.Error("nil chain should be empty")
+	}
+	if err := chain.RunRequest(context.Background(), &schema.ResponseRequest{}); err != nil {
+		t.Errorf("RunRequest on nil chain: %v", err)
+	}
+	if err := chain.RunResponse(context.Background(), &schema.ResponseRequest{}, &schema.Response{}); err != nil {
+		t.Errorf("RunResponse on nil chain: %v", err)
+	}
+}
+
+func TestHTTPHook(t *testing.T) {
+	model := "rewritten-model"
+
+	tests := []struct {
+		name       string
+		status     int
+		result     httpHookResult
+		failOpen   bool
+		wantReject bool
+		wantErr    bool
+		wantModel  string
+	}{
+		{
+			name:      "allow unchanged",
+			status:    http.StatusOK,
+			result:    httpHookResult{Action: "allow"},
+			wantModel: "original",
+		},
+		{
+			name:      "allow with mutation",
+			status:    http.StatusOK,
+			result:    httpHookResult{Action: "allow", Request: &schema.ResponseRequest{Model: &model}},
+			wantModel: model,
+		},
+		{
+			name:       "reject",
+			status:     http.StatusOK,
+			result:     httpHookResult{Action: "reject", Message: "blocked"},
+			wantReject: true,
+			wantModel:  "original",
+		},
+		{
+			name:      "hook failure",
+			status:    http.StatusInternalServerError,
+			wantErr:   true,
+			wantModel: "original",
+		},
+		{
+			name:      "hook failure fail open",
+			status:    http.StatusInternalServerError,
+			failOpen:  true,
+			wantModel: "original",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload httpHookPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				if payload.Stage != "request" {
+					t.Errorf("stage = %q, want request", payload.Stage)
+				}
+				w.WriteHeader(tt.status)
+				_ = json.NewEncoder(w).Encode(tt.result)
+			}))
+			defer srv.Close()
+
+			hook := NewHTTPHook(HTTPHookOptions{Name: "test", URL: srv.URL, OnRequest: true, FailOpen: tt.failOpen})
+			original := "original"
+			req := &schema.ResponseRequest{Model: &original}
+
+			err := hook.OnRequest(context.Background(), req)
+
+			var rejectErr *RejectError
+			if got := errors.As(err, &rejectErr); got != tt.wantReject {
+				t.Errorf("reject = %v, want %v (err: %v)", got, tt.wantReject, err)
+			}
+			if !tt.wantReject && (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if req.Model == nil || *req.Model != tt.wantModel {
+				t.Errorf("model = %v, want %q", req.Model, tt.wantModel)
+			}
+		})
+	}
+}
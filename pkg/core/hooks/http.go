@@ -0,0 +1,152 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// HTTPHook delegates request and/or response processing to an external
+// service. The gateway POSTs a JSON payload of the form
+//
+//	{"stage": "request",  "request": {...}}
+//	{"stage": "response", "request": {...}, "response": {...}}
+//
+// and expects back
+//
+//	{"action": "allow" | "reject", "message": "...", "request": {...}, "response": {...}}
+//
+// A returned "request" or "response" object replaces the current one,
+// which is how an external hook mutates or redacts content.
+type HTTPHook struct {
+	name       string
+	url        string
+	onRequest  bool
+	onResponse bool
+	failOpen   bool
+	httpClient *http.Client
+}
+
+// HTTPHookOptions configures an HTTPHook.
+type HTTPHookOptions struct {
+	Name       string
+	URL        string
+	OnRequest  bool          // call the hook before the backend request
+	OnResponse bool          // call the hook after the final response
+	FailOpen   bool          // allow traffic when the hook is unreachable
+	Timeout    time.Duration // default 5s
+}
+
+// NewHTTPHook creates an external HTTP hook.
+func NewHTTPHook(opts HTTPHookOptions) *HTTPHook {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &HTTPHook{
+		name:       opts.Name,
+		url:        opts.URL,
+		onRequest:  opts.OnRequest,
+		onResponse: opts.OnResponse,
+		failOpen:   opts.FailOpen,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type httpHookPayload struct {
+	Stage    string                  `json:"stage"`
+	Request  *schema.ResponseRequest `json:"request"`
+	Response *schema.Response        `json:"response,omitempty"`
+}
+
+type httpHookResult struct {
+	Action   string                  `json:"action"`
+	Message  string                  `json:"message,omitempty"`
+	Request  *schema.ResponseRequest `json:"request,omitempty"`
+	Response *schema.Response        `json:"response,omitempty"`
+}
+
+// OnRequest implements RequestHook.
+func (h *HTTPHook) OnRequest(ctx context.Context, req *schema.ResponseRequest) error {
+	if !h.onRequest {
+		return nil
+	}
+	result, err := h.call(ctx, &httpHookPayload{Stage: "request", Request: req})
+	if err != nil {
+		return h.handleCallError(err)
+	}
+	if result.Action == "reject" {
+		return &RejectError{Hook: h.name, Message: result.Message}
+	}
+	if result.Request != nil {
+		*req = *result.Request
+	}
+	return nil
+}
+
+// OnResponse implements ResponseHook.
+func (h *HTTPHook) OnResponse(ctx context.Context, req *schema.ResponseRequest, resp *schema.Response) error {
+	if !h.onResponse {
+		return nil
+	}
+	result, err := h.call(ctx, &httpHookPayload{Stage: "response", Request: req, Response: resp})
+	if err != nil {
+		return h.handleCallError(err)
+	}
+	if result.Action == "reject" {
+		return &RejectError{Hook: h.name, Message: result.Message}
+	}
+	if result.Response != nil {
+		*resp = *result.Response
+	}
+	return nil
+}
+
+func (h *HTTPHook) handleCallError(err error) error {
+	if h.failOpen {
+		return nil
+	}
+	return fmt.Errorf("hook %s: %w", h.name, err)
+}
+
+func (h *HTTPHook) call(ctx context.Context, payload *httpHookPayload) (*httpHookResult, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result httpHookResult
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+	return &result, nil
+}
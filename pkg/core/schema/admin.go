@@ -0,0 +1,243 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+// GarbageCollectRequest represents a request to run the orphan garbage collector
+type GarbageCollectRequest struct {
+	DryRun        *bool  `json:"dry_run,omitempty"`         // Report without removing (default true)
+	MinAgeSeconds *int64 `json:"min_age_seconds,omitempty"` // Only collect files older than this (default from config)
+	IncludeFiles  *bool  `json:"include_files,omitempty"`   // Also collect unattached "assistants" files (default from config)
+}
+
+// GarbageCollectResponse reports the orphans found by a garbage collection run
+type GarbageCollectResponse struct {
+	Object  string                 `json:"object"`  // Always "garbage_collection"
+	DryRun  bool                   `json:"dry_run"` // Whether orphans were left in place
+	Orphans []GarbageCollectOrphan `json:"orphans"` // Orphans found
+	Found   int                    `json:"found"`   // Number of orphans found
+	Removed int                    `json:"removed"` // Number of orphans removed
+}
+
+// GarbageCollectOrphan is an object that nothing refers to anymore
+type GarbageCollectOrphan struct {
+	Kind          string `json:"kind"`                      // "vector_store_file", "chunks", "backend_store", or "file"
+	VectorStoreID string `json:"vector_store_id,omitempty"` // Vector store the orphan belongs to
+	FileID        string `json:"file_id,omitempty"`         // File the orphan belongs to
+	Removed       bool   `json:"removed"`                   // Whether the orphan was removed
+	Error         string `json:"error,omitempty"`           // Removal error, if any
+}
+
+// DataDeletionRequest selects the data to delete for a data subject request.
+// Data matching every given condition is deleted; at least one is required.
+type DataDeletionRequest struct {
+	Tenant   string            `json:"tenant,omitempty"`   // Tenant that created the data
+	Metadata map[string]string `json:"metadata,omitempty"` // Metadata pairs the data must all carry
+	DryRun   *bool             `json:"dry_run,omitempty"`  // Report without deleting (default true)
+}
+
+// DataDeletionResponse reports the data matched and deleted by a data deletion
+type DataDeletionResponse struct {
+	Object  string               `json:"object"`  // Always "data_deletion"
+	DryRun  bool                 `json:"dry_run"` // Whether the data was left in place
+	Counts  DataDeletionCounts   `json:"counts"`  // Matched objects per kind
+	Objects []DataDeletionObject `json:"objects"` // Matched objects
+	Found   int                  `json:"found"`   // Number of objects matched
+	Removed int                  `json:"removed"` // Number of objects deleted
+}
+
+// DataDeletionCounts counts the objects matched by a data deletion
+type DataDeletionCounts struct {
+	Responses         int `json:"responses"`          // Responses
+	Conversations     int `json:"conversations"`      // Conversations
+	ConversationItems int `json:"conversation_items"` // Items of the matched conversations
+	Files             int `json:"files"`              // Files
+	VectorStores      int `json:"vector_stores"`      // Vector stores
+}
+
+// DataDeletionObject is an object matched by a data deletion
+type DataDeletionObject struct {
+	Kind           string   `json:"kind"`                       // "response", "conversation", "file", or "vector_store"
+	ID             string   `json:"id"`                         // Object ID
+	Items          int      `json:"items,omitempty"`            // Items deleted with a conversation
+	VectorStoreIDs []string `json:"vector_store_ids,omitempty"` // Vector stores a file is detached from
+	Removed        bool     `json:"removed"`                    // Whether the object was deleted
+	Error          string   `json:"error,omitempty"`            // Deletion error, if any
+}
+
+// SessionRetentionResponse reports the rows deleted from the session store
+// by the retention job since the gateway started
+type SessionRetentionResponse struct {
+	Object    string                 `json:"object"`                // Always "session_retention"
+	Runs      int                    `json:"runs"`                  // Number of runs
+	Reclaimed SessionRetentionCounts `json:"reclaimed"`             // Rows deleted per kind
+	LastRunAt int64                  `json:"last_run_at,omitempty"` // Unix timestamp of the last run
+	LastError string                 `json:"last_error,omitempty"`  // Error of the last run, if it failed
+}
+
+// SessionRetentionCounts counts the rows deleted by the retention job
+type SessionRetentionCounts struct {
+	Sessions      int `json:"sessions"`      // Expired sessions
+	Responses     int `json:"responses"`     // Responses past their TTL
+	Conversations int `json:"conversations"` // Conversations past their TTL, with their items
+}
+
+// StreamStatsResponse reports how streamed responses kept up with their
+// clients since the gateway started
+type StreamStatsResponse struct {
+	Object         string `json:"object"`          // Always "stream_stats"
+	Buffer         int    `json:"buffer"`          // Events buffered per stream
+	Overflow       string `json:"overflow"`        // Overflow policy: "block", "merge" or "drop"
+	Streams        int64  `json:"streams"`         // Streams started
+	StalledStreams int64  `json:"stalled_streams"` // Streams that waited for their client at least once
+	Stalls         int64  `json:"stalls"`          // Events that waited for the client
+	BlockedMS      int64  `json:"blocked_ms"`      // Total time spent waiting for clients
+	MergedDeltas   int64  `json:"merged_deltas"`   // Deltas merged into the next one ("merge" policy)
+	DroppedDeltas  int64  `json:"dropped_deltas"`  // Deltas discarded ("drop" policy)
+}
+
+// FeatureFlag represents the rollout rule of a feature flag
+type FeatureFlag struct {
+	Object     string   `json:"object"`     // Always "feature_flag"
+	Name       string   `json:"name"`       // Flag name
+	Enabled    bool     `json:"enabled"`    // Enabled for every tenant
+	Percentage int      `json:"percentage"` // Share of tenants enabled, 0-100
+	Tenants    []string `json:"tenants"`    // Tenants always enabled
+	Overridden bool     `json:"overridden"` // Set at runtime, replacing the configured rule
+}
+
+// ListFeatureFlagsResponse represents the list of feature flags
+type ListFeatureFlagsResponse struct {
+	Object string        `json:"object"` // Always "list"
+	Data   []FeatureFlag `json:"data"`   // Every known flag
+}
+
+// UpdateFeatureFlagRequest replaces the rollout rule of a feature flag until
+// the gateway restarts or the override is deleted
+type UpdateFeatureFlagRequest struct {
+	Enabled    bool     `json:"enabled,omitempty"`    // Enable for every tenant
+	Percentage int      `json:"percentage,omitempty"` // Share of tenants to enable, 0-100
+	Tenants    []string `json:"tenants,omitempty"`    // Tenants to always enable
+}
+
+// ModelAlias represents a model name clients can use in place of a backend model
+type ModelAlias struct {
+	Object string `json:"object"` // Always "model_alias"
+	Alias  string `json:"alias"`  // Name used by clients
+	Model  string `json:"model"`  // Model sent to the backend
+}
+
+// ListModelAliasesResponse represents the list of model aliases
+type ListModelAliasesResponse struct {
+	Object string       `json:"object"` // Always "list"
+	Data   []ModelAlias `json:"data"`   // Aliases, sorted by alias
+}
+
+// UpdateModelAliasRequest points a model alias to a backend model until the
+// gateway restarts or the alias is deleted
+type UpdateModelAliasRequest struct {
+	Model string `json:"model"` // Model sent to the backend
+}
+
+// DeleteModelAliasResponse represents the deletion of a model alias
+type DeleteModelAliasResponse struct {
+	Alias   string `json:"alias"`   // Deleted alias
+	Object  string `json:"object"`  // Always "model_alias.deleted"
+	Deleted bool   `json:"deleted"` // Always true
+}
+
+// CompactConversationRequest represents a request to compact a conversation's items
+type CompactConversationRequest struct {
+	DryRun *bool `json:"dry_run,omitempty"` // Report without rewriting (default true)
+}
+
+// CompactConversationResponse reports the changes made by compacting a conversation
+type CompactConversationResponse struct {
+	Object         string                   `json:"object"`          // Always "conversation.compaction"
+	ConversationID string                   `json:"conversation_id"` // Compacted conversation
+	DryRun         bool                     `json:"dry_run"`         // Whether the items were left unchanged
+	ItemsBefore    int                      `json:"items_before"`    // Number of items before compaction
+	ItemsAfter     int                      `json:"items_after"`     // Number of items after compaction
+	Changes        []ConversationItemChange `json:"changes"`         // One entry per affected item
+}
+
+// ConversationItemChange is a change made to a single conversation item
+type ConversationItemChange struct {
+	ItemID string `json:"item_id"`        // Affected item
+	Action string `json:"action"`         // "merged", "dropped", or "normalized"
+	Into   string `json:"into,omitempty"` // Item a merged item was appended to
+}
+
+// HealthResponse reports the status of the gateway's dependencies
+type HealthResponse struct {
+	Status    string                       `json:"status"`     // "ok", "degraded" if any check failed, or "draining" while shutting down
+	Checks    map[string]HealthCheckResult `json:"checks"`     // Result per dependency
+	CheckedAt int64                        `json:"checked_at"` // Unix timestamp of the checks (results are cached briefly)
+}
+
+// HealthCheckResult is the outcome of checking one dependency
+type HealthCheckResult struct {
+	Status    string `json:"status"`          // "ok" or "error"
+	Error     string `json:"error,omitempty"` // Why the check failed
+	LatencyMS int64  `json:"latency_ms"`      // Time the check took, in milliseconds
+}
+
+// AuditLogEntry records a mutating operation performed through the API
+type AuditLogEntry struct {
+	ID           string `json:"id"`                    // Entry ID
+	Object       string `json:"object"`                // Always "audit_log"
+	CreatedAt    int64  `json:"created_at"`            // Unix timestamp of the operation
+	Actor        string `json:"actor,omitempty"`       // Fingerprint of the API key used ("key_" + SHA-256 prefix)
+	Tenant       string `json:"tenant,omitempty"`      // Tenant header value, if configured and sent
+	RemoteAddr   string `json:"remote_addr,omitempty"` // Client address
+	Method       string `json:"method"`                // HTTP method
+	Path         string `json:"path"`                  // Request path
+	Action       string `json:"action"`                // "create", "update", or "delete"
+	ResourceType string `json:"resource_type"`         // "response", "file", "vector_store", "prompt", ...
+	ResourceID   string `json:"resource_id,omitempty"` // Affected resource, when known
+	Status       int    `json:"status"`                // HTTP status of the operation
+}
+
+// ListAuditLogsResponse represents a page of audit log entries, newest first
+type ListAuditLogsResponse struct {
+	Object  string          `json:"object"`             // Always "list"
+	Data    []AuditLogEntry `json:"data"`               // Entries
+	FirstID string          `json:"first_id,omitempty"` // ID of the first entry
+	LastID  string          `json:"last_id,omitempty"`  // ID of the last entry, the cursor for the next page
+	HasMore bool            `json:"has_more"`           // Whether more entries match
+}
+
+// AdminOverviewResponse summarizes the state of the gateway for the admin
+// dashboard
+type AdminOverviewResponse struct {
+	Object    string              `json:"object"`     // Always "admin_overview"
+	Version   string              `json:"version"`    // Gateway version
+	StartedAt int64               `json:"started_at"` // Unix timestamp the gateway started at
+	Counts    AdminOverviewCounts `json:"counts"`     // Stored objects per kind
+	Streams   StreamStatsResponse `json:"streams"`    // Streaming statistics
+}
+
+// AdminOverviewCounts counts the objects stored by the gateway
+type AdminOverviewCounts struct {
+	Responses     int `json:"responses"`     // Stored responses
+	Conversations int `json:"conversations"` // Conversations
+	Files         int `json:"files"`         // Files
+	VectorStores  int `json:"vector_stores"` // Vector stores
+}
+
+// AdminConfigResponse is the part of the gateway configuration shown on the
+// admin dashboard. It holds no credentials.
+type AdminConfigResponse struct {
+	Object       string   `json:"object"`               // Always "admin_config"
+	BackendAPI   string   `json:"backend_api"`          // "responses", "chat_completions" or "ollama"
+	ModelHost    string   `json:"model_host,omitempty"` // Host of the model endpoint
+	SessionStore string   `json:"session_store"`        // Session store type
+	FileStore    string   `json:"file_store"`           // File store type
+	VectorStore  string   `json:"vector_store"`         // Vector store type
+	Embedding    string   `json:"embedding,omitempty"`  // Embedding backend type
+	WebSearch    string   `json:"web_search,omitempty"` // Web search provider
+	Moderation   string   `json:"moderation,omitempty"` // Moderation provider
+	EventBus     string   `json:"event_bus,omitempty"`  // Event bus provider
+	LogLevel     string   `json:"log_level"`            // Logging level at startup
+	Features     []string `json:"features"`             // Optional features that are enabled
+}
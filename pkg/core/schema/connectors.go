@@ -3,33 +3,92 @@
 
 package schema
 
-// Connector represents a registered MCP connector
+// Connector represents a registered connector: an MCP server, or an HTTP
+// tool run by the gateway
 type Connector struct {
-	ConnectorID   string                 `json:"connector_id"`
-	Object        string                 `json:"object"`                 // Always "connector"
-	ConnectorType string                 `json:"connector_type"`         // Always "mcp" for now
-	URL           string                 `json:"url"`                    // MCP server URL
-	ServerLabel   string                 `json:"server_label,omitempty"` // Display label
-	CreatedAt     int64                  `json:"created_at"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+	ConnectorID    string                 `json:"connector_id"`
+	Object         string                 `json:"object"`                     // Always "connector"
+	ConnectorType  string                 `json:"connector_type"`             // "mcp" or "http_tool"
+	URL            string                 `json:"url,omitempty"`              // MCP server URL (HTTP servers)
+	HTTPTool       *HTTPTool              `json:"http_tool,omitempty"`        // Tool definition (http_tool connectors)
+	Command        string                 `json:"command,omitempty"`          // Local command (stdio servers)
+	Args           []string               `json:"args,omitempty"`             // Command arguments (stdio servers)
+	EnvNames       []string               `json:"env_names,omitempty"`        // Names of environment variables set for the command (values omitted)
+	ServerLabel    string                 `json:"server_label,omitempty"`     // Display label
+	Auth           *ConnectorAuthInfo     `json:"auth,omitempty"`             // Authentication (secrets omitted)
+	DeniedTools    []string               `json:"denied_tools,omitempty"`     // Tools never exposed to the model
+	MaxOutputBytes int                    `json:"max_output_bytes,omitempty"` // Limit of the tool output given to the model
+	CreatedAt      int64                  `json:"created_at"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
 }
 
 // RegisterConnectorRequest represents a request to register a connector
 type RegisterConnectorRequest struct {
-	ConnectorID   string                 `json:"connector_id"`   // Required
-	ConnectorType string                 `json:"connector_type"` // Required, must be "mcp"
-	URL           string                 `json:"url"`            // Required
-	ServerLabel   string                 `json:"server_label,omitempty"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+	ConnectorID    string                 `json:"connector_id"`        // Required
+	ConnectorType  string                 `json:"connector_type"`      // Required, "mcp" or "http_tool"
+	URL            string                 `json:"url,omitempty"`       // HTTP server URL; required for mcp connectors unless command is set
+	HTTPTool       *HTTPTool              `json:"http_tool,omitempty"` // Required for http_tool connectors
+	Command        string                 `json:"command,omitempty"`   // Local command to spawn as a stdio server; must be allowed by the gateway config
+	Args           []string               `json:"args,omitempty"`      // Command arguments
+	Env            map[string]string      `json:"env,omitempty"`       // Extra environment variables for the command (stored encrypted)
+	ServerLabel    string                 `json:"server_label,omitempty"`
+	Auth           *ConnectorAuth         `json:"auth,omitempty"`             // Optional, HTTP servers and tools only
+	DeniedTools    []string               `json:"denied_tools,omitempty"`     // Tools never exposed to the model, even if a request allows them
+	MaxOutputBytes int                    `json:"max_output_bytes,omitempty"` // Limit of the tool output given to the model; defaults to engine.max_tool_output_bytes
+	Metadata       map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// HTTPTool defines the single tool of an http_tool connector: when the
+// model calls it, the gateway makes the HTTP request built from the call's
+// arguments and gives the response body to the model. In url, headers and
+// body, {{name}} stands for the argument of that name: URL-escaped in url,
+// as is in headers and JSON-encoded in body.
+type HTTPTool struct {
+	Name        string                 `json:"name"`                                      // Function name the model calls
+	Description string                 `json:"description,omitempty"`                     // Tells the model when to call it
+	Parameters  map[string]interface{} `json:"parameters,omitempty" swaggertype:"object"` // JSON Schema of the arguments
+	Method      string                 `json:"method,omitempty"`                          // GET (default), POST, PUT, PATCH or DELETE
+	URL         string                 `json:"url"`                                       // URL template
+	Headers     map[string]string      `json:"headers,omitempty"`                         // Header templates; credentials belong in auth
+	Body        string                 `json:"body,omitempty"`                            // Body template; POST, PUT and PATCH send the arguments as JSON without one
+}
+
+// ConnectorAuth configures how the gateway authenticates to an MCP server
+// or HTTP tool. Credentials are stored encrypted and never returned by the
+// API.
+type ConnectorAuth struct {
+	Type string `json:"type"` // "bearer", "headers", or "oauth2_client_credentials"
+
+	// Static bearer token (type "bearer")
+	Token string `json:"token,omitempty"`
+
+	// Headers sent with every request (type "headers")
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// OAuth2 client credentials grant (type "oauth2_client_credentials")
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// ConnectorAuthInfo describes a connector's authentication without secrets
+type ConnectorAuthInfo struct {
+	Type        string   `json:"type"`                   // "bearer", "headers", or "oauth2_client_credentials"
+	HeaderNames []string `json:"header_names,omitempty"` // Names of configured headers (type "headers")
+	TokenURL    string   `json:"token_url,omitempty"`    // OAuth2 token endpoint
+	ClientID    string   `json:"client_id,omitempty"`    // OAuth2 client ID
+	Scopes      []string `json:"scopes,omitempty"`       // OAuth2 scopes
 }
 
 // ListConnectorsResponse represents a list of connectors
 type ListConnectorsResponse struct {
-	Object  string      `json:"object"`             // Always "list"
-	Data    []Connector `json:"data"`               // Array of connectors
-	FirstID string      `json:"first_id,omitempty"` // ID of first item
-	LastID  string      `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool        `json:"has_more"`           // Whether there are more results
+	Object     string      `json:"object"`                // Always "list"
+	Data       []Connector `json:"data"`                  // Array of connectors
+	FirstID    string      `json:"first_id,omitempty"`    // ID of first item
+	LastID     string      `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool        `json:"has_more"`              // Whether there are more results
+	TotalCount *int        `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeleteConnectorResponse represents the response from deleting a connector
@@ -9,6 +9,14 @@ type Conversation struct {
 	Object    string                 `json:"object"`     // Always "conversation"
 	CreatedAt int64                  `json:"created_at"` // Unix timestamp
 	Metadata  map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+
+	// Gateway extension: the title of the conversation, its "title"
+	// metadata, set by clients or generated from the first turn
+	Title *string `json:"title,omitempty"`
+
+	// Gateway extension: the response of another conversation this one
+	// was forked from
+	ForkedFrom *string `json:"forked_from,omitempty"`
 }
 
 // CreateConversationRequest represents a request to create a conversation
@@ -26,11 +34,12 @@ type ListConversationsRequest struct {
 
 // ListConversationsResponse represents a list of conversations
 type ListConversationsResponse struct {
-	Object  string         `json:"object"`             // Always "list"
-	Data    []Conversation `json:"data"`               // Array of conversations
-	FirstID string         `json:"first_id,omitempty"` // ID of first item
-	LastID  string         `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool           `json:"has_more"`           // Whether there are more results
+	Object     string         `json:"object"`                // Always "list"
+	Data       []Conversation `json:"data"`                  // Array of conversations
+	FirstID    string         `json:"first_id,omitempty"`    // ID of first item
+	LastID     string         `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool           `json:"has_more"`              // Whether there are more results
+	TotalCount *int           `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeleteConversationResponse represents the response from deleting a conversation
@@ -78,3 +87,63 @@ type ListConversationItemsResponse struct {
 	LastID  string             `json:"last_id,omitempty"`  // ID of last item
 	HasMore bool               `json:"has_more"`           // Whether there are more results
 }
+
+// ConversationExport is a portable bundle of a conversation, its items and
+// the responses made in it, produced by export and accepted by import
+type ConversationExport struct {
+	Object       string             `json:"object"`      // Always "conversation.export"
+	Version      int                `json:"version"`     // Bundle format version, currently 1
+	ExportedAt   int64              `json:"exported_at"` // Unix timestamp
+	Conversation Conversation       `json:"conversation"`
+	Items        []ConversationItem `json:"items"`     // Items, oldest first
+	Responses    []ExportedResponse `json:"responses"` // Responses made in the conversation, oldest first
+}
+
+// ExportedResponse is a stored response in a conversation export
+type ExportedResponse struct {
+	ID                 string            `json:"id"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Status             string            `json:"status"`
+	CreatedAt          int64             `json:"created_at"`             // Unix timestamp
+	CompletedAt        *int64            `json:"completed_at,omitempty"` // Unix timestamp
+	ExternalID         string            `json:"external_id,omitempty"`
+	Request            interface{}       `json:"request,omitempty" swaggertype:"object"` // Request as stored
+	Output             interface{}       `json:"output,omitempty" swaggertype:"object"`  // Output items as stored
+	Error              interface{}       `json:"error,omitempty" swaggertype:"object"`
+	Usage              interface{}       `json:"usage,omitempty" swaggertype:"object"`
+	Messages           []ExportedMessage `json:"messages"` // Full message history, used to continue the conversation
+}
+
+// ExportedMessage is a message of a response's history in a conversation export
+type ExportedMessage struct {
+	Role       string             `json:"role"`
+	Content    string             `json:"content"`
+	ToolCalls  []ExportedToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string             `json:"tool_call_id,omitempty"`
+	Reasoning  *ExportedReasoning `json:"reasoning,omitempty"`
+}
+
+// ExportedReasoning is a reasoning item in an exported message
+type ExportedReasoning struct {
+	ID               string   `json:"id"`
+	Summary          []string `json:"summary,omitempty"`
+	Text             string   `json:"text,omitempty"`
+	EncryptedContent string   `json:"encrypted_content,omitempty"`
+}
+
+// ExportedToolCall is a tool call in an exported message
+type ExportedToolCall struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ImportConversationResponse represents the result of importing a conversation
+type ImportConversationResponse struct {
+	Object       string            `json:"object"` // Always "conversation.import"
+	Conversation Conversation      `json:"conversation"`
+	Items        int               `json:"items"`         // Number of items imported
+	Responses    int               `json:"responses"`     // Number of responses imported
+	IDs          map[string]string `json:"ids,omitempty"` // Bundle IDs mapped to new IDs, unless IDs were preserved
+}
@@ -0,0 +1,78 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import "encoding/json"
+
+// EvalRunRequest represents a request to compare two model configurations
+// over a prompt set
+type EvalRunRequest struct {
+	Name     string            `json:"name,omitempty"`
+	Prompts  []EvalPrompt      `json:"prompts,omitempty"`      // Inline prompt set; mutually exclusive with file_id
+	FileID   string            `json:"file_id,omitempty"`      // JSONL file of {"id", "input"} objects
+	A        json.RawMessage   `json:"a" swaggertype:"object"` // Required: /v1/responses parameters, without input
+	B        json.RawMessage   `json:"b" swaggertype:"object"` // Required: /v1/responses parameters, without input
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// EvalPrompt is a prompt of an eval run
+type EvalPrompt struct {
+	ID    string          `json:"id,omitempty"`               // Default: "prompt_{n}"
+	Input json.RawMessage `json:"input" swaggertype:"object"` // String or input items, as in /v1/responses
+}
+
+// EvalRun represents an eval run and the paired outputs recorded so far
+type EvalRun struct {
+	ID          string            `json:"id"`     // Format: "eval_{id}"
+	Object      string            `json:"object"` // Always "eval.run"
+	Name        string            `json:"name,omitempty"`
+	Status      string            `json:"status"` // "in_progress" or "completed"
+	FileID      string            `json:"file_id,omitempty"`
+	A           json.RawMessage   `json:"a" swaggertype:"object"`
+	B           json.RawMessage   `json:"b" swaggertype:"object"`
+	Summary     EvalSummary       `json:"summary"`
+	Results     []EvalResult      `json:"results"` // One per prompt, in prompt order
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   int64             `json:"created_at"`   // Unix timestamp
+	CompletedAt *int64            `json:"completed_at"` // Unix timestamp, null while in progress
+}
+
+// EvalSummary aggregates the outputs of an eval run
+type EvalSummary struct {
+	Prompts   int                `json:"prompts"`
+	Completed int                `json:"completed"` // Prompts both configurations have run
+	Identical int                `json:"identical"` // Prompts both completed with the same output text
+	A         EvalVariantSummary `json:"a"`
+	B         EvalVariantSummary `json:"b"`
+}
+
+// EvalVariantSummary aggregates the outputs of one configuration
+type EvalVariantSummary struct {
+	Completed        int   `json:"completed"` // Responses with status "completed"
+	Failed           int   `json:"failed"`    // Other outputs
+	InputTokens      int   `json:"input_tokens"`
+	OutputTokens     int   `json:"output_tokens"`
+	AverageLatencyMs int64 `json:"average_latency_ms"`
+}
+
+// EvalResult pairs the outputs of both configurations for one prompt
+type EvalResult struct {
+	PromptID  string          `json:"prompt_id"`
+	Input     json.RawMessage `json:"input" swaggertype:"object"`
+	A         *EvalOutput     `json:"a"`         // Null until configuration A has run
+	B         *EvalOutput     `json:"b"`         // Null until configuration B has run
+	Identical bool            `json:"identical"` // Both completed with the same output text
+}
+
+// EvalOutput is the output of one configuration for one prompt
+type EvalOutput struct {
+	ResponseID   string  `json:"response_id,omitempty"` // Stored response, for the full output
+	Model        string  `json:"model"`
+	Status       string  `json:"status"` // Response status, or "failed" if no response was created
+	OutputText   string  `json:"output_text"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	LatencyMs    int64   `json:"latency_ms"`
+	Error        *string `json:"error"`
+}
@@ -36,11 +36,12 @@ type ListFilesRequest struct {
 
 // ListFilesResponse represents a list of files
 type ListFilesResponse struct {
-	Object  string `json:"object"`             // Always "list"
-	Data    []File `json:"data"`               // Array of files
-	FirstID string `json:"first_id,omitempty"` // ID of first item
-	LastID  string `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool   `json:"has_more"`           // Whether there are more results
+	Object     string `json:"object"`                // Always "list"
+	Data       []File `json:"data"`                  // Array of files
+	FirstID    string `json:"first_id,omitempty"`    // ID of first item
+	LastID     string `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool   `json:"has_more"`              // Whether there are more results
+	TotalCount *int   `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeleteFileResponse represents the response from deleting a file
@@ -49,3 +50,12 @@ type DeleteFileResponse struct {
 	Object  string `json:"object" enums:"file"` // Always "file"
 	Deleted bool   `json:"deleted"`             // Always true
 }
+
+// FileDownloadURL is a time-limited URL from which a file's content can be
+// downloaded directly from the file store
+type FileDownloadURL struct {
+	ID        string `json:"id"`                               // File ID
+	Object    string `json:"object" enums:"file.download_url"` // Always "file.download_url"
+	URL       string `json:"url"`                              // Presigned download URL
+	ExpiresAt int64  `json:"expires_at"`                       // Unix timestamp after which the URL stops working
+}
@@ -0,0 +1,42 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func FuzzResponsesToolParamUnmarshalJSON(f *testing.F) {
+	f.Add(`{"type":"function","name":"f","parameters":{"type":"object"}}`)
+	f.Add(`{"type":"file_search","vector_store_ids":["vs_1"],"max_num_results":3}`)
+	f.Add(`{"type":"file_search","file_search":{"vector_store_ids":["vs_1"],"filters":{"type":"eq","key":"k","value":1}}}`)
+	f.Add(`{"type":"web_search","web_search":{"search_context_size":"low","user_location":{"country":"FR"}}}`)
+	f.Add(`{"type":"mcp","server_label":"x","server_url":"http://s","allowed_tools":["a"]}`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var tool ResponsesToolParam
+		if err := json.Unmarshal([]byte(input), &tool); err != nil {
+			t.Skip()
+		}
+
+		// Round trip: re-encoding the flattened form must be stable.
+		data, err := json.Marshal(&tool)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var again ResponsesToolParam
+		if err := json.Unmarshal(data, &again); err != nil {
+			t.Fatalf("unmarshal of marshalled tool failed: %v\n%s", err, data)
+		}
+		data2, err := json.Marshal(&again)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if !bytes.Equal(data, data2) {
+			t.Fatalf("round trip mismatch:\n first: %s\nsecond: %s", data, data2)
+		}
+	})
+}
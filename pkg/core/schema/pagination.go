@@ -14,11 +14,12 @@ type ListResponsesRequest struct {
 
 // ListResponsesResponse represents a paginated list of responses
 type ListResponsesResponse struct {
-	Object  string     `json:"object"`             // Always "list"
-	Data    []Response `json:"data"`               // Array of responses
-	FirstID string     `json:"first_id,omitempty"` // ID of first item
-	LastID  string     `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool       `json:"has_more"`           // Whether there are more results
+	Object     string     `json:"object"`                // Always "list"
+	Data       []Response `json:"data"`                  // Array of responses
+	FirstID    string     `json:"first_id,omitempty"`    // ID of first item
+	LastID     string     `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool       `json:"has_more"`              // Whether there are more results
+	TotalCount *int       `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeleteResponseResponse represents the response from deleting a response
@@ -32,16 +33,46 @@ type DeleteResponseResponse struct {
 type ListInputItemsRequest struct {
 	After   string   `json:"after,omitempty"`   // Cursor for pagination
 	Before  string   `json:"before,omitempty"`  // Cursor for pagination
-	Limit   int      `json:"limit,omitempty"`   // Number of items (default 50)
-	Order   string   `json:"order,omitempty"`   // Sort order
+	Limit   int      `json:"limit,omitempty"`   // Number of items (1-100, default 20)
+	Order   string   `json:"order,omitempty"`   // Sort order: "asc" or "desc" (default "desc")
 	Include []string `json:"include,omitempty"` // Fields to include
 }
 
 // ListInputItemsResponse represents a list of input items
 type ListInputItemsResponse struct {
-	Object  string        `json:"object"`                    // Always "list"
-	Data    []interface{} `json:"data" swaggertype:"object"` // Input items (messages/files/etc)
-	FirstID string        `json:"first_id,omitempty"`        // ID of first item
-	LastID  string        `json:"last_id,omitempty"`         // ID of last item
-	HasMore bool          `json:"has_more"`                  // Whether there are more results
+	Object  string      `json:"object"`             // Always "list"
+	Data    []InputItem `json:"data"`               // Input items
+	FirstID string      `json:"first_id,omitempty"` // ID of first item
+	LastID  string      `json:"last_id,omitempty"`  // ID of last item
+	HasMore bool        `json:"has_more"`           // Whether there are more results
+}
+
+// InputItem is an item of the input of a response. A string input is
+// listed as a user message with an input_text part, and message content
+// given as a string as a single input_text part (output_text for
+// assistant messages). Items the client gave no ID get one derived from
+// the response and their position.
+type InputItem struct {
+	Type   string `json:"type"`             // "message", "function_call", "function_call_output", "reasoning", "mcp_call", "file_search_call"
+	ID     string `json:"id"`               // required for all item types
+	Status string `json:"status,omitempty"` // "completed" for messages, calls and outputs
+
+	// Message fields (type="message"): input_text, input_image,
+	// input_file and input_audio parts, output_text for assistant messages
+	Role    string                   `json:"role,omitempty"` // "user", "assistant", "system", "developer"
+	Content []map[string]interface{} `json:"content,omitempty"`
+
+	// Function call fields (type="function_call", "function_call_output")
+	CallID    string      `json:"call_id,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Arguments string      `json:"arguments,omitempty"`
+	Output    interface{} `json:"output,omitempty" swaggertype:"object"` // a string or content parts
+
+	// Failed server-side tool call fields (type="mcp_call", "file_search_call")
+	ServerLabel string `json:"server_label,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	// Reasoning fields (type="reasoning")
+	Summary          []map[string]interface{} `json:"summary,omitempty"`
+	EncryptedContent string                   `json:"encrypted_content,omitempty"`
 }
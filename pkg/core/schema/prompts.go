@@ -51,11 +51,12 @@ type ListPromptsRequest struct {
 
 // ListPromptsResponse represents a list of prompts
 type ListPromptsResponse struct {
-	Object  string   `json:"object"`             // Always "list"
-	Data    []Prompt `json:"data"`               // Array of prompts
-	FirstID string   `json:"first_id,omitempty"` // ID of first item
-	LastID  string   `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool     `json:"has_more"`           // Whether there are more results
+	Object     string   `json:"object"`                // Always "list"
+	Data       []Prompt `json:"data"`                  // Array of prompts
+	FirstID    string   `json:"first_id,omitempty"`    // ID of first item
+	LastID     string   `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool     `json:"has_more"`              // Whether there are more results
+	TotalCount *int     `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeletePromptResponse represents the response from deleting a prompt
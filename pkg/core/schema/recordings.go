@@ -0,0 +1,61 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import "encoding/json"
+
+// ResponseRecording represents what the agentic loop of a response received
+// from outside the gateway, recorded for replay
+type ResponseRecording struct {
+	ID           string                `json:"id"`                           // ID of the recorded response
+	Object       string                `json:"object"`                       // Always "response.recording"
+	Status       string                `json:"status"`                       // Status of the recorded response
+	Request      json.RawMessage       `json:"request" swaggertype:"object"` // The request as processed, after hooks, model aliases and parameters
+	MCPTools     []RecordedMCPTools    `json:"mcp_tools,omitempty"`          // Tools listed by the MCP servers of the request
+	BackendCalls []RecordedBackendCall `json:"backend_calls"`                // In call order
+	ToolCalls    []RecordedToolCall    `json:"tool_calls"`                   // Server-side tool calls, in call order
+	CreatedAt    int64                 `json:"created_at"`                   // Unix timestamp
+}
+
+// RecordedMCPTools holds the tools an MCP server listed
+type RecordedMCPTools struct {
+	ServerLabel string          `json:"server_label"`
+	Tools       json.RawMessage `json:"tools" swaggertype:"array,object"`
+}
+
+// RecordedBackendCall holds a backend request and what the backend returned
+type RecordedBackendCall struct {
+	Request  json.RawMessage       `json:"request" swaggertype:"object"`
+	Response json.RawMessage       `json:"response,omitempty" swaggertype:"object"` // Non-streaming calls
+	Events   []RecordedStreamEvent `json:"events,omitempty"`                        // Streaming calls
+	Error    string                `json:"error,omitempty"`                         // The call failed
+}
+
+// RecordedStreamEvent is an event streamed by the backend
+type RecordedStreamEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// RecordedToolCall holds the result of a server-side tool call
+type RecordedToolCall struct {
+	CallID string          `json:"call_id"`
+	Name   string          `json:"name"`
+	Result json.RawMessage `json:"result,omitempty" swaggertype:"object"` // Tool-specific
+	Error  string          `json:"error,omitempty"`                       // The call failed
+}
+
+// ResponseReplay represents the replay of a recorded response
+type ResponseReplay struct {
+	Object      string             `json:"object"`    // Always "response.replay"
+	ReplayOf    string             `json:"replay_of"` // ID of the recorded response
+	Response    *Response          `json:"response"`  // The replayed response
+	Divergences []ReplayDivergence `json:"divergences"`
+}
+
+// ReplayDivergence describes where a replay departed from its recording
+type ReplayDivergence struct {
+	Type    string `json:"type"` // "backend_request", "backend_call", "tool_call" or "mcp_tools"
+	Message string `json:"message"`
+}
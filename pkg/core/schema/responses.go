@@ -6,7 +6,9 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
+	"unicode/utf8"
 )
 
 // ResponseRequest represents a request to the /v1/responses endpoint
@@ -71,14 +73,67 @@ type ResponseRequest struct {
 	// Service tier preference
 	ServiceTier *string `json:"service_tier,omitempty"`
 
+	// Groups requests that share a long prompt prefix, to improve the backend's prompt cache hit rate
+	PromptCacheKey *string `json:"prompt_cache_key,omitempty"`
+
 	// Whether the gateway should persist the response (gateway-managed)
 	Store *bool `json:"store,omitempty"`
 
 	// Whether to stream the response (HTTP-specific, not in spec but required for SSE)
 	Stream bool `json:"stream,omitempty"`
 
+	// Run the response in the background: it is returned at once, in_progress, and retrieved when done. Requires the background_mode feature flag
+	Background *bool `json:"background,omitempty"`
+
+	// Which streaming events to send; only valid when stream is true
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
 	// Prompt reference for template resolution (mutually exclusive with instructions)
 	Prompt *PromptReference `json:"prompt,omitempty"`
+
+	// Client-supplied identifier stored alongside the response (gateway extension)
+	ExternalID *string `json:"external_id,omitempty"`
+
+	// Wall-clock limit in seconds for the agentic loop (gateway extension)
+	MaxDurationSeconds *int `json:"max_duration_seconds,omitempty"`
+
+	// Maximum number of backend model calls across the agentic loop (gateway extension)
+	MaxBackendCalls *int `json:"max_backend_calls,omitempty"`
+
+	// Maximum input plus output tokens across all backend calls (gateway extension)
+	MaxTotalTokens *int `json:"max_total_tokens,omitempty"`
+
+	// How instructions combine with the conversation's stored system prompt: replace (default), inherit, prepend, or append (gateway extension)
+	InstructionsMerge *string `json:"instructions_merge,omitempty"`
+
+	// Number of candidate outputs to sample, each a message tagged with its candidate_index (gateway extension)
+	CandidateCount *int `json:"candidate_count,omitempty"`
+}
+
+// StreamOptions selects the streaming events sent to the client, to save
+// bandwidth when only some of them are used. Error events are always sent.
+type StreamOptions struct {
+	// Event types to send; all others are dropped (gateway extension)
+	IncludeEvents []string `json:"include_events,omitempty"`
+
+	// Event types to drop (gateway extension)
+	ExcludeEvents []string `json:"exclude_events,omitempty"`
+
+	// Emit a response.usage event after every backend call of the agentic
+	// loop (gateway extension)
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// Allows reports whether events of eventType should be sent. A nil
+// StreamOptions allows every event.
+func (o *StreamOptions) Allows(eventType string) bool {
+	if o == nil || eventType == "error" {
+		return true
+	}
+	if len(o.IncludeEvents) > 0 && !slices.Contains(o.IncludeEvents, eventType) {
+		return false
+	}
+	return !slices.Contains(o.ExcludeEvents, eventType)
 }
 
 // PromptReference references a stored prompt template with optional variable values.
@@ -89,8 +144,41 @@ type PromptReference struct {
 	// Specific version (default: latest/default)
 	Version *int `json:"version,omitempty"`
 
-	// Template variable values
-	Variables map[string]string `json:"variables,omitempty"`
+	// Template variable values: a string or an input content part
+	Variables map[string]PromptVariable `json:"variables,omitempty"`
+}
+
+// PromptVariable is the value of a prompt template variable. It is either a
+// plain string (decoded as input_text) or an input content part object.
+type PromptVariable struct {
+	Type     string `json:"type"` // "input_text", "input_image", "input_file"
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// UnmarshalJSON accepts either a JSON string or a content part object.
+func (v *PromptVariable) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = PromptVariable{Type: "input_text", Text: s}
+		return nil
+	}
+	type Alias PromptVariable
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return fmt.Errorf("prompt variable must be a string or content part: %w", err)
+	}
+	switch alias.Type {
+	case "input_text", "input_image", "input_file":
+	default:
+		return fmt.Errorf("unsupported prompt variable type %q", alias.Type)
+	}
+	*v = PromptVariable(alias)
+	return nil
 }
 
 // Response represents a response from the API
@@ -152,13 +240,55 @@ type Response struct {
 	// Service tier (echoed from request)
 	ServiceTier *string `json:"service_tier"` // nullable
 
+	// Prompt cache key (echoed from request)
+	PromptCacheKey *string `json:"prompt_cache_key,omitempty"`
+
 	// Gateway-managed persistence flag
 	Store bool `json:"store"` // required, default true
+
+	// Whether the response runs in the background (echoed from request)
+	Background *bool `json:"background,omitempty"`
+
+	// Client-supplied identifier (echoed from request, gateway extension)
+	ExternalID *string `json:"external_id,omitempty"`
+
+	// Agentic loop limits (echoed from request, gateway extension)
+	MaxDurationSeconds *int `json:"max_duration_seconds,omitempty"`
+	MaxBackendCalls    *int `json:"max_backend_calls,omitempty"`
+	MaxTotalTokens     *int `json:"max_total_tokens,omitempty"`
+
+	// Instructions merge strategy (echoed from request, gateway extension)
+	InstructionsMerge *string `json:"instructions_merge,omitempty"`
+
+	// Number of candidate outputs sampled (echoed from request, gateway extension)
+	CandidateCount *int `json:"candidate_count,omitempty"`
+
+	// SHA-256 of the instructions sent to the backend after merging (gateway extension)
+	EffectiveInstructionsHash *string `json:"effective_instructions_sha256,omitempty"`
+
+	// Where and how the output was generated, when provenance is enabled (gateway extension)
+	Provenance *ProvenanceField `json:"provenance,omitempty"`
+
+	// Alias the client requested the model by, when it was resolved to Model (gateway extension)
+	ModelAlias *string `json:"model_alias,omitempty"`
+
+	// Whether the output was served from the response cache (reported in a header, not the body)
+	CacheHit bool `json:"-"`
+}
+
+// ProvenanceField records where and how a response's output was generated
+type ProvenanceField struct {
+	GatewayVersion        string `json:"gateway_version"`         // version of the gateway that produced the response
+	Model                 string `json:"model"`                   // model that generated the output
+	BackendEndpointSHA256 string `json:"backend_endpoint_sha256"` // SHA-256 of the backend endpoint URL
+	GeneratedAt           int64  `json:"generated_at"`            // Unix timestamp at which the output was finalized
+	ContentSHA256         string `json:"content_sha256"`          // SHA-256 of the output text, parts joined by newlines
+	Watermarked           bool   `json:"watermarked"`             // whether a watermark was embedded in the output text
 }
 
 // ItemField represents an output item (discriminated union by type)
 type ItemField struct {
-	Type string `json:"type"` // "message", "function_call", "function_call_output", "reasoning"
+	Type string `json:"type"` // "message", "function_call", "function_call_output", "reasoning", "mcp_call", "file_search_call"
 	ID   string `json:"id"`   // required for all item types
 
 	// Message fields (required when type="message")
@@ -174,17 +304,31 @@ type ItemField struct {
 	// Function output fields (required when type="function_call_output")
 	Output *string `json:"output,omitempty"`
 
-	// Reasoning fields (required when type="reasoning")
-	Summary *string `json:"summary,omitempty"`
+	// Server-side tool call fields, set on the "mcp_call" and
+	// "file_search_call" items of calls that failed (status "failed")
+	ServerLabel *string  `json:"server_label,omitempty"`
+	Queries     []string `json:"queries,omitempty"`
+	Error       *string  `json:"error,omitempty"`
+
+	// Candidate that produced the item when several were sampled (gateway extension)
+	CandidateIndex *int `json:"candidate_index,omitempty"`
+
+	// Reasoning fields (type="reasoning"). The reasoning text, when the
+	// backend exposes it, is in Content as "reasoning_text" parts.
+	Summary          []ContentPart `json:"summary,omitempty"` // "summary_text" parts
+	EncryptedContent *string       `json:"encrypted_content,omitempty"`
 }
 
 // ContentPart represents a part of message content
 type ContentPart struct {
-	Type string `json:"type"` // "text", "image", "file", "video", "refusal", "output_text_annotation"
+	Type string `json:"type"` // "text", "image", "file", "video", "output_audio", "refusal", "output_text_annotation"
 
 	// Text content
 	Text *string `json:"text,omitempty"`
 
+	// Refusal content (type="refusal")
+	Refusal *string `json:"refusal,omitempty"`
+
 	// Image content
 	ImageURL *ImageURL `json:"image_url,omitempty"`
 
@@ -194,6 +338,12 @@ type ContentPart struct {
 	// Video content
 	VideoURL *VideoURL `json:"video_url,omitempty"`
 
+	// Audio content (type="output_audio"): base64 data, its format and
+	// its transcript
+	Data       *string `json:"data,omitempty"`
+	Format     *string `json:"format,omitempty"`
+	Transcript *string `json:"transcript,omitempty"`
+
 	// Annotation fields
 	StartIndex *int `json:"start_index,omitempty"`
 	EndIndex   *int `json:"end_index,omitempty"`
@@ -260,7 +410,8 @@ type ResponsesToolParam struct {
 	Strict      *bool                  `json:"strict,omitempty"`
 
 	// MCP fields (type="mcp")
-	ServerLabel string `json:"server_label,omitempty"` // matches connector_id
+	ServerLabel  string           `json:"server_label,omitempty"`  // matches connector_id
+	AllowedTools *MCPAllowedTools `json:"allowed_tools,omitempty"` // expose only these server tools (default: all)
 
 	// Web search fields (type="web_search")
 	SearchContextSize *string                `json:"search_context_size,omitempty"`
@@ -273,6 +424,42 @@ type ResponsesToolParam struct {
 	Filters        interface{}            `json:"filters,omitempty" swaggertype:"object"`
 }
 
+// MCPAllowedTools restricts which tools of an MCP server are exposed to the
+// model. It is sent either as a list of tool names or as a filter object.
+type MCPAllowedTools struct {
+	ToolNames []string `json:"tool_names,omitempty"` // tool names to expose
+	ReadOnly  bool     `json:"read_only,omitempty"`  // expose only tools annotated as read-only
+}
+
+// UnmarshalJSON accepts both the list form and the filter object form:
+//
+//	"allowed_tools": ["search", "fetch"]
+//	"allowed_tools": {"tool_names": ["search"], "read_only": true}
+func (a *MCPAllowedTools) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*a = MCPAllowedTools{ToolNames: names}
+		return nil
+	}
+	type Alias MCPAllowedTools
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return fmt.Errorf("allowed_tools must be a list of tool names or an object: %w", err)
+	}
+	*a = MCPAllowedTools(alias)
+	return nil
+}
+
+// MarshalJSON emits the list form when only tool names are set, so that an
+// empty list (no tools allowed) survives a round trip.
+func (a MCPAllowedTools) MarshalJSON() ([]byte, error) {
+	if a.ToolNames != nil && !a.ReadOnly {
+		return json.Marshal(a.ToolNames)
+	}
+	type Alias MCPAllowedTools
+	return json.Marshal(Alias(a))
+}
+
 // UnmarshalJSON handles both the flat format used by the Open Responses spec
 // and the nested format sent by the OpenAI SDK.
 //
@@ -348,7 +535,8 @@ type ResponsesTool struct {
 	Strict      *bool                  `json:"strict"`                          // nullable
 
 	// MCP fields
-	ServerLabel string `json:"server_label,omitempty"`
+	ServerLabel  string           `json:"server_label,omitempty"`
+	AllowedTools *MCPAllowedTools `json:"allowed_tools,omitempty"`
 
 	// Web search fields
 	SearchContextSize *string                `json:"search_context_size,omitempty"`
@@ -511,79 +699,106 @@ type ResponseOutputTextDoneStreamingEvent struct {
 
 // ResponseRefusalDeltaStreamingEvent - response.refusal.delta
 type ResponseRefusalDeltaStreamingEvent struct {
-	Type         string `json:"type"` // "response.refusal.delta"
-	ResponseID   string `json:"response_id"`
-	OutputIndex  int    `json:"output_index"`
-	ContentIndex int    `json:"content_index"`
-	Delta        string `json:"delta"`
+	Type           string `json:"type"` // "response.refusal.delta"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	ContentIndex   int    `json:"content_index"`
+	Delta          string `json:"delta"`
 }
 
 // ResponseRefusalDoneStreamingEvent - response.refusal.done
 type ResponseRefusalDoneStreamingEvent struct {
-	Type         string `json:"type"` // "response.refusal.done"
-	ResponseID   string `json:"response_id"`
-	OutputIndex  int    `json:"output_index"`
-	ContentIndex int    `json:"content_index"`
-	Refusal      string `json:"refusal"`
+	Type           string `json:"type"` // "response.refusal.done"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	ContentIndex   int    `json:"content_index"`
+	Refusal        string `json:"refusal"`
 }
 
 // ResponseReasoningDeltaStreamingEvent - response.reasoning.delta
 type ResponseReasoningDeltaStreamingEvent struct {
-	Type        string `json:"type"` // "response.reasoning.delta"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Delta       string `json:"delta"`
+	Type           string `json:"type"` // "response.reasoning.delta"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Delta          string `json:"delta"`
 }
 
 // ResponseReasoningDoneStreamingEvent - response.reasoning.done
 type ResponseReasoningDoneStreamingEvent struct {
-	Type        string `json:"type"` // "response.reasoning.done"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Reasoning   string `json:"reasoning"`
+	Type           string `json:"type"` // "response.reasoning.done"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Reasoning      string `json:"reasoning"`
 }
 
 // ResponseReasoningSummaryDeltaStreamingEvent - response.reasoning_summary.delta
 type ResponseReasoningSummaryDeltaStreamingEvent struct {
-	Type        string `json:"type"` // "response.reasoning_summary.delta"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Delta       string `json:"delta"`
+	Type           string `json:"type"` // "response.reasoning_summary.delta"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Delta          string `json:"delta"`
 }
 
 // ResponseReasoningSummaryDoneStreamingEvent - response.reasoning_summary.done
 type ResponseReasoningSummaryDoneStreamingEvent struct {
-	Type        string `json:"type"` // "response.reasoning_summary.done"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Summary     string `json:"summary"`
+	Type           string `json:"type"` // "response.reasoning_summary.done"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Summary        string `json:"summary"`
 }
 
 // ResponseReasoningSummaryPartAddedStreamingEvent - response.reasoning_summary_part.added
 type ResponseReasoningSummaryPartAddedStreamingEvent struct {
-	Type         string      `json:"type"` // "response.reasoning_summary_part.added"
-	ResponseID   string      `json:"response_id"`
-	OutputIndex  int         `json:"output_index"`
-	ContentIndex int         `json:"content_index"`
-	Part         ContentPart `json:"part"`
+	Type           string      `json:"type"` // "response.reasoning_summary_part.added"
+	SequenceNumber int         `json:"sequence_number"`
+	ResponseID     string      `json:"response_id"`
+	OutputIndex    int         `json:"output_index"`
+	ContentIndex   int         `json:"content_index"`
+	Part           ContentPart `json:"part"`
 }
 
 // ResponseReasoningSummaryPartDoneStreamingEvent - response.reasoning_summary_part.done
 type ResponseReasoningSummaryPartDoneStreamingEvent struct {
-	Type         string      `json:"type"` // "response.reasoning_summary_part.done"
-	ResponseID   string      `json:"response_id"`
-	OutputIndex  int         `json:"output_index"`
-	ContentIndex int         `json:"content_index"`
-	Part         ContentPart `json:"part"`
+	Type           string      `json:"type"` // "response.reasoning_summary_part.done"
+	SequenceNumber int         `json:"sequence_number"`
+	ResponseID     string      `json:"response_id"`
+	OutputIndex    int         `json:"output_index"`
+	ContentIndex   int         `json:"content_index"`
+	Part           ContentPart `json:"part"`
 }
 
 // ResponseOutputTextAnnotationAddedStreamingEvent - response.output_text_annotation.added
 type ResponseOutputTextAnnotationAddedStreamingEvent struct {
-	Type         string      `json:"type"` // "response.output_text_annotation.added"
-	ResponseID   string      `json:"response_id"`
-	OutputIndex  int         `json:"output_index"`
-	ContentIndex int         `json:"content_index"`
-	Annotation   ContentPart `json:"annotation"`
+	Type           string      `json:"type"` // "response.output_text_annotation.added"
+	SequenceNumber int         `json:"sequence_number"`
+	ResponseID     string      `json:"response_id"`
+	OutputIndex    int         `json:"output_index"`
+	ContentIndex   int         `json:"content_index"`
+	Annotation     ContentPart `json:"annotation"`
+}
+
+// ResponseUsageStreamingEvent - response.usage (gateway extension). Sent
+// after each backend call when stream_options.include_usage is set, so
+// clients can show the running cost of long agentic runs.
+type ResponseUsageStreamingEvent struct {
+	Type           string     `json:"type"` // "response.usage"
+	SequenceNumber int        `json:"sequence_number"`
+	ResponseID     string     `json:"response_id"`
+	Iteration      int        `json:"iteration"`   // 0-based agentic loop iteration
+	Usage          UsageField `json:"usage"`       // usage of this backend call
+	TotalUsage     UsageField `json:"total_usage"` // usage of every backend call so far
 }
 
 // ResponseFileSearchCallInProgressStreamingEvent - response.file_search_call.in_progress
@@ -610,6 +825,24 @@ type ResponseFileSearchCallCompletedStreamingEvent struct {
 	ItemID         string `json:"item_id"`
 }
 
+// ResponseFileSearchCallFailedStreamingEvent - response.file_search_call.failed
+// (gateway extension). Sent instead of response.file_search_call.completed
+// when the search failed.
+type ResponseFileSearchCallFailedStreamingEvent struct {
+	Type           string `json:"type"` // "response.file_search_call.failed"
+	SequenceNumber int    `json:"sequence_number"`
+	OutputIndex    int    `json:"output_index"`
+	ItemID         string `json:"item_id"`
+}
+
+// ResponseMCPCallFailedStreamingEvent - response.mcp_call.failed
+type ResponseMCPCallFailedStreamingEvent struct {
+	Type           string `json:"type"` // "response.mcp_call.failed"
+	SequenceNumber int    `json:"sequence_number"`
+	OutputIndex    int    `json:"output_index"`
+	ItemID         string `json:"item_id"`
+}
+
 // ResponseWebSearchCallInProgressStreamingEvent - response.web_search_call.in_progress
 type ResponseWebSearchCallInProgressStreamingEvent struct {
 	Type           string `json:"type"` // "response.web_search_call.in_progress"
@@ -636,39 +869,139 @@ type ResponseWebSearchCallCompletedStreamingEvent struct {
 
 // ResponseFunctionCallArgumentsDeltaStreamingEvent - response.function_call_arguments.delta
 type ResponseFunctionCallArgumentsDeltaStreamingEvent struct {
-	Type        string `json:"type"` // "response.function_call_arguments.delta"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Delta       string `json:"delta"`
+	Type           string `json:"type"` // "response.function_call_arguments.delta"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Delta          string `json:"delta"`
 }
 
 // ResponseFunctionCallArgumentsDoneStreamingEvent - response.function_call_arguments.done
 type ResponseFunctionCallArgumentsDoneStreamingEvent struct {
-	Type        string `json:"type"` // "response.function_call_arguments.done"
-	ResponseID  string `json:"response_id"`
-	OutputIndex int    `json:"output_index"`
-	Arguments   string `json:"arguments"`
+	Type           string `json:"type"` // "response.function_call_arguments.done"
+	SequenceNumber int    `json:"sequence_number"`
+	ResponseID     string `json:"response_id"`
+	ItemID         string `json:"item_id"`
+	OutputIndex    int    `json:"output_index"`
+	Name           string `json:"name,omitempty"`
+	Arguments      string `json:"arguments"`
 }
 
 // ErrorStreamingEvent - error
 type ErrorStreamingEvent struct {
-	Type  string     `json:"type"` // "error"
-	Error ErrorField `json:"error"`
+	Type           string     `json:"type"` // "error"
+	SequenceNumber int        `json:"sequence_number"`
+	Error          ErrorField `json:"error"`
+}
+
+// StreamingEvents lists the events a /v1/responses stream can carry: each
+// event type with a value of its struct. It documents the stream in the
+// OpenAPI spec.
+var StreamingEvents = []struct {
+	Type  string
+	Event interface{}
+}{
+	{"response.created", ResponseCreatedStreamingEvent{}},
+	{"response.queued", ResponseQueuedStreamingEvent{}},
+	{"response.in_progress", ResponseInProgressStreamingEvent{}},
+	{"response.completed", ResponseCompletedStreamingEvent{}},
+	{"response.failed", ResponseFailedStreamingEvent{}},
+	{"response.incomplete", ResponseIncompleteStreamingEvent{}},
+	{"response.output_item.added", ResponseOutputItemAddedStreamingEvent{}},
+	{"response.output_item.done", ResponseOutputItemDoneStreamingEvent{}},
+	{"response.content_part.added", ResponseContentPartAddedStreamingEvent{}},
+	{"response.content_part.done", ResponseContentPartDoneStreamingEvent{}},
+	{"response.output_text.delta", ResponseOutputTextDeltaStreamingEvent{}},
+	{"response.output_text.done", ResponseOutputTextDoneStreamingEvent{}},
+	{"response.refusal.delta", ResponseRefusalDeltaStreamingEvent{}},
+	{"response.refusal.done", ResponseRefusalDoneStreamingEvent{}},
+	{"response.reasoning.delta", ResponseReasoningDeltaStreamingEvent{}},
+	{"response.reasoning.done", ResponseReasoningDoneStreamingEvent{}},
+	{"response.reasoning_summary.delta", ResponseReasoningSummaryDeltaStreamingEvent{}},
+	{"response.reasoning_summary.done", ResponseReasoningSummaryDoneStreamingEvent{}},
+	{"response.reasoning_summary_part.added", ResponseReasoningSummaryPartAddedStreamingEvent{}},
+	{"response.reasoning_summary_part.done", ResponseReasoningSummaryPartDoneStreamingEvent{}},
+	{"response.output_text_annotation.added", ResponseOutputTextAnnotationAddedStreamingEvent{}},
+	{"response.usage", ResponseUsageStreamingEvent{}},
+	{"response.file_search_call.in_progress", ResponseFileSearchCallInProgressStreamingEvent{}},
+	{"response.file_search_call.searching", ResponseFileSearchCallSearchingStreamingEvent{}},
+	{"response.file_search_call.completed", ResponseFileSearchCallCompletedStreamingEvent{}},
+	{"response.file_search_call.failed", ResponseFileSearchCallFailedStreamingEvent{}},
+	{"response.mcp_call.failed", ResponseMCPCallFailedStreamingEvent{}},
+	{"response.web_search_call.in_progress", ResponseWebSearchCallInProgressStreamingEvent{}},
+	{"response.web_search_call.searching", ResponseWebSearchCallSearchingStreamingEvent{}},
+	{"response.web_search_call.completed", ResponseWebSearchCallCompletedStreamingEvent{}},
+	{"response.function_call_arguments.delta", ResponseFunctionCallArgumentsDeltaStreamingEvent{}},
+	{"response.function_call_arguments.done", ResponseFunctionCallArgumentsDoneStreamingEvent{}},
+	{"error", ErrorStreamingEvent{}},
+}
+
+// MaxExternalIDLength is the maximum length of a client-supplied external_id.
+const MaxExternalIDLength = 512
+
+// MaxCandidateCount is the maximum number of candidates a request can sample.
+const MaxCandidateCount = 8
+
+// Metadata limits of the API.
+const (
+	MaxMetadataEntries     = 16
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+)
+
+// LimitError is returned when a request exceeds a size limit. The HTTP
+// adapter answers it with 413 Request Entity Too Large.
+type LimitError struct {
+	Param   string // the request field over the limit
+	Message string
 }
 
-// RawStreamingEvent wraps a pre-serialized SSE event from the backend.
-// It implements json.Marshaler so the HTTP adapter can forward it as-is.
-// @swaggerignore
-type RawStreamingEvent struct {
-	EventType string          // SSE event type (e.g. "response.output_text.delta")
-	RawData   json.RawMessage // Pre-serialized JSON payload
+func (e *LimitError) Error() string { return e.Message }
+
+// RequestLimits are the configurable size limits of a request. Zero means
+// unlimited.
+type RequestLimits struct {
+	MaxInputItems int
+	MaxTools      int
 }
 
-// MarshalJSON returns the raw data directly, avoiding double-serialization.
-func (e *RawStreamingEvent) MarshalJSON() ([]byte, error) {
-	return e.RawData, nil
+// CheckLimits returns a *LimitError if the request exceeds limits.
+func (r *ResponseRequest) CheckLimits(limits RequestLimits) error {
+	if items, ok := r.Input.([]interface{}); ok && limits.MaxInputItems > 0 && len(items) > limits.MaxInputItems {
+		return &LimitError{Param: "input", Message: fmt.Sprintf("'input' has %d items, more than the limit of %d", len(items), limits.MaxInputItems)}
+	}
+	if limits.MaxTools > 0 && len(r.Tools) > limits.MaxTools {
+		return &LimitError{Param: "tools", Message: fmt.Sprintf("'tools' has %d tools, more than the limit of %d", len(r.Tools), limits.MaxTools)}
+	}
+	return nil
+}
+
+// ValidateMetadata returns a *LimitError if metadata, the value of param,
+// exceeds the metadata limits of the API.
+func ValidateMetadata(param string, metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return &LimitError{Param: param, Message: fmt.Sprintf("'%s' has %d entries, more than the limit of %d", param, len(metadata), MaxMetadataEntries)}
+	}
+	for key, value := range metadata {
+		if utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+			return &LimitError{Param: param, Message: fmt.Sprintf("'%s' key %q is longer than %d characters", param, key, MaxMetadataKeyLength)}
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return &LimitError{Param: param, Message: fmt.Sprintf("'%s' value of key %q is longer than %d characters", param, key, MaxMetadataValueLength)}
+		}
+	}
+	return nil
 }
 
+// Instruction merge strategies for ResponseRequest.InstructionsMerge.
+const (
+	InstructionsMergeReplace = "replace"
+	InstructionsMergeInherit = "inherit"
+	InstructionsMergePrepend = "prepend"
+	InstructionsMergeAppend  = "append"
+)
+
 // Validate validates the request
 func (r *ResponseRequest) Validate() error {
 	if r.Model == nil || *r.Model == "" {
@@ -681,6 +1014,51 @@ func (r *ResponseRequest) Validate() error {
 		r.PreviousResponseID != nil && *r.PreviousResponseID != "" {
 		return fmt.Errorf("'conversation' and 'previous_response_id' are mutually exclusive")
 	}
+	if r.ExternalID != nil && len(*r.ExternalID) > MaxExternalIDLength {
+		return fmt.Errorf("'external_id' must be at most %d characters", MaxExternalIDLength)
+	}
+	if err := ValidateMetadata("metadata", r.Metadata); err != nil {
+		return err
+	}
+	for _, limit := range []struct {
+		name  string
+		value *int
+	}{
+		{"max_duration_seconds", r.MaxDurationSeconds},
+		{"max_backend_calls", r.MaxBackendCalls},
+		{"max_total_tokens", r.MaxTotalTokens},
+	} {
+		if limit.value != nil && *limit.value <= 0 {
+			return fmt.Errorf("'%s' must be a positive integer", limit.name)
+		}
+	}
+	if r.Background != nil && *r.Background && r.Store != nil && !*r.Store {
+		return fmt.Errorf("'background' requires 'store' to be true")
+	}
+	if r.StreamOptions != nil {
+		if !r.Stream {
+			return fmt.Errorf("'stream_options' requires 'stream' to be true")
+		}
+		if len(r.StreamOptions.IncludeEvents) > 0 && len(r.StreamOptions.ExcludeEvents) > 0 {
+			return fmt.Errorf("'include_events' and 'exclude_events' are mutually exclusive")
+		}
+	}
+	if r.CandidateCount != nil {
+		if *r.CandidateCount < 1 || *r.CandidateCount > MaxCandidateCount {
+			return fmt.Errorf("'candidate_count' must be between 1 and %d", MaxCandidateCount)
+		}
+		if *r.CandidateCount > 1 && len(r.Tools) > 0 {
+			return fmt.Errorf("'candidate_count' greater than 1 cannot be combined with tools")
+		}
+	}
+	if r.InstructionsMerge != nil {
+		switch *r.InstructionsMerge {
+		case InstructionsMergeReplace, InstructionsMergeInherit, InstructionsMergePrepend, InstructionsMergeAppend:
+		default:
+			return fmt.Errorf("'instructions_merge' must be one of %q, %q, %q or %q",
+				InstructionsMergeReplace, InstructionsMergeInherit, InstructionsMergePrepend, InstructionsMergeAppend)
+		}
+	}
 	return nil
 }
 
@@ -781,12 +1159,18 @@ func ExtractEventType(event interface{}) string {
 		return e.Type
 	case *ResponseOutputTextAnnotationAddedStreamingEvent:
 		return e.Type
+	case *ResponseUsageStreamingEvent:
+		return e.Type
 	case *ResponseFileSearchCallInProgressStreamingEvent:
 		return e.Type
 	case *ResponseFileSearchCallSearchingStreamingEvent:
 		return e.Type
 	case *ResponseFileSearchCallCompletedStreamingEvent:
 		return e.Type
+	case *ResponseFileSearchCallFailedStreamingEvent:
+		return e.Type
+	case *ResponseMCPCallFailedStreamingEvent:
+		return e.Type
 	case *ResponseWebSearchCallInProgressStreamingEvent:
 		return e.Type
 	case *ResponseWebSearchCallSearchingStreamingEvent:
@@ -799,9 +1183,81 @@ func ExtractEventType(event interface{}) string {
 		return e.Type
 	case *ErrorStreamingEvent:
 		return e.Type
-	case *RawStreamingEvent:
-		return e.EventType
 	default:
 		return "message"
 	}
 }
+
+// SetSequenceNumber sets the sequence_number of a streaming event. Events
+// are numbered as they are sent, so that the numbering stays contiguous
+// whichever code path produced them.
+func SetSequenceNumber(event interface{}, n int) {
+	switch e := event.(type) {
+	case *ResponseCreatedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseQueuedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseInProgressStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseCompletedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFailedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseIncompleteStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseOutputItemAddedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseOutputItemDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseContentPartAddedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseContentPartDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseOutputTextDeltaStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseOutputTextDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseRefusalDeltaStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseRefusalDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningDeltaStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningSummaryDeltaStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningSummaryDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningSummaryPartAddedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseReasoningSummaryPartDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseOutputTextAnnotationAddedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseUsageStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFileSearchCallInProgressStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFileSearchCallSearchingStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFileSearchCallCompletedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFileSearchCallFailedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseMCPCallFailedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseWebSearchCallInProgressStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseWebSearchCallSearchingStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseWebSearchCallCompletedStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFunctionCallArgumentsDeltaStreamingEvent:
+		e.SequenceNumber = n
+	case *ResponseFunctionCallArgumentsDoneStreamingEvent:
+		e.SequenceNumber = n
+	case *ErrorStreamingEvent:
+		e.SequenceNumber = n
+	}
+}
@@ -5,6 +5,9 @@ package schema
 
 import (
 	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -140,3 +143,197 @@ func TestResponsesToolParam_UnmarshalJSON_FlatTakesPrecedence(t *testing.T) {
 		t.Errorf("VectorStoreIDs = %v, want [vs_flat] (flat should take precedence)", tool.VectorStoreIDs)
 	}
 }
+
+func TestPromptReference_UnmarshalJSON_Variables(t *testing.T) {
+	input := `{"id":"prompt_1","variables":{
+		"name":"Alice",
+		"topic":{"type":"input_text","text":"billing"},
+		"photo":{"type":"input_image","image_url":"https://example.com/a.png","detail":"low"},
+		"doc":{"type":"input_file","file_id":"file_1"}
+	}}`
+
+	var ref PromptReference
+	if err := json.Unmarshal([]byte(input), &ref); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	if v := ref.Variables["name"]; v.Type != "input_text" || v.Text != "Alice" {
+		t.Errorf("name = %+v, want input_text Alice", v)
+	}
+	if v := ref.Variables["topic"]; v.Type != "input_text" || v.Text != "billing" {
+		t.Errorf("topic = %+v, want input_text billing", v)
+	}
+	if v := ref.Variables["photo"]; v.Type != "input_image" || v.ImageURL != "https://example.com/a.png" || v.Detail != "low" {
+		t.Errorf("photo = %+v", v)
+	}
+	if v := ref.Variables["doc"]; v.Type != "input_file" || v.FileID != "file_1" {
+		t.Errorf("doc = %+v", v)
+	}
+}
+
+func TestPromptVariable_UnmarshalJSON_Invalid(t *testing.T) {
+	for _, input := range []string{`42`, `{"type":"output_text","text":"x"}`} {
+		var v PromptVariable
+		if err := json.Unmarshal([]byte(input), &v); err == nil {
+			t.Errorf("expected error for %s, got %+v", input, v)
+		}
+	}
+}
+
+func TestMCPAllowedTools_JSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantNames []string
+		wantRO    bool
+		wantJSON  string
+	}{
+		{"list", `["search","fetch"]`, []string{"search", "fetch"}, false, `["search","fetch"]`},
+		{"empty list", `[]`, []string{}, false, `[]`},
+		{"object", `{"tool_names":["search"]}`, []string{"search"}, false, `["search"]`},
+		{"read only", `{"read_only":true}`, nil, true, `{"read_only":true}`},
+		{"names and read only", `{"tool_names":["a"],"read_only":true}`, []string{"a"}, true, `{"tool_names":["a"],"read_only":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MCPAllowedTools
+			if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if (got.ToolNames == nil) != (tt.wantNames == nil) || len(got.ToolNames) != len(tt.wantNames) {
+				t.Fatalf("ToolNames = %#v, want %#v", got.ToolNames, tt.wantNames)
+			}
+			for i := range tt.wantNames {
+				if got.ToolNames[i] != tt.wantNames[i] {
+					t.Errorf("ToolNames[%d] = %q, want %q", i, got.ToolNames[i], tt.wantNames[i])
+				}
+			}
+			if got.ReadOnly != tt.wantRO {
+				t.Errorf("ReadOnly = %v, want %v", got.ReadOnly, tt.wantRO)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("marshal = %s, want %s", data, tt.wantJSON)
+			}
+		})
+	}
+
+	var bad MCPAllowedTools
+	if err := json.Unmarshal([]byte(`"search"`), &bad); err == nil {
+		t.Error("expected error for a bare string")
+	}
+}
+
+func TestStreamOptions_Allows(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      *StreamOptions
+		eventType string
+		want      bool
+	}{
+		{"nil options", nil, "response.output_text.delta", true},
+		{"included", &StreamOptions{IncludeEvents: []string{"response.completed"}}, "response.completed", true},
+		{"not included", &StreamOptions{IncludeEvents: []string{"response.completed"}}, "response.output_text.delta", false},
+		{"excluded", &StreamOptions{ExcludeEvents: []string{"response.output_item.added"}}, "response.output_item.added", false},
+		{"not excluded", &StreamOptions{ExcludeEvents: []string{"response.output_item.added"}}, "response.completed", true},
+		{"error always sent", &StreamOptions{IncludeEvents: []string{"response.completed"}}, "error", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Allows(tt.eventType); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.eventType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseRequest_Limits(t *testing.T) {
+	model := "m"
+	manyEntries := make(map[string]string)
+	for i := range MaxMetadataEntries + 1 {
+		manyEntries[strings.Repeat("k", i+1)] = "v"
+	}
+	tests := []struct {
+		name      string
+		req       ResponseRequest
+		limits    RequestLimits
+		wantParam string // "" means no error
+	}{
+		{
+			name: "within limits",
+			req: ResponseRequest{Model: &model, Input: []interface{}{"a", "b"}, Tools: make([]ResponsesToolParam, 2),
+				Metadata: map[string]string{strings.Repeat("k", MaxMetadataKeyLength): strings.Repeat("é", MaxMetadataValueLength)}},
+			limits: RequestLimits{MaxInputItems: 2, MaxTools: 2},
+		},
+		{
+			name:      "too many metadata entries",
+			req:       ResponseRequest{Model: &model, Input: "hi", Metadata: manyEntries},
+			wantParam: "metadata",
+		},
+		{
+			name:      "metadata key too long",
+			req:       ResponseRequest{Model: &model, Input: "hi", Metadata: map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "v"}},
+			wantParam: "metadata",
+		},
+		{
+			name:      "metadata value too long",
+			req:       ResponseRequest{Model: &model, Input: "hi", Metadata: map[string]string{"k": strings.Repeat("v", MaxMetadataValueLength+1)}},
+			wantParam: "metadata",
+		},
+		{
+			name:      "too many input items",
+			req:       ResponseRequest{Model: &model, Input: []interface{}{"a", "b", "c"}},
+			limits:    RequestLimits{MaxInputItems: 2},
+			wantParam: "input",
+		},
+		{
+			name:   "string input has no items",
+			req:    ResponseRequest{Model: &model, Input: strings.Repeat("a", 100)},
+			limits: RequestLimits{MaxInputItems: 2},
+		},
+		{
+			name:      "too many tools",
+			req:       ResponseRequest{Model: &model, Input: "hi", Tools: make([]ResponsesToolParam, 3)},
+			limits:    RequestLimits{MaxTools: 2},
+			wantParam: "tools",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if err == nil {
+				err = tt.req.CheckLimits(tt.limits)
+			}
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Errorf("error = %v", err)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || limitErr.Param != tt.wantParam {
+				t.Errorf("error = %v, want a limit error on %s", err, tt.wantParam)
+			}
+		})
+	}
+}
+
+func TestStreamingEvents(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, event := range StreamingEvents {
+		if seen[event.Type] {
+			t.Errorf("%s listed twice", event.Type)
+		}
+		seen[event.Type] = true
+
+		// Every listed struct is a streaming event with a type field
+		ptr := reflect.New(reflect.TypeOf(event.Event))
+		ptr.Elem().FieldByName("Type").SetString(event.Type)
+		if got := ExtractEventType(ptr.Interface()); got != event.Type {
+			t.Errorf("ExtractEventType(%T) = %q, want %q", event.Event, got, event.Type)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import "encoding/json"
+
+// ShadowResult pairs what the primary and secondary backends returned for
+// one backend call of a response sent as shadow traffic
+type ShadowResult struct {
+	ID         string          `json:"id"`     // Format: "shadow_{id}"
+	Object     string          `json:"object"` // Always "shadow.result"
+	ResponseID string          `json:"response_id"`
+	Call       int             `json:"call"` // Index of the backend call in the agentic loop of the response, from 0
+	Stream     bool            `json:"stream"`
+	Request    json.RawMessage `json:"request" swaggertype:"object"` // Backend request, as sent to the primary backend
+	Primary    ShadowOutput    `json:"primary"`
+	Shadow     ShadowOutput    `json:"shadow"`
+	Identical  bool            `json:"identical"`  // Both completed with the same output text
+	CreatedAt  int64           `json:"created_at"` // Unix timestamp
+}
+
+// ShadowOutput is what one backend returned for a backend call
+type ShadowOutput struct {
+	Model        string          `json:"model"`
+	Status       string          `json:"status"` // Backend response status, or "failed" if the call failed
+	OutputText   string          `json:"output_text"`
+	Output       json.RawMessage `json:"output,omitempty" swaggertype:"array,object"` // Output items
+	InputTokens  int             `json:"input_tokens"`
+	OutputTokens int             `json:"output_tokens"`
+	LatencyMs    int64           `json:"latency_ms"`
+	FirstTokenMs *int64          `json:"first_token_ms,omitempty"` // Streamed calls: time to the first delta
+	Error        *string         `json:"error"`
+}
+
+// ListShadowResultsResponse represents a page of shadow results, newest
+// first
+type ListShadowResultsResponse struct {
+	Object  string         `json:"object"`             // Always "list"
+	Data    []ShadowResult `json:"data"`               // Results
+	FirstID string         `json:"first_id,omitempty"` // ID of the first result
+	LastID  string         `json:"last_id,omitempty"`  // ID of the last result, the cursor for the next page
+	HasMore bool           `json:"has_more"`           // Whether more results match
+}
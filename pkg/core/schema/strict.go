@@ -0,0 +1,191 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ValidationError is returned when a request is malformed. Param is the
+// path of the offending field, such as "input[2].content[0].type".
+type ValidationError struct {
+	Param   string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+// requestFields returns the top-level fields of a ResponseRequest.
+var requestFields = sync.OnceValue(func() []string {
+	t := reflect.TypeFor[ResponseRequest]()
+	fields := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+})
+
+// Fields allowed on each input item type. Clients echo output items back as
+// input, so output-only fields such as status are accepted.
+var inputItemFields = map[string][]string{
+	"message":              {"type", "id", "role", "content", "status"},
+	"function_call":        {"type", "id", "call_id", "name", "arguments", "status"},
+	"function_call_output": {"type", "id", "call_id", "output", "status"},
+	"reasoning":            {"type", "id", "summary", "content", "encrypted_content", "status"},
+	"mcp_call":             {"type", "id", "call_id", "name", "arguments", "server_label", "output", "error", "status"},
+	"file_search_call":     {"type", "id", "call_id", "queries", "results", "error", "status"},
+}
+
+// Fields allowed on each message content part type.
+var contentPartFields = map[string][]string{
+	"input_text":   {"type", "text"},
+	"text":         {"type", "text"},
+	"input_image":  {"type", "image_url", "file_id", "detail", "url"},
+	"input_file":   {"type", "file", "file_id", "file_data", "filename", "file_url"},
+	"input_audio":  {"type", "input_audio", "data", "format"},
+	"input_video":  {"type", "video_url", "file_id", "url"},
+	"output_text":  {"type", "text", "annotations", "logprobs"},
+	"output_audio": {"type", "data", "format", "transcript"},
+	"refusal":      {"type", "refusal"},
+}
+
+var messageRoles = []string{"user", "assistant", "system", "developer"}
+
+// DecodeStrict decodes a ResponseRequest, returning a *ValidationError for
+// unknown top-level fields, fields of the wrong type, and malformed input
+// items, which the permissive decoding ignores.
+func DecodeStrict(data []byte, req *ResponseRequest) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return &ValidationError{Message: "request body must be a JSON object"}
+	}
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !slices.Contains(requestFields(), name) {
+			return &ValidationError{Param: name, Message: fmt.Sprintf("unknown parameter '%s'", name)}
+		}
+	}
+
+	if err := json.Unmarshal(data, req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &ValidationError{Param: typeErr.Field, Message: fmt.Sprintf("invalid type for '%s': got %s", typeErr.Field, typeErr.Value)}
+		}
+		return &ValidationError{Message: err.Error()}
+	}
+
+	if items, ok := req.Input.([]interface{}); ok {
+		for i, item := range items {
+			if err := validateInputItem(fmt.Sprintf("input[%d]", i), item); err != nil {
+				return err
+			}
+		}
+	} else if _, ok := req.Input.(string); !ok && req.Input != nil {
+		return &ValidationError{Param: "input", Message: "'input' must be a string or an array of items"}
+	}
+	return nil
+}
+
+// validateInputItem checks the input item at path.
+func validateInputItem(path string, item interface{}) error {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return &ValidationError{Param: path, Message: fmt.Sprintf("'%s' must be an object", path)}
+	}
+
+	// Items without a type are messages, as in the OpenAI API
+	itemType := "message"
+	if v, ok := fields["type"]; ok {
+		if itemType, ok = v.(string); !ok {
+			return &ValidationError{Param: path + ".type", Message: fmt.Sprintf("'%s.type' must be a string", path)}
+		}
+	}
+	allowed, ok := inputItemFields[itemType]
+	if !ok {
+		return &ValidationError{Param: path + ".type", Message: fmt.Sprintf("unsupported item type '%s'", itemType)}
+	}
+	if err := checkFields(path, fields, allowed); err != nil {
+		return err
+	}
+
+	switch itemType {
+	case "message":
+		role, _ := fields["role"].(string)
+		if !slices.Contains(messageRoles, role) {
+			return &ValidationError{Param: path + ".role", Message: fmt.Sprintf("'%s.role' must be one of %s", path, strings.Join(messageRoles, ", "))}
+		}
+		switch content := fields["content"].(type) {
+		case string:
+		case []interface{}:
+			for j, part := range content {
+				if err := validateContentPart(fmt.Sprintf("%s.content[%d]", path, j), part); err != nil {
+					return err
+				}
+			}
+		default:
+			return &ValidationError{Param: path + ".content", Message: fmt.Sprintf("'%s.content' must be a string or an array of content parts", path)}
+		}
+	case "function_call":
+		return requireStrings(path, fields, "call_id", "name", "arguments")
+	case "function_call_output":
+		return requireStrings(path, fields, "call_id", "output")
+	case "reasoning":
+		return requireStrings(path, fields, "id")
+	}
+	return nil
+}
+
+// validateContentPart checks the message content part at path.
+func validateContentPart(path string, part interface{}) error {
+	fields, ok := part.(map[string]interface{})
+	if !ok {
+		return &ValidationError{Param: path, Message: fmt.Sprintf("'%s' must be an object", path)}
+	}
+	partType, _ := fields["type"].(string)
+	allowed, ok := contentPartFields[partType]
+	if !ok {
+		return &ValidationError{Param: path + ".type", Message: fmt.Sprintf("unsupported content part type '%s'", partType)}
+	}
+	if err := checkFields(path, fields, allowed); err != nil {
+		return err
+	}
+	switch partType {
+	case "input_text", "text", "output_text":
+		return requireStrings(path, fields, "text")
+	case "refusal":
+		return requireStrings(path, fields, "refusal")
+	}
+	return nil
+}
+
+// checkFields returns an error for the first field of fields, in name
+// order, that is not allowed.
+func checkFields(path string, fields map[string]interface{}, allowed []string) error {
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !slices.Contains(allowed, name) {
+			return &ValidationError{Param: path + "." + name, Message: fmt.Sprintf("unknown parameter '%s.%s'", path, name)}
+		}
+	}
+	return nil
+}
+
+// requireStrings returns an error unless every named field of fields is a
+// string.
+func requireStrings(path string, fields map[string]interface{}, names ...string) error {
+	for _, name := range names {
+		if _, ok := fields[name].(string); !ok {
+			return &ValidationError{Param: path + "." + name, Message: fmt.Sprintf("'%s.%s' must be a string", path, name)}
+		}
+	}
+	return nil
+}
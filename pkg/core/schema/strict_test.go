@@ -0,0 +1,109 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantParam string // empty when the request is valid
+	}{
+		{
+			name: "string input",
+			body: `{"model":"m","input":"hi","temperature":0.5}`,
+		},
+		{
+			name: "items",
+			body: `{"model":"m","input":[
+				{"role":"user","content":"hi"},
+				{"type":"message","role":"user","content":[{"type":"input_text","text":"look"},{"type":"input_image","image_url":"https://example.com/a.png"},{"type":"input_audio","input_audio":{"data":"UklGRg==","format":"wav"}}]},
+				{"type":"message","id":"msg_1","role":"assistant","status":"completed","content":[{"type":"output_text","text":"ok","annotations":[]}]},
+				{"type":"function_call","call_id":"call_1","name":"f","arguments":"{}"},
+				{"type":"function_call_output","call_id":"call_1","output":"done"},
+				{"type":"reasoning","id":"rs_1","summary":[]}]}`,
+		},
+		{
+			name:      "unknown top-level field",
+			body:      `{"model":"m","input":"hi","temprature":0.5}`,
+			wantParam: "temprature",
+		},
+		{
+			name:      "wrong type",
+			body:      `{"model":"m","input":"hi","temperature":"hot"}`,
+			wantParam: "temperature",
+		},
+		{
+			name:      "input not a string or array",
+			body:      `{"model":"m","input":{"role":"user"}}`,
+			wantParam: "input",
+		},
+		{
+			name:      "item not an object",
+			body:      `{"model":"m","input":["hi"]}`,
+			wantParam: "input[0]",
+		},
+		{
+			name:      "unsupported item type",
+			body:      `{"model":"m","input":[{"role":"user","content":"hi"},{"type":"mesage","role":"user","content":"hi"}]}`,
+			wantParam: "input[1].type",
+		},
+		{
+			name:      "unknown item field",
+			body:      `{"model":"m","input":[{"role":"user","contents":"hi"}]}`,
+			wantParam: "input[0].contents",
+		},
+		{
+			name:      "invalid role",
+			body:      `{"model":"m","input":[{"role":"usr","content":"hi"}]}`,
+			wantParam: "input[0].role",
+		},
+		{
+			name:      "missing content",
+			body:      `{"model":"m","input":[{"role":"user"}]}`,
+			wantParam: "input[0].content",
+		},
+		{
+			name:      "unknown content part type",
+			body:      `{"model":"m","input":[{"role":"user","content":[{"type":"input_txt","text":"hi"}]}]}`,
+			wantParam: "input[0].content[0].type",
+		},
+		{
+			name:      "content part without text",
+			body:      `{"model":"m","input":[{"role":"user","content":[{"type":"input_text","txt":"hi"}]}]}`,
+			wantParam: "input[0].content[0].txt",
+		},
+		{
+			name:      "function call output without call_id",
+			body:      `{"model":"m","input":[{"type":"function_call_output","output":"done"}]}`,
+			wantParam: "input[0].call_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req ResponseRequest
+			err := DecodeStrict([]byte(tt.body), &req)
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("DecodeStrict() error = %v", err)
+				}
+				if req.Model == nil || *req.Model != "m" {
+					t.Errorf("model = %v", req.Model)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("DecodeStrict() error = %v, want *ValidationError", err)
+			}
+			if validationErr.Param != tt.wantParam {
+				t.Errorf("param = %q, want %q (%s)", validationErr.Param, tt.wantParam, validationErr.Message)
+			}
+		})
+	}
+}
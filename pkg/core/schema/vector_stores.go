@@ -16,6 +16,38 @@ type VectorStore struct {
 	ExpiresAfter *VectorStoreExpiration `json:"expires_after,omitempty"`                      // Expiration policy
 	LastActiveAt *int64                 `json:"last_active_at,omitempty"`                     // Unix timestamp
 	Metadata     map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+
+	Embedder            string              `json:"embedder,omitempty"`             // Name of the embedder the store is indexed with
+	EmbeddingModel      string              `json:"embedding_model,omitempty"`      // Model the store is indexed with
+	EmbeddingDimensions int                 `json:"embedding_dimensions,omitempty"` // Dimensions of the store's vectors
+	Reindex             *VectorStoreReindex `json:"reindex,omitempty"`              // Latest re-index, if any
+}
+
+// VectorStoreReindex reports the progress of re-indexing a vector store
+type VectorStoreReindex struct {
+	Status         string                `json:"status" enums:"in_progress,completed,failed"`
+	Embedder       string                `json:"embedder,omitempty"`        // Embedder the files are re-embedded with
+	EmbeddingModel string                `json:"embedding_model,omitempty"` // Model the files are re-embedded with
+	FileCounts     ReindexFileCounts     `json:"file_counts"`
+	StartedAt      int64                 `json:"started_at"`             // Unix timestamp
+	CompletedAt    *int64                `json:"completed_at,omitempty"` // Unix timestamp
+	LastError      *VectorStoreFileError `json:"last_error,omitempty"`
+}
+
+// ReindexFileCounts counts the files of a re-index
+type ReindexFileCounts struct {
+	Completed int `json:"completed"` // Files re-indexed
+	Failed    int `json:"failed"`    // Files that failed re-indexing
+	Total     int `json:"total"`     // Files to re-index
+}
+
+// ReindexVectorStoreRequest represents a request to re-index a vector store.
+// Without an embedder or a model, files are re-embedded with the store's
+// embedder, or the configured one.
+type ReindexVectorStoreRequest struct {
+	Embedder            string `json:"embedder,omitempty"`             // Name of a configured embedder to switch the store to
+	EmbeddingModel      string `json:"embedding_model,omitempty"`      // Embedding model to switch the store to
+	EmbeddingDimensions int    `json:"embedding_dimensions,omitempty"` // Output dimensions, for models that support several
 }
 
 // VectorStoreFileCounts represents file count statistics
@@ -40,6 +72,7 @@ type CreateVectorStoreRequest struct {
 	ExpiresAfter     *VectorStoreExpiration `json:"expires_after,omitempty"`
 	ChunkingStrategy *ChunkingStrategy      `json:"chunking_strategy,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+	Embedder         string                 `json:"embedder,omitempty"` // Name of a configured embedder (default "default")
 }
 
 // UpdateVectorStoreRequest represents a request to update a vector store
@@ -59,11 +92,12 @@ type ListVectorStoresRequest struct {
 
 // ListVectorStoresResponse represents a list of vector stores
 type ListVectorStoresResponse struct {
-	Object  string        `json:"object"`             // Always "list"
-	Data    []VectorStore `json:"data"`               // Array of vector stores
-	FirstID string        `json:"first_id,omitempty"` // ID of first item
-	LastID  string        `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool          `json:"has_more"`           // Whether there are more results
+	Object     string        `json:"object"`                // Always "list"
+	Data       []VectorStore `json:"data"`                  // Array of vector stores
+	FirstID    string        `json:"first_id,omitempty"`    // ID of first item
+	LastID     string        `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool          `json:"has_more"`              // Whether there are more results
+	TotalCount *int          `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
 }
 
 // DeleteVectorStoreResponse represents the response from deleting a vector store
@@ -111,6 +145,14 @@ type AddVectorStoreFileRequest struct {
 	Attributes       map[string]interface{} `json:"attributes,omitempty" swaggertype:"object"` // File attributes
 }
 
+// VectorStoreFileUploadResponse represents the result of a one-shot upload:
+// the stored file and its vector store entry
+type VectorStoreFileUploadResponse struct {
+	Object          string          `json:"object"` // Always "vector_store.file_upload"
+	File            File            `json:"file"`
+	VectorStoreFile VectorStoreFile `json:"vector_store_file"`
+}
+
 // ListVectorStoreFilesRequest represents a request to list files in a vector store
 type ListVectorStoreFilesRequest struct {
 	After  string `json:"after,omitempty"`
@@ -122,11 +164,33 @@ type ListVectorStoreFilesRequest struct {
 
 // ListVectorStoreFilesResponse represents a list of files in a vector store
 type ListVectorStoreFilesResponse struct {
-	Object  string            `json:"object"`             // Always "list"
-	Data    []VectorStoreFile `json:"data"`               // Array of files
-	FirstID string            `json:"first_id,omitempty"` // ID of first item
-	LastID  string            `json:"last_id,omitempty"`  // ID of last item
-	HasMore bool              `json:"has_more"`           // Whether there are more results
+	Object     string            `json:"object"`                // Always "list"
+	Data       []VectorStoreFile `json:"data"`                  // Array of files
+	FirstID    string            `json:"first_id,omitempty"`    // ID of first item
+	LastID     string            `json:"last_id,omitempty"`     // ID of last item
+	HasMore    bool              `json:"has_more"`              // Whether there are more results
+	TotalCount *int              `json:"total_count,omitempty"` // Total number of matching items (with include_total=true)
+}
+
+// VectorStoreFileChunk represents a chunk of a vector store file as indexed
+type VectorStoreFileChunk struct {
+	ID                  string                 `json:"id"`                                        // Chunk ID
+	Object              string                 `json:"object"`                                    // Always "vector_store.file.chunk"
+	FileID              string                 `json:"file_id"`                                   // File the chunk was cut from
+	Content             string                 `json:"content"`                                   // Indexed text
+	StartOffset         *int                   `json:"start_offset"`                              // Byte offset of the chunk in the text extracted from the file; null if not recorded
+	EndOffset           *int                   `json:"end_offset"`                                // Byte offset of the end of the chunk; null if not recorded
+	EmbeddingDimensions int                    `json:"embedding_dimensions"`                      // Dimensions of the chunk's embedding
+	Attributes          map[string]interface{} `json:"attributes,omitempty" swaggertype:"object"` // Attributes of the file
+}
+
+// ListVectorStoreFileChunksResponse represents the chunks of a vector store file
+type ListVectorStoreFileChunksResponse struct {
+	Object  string                 `json:"object"`             // Always "list"
+	Data    []VectorStoreFileChunk `json:"data"`               // Chunks in file order
+	FirstID string                 `json:"first_id,omitempty"` // ID of first item
+	LastID  string                 `json:"last_id,omitempty"`  // ID of last item
+	HasMore bool                   `json:"has_more"`           // Whether there are more results
 }
 
 // DeleteVectorStoreFileResponse represents the response from removing a file from a vector store
@@ -0,0 +1,215 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// Item change actions reported by the conversation compactor.
+const (
+	// ItemMerged is an assistant text fragment appended to the item before it.
+	ItemMerged = "merged"
+	// ItemDropped is an item without content.
+	ItemDropped = "dropped"
+	// ItemNormalized is an item rewritten from a legacy format.
+	ItemNormalized = "normalized"
+)
+
+// ItemChange is a single change made to a conversation's items.
+type ItemChange struct {
+	ItemID string
+	Action string
+	Into   string // item an ItemMerged item was appended to
+}
+
+// CompactionReport is the result of compacting one conversation.
+type CompactionReport struct {
+	ConversationID string
+	DryRun         bool
+	ItemsBefore    int
+	ItemsAfter     int
+	Changes        []ItemChange
+}
+
+// ConversationCompactor rewrites stored conversation items into the format
+// the current gateway writes, so that older conversations can be used with
+// features that rely on it.
+type ConversationCompactor struct {
+	store state.SessionStore
+}
+
+// NewConversationCompactor creates a ConversationCompactor.
+func NewConversationCompactor(store state.SessionStore) *ConversationCompactor {
+	return &ConversationCompactor{store: store}
+}
+
+// Compact compacts the items of conv and, unless dryRun is set, saves the
+// result. Item IDs of merged and dropped items no longer exist afterwards.
+func (c *ConversationCompactor) Compact(ctx context.Context, conv *state.Conversation, dryRun bool) (*CompactionReport, error) {
+	items, changes := CompactItems(conv.Messages)
+	report := &CompactionReport{
+		ConversationID: conv.ID,
+		DryRun:         dryRun,
+		ItemsBefore:    len(conv.Messages),
+		ItemsAfter:     len(items),
+		Changes:        changes,
+	}
+	if dryRun || len(changes) == 0 {
+		return report, nil
+	}
+
+	updated := *conv
+	updated.Messages = items
+	if err := c.store.SaveConversation(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("save conversation %s: %w", conv.ID, err)
+	}
+	return report, nil
+}
+
+// CompactItems returns items with legacy formats normalized, empty messages
+// dropped and consecutive assistant text fragments merged, along with one
+// change per affected item. items is not modified.
+func CompactItems(items []state.Message) ([]state.Message, []ItemChange) {
+	out := make([]state.Message, 0, len(items))
+	var changes []ItemChange
+	for _, item := range items {
+		item, normalized := normalizeItem(item)
+		if isEmptyItem(item) {
+			changes = append(changes, ItemChange{ItemID: item.ID, Action: ItemDropped})
+			continue
+		}
+		if n := len(out); n > 0 && isTextFragment(out[n-1]) && isTextFragment(item) {
+			out[n-1].Content = out[n-1].Content.(string) + item.Content.(string)
+			changes = append(changes, ItemChange{ItemID: item.ID, Action: ItemMerged, Into: out[n-1].ID})
+			continue
+		}
+		if normalized {
+			changes = append(changes, ItemChange{ItemID: item.ID, Action: ItemNormalized})
+		}
+		out = append(out, item)
+	}
+	return out, changes
+}
+
+// normalizeItem rewrites legacy item formats:
+//   - role "function" (Chat Completions) and untyped tool results become
+//     "tool" items of type function_call_output;
+//   - content made only of text parts becomes a plain string;
+//   - function calls stored as an object become the {"name","arguments"}
+//     JSON string written by the engine.
+func normalizeItem(item state.Message) (state.Message, bool) {
+	changed := false
+	if item.Role == "function" {
+		item.Role = "tool"
+		changed = true
+	}
+	if item.Role == "tool" && item.Metadata["type"] == "" {
+		meta := maps.Clone(item.Metadata)
+		if meta == nil {
+			meta = make(map[string]string, 1)
+		}
+		meta["type"] = "function_call_output"
+		item.Metadata = meta
+		changed = true
+	}
+
+	switch content := item.Content.(type) {
+	case []interface{}:
+		if text, ok := textParts(content); ok {
+			item.Content = text
+			changed = true
+		}
+	case map[string]interface{}:
+		if item.Metadata["type"] == "function_call" {
+			if call, ok := functionCallContent(content); ok {
+				item.Content = call
+				changed = true
+			}
+		}
+	}
+	return item, changed
+}
+
+// textParts joins content parts that are all text. It reports false if any
+// part carries something else, such as an image or a file.
+func textParts(parts []interface{}) (string, bool) {
+	if len(parts) == 0 {
+		return "", false
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		part, ok := p.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		switch part["type"] {
+		case "text", "input_text", "output_text":
+		default:
+			return "", false
+		}
+		text, ok := part["text"].(string)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(text)
+	}
+	return b.String(), true
+}
+
+func functionCallContent(content map[string]interface{}) (string, bool) {
+	name, ok := content["name"].(string)
+	if !ok {
+		return "", false
+	}
+	var args string
+	switch a := content["arguments"].(type) {
+	case string:
+		args = a
+	case nil:
+		args = "{}"
+	default:
+		data, err := json.Marshal(a)
+		if err != nil {
+			return "", false
+		}
+		args = string(data)
+	}
+	if !json.Valid([]byte(args)) {
+		return "", false
+	}
+	return fmt.Sprintf(`{"name":%q,"arguments":%s}`, name, args), true
+}
+
+// isEmptyItem reports whether item is a message without content. Typed
+// items such as tool results are kept even when empty.
+func isEmptyItem(item state.Message) bool {
+	if item.Metadata["type"] != "" {
+		return false
+	}
+	switch content := item.Content.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(content) == ""
+	case []interface{}:
+		return len(content) == 0
+	}
+	return false
+}
+
+// isTextFragment reports whether item is a plain assistant text message.
+func isTextFragment(item state.Message) bool {
+	if item.Role != "assistant" || item.Metadata["type"] != "" {
+		return false
+	}
+	_, ok := item.Content.(string)
+	return ok
+}
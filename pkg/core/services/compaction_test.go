@@ -0,0 +1,125 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestCompactItems(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []state.Message
+		want        []state.Message
+		wantChanges []ItemChange
+	}{
+		{
+			name: "already compact",
+			items: []state.Message{
+				{ID: "a", Role: "user", Content: "hi"},
+				{ID: "b", Role: "assistant", Content: "hello"},
+			},
+			want: []state.Message{
+				{ID: "a", Role: "user", Content: "hi"},
+				{ID: "b", Role: "assistant", Content: "hello"},
+			},
+		},
+		{
+			name: "merge assistant fragments",
+			items: []state.Message{
+				{ID: "a", Role: "assistant", Content: "Hel"},
+				{ID: "b", Role: "assistant", Content: "lo"},
+				{ID: "c", Role: "user", Content: "one"},
+				{ID: "d", Role: "user", Content: "two"},
+			},
+			want: []state.Message{
+				{ID: "a", Role: "assistant", Content: "Hello"},
+				{ID: "c", Role: "user", Content: "one"},
+				{ID: "d", Role: "user", Content: "two"},
+			},
+			wantChanges: []ItemChange{{ItemID: "b", Action: ItemMerged, Into: "a"}},
+		},
+		{
+			name: "drop empty messages",
+			items: []state.Message{
+				{ID: "a", Role: "assistant", Content: "x"},
+				{ID: "b", Role: "assistant", Content: "  "},
+				{ID: "c", Role: "user", Content: nil},
+				{ID: "d", Role: "tool", Content: "", Metadata: map[string]string{"type": "function_call_output"}},
+			},
+			want: []state.Message{
+				{ID: "a", Role: "assistant", Content: "x"},
+				{ID: "d", Role: "tool", Content: "", Metadata: map[string]string{"type": "function_call_output"}},
+			},
+			wantChanges: []ItemChange{{ItemID: "b", Action: ItemDropped}, {ItemID: "c", Action: ItemDropped}},
+		},
+		{
+			name: "normalize legacy formats",
+			items: []state.Message{
+				{ID: "a", Role: "user", Content: []interface{}{
+					map[string]interface{}{"type": "input_text", "text": "look "},
+					map[string]interface{}{"type": "input_text", "text": "here"},
+				}},
+				{ID: "b", Role: "user", Content: []interface{}{
+					map[string]interface{}{"type": "input_image", "image_url": "https://example.com/a.png"},
+				}},
+				{ID: "c", Role: "assistant", Metadata: map[string]string{"type": "function_call"},
+					Content: map[string]interface{}{"name": "lookup", "arguments": map[string]interface{}{"q": "x"}}},
+				{ID: "d", Role: "function", Content: "42"},
+			},
+			want: []state.Message{
+				{ID: "a", Role: "user", Content: "look here"},
+				{ID: "b", Role: "user", Content: []interface{}{
+					map[string]interface{}{"type": "input_image", "image_url": "https://example.com/a.png"},
+				}},
+				{ID: "c", Role: "assistant", Metadata: map[string]string{"type": "function_call"},
+					Content: `{"name":"lookup","arguments":{"q":"x"}}`},
+				{ID: "d", Role: "tool", Content: "42", Metadata: map[string]string{"type": "function_call_output"}},
+			},
+			wantChanges: []ItemChange{
+				{ItemID: "a", Action: ItemNormalized},
+				{ItemID: "c", Action: ItemNormalized},
+				{ItemID: "d", Action: ItemNormalized},
+			},
+		},
+		{
+			name: "normalized fragments merge",
+			items: []state.Message{
+				{ID: "a", Role: "assistant", Content: "Hel"},
+				{ID: "b", Role: "assistant", Content: []interface{}{
+					map[string]interface{}{"type": "output_text", "text": "lo"},
+				}},
+			},
+			want: []state.Message{
+				{ID: "a", Role: "assistant", Content: "Hello"},
+			},
+			wantChanges: []ItemChange{{ItemID: "b", Action: ItemMerged, Into: "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := CompactItems(tt.items)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("changes = %+v, want %+v", changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestCompactItems_DoesNotModifyInput(t *testing.T) {
+	items := []state.Message{
+		{ID: "a", Role: "assistant", Content: "Hel"},
+		{ID: "b", Role: "assistant", Content: "lo"},
+	}
+	CompactItems(items)
+	if items[0].Content != "Hel" {
+		t.Errorf("input item modified: %q", items[0].Content)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/ids"
+)
+
+// ErrImportConflict is returned when importing with preserved IDs would
+// overwrite an existing conversation or response.
+var ErrImportConflict = errors.New("conversation or response already exists")
+
+// ConversationBundle is a conversation with its items and the responses made
+// in it, as moved between gateways by export and import.
+type ConversationBundle struct {
+	// Conversation includes its items as Messages.
+	Conversation *state.Conversation
+	// Responses carry their full message history, oldest first. The
+	// response a fork was forked from comes first, so that the imported
+	// conversation keeps the history it shared.
+	Responses []*state.Response
+}
+
+// ImportResult describes an imported conversation.
+type ImportResult struct {
+	Conversation *state.Conversation
+	Responses    int
+	// IDs maps the bundle's conversation and response IDs to the imported
+	// ones. It is empty when IDs were preserved.
+	IDs map[string]string
+}
+
+// ExportConversation collects a conversation, its items and its responses.
+func ExportConversation(ctx context.Context, store state.SessionStore, conversationID string) (*ConversationBundle, error) {
+	conv, err := store.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := store.ListResponses(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("list responses: %w", err)
+	}
+	// Listings may hold only the messages each response added; export the
+	// full history so the bundle does not depend on how it was stored.
+	responses := make([]*state.Response, 0, len(listed))
+	for _, r := range listed {
+		full, err := store.GetResponse(ctx, r.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get response %s: %w", r.ID, err)
+		}
+		responses = append(responses, full)
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		if !responses[i].CreatedAt.Equal(responses[j].CreatedAt) {
+			return responses[i].CreatedAt.Before(responses[j].CreatedAt)
+		}
+		return responses[i].ID < responses[j].ID
+	})
+	if conv.ForkedFrom != "" {
+		from, err := store.GetResponse(ctx, conv.ForkedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("get response %s: %w", conv.ForkedFrom, err)
+		}
+		responses = append([]*state.Response{from}, responses...)
+	}
+
+	return &ConversationBundle{Conversation: conv, Responses: responses}, nil
+}
+
+// ImportConversation recreates a bundle in store. With preserveIDs the
+// conversation and responses keep their IDs and ErrImportConflict is
+// returned if any exists already; otherwise they get new IDs, and references
+// to the old ones in previous_response_id, stored requests and outputs are
+// rewritten. Item IDs are scoped to their conversation and always kept.
+//
+// Responses are saved oldest first, so stores can keep only the messages
+// each one added. If saving fails, what was already imported is removed.
+func ImportConversation(ctx context.Context, store state.SessionStore, bundle *ConversationBundle, preserveIDs bool) (*ImportResult, error) {
+	if bundle.Conversation == nil || bundle.Conversation.ID == "" {
+		return nil, fmt.Errorf("bundle has no conversation")
+	}
+
+	ids := make(map[string]string)
+	if preserveIDs {
+		if _, err := store.GetConversation(ctx, bundle.Conversation.ID); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrImportConflict, bundle.Conversation.ID)
+		}
+		for _, r := range bundle.Responses {
+			if _, err := store.GetResponse(ctx, r.ID); err == nil {
+				return nil, fmt.Errorf("%w: %s", ErrImportConflict, r.ID)
+			}
+		}
+	} else {
+		ids[bundle.Conversation.ID] = newID("conv_")
+		for _, r := range bundle.Responses {
+			ids[r.ID] = newID("resp_")
+		}
+	}
+	remap := func(id string) string {
+		if newID, ok := ids[id]; ok {
+			return newID
+		}
+		return id
+	}
+
+	conv := *bundle.Conversation
+	conv.ID = remap(conv.ID)
+	conv.SessionID = ""
+	conv.ForkedFrom = "" // the fork point is imported with the responses
+	conv.UpdatedAt = time.Now()
+	items := conv.Messages
+	conv.Messages = []state.Message{}
+	if err := store.CreateConversation(ctx, &conv); err != nil {
+		return nil, fmt.Errorf("create conversation: %w", err)
+	}
+	var saved []string
+	rollback := func() {
+		cleanupCtx := context.WithoutCancel(ctx)
+		for _, id := range saved {
+			_ = store.DeleteResponse(cleanupCtx, id)
+		}
+		_ = store.DeleteConversation(cleanupCtx, conv.ID)
+	}
+
+	if len(items) > 0 {
+		if err := store.AddConversationItems(ctx, conv.ID, items); err != nil {
+			rollback()
+			return nil, fmt.Errorf("add items: %w", err)
+		}
+	}
+
+	responses := make([]*state.Response, len(bundle.Responses))
+	copy(responses, bundle.Responses)
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].CreatedAt.Before(responses[j].CreatedAt)
+	})
+	for _, r := range responses {
+		resp := *r
+		resp.ID = remap(r.ID)
+		resp.ConversationID = conv.ID
+		resp.PreviousResponseID = remap(r.PreviousResponseID)
+		resp.MessagesBase = ""
+		if len(ids) > 0 {
+			var err error
+			if resp.Request, err = remapJSON(r.Request, ids); err == nil {
+				resp.Output, err = remapJSON(r.Output, ids)
+			}
+			if err != nil {
+				rollback()
+				return nil, fmt.Errorf("response %s: %w", r.ID, err)
+			}
+		}
+		if err := store.SaveResponse(ctx, &resp); err != nil {
+			rollback()
+			return nil, fmt.Errorf("save response %s: %w", r.ID, err)
+		}
+		saved = append(saved, resp.ID)
+	}
+
+	conv.Messages = items
+	result := &ImportResult{Conversation: &conv, Responses: len(responses)}
+	if !preserveIDs {
+		result.IDs = ids
+	}
+	return result, nil
+}
+
+// remapJSON replaces every string in v that is a key of ids with its value.
+// IDs are random, so matching whole JSON strings cannot hit other content.
+func remapJSON(v interface{}, ids map[string]string) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	for oldID, newID := range ids {
+		s = strings.ReplaceAll(s, `"`+oldID+`"`, `"`+newID+`"`)
+	}
+	var out interface{}
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newID(prefix string) string {
+	return ids.Default.NewID(prefix)
+}
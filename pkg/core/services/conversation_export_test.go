@@ -0,0 +1,147 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+func newExportStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// seedConversation stores a conversation with two items and two chained
+// responses.
+func seedConversation(t *testing.T, store state.SessionStore) {
+	t.Helper()
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	if err := store.CreateConversation(ctx, &state.Conversation{
+		ID: "conv_src", Metadata: map[string]string{"user": "u1"}, CreatedAt: created, UpdatedAt: created,
+	}); err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	if err := store.AddConversationItems(ctx, "conv_src", []state.Message{
+		{ID: "msg_1", Role: "user", Content: "hi", CreatedAt: created},
+		{ID: "msg_2", Role: "assistant", Content: "hello", CreatedAt: created},
+	}); err != nil {
+		t.Fatalf("AddConversationItems: %v", err)
+	}
+
+	first := []state.ConversationMessage{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+	for _, resp := range []*state.Response{
+		{
+			ID: "resp_1", ConversationID: "conv_src", Status: "completed", CreatedAt: created,
+			Request:  map[string]interface{}{"conversation": "conv_src"},
+			Messages: first,
+		},
+		{
+			ID: "resp_2", ConversationID: "conv_src", PreviousResponseID: "resp_1", Status: "completed", CreatedAt: created.Add(time.Minute),
+			Request:  map[string]interface{}{"previous_response_id": "resp_1", "input": "mentions resp_1 in text"},
+			Messages: append(first, state.ConversationMessage{Role: "user", Content: "more"}),
+		},
+	} {
+		if err := store.SaveResponse(ctx, resp); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+}
+
+func TestExportImportConversation(t *testing.T) {
+	ctx := context.Background()
+	src := newExportStore(t)
+	seedConversation(t, src)
+
+	bundle, err := ExportConversation(ctx, src, "conv_src")
+	if err != nil {
+		t.Fatalf("ExportConversation: %v", err)
+	}
+	if len(bundle.Conversation.Messages) != 2 || len(bundle.Responses) != 2 {
+		t.Fatalf("bundle has %d items and %d responses", len(bundle.Conversation.Messages), len(bundle.Responses))
+	}
+	if bundle.Responses[0].ID != "resp_1" || len(bundle.Responses[1].Messages) != 3 {
+		t.Fatalf("responses not exported oldest first with full history: %+v", bundle.Responses)
+	}
+
+	t.Run("new IDs", func(t *testing.T) {
+		dst := newExportStore(t)
+		result, err := ImportConversation(ctx, dst, bundle, false)
+		if err != nil {
+			t.Fatalf("ImportConversation: %v", err)
+		}
+		convID := result.IDs["conv_src"]
+		if convID == "" || convID == "conv_src" || result.Conversation.ID != convID {
+			t.Fatalf("conversation ID not remapped: %v", result.IDs)
+		}
+
+		conv, err := dst.GetConversation(ctx, convID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if len(conv.Messages) != 2 || conv.Messages[0].ID != "msg_1" || conv.Metadata["user"] != "u1" {
+			t.Errorf("imported conversation = %+v", conv)
+		}
+
+		resp2, err := dst.GetResponse(ctx, result.IDs["resp_2"])
+		if err != nil {
+			t.Fatalf("GetResponse: %v", err)
+		}
+		if resp2.ConversationID != convID || resp2.PreviousResponseID != result.IDs["resp_1"] {
+			t.Errorf("links not remapped: conversation %q, previous %q", resp2.ConversationID, resp2.PreviousResponseID)
+		}
+		if len(resp2.Messages) != 3 {
+			t.Errorf("history has %d messages, want 3", len(resp2.Messages))
+		}
+		req := resp2.Request.(map[string]interface{})
+		if req["previous_response_id"] != result.IDs["resp_1"] {
+			t.Errorf("request previous_response_id = %v", req["previous_response_id"])
+		}
+		if !strings.Contains(req["input"].(string), "resp_1") {
+			t.Errorf("text mentioning an ID was rewritten: %v", req["input"])
+		}
+
+		// Importing again makes another copy
+		if _, err := ImportConversation(ctx, dst, bundle, false); err != nil {
+			t.Errorf("second import: %v", err)
+		}
+	})
+
+	t.Run("preserved IDs", func(t *testing.T) {
+		dst := newExportStore(t)
+		result, err := ImportConversation(ctx, dst, bundle, true)
+		if err != nil {
+			t.Fatalf("ImportConversation: %v", err)
+		}
+		if len(result.IDs) != 0 || result.Conversation.ID != "conv_src" {
+			t.Errorf("IDs not preserved: %+v", result)
+		}
+		resp2, err := dst.GetResponse(ctx, "resp_2")
+		if err != nil {
+			t.Fatalf("GetResponse: %v", err)
+		}
+		if resp2.PreviousResponseID != "resp_1" || len(resp2.Messages) != 3 {
+			t.Errorf("resp_2 = %+v", resp2)
+		}
+
+		// The same IDs cannot be imported twice
+		if _, err := ImportConversation(ctx, dst, bundle, true); !errors.Is(err, ErrImportConflict) {
+			t.Errorf("second import error = %v, want ErrImportConflict", err)
+		}
+	})
+}
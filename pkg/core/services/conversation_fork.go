@@ -0,0 +1,85 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// ErrForkPoint is returned when the response to fork from does not belong
+// to the conversation or has not finished.
+var ErrForkPoint = errors.New("invalid fork point")
+
+// ForkConversation creates a conversation that branches conversationID at
+// the response fromResponseID. The fork shares the history up to and
+// including that response, which its first turn continues from, and gets a
+// copy of the items added until then; later turns of either conversation
+// do not show in the other.
+//
+// Turns on a conversation do not overlap, so the items of the fork point
+// are those added before the next response of the conversation started.
+func ForkConversation(ctx context.Context, store state.SessionStore, conversationID, fromResponseID string) (*state.Conversation, error) {
+	src, err := store.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := store.GetResponse(ctx, fromResponseID)
+	if err != nil || from.ConversationID != conversationID {
+		return nil, fmt.Errorf("%w: response %s is not part of conversation %s", ErrForkPoint, fromResponseID, conversationID)
+	}
+	if from.Status == "in_progress" || from.Status == "queued" {
+		return nil, fmt.Errorf("%w: response %s is still %s", ErrForkPoint, fromResponseID, from.Status)
+	}
+
+	responses, err := store.ListResponses(ctx, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("list responses: %w", err)
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		if !responses[i].CreatedAt.Equal(responses[j].CreatedAt) {
+			return responses[i].CreatedAt.Before(responses[j].CreatedAt)
+		}
+		return responses[i].ID < responses[j].ID
+	})
+	var cutoff time.Time // zero: keep every item
+	for i, r := range responses {
+		if r.ID == fromResponseID && i+1 < len(responses) {
+			cutoff = responses[i+1].CreatedAt
+		}
+	}
+	items := make([]state.Message, 0, len(src.Messages))
+	for _, item := range src.Messages {
+		if cutoff.IsZero() || item.CreatedAt.Before(cutoff) {
+			items = append(items, item)
+		}
+	}
+
+	now := time.Now()
+	fork := &state.Conversation{
+		ID:         newID("conv_"),
+		Metadata:   src.Metadata,
+		Tenant:     src.Tenant,
+		ForkedFrom: fromResponseID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Messages:   []state.Message{},
+	}
+	if err := store.CreateConversation(ctx, fork); err != nil {
+		return nil, fmt.Errorf("create conversation: %w", err)
+	}
+	if len(items) > 0 {
+		if err := store.AddConversationItems(ctx, fork.ID, items); err != nil {
+			_ = store.DeleteConversation(context.WithoutCancel(ctx), fork.ID)
+			return nil, fmt.Errorf("add items: %w", err)
+		}
+	}
+	fork.Messages = items
+	return fork, nil
+}
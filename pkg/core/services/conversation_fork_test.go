@@ -0,0 +1,88 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestForkConversation(t *testing.T) {
+	ctx := context.Background()
+	store := newExportStore(t)
+	seedConversation(t, store)
+
+	// An item of the second turn, which a fork at resp_1 must not copy
+	src, err := store.GetConversation(ctx, "conv_src")
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if err := store.AddConversationItems(ctx, "conv_src", []state.Message{
+		{ID: "msg_3", Role: "user", Content: "more", CreatedAt: src.CreatedAt.Add(2 * time.Minute)},
+	}); err != nil {
+		t.Fatalf("AddConversationItems: %v", err)
+	}
+
+	fork, err := ForkConversation(ctx, store, "conv_src", "resp_1")
+	if err != nil {
+		t.Fatalf("ForkConversation: %v", err)
+	}
+	if fork.ID == "conv_src" || fork.ForkedFrom != "resp_1" || fork.Metadata["user"] != "u1" {
+		t.Fatalf("fork = %+v", fork)
+	}
+	stored, err := store.GetConversation(ctx, fork.ID)
+	if err != nil {
+		t.Fatalf("GetConversation(fork): %v", err)
+	}
+	if stored.ForkedFrom != "resp_1" {
+		t.Errorf("stored ForkedFrom = %q, want resp_1", stored.ForkedFrom)
+	}
+	if len(stored.Messages) != 2 || stored.Messages[1].ID != "msg_2" {
+		t.Errorf("fork items = %+v, want msg_1 and msg_2", stored.Messages)
+	}
+
+	// Forking at the last response copies every item
+	last, err := ForkConversation(ctx, store, "conv_src", "resp_2")
+	if err != nil {
+		t.Fatalf("ForkConversation(resp_2): %v", err)
+	}
+	if len(last.Messages) != 3 {
+		t.Errorf("fork at resp_2 has %d items, want 3", len(last.Messages))
+	}
+
+	// The fork point is exported with the fork
+	bundle, err := ExportConversation(ctx, store, fork.ID)
+	if err != nil {
+		t.Fatalf("ExportConversation: %v", err)
+	}
+	if len(bundle.Responses) != 1 || bundle.Responses[0].ID != "resp_1" {
+		t.Errorf("fork bundle responses = %+v, want resp_1", bundle.Responses)
+	}
+}
+
+func TestForkConversation_InvalidForkPoint(t *testing.T) {
+	ctx := context.Background()
+	store := newExportStore(t)
+	seedConversation(t, store)
+	if err := store.SaveResponse(ctx, &state.Response{
+		ID: "resp_other", ConversationID: "conv_other", Status: "completed", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+	if err := store.SaveResponse(ctx, &state.Response{
+		ID: "resp_running", ConversationID: "conv_src", Status: "in_progress", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+
+	for _, id := range []string{"resp_missing", "resp_other", "resp_running"} {
+		if _, err := ForkConversation(ctx, store, "conv_src", id); !errors.Is(err, ErrForkPoint) {
+			t.Errorf("ForkConversation(%s) error = %v, want ErrForkPoint", id, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// Kinds of objects removed by the data eraser.
+const (
+	ErasedResponse     = "response"
+	ErasedConversation = "conversation"
+	ErasedFile         = "file"
+	ErasedVectorStore  = "vector_store"
+)
+
+// ErrEmptySelector is returned when an erasure selector has no conditions,
+// which would match every object.
+var ErrEmptySelector = errors.New("selector must set a tenant or metadata")
+
+// responsePageSize is the page size used when listing responses and
+// conversations.
+const responsePageSize = 100
+
+// ErasureSelector picks the data to erase. Every condition that is set must
+// match. Files have no metadata, so they only match a selector with a tenant
+// and no metadata.
+type ErasureSelector struct {
+	Tenant   string
+	Metadata map[string]string
+}
+
+func (s ErasureSelector) matches(tenant string, metadata map[string]string) bool {
+	if s.Tenant != "" && tenant != s.Tenant {
+		return false
+	}
+	for k, v := range s.Metadata {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ErasedObject is a single object matched by an erasure.
+type ErasedObject struct {
+	Kind string
+	ID   string
+	// Items is the number of conversation items removed with a
+	// conversation.
+	Items int
+	// VectorStoreIDs lists the vector stores a file was detached from.
+	VectorStoreIDs []string
+	Removed        bool
+	Error          string
+}
+
+// ErasureReport is the result of an erasure.
+type ErasureReport struct {
+	DryRun  bool
+	Objects []ErasedObject
+}
+
+// Count returns the number of matched objects of kind.
+func (r *ErasureReport) Count(kind string) int {
+	n := 0
+	for _, o := range r.Objects {
+		if o.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Removed returns the number of objects that were removed.
+func (r *ErasureReport) Removed() int {
+	n := 0
+	for _, o := range r.Objects {
+		if o.Removed {
+			n++
+		}
+	}
+	return n
+}
+
+// DataEraser deletes every response, conversation, file and vector store
+// that belongs to a tenant or carries given metadata, for data subject
+// deletion requests. Deleting a conversation removes its items and the
+// responses made in it; deleting a file detaches it from vector stores and
+// removes its chunks; deleting a vector store removes its backend storage.
+//
+// Only data stored with a tenant can be matched by tenant. The audit log
+// and the response cache are not erased.
+type DataEraser struct {
+	store        state.SessionStore
+	files        filestore.FileStore
+	vectorStores *memory.VectorStoresStore
+	backend      vectorstore.Backend
+}
+
+// NewDataEraser creates a DataEraser. backend may be nil when vector search
+// is disabled.
+func NewDataEraser(store state.SessionStore, files filestore.FileStore, vectorStores *memory.VectorStoresStore, backend vectorstore.Backend) *DataEraser {
+	return &DataEraser{
+		store:        store,
+		files:        files,
+		vectorStores: vectorStores,
+		backend:      backend,
+	}
+}
+
+// Erase removes the data matched by sel, or only reports it when dryRun is
+// set. Objects that fail to be removed are reported with an error and the
+// erasure goes on, so it can be retried.
+func (e *DataEraser) Erase(ctx context.Context, sel ErasureSelector, dryRun bool) (*ErasureReport, error) {
+	if sel.Tenant == "" && len(sel.Metadata) == 0 {
+		return nil, ErrEmptySelector
+	}
+
+	conversations, err := e.matchConversations(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := e.matchResponses(ctx, sel, conversations)
+	if err != nil {
+		return nil, err
+	}
+	vectorStores, vsFiles, err := e.vectorStores.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list vector stores: %w", err)
+	}
+	var files []*filestore.File
+	if len(sel.Metadata) == 0 {
+		if files, err = e.matchFiles(ctx, sel.Tenant); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ErasureReport{DryRun: dryRun}
+	erase := func(o ErasedObject, remove func() error) {
+		if !dryRun {
+			if err := remove(); err != nil {
+				o.Error = err.Error()
+			} else {
+				o.Removed = true
+			}
+		}
+		report.Objects = append(report.Objects, o)
+	}
+
+	// Newest first, so stores that keep only the messages each response
+	// added do not rebuild the history of responses about to be deleted.
+	for _, r := range responses {
+		erase(ErasedObject{Kind: ErasedResponse, ID: r.ID}, func() error {
+			return e.store.DeleteResponse(ctx, r.ID)
+		})
+	}
+	for _, c := range conversations {
+		erase(ErasedObject{Kind: ErasedConversation, ID: c.ID, Items: len(c.Messages)}, func() error {
+			return e.store.DeleteConversation(ctx, c.ID)
+		})
+	}
+
+	erasedStores := make(map[string]bool)
+	sort.Slice(vectorStores, func(i, j int) bool { return vectorStores[i].ID < vectorStores[j].ID })
+	for _, vs := range vectorStores {
+		if !sel.matches(vs.Tenant, vs.Metadata) {
+			continue
+		}
+		erasedStores[vs.ID] = true
+		erase(ErasedObject{Kind: ErasedVectorStore, ID: vs.ID}, func() error {
+			if err := e.vectorStores.DeleteVectorStore(ctx, vs.ID); err != nil {
+				return err
+			}
+			if e.backend != nil {
+				return e.backend.DeleteStore(ctx, vs.ID)
+			}
+			return nil
+		})
+	}
+
+	attached := make(map[string][]string)
+	for _, f := range vsFiles {
+		if !erasedStores[f.VectorStoreID] {
+			attached[f.FileID] = append(attached[f.FileID], f.VectorStoreID)
+		}
+	}
+	for _, f := range files {
+		o := ErasedObject{Kind: ErasedFile, ID: f.ID, VectorStoreIDs: attached[f.ID]}
+		erase(o, func() error {
+			_, err := deleteFile(ctx, e.files, e.vectorStores, e.backend, f.ID, attached[f.ID])
+			return err
+		})
+	}
+
+	return report, nil
+}
+
+// matchConversations lists the conversations matched by sel.
+func (e *DataEraser) matchConversations(ctx context.Context, sel ErasureSelector) ([]*state.Conversation, error) {
+	var matched []*state.Conversation
+	after := ""
+	for {
+		page, hasMore, err := e.store.ListConversationsPaginated(ctx, after, "", responsePageSize, "asc")
+		if err != nil {
+			return nil, fmt.Errorf("list conversations: %w", err)
+		}
+		for _, c := range page {
+			if sel.matches(c.Tenant, c.Metadata) {
+				matched = append(matched, c)
+			}
+		}
+		if !hasMore || len(page) == 0 {
+			return matched, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// matchResponses lists the responses matched by sel and those made in
+// conversations, newest first.
+func (e *DataEraser) matchResponses(ctx context.Context, sel ErasureSelector, conversations []*state.Conversation) ([]*state.Response, error) {
+	seen := make(map[string]bool)
+	var matched []*state.Response
+	add := func(r *state.Response) {
+		if !seen[r.ID] {
+			seen[r.ID] = true
+			matched = append(matched, r)
+		}
+	}
+
+	filter := state.ResponseFilter{Tenant: sel.Tenant, Metadata: sel.Metadata}
+	after := ""
+	for {
+		page, hasMore, err := e.store.ListResponsesPaginated(ctx, after, "", responsePageSize, "desc", filter)
+		if err != nil {
+			return nil, fmt.Errorf("list responses: %w", err)
+		}
+		for _, r := range page {
+			add(r)
+		}
+		if !hasMore || len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+
+	for _, c := range conversations {
+		responses, err := e.store.ListResponses(ctx, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list responses of %s: %w", c.ID, err)
+		}
+		for _, r := range responses {
+			add(r)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+	return matched, nil
+}
+
+// matchFiles lists the files uploaded by tenant.
+func (e *DataEraser) matchFiles(ctx context.Context, tenant string) ([]*filestore.File, error) {
+	var matched []*filestore.File
+	after := ""
+	for {
+		page, hasMore, err := e.files.ListFilesPaginated(ctx, after, "", filePageSize, "asc", "")
+		if err != nil {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+		for _, f := range page {
+			if f.Tenant == tenant {
+				matched = append(matched, f)
+			}
+		}
+		if !hasMore || len(page) == 0 {
+			return matched, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
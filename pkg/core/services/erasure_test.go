@@ -0,0 +1,237 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	fsmemory "github.com/leseb/openresponses-gw/pkg/filestore/memory"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+// newErasureFixture stores data of tenants "acme" and "globex":
+//
+//   - conv_acme (acme) with resp_acme_1 and resp_acme_2
+//   - resp_tagged (globex) with request metadata user=u1
+//   - conv_globex and resp_globex (globex)
+//   - file_acme attached to vs_globex, file_globex
+//   - vs_acme (acme) holding file_globex, vs_globex (globex)
+func newErasureFixture(t *testing.T) (*DataEraser, state.SessionStore, filestore.FileStore, *memory.VectorStoresStore, *fakeBackend) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	store := newExportStore(t)
+	for _, c := range []*state.Conversation{
+		{ID: "conv_acme", Tenant: "acme", Metadata: map[string]string{}, CreatedAt: now, UpdatedAt: now},
+		{ID: "conv_globex", Tenant: "globex", Metadata: map[string]string{"user": "u2"}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := store.CreateConversation(ctx, c); err != nil {
+			t.Fatalf("CreateConversation: %v", err)
+		}
+	}
+	if err := store.AddConversationItems(ctx, "conv_acme", []state.Message{{ID: "msg_1", Role: "user", Content: "hi", CreatedAt: now}}); err != nil {
+		t.Fatalf("AddConversationItems: %v", err)
+	}
+	for i, r := range []*state.Response{
+		{ID: "resp_acme_1", ConversationID: "conv_acme", Tenant: "acme"},
+		{ID: "resp_acme_2", ConversationID: "conv_acme", PreviousResponseID: "resp_acme_1", Tenant: "acme"},
+		{ID: "resp_tagged", Tenant: "globex", Request: map[string]interface{}{"metadata": map[string]interface{}{"user": "u1"}}},
+		{ID: "resp_globex", ConversationID: "conv_globex", Tenant: "globex"},
+	} {
+		r.Status = "completed"
+		r.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		if err := store.SaveResponse(ctx, r); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+
+	files := fsmemory.New()
+	for _, f := range []*filestore.File{
+		{ID: "file_acme", Purpose: "assistants", Tenant: "acme", CreatedAt: now},
+		{ID: "file_globex", Purpose: "assistants", Tenant: "globex", CreatedAt: now},
+	} {
+		if err := files.CreateFile(ctx, f); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+
+	vectorStores := memory.NewVectorStoresStore()
+	for _, vs := range []*memory.VectorStore{
+		{ID: "vs_acme", Tenant: "acme"},
+		{ID: "vs_globex", Tenant: "globex", Metadata: map[string]string{"user": "u2"}},
+	} {
+		if err := vectorStores.CreateVectorStore(ctx, vs); err != nil {
+			t.Fatalf("CreateVectorStore: %v", err)
+		}
+	}
+	for _, f := range []*memory.VectorStoreFile{
+		{ID: "file_acme", VectorStoreID: "vs_globex", FileID: "file_acme", Status: "completed"},
+		{ID: "file_globex", VectorStoreID: "vs_acme", FileID: "file_globex", Status: "completed"},
+	} {
+		if err := vectorStores.AddVectorStoreFile(ctx, f); err != nil {
+			t.Fatalf("AddVectorStoreFile: %v", err)
+		}
+	}
+	backend := &fakeBackend{stores: map[string]map[string]bool{
+		"vs_acme":   {"file_globex": true},
+		"vs_globex": {"file_acme": true},
+	}}
+
+	return NewDataEraser(store, files, vectorStores, backend), store, files, vectorStores, backend
+}
+
+func erasedIDs(report *ErasureReport, kind string) []string {
+	var ids []string
+	for _, o := range report.Objects {
+		if o.Kind == kind {
+			ids = append(ids, o.ID)
+		}
+	}
+	return ids
+}
+
+func TestDataEraser_Erase(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name              string
+		sel               ErasureSelector
+		wantResponses     []string
+		wantConversations []string
+		wantFiles         []string
+		wantVectorStores  []string
+	}{
+		{
+			name:              "tenant",
+			sel:               ErasureSelector{Tenant: "acme"},
+			wantResponses:     []string{"resp_acme_2", "resp_acme_1"},
+			wantConversations: []string{"conv_acme"},
+			wantFiles:         []string{"file_acme"},
+			wantVectorStores:  []string{"vs_acme"},
+		},
+		{
+			name:              "metadata",
+			sel:               ErasureSelector{Metadata: map[string]string{"user": "u2"}},
+			wantResponses:     []string{"resp_globex"},
+			wantConversations: []string{"conv_globex"},
+			wantVectorStores:  []string{"vs_globex"},
+		},
+		{
+			name:          "tenant and metadata",
+			sel:           ErasureSelector{Tenant: "globex", Metadata: map[string]string{"user": "u1"}},
+			wantResponses: []string{"resp_tagged"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eraser, store, _, _, _ := newErasureFixture(t)
+
+			report, err := eraser.Erase(ctx, tt.sel, false)
+			if err != nil {
+				t.Fatalf("Erase: %v", err)
+			}
+			for _, c := range []struct {
+				kind string
+				want []string
+			}{
+				{ErasedResponse, tt.wantResponses},
+				{ErasedConversation, tt.wantConversations},
+				{ErasedFile, tt.wantFiles},
+				{ErasedVectorStore, tt.wantVectorStores},
+			} {
+				if got := erasedIDs(report, c.kind); !slices.Equal(got, c.want) {
+					t.Errorf("%s erased = %v, want %v", c.kind, got, c.want)
+				}
+			}
+			if report.Removed() != len(report.Objects) {
+				t.Errorf("removed %d of %d: %+v", report.Removed(), len(report.Objects), report.Objects)
+			}
+			for _, id := range tt.wantResponses {
+				if _, err := store.GetResponse(ctx, id); err == nil {
+					t.Errorf("%s was not deleted", id)
+				}
+			}
+		})
+	}
+}
+
+func TestDataEraser_EraseTenantCleansVectorStores(t *testing.T) {
+	ctx := context.Background()
+	eraser, store, files, vectorStores, backend := newErasureFixture(t)
+
+	report, err := eraser.Erase(ctx, ErasureSelector{Tenant: "acme"}, false)
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	for _, o := range report.Objects {
+		if o.Kind == ErasedConversation && o.Items != 1 {
+			t.Errorf("conv_acme items = %d, want 1", o.Items)
+		}
+		if o.Kind == ErasedFile && (len(o.VectorStoreIDs) != 1 || o.VectorStoreIDs[0] != "vs_globex") {
+			t.Errorf("file_acme detached from %v, want [vs_globex]", o.VectorStoreIDs)
+		}
+	}
+
+	if _, err := store.GetConversation(ctx, "conv_acme"); err == nil {
+		t.Error("conv_acme was not deleted")
+	}
+	if _, err := files.GetFile(ctx, "file_acme"); err == nil {
+		t.Error("file_acme was not deleted")
+	}
+	if _, err := vectorStores.GetVectorStoreFile(ctx, "vs_globex", "file_acme"); err == nil {
+		t.Error("file_acme was not detached from vs_globex")
+	}
+	if backend.stores["vs_globex"]["file_acme"] {
+		t.Error("chunks of file_acme were not deleted")
+	}
+	if _, ok := backend.stores["vs_acme"]; ok {
+		t.Error("backend store of vs_acme was not deleted")
+	}
+
+	// Data of other tenants is untouched, including files in erased stores
+	for _, id := range []string{"resp_tagged", "resp_globex"} {
+		if _, err := store.GetResponse(ctx, id); err != nil {
+			t.Errorf("%s was deleted: %v", id, err)
+		}
+	}
+	if _, err := files.GetFile(ctx, "file_globex"); err != nil {
+		t.Errorf("file_globex was deleted: %v", err)
+	}
+	if _, err := vectorStores.GetVectorStore(ctx, "vs_globex"); err != nil {
+		t.Errorf("vs_globex was deleted: %v", err)
+	}
+}
+
+func TestDataEraser_DryRun(t *testing.T) {
+	ctx := context.Background()
+	eraser, store, files, _, _ := newErasureFixture(t)
+
+	report, err := eraser.Erase(ctx, ErasureSelector{Tenant: "acme"}, true)
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if !report.DryRun || len(report.Objects) != 5 || report.Removed() != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if _, err := store.GetResponse(ctx, "resp_acme_1"); err != nil {
+		t.Errorf("dry run deleted resp_acme_1: %v", err)
+	}
+	if _, err := files.GetFile(ctx, "file_acme"); err != nil {
+		t.Errorf("dry run deleted file_acme: %v", err)
+	}
+}
+
+func TestDataEraser_EmptySelector(t *testing.T) {
+	eraser, _, _, _, _ := newErasureFixture(t)
+	if _, err := eraser.Erase(context.Background(), ErasureSelector{}, true); !errors.Is(err, ErrEmptySelector) {
+		t.Errorf("Erase error = %v, want ErrEmptySelector", err)
+	}
+}
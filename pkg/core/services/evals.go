@@ -0,0 +1,320 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+// Eval run statuses.
+const (
+	EvalStatusInProgress = "in_progress"
+	EvalStatusCompleted  = "completed"
+)
+
+const (
+	// MaxEvalPrompts bounds the prompts of an eval run.
+	MaxEvalPrompts = 1000
+	// maxEvalPromptIDLength bounds prompt IDs, which are recorded in the
+	// metadata of the responses.
+	maxEvalPromptIDLength = 64
+	// evalConcurrency is the number of prompts of a run processed at once,
+	// each by both configurations.
+	evalConcurrency = 4
+)
+
+// ErrInvalidEval is returned for eval runs that cannot be started as
+// requested.
+var ErrInvalidEval = errors.New("invalid eval run")
+
+// EvalPrompt is a prompt of an eval run.
+type EvalPrompt struct {
+	ID    string          `json:"id,omitempty"`
+	Input json.RawMessage `json:"input"` // string or input items, as in /v1/responses
+}
+
+// ResponseProcessor creates responses; the engine implements it.
+type ResponseProcessor interface {
+	ProcessRequest(ctx context.Context, req *schema.ResponseRequest) (*schema.Response, error)
+}
+
+// ParseEvalPrompts parses a prompt set in JSON Lines format: one
+// {"id": ..., "input": ...} object per line, blank lines ignored.
+func ParseEvalPrompts(data []byte) ([]EvalPrompt, error) {
+	var prompts []EvalPrompt
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var p EvalPrompt
+		if err := json.Unmarshal(text, &p); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidEval, line, err)
+		}
+		prompts = append(prompts, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read prompts: %w", err)
+	}
+	return prompts, nil
+}
+
+// EvalRunner runs eval runs: every prompt through both configurations of
+// the run, recording the paired outputs in the store as they come.
+type EvalRunner struct {
+	store     *memory.EvalsStore
+	processor ResponseProcessor
+}
+
+// NewEvalRunner creates an EvalRunner.
+func NewEvalRunner(store *memory.EvalsStore, processor ResponseProcessor) *EvalRunner {
+	return &EvalRunner{store: store, processor: processor}
+}
+
+// Start checks the prompts and the configurations of run, stores run and
+// processes it in the background, with the values of ctx, such as the API
+// key, but not its cancellation. Prompts without an ID are numbered from
+// "prompt_1". Errors wrapping ErrInvalidEval are the caller's.
+func (r *EvalRunner) Start(ctx context.Context, run *memory.EvalRun, prompts []EvalPrompt) error {
+	if len(prompts) == 0 {
+		return fmt.Errorf("%w: no prompts", ErrInvalidEval)
+	}
+	if len(prompts) > MaxEvalPrompts {
+		return fmt.Errorf("%w: %d prompts, at most %d are allowed", ErrInvalidEval, len(prompts), MaxEvalPrompts)
+	}
+	seen := make(map[string]bool, len(prompts))
+	run.Results = make([]memory.EvalResult, len(prompts))
+	for i, p := range prompts {
+		if p.ID == "" {
+			p.ID = fmt.Sprintf("prompt_%d", i+1)
+		}
+		if len(p.ID) > maxEvalPromptIDLength {
+			return fmt.Errorf("%w: prompt id %q is longer than %d characters", ErrInvalidEval, p.ID, maxEvalPromptIDLength)
+		}
+		if seen[p.ID] {
+			return fmt.Errorf("%w: duplicate prompt id %q", ErrInvalidEval, p.ID)
+		}
+		seen[p.ID] = true
+		for _, variant := range []string{"a", "b"} {
+			if _, err := evalRequest(run, variant, p.ID, p.Input); err != nil {
+				return fmt.Errorf("%w: prompt %s: %v", ErrInvalidEval, p.ID, err)
+			}
+		}
+		run.Results[i] = memory.EvalResult{PromptID: p.ID, Input: p.Input}
+	}
+
+	run.Status = EvalStatusInProgress
+	if err := r.store.CreateEvalRun(ctx, run); err != nil {
+		return err
+	}
+	// The caller keeps run
+	own := *run
+	own.Results = slices.Clone(run.Results)
+	go r.run(context.WithoutCancel(ctx), &own)
+	return nil
+}
+
+// run processes the prompts of a stored run and completes it.
+func (r *EvalRunner) run(ctx context.Context, run *memory.EvalRun) {
+	var mu sync.Mutex // guards run
+	record := func(i int, variant string, out *memory.EvalOutput) {
+		mu.Lock()
+		defer mu.Unlock()
+		if variant == "a" {
+			run.Results[i].A = out
+		} else {
+			run.Results[i].B = out
+		}
+		r.store.UpdateEvalRun(ctx, run)
+	}
+
+	prompts := slices.Clone(run.Results)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(evalConcurrency, len(prompts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var pair sync.WaitGroup
+				for _, variant := range []string{"a", "b"} {
+					pair.Add(1)
+					go func() {
+						defer pair.Done()
+						record(i, variant, r.process(ctx, run, variant, prompts[i]))
+					}()
+				}
+				pair.Wait()
+			}
+		}()
+	}
+	for i := range prompts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	run.Status = EvalStatusCompleted
+	run.CompletedAt = &now
+	r.store.UpdateEvalRun(ctx, run)
+}
+
+// process runs one prompt through one configuration.
+func (r *EvalRunner) process(ctx context.Context, run *memory.EvalRun, variant string, result memory.EvalResult) *memory.EvalOutput {
+	req, err := evalRequest(run, variant, result.PromptID, result.Input)
+	if err != nil {
+		return &memory.EvalOutput{Status: "failed", Error: err.Error()}
+	}
+	start := time.Now()
+	resp, err := r.processor.ProcessRequest(ctx, req)
+	latency := time.Since(start)
+	if err != nil {
+		return &memory.EvalOutput{Model: *req.Model, Status: "failed", Latency: latency, Error: err.Error()}
+	}
+
+	out := &memory.EvalOutput{
+		ResponseID: resp.ID,
+		Model:      resp.Model,
+		Status:     resp.Status,
+		Latency:    latency,
+	}
+	var text []string
+	for _, item := range resp.Output {
+		for _, cp := range item.Content {
+			if cp.Type == "output_text" && cp.Text != nil {
+				text = append(text, *cp.Text)
+			}
+		}
+	}
+	out.OutputText = strings.Join(text, "\n")
+	if resp.Usage != nil {
+		out.InputTokens = resp.Usage.InputTokens
+		out.OutputTokens = resp.Usage.OutputTokens
+	}
+	switch {
+	case resp.Error != nil:
+		out.Error = resp.Error.Message
+	case resp.IncompleteDetails != nil:
+		out.Error = "incomplete: " + resp.IncompleteDetails.Reason
+	}
+	return out
+}
+
+// evalRequest returns the request of a prompt for configuration variant
+// ("a" or "b") of run. The responses are tagged with the run, variant and
+// prompt in their metadata, so that they can be listed later.
+func evalRequest(run *memory.EvalRun, variant, promptID string, input json.RawMessage) (*schema.ResponseRequest, error) {
+	config := run.A
+	if variant == "b" {
+		config = run.B
+	}
+	var req schema.ResponseRequest
+	if err := json.Unmarshal(config, &req); err != nil {
+		return nil, fmt.Errorf("%s: %v", variant, err)
+	}
+	switch {
+	case req.Input != nil:
+		return nil, fmt.Errorf("%s: input is set by the prompts", variant)
+	case req.PreviousResponseID != nil, req.Conversation != nil:
+		return nil, fmt.Errorf("%s: previous_response_id and conversation are not supported", variant)
+	case req.Stream:
+		return nil, fmt.Errorf("%s: stream is not supported", variant)
+	}
+	if len(input) == 0 {
+		return nil, fmt.Errorf("input is required")
+	}
+	if err := json.Unmarshal(input, &req.Input); err != nil {
+		return nil, fmt.Errorf("input: %v", err)
+	}
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]string)
+	}
+	req.Metadata["eval_id"] = run.ID
+	req.Metadata["eval_variant"] = variant
+	req.Metadata["eval_prompt_id"] = promptID
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", variant, err)
+	}
+	return &req, nil
+}
+
+// EvalSummary aggregates the outputs of an eval run.
+type EvalSummary struct {
+	Prompts   int // prompts of the run
+	Completed int // prompts both configurations have run
+	Identical int // completed prompts with the same output text for both
+	A, B      EvalVariantSummary
+}
+
+// EvalVariantSummary aggregates the outputs of one configuration.
+type EvalVariantSummary struct {
+	Completed      int // responses with status "completed"
+	Failed         int // other outputs
+	InputTokens    int
+	OutputTokens   int
+	AverageLatency time.Duration
+}
+
+// SummarizeEval aggregates the outputs recorded so far for run.
+func SummarizeEval(run *memory.EvalRun) EvalSummary {
+	s := EvalSummary{Prompts: len(run.Results)}
+	var latencyA, latencyB time.Duration
+	for _, result := range run.Results {
+		latencyA += s.A.add(result.A)
+		latencyB += s.B.add(result.B)
+		if result.A != nil && result.B != nil {
+			s.Completed++
+			if EvalIdentical(result) {
+				s.Identical++
+			}
+		}
+	}
+	if n := s.A.Completed + s.A.Failed; n > 0 {
+		s.A.AverageLatency = latencyA / time.Duration(n)
+	}
+	if n := s.B.Completed + s.B.Failed; n > 0 {
+		s.B.AverageLatency = latencyB / time.Duration(n)
+	}
+	return s
+}
+
+// add counts out, if recorded, and returns its latency.
+func (s *EvalVariantSummary) add(out *memory.EvalOutput) time.Duration {
+	if out == nil {
+		return 0
+	}
+	if out.Status == "completed" {
+		s.Completed++
+	} else {
+		s.Failed++
+	}
+	s.InputTokens += out.InputTokens
+	s.OutputTokens += out.OutputTokens
+	return out.Latency
+}
+
+// EvalIdentical reports whether both configurations completed result with
+// the same output text.
+func EvalIdentical(result memory.EvalResult) bool {
+	return result.A != nil && result.B != nil &&
+		result.A.Status == "completed" && result.B.Status == "completed" &&
+		result.A.OutputText == result.B.OutputText
+}
@@ -0,0 +1,183 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+// echoProcessor answers with the input, upper-cased by model "upper", and
+// fails for model "broken".
+type echoProcessor struct {
+	mu   sync.Mutex
+	reqs []*schema.ResponseRequest
+}
+
+func (p *echoProcessor) ProcessRequest(ctx context.Context, req *schema.ResponseRequest) (*schema.Response, error) {
+	p.mu.Lock()
+	p.reqs = append(p.reqs, req)
+	p.mu.Unlock()
+	if *req.Model == "broken" {
+		return nil, errors.New("backend unavailable")
+	}
+	text := fmt.Sprint(req.Input)
+	if *req.Model == "upper" && text == "hi" {
+		text = "HI"
+	}
+	return &schema.Response{
+		ID:     "resp_" + req.Metadata["eval_variant"] + "_" + req.Metadata["eval_prompt_id"],
+		Model:  *req.Model,
+		Status: "completed",
+		Output: []schema.ItemField{{Type: "message", Content: []schema.ContentPart{{Type: "output_text", Text: &text}}}},
+		Usage:  &schema.UsageField{InputTokens: 3, OutputTokens: 2},
+	}, nil
+}
+
+// waitEval waits for an eval run to complete.
+func waitEval(t *testing.T, store *memory.EvalsStore, id string) *memory.EvalRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := store.GetEvalRun(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetEvalRun: %v", err)
+		}
+		if run.Status == EvalStatusCompleted {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("eval run still %s", run.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEvalRunner(t *testing.T) {
+	store := memory.NewEvalsStore()
+	processor := &echoProcessor{}
+	runner := NewEvalRunner(store, processor)
+
+	run := &memory.EvalRun{
+		ID: "eval_1",
+		A:  json.RawMessage(`{"model": "echo", "metadata": {"team": "search"}}`),
+		B:  json.RawMessage(`{"model": "upper", "temperature": 0.2}`),
+	}
+	prompts := []EvalPrompt{
+		{ID: "greeting", Input: json.RawMessage(`"hi"`)},
+		{Input: json.RawMessage(`"bye"`)},
+	}
+	if err := runner.Start(context.Background(), run, prompts); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if run.Status != EvalStatusInProgress || len(run.Results) != 2 || run.Results[1].PromptID != "prompt_2" {
+		t.Fatalf("started run = %+v", run)
+	}
+
+	done := waitEval(t, store, "eval_1")
+	if done.CompletedAt == nil {
+		t.Error("CompletedAt not set")
+	}
+	greeting := done.Results[0]
+	if greeting.A.OutputText != "hi" || greeting.B.OutputText != "HI" || greeting.B.ResponseID != "resp_b_greeting" || EvalIdentical(greeting) {
+		t.Errorf("greeting result = A %+v, B %+v", greeting.A, greeting.B)
+	}
+	if !EvalIdentical(done.Results[1]) {
+		t.Errorf("bye result = A %+v, B %+v, want identical", done.Results[1].A, done.Results[1].B)
+	}
+
+	for _, req := range processor.reqs {
+		if req.Metadata["eval_id"] != "eval_1" || req.Stream {
+			t.Errorf("request metadata = %v", req.Metadata)
+		}
+		if req.Metadata["eval_variant"] == "a" && req.Metadata["team"] != "search" {
+			t.Errorf("configuration metadata dropped: %v", req.Metadata)
+		}
+		if req.Metadata["eval_variant"] == "b" && (req.Temperature == nil || *req.Temperature != 0.2) {
+			t.Errorf("configuration B temperature = %v", req.Temperature)
+		}
+	}
+
+	summary := SummarizeEval(done)
+	if summary.Prompts != 2 || summary.Completed != 2 || summary.Identical != 1 ||
+		summary.A.Completed != 2 || summary.B.OutputTokens != 4 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestEvalRunner_Failures(t *testing.T) {
+	store := memory.NewEvalsStore()
+	runner := NewEvalRunner(store, &echoProcessor{})
+	run := &memory.EvalRun{
+		ID: "eval_2",
+		A:  json.RawMessage(`{"model": "echo"}`),
+		B:  json.RawMessage(`{"model": "broken"}`),
+	}
+	if err := runner.Start(context.Background(), run, []EvalPrompt{{Input: json.RawMessage(`"hi"`)}}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	done := waitEval(t, store, "eval_2")
+	b := done.Results[0].B
+	if b.Status != "failed" || b.Error != "backend unavailable" || b.Model != "broken" {
+		t.Errorf("B = %+v, want the backend error", b)
+	}
+	if summary := SummarizeEval(done); summary.B.Failed != 1 || summary.Identical != 0 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestEvalRunner_Invalid(t *testing.T) {
+	hi := json.RawMessage(`"hi"`)
+	tests := []struct {
+		name    string
+		a       string
+		prompts []EvalPrompt
+	}{
+		{"no prompts", `{"model": "m"}`, nil},
+		{"no model", `{}`, []EvalPrompt{{Input: hi}}},
+		{"input in configuration", `{"model": "m", "input": "x"}`, []EvalPrompt{{Input: hi}}},
+		{"conversation", `{"model": "m", "conversation": "conv_1"}`, []EvalPrompt{{Input: hi}}},
+		{"stream", `{"model": "m", "stream": true}`, []EvalPrompt{{Input: hi}}},
+		{"missing input", `{"model": "m"}`, []EvalPrompt{{ID: "p"}}},
+		{"duplicate ids", `{"model": "m"}`, []EvalPrompt{{ID: "p", Input: hi}, {ID: "p", Input: hi}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewEvalsStore()
+			run := &memory.EvalRun{ID: "eval_x", A: json.RawMessage(tt.a), B: json.RawMessage(`{"model": "m"}`)}
+			err := NewEvalRunner(store, &echoProcessor{}).Start(context.Background(), run, tt.prompts)
+			if !errors.Is(err, ErrInvalidEval) {
+				t.Fatalf("Start error = %v, want ErrInvalidEval", err)
+			}
+			if _, err := store.GetEvalRun(context.Background(), "eval_x"); err == nil {
+				t.Error("invalid run was stored")
+			}
+		})
+	}
+}
+
+func TestParseEvalPrompts(t *testing.T) {
+	data := []byte(`{"id": "q1", "input": "What is 2+2?"}
+
+{"input": [{"role": "user", "content": "Hello"}]}
+`)
+	prompts, err := ParseEvalPrompts(data)
+	if err != nil {
+		t.Fatalf("ParseEvalPrompts: %v", err)
+	}
+	if len(prompts) != 2 || prompts[0].ID != "q1" || string(prompts[0].Input) != `"What is 2+2?"` || prompts[1].ID != "" {
+		t.Errorf("prompts = %+v", prompts)
+	}
+	if _, err := ParseEvalPrompts([]byte("{\"input\": \"ok\"}\nnot json\n")); !errors.Is(err, ErrInvalidEval) {
+		t.Errorf("invalid line: err = %v, want ErrInvalidEval", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+)
+
+// EventRelayOptions configures an EventRelay.
+type EventRelayOptions struct {
+	Topics        eventbus.Topics
+	Serialization string        // "json" or "cloudevents"
+	Source        string        // CloudEvents source
+	BatchSize     int           // events claimed and published at once
+	Lease         time.Duration // how long claimed events wait before another replica retries them
+}
+
+// EventRelayStats counts the events published by an EventRelay since the
+// gateway started.
+type EventRelayStats struct {
+	Runs      int       // Completed or failed runs
+	Published int       // Events published
+	LastRun   time.Time // When the last run finished
+	LastError string    // Error of the last run, if it failed
+}
+
+// EventRelay publishes the events of the session store outbox to the
+// event bus, deleting them once the broker acknowledged them. Events whose
+// publication fails stay in the outbox and are retried once their lease
+// elapses.
+type EventRelay struct {
+	outbox    state.EventOutbox
+	publisher eventbus.Publisher
+	opts      EventRelayOptions
+
+	mu    sync.Mutex
+	stats EventRelayStats
+}
+
+// NewEventRelay creates an EventRelay.
+func NewEventRelay(outbox state.EventOutbox, publisher eventbus.Publisher, opts EventRelayOptions) *EventRelay {
+	return &EventRelay{outbox: outbox, publisher: publisher, opts: opts}
+}
+
+// Run publishes batches of events until the outbox is empty and returns
+// the number of events published. Batches published before an error are
+// still counted.
+func (r *EventRelay) Run(ctx context.Context) (int, error) {
+	published := 0
+	var err error
+	for {
+		var n int
+		n, err = r.publishBatch(ctx)
+		published += n
+		if err != nil || n < r.opts.BatchSize {
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Runs++
+	r.stats.Published += published
+	r.stats.LastRun = time.Now()
+	r.stats.LastError = ""
+	if err != nil {
+		r.stats.LastError = err.Error()
+	}
+	return published, err
+}
+
+// publishBatch claims, publishes and deletes a batch of events. An event
+// that cannot be encoded is left claimed, so it does not hold back the
+// others.
+func (r *EventRelay) publishBatch(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	events, err := r.outbox.ClaimOutboxEvents(ctx, r.opts.BatchSize, r.opts.Lease)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	var (
+		msgs      = make([]eventbus.Message, 0, len(events))
+		ids       = make([]string, 0, len(events))
+		encodeErr error
+	)
+	for _, event := range events {
+		msg, err := eventbus.Encode(event, r.opts.Topics.For(event.Type), r.opts.Serialization, r.opts.Source)
+		if err != nil {
+			encodeErr = errors.Join(encodeErr, err)
+			continue
+		}
+		msgs = append(msgs, msg)
+		ids = append(ids, event.ID)
+	}
+	if err := r.publisher.Publish(ctx, msgs); err != nil {
+		return 0, err
+	}
+	if err := r.outbox.DeleteOutboxEvents(ctx, ids); err != nil {
+		return 0, err
+	}
+	if encodeErr != nil {
+		return len(ids), encodeErr
+	}
+	return len(events), nil
+}
+
+// Stats returns the events published since the gateway started.
+func (r *EventRelay) Stats() EventRelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
@@ -0,0 +1,115 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+)
+
+// fakeOutbox holds events in order; claimed events are skipped until
+// released.
+type fakeOutbox struct {
+	events  []*state.OutboxEvent
+	claimed map[string]bool
+}
+
+func (f *fakeOutbox) ClaimOutboxEvents(_ context.Context, limit int, _ time.Duration) ([]*state.OutboxEvent, error) {
+	var out []*state.OutboxEvent
+	for _, event := range f.events {
+		if len(out) < limit && !f.claimed[event.ID] {
+			f.claimed[event.ID] = true
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeOutbox) DeleteOutboxEvents(_ context.Context, ids []string) error {
+	for _, id := range ids {
+		for i, event := range f.events {
+			if event.ID == id {
+				f.events = append(f.events[:i], f.events[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// fakePublisher records published messages, or fails while err is set.
+type fakePublisher struct {
+	msgs []eventbus.Message
+	err  error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msgs []eventbus.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestEventRelay_Run(t *testing.T) {
+	ctx := context.Background()
+	outbox := &fakeOutbox{claimed: make(map[string]bool)}
+	for i := range 5 {
+		eventType := eventbus.ResponseCompleted
+		if i%2 == 1 {
+			eventType = eventbus.ResponseUsage
+		}
+		outbox.events = append(outbox.events, &state.OutboxEvent{
+			ID: fmt.Sprintf("evt_%d", i), Type: eventType, ResponseID: "resp_1", Data: []byte(`{}`), CreatedAt: time.Now(),
+		})
+	}
+	publisher := &fakePublisher{err: errors.New("broker down")}
+	relay := NewEventRelay(outbox, publisher, EventRelayOptions{
+		Topics:        eventbus.Topics{Responses: "responses", Usage: "usage"},
+		Serialization: eventbus.SerializationJSON,
+		BatchSize:     2,
+		Lease:         time.Minute,
+	})
+
+	// A failed batch stays in the outbox
+	if n, err := relay.Run(ctx); err == nil || n != 0 {
+		t.Fatalf("Run with the broker down = %d, %v", n, err)
+	}
+	if len(outbox.events) != 5 {
+		t.Fatalf("outbox has %d events, want 5", len(outbox.events))
+	}
+
+	// Once its lease elapsed, it is published with the rest
+	publisher.err = nil
+	clear(outbox.claimed)
+	n, err := relay.Run(ctx)
+	if err != nil || n != 5 {
+		t.Fatalf("Run = %d, %v, want 5", n, err)
+	}
+	if len(outbox.events) != 0 {
+		t.Errorf("outbox has %d events after publishing, want 0", len(outbox.events))
+	}
+	for i, msg := range publisher.msgs {
+		wantTopic := "responses"
+		if i%2 == 1 {
+			wantTopic = "usage"
+		}
+		if msg.ID != fmt.Sprintf("evt_%d", i) || msg.Topic != wantTopic || msg.Key != "resp_1" {
+			t.Errorf("message %d = %+v", i, msg)
+		}
+	}
+
+	stats := relay.Stats()
+	if stats.Runs != 2 || stats.Published != 5 || stats.LastError != "" {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// Orphan kinds reported by the garbage collector.
+const (
+	// OrphanVectorStoreFile is a vector store file whose underlying file no
+	// longer exists in the file store.
+	OrphanVectorStoreFile = "vector_store_file"
+	// OrphanChunks are backend chunks for a file that is not attached to
+	// the vector store.
+	OrphanChunks = "chunks"
+	// OrphanBackendStore is backend storage for a vector store that no
+	// longer exists.
+	OrphanBackendStore = "backend_store"
+	// OrphanFile is an "assistants" file that no vector store refers to.
+	OrphanFile = "file"
+)
+
+// filePageSize is the page size used when listing the file store.
+const filePageSize = 100
+
+// GCOptions controls a garbage collection run.
+type GCOptions struct {
+	// DryRun reports orphans without removing them.
+	DryRun bool
+	// MinAge protects files created more recently than this from removal,
+	// so that uploads about to be attached are not collected.
+	MinAge time.Duration
+	// IncludeFiles also collects "assistants" files that are not attached
+	// to any vector store.
+	IncludeFiles bool
+}
+
+// Orphan is a single object found by the garbage collector.
+type Orphan struct {
+	Kind          string
+	VectorStoreID string
+	FileID        string
+	Removed       bool
+	Error         string
+}
+
+// GCReport is the result of a garbage collection run.
+type GCReport struct {
+	DryRun  bool
+	Orphans []Orphan
+}
+
+// Removed returns the number of orphans that were removed.
+func (r *GCReport) Removed() int {
+	n := 0
+	for _, o := range r.Orphans {
+		if o.Removed {
+			n++
+		}
+	}
+	return n
+}
+
+// GarbageCollector cross-references the file store, the vector store
+// metadata and the vector store backend, and reports or removes objects
+// that are no longer reachable.
+//
+// Backend checks only run when the backend implements vectorstore.Inventory.
+type GarbageCollector struct {
+	files        filestore.FileStore
+	vectorStores *memory.VectorStoresStore
+	backend      vectorstore.Backend
+}
+
+// NewGarbageCollector creates a GarbageCollector. backend may be nil when
+// vector search is disabled.
+func NewGarbageCollector(files filestore.FileStore, vectorStores *memory.VectorStoresStore, backend vectorstore.Backend) *GarbageCollector {
+	return &GarbageCollector{
+		files:        files,
+		vectorStores: vectorStores,
+		backend:      backend,
+	}
+}
+
+// Run performs one garbage collection pass.
+func (gc *GarbageCollector) Run(ctx context.Context, opts GCOptions) (*GCReport, error) {
+	report := &GCReport{DryRun: opts.DryRun}
+
+	_, vsFiles, err := gc.vectorStores.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list vector stores: %w", err)
+	}
+
+	// Vector store files whose file is gone from the file store
+	for _, f := range vsFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, err := gc.files.GetFile(ctx, f.FileID)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, filestore.ErrFileNotFound):
+			report.add(opts, Orphan{Kind: OrphanVectorStoreFile, VectorStoreID: f.VectorStoreID, FileID: f.FileID}, func() error {
+				if err := gc.vectorStores.DeleteVectorStoreFile(ctx, f.VectorStoreID, f.FileID); err != nil {
+					return err
+				}
+				if gc.backend != nil {
+					return gc.backend.DeleteFileChunks(ctx, f.VectorStoreID, f.FileID)
+				}
+				return nil
+			})
+		default:
+			return nil, fmt.Errorf("get file %s: %w", f.FileID, err)
+		}
+	}
+
+	if inv, ok := gc.backend.(vectorstore.Inventory); ok {
+		if err := gc.collectBackend(ctx, inv, opts, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.IncludeFiles {
+		if err := gc.collectFiles(ctx, opts, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// collectBackend finds backend stores without a vector store and chunks for
+// files that are not attached to their vector store. Both are checked
+// against the live metadata rather than the snapshot taken by Run, so that
+// stores and files created while the pass is running are left alone.
+func (gc *GarbageCollector) collectBackend(ctx context.Context, inv vectorstore.Inventory, opts GCOptions, report *GCReport) error {
+	backendStores, err := inv.ListStores(ctx)
+	if err != nil {
+		return fmt.Errorf("list backend stores: %w", err)
+	}
+	sort.Strings(backendStores)
+
+	for _, vsID := range backendStores {
+		if _, err := gc.vectorStores.GetVectorStore(ctx, vsID); err != nil {
+			report.add(opts, Orphan{Kind: OrphanBackendStore, VectorStoreID: vsID}, func() error {
+				return gc.backend.DeleteStore(ctx, vsID)
+			})
+			continue
+		}
+
+		fileIDs, err := inv.ListFileIDs(ctx, vsID)
+		if err != nil {
+			return fmt.Errorf("list backend files for %s: %w", vsID, err)
+		}
+		sort.Strings(fileIDs)
+		for _, fileID := range fileIDs {
+			if _, err := gc.vectorStores.GetVectorStoreFile(ctx, vsID, fileID); err == nil {
+				continue
+			}
+			report.add(opts, Orphan{Kind: OrphanChunks, VectorStoreID: vsID, FileID: fileID}, func() error {
+				return gc.backend.DeleteFileChunks(ctx, vsID, fileID)
+			})
+		}
+	}
+	return nil
+}
+
+// collectFiles finds "assistants" files older than opts.MinAge that no
+// vector store refers to.
+func (gc *GarbageCollector) collectFiles(ctx context.Context, opts GCOptions, report *GCReport) error {
+	cutoff := time.Now().Add(-opts.MinAge)
+
+	// Collect candidates first so that deletions don't shift the pages.
+	var candidates []*filestore.File
+	after := ""
+	for {
+		page, hasMore, err := gc.files.ListFilesPaginated(ctx, after, "", filePageSize, "asc", "assistants")
+		if err != nil {
+			return fmt.Errorf("list files: %w", err)
+		}
+		for _, f := range page {
+			if f.CreatedAt.Before(cutoff) {
+				candidates = append(candidates, f)
+			}
+		}
+		if !hasMore || len(page) == 0 {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+
+	// Snapshot the references after listing, so that a file attached while
+	// the listing ran is seen as referenced.
+	_, vsFiles, err := gc.vectorStores.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("list vector stores: %w", err)
+	}
+	referenced := make(map[string]bool, len(vsFiles))
+	for _, f := range vsFiles {
+		referenced[f.FileID] = true
+	}
+
+	for _, f := range candidates {
+		if referenced[f.ID] {
+			continue
+		}
+		report.add(opts, Orphan{Kind: OrphanFile, FileID: f.ID}, func() error {
+			return gc.files.DeleteFile(ctx, f.ID)
+		})
+	}
+	return nil
+}
+
+// add records an orphan and, unless this is a dry run, removes it.
+func (r *GCReport) add(opts GCOptions, o Orphan, remove func() error) {
+	if !opts.DryRun {
+		if err := remove(); err != nil {
+			o.Error = err.Error()
+		} else {
+			o.Removed = true
+		}
+	}
+	r.Orphans = append(r.Orphans, o)
+}
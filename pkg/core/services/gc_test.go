@@ -0,0 +1,214 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	fsmemory "github.com/leseb/openresponses-gw/pkg/filestore/memory"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// fakeBackend is an in-memory vectorstore.Backend that also implements
+// vectorstore.Inventory. stores maps vector store ID -> file IDs with chunks.
+type fakeBackend struct {
+	stores map[string]map[string]bool
+}
+
+func (b *fakeBackend) CreateStore(_ context.Context, vsID string, _ int) error {
+	b.stores[vsID] = make(map[string]bool)
+	return nil
+}
+
+func (b *fakeBackend) DeleteStore(_ context.Context, vsID string) error {
+	delete(b.stores, vsID)
+	return nil
+}
+
+func (b *fakeBackend) InsertChunks(_ context.Context, chunks []vectorstore.Chunk) error {
+	for _, c := range chunks {
+		b.stores[c.VectorStoreID][c.FileID] = true
+	}
+	return nil
+}
+
+func (b *fakeBackend) DeleteFileChunks(_ context.Context, vsID, fileID string) error {
+	delete(b.stores[vsID], fileID)
+	return nil
+}
+
+func (b *fakeBackend) Search(context.Context, string, []float32, int, string) ([]vectorstore.SearchResult, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) Close(context.Context) error { return nil }
+
+func (b *fakeBackend) ListStores(context.Context) ([]string, error) {
+	var ids []string
+	for id := range b.stores {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (b *fakeBackend) ListFileIDs(_ context.Context, vsID string) ([]string, error) {
+	var ids []string
+	for id := range b.stores[vsID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newGCFixture builds stores with one orphan of every kind:
+//
+//   - vs_1/file_gone: vector store file whose file was deleted
+//   - vs_1/file_detached: chunks left behind after the file was detached
+//   - vs_deleted: backend store whose vector store was deleted
+//   - file_unused: old "assistants" file not attached anywhere
+//
+// plus live objects (vs_1/file_live, file_recent) that must survive.
+func newGCFixture(t *testing.T) (*GarbageCollector, filestore.FileStore, *memory.VectorStoresStore, *fakeBackend) {
+	t.Helper()
+	ctx := context.Background()
+
+	files := fsmemory.New()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, f := range []*filestore.File{
+		{ID: "file_live", Purpose: "assistants", CreatedAt: old},
+		{ID: "file_unused", Purpose: "assistants", CreatedAt: old},
+		{ID: "file_recent", Purpose: "assistants", CreatedAt: time.Now()},
+		{ID: "file_batch", Purpose: "batch", CreatedAt: old},
+	} {
+		if err := files.CreateFile(ctx, f); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+
+	vectorStores := memory.NewVectorStoresStore()
+	if err := vectorStores.CreateVectorStore(ctx, &memory.VectorStore{ID: "vs_1"}); err != nil {
+		t.Fatalf("CreateVectorStore: %v", err)
+	}
+	for _, fileID := range []string{"file_live", "file_gone"} {
+		if err := vectorStores.AddVectorStoreFile(ctx, &memory.VectorStoreFile{ID: fileID, VectorStoreID: "vs_1", FileID: fileID, Status: "completed"}); err != nil {
+			t.Fatalf("AddVectorStoreFile: %v", err)
+		}
+	}
+
+	backend := &fakeBackend{stores: map[string]map[string]bool{
+		"vs_1":       {"file_live": true, "file_gone": true, "file_detached": true},
+		"vs_deleted": {"file_live": true},
+	}}
+
+	return NewGarbageCollector(files, vectorStores, backend), files, vectorStores, backend
+}
+
+func orphanKeys(report *GCReport) []string {
+	var keys []string
+	for _, o := range report.Orphans {
+		keys = append(keys, o.Kind+":"+o.VectorStoreID+"/"+o.FileID)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestGarbageCollector_DryRun(t *testing.T) {
+	gc, files, vectorStores, backend := newGCFixture(t)
+	ctx := context.Background()
+
+	report, err := gc.Run(ctx, GCOptions{DryRun: true, MinAge: time.Hour, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{
+		"backend_store:vs_deleted/",
+		"chunks:vs_1/file_detached",
+		"file:/file_unused",
+		"vector_store_file:vs_1/file_gone",
+	}
+	got := orphanKeys(report)
+	if len(got) != len(want) {
+		t.Fatalf("orphans = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("orphans[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if report.Removed() != 0 {
+		t.Errorf("dry run removed %d objects", report.Removed())
+	}
+
+	// Nothing was touched
+	if _, err := files.GetFile(ctx, "file_unused"); err != nil {
+		t.Errorf("file_unused deleted in dry run: %v", err)
+	}
+	if _, err := vectorStores.GetVectorStoreFile(ctx, "vs_1", "file_gone"); err != nil {
+		t.Errorf("vs_1/file_gone deleted in dry run: %v", err)
+	}
+	if _, ok := backend.stores["vs_deleted"]; !ok {
+		t.Error("vs_deleted removed in dry run")
+	}
+}
+
+func TestGarbageCollector_Remove(t *testing.T) {
+	gc, files, vectorStores, backend := newGCFixture(t)
+	ctx := context.Background()
+
+	report, err := gc.Run(ctx, GCOptions{MinAge: time.Hour, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Removed() != len(report.Orphans) {
+		t.Errorf("removed %d of %d orphans: %+v", report.Removed(), len(report.Orphans), report.Orphans)
+	}
+
+	if _, err := files.GetFile(ctx, "file_unused"); err == nil {
+		t.Error("file_unused was not deleted")
+	}
+	if _, err := vectorStores.GetVectorStoreFile(ctx, "vs_1", "file_gone"); err == nil {
+		t.Error("vs_1/file_gone was not deleted")
+	}
+	if _, ok := backend.stores["vs_deleted"]; ok {
+		t.Error("vs_deleted was not removed from the backend")
+	}
+	if len(backend.stores["vs_1"]) != 1 || !backend.stores["vs_1"]["file_live"] {
+		t.Errorf("vs_1 chunks = %v, want only file_live", backend.stores["vs_1"])
+	}
+
+	// Live, recent and non-assistants files survive
+	for _, id := range []string{"file_live", "file_recent", "file_batch"} {
+		if _, err := files.GetFile(ctx, id); err != nil {
+			t.Errorf("%s was deleted: %v", id, err)
+		}
+	}
+
+	// A second pass finds nothing
+	report, err = gc.Run(ctx, GCOptions{MinAge: time.Hour, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Orphans) != 0 {
+		t.Errorf("second pass found orphans: %v", orphanKeys(report))
+	}
+}
+
+func TestGarbageCollector_SkipsFilesByDefault(t *testing.T) {
+	gc, _, _, _ := newGCFixture(t)
+
+	report, err := gc.Run(context.Background(), GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, o := range report.Orphans {
+		if o.Kind == OrphanFile {
+			t.Errorf("unexpected file orphan %q without IncludeFiles", o.FileID)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/vectorstore"
+)
+
+// RetentionPolicy maps a file purpose to how long files with that purpose
+// are kept after upload. Purposes not listed never expire.
+type RetentionPolicy map[string]time.Duration
+
+// ExpiresAt returns when f expires under the policy, or false if it never
+// does.
+func (p RetentionPolicy) ExpiresAt(f *filestore.File) (time.Time, bool) {
+	ttl, ok := p[f.Purpose]
+	if !ok || ttl <= 0 {
+		return time.Time{}, false
+	}
+	return f.CreatedAt.Add(ttl), true
+}
+
+// ReapedFile is an expired file removed by the reaper.
+type ReapedFile struct {
+	FileID  string
+	Purpose string
+	// VectorStoreIDs lists the vector stores the file was detached from.
+	VectorStoreIDs []string
+	// Error is set when the file could not be removed; it is retried on
+	// the next run.
+	Error string
+}
+
+// FileReaper deletes files that have outlived their purpose's retention,
+// detaching them from vector stores and removing their chunks first so no
+// vector store refers to a deleted file.
+type FileReaper struct {
+	files        filestore.FileStore
+	vectorStores *memory.VectorStoresStore
+	backend      vectorstore.Backend
+	policy       RetentionPolicy
+}
+
+// NewFileReaper creates a FileReaper. backend may be nil when vector search
+// is disabled.
+func NewFileReaper(files filestore.FileStore, vectorStores *memory.VectorStoresStore, backend vectorstore.Backend, policy RetentionPolicy) *FileReaper {
+	return &FileReaper{
+		files:        files,
+		vectorStores: vectorStores,
+		backend:      backend,
+		policy:       policy,
+	}
+}
+
+// Run removes every file that expired before now and returns what it
+// removed or failed to remove.
+func (r *FileReaper) Run(ctx context.Context, now time.Time) ([]ReapedFile, error) {
+	var expired []*filestore.File
+	for _, purpose := range slices.Sorted(maps.Keys(r.policy)) {
+		files, err := r.expiredFiles(ctx, purpose, now)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, files...)
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	_, vsFiles, err := r.vectorStores.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list vector stores: %w", err)
+	}
+	attached := make(map[string][]string)
+	for _, f := range vsFiles {
+		attached[f.FileID] = append(attached[f.FileID], f.VectorStoreID)
+	}
+
+	reaped := make([]ReapedFile, 0, len(expired))
+	for _, f := range expired {
+		if err := ctx.Err(); err != nil {
+			return reaped, err
+		}
+		rf := ReapedFile{FileID: f.ID, Purpose: f.Purpose}
+		if err := r.remove(ctx, f.ID, attached[f.ID], &rf); err != nil {
+			rf.Error = err.Error()
+		}
+		reaped = append(reaped, rf)
+	}
+	return reaped, nil
+}
+
+// expiredFiles lists the files of purpose that expired before now. Files
+// are listed oldest first, so the listing stops at the first live one.
+func (r *FileReaper) expiredFiles(ctx context.Context, purpose string, now time.Time) ([]*filestore.File, error) {
+	var expired []*filestore.File
+	after := ""
+	for {
+		page, hasMore, err := r.files.ListFilesPaginated(ctx, after, "", filePageSize, "asc", purpose)
+		if err != nil {
+			return nil, fmt.Errorf("list %s files: %w", purpose, err)
+		}
+		for _, f := range page {
+			if expiresAt, ok := r.policy.ExpiresAt(f); !ok || expiresAt.After(now) {
+				return expired, nil
+			}
+			expired = append(expired, f)
+		}
+		if !hasMore || len(page) == 0 {
+			return expired, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// remove detaches a file from its vector stores and deletes it. The file is
+// deleted last, so a failure leaves it in place to be retried.
+func (r *FileReaper) remove(ctx context.Context, fileID string, vectorStoreIDs []string, rf *ReapedFile) error {
+	var err error
+	rf.VectorStoreIDs, err = deleteFile(ctx, r.files, r.vectorStores, r.backend, fileID, vectorStoreIDs)
+	return err
+}
+
+// deleteFile detaches a file from vectorStoreIDs, removing its chunks, then
+// deletes it from the file store. It returns the vector stores the file was
+// detached from, which is all of them unless it fails.
+func deleteFile(ctx context.Context, files filestore.FileStore, vectorStores *memory.VectorStoresStore, backend vectorstore.Backend, fileID string, vectorStoreIDs []string) ([]string, error) {
+	var detached []string
+	for _, vsID := range vectorStoreIDs {
+		if err := vectorStores.DeleteVectorStoreFile(ctx, vsID, fileID); err != nil {
+			return detached, fmt.Errorf("detach from %s: %w", vsID, err)
+		}
+		if backend != nil {
+			if err := backend.DeleteFileChunks(ctx, vsID, fileID); err != nil {
+				return detached, fmt.Errorf("delete chunks in %s: %w", vsID, err)
+			}
+		}
+		detached = append(detached, vsID)
+	}
+	return detached, files.DeleteFile(ctx, fileID)
+}
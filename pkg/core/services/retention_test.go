@@ -0,0 +1,115 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	fsmemory "github.com/leseb/openresponses-gw/pkg/filestore/memory"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+func TestRetentionPolicy_ExpiresAt(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := RetentionPolicy{"batch": 24 * time.Hour, "vision": 0}
+
+	tests := []struct {
+		purpose string
+		want    time.Time
+		wantOK  bool
+	}{
+		{"batch", created.Add(24 * time.Hour), true},
+		{"vision", time.Time{}, false},     // zero never expires
+		{"assistants", time.Time{}, false}, // not in the policy
+	}
+	for _, tt := range tests {
+		got, ok := policy.ExpiresAt(&filestore.File{Purpose: tt.purpose, CreatedAt: created})
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("ExpiresAt(%s) = %v, %v, want %v, %v", tt.purpose, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFileReaper_Run(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	files := fsmemory.New()
+	for _, f := range []*filestore.File{
+		{ID: "file_old_batch", Purpose: "batch", CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "file_attached", Purpose: "batch", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "file_new_batch", Purpose: "batch", CreatedAt: now.Add(-30 * time.Minute)},
+		{ID: "file_assistants", Purpose: "assistants", CreatedAt: now.Add(-3 * time.Hour)},
+	} {
+		if err := files.CreateFile(ctx, f); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+
+	vectorStores := memory.NewVectorStoresStore()
+	if err := vectorStores.CreateVectorStore(ctx, &memory.VectorStore{ID: "vs_1"}); err != nil {
+		t.Fatalf("CreateVectorStore: %v", err)
+	}
+	for _, fileID := range []string{"file_attached", "file_assistants"} {
+		if err := vectorStores.AddVectorStoreFile(ctx, &memory.VectorStoreFile{ID: fileID, VectorStoreID: "vs_1", FileID: fileID, Status: "completed"}); err != nil {
+			t.Fatalf("AddVectorStoreFile: %v", err)
+		}
+	}
+	backend := &fakeBackend{stores: map[string]map[string]bool{
+		"vs_1": {"file_attached": true, "file_assistants": true},
+	}}
+
+	reaper := NewFileReaper(files, vectorStores, backend, RetentionPolicy{"batch": time.Hour})
+	reaped, err := reaper.Run(ctx, now)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(reaped) != 2 {
+		t.Fatalf("reaped = %+v, want 2 files", reaped)
+	}
+	if reaped[0].FileID != "file_old_batch" || len(reaped[0].VectorStoreIDs) != 0 {
+		t.Errorf("reaped[0] = %+v", reaped[0])
+	}
+	if reaped[1].FileID != "file_attached" || len(reaped[1].VectorStoreIDs) != 1 || reaped[1].VectorStoreIDs[0] != "vs_1" {
+		t.Errorf("reaped[1] = %+v", reaped[1])
+	}
+	for _, rf := range reaped {
+		if rf.Error != "" {
+			t.Errorf("%s: %s", rf.FileID, rf.Error)
+		}
+		if _, err := files.GetFile(ctx, rf.FileID); err == nil {
+			t.Errorf("%s was not deleted", rf.FileID)
+		}
+	}
+
+	// The attachment and chunks of the expired file are gone, the others stay
+	if _, err := vectorStores.GetVectorStoreFile(ctx, "vs_1", "file_attached"); err == nil {
+		t.Error("vs_1/file_attached was not detached")
+	}
+	if backend.stores["vs_1"]["file_attached"] {
+		t.Error("chunks of file_attached were not deleted")
+	}
+	vs, _ := vectorStores.GetVectorStore(ctx, "vs_1")
+	if vs.FileCounts.Total != 1 {
+		t.Errorf("vs_1 file count = %d, want 1", vs.FileCounts.Total)
+	}
+	for _, id := range []string{"file_new_batch", "file_assistants"} {
+		if _, err := files.GetFile(ctx, id); err != nil {
+			t.Errorf("%s was deleted: %v", id, err)
+		}
+	}
+
+	// A second pass finds nothing
+	reaped, err = reaper.Run(ctx, now)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(reaped) != 0 {
+		t.Errorf("second pass reaped %+v", reaped)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// SessionRetention sets how long responses and conversations are kept in
+// the session store. Zero keeps them forever. Sessions always expire at
+// their own expires_at.
+type SessionRetention struct {
+	Responses     time.Duration // since the response was created
+	Conversations time.Duration // since the conversation was last updated
+}
+
+// SessionRetentionStats counts the rows deleted by a SessionReaper since
+// the gateway started.
+type SessionRetentionStats struct {
+	Runs      int            // Completed or failed runs
+	Reclaimed map[string]int // Rows deleted per kind (state.ExpiredSessions, ...)
+	LastRun   time.Time      // When the last run finished
+	LastError string         // Error of the last run, if it failed
+}
+
+// SessionReaper deletes expired sessions, responses and conversations from
+// the session store in batches, so each statement holds its locks briefly.
+type SessionReaper struct {
+	store     state.Expirer
+	policy    SessionRetention
+	batchSize int
+
+	mu    sync.Mutex
+	stats SessionRetentionStats
+}
+
+// NewSessionReaper creates a SessionReaper that deletes at most batchSize
+// rows per statement.
+func NewSessionReaper(store state.Expirer, policy SessionRetention, batchSize int) *SessionReaper {
+	return &SessionReaper{
+		store:     store,
+		policy:    policy,
+		batchSize: batchSize,
+		stats:     SessionRetentionStats{Reclaimed: make(map[string]int)},
+	}
+}
+
+// Run deletes everything that expired before now and returns the number of
+// rows deleted per kind. Kinds deleted before an error are still counted.
+func (r *SessionReaper) Run(ctx context.Context, now time.Time) (map[string]int, error) {
+	cutoffs := []struct {
+		kind    string
+		cutoff  time.Time
+		enabled bool
+	}{
+		{state.ExpiredSessions, now, true},
+		{state.ExpiredResponses, now.Add(-r.policy.Responses), r.policy.Responses > 0},
+		{state.ExpiredConversations, now.Add(-r.policy.Conversations), r.policy.Conversations > 0},
+	}
+
+	deleted := make(map[string]int)
+	var err error
+	for _, c := range cutoffs {
+		if !c.enabled {
+			continue
+		}
+		var n int
+		n, err = r.deleteAll(ctx, c.kind, c.cutoff)
+		if n > 0 {
+			deleted[c.kind] = n
+		}
+		if err != nil {
+			err = fmt.Errorf("delete expired %s: %w", c.kind, err)
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Runs++
+	r.stats.LastRun = time.Now()
+	r.stats.LastError = ""
+	if err != nil {
+		r.stats.LastError = err.Error()
+	}
+	for kind, n := range deleted {
+		r.stats.Reclaimed[kind] += n
+	}
+	return deleted, err
+}
+
+// deleteAll deletes batches of kind until one comes back short.
+func (r *SessionReaper) deleteAll(ctx context.Context, kind string, cutoff time.Time) (int, error) {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := r.store.DeleteExpired(ctx, kind, cutoff, r.batchSize)
+		total += n
+		if err != nil || n < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+// Stats returns the rows deleted since the gateway started.
+func (r *SessionReaper) Stats() SessionRetentionStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Reclaimed = maps.Clone(r.stats.Reclaimed)
+	return stats
+}
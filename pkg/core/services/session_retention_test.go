@@ -0,0 +1,103 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// fakeExpirer has a number of expired rows per kind and records the
+// cutoffs it was called with.
+type fakeExpirer struct {
+	expired map[string]int
+	cutoffs map[string]time.Time
+	calls   int
+	failOn  string
+}
+
+func (f *fakeExpirer) DeleteExpired(_ context.Context, kind string, cutoff time.Time, limit int) (int, error) {
+	f.calls++
+	f.cutoffs[kind] = cutoff
+	if kind == f.failOn {
+		return 0, errors.New("boom")
+	}
+	n := min(f.expired[kind], limit)
+	f.expired[kind] -= n
+	return n, nil
+}
+
+func TestSessionReaper_Run(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		policy    SessionRetention
+		expired   map[string]int
+		failOn    string
+		want      map[string]int
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "sessions only",
+			expired:   map[string]int{state.ExpiredSessions: 5, state.ExpiredResponses: 9},
+			want:      map[string]int{state.ExpiredSessions: 5},
+			wantCalls: 3, // 2, 2, 1
+		},
+		{
+			name:      "full batches",
+			policy:    SessionRetention{Responses: time.Hour, Conversations: 24 * time.Hour},
+			expired:   map[string]int{state.ExpiredResponses: 4, state.ExpiredConversations: 1},
+			want:      map[string]int{state.ExpiredResponses: 4, state.ExpiredConversations: 1},
+			wantCalls: 5, // sessions 0, responses 2, 2, 0, conversations 1
+		},
+		{
+			name:      "error stops the run",
+			policy:    SessionRetention{Responses: time.Hour, Conversations: time.Hour},
+			expired:   map[string]int{state.ExpiredSessions: 3},
+			failOn:    state.ExpiredResponses,
+			want:      map[string]int{state.ExpiredSessions: 3},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeExpirer{expired: tt.expired, cutoffs: make(map[string]time.Time), failOn: tt.failOn}
+			reaper := NewSessionReaper(store, tt.policy, 2)
+
+			got, err := reaper.Run(context.Background(), now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run = %v, want %v", got, tt.want)
+			}
+			if store.calls != tt.wantCalls {
+				t.Errorf("DeleteExpired called %d times, want %d", store.calls, tt.wantCalls)
+			}
+			if c := store.cutoffs[state.ExpiredSessions]; !c.Equal(now) {
+				t.Errorf("sessions cutoff = %v, want %v", c, now)
+			}
+			if tt.policy.Responses > 0 {
+				if c := store.cutoffs[state.ExpiredResponses]; !c.Equal(now.Add(-tt.policy.Responses)) {
+					t.Errorf("responses cutoff = %v", c)
+				}
+			} else if _, ok := store.cutoffs[state.ExpiredResponses]; ok {
+				t.Error("responses expired without a TTL")
+			}
+
+			stats := reaper.Stats()
+			if stats.Runs != 1 || !reflect.DeepEqual(stats.Reclaimed, tt.want) || (stats.LastError != "") != tt.wantErr {
+				t.Errorf("Stats = %+v", stats)
+			}
+		})
+	}
+}
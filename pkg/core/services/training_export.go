@@ -0,0 +1,148 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// Training example formats.
+const (
+	// TrainingFormatChat is the chat format of fine-tuning APIs: the
+	// messages of the response, from the instructions to the final answer.
+	TrainingFormatChat = "chat"
+	// TrainingFormatPromptCompletion pairs the last user message of a
+	// response with its final answer.
+	TrainingFormatPromptCompletion = "prompt_completion"
+)
+
+// trainingExportPageSize is the number of responses read at once.
+const trainingExportPageSize = 100
+
+// ChatExample is a training example in chat format.
+type ChatExample struct {
+	Messages []ChatExampleMessage `json:"messages"`
+}
+
+// ChatExampleMessage is a message of a ChatExample.
+type ChatExampleMessage struct {
+	Role       string                `json:"role"` // "system", "user", "assistant" or "tool"
+	Content    string                `json:"content,omitempty"`
+	ToolCalls  []ChatExampleToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string                `json:"tool_call_id,omitempty"`
+}
+
+// ChatExampleToolCall is a function call made by the assistant.
+type ChatExampleToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // "function"
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// PromptCompletionExample is a training example in prompt/completion
+// format.
+type PromptCompletionExample struct {
+	Prompt     string `json:"prompt"`
+	Completion string `json:"completion"`
+}
+
+// ExportTrainingData derives a training example in format from each
+// completed response matching filter, oldest first, and passes it to emit.
+// Responses that do not end with an assistant answer are skipped. It
+// returns the number of examples emitted, and stops at the first error of
+// emit.
+func ExportTrainingData(ctx context.Context, store state.SessionStore, filter state.ResponseFilter, format string, emit func(example interface{}) error) (int, error) {
+	if format != TrainingFormatChat && format != TrainingFormatPromptCompletion {
+		return 0, fmt.Errorf("unknown training format %q", format)
+	}
+	filter.Status = "completed"
+
+	emitted := 0
+	after := ""
+	for {
+		page, hasMore, err := store.ListResponsesPaginated(ctx, after, "", trainingExportPageSize, "asc", filter)
+		if err != nil {
+			return emitted, fmt.Errorf("list responses: %w", err)
+		}
+		for _, listed := range page {
+			// Listings may hold only the messages each response added
+			resp, err := store.GetResponse(ctx, listed.ID)
+			if err != nil {
+				return emitted, fmt.Errorf("get response %s: %w", listed.ID, err)
+			}
+			var example interface{}
+			if format == TrainingFormatChat {
+				example = chatExample(resp.Messages)
+			} else {
+				example = promptCompletionExample(resp.Messages)
+			}
+			if example == nil {
+				continue
+			}
+			if err := emit(example); err != nil {
+				return emitted, err
+			}
+			emitted++
+		}
+		if !hasMore || len(page) == 0 {
+			return emitted, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// chatExample converts the history of a response, or returns nil if it
+// does not end with an assistant answer. Reasoning is left out, and
+// developer messages become system messages.
+func chatExample(history []state.ConversationMessage) interface{} {
+	var messages []ChatExampleMessage
+	for _, msg := range history {
+		if msg.Content == "" && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		out := ChatExampleMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		if out.Role == "developer" {
+			out.Role = "system"
+		}
+		for _, tc := range msg.ToolCalls {
+			call := ChatExampleToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			out.ToolCalls = append(out.ToolCalls, call)
+		}
+		messages = append(messages, out)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if last := messages[len(messages)-1]; last.Role != "assistant" || last.Content == "" {
+		return nil
+	}
+	return ChatExample{Messages: messages}
+}
+
+// promptCompletionExample pairs the last user message of a history with
+// the answer that ends it, or returns nil if there is no such pair.
+func promptCompletionExample(history []state.ConversationMessage) interface{} {
+	var completion string
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		switch {
+		case completion == "" && msg.Role == "assistant" && msg.Content != "":
+			completion = msg.Content
+		case completion == "" && msg.Content != "":
+			// The history does not end with an answer
+			return nil
+		case completion != "" && msg.Role == "user" && msg.Content != "":
+			return PromptCompletionExample{Prompt: msg.Content, Completion: completion}
+		}
+	}
+	return nil
+}
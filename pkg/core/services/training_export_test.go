@@ -0,0 +1,118 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestExportTrainingData(t *testing.T) {
+	ctx := context.Background()
+	store := newExportStore(t)
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	save := func(id, model, status string, created time.Time, messages ...state.ConversationMessage) {
+		t.Helper()
+		if err := store.SaveResponse(ctx, &state.Response{
+			ID: id, Status: status, CreatedAt: created, Messages: messages,
+			Request: map[string]interface{}{"model": model, "metadata": map[string]interface{}{"split": "train"}},
+		}); err != nil {
+			t.Fatalf("SaveResponse(%s): %v", id, err)
+		}
+	}
+	save("resp_1", "m1", "completed", created,
+		state.ConversationMessage{Role: "developer", Content: "be brief"},
+		state.ConversationMessage{Role: "user", Content: "weather in Paris?"},
+		state.ConversationMessage{Role: "assistant", Reasoning: &state.ReasoningRecord{ID: "rs_1", Text: "thinking"}},
+		state.ConversationMessage{Role: "assistant", ToolCalls: []state.ToolCallRecord{{ID: "call_1", Type: "function", Name: "weather", Arguments: `{"city":"Paris"}`}}},
+		state.ConversationMessage{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+		state.ConversationMessage{Role: "assistant", Content: "Sunny."},
+	)
+	save("resp_2", "m2", "completed", created.Add(time.Minute),
+		state.ConversationMessage{Role: "user", Content: "hi"},
+		state.ConversationMessage{Role: "assistant", Content: "hello"},
+	)
+	// Failed responses and those without an answer are skipped
+	save("resp_3", "m1", "failed", created.Add(2*time.Minute),
+		state.ConversationMessage{Role: "user", Content: "boom"},
+	)
+	save("resp_4", "m1", "completed", created.Add(3*time.Minute),
+		state.ConversationMessage{Role: "user", Content: "call it"},
+		state.ConversationMessage{Role: "assistant", ToolCalls: []state.ToolCallRecord{{ID: "call_2", Name: "f"}}},
+	)
+
+	export := func(filter state.ResponseFilter, format string) []string {
+		t.Helper()
+		var lines []string
+		n, err := ExportTrainingData(ctx, store, filter, format, func(example interface{}) error {
+			data, err := json.Marshal(example)
+			lines = append(lines, string(data))
+			return err
+		})
+		if err != nil || n != len(lines) {
+			t.Fatalf("ExportTrainingData = %d, %v", n, err)
+		}
+		return lines
+	}
+
+	lines := export(state.ResponseFilter{}, TrainingFormatChat)
+	want := []string{
+		`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"weather in Paris?"},` +
+			`{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Paris\"}"}}]},` +
+			`{"role":"tool","content":"sunny","tool_call_id":"call_1"},{"role":"assistant","content":"Sunny."}]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("chat export =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	lines = export(state.ResponseFilter{Model: "m1"}, TrainingFormatPromptCompletion)
+	if len(lines) != 1 || lines[0] != `{"prompt":"weather in Paris?","completion":"Sunny."}` {
+		t.Errorf("prompt_completion export = %v", lines)
+	}
+
+	lines = export(state.ResponseFilter{CreatedAfter: created, Metadata: map[string]string{"split": "train"}}, TrainingFormatChat)
+	if len(lines) != 1 || !strings.Contains(lines[0], `"hello"`) {
+		t.Errorf("export after %v = %v", created, lines)
+	}
+
+	if _, err := ExportTrainingData(ctx, store, state.ResponseFilter{}, "csv", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestExportTrainingData_Pages(t *testing.T) {
+	ctx := context.Background()
+	store := newExportStore(t)
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	total := trainingExportPageSize + 5
+	for i := range total {
+		if err := store.SaveResponse(ctx, &state.Response{
+			ID: fmt.Sprintf("resp_%03d", i), Status: "completed", CreatedAt: created.Add(time.Duration(i) * time.Second),
+			Request:  map[string]interface{}{"model": "m"},
+			Messages: []state.ConversationMessage{{Role: "user", Content: "q"}, {Role: "assistant", Content: fmt.Sprint(i)}},
+		}); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+
+	next := 0
+	n, err := ExportTrainingData(ctx, store, state.ResponseFilter{}, TrainingFormatPromptCompletion, func(example interface{}) error {
+		if got := example.(PromptCompletionExample).Completion; got != fmt.Sprint(next) {
+			return fmt.Errorf("example %d has completion %s", next, got)
+		}
+		next++
+		return nil
+	})
+	if err != nil || n != total {
+		t.Errorf("ExportTrainingData = %d, %v, want %d", n, err, total)
+	}
+}
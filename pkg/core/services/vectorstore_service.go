@@ -5,7 +5,9 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/leseb/openresponses-gw/pkg/core/api"
 	"github.com/leseb/openresponses-gw/pkg/filestore"
@@ -20,29 +22,149 @@ import (
 // All methods are nil-receiver safe and return nil on a nil receiver.
 type VectorStoreService struct {
 	files    filestore.FileStore
-	embedder api.EmbeddingClient
+	embedder Embedder // configured, named DefaultEmbedder
 	backend  vectorstore.Backend
+
+	providers   map[string]Embedder // named embedders vector stores can select
+	newEmbedder EmbedderFactory     // nil: no models other than the configured ones
+
+	mu     sync.RWMutex
+	stores map[string]Embedder // vector store ID -> its embedder, when not the default
+}
+
+// DefaultEmbedder is the name of the configured embedder.
+const DefaultEmbedder = "default"
+
+// Embedder is an embedding client a vector store is indexed with. Queries
+// of a store must be embedded by the same model, into vectors of the same
+// dimensions.
+type Embedder struct {
+	Name       string
+	Model      string
+	Dimensions int // 0 when unknown
+	Client     api.EmbeddingClient
 }
 
+// EmbedderFactory creates an embedding client for a model of the configured
+// endpoint, for vector stores re-indexed with another model.
+type EmbedderFactory func(model string, dimensions int) api.EmbeddingClient
+
+var (
+	// ErrUnknownEmbedder is returned for an embedder name that is not
+	// configured.
+	ErrUnknownEmbedder = errors.New("unknown embedder")
+
+	// ErrEmbeddingModelUnsupported is returned by NewEmbedder when no
+	// embedder factory is set.
+	ErrEmbeddingModelUnsupported = errors.New("re-indexing with another embedding model is not supported")
+
+	// ErrDimensionMismatch is returned when an embedder's vectors do not
+	// have the dimensions of the vector store, which must then be
+	// re-indexed.
+	ErrDimensionMismatch = errors.New("embedding dimensions do not match the vector store")
+)
+
 // NewVectorStoreService creates a VectorStoreService.
-// Returns nil if either embedder or backend is nil (feature disabled).
-func NewVectorStoreService(files filestore.FileStore, embedder api.EmbeddingClient, backend vectorstore.Backend) *VectorStoreService {
-	if embedder == nil || backend == nil {
+// Returns nil if either embedder.Client or backend is nil (feature disabled).
+func NewVectorStoreService(files filestore.FileStore, embedder Embedder, backend vectorstore.Backend) *VectorStoreService {
+	if embedder.Client == nil || backend == nil {
 		return nil
 	}
+	embedder.Name = DefaultEmbedder
 	return &VectorStoreService{
-		files:    files,
-		embedder: embedder,
-		backend:  backend,
+		files:     files,
+		embedder:  embedder,
+		backend:   backend,
+		providers: make(map[string]Embedder),
+		stores:    make(map[string]Embedder),
+	}
+}
+
+// AddEmbedder makes a named embedder available to vector stores.
+func (s *VectorStoreService) AddEmbedder(e Embedder) {
+	if s == nil {
+		return
+	}
+	s.providers[e.Name] = e
+}
+
+// SetEmbedderFactory allows re-indexing vector stores with other models of
+// the configured endpoint, created by newEmbedder.
+func (s *VectorStoreService) SetEmbedderFactory(newEmbedder EmbedderFactory) {
+	if s == nil {
+		return
 	}
+	s.newEmbedder = newEmbedder
 }
 
-// CreateStore provisions the backend storage for a vector store.
-func (s *VectorStoreService) CreateStore(ctx context.Context, vectorStoreID string, dimensions int) error {
+// Embedder returns the embedder with the given name, or the configured one
+// when name is empty.
+func (s *VectorStoreService) Embedder(name string) (Embedder, error) {
+	if s == nil {
+		return Embedder{}, nil
+	}
+	if name == "" || name == DefaultEmbedder {
+		return s.embedder, nil
+	}
+	e, ok := s.providers[name]
+	if !ok {
+		return Embedder{}, fmt.Errorf("%w %q", ErrUnknownEmbedder, name)
+	}
+	return e, nil
+}
+
+// NewEmbedder returns an embedder for model of the configured endpoint,
+// with the given output dimensions, or the configured embedder when
+// neither is set.
+func (s *VectorStoreService) NewEmbedder(model string, dimensions int) (Embedder, error) {
+	if s == nil {
+		return Embedder{}, nil
+	}
+	if model == "" && dimensions == 0 {
+		return s.embedder, nil
+	}
+	if s.newEmbedder == nil {
+		return Embedder{}, ErrEmbeddingModelUnsupported
+	}
+	if model == "" {
+		model = s.embedder.Model
+	}
+	return Embedder{
+		Name:       DefaultEmbedder,
+		Model:      model,
+		Dimensions: dimensions,
+		Client:     s.newEmbedder(model, dimensions),
+	}, nil
+}
+
+// embedderFor returns the embedder of a vector store.
+func (s *VectorStoreService) embedderFor(vectorStoreID string) Embedder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e, ok := s.stores[vectorStoreID]; ok {
+		return e
+	}
+	return s.embedder
+}
+
+func (s *VectorStoreService) bind(vectorStoreID string, e Embedder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e == s.embedder {
+		delete(s.stores, vectorStoreID)
+	} else {
+		s.stores[vectorStoreID] = e
+	}
+}
+
+// CreateStore provisions the backend storage for a vector store, whose
+// files and queries are embedded by embedder from then on.
+func (s *VectorStoreService) CreateStore(ctx context.Context, vectorStoreID string, embedder Embedder) error {
 	if s == nil {
 		return nil
 	}
-	return s.backend.CreateStore(ctx, vectorStoreID, dimensions)
+	s.bind(vectorStoreID, embedder)
+	return s.backend.CreateStore(ctx, vectorStoreID, embedder.Dimensions)
 }
 
 // DeleteStore removes the backend storage for a vector store.
@@ -50,6 +172,9 @@ func (s *VectorStoreService) DeleteStore(ctx context.Context, vectorStoreID stri
 	if s == nil {
 		return nil
 	}
+	s.mu.Lock()
+	delete(s.stores, vectorStoreID)
+	s.mu.Unlock()
 	return s.backend.DeleteStore(ctx, vectorStoreID)
 }
 
@@ -59,6 +184,10 @@ func (s *VectorStoreService) IngestFile(ctx context.Context, vectorStoreID, file
 	if s == nil {
 		return nil
 	}
+	return s.ingest(ctx, s.embedderFor(vectorStoreID), vectorStoreID, fileID, chunkSize, overlap)
+}
+
+func (s *VectorStoreService) ingest(ctx context.Context, embedder Embedder, vectorStoreID, fileID string, chunkSize, overlap int) error {
 
 	// Read file content
 	content, err := s.files.GetFileContent(ctx, fileID)
@@ -84,13 +213,17 @@ func (s *VectorStoreService) IngestFile(ctx context.Context, vectorStoreID, file
 	}
 
 	// Chunk the text
-	chunks := vectorstore.ChunkText(text, chunkSize, overlap)
+	chunks := vectorstore.SplitText(text, chunkSize, overlap)
 	if len(chunks) == 0 {
 		return nil
 	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
 
 	// Embed all chunks in a single batch
-	vectors, err := s.embedder.Embed(ctx, chunks)
+	vectors, err := embedder.Client.Embed(ctx, texts)
 	if err != nil {
 		return fmt.Errorf("embed chunks for file %s: %w", fileID, err)
 	}
@@ -98,15 +231,19 @@ func (s *VectorStoreService) IngestFile(ctx context.Context, vectorStoreID, file
 	if len(vectors) != len(chunks) {
 		return fmt.Errorf("embedding count mismatch: got %d, expected %d", len(vectors), len(chunks))
 	}
+	if err := checkDimensions(embedder, vectors[0]); err != nil {
+		return err
+	}
 
 	// Build chunk objects
 	vsChunks := make([]vectorstore.Chunk, len(chunks))
-	for i, text := range chunks {
+	for i, c := range chunks {
 		vsChunks[i] = vectorstore.Chunk{
 			ChunkID:       fmt.Sprintf("%s_chunk_%d", fileID, i),
 			FileID:        fileID,
 			VectorStoreID: vectorStoreID,
-			Content:       text,
+			Content:       c.Text,
+			Offset:        c.Offset,
 			Vector:        vectors[i],
 		}
 	}
@@ -119,6 +256,49 @@ func (s *VectorStoreService) IngestFile(ctx context.Context, vectorStoreID, file
 	return nil
 }
 
+// ReindexFile is a file to re-index, with the chunking it was ingested with.
+type ReindexFile struct {
+	FileID    string
+	ChunkSize int
+	Overlap   int
+}
+
+// Reindex rebuilds the backend storage of a vector store with embedder,
+// which the store uses from then on, and re-ingests files into it. done is
+// called after each file with its ingestion error, if any. It returns the
+// embedder with the dimensions of its vectors. Reindex fails only if the
+// storage cannot be rebuilt; the store is then left without its chunks.
+func (s *VectorStoreService) Reindex(ctx context.Context, vectorStoreID string, embedder Embedder, files []ReindexFile, done func(fileID string, err error)) (Embedder, error) {
+	if s == nil {
+		return embedder, nil
+	}
+
+	// Vectors of another model may not even have the same dimensions, so
+	// the store is recreated for the embedder's.
+	probe, err := embedder.Client.Embed(ctx, []string{"dimensions"})
+	if err != nil {
+		return embedder, fmt.Errorf("embed probe: %w", err)
+	}
+	if len(probe) == 0 || len(probe[0]) == 0 {
+		return embedder, errors.New("embedder returned no vector")
+	}
+	if err := checkDimensions(embedder, probe[0]); err != nil {
+		return embedder, err
+	}
+	embedder.Dimensions = len(probe[0])
+	if err := s.backend.DeleteStore(ctx, vectorStoreID); err != nil {
+		return embedder, fmt.Errorf("delete store: %w", err)
+	}
+	if err := s.CreateStore(ctx, vectorStoreID, embedder); err != nil {
+		return embedder, fmt.Errorf("create store: %w", err)
+	}
+
+	for _, f := range files {
+		done(f.FileID, s.ingest(ctx, embedder, vectorStoreID, f.FileID, f.ChunkSize, f.Overlap))
+	}
+	return embedder, nil
+}
+
 // RemoveFile removes all chunks for a file from the vector store backend.
 func (s *VectorStoreService) RemoveFile(ctx context.Context, vectorStoreID, fileID string) error {
 	if s == nil {
@@ -127,6 +307,23 @@ func (s *VectorStoreService) RemoveFile(ctx context.Context, vectorStoreID, file
 	return s.backend.DeleteFileChunks(ctx, vectorStoreID, fileID)
 }
 
+// ErrChunksUnsupported is returned by ListFileChunks when the backend
+// cannot list the chunks it stores.
+var ErrChunksUnsupported = errors.New("the vector store backend cannot list chunks")
+
+// ListFileChunks returns the chunks stored for a file in a vector store,
+// in file order.
+func (s *VectorStoreService) ListFileChunks(ctx context.Context, vectorStoreID, fileID string) ([]vectorstore.StoredChunk, error) {
+	if s == nil {
+		return nil, nil
+	}
+	lister, ok := s.backend.(vectorstore.ChunkLister)
+	if !ok {
+		return nil, ErrChunksUnsupported
+	}
+	return lister.ListFileChunks(ctx, vectorStoreID, fileID)
+}
+
 // Search embeds the query and performs vector similarity search.
 // filterExpr is an optional backend-specific filter expression (e.g. Milvus boolean expression).
 func (s *VectorStoreService) Search(ctx context.Context, vectorStoreID, query string, topK int, filterExpr string) ([]vectorstore.SearchResult, error) {
@@ -139,14 +336,28 @@ func (s *VectorStoreService) Search(ctx context.Context, vectorStoreID, query st
 	}
 
 	// Embed the query
-	vectors, err := s.embedder.Embed(ctx, []string{query})
+	embedder := s.embedderFor(vectorStoreID)
+	vectors, err := embedder.Client.Embed(ctx, []string{query})
 	if err != nil {
 		return nil, fmt.Errorf("embed query: %w", err)
 	}
 	if len(vectors) == 0 {
 		return nil, nil
 	}
+	if err := checkDimensions(embedder, vectors[0]); err != nil {
+		return nil, err
+	}
 
 	// Search
 	return s.backend.Search(ctx, vectorStoreID, vectors[0], topK, filterExpr)
 }
+
+// checkDimensions reports vectors of embedder that do not have its
+// dimensions, which the vector store was created with.
+func checkDimensions(embedder Embedder, vector []float32) error {
+	if embedder.Dimensions > 0 && len(vector) != embedder.Dimensions {
+		return fmt.Errorf("%w: embedder %q (%s) returned %d dimensions, want %d",
+			ErrDimensionMismatch, embedder.Name, embedder.Model, len(vector), embedder.Dimensions)
+	}
+	return nil
+}
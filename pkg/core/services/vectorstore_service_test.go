@@ -0,0 +1,178 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/api"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
+	fsmemory "github.com/leseb/openresponses-gw/pkg/filestore/memory"
+)
+
+// fakeEmbedder returns vectors of a fixed size and counts its calls.
+type fakeEmbedder struct {
+	dimensions int
+	calls      int
+}
+
+func (e *fakeEmbedder) Embed(_ context.Context, inputs []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(inputs))
+	for i := range inputs {
+		vectors[i] = make([]float32, e.dimensions)
+	}
+	return vectors, nil
+}
+
+// dimsBackend records the dimensions stores are created with.
+type dimsBackend struct {
+	fakeBackend
+	dimensions map[string]int
+}
+
+func (b *dimsBackend) CreateStore(ctx context.Context, vsID string, dimensions int) error {
+	b.dimensions[vsID] = dimensions
+	return b.fakeBackend.CreateStore(ctx, vsID, dimensions)
+}
+
+func TestVectorStoreService_Reindex(t *testing.T) {
+	ctx := context.Background()
+	files := fsmemory.New()
+	for _, id := range []string{"file_a", "file_b"} {
+		if err := files.CreateFile(ctx, &filestore.File{ID: id, Filename: id + ".txt", Content: []byte("some text of " + id)}); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+
+	configured := &fakeEmbedder{dimensions: 4}
+	backend := &dimsBackend{
+		fakeBackend: fakeBackend{stores: map[string]map[string]bool{"vs_1": {"file_a": true}}},
+		dimensions:  make(map[string]int),
+	}
+	svc := NewVectorStoreService(files, Embedder{Model: "configured-model", Dimensions: 4, Client: configured}, backend)
+
+	if _, err := svc.NewEmbedder("other-model", 0); !errors.Is(err, ErrEmbeddingModelUnsupported) {
+		t.Fatalf("NewEmbedder without factory: got %v, want ErrEmbeddingModelUnsupported", err)
+	}
+
+	other := &fakeEmbedder{dimensions: 8}
+	svc.SetEmbedderFactory(func(model string, dimensions int) api.EmbeddingClient {
+		if model != "other-model" {
+			t.Errorf("factory called with model %q", model)
+		}
+		return other
+	})
+	embedder, err := svc.NewEmbedder("other-model", 0)
+	if err != nil || embedder.Model != "other-model" {
+		t.Fatalf("NewEmbedder = %+v, %v", embedder, err)
+	}
+
+	done := map[string]error{}
+	bound, err := svc.Reindex(ctx, "vs_1", embedder, []ReindexFile{
+		{FileID: "file_a", ChunkSize: 100},
+		{FileID: "file_b", ChunkSize: 100},
+		{FileID: "file_missing", ChunkSize: 100},
+	}, func(fileID string, err error) { done[fileID] = err })
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+
+	if got := backend.dimensions["vs_1"]; got != 8 || bound.Dimensions != 8 {
+		t.Errorf("store recreated with %d dimensions, bound with %d, want 8", got, bound.Dimensions)
+	}
+	if !backend.stores["vs_1"]["file_a"] || !backend.stores["vs_1"]["file_b"] {
+		t.Errorf("files not re-ingested: %v", backend.stores["vs_1"])
+	}
+	if done["file_a"] != nil || done["file_b"] != nil || done["file_missing"] == nil {
+		t.Errorf("unexpected per-file results: %v", done)
+	}
+	if configured.calls != 0 {
+		t.Errorf("configured embedder called %d times during re-index", configured.calls)
+	}
+
+	// Searches of the store now embed queries with the new model
+	otherCalls := other.calls
+	if _, err := svc.Search(ctx, "vs_1", "query", 5, ""); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if other.calls != otherCalls+1 || configured.calls != 0 {
+		t.Errorf("search used the wrong embedder: other %d -> %d, configured %d", otherCalls, other.calls, configured.calls)
+	}
+
+	// Re-indexing with the configured embedder switches back
+	defaultEmbedder, err := svc.Embedder("")
+	if err != nil {
+		t.Fatalf("Embedder: %v", err)
+	}
+	if _, err := svc.Reindex(ctx, "vs_1", defaultEmbedder, nil, func(string, error) {}); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if got := backend.dimensions["vs_1"]; got != 4 {
+		t.Errorf("store recreated with %d dimensions, want 4", got)
+	}
+	if _, err := svc.Search(ctx, "vs_1", "query", 5, ""); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if configured.calls != 2 {
+		t.Errorf("configured embedder called %d times, want 2 (probe and search)", configured.calls)
+	}
+}
+
+func TestVectorStoreService_Embedders(t *testing.T) {
+	ctx := context.Background()
+	configured := &fakeEmbedder{dimensions: 4}
+	teamA := &fakeEmbedder{dimensions: 6}
+	backend := &dimsBackend{
+		fakeBackend: fakeBackend{stores: map[string]map[string]bool{}},
+		dimensions:  make(map[string]int),
+	}
+	svc := NewVectorStoreService(fsmemory.New(), Embedder{Model: "configured-model", Dimensions: 4, Client: configured}, backend)
+	svc.AddEmbedder(Embedder{Name: "team-a", Model: "team-a-model", Dimensions: 6, Client: teamA})
+
+	if _, err := svc.Embedder("team-b"); !errors.Is(err, ErrUnknownEmbedder) {
+		t.Fatalf("Embedder(team-b): got %v, want ErrUnknownEmbedder", err)
+	}
+	def, err := svc.Embedder(DefaultEmbedder)
+	if err != nil || def.Name != DefaultEmbedder || def.Model != "configured-model" {
+		t.Fatalf("Embedder(default) = %+v, %v", def, err)
+	}
+	a, err := svc.Embedder("team-a")
+	if err != nil {
+		t.Fatalf("Embedder(team-a): %v", err)
+	}
+
+	if err := svc.CreateStore(ctx, "vs_a", a); err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	if err := svc.CreateStore(ctx, "vs_default", def); err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	if backend.dimensions["vs_a"] != 6 || backend.dimensions["vs_default"] != 4 {
+		t.Errorf("stores created with dimensions %v", backend.dimensions)
+	}
+
+	if _, err := svc.Search(ctx, "vs_a", "query", 5, ""); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if teamA.calls != 1 || configured.calls != 0 {
+		t.Errorf("vs_a search embedded by team-a %d times, default %d times", teamA.calls, configured.calls)
+	}
+
+	// A model that no longer returns the store's dimensions is caught
+	// before the backend is searched
+	teamA.dimensions = 7
+	if _, err := svc.Search(ctx, "vs_a", "query", 5, ""); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Search with changed dimensions: got %v, want ErrDimensionMismatch", err)
+	}
+
+	if err := svc.DeleteStore(ctx, "vs_a"); err != nil {
+		t.Fatalf("DeleteStore: %v", err)
+	}
+	if _, err := svc.Search(ctx, "vs_a", "query", 5, ""); err != nil || configured.calls != 1 {
+		t.Errorf("deleted store not unbound: %v, default embedder calls %d", err, configured.calls)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// APIKeyFingerprint identifies an API key without revealing it: "key_"
+// followed by the start of its SHA-256 hash. It returns "" for an empty key.
+func APIKeyFingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key_" + hex.EncodeToString(sum[:8])
+}
+
+type apiKeyKey struct{}
+
+// WithAPIKey returns a context carrying the fingerprint of the API key of
+// the request, which is stored with the responses it creates.
+func WithAPIKey(ctx context.Context, fingerprint string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, fingerprint)
+}
+
+// APIKeyFromContext returns the fingerprint set by WithAPIKey, or "".
+func APIKeyFromContext(ctx context.Context) string {
+	fingerprint, _ := ctx.Value(apiKeyKey{}).(string)
+	return fingerprint
+}
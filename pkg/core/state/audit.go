@@ -0,0 +1,57 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// AuditLog stores a record of the mutating API operations. Session stores
+// that implement it can hold the audit log.
+type AuditLog interface {
+	AppendAuditEvent(ctx context.Context, event *AuditEvent) error
+
+	// ListAuditEvents returns events newest first. after is the ID of the
+	// last event of the previous page.
+	ListAuditEvents(ctx context.Context, filter AuditFilter, after string, limit int) ([]*AuditEvent, bool, error)
+}
+
+// AuditEvent records who performed an operation, on what, and when.
+type AuditEvent struct {
+	ID           string
+	CreatedAt    time.Time
+	Actor        string // fingerprint of the API key, empty when none was sent
+	Tenant       string
+	RemoteAddr   string
+	Method       string
+	Path         string
+	Action       string // "create", "update" or "delete"
+	ResourceType string // "response", "file", "vector_store", ...
+	ResourceID   string
+	Status       int // HTTP status of the operation
+}
+
+// AuditFilter narrows ListAuditEvents. Zero-valued fields match every
+// event.
+type AuditFilter struct {
+	Actor         string
+	Tenant        string
+	Action        string
+	ResourceType  string
+	ResourceID    string
+	CreatedAfter  time.Time // exclusive
+	CreatedBefore time.Time // exclusive
+}
+
+// Match reports whether event passes the filter.
+func (f AuditFilter) Match(event *AuditEvent) bool {
+	return (f.Actor == "" || event.Actor == f.Actor) &&
+		(f.Tenant == "" || event.Tenant == f.Tenant) &&
+		(f.Action == "" || event.Action == f.Action) &&
+		(f.ResourceType == "" || event.ResourceType == f.ResourceType) &&
+		(f.ResourceID == "" || event.ResourceID == f.ResourceID) &&
+		(f.CreatedAfter.IsZero() || event.CreatedAt.After(f.CreatedAfter)) &&
+		(f.CreatedBefore.IsZero() || event.CreatedAt.Before(f.CreatedBefore))
+}
@@ -0,0 +1,29 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrConversationLocked is returned by LockConversation while another
+// request holds the conversation.
+var ErrConversationLocked = errors.New("another request is in progress on this conversation")
+
+// ConversationLocker is implemented by session stores that can hold a
+// conversation for the duration of a turn, so that concurrent requests on
+// the same conversation cannot interleave their history. Locks are leases:
+// one whose holder never unlocks it, such as a crashed replica, lapses
+// after its TTL.
+type ConversationLocker interface {
+	// LockConversation holds the conversation for holder until it is
+	// unlocked or ttl elapses. It returns ErrConversationLocked if another
+	// holder has an unexpired lock.
+	LockConversation(ctx context.Context, conversationID, holder string, ttl time.Duration) error
+
+	// UnlockConversation releases the lock of holder, if it still has it.
+	UnlockConversation(ctx context.Context, conversationID, holder string) error
+}
@@ -0,0 +1,17 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import "context"
+
+// ConversationMetadataAdder is implemented by session stores that can add
+// a metadata key to a conversation without rewriting its items, so that
+// background updates, such as generated titles, cannot race with the turns
+// appended to it.
+type ConversationMetadataAdder interface {
+	// AddConversationMetadata sets the key of the conversation's metadata
+	// to value unless the key is already set. It reports whether it was
+	// added.
+	AddConversationMetadata(ctx context.Context, conversationID, key, value string) (bool, error)
+}
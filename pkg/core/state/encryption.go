@@ -0,0 +1,17 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import "context"
+
+// PayloadEncrypter is implemented by session stores that can encrypt the
+// payloads they hold (requests, outputs, message histories, conversation
+// items and cached responses) at rest.
+type PayloadEncrypter interface {
+	// ReencryptPayloads rewrites every payload that is stored in plain JSON
+	// or sealed with a previous key so that it is sealed with the current
+	// key, and returns the number of rows rewritten. It is used after a key
+	// rotation, or after turning encryption on for an existing store.
+	ReencryptPayloads(ctx context.Context) (int, error)
+}
@@ -0,0 +1,43 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import "slices"
+
+// SplitHistory returns the messages of full that follow base, and whether
+// base is a prefix of full. Stores use it to keep only the messages added by
+// a turn instead of the whole conversation, which otherwise grows
+// quadratically over long threads.
+//
+// The prefix check is needed because the engine may rewrite earlier history,
+// e.g. by prepending a system message for new instructions.
+func SplitHistory(base, full []ConversationMessage) ([]ConversationMessage, bool) {
+	if len(base) > len(full) {
+		return nil, false
+	}
+	for i := range base {
+		if !base[i].equal(full[i]) {
+			return nil, false
+		}
+	}
+	return full[len(base):], true
+}
+
+func (m ConversationMessage) equal(o ConversationMessage) bool {
+	return m.Role == o.Role &&
+		m.Content == o.Content &&
+		m.ToolCallID == o.ToolCallID &&
+		slices.Equal(m.ToolCalls, o.ToolCalls) &&
+		m.Reasoning.equal(o.Reasoning)
+}
+
+func (r *ReasoningRecord) equal(o *ReasoningRecord) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	return r.ID == o.ID &&
+		r.Text == o.Text &&
+		r.EncryptedContent == o.EncryptedContent &&
+		slices.Equal(r.Summary, o.Summary)
+}
@@ -0,0 +1,34 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// EventOutbox is implemented by session stores that can hold the events of
+// responses until they are published to the event bus. The events of a
+// response (Response.Events) are appended in the same transaction as the
+// response, so the events of every saved response are published at least
+// once, across restarts and broker outages.
+type EventOutbox interface {
+	// ClaimOutboxEvents returns up to limit events, oldest first, and
+	// claims them for lease: they are not returned again until it elapses,
+	// so a replica that fails to publish them lets another retry.
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*OutboxEvent, error)
+
+	// DeleteOutboxEvents removes events once published.
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+}
+
+// OutboxEvent is an event waiting in the outbox.
+type OutboxEvent struct {
+	ID         string
+	Type       string // "response.created", "response.completed", ...
+	ResponseID string
+	Data       []byte // JSON payload
+	CreatedAt  time.Time
+	Attempts   int // times the event was claimed, including the current claim
+}
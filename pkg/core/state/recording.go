@@ -0,0 +1,20 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import "context"
+
+// RecordingStore is implemented by session stores that can keep the
+// recordings of responses made in record mode: the backend calls and tool
+// results of their agentic loop, for replay. A recording is deleted with its
+// response.
+type RecordingStore interface {
+	// SaveRecording stores the recording of a response, replacing any
+	// previous one.
+	SaveRecording(ctx context.Context, responseID string, recording []byte) error
+
+	// GetRecording returns the recording of a response, or false if there
+	// is none.
+	GetRecording(ctx context.Context, responseID string) ([]byte, bool, error)
+}
@@ -0,0 +1,22 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// ResponseCache holds the output of deterministic requests, keyed by a hash
+// of what was sent to the backend. Session stores that implement it can back
+// the response cache; replicas sharing the store share its entries.
+type ResponseCache interface {
+	// GetCachedResponse returns the value stored under key, or false if
+	// there is none or it has expired.
+	GetCachedResponse(ctx context.Context, key string) ([]byte, bool, error)
+
+	// PutCachedResponse stores value under key until expiresAt, replacing
+	// any previous value.
+	PutCachedResponse(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+}
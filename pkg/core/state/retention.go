@@ -0,0 +1,30 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of data removed by Expirer.DeleteExpired.
+const (
+	// ExpiredSessions are sessions whose expires_at is not after the cutoff.
+	ExpiredSessions = "sessions"
+	// ExpiredResponses are responses created before the cutoff. Newer
+	// responses that store their history relative to one take over its
+	// messages, as with DeleteResponse.
+	ExpiredResponses = "responses"
+	// ExpiredConversations are conversations last updated before the
+	// cutoff, removed with their items.
+	ExpiredConversations = "conversations"
+)
+
+// Expirer is implemented by session stores that can delete expired data in
+// batches.
+type Expirer interface {
+	// DeleteExpired deletes at most limit objects of kind that expired
+	// before the cutoff, oldest first, and returns how many it deleted.
+	DeleteExpired(ctx context.Context, kind string, cutoff time.Time, limit int) (int, error)
+}
@@ -0,0 +1,40 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// ShadowStore is implemented by session stores that can keep the results of
+// shadow traffic: the backend calls of responses, mirrored to a secondary
+// backend, paired with what each backend returned. Shadow results are
+// deleted with their response.
+type ShadowStore interface {
+	SaveShadowResult(ctx context.Context, result *ShadowResult) error
+
+	// ListShadowResults returns results newest first. after is the ID of
+	// the last result of the previous page.
+	ListShadowResults(ctx context.Context, filter ShadowFilter, after string, limit int) ([]*ShadowResult, bool, error)
+}
+
+// ShadowResult pairs the outputs of the primary and secondary backends for
+// one backend call of a response.
+type ShadowResult struct {
+	ID         string
+	CreatedAt  time.Time
+	ResponseID string
+	Model      string // model of the backend request
+	Data       []byte // the comparison, as JSON
+}
+
+// ShadowFilter narrows ListShadowResults. Zero-valued fields match every
+// result.
+type ShadowFilter struct {
+	ResponseID    string
+	Model         string
+	CreatedAfter  time.Time // exclusive
+	CreatedBefore time.Time // exclusive
+}
@@ -33,22 +33,45 @@ type SessionStore interface {
 	// Conversation API endpoints
 	CreateConversation(ctx context.Context, conv *Conversation) error
 	ListConversationsPaginated(ctx context.Context, after, before string, limit int, order string) ([]*Conversation, bool, error)
+	CountConversations(ctx context.Context) (int, error)
 	DeleteConversation(ctx context.Context, conversationID string) error
 	AddConversationItems(ctx context.Context, conversationID string, items []Message) error
 	ListConversationItems(ctx context.Context, conversationID string, after, before string, limit int, order string) ([]Message, bool, error)
 
-	// Response history
+	// Response history. GetResponse returns the full message history;
+	// the list methods may return only the messages added by each response.
 	GetResponse(ctx context.Context, responseID string) (*Response, error)
 	SaveResponse(ctx context.Context, resp *Response) error
+	// SaveResponseWithItems saves a response and appends items to the
+	// conversation it belongs to atomically.
+	SaveResponseWithItems(ctx context.Context, resp *Response, items []Message) error
 	ListResponses(ctx context.Context, conversationID string) ([]*Response, error)
 	LinkResponses(ctx context.Context, currentID, previousID string) error
 
-	// Response management (paginated)
-	ListResponsesPaginated(ctx context.Context, after, before string, limit int, order, model string) ([]*Response, bool, error)
+	// Response management (paginated). Listings are ordered by creation
+	// time and then by ID; the after and before cursors follow the listing
+	// order.
+	ListResponsesPaginated(ctx context.Context, after, before string, limit int, order string, filter ResponseFilter) ([]*Response, bool, error)
+	CountResponses(ctx context.Context, filter ResponseFilter) (int, error)
 	DeleteResponse(ctx context.Context, responseID string) error
 	GetResponseInputItems(ctx context.Context, responseID string) (interface{}, error)
 }
 
+// ResponseFilter narrows ListResponsesPaginated and CountResponses.
+// Zero-valued fields match every response.
+type ResponseFilter struct {
+	Model          string
+	ExternalID     string
+	ConversationID string
+	Status         string
+	Tenant         string
+	APIKey         string // API key fingerprint
+	RequestID      string
+	Metadata       map[string]string // every pair must match the request metadata
+	CreatedAfter   time.Time         // exclusive
+	CreatedBefore  time.Time         // exclusive
+}
+
 // Session represents a user session
 type Session struct {
 	ID             string
@@ -66,8 +89,13 @@ type Conversation struct {
 	SessionID string
 	Messages  []Message
 	Metadata  map[string]string
+	Tenant    string // tenant that created the conversation, if any
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// ForkedFrom is the response of another conversation this one was
+	// forked from. Its history precedes the conversation's own turns.
+	ForkedFrom string
 }
 
 // Message represents a message in a conversation
@@ -89,9 +117,21 @@ type Response struct {
 	Status             string
 	Error              interface{}
 	Usage              interface{}
+	Provenance         interface{} // provenance block of the output, if any
 	Messages           []ConversationMessage
+	MessagesBase       string // response whose history prefixes Messages; see SplitHistory
 	CreatedAt          time.Time
 	CompletedAt        *time.Time
+	ExternalID         string // client-supplied correlation ID
+	Tenant             string // tenant that made the request, if any
+	APIKey             string // fingerprint of the API key that made the request, if any; see APIKeyFingerprint
+	RequestID          string // ID of the request that created the response, as in X-Request-ID
+	Model              string // model of the request; set by the store on read
+
+	// Events are appended to the outbox with the response by stores that
+	// implement EventOutbox, and ignored by the others. They are not read
+	// back.
+	Events []OutboxEvent
 }
 
 // ConversationMessage stores a message from a conversation for multi-turn support
@@ -100,6 +140,15 @@ type ConversationMessage struct {
 	Content    string
 	ToolCalls  []ToolCallRecord
 	ToolCallID string
+	Reasoning  *ReasoningRecord
+}
+
+// ReasoningRecord stores a reasoning item for conversation history
+type ReasoningRecord struct {
+	ID               string
+	Summary          []string
+	Text             string
+	EncryptedContent string
 }
 
 // ToolCallRecord stores tool call details for conversation history
@@ -0,0 +1,192 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cors implements HTTP middleware for cross-origin resource sharing,
+// so that pages served from other origins can call the gateway from the
+// browser.
+//
+// Preflight requests are answered by the middleware itself. Other requests
+// get their CORS headers before the wrapped handler runs, and the response
+// writer is passed through as is, so that server-sent event streams are
+// flushed event by event and carry the headers from their first byte.
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+)
+
+// Default option values.
+var (
+	DefaultAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	DefaultMaxAge         = 10 * time.Minute
+)
+
+// Options configures the middleware. Zero values select the defaults.
+type Options struct {
+	// AllowedOrigins lists the origins allowed to call the gateway, such as
+	// "https://app.example.com". "*" allows any origin, and
+	// "https://*.example.com" any subdomain of example.com.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed by preflight requests.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed by preflight
+	// requests. Empty allows any header the browser asks for.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers pages may read besides
+	// the CORS-safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials lets pages send cookies and HTTP authentication.
+	// It requires explicit origins.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response. Negative
+	// disables caching.
+	MaxAge time.Duration
+}
+
+// Handler wraps next with CORS handling.
+func Handler(next http.Handler, opts Options) (http.Handler, error) {
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = DefaultAllowedMethods
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if len(opts.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("no allowed origins")
+	}
+
+	h := &handler{next: next, opts: opts}
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			if opts.AllowCredentials {
+				return nil, fmt.Errorf("credentials cannot be allowed for any origin")
+			}
+			h.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid origin %q (expected scheme://host[:port])", origin)
+		}
+		if host, ok := strings.CutPrefix(u.Host, "*."); ok {
+			h.suffixes = append(h.suffixes, originSuffix{scheme: u.Scheme, suffix: "." + strings.ToLower(host)})
+			continue
+		}
+		h.origins = append(h.origins, strings.ToLower(u.Scheme+"://"+u.Host))
+	}
+
+	h.methods = strings.Join(opts.AllowedMethods, ", ")
+	h.headers = strings.Join(opts.AllowedHeaders, ", ")
+	h.exposed = strings.Join(opts.ExposedHeaders, ", ")
+	h.maxAge = strconv.Itoa(max(int(opts.MaxAge.Seconds()), 0))
+	return h, nil
+}
+
+// originSuffix matches the subdomains of a wildcard origin.
+type originSuffix struct {
+	scheme string
+	suffix string // ".example.com"
+}
+
+type handler struct {
+	next      http.Handler
+	opts      Options
+	anyOrigin bool
+	origins   []string // lower-cased scheme://host[:port]
+	suffixes  []originSuffix
+	methods   string
+	headers   string // empty echoes the requested headers
+	exposed   string
+	maxAge    string // seconds
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	header := w.Header()
+	if !h.anyOrigin || h.opts.AllowCredentials {
+		// The headers depend on the origin: caches must not share them
+		header.Add("Vary", "Origin")
+	}
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if !h.allowed(origin) {
+		if preflight {
+			apierror.Write(w, apierror.New(http.StatusForbidden, apierror.CodeInvalidRequest, fmt.Sprintf("Origin %q is not allowed", origin)))
+			return
+		}
+		// Without CORS headers, the browser hides the response from the page
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	if preflight {
+		if method := r.Header.Get("Access-Control-Request-Method"); !slices.Contains(h.opts.AllowedMethods, method) {
+			apierror.Write(w, apierror.New(http.StatusForbidden, apierror.CodeInvalidRequest, fmt.Sprintf("Method %q is not allowed", method)))
+			return
+		}
+	}
+
+	if h.anyOrigin && !h.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if h.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if h.exposed != "" {
+			header.Set("Access-Control-Expose-Headers", h.exposed)
+		}
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	header.Set("Access-Control-Allow-Methods", h.methods)
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		if h.headers == "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		} else {
+			header.Set("Access-Control-Allow-Headers", h.headers)
+		}
+	}
+	header.Set("Access-Control-Max-Age", h.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowed reports whether origin may call the gateway.
+func (h *handler) allowed(origin string) bool {
+	if h.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(h.origins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, s := range h.suffixes {
+		if u.Scheme == s.scheme && strings.HasSuffix(u.Host, s.suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T, opts Options) http.Handler {
+	t.Helper()
+	h, err := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Session-Affinity", "conv_1")
+		w.Write([]byte("ok"))
+	}), opts)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	return h
+}
+
+func serve(h http.Handler, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/v1/responses", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Request(t *testing.T) {
+	h := newTestHandler(t, Options{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		ExposedHeaders: []string{"X-Session-Affinity"},
+	})
+	tests := []struct {
+		origin    string
+		wantAllow string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://APP.example.com", "https://APP.example.com"},
+		{"https://a.b.example.org", "https://a.b.example.org"},
+		{"http://a.example.org", ""},
+		{"https://example.org", ""},
+		{"https://evil.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		rec := serve(h, http.MethodGet, tt.origin, nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+			t.Errorf("origin %q: served %d %q", tt.origin, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+			t.Errorf("origin %q: Allow-Origin = %q, want %q", tt.origin, got, tt.wantAllow)
+		}
+		wantExpose := ""
+		if tt.wantAllow != "" {
+			wantExpose = "X-Session-Affinity"
+		}
+		if got := rec.Header().Get("Access-Control-Expose-Headers"); got != wantExpose {
+			t.Errorf("origin %q: Expose-Headers = %q, want %q", tt.origin, got, wantExpose)
+		}
+		if got := rec.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("origin %q: Vary = %q", tt.origin, got)
+		}
+	}
+}
+
+func TestHandler_Preflight(t *testing.T) {
+	h := newTestHandler(t, Options{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+	preflight := func(origin, method string) *httptest.ResponseRecorder {
+		return serve(h, http.MethodOptions, origin, map[string]string{
+			"Access-Control-Request-Method":  method,
+			"Access-Control-Request-Headers": "authorization, content-type",
+		})
+	}
+
+	rec := preflight("https://app.example.com", http.MethodPost)
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("preflight = %d %q, want 204 without body", rec.Code, rec.Body.String())
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, PUT, DELETE",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, rec := range []*httptest.ResponseRecorder{
+		preflight("https://evil.com", http.MethodPost),
+		preflight("https://app.example.com", http.MethodPatch),
+	} {
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("refused preflight = %d, Allow-Origin %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+
+	// OPTIONS without a requested method is not a preflight
+	if rec := serve(h, http.MethodOptions, "https://app.example.com", nil); rec.Body.String() != "ok" {
+		t.Errorf("plain OPTIONS was not passed through: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandler_AnyOrigin(t *testing.T) {
+	h := newTestHandler(t, Options{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Content-Type"}})
+	rec := serve(h, http.MethodOptions, "https://anywhere.test", map[string]string{
+		"Access-Control-Request-Method":  http.MethodGet,
+		"Access-Control-Request-Headers": "x-custom",
+	})
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+		t.Errorf("Allow-Headers = %q, want the configured list", got)
+	}
+}
+
+func TestHandler_Streaming(t *testing.T) {
+	h, err := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("the response writer lost http.Flusher")
+			return
+		}
+		for i := range 2 {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			flusher.Flush()
+		}
+	}), Options{AllowedOrigins: []string{"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	rec := serve(h, http.MethodPost, "https://app.example.com", nil)
+	if !rec.Flushed || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("stream flushed %v with Allow-Origin %q", rec.Flushed, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestHandler_InvalidOptions(t *testing.T) {
+	next := http.NotFoundHandler()
+	for _, opts := range []Options{
+		{},
+		{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowedOrigins: []string{"app.example.com"}},
+		{AllowedOrigins: []string{"https://app.example.com/path"}},
+	} {
+		if _, err := Handler(next, opts); err == nil {
+			t.Errorf("Handler(%+v) succeeded, want an error", opts)
+		}
+	}
+}
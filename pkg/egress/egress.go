@@ -0,0 +1,270 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package egress enforces the outbound network policy of tool calls: the
+// hosts and addresses MCP servers, HTTP tools, web search providers and
+// fetched URLs may be reached at, how large their responses may be and how
+// long they may take. It keeps tool calls from reaching internal services
+// (SSRF) or sending data to arbitrary hosts.
+//
+// Addresses are checked when they are dialed, after DNS resolution, so a
+// host name resolving to a forbidden address is refused too.
+package egress
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrBlocked is returned for requests to hosts or addresses the
+	// policy does not allow.
+	ErrBlocked = errors.New("destination is not allowed")
+	// ErrResponseTooLarge is returned when reading a response body larger
+	// than the policy allows.
+	ErrResponseTooLarge = errors.New("response is too large")
+)
+
+// Policy restricts outbound requests. The zero Policy allows everything.
+type Policy struct {
+	// AllowedHosts, when set, are the only hosts requests may be sent to,
+	// redirects included: exact names, or "*.example.com" for the
+	// subdomains of example.com. IP address hosts match as written.
+	AllowedHosts []string
+	// AllowedCIDRs, when set, are the only addresses that may be dialed,
+	// private ones included.
+	AllowedCIDRs []netip.Prefix
+	// BlockPrivateNetworks refuses loopback, private, link-local and
+	// multicast addresses. It has no effect when AllowedCIDRs is set.
+	BlockPrivateNetworks bool
+	// MaxResponseBytes bounds the response bodies read; 0 is no limit.
+	MaxResponseBytes int64
+	// Timeout bounds requests, reading the response included; 0 is no
+	// limit.
+	Timeout time.Duration
+	// InsecureSkipVerify disables the verification of TLS certificates.
+	InsecureSkipVerify bool
+}
+
+// Override returns p with the fields set in o replacing its own. Boolean
+// fields are combined: set in either, they are set in the result.
+func (p Policy) Override(o Policy) Policy {
+	if o.AllowedHosts != nil {
+		p.AllowedHosts = o.AllowedHosts
+	}
+	if o.AllowedCIDRs != nil {
+		p.AllowedCIDRs = o.AllowedCIDRs
+	}
+	if o.MaxResponseBytes > 0 {
+		p.MaxResponseBytes = o.MaxResponseBytes
+	}
+	if o.Timeout > 0 {
+		p.Timeout = o.Timeout
+	}
+	p.BlockPrivateNetworks = p.BlockPrivateNetworks || o.BlockPrivateNetworks
+	p.InsecureSkipVerify = p.InsecureSkipVerify || o.InsecureSkipVerify
+	return p
+}
+
+// restrictsAddresses reports whether the policy checks dialed addresses.
+func (p Policy) restrictsAddresses() bool {
+	return len(p.AllowedCIDRs) > 0 || p.BlockPrivateNetworks
+}
+
+// CheckHost returns an error wrapping ErrBlocked if requests may not be
+// sent to host.
+func (p Policy) CheckHost(host string) error {
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.AllowedHosts {
+		if hostMatch(strings.ToLower(pattern), host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s", ErrBlocked, host)
+}
+
+// CheckAddr returns an error wrapping ErrBlocked if addr may not be
+// dialed.
+func (p Policy) CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if len(p.AllowedCIDRs) > 0 {
+		for _, prefix := range p.AllowedCIDRs {
+			if prefix.Contains(addr) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: address %s", ErrBlocked, addr)
+	}
+	if p.BlockPrivateNetworks && isPrivate(addr) {
+		return fmt.Errorf("%w: %s is a private address", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// Transport returns a round tripper enforcing the policy, Timeout aside.
+// When the policy checks addresses, proxies set in the environment are not
+// used, since the address dialed would be the proxy's.
+func (p Policy) Transport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if p.restrictsAddresses() {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return fmt.Errorf("%w: %s", ErrBlocked, address)
+				}
+				return p.CheckAddr(addrPort.Addr())
+			},
+		}
+		t.DialContext = dialer.DialContext
+		t.Proxy = nil
+	}
+	if p.InsecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &transport{policy: p, next: t}
+}
+
+// Client returns an HTTP client enforcing the policy.
+func (p Policy) Client() *http.Client {
+	return &http.Client{Transport: p.Transport(), Timeout: p.Timeout}
+}
+
+// transport checks the host of every request, redirects included, and
+// bounds the response bodies.
+type transport struct {
+	policy Policy
+	next   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.CheckHost(req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || t.policy.MaxResponseBytes <= 0 {
+		return resp, err
+	}
+	if resp.ContentLength > t.policy.MaxResponseBytes {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrResponseTooLarge, resp.ContentLength, t.policy.MaxResponseBytes)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: t.policy.MaxResponseBytes}
+	return resp, nil
+}
+
+// limitedBody fails reads past its limit rather than cutting the body
+// short, so that callers cannot mistake a partial body for a whole one.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// Read one byte past the limit to tell a body of exactly the limit
+	// from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// Set holds the global policy and the policies of connectors, and the
+// clients enforcing them. It is safe for concurrent use.
+type Set struct {
+	global     Policy
+	connectors map[string]Policy
+
+	mu      sync.Mutex
+	clients map[string]*http.Client // keyed by connector ID, "" for the global policy
+}
+
+// NewSet returns a set of policies. The policies of connectors, keyed by
+// connector ID, override the global one.
+func NewSet(global Policy, connectors map[string]Policy) *Set {
+	s := &Set{
+		global:     global,
+		connectors: make(map[string]Policy, len(connectors)),
+		clients:    make(map[string]*http.Client),
+	}
+	for id, p := range connectors {
+		s.connectors[id] = global.Override(p)
+	}
+	return s
+}
+
+// Policy returns the policy of a connector, or the global policy for
+// connectors without their own and for connectorID "".
+func (s *Set) Policy(connectorID string) Policy {
+	if p, ok := s.connectors[connectorID]; ok {
+		return p
+	}
+	return s.global
+}
+
+// Client returns the client enforcing the policy of a connector, as
+// Policy selects it. Clients are shared, so that connections are reused.
+func (s *Set) Client(connectorID string) *http.Client {
+	if _, ok := s.connectors[connectorID]; !ok {
+		connectorID = ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clients[connectorID]
+	if !ok {
+		c = s.Policy(connectorID).Client()
+		s.clients[connectorID] = c
+	}
+	return c
+}
+
+// ParsePrefix parses a CIDR, such as "10.0.0.0/8", or a single address.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// hostMatch reports whether host matches pattern, which may start with
+// "*." to match any subdomain.
+func hostMatch(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// isPrivate reports whether addr is not a public internet address.
+func isPrivate(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast()
+}
@@ -0,0 +1,173 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package egress
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicy_CheckHost(t *testing.T) {
+	p := Policy{AllowedHosts: []string{"api.example.com", "*.Search.example.org", "10.0.0.1"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"api.example.com", true},
+		{"API.example.com.", true},
+		{"www.example.com", false},
+		{"eu.search.example.org", true},
+		{"search.example.org", false},
+		{"evilsearch.example.org", false},
+		{"10.0.0.1", true},
+		{"10.0.0.2", false},
+	}
+	for _, tt := range tests {
+		err := p.CheckHost(tt.host)
+		if got := err == nil; got != tt.want {
+			t.Errorf("CheckHost(%q) = %v, want allowed %v", tt.host, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, ErrBlocked) {
+			t.Errorf("CheckHost(%q) error = %v, want ErrBlocked", tt.host, err)
+		}
+	}
+	if err := (Policy{}).CheckHost("anything.example"); err != nil {
+		t.Errorf("zero policy: CheckHost = %v", err)
+	}
+}
+
+func TestPolicy_CheckAddr(t *testing.T) {
+	prefix, err := ParsePrefix("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		addr   string
+		want   bool
+	}{
+		{"zero policy", Policy{}, "127.0.0.1", true},
+		{"private blocked", Policy{BlockPrivateNetworks: true}, "10.1.2.3", false},
+		{"loopback blocked", Policy{BlockPrivateNetworks: true}, "::1", false},
+		{"mapped loopback blocked", Policy{BlockPrivateNetworks: true}, "::ffff:127.0.0.1", false},
+		{"link-local blocked", Policy{BlockPrivateNetworks: true}, "169.254.169.254", false},
+		{"public allowed", Policy{BlockPrivateNetworks: true}, "93.184.216.34", true},
+		{"in CIDR", Policy{AllowedCIDRs: []netip.Prefix{prefix}, BlockPrivateNetworks: true}, "192.168.1.7", true},
+		{"outside CIDR", Policy{AllowedCIDRs: []netip.Prefix{prefix}}, "93.184.216.34", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckAddr(netip.MustParseAddr(tt.addr))
+			if got := err == nil; got != tt.want {
+				t.Errorf("CheckAddr(%s) = %v, want allowed %v", tt.addr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Client(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://elsewhere.example/", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/stream":
+			// No Content-Length: the limit applies while reading
+			w.Write([]byte(strings.Repeat("a", 40)))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("a", 40)))
+		default:
+			w.Write([]byte(strings.Repeat("a", 80)))
+		}
+	}))
+	defer srv.Close()
+
+	get := func(p Policy, path string) (string, error) {
+		resp, err := p.Client().Get(srv.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if body, err := get(Policy{AllowedHosts: []string{"127.0.0.1"}, MaxResponseBytes: 80}, "/"); err != nil || len(body) != 80 {
+		t.Errorf("allowed: body %d bytes, err %v", len(body), err)
+	}
+	if _, err := get(Policy{BlockPrivateNetworks: true}, "/"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("private address: err = %v, want ErrBlocked", err)
+	}
+	if _, err := get(Policy{AllowedHosts: []string{"api.example.com"}}, "/"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("host not allowed: err = %v, want ErrBlocked", err)
+	}
+	if _, err := get(Policy{AllowedHosts: []string{"127.0.0.1"}}, "/redirect"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("redirect: err = %v, want ErrBlocked", err)
+	}
+	if _, err := get(Policy{MaxResponseBytes: 79}, "/"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Content-Length over limit: err = %v, want ErrResponseTooLarge", err)
+	}
+	if _, err := get(Policy{MaxResponseBytes: 50}, "/stream"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("body over limit: err = %v, want ErrResponseTooLarge", err)
+	}
+	if _, err := get(Policy{Timeout: 50 * time.Millisecond}, "/slow"); err == nil {
+		t.Error("timeout: request succeeded")
+	}
+}
+
+func TestSet(t *testing.T) {
+	global := Policy{AllowedHosts: []string{"*.example.com"}, BlockPrivateNetworks: true, Timeout: time.Minute}
+	s := NewSet(global, map[string]Policy{
+		"internal": {AllowedHosts: []string{"wiki.corp"}, MaxResponseBytes: 1024},
+	})
+
+	p := s.Policy("internal")
+	if p.CheckHost("wiki.corp") != nil || p.CheckHost("api.example.com") == nil {
+		t.Errorf("connector hosts = %v, want only its own", p.AllowedHosts)
+	}
+	if !p.BlockPrivateNetworks || p.Timeout != time.Minute || p.MaxResponseBytes != 1024 {
+		t.Errorf("connector policy = %+v, want the global settings it does not set", p)
+	}
+	if got := s.Policy("other"); got.CheckHost("api.example.com") != nil {
+		t.Errorf("other connector policy = %+v, want the global one", got)
+	}
+
+	if s.Client("internal") != s.Client("internal") {
+		t.Error("connector client is not reused")
+	}
+	if s.Client("other") != s.Client("") {
+		t.Error("connectors without a policy do not share the global client")
+	}
+	if s.Client("internal") == s.Client("") {
+		t.Error("connector policy uses the global client")
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.0.0.0/8":   "10.0.0.0/8",
+		"10.1.2.3/8":   "10.0.0.0/8",
+		"192.168.1.10": "192.168.1.10/32",
+		"fd00::/8":     "fd00::/8",
+		"::1":          "::1/128",
+	} {
+		got, err := ParsePrefix(in)
+		if err != nil || got.String() != want {
+			t.Errorf("ParsePrefix(%q) = %v, %v, want %s", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "10.0.0.0/33", "example.com"} {
+		if _, err := ParsePrefix(in); err == nil {
+			t.Errorf("ParsePrefix(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package local implements api.EmbeddingClient in-process, with a sentence
+// embedding model exported to ONNX, so that vector search works without an
+// embeddings server.
+//
+// A model directory holds the model as model.onnx and the WordPiece
+// vocabulary of its tokenizer as vocab.txt, as found in the onnx/ folder of
+// sentence-transformers models. A tokenizer_config.json with do_lower_case
+// is honored; inputs are lowercased otherwise.
+//
+// Running models needs ONNX Runtime: the gateway must be built with
+// "-tags onnx" and find the ONNX Runtime shared library at run time.
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// DefaultMaxTokens is the number of tokens inputs are truncated to by
+// default, the context of BERT models.
+const DefaultMaxTokens = 512
+
+// batchSize bounds the inputs run through the model at once.
+const batchSize = 32
+
+// Options configures a Client.
+type Options struct {
+	ModelPath      string // directory of model.onnx and vocab.txt
+	RuntimeLibrary string // ONNX Runtime shared library; the system's when empty
+	MaxTokens      int    // longer inputs are truncated (default DefaultMaxTokens)
+	Dimensions     int    // expected dimensions of the vectors; checked when set
+}
+
+// model runs batches of token IDs through an embedding model.
+type model interface {
+	// run returns the vectors of a batch of sequences, padded to the same
+	// length; mask is 1 for tokens and 0 for padding.
+	run(ids, mask [][]int64) ([][]float32, error)
+}
+
+// Client embeds texts with a local model.
+type Client struct {
+	tokenizer  *tokenizer
+	model      model
+	maxTokens  int
+	dimensions int
+}
+
+// New loads the model of opts.ModelPath.
+func New(opts Options) (*Client, error) {
+	tok, err := loadTokenizer(filepath.Join(opts.ModelPath, "vocab.txt"), lowercases(opts.ModelPath))
+	if err != nil {
+		return nil, err
+	}
+	m, err := loadModel(filepath.Join(opts.ModelPath, "model.onnx"), opts.RuntimeLibrary)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(tok, m, opts.MaxTokens, opts.Dimensions)
+}
+
+func newClient(tok *tokenizer, m model, maxTokens, dimensions int) (*Client, error) {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+	c := &Client{tokenizer: tok, model: m, maxTokens: maxTokens}
+
+	// The dimensions come from the model; learn them once
+	probe, err := c.Embed(context.Background(), []string{"dimensions"})
+	if err != nil {
+		return nil, fmt.Errorf("run model: %w", err)
+	}
+	c.dimensions = len(probe[0])
+	if dimensions > 0 && dimensions != c.dimensions {
+		return nil, fmt.Errorf("model returns %d dimensions, not the %d configured", c.dimensions, dimensions)
+	}
+	return c, nil
+}
+
+// Dimensions returns the dimensions of the model's vectors.
+func (c *Client) Dimensions() int {
+	return c.dimensions
+}
+
+// Embed generates normalized embeddings for the given text inputs.
+func (c *Client) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch := inputs[start:min(start+batchSize, len(inputs))]
+		ids, mask := c.encode(batch)
+		out, err := c.model.run(ids, mask)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != len(batch) {
+			return nil, fmt.Errorf("model returned %d vectors for %d inputs", len(out), len(batch))
+		}
+		for _, v := range out {
+			vectors = append(vectors, normalize(v))
+		}
+	}
+	return vectors, nil
+}
+
+// encode tokenizes a batch, padding its sequences to the longest.
+func (c *Client) encode(batch []string) (ids, mask [][]int64) {
+	ids = make([][]int64, len(batch))
+	longest := 0
+	for i, text := range batch {
+		ids[i] = c.tokenizer.encode(text, c.maxTokens)
+		longest = max(longest, len(ids[i]))
+	}
+	mask = make([][]int64, len(batch))
+	for i := range ids {
+		mask[i] = make([]int64, longest)
+		for j := range ids[i] {
+			mask[i][j] = 1
+		}
+		for len(ids[i]) < longest {
+			ids[i] = append(ids[i], c.tokenizer.pad)
+		}
+	}
+	return ids, mask
+}
+
+// meanPool averages the token vectors of each sequence of hidden, laid out
+// as [sequence][token][dimension], over the tokens of mask.
+func meanPool(hidden []float32, mask [][]int64, dimensions int) [][]float32 {
+	vectors := make([][]float32, len(mask))
+	for i, m := range mask {
+		v := make([]float32, dimensions)
+		tokens := 0
+		for j, on := range m {
+			if on == 0 {
+				continue
+			}
+			tokens++
+			token := hidden[(i*len(m)+j)*dimensions:][:dimensions]
+			for k, x := range token {
+				v[k] += x
+			}
+		}
+		for k := range v {
+			v[k] /= float32(max(tokens, 1))
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+// normalize scales v to unit length, as sentence embedding models do for
+// cosine similarity.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// lowercases reports whether the model's tokenizer lowercases inputs, as
+// uncased BERT models do.
+func lowercases(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "tokenizer_config.json"))
+	if err != nil {
+		return true
+	}
+	var cfg struct {
+		DoLowerCase *bool `json:"do_lower_case"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil || cfg.DoLowerCase == nil {
+		return true
+	}
+	return *cfg.DoLowerCase
+}
+
+// errNoRuntime is returned by loadModel in builds without ONNX Runtime.
+var errNoRuntime = errors.New("local embeddings need ONNX Runtime: build the gateway with -tags onnx")
@@ -0,0 +1,95 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeModel returns token vectors of [id, 1] mean-pooled, and records the
+// batches it runs.
+type fakeModel struct {
+	batches [][][]int64
+}
+
+func (m *fakeModel) run(ids, mask [][]int64) ([][]float32, error) {
+	m.batches = append(m.batches, ids)
+	var hidden []float32
+	for _, row := range ids {
+		for _, id := range row {
+			hidden = append(hidden, float32(id), 1)
+		}
+	}
+	return meanPool(hidden, mask, 2), nil
+}
+
+func TestClient_Embed(t *testing.T) {
+	m := &fakeModel{}
+	c, err := newClient(newTestTokenizer(t, true), m, 4, 0)
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+	if c.Dimensions() != 2 {
+		t.Errorf("Dimensions = %d, want 2", c.Dimensions())
+	}
+
+	inputs := make([]string, batchSize+1)
+	for i := range inputs {
+		inputs[i] = "hello"
+	}
+	inputs[1] = "hello world hello world"
+	m.batches = nil
+	vectors, err := c.Embed(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vectors) != len(inputs) {
+		t.Fatalf("got %d vectors, want %d", len(vectors), len(inputs))
+	}
+	if len(m.batches) != 2 || len(m.batches[0]) != batchSize || len(m.batches[1]) != 1 {
+		t.Errorf("inputs not run in batches of %d", batchSize)
+	}
+
+	// Sequences are truncated to 4 tokens and padded to the longest, and
+	// padding does not count in the mean
+	if got := m.batches[0][1]; len(got) != 4 {
+		t.Errorf("long input encoded to %v, want 4 tokens", got)
+	}
+	if got := m.batches[0][0]; len(got) != 4 || got[3] != 0 {
+		t.Errorf("short input encoded to %v, want padding to 4 tokens", got)
+	}
+	// "hello" is [CLS]=2, hello=4, [SEP]=3: mean (3, 1), normalized
+	want := []float32{float32(3 / math.Sqrt(10)), float32(1 / math.Sqrt(10))}
+	for i, v := range vectors[0] {
+		if math.Abs(float64(v-want[i])) > 1e-6 {
+			t.Errorf("vector = %v, want %v", vectors[0], want)
+			break
+		}
+	}
+}
+
+func TestNewClient_Dimensions(t *testing.T) {
+	_, err := newClient(newTestTokenizer(t, true), &fakeModel{}, 0, 384)
+	if err == nil || !strings.Contains(err.Error(), "2 dimensions") {
+		t.Errorf("newClient = %v, want dimension mismatch", err)
+	}
+}
+
+func TestLowercases(t *testing.T) {
+	dir := t.TempDir()
+	if !lowercases(dir) {
+		t.Error("lowercases without tokenizer_config.json = false, want true")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tokenizer_config.json"), []byte(`{"do_lower_case": false}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if lowercases(dir) {
+		t.Error("lowercases with do_lower_case false = true")
+	}
+}
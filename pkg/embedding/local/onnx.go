@@ -0,0 +1,125 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build onnx
+
+package local
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// The ONNX Runtime environment is process-wide.
+var (
+	ortOnce sync.Once
+	ortErr  error
+)
+
+func initRuntime(runtimeLibrary string) error {
+	ortOnce.Do(func() {
+		if runtimeLibrary != "" {
+			ort.SetSharedLibraryPath(runtimeLibrary)
+		}
+		ortErr = ort.InitializeEnvironment()
+	})
+	return ortErr
+}
+
+// onnxModel runs a model with ONNX Runtime. Models take input_ids,
+// attention_mask and, for most, token_type_ids, and return either token
+// vectors, which are mean-pooled, or pooled sentence vectors.
+type onnxModel struct {
+	session *ort.DynamicAdvancedSession
+	inputs  []string
+	pooled  bool // output is [batch][dimension] rather than [batch][token][dimension]
+}
+
+func loadModel(path, runtimeLibrary string) (model, error) {
+	if err := initRuntime(runtimeLibrary); err != nil {
+		return nil, fmt.Errorf("initialize ONNX Runtime: %w", err)
+	}
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(path)
+	if err != nil {
+		return nil, fmt.Errorf("load model %s: %w", path, err)
+	}
+
+	var inputs []string
+	for _, in := range inputInfo {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputs = append(inputs, in.Name)
+		default:
+			return nil, fmt.Errorf("model input %q is not supported", in.Name)
+		}
+	}
+	if !slices.Contains(inputs, "input_ids") || !slices.Contains(inputs, "attention_mask") {
+		return nil, fmt.Errorf("model must take input_ids and attention_mask")
+	}
+
+	// Prefer the pooled output of models that have one
+	output := outputInfo[0]
+	for _, out := range outputInfo {
+		if out.Name == "sentence_embedding" {
+			output = out
+		}
+	}
+	rank := len(output.Dimensions)
+	if rank != 2 && rank != 3 {
+		return nil, fmt.Errorf("model output %q has rank %d, want 2 or 3", output.Name, rank)
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(path, inputs, []string{output.Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create session for %s: %w", path, err)
+	}
+	return &onnxModel{session: session, inputs: inputs, pooled: rank == 2}, nil
+}
+
+func (m *onnxModel) run(ids, mask [][]int64) ([][]float32, error) {
+	batch, length := int64(len(ids)), int64(len(ids[0]))
+	shape := ort.NewShape(batch, length)
+
+	inputs := make([]ort.Value, len(m.inputs))
+	for i, name := range m.inputs {
+		var flat []int64
+		switch name {
+		case "input_ids":
+			flat = slices.Concat(ids...)
+		case "attention_mask":
+			flat = slices.Concat(mask...)
+		default: // token_type_ids: a single segment
+			flat = make([]int64, batch*length)
+		}
+		t, err := ort.NewTensor(shape, flat)
+		if err != nil {
+			return nil, fmt.Errorf("create %s tensor: %w", name, err)
+		}
+		defer t.Destroy()
+		inputs[i] = t
+	}
+
+	outputs := []ort.Value{nil}
+	if err := m.session.Run(inputs, outputs); err != nil {
+		return nil, err
+	}
+	defer outputs[0].Destroy()
+	out, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("model output is not a float32 tensor")
+	}
+	data, outShape := out.GetData(), out.GetShape()
+
+	dimensions := int(outShape[len(outShape)-1])
+	if !m.pooled {
+		return meanPool(data, mask, dimensions), nil
+	}
+	vectors := make([][]float32, batch)
+	for i := range vectors {
+		vectors[i] = slices.Clone(data[i*dimensions : (i+1)*dimensions])
+	}
+	return vectors, nil
+}
@@ -0,0 +1,10 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !onnx
+
+package local
+
+func loadModel(path, runtimeLibrary string) (model, error) {
+	return nil, errNoRuntime
+}
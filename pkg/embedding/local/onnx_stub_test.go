@@ -0,0 +1,24 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !onnx
+
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_WithoutRuntime(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "vocab.txt"), []byte(strings.Join(testVocab, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Options{ModelPath: dir}); !errors.Is(err, errNoRuntime) {
+		t.Errorf("New = %v, want errNoRuntime", err)
+	}
+}
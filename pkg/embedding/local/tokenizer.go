@@ -0,0 +1,166 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordRunes is the length beyond which a word is not split into pieces
+// but mapped to the unknown token, as BERT tokenizers do.
+const maxWordRunes = 100
+
+// tokenizer is a BERT WordPiece tokenizer, the one of most sentence
+// embedding models.
+type tokenizer struct {
+	vocab     map[string]int64
+	lowercase bool
+
+	cls, sep, pad, unk int64
+}
+
+// loadTokenizer reads a WordPiece vocabulary, one token per line.
+func loadTokenizer(path string, lowercase bool) (*tokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for id := int64(0); scanner.Scan(); id++ {
+		vocab[strings.TrimRight(scanner.Text(), "\r")] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read vocabulary: %w", err)
+	}
+	return newTokenizer(vocab, lowercase)
+}
+
+func newTokenizer(vocab map[string]int64, lowercase bool) (*tokenizer, error) {
+	t := &tokenizer{vocab: vocab, lowercase: lowercase}
+	for _, special := range []struct {
+		token string
+		id    *int64
+	}{{"[CLS]", &t.cls}, {"[SEP]", &t.sep}, {"[PAD]", &t.pad}, {"[UNK]", &t.unk}} {
+		id, ok := vocab[special.token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary has no %s token", special.token)
+		}
+		*special.id = id
+	}
+	return t, nil
+}
+
+// encode returns the token IDs of text between [CLS] and [SEP], keeping at
+// most maxTokens IDs in all.
+func (t *tokenizer) encode(text string, maxTokens int) []int64 {
+	ids := []int64{t.cls}
+	for _, word := range t.words(text) {
+		for _, id := range t.pieces(word) {
+			if len(ids) == maxTokens-1 {
+				return append(ids, t.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// words splits text on whitespace and punctuation, each punctuation and
+// CJK character being a word of its own.
+func (t *tokenizer) words(text string) []string {
+	if t.lowercase {
+		text = stripAccents(strings.ToLower(text))
+	}
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || isControl(r):
+		case unicode.IsSpace(r):
+			flush()
+		case isPunctuation(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// pieces splits a word into the longest vocabulary pieces, greedily from
+// its start. Words that cannot be split entirely are unknown.
+func (t *tokenizer) pieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordRunes {
+		return []int64{t.unk}
+	}
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := t.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []int64{t.unk}
+		}
+		start = end
+	}
+	return ids
+}
+
+func stripAccents(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isControl(r rune) bool {
+	if r == '\t' || r == '\n' || r == '\r' {
+		return false
+	}
+	return unicode.IsControl(r) || unicode.In(r, unicode.Cf)
+}
+
+// isPunctuation also counts ASCII symbols such as "$" and "^", which
+// Unicode does not class as punctuation.
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
@@ -0,0 +1,79 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testVocab = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "hello", "world", "!", "un", "##aff", "##able", "cafe", "的", "$"}
+
+func newTestTokenizer(t *testing.T, lowercase bool) *tokenizer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(testVocab, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := loadTokenizer(path, lowercase)
+	if err != nil {
+		t.Fatalf("loadTokenizer: %v", err)
+	}
+	return tok
+}
+
+func TestTokenizer_Encode(t *testing.T) {
+	tok := newTestTokenizer(t, true)
+
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{"words and punctuation", "Hello, world!", 512, []string{"[CLS]", "hello", "[UNK]", "world", "!", "[SEP]"}},
+		{"word pieces", "unaffable", 512, []string{"[CLS]", "un", "##aff", "##able", "[SEP]"}},
+		{"unknown piece makes the word unknown", "unaffx", 512, []string{"[CLS]", "[UNK]", "[SEP]"}},
+		{"accents stripped", "Café", 512, []string{"[CLS]", "cafe", "[SEP]"}},
+		{"CJK characters split", "的的", 512, []string{"[CLS]", "的", "的", "[SEP]"}},
+		{"ASCII symbols split", "hello$world", 512, []string{"[CLS]", "hello", "$", "world", "[SEP]"}},
+		{"control characters dropped", "hel\x00lo​", 512, []string{"[CLS]", "hello", "[SEP]"}},
+		{"truncated", "hello world hello world", 4, []string{"[CLS]", "hello", "world", "[SEP]"}},
+		{"too long word", strings.Repeat("a", maxWordRunes+1), 512, []string{"[CLS]", "[UNK]", "[SEP]"}},
+		{"empty", "", 512, []string{"[CLS]", "[SEP]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, id := range tok.encode(tt.text, tt.maxTokens) {
+				got = append(got, testVocab[id])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizer_Cased(t *testing.T) {
+	tok := newTestTokenizer(t, false)
+	got := tok.encode("Hello hello", 512)
+	want := []int64{2, 1, 4, 3}
+	if !slices.Equal(got, want) {
+		t.Errorf("encode = %v, want %v", got, want)
+	}
+}
+
+func TestLoadTokenizer_MissingSpecialToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(path, []byte("[PAD]\n[UNK]\n[CLS]\nhello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTokenizer(path, true); err == nil || !strings.Contains(err.Error(), "[SEP]") {
+		t.Errorf("loadTokenizer = %v, want missing [SEP] error", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package eventbus publishes the lifecycle and usage events of responses
+// to a message broker, for analytics and billing systems.
+//
+// The engine records events in the outbox of the session store with the
+// responses they describe (see state.EventOutbox), and a relay publishes
+// them from there, so every event is published at least once. Consumers
+// should deduplicate on the event ID.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/provider"
+)
+
+// Providers is the registry of publisher implementations. The Kafka and
+// NATS publishers register themselves when their packages are imported.
+var Providers = provider.NewRegistry[Publisher]("event_bus")
+
+// Event types.
+const (
+	ResponseCreated    = "response.created"    // the response was accepted
+	ResponseCompleted  = "response.completed"  // it finished
+	ResponseIncomplete = "response.incomplete" // it stopped early, e.g. at max_output_tokens
+	ResponseFailed     = "response.failed"     // it failed
+	ResponseUsage      = "response.usage"      // the tokens it used; sent with each of the three above
+)
+
+// Publisher sends messages to a broker.
+type Publisher interface {
+	// Publish returns once the broker acknowledged every message. On error,
+	// some messages may have been published.
+	Publish(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// Message is an event as sent to the broker.
+type Message struct {
+	ID          string // ID of the event; brokers that deduplicate use it
+	Topic       string
+	Key         string // ID of the response, so that its events stay in order
+	Value       []byte
+	ContentType string
+}
+
+// ResponseEvent is the data of the events of a response.
+type ResponseEvent struct {
+	ResponseID         string                         `json:"response_id"`
+	Status             string                         `json:"status"`
+	Model              string                         `json:"model"`
+	ConversationID     string                         `json:"conversation_id,omitempty"`
+	PreviousResponseID string                         `json:"previous_response_id,omitempty"`
+	ExternalID         string                         `json:"external_id,omitempty"`
+	Tenant             string                         `json:"tenant,omitempty"`
+	APIKey             string                         `json:"api_key,omitempty"` // fingerprint, see state.APIKeyFingerprint
+	RequestID          string                         `json:"request_id,omitempty"`
+	Metadata           map[string]string              `json:"metadata,omitempty"`
+	Usage              *schema.UsageField             `json:"usage,omitempty"`
+	Error              *schema.ErrorField             `json:"error,omitempty"`
+	IncompleteDetails  *schema.IncompleteDetailsField `json:"incomplete_details,omitempty"`
+	CreatedAt          int64                          `json:"created_at"`
+	CompletedAt        *int64                         `json:"completed_at,omitempty"`
+}
+
+// Topics maps events to the topics they are published to.
+type Topics struct {
+	Responses string // lifecycle events
+	Usage     string // response.usage events
+}
+
+// For returns the topic of an event type.
+func (t Topics) For(eventType string) string {
+	if eventType == ResponseUsage {
+		return t.Usage
+	}
+	return t.Responses
+}
+
+// Serializations of events.
+const (
+	SerializationJSON        = "json"
+	SerializationCloudEvents = "cloudevents"
+)
+
+// envelope is the JSON serialization of an event.
+type envelope struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// cloudEvent is the CloudEvents 1.0 serialization of an event, in
+// structured mode.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Encode serializes an outbox event for topic. source identifies the
+// gateway in CloudEvents.
+func Encode(event *state.OutboxEvent, topic, serialization, source string) (Message, error) {
+	msg := Message{ID: event.ID, Topic: topic, Key: event.ResponseID}
+	var (
+		v   interface{}
+		err error
+	)
+	switch serialization {
+	case SerializationJSON, "":
+		msg.ContentType = "application/json"
+		v = envelope{ID: event.ID, Type: event.Type, Time: event.CreatedAt.UTC(), Data: event.Data}
+	case SerializationCloudEvents:
+		msg.ContentType = "application/cloudevents+json"
+		v = cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              event.ID,
+			Source:          source,
+			Type:            event.Type,
+			Subject:         event.ResponseID,
+			Time:            event.CreatedAt.UTC(),
+			DataContentType: "application/json",
+			Data:            event.Data,
+		}
+	default:
+		return msg, fmt.Errorf("unknown event serialization %q", serialization)
+	}
+	if msg.Value, err = json.Marshal(v); err != nil {
+		return msg, fmt.Errorf("encode event %s: %w", event.ID, err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,67 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package eventbus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+func TestEncode(t *testing.T) {
+	event := &state.OutboxEvent{
+		ID:         "evt_1",
+		Type:       ResponseCompleted,
+		ResponseID: "resp_1",
+		Data:       []byte(`{"response_id":"resp_1","status":"completed"}`),
+		CreatedAt:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	msg, err := Encode(event, "responses", SerializationJSON, "gw")
+	if err != nil {
+		t.Fatalf("Encode(json) error = %v", err)
+	}
+	if msg.ID != "evt_1" || msg.Topic != "responses" || msg.Key != "resp_1" || msg.ContentType != "application/json" {
+		t.Errorf("json message = %+v", msg)
+	}
+	if want := `{"id":"evt_1","type":"response.completed","time":"2026-03-01T12:00:00Z","data":{"response_id":"resp_1","status":"completed"}}`; string(msg.Value) != want {
+		t.Errorf("json value = %s, want %s", msg.Value, want)
+	}
+
+	msg, err = Encode(event, "responses", SerializationCloudEvents, "gw")
+	if err != nil {
+		t.Fatalf("Encode(cloudevents) error = %v", err)
+	}
+	if msg.ContentType != "application/cloudevents+json" {
+		t.Errorf("cloudevents content type = %q", msg.ContentType)
+	}
+	var ce map[string]interface{}
+	if err := json.Unmarshal(msg.Value, &ce); err != nil {
+		t.Fatalf("cloudevents value: %v", err)
+	}
+	for key, want := range map[string]string{
+		"specversion": "1.0", "id": "evt_1", "source": "gw", "type": "response.completed",
+		"subject": "resp_1", "datacontenttype": "application/json",
+	} {
+		if ce[key] != want {
+			t.Errorf("cloudevents %s = %v, want %q", key, ce[key], want)
+		}
+	}
+
+	if _, err := Encode(event, "responses", "avro", "gw"); err == nil {
+		t.Error("expected an error for an unknown serialization")
+	}
+}
+
+func TestTopicsFor(t *testing.T) {
+	topics := Topics{Responses: "r", Usage: "u"}
+	if got := topics.For(ResponseFailed); got != "r" {
+		t.Errorf("For(%s) = %q", ResponseFailed, got)
+	}
+	if got := topics.For(ResponseUsage); got != "u" {
+		t.Errorf("For(%s) = %q", ResponseUsage, got)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kafka publishes events to Kafka topics.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+)
+
+func init() {
+	eventbus.Providers.Register("kafka", func(_ context.Context, params map[string]string) (eventbus.Publisher, error) {
+		return New(Options{
+			Brokers:          splitList(params["brokers"]),
+			ClientID:         params["client_id"],
+			SASLMechanism:    params["sasl_mechanism"],
+			Username:         params["username"],
+			Password:         params["password"],
+			TLS:              params["tls"] == "true",
+			AutoCreateTopics: params["auto_create_topics"] == "true",
+		})
+	})
+}
+
+// compile-time check
+var _ eventbus.Publisher = (*Publisher)(nil)
+
+// Options configures the Kafka publisher.
+type Options struct {
+	Brokers  []string // required, host:port
+	ClientID string
+
+	// SASLMechanism is "" (no authentication), "plain", "scram-sha-256"
+	// or "scram-sha-512".
+	SASLMechanism string
+	Username      string
+	Password      string
+
+	TLS              bool
+	AutoCreateTopics bool
+}
+
+// Publisher writes events to Kafka, waiting for every in-sync replica to
+// acknowledge them. Events are partitioned by key, so those of a response
+// stay in order.
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+// New creates a Kafka publisher.
+func New(opts Options) (*Publisher, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, errors.New("kafka event bus: brokers are required")
+	}
+	transport := &kafka.Transport{ClientID: opts.ClientID}
+	if opts.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	mechanism, err := saslMechanism(opts.SASLMechanism, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	transport.SASL = mechanism
+
+	return &Publisher{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(opts.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: opts.AutoCreateTopics,
+		Transport:              transport,
+	}}, nil
+}
+
+// saslMechanism returns the SASL mechanism named by name.
+func saslMechanism(name, username, password string) (sasl.Mechanism, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("kafka event bus: unknown sasl_mechanism %q", name)
+	}
+}
+
+// Publish implements eventbus.Publisher.
+func (p *Publisher) Publish(ctx context.Context, msgs []eventbus.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	records := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		records[i] = kafka.Message{
+			Topic: msg.Topic,
+			Key:   []byte(msg.Key),
+			Value: msg.Value,
+			Headers: []kafka.Header{
+				{Key: "id", Value: []byte(msg.ID)},
+				{Key: "content-type", Value: []byte(msg.ContentType)},
+			},
+		}
+	}
+	if err := p.writer.WriteMessages(ctx, records...); err != nil {
+		return fmt.Errorf("kafka publish: %w", err)
+	}
+	return nil
+}
+
+// Close implements eventbus.Publisher.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nats publishes events to NATS subjects.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/leseb/openresponses-gw/pkg/eventbus"
+)
+
+func init() {
+	eventbus.Providers.Register("nats", func(_ context.Context, params map[string]string) (eventbus.Publisher, error) {
+		return New(Options{
+			URLs:      splitList(params["brokers"]),
+			Name:      params["client_id"],
+			Username:  params["username"],
+			Password:  params["password"],
+			Token:     params["token"],
+			CredsFile: params["creds_file"],
+			JetStream: params["jetstream"] == "true",
+		})
+	})
+}
+
+// compile-time check
+var _ eventbus.Publisher = (*Publisher)(nil)
+
+// flushTimeout bounds the wait for the server to receive core NATS
+// messages when the context has no deadline.
+const flushTimeout = 10 * time.Second
+
+// Options configures the NATS publisher.
+type Options struct {
+	URLs []string // required, e.g. "nats://localhost:4222"
+	Name string   // connection name shown by the server
+
+	// Credentials: user and password, a token, or a credentials file.
+	Username  string
+	Password  string
+	Token     string
+	CredsFile string
+
+	// JetStream publishes to JetStream streams, which store the events and
+	// acknowledge them, deduplicating on the event ID. Core NATS only
+	// delivers events to the subscribers connected when they are sent.
+	JetStream bool
+}
+
+// Publisher publishes events to NATS, with the topic as subject.
+type Publisher struct {
+	conn *nats.Conn
+	js   jetstream.JetStream // nil with core NATS
+}
+
+// New connects to NATS.
+func New(opts Options) (*Publisher, error) {
+	if len(opts.URLs) == 0 {
+		return nil, errors.New("nats event bus: brokers are required")
+	}
+	var natsOpts []nats.Option
+	if opts.Name != "" {
+		natsOpts = append(natsOpts, nats.Name(opts.Name))
+	}
+	if opts.Username != "" {
+		natsOpts = append(natsOpts, nats.UserInfo(opts.Username, opts.Password))
+	}
+	if opts.Token != "" {
+		natsOpts = append(natsOpts, nats.Token(opts.Token))
+	}
+	if opts.CredsFile != "" {
+		natsOpts = append(natsOpts, nats.UserCredentials(opts.CredsFile))
+	}
+	conn, err := nats.Connect(strings.Join(opts.URLs, ","), natsOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats event bus: connect: %w", err)
+	}
+	p := &Publisher{conn: conn}
+	if opts.JetStream {
+		if p.js, err = jetstream.New(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("nats event bus: jetstream: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Publish implements eventbus.Publisher. With JetStream, each message is
+// acknowledged by its stream; with core NATS, Publish returns once the
+// server received the messages.
+func (p *Publisher) Publish(ctx context.Context, msgs []eventbus.Message) error {
+	for _, msg := range msgs {
+		m := nats.NewMsg(msg.Topic)
+		m.Data = msg.Value
+		m.Header.Set(nats.MsgIdHdr, msg.ID)
+		m.Header.Set("Content-Type", msg.ContentType)
+		m.Header.Set("Response-Id", msg.Key)
+		if p.js != nil {
+			if _, err := p.js.PublishMsg(ctx, m); err != nil {
+				return fmt.Errorf("nats publish to %s: %w", msg.Topic, err)
+			}
+			continue
+		}
+		if err := p.conn.PublishMsg(m); err != nil {
+			return fmt.Errorf("nats publish to %s: %w", msg.Topic, err)
+		}
+	}
+	if p.js != nil {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("nats flush: %w", err)
+	}
+	return nil
+}
+
+// Close implements eventbus.Publisher. Pending messages are sent first.
+func (p *Publisher) Close() error {
+	return p.conn.Drain()
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
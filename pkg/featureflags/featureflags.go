@@ -0,0 +1,208 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package featureflags gates experimental gateway behaviors per tenant or
+// by percentage rollout, so that large engine changes can ship dark and be
+// enabled gradually.
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+// Known flags. Rules may only be configured for these names, so that a typo
+// in the configuration fails at startup instead of silently doing nothing.
+const (
+	// StreamingNormalization enables the new streaming event normalization.
+	StreamingNormalization = "streaming_normalization"
+	// TypedStorage stores responses as typed rows instead of JSON blobs.
+	TypedStorage = "typed_storage"
+	// BackgroundMode allows requests with background=true.
+	BackgroundMode = "background_mode"
+)
+
+// Known is the list of flags the gateway understands, sorted by name.
+var Known = []string{BackgroundMode, StreamingNormalization, TypedStorage}
+
+// Rule decides for which tenants a flag is enabled.
+type Rule struct {
+	Enabled    bool     // enabled for everyone
+	Percentage int      // share of tenants enabled, 0-100
+	Tenants    []string // tenants always enabled
+}
+
+// Validate checks that the rule is well formed.
+func (r Rule) Validate() error {
+	if r.Percentage < 0 || r.Percentage > 100 {
+		return fmt.Errorf("percentage must be between 0 and 100, got %d", r.Percentage)
+	}
+	return nil
+}
+
+// Flag is the state of a single flag.
+type Flag struct {
+	Name       string
+	Rule       Rule // effective rule
+	Overridden bool // Rule was set at runtime and replaces the configured one
+}
+
+// Flags evaluates feature flags. Configured rules can be replaced at
+// runtime with Override; a nil *Flags reports every flag as disabled.
+type Flags struct {
+	mu         sync.RWMutex
+	configured map[string]Rule
+	overrides  map[string]Rule
+}
+
+// New creates Flags from the configured rules. Flags without a rule are
+// disabled.
+func New(rules map[string]Rule) (*Flags, error) {
+	f := &Flags{
+		configured: make(map[string]Rule, len(rules)),
+		overrides:  make(map[string]Rule),
+	}
+	for name, rule := range rules {
+		if err := checkKnown(name); err != nil {
+			return nil, err
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("feature flag %q: %w", name, err)
+		}
+		f.configured[name] = rule
+	}
+	return f, nil
+}
+
+// Enabled reports whether the flag is enabled for the tenant carried by ctx.
+func (f *Flags) Enabled(ctx context.Context, name string) bool {
+	return f.EnabledFor(name, TenantFromContext(ctx))
+}
+
+// EnabledFor reports whether the flag is enabled for tenant. Percentage
+// rollouts hash the tenant, so a tenant keeps the same result as the
+// percentage grows; requests without a tenant all share one bucket.
+func (f *Flags) EnabledFor(name, tenant string) bool {
+	if f == nil {
+		return false
+	}
+	rule, ok := f.rule(name)
+	if !ok {
+		return false
+	}
+	switch {
+	case rule.Enabled:
+		return true
+	case tenant != "" && slices.Contains(rule.Tenants, tenant):
+		return true
+	case rule.Percentage > 0:
+		return bucket(name, tenant) < rule.Percentage
+	}
+	return false
+}
+
+// Override replaces the configured rule for name until Reset is called.
+func (f *Flags) Override(name string, rule Rule) error {
+	if err := checkKnown(name); err != nil {
+		return err
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.overrides[name] = rule
+	f.mu.Unlock()
+	return nil
+}
+
+// Reset removes the runtime override for name, restoring the configured
+// rule.
+func (f *Flags) Reset(name string) error {
+	if err := checkKnown(name); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	delete(f.overrides, name)
+	f.mu.Unlock()
+	return nil
+}
+
+// Get returns the state of a single flag.
+func (f *Flags) Get(name string) (Flag, error) {
+	if err := checkKnown(name); err != nil {
+		return Flag{}, err
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flagLocked(name), nil
+}
+
+// List returns the state of every known flag, sorted by name.
+func (f *Flags) List() []Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]Flag, 0, len(Known))
+	for _, name := range Known {
+		out = append(out, f.flagLocked(name))
+	}
+	return out
+}
+
+func (f *Flags) flagLocked(name string) Flag {
+	if rule, ok := f.overrides[name]; ok {
+		return Flag{Name: name, Rule: rule, Overridden: true}
+	}
+	return Flag{Name: name, Rule: f.configured[name]}
+}
+
+func (f *Flags) rule(name string) (Rule, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if rule, ok := f.overrides[name]; ok {
+		return rule, true
+	}
+	rule, ok := f.configured[name]
+	return rule, ok
+}
+
+// UnknownFlagError is returned for flag names that are not in Known.
+type UnknownFlagError struct {
+	Name string
+}
+
+func (e *UnknownFlagError) Error() string {
+	return fmt.Sprintf("unknown feature flag %q", e.Name)
+}
+
+func checkKnown(name string) error {
+	if !slices.Contains(Known, name) {
+		return &UnknownFlagError{Name: name}
+	}
+	return nil
+}
+
+// bucket maps a tenant to [0, 100). The flag name is part of the hash so
+// that the same tenants are not always the first to get every flag.
+func bucket(name, tenant string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(tenant))
+	return int(h.Sum32() % 100)
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant used to evaluate flags.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, or "".
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
@@ -0,0 +1,120 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEnabledFor(t *testing.T) {
+	flags, err := New(map[string]Rule{
+		BackgroundMode:         {Enabled: true},
+		StreamingNormalization: {Tenants: []string{"acme"}},
+		TypedStorage:           {Percentage: 100},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		flag   string
+		tenant string
+		want   bool
+	}{
+		{"enabled for everyone", BackgroundMode, "", true},
+		{"listed tenant", StreamingNormalization, "acme", true},
+		{"unlisted tenant", StreamingNormalization, "other", false},
+		{"no tenant", StreamingNormalization, "", false},
+		{"full rollout", TypedStorage, "other", true},
+		{"unknown flag", "nope", "acme", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flags.EnabledFor(tt.flag, tt.tenant); got != tt.want {
+				t.Errorf("EnabledFor(%q, %q) = %v, want %v", tt.flag, tt.tenant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnabledFor_Percentage(t *testing.T) {
+	flags, err := New(map[string]Rule{TypedStorage: {Percentage: 30}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	enabled := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		if flags.EnabledFor(TypedStorage, tenant) {
+			enabled[tenant] = true
+		}
+	}
+	if n := len(enabled); n < 200 || n > 400 {
+		t.Errorf("30%% rollout enabled %d of 1000 tenants", n)
+	}
+
+	// Growing the rollout keeps every tenant that already had the flag
+	if err := flags.Override(TypedStorage, Rule{Percentage: 60}); err != nil {
+		t.Fatalf("Override: %v", err)
+	}
+	for tenant := range enabled {
+		if !flags.EnabledFor(TypedStorage, tenant) {
+			t.Fatalf("%s lost the flag when the rollout grew", tenant)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	flags, err := New(map[string]Rule{BackgroundMode: {Enabled: true}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := WithTenant(context.Background(), "acme")
+
+	if err := flags.Override(BackgroundMode, Rule{}); err != nil {
+		t.Fatalf("Override: %v", err)
+	}
+	if flags.Enabled(ctx, BackgroundMode) {
+		t.Error("flag still enabled after override")
+	}
+	if f, _ := flags.Get(BackgroundMode); !f.Overridden {
+		t.Error("Get does not report the override")
+	}
+
+	if err := flags.Reset(BackgroundMode); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if !flags.Enabled(ctx, BackgroundMode) {
+		t.Error("configured rule not restored after reset")
+	}
+
+	var unknown *UnknownFlagError
+	if err := flags.Override("nope", Rule{Enabled: true}); !errors.As(err, &unknown) {
+		t.Errorf("Override(unknown) error = %v, want UnknownFlagError", err)
+	}
+	if err := flags.Override(BackgroundMode, Rule{Percentage: 101}); err == nil {
+		t.Error("Override accepted percentage 101")
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(map[string]Rule{"nope": {Enabled: true}}); err == nil {
+		t.Error("New accepted an unknown flag")
+	}
+	if _, err := New(map[string]Rule{TypedStorage: {Percentage: -1}}); err == nil {
+		t.Error("New accepted a negative percentage")
+	}
+}
+
+func TestNilFlags(t *testing.T) {
+	var flags *Flags
+	if flags.Enabled(context.Background(), BackgroundMode) {
+		t.Error("nil Flags reported a flag as enabled")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/pagination"
 	"github.com/leseb/openresponses-gw/pkg/provider"
 )
 
@@ -31,6 +32,7 @@ type File struct {
 	Bytes     int64
 	Content   []byte // populated for CreateFile input; nil for GetFile output
 	Status    string
+	Tenant    string // tenant that uploaded the file, if any
 	CreatedAt time.Time
 }
 
@@ -41,5 +43,26 @@ type FileStore interface {
 	GetFileContent(ctx context.Context, fileID string) ([]byte, error)
 	DeleteFile(ctx context.Context, fileID string) error
 	ListFilesPaginated(ctx context.Context, after, before string, limit int, order, purpose string) ([]*File, bool, error)
+	CountFiles(ctx context.Context, purpose string) (int, error)
 	Close(ctx context.Context) error
 }
+
+// URLSigner is implemented by file stores that can hand out time-limited
+// URLs from which clients download file content directly, instead of
+// through the gateway.
+type URLSigner interface {
+	// PresignContentURL returns a download URL for the file's content
+	// valid for expires (zero uses the store's default), and when it
+	// expires.
+	PresignContentURL(ctx context.Context, file *File, expires time.Duration) (string, time.Time, error)
+}
+
+// Paginate returns the page of files selected by the cursors, for backends
+// that list every file and page in memory.
+func Paginate(files []*File, after, before string, limit int, order string) ([]*File, bool) {
+	return pagination.Slice(files, fileKey, after, before, limit, order)
+}
+
+func fileKey(f *File) pagination.Key {
+	return pagination.Key{CreatedAt: f.CreatedAt, ID: f.ID}
+}
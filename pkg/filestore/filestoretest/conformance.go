@@ -34,6 +34,7 @@ func RunConformanceTests(t *testing.T, newStore func(t *testing.T) filestore.Fil
 			Bytes:     5,
 			Content:   []byte("hello"),
 			Status:    "uploaded",
+			Tenant:    "acme",
 			CreatedAt: time.Now().Truncate(time.Millisecond),
 		}
 
@@ -47,7 +48,7 @@ func RunConformanceTests(t *testing.T, newStore func(t *testing.T) filestore.Fil
 		}
 
 		if got.ID != f.ID || got.Filename != f.Filename || got.Purpose != f.Purpose ||
-			got.MimeType != f.MimeType || got.Bytes != f.Bytes || got.Status != f.Status {
+			got.MimeType != f.MimeType || got.Bytes != f.Bytes || got.Status != f.Status || got.Tenant != f.Tenant {
 			t.Errorf("GetFile returned unexpected metadata: %+v", got)
 		}
 
@@ -231,6 +232,87 @@ func RunConformanceTests(t *testing.T, newStore func(t *testing.T) filestore.Fil
 		}
 	})
 
+	t.Run("ListSameCreatedAt", func(t *testing.T) {
+		store := newStore(t)
+		defer store.Close(context.Background())
+		ctx := context.Background()
+
+		// Files created in the same instant are ordered by ID
+		createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, id := range []string{"file_c", "file_a", "file_d", "file_b"} {
+			f := &filestore.File{
+				ID:        id,
+				Filename:  "f.txt",
+				Purpose:   "assistants",
+				MimeType:  "text/plain",
+				Bytes:     1,
+				Content:   []byte("x"),
+				Status:    "uploaded",
+				CreatedAt: createdAt,
+			}
+			if err := store.CreateFile(ctx, f); err != nil {
+				t.Fatalf("CreateFile(%s): %v", id, err)
+			}
+		}
+
+		var got []string
+		after := ""
+		for {
+			files, hasMore, err := store.ListFilesPaginated(ctx, after, "", 3, "desc", "")
+			if err != nil {
+				t.Fatalf("ListFilesPaginated: %v", err)
+			}
+			for _, f := range files {
+				got = append(got, f.ID)
+			}
+			if !hasMore {
+				break
+			}
+			after = files[len(files)-1].ID
+		}
+		want := []string{"file_d", "file_c", "file_b", "file_a"}
+		if len(got) != len(want) {
+			t.Fatalf("listed %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("listed %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("CountFiles", func(t *testing.T) {
+		store := newStore(t)
+		defer store.Close(context.Background())
+		ctx := context.Background()
+
+		for i, p := range []string{"assistants", "vision", "assistants"} {
+			f := &filestore.File{
+				ID:        "file_count" + string(rune('a'+i)),
+				Filename:  "f.txt",
+				Purpose:   p,
+				MimeType:  "text/plain",
+				Bytes:     1,
+				Content:   []byte("x"),
+				Status:    "uploaded",
+				CreatedAt: time.Now().Truncate(time.Millisecond),
+			}
+			if err := store.CreateFile(ctx, f); err != nil {
+				t.Fatalf("CreateFile[%d]: %v", i, err)
+			}
+		}
+
+		for purpose, want := range map[string]int{"": 3, "assistants": 2, "batch": 0} {
+			got, err := store.CountFiles(ctx, purpose)
+			if err != nil {
+				t.Fatalf("CountFiles(%q): %v", purpose, err)
+			}
+			if got != want {
+				t.Errorf("CountFiles(%q) = %d, want %d", purpose, got, want)
+			}
+		}
+	})
+
 	t.Run("DuplicateCreate", func(t *testing.T) {
 		store := newStore(t)
 		defer store.Close(context.Background())
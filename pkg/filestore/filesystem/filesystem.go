@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"time"
 
 	"github.com/leseb/openresponses-gw/pkg/filestore"
@@ -32,6 +31,7 @@ type fileMetadata struct {
 	MimeType  string    `json:"mime_type"`
 	Bytes     int64     `json:"bytes"`
 	Status    string    `json:"status"`
+	Tenant    string    `json:"tenant,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -78,6 +78,7 @@ func (s *Store) CreateFile(_ context.Context, file *filestore.File) error {
 		MimeType:  file.MimeType,
 		Bytes:     file.Bytes,
 		Status:    file.Status,
+		Tenant:    file.Tenant,
 		CreatedAt: file.CreatedAt,
 	}
 	metaBytes, err := json.Marshal(meta)
@@ -111,6 +112,7 @@ func (s *Store) GetFile(_ context.Context, fileID string) (*filestore.File, erro
 		MimeType:  meta.MimeType,
 		Bytes:     meta.Bytes,
 		Status:    meta.Status,
+		Tenant:    meta.Tenant,
 		CreatedAt: meta.CreatedAt,
 	}, nil
 }
@@ -146,9 +148,28 @@ func (s *Store) ListFilesPaginated(_ context.Context, after, before string, limi
 		limit = 50
 	}
 
+	allFiles, err := s.listFiles(purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	page, hasMore := filestore.Paginate(allFiles, after, before, limit, order)
+	return page, hasMore, nil
+}
+
+// CountFiles returns the number of files, optionally only those with the given purpose.
+func (s *Store) CountFiles(_ context.Context, purpose string) (int, error) {
+	files, err := s.listFiles(purpose)
+	if err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
+// listFiles reads the metadata of every stored file with the given purpose.
+func (s *Store) listFiles(purpose string) ([]*filestore.File, error) {
 	entries, err := os.ReadDir(s.baseDir)
 	if err != nil {
-		return nil, false, fmt.Errorf("read base dir: %w", err)
+		return nil, fmt.Errorf("read base dir: %w", err)
 	}
 
 	// Read metadata for each entry
@@ -171,44 +192,12 @@ func (s *Store) ListFilesPaginated(_ context.Context, after, before string, limi
 			MimeType:  meta.MimeType,
 			Bytes:     meta.Bytes,
 			Status:    meta.Status,
+			Tenant:    meta.Tenant,
 			CreatedAt: meta.CreatedAt,
 		})
 	}
 
-	// Sort by CreatedAt
-	sort.Slice(allFiles, func(i, j int) bool {
-		if order == "desc" {
-			return allFiles[i].CreatedAt.After(allFiles[j].CreatedAt)
-		}
-		return allFiles[i].CreatedAt.Before(allFiles[j].CreatedAt)
-	})
-
-	// Apply cursor-based pagination
-	var filtered []*filestore.File
-	foundAfter := after == ""
-
-	for _, file := range allFiles {
-		if after != "" && !foundAfter {
-			if file.ID == after {
-				foundAfter = true
-			}
-			continue
-		}
-
-		if before != "" && file.ID == before {
-			break
-		}
-
-		filtered = append(filtered, file)
-
-		if len(filtered) >= limit {
-			break
-		}
-	}
-
-	hasMore := len(allFiles) > len(filtered) && len(filtered) == limit
-
-	return filtered, hasMore, nil
+	return allFiles, nil
 }
 
 // Close is a no-op for the filesystem store.
@@ -216,6 +205,18 @@ func (s *Store) Close(_ context.Context) error {
 	return nil
 }
 
+// Ping checks that the base directory is still accessible.
+func (s *Store) Ping(_ context.Context) error {
+	info, err := os.Stat(s.baseDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.baseDir)
+	}
+	return nil
+}
+
 // readMetadata reads and unmarshals the metadata.json for a file ID.
 func (s *Store) readMetadata(fileID string) (*fileMetadata, error) {
 	metaPath := filepath.Join(s.baseDir, fileID, "metadata.json")
@@ -6,7 +6,6 @@ package memory
 import (
 	"context"
 	"fmt"
-	"sort"
 	"sync"
 
 	"github.com/leseb/openresponses-gw/pkg/filestore"
@@ -98,49 +97,26 @@ func (s *Store) ListFilesPaginated(_ context.Context, after, before string, limi
 		limit = 50
 	}
 
-	// Collect and filter by purpose
-	allFiles := make([]*filestore.File, 0, len(s.files))
-	for _, file := range s.files {
-		if purpose != "" && file.Purpose != purpose {
-			continue
-		}
-		allFiles = append(allFiles, file)
-	}
-
-	// Sort by CreatedAt for deterministic ordering
-	sort.Slice(allFiles, func(i, j int) bool {
-		if order == "desc" {
-			return allFiles[i].CreatedAt.After(allFiles[j].CreatedAt)
-		}
-		return allFiles[i].CreatedAt.Before(allFiles[j].CreatedAt)
-	})
+	page, hasMore := filestore.Paginate(s.listFilesLocked(purpose), after, before, limit, order)
+	return page, hasMore, nil
+}
 
-	// Apply cursor-based pagination
-	var filtered []*filestore.File
-	foundAfter := after == ""
+// CountFiles returns the number of files, optionally only those with the given purpose.
+func (s *Store) CountFiles(_ context.Context, purpose string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.listFilesLocked(purpose)), nil
+}
 
-	for _, file := range allFiles {
-		if after != "" && !foundAfter {
-			if file.ID == after {
-				foundAfter = true
-			}
+func (s *Store) listFilesLocked(purpose string) []*filestore.File {
+	files := make([]*filestore.File, 0, len(s.files))
+	for _, file := range s.files {
+		if purpose != "" && file.Purpose != purpose {
 			continue
 		}
-
-		if before != "" && file.ID == before {
-			break
-		}
-
-		filtered = append(filtered, file)
-
-		if len(filtered) >= limit {
-			break
-		}
+		files = append(files, file)
 	}
-
-	hasMore := len(allFiles) > len(filtered) && len(filtered) == limit
-
-	return filtered, hasMore, nil
+	return files
 }
 
 // Close is a no-op for the in-memory store.
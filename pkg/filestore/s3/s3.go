@@ -10,13 +10,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"sort"
+	"mime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 
@@ -25,17 +26,40 @@ import (
 
 func init() {
 	filestore.Providers.Register("s3", func(ctx context.Context, params map[string]string) (filestore.FileStore, error) {
-		return New(ctx, Options{
-			Bucket:   params["bucket"],
-			Region:   params["region"],
-			Prefix:   params["prefix"],
-			Endpoint: params["endpoint"],
-		})
+		opts := Options{
+			Bucket:               params["bucket"],
+			Region:               params["region"],
+			Prefix:               params["prefix"],
+			Endpoint:             params["endpoint"],
+			AccessKeyID:          params["access_key_id"],
+			SecretAccessKey:      params["secret_access_key"],
+			ServerSideEncryption: params["server_side_encryption"],
+			KMSKeyID:             params["kms_key_id"],
+		}
+		if v := params["presign_expiry"]; v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("s3 filestore: invalid presign_expiry: %w", err)
+			}
+			opts.PresignExpiry = d
+		}
+		return New(ctx, opts)
 	})
 }
 
-// compile-time check
-var _ filestore.FileStore = (*Store)(nil)
+// compile-time checks
+var (
+	_ filestore.FileStore = (*Store)(nil)
+	_ filestore.URLSigner = (*Store)(nil)
+)
+
+const (
+	defaultPresignExpiry      = 15 * time.Minute
+	maxPresignExpiry          = 7 * 24 * time.Hour // SigV4 limit
+	defaultMultipartThreshold = 64 << 20
+	defaultPartSize           = 16 << 20
+	minPartSize               = 5 << 20 // S3 minimum for every part but the last
+)
 
 // Options configures the S3 backend.
 type Options struct {
@@ -43,6 +67,26 @@ type Options struct {
 	Region   string // e.g. "us-east-1"
 	Prefix   string // key prefix, e.g. "files/"
 	Endpoint string // custom endpoint for MinIO compatibility
+
+	// Static credentials; the AWS default credential chain is used when
+	// AccessKeyID is empty.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// ServerSideEncryption is "" (the bucket default), "AES256" or
+	// "aws:kms". With "aws:kms", KMSKeyID selects the key; empty uses the
+	// AWS managed key.
+	ServerSideEncryption string
+	KMSKeyID             string
+
+	// PresignExpiry is how long download URLs are valid by default
+	// (default 15m).
+	PresignExpiry time.Duration
+
+	// Content larger than MultipartThreshold (default 64 MiB) is uploaded
+	// in parts of PartSize (default 16 MiB, at least 5 MiB).
+	MultipartThreshold int64
+	PartSize           int64
 }
 
 // fileMetadata is the JSON sidecar stored alongside each file in S3.
@@ -53,6 +97,7 @@ type fileMetadata struct {
 	MimeType  string    `json:"mime_type"`
 	Bytes     int64     `json:"bytes"`
 	Status    string    `json:"status"`
+	Tenant    string    `json:"tenant,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -63,9 +108,16 @@ type fileMetadata struct {
 //	<prefix><file_id>/content
 //	<prefix><file_id>/metadata.json
 type Store struct {
-	client *s3.Client
-	bucket string
-	prefix string
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	prefix  string
+
+	sse                s3types.ServerSideEncryption
+	kmsKeyID           string
+	presignExpiry      time.Duration
+	multipartThreshold int64
+	partSize           int64
 }
 
 // New creates an S3-backed Store.
@@ -73,11 +125,39 @@ func New(ctx context.Context, opts Options) (*Store, error) {
 	if opts.Bucket == "" {
 		return nil, fmt.Errorf("s3 filestore: bucket is required")
 	}
+	sse := s3types.ServerSideEncryption(opts.ServerSideEncryption)
+	switch sse {
+	case "", s3types.ServerSideEncryptionAes256, s3types.ServerSideEncryptionAwsKms:
+	default:
+		return nil, fmt.Errorf("s3 filestore: unsupported server-side encryption %q", opts.ServerSideEncryption)
+	}
+	if opts.KMSKeyID != "" && sse != s3types.ServerSideEncryptionAwsKms {
+		return nil, fmt.Errorf("s3 filestore: kms key id requires aws:kms server-side encryption")
+	}
+	if opts.PresignExpiry < 0 || opts.PresignExpiry > maxPresignExpiry {
+		return nil, fmt.Errorf("s3 filestore: presign expiry must be at most %s", maxPresignExpiry)
+	}
+	if opts.PresignExpiry == 0 {
+		opts.PresignExpiry = defaultPresignExpiry
+	}
+	if opts.MultipartThreshold <= 0 {
+		opts.MultipartThreshold = defaultMultipartThreshold
+	}
+	if opts.PartSize <= 0 {
+		opts.PartSize = defaultPartSize
+	}
+	if opts.PartSize < minPartSize {
+		return nil, fmt.Errorf("s3 filestore: part size must be at least %d bytes", minPartSize)
+	}
 
 	optFns := []func(*awsconfig.LoadOptions) error{}
 	if opts.Region != "" {
 		optFns = append(optFns, awsconfig.WithRegion(opts.Region))
 	}
+	if opts.AccessKeyID != "" {
+		optFns = append(optFns, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, "")))
+	}
 
 	cfg, err := awsconfig.LoadDefaultConfig(ctx, optFns...)
 	if err != nil {
@@ -95,9 +175,15 @@ func New(ctx context.Context, opts Options) (*Store, error) {
 	client := s3.NewFromConfig(cfg, s3Opts...)
 
 	return &Store{
-		client: client,
-		bucket: opts.Bucket,
-		prefix: opts.Prefix,
+		client:             client,
+		presign:            s3.NewPresignClient(client),
+		bucket:             opts.Bucket,
+		prefix:             opts.Prefix,
+		sse:                sse,
+		kmsKeyID:           opts.KMSKeyID,
+		presignExpiry:      opts.PresignExpiry,
+		multipartThreshold: opts.MultipartThreshold,
+		partSize:           opts.PartSize,
 	}, nil
 }
 
@@ -118,6 +204,7 @@ func (s *Store) CreateFile(ctx context.Context, file *filestore.File) error {
 		MimeType:  file.MimeType,
 		Bytes:     file.Bytes,
 		Status:    file.Status,
+		Tenant:    file.Tenant,
 		CreatedAt: file.CreatedAt,
 	}
 	metaBytes, err := json.Marshal(meta)
@@ -126,30 +213,115 @@ func (s *Store) CreateFile(ctx context.Context, file *filestore.File) error {
 	}
 
 	// Upload content
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(s.contentKey(file.ID)),
-		Body:        bytes.NewReader(file.Content),
-		ContentType: aws.String(file.MimeType),
-	})
-	if err != nil {
+	if err := s.putObject(ctx, s.contentKey(file.ID), file.Content, file.MimeType); err != nil {
 		return fmt.Errorf("put content: %w", err)
 	}
 
 	// Upload metadata
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(s.metadataKey(file.ID)),
-		Body:        bytes.NewReader(metaBytes),
-		ContentType: aws.String("application/json"),
+	if err := s.putObject(ctx, s.metadataKey(file.ID), metaBytes, "application/json"); err != nil {
+		return fmt.Errorf("put metadata: %w", err)
+	}
+
+	return nil
+}
+
+// putObject uploads an object with the configured encryption, in parts
+// when it is larger than the multipart threshold.
+func (s *Store) putObject(ctx context.Context, key string, data []byte, contentType string) error {
+	var kmsKeyID *string
+	if s.kmsKeyID != "" {
+		kmsKeyID = aws.String(s.kmsKeyID)
+	}
+	if int64(len(data)) <= s.multipartThreshold {
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:               aws.String(s.bucket),
+			Key:                  aws.String(key),
+			Body:                 bytes.NewReader(data),
+			ContentType:          aws.String(contentType),
+			ServerSideEncryption: s.sse,
+			SSEKMSKeyId:          kmsKeyID,
+		})
+		return err
+	}
+
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		ContentType:          aws.String(contentType),
+		ServerSideEncryption: s.sse,
+		SSEKMSKeyId:          kmsKeyID,
 	})
 	if err != nil {
-		return fmt.Errorf("put metadata: %w", err)
+		return fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	var parts []s3types.CompletedPart
+	for offset := int64(0); offset < int64(len(data)); offset += s.partSize {
+		end := min(offset+s.partSize, int64(len(data)))
+		partNumber := aws.Int32(int32(len(parts) + 1))
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(key),
+			UploadId:   upload.UploadId,
+			PartNumber: partNumber,
+			Body:       bytes.NewReader(data[offset:end]),
+		})
+		if err != nil {
+			s.abortUpload(ctx, key, upload.UploadId)
+			return fmt.Errorf("upload part %d: %w", *partNumber, err)
+		}
+		parts = append(parts, s3types.CompletedPart{ETag: out.ETag, PartNumber: partNumber})
 	}
 
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s.abortUpload(ctx, key, upload.UploadId)
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
 	return nil
 }
 
+// abortUpload discards the parts of a failed multipart upload, so they do
+// not linger and get billed. It runs even if ctx was canceled.
+func (s *Store) abortUpload(ctx context.Context, key string, uploadID *string) {
+	_, _ = s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+}
+
+// PresignContentURL implements filestore.URLSigner: it returns a URL from
+// which the file's content can be downloaded directly from S3, as an
+// attachment named after the file. expires of zero uses the configured
+// default.
+func (s *Store) PresignContentURL(ctx context.Context, file *filestore.File, expires time.Duration) (string, time.Time, error) {
+	if expires <= 0 {
+		expires = s.presignExpiry
+	}
+	if expires > maxPresignExpiry {
+		return "", time.Time{}, fmt.Errorf("presign expiry must be at most %s", maxPresignExpiry)
+	}
+	input := &s3.GetObjectInput{
+		Bucket:                     aws.String(s.bucket),
+		Key:                        aws.String(s.contentKey(file.ID)),
+		ResponseContentDisposition: aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename})),
+	}
+	if file.MimeType != "" {
+		input.ResponseContentType = aws.String(file.MimeType)
+	}
+	req, err := s.presign.PresignGetObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("presign content: %w", err)
+	}
+	return req.URL, time.Now().Add(expires), nil
+}
+
 // GetFile returns file metadata (Content is nil).
 func (s *Store) GetFile(ctx context.Context, fileID string) (*filestore.File, error) {
 	meta, err := s.readMetadata(ctx, fileID)
@@ -164,6 +336,7 @@ func (s *Store) GetFile(ctx context.Context, fileID string) (*filestore.File, er
 		MimeType:  meta.MimeType,
 		Bytes:     meta.Bytes,
 		Status:    meta.Status,
+		Tenant:    meta.Tenant,
 		CreatedAt: meta.CreatedAt,
 	}, nil
 }
@@ -219,6 +392,25 @@ func (s *Store) ListFilesPaginated(ctx context.Context, after, before string, li
 		limit = 50
 	}
 
+	allFiles, err := s.listFiles(ctx, purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	page, hasMore := filestore.Paginate(allFiles, after, before, limit, order)
+	return page, hasMore, nil
+}
+
+// CountFiles returns the number of files, optionally only those with the given purpose.
+func (s *Store) CountFiles(ctx context.Context, purpose string) (int, error) {
+	files, err := s.listFiles(ctx, purpose)
+	if err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
+// listFiles fetches the metadata of every stored file with the given purpose.
+func (s *Store) listFiles(ctx context.Context, purpose string) ([]*filestore.File, error) {
 	// List "directories" under prefix using delimiter
 	delimiter := "/"
 	var allFileIDs []string
@@ -232,7 +424,7 @@ func (s *Store) ListFilesPaginated(ctx context.Context, after, before string, li
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, false, fmt.Errorf("list objects: %w", err)
+			return nil, fmt.Errorf("list objects: %w", err)
 		}
 		for _, cp := range page.CommonPrefixes {
 			// Extract file ID from prefix: "<prefix><file_id>/"
@@ -284,6 +476,7 @@ func (s *Store) ListFilesPaginated(ctx context.Context, after, before string, li
 				MimeType:  meta.MimeType,
 				Bytes:     meta.Bytes,
 				Status:    meta.Status,
+				Tenant:    meta.Tenant,
 				CreatedAt: meta.CreatedAt,
 			}
 
@@ -295,43 +488,10 @@ func (s *Store) ListFilesPaginated(ctx context.Context, after, before string, li
 	wg.Wait()
 
 	if fetchErr != nil {
-		return nil, false, fetchErr
+		return nil, fetchErr
 	}
 
-	// Sort by CreatedAt
-	sort.Slice(allFiles, func(i, j int) bool {
-		if order == "desc" {
-			return allFiles[i].CreatedAt.After(allFiles[j].CreatedAt)
-		}
-		return allFiles[i].CreatedAt.Before(allFiles[j].CreatedAt)
-	})
-
-	// Apply cursor-based pagination
-	var filtered []*filestore.File
-	foundAfter := after == ""
-
-	for _, file := range allFiles {
-		if after != "" && !foundAfter {
-			if file.ID == after {
-				foundAfter = true
-			}
-			continue
-		}
-
-		if before != "" && file.ID == before {
-			break
-		}
-
-		filtered = append(filtered, file)
-
-		if len(filtered) >= limit {
-			break
-		}
-	}
-
-	hasMore := len(allFiles) > len(filtered) && len(filtered) == limit
-
-	return filtered, hasMore, nil
+	return allFiles, nil
 }
 
 // Close is a no-op for the S3 store.
@@ -339,6 +499,15 @@ func (s *Store) Close(_ context.Context) error {
 	return nil
 }
 
+// Ping checks that the bucket exists and is accessible.
+func (s *Store) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err != nil {
+		return fmt.Errorf("head bucket %s: %w", s.bucket, err)
+	}
+	return nil
+}
+
 // readMetadata fetches and unmarshals metadata.json from S3.
 func (s *Store) readMetadata(ctx context.Context, fileID string) (*fileMetadata, error) {
 	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
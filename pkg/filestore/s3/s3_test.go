@@ -4,9 +4,18 @@
 package s3_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/leseb/openresponses-gw/pkg/filestore"
 	"github.com/leseb/openresponses-gw/pkg/filestore/filestoretest"
@@ -38,3 +47,160 @@ func TestS3Conformance(t *testing.T) {
 		return store
 	})
 }
+
+func TestPresignContentURL(t *testing.T) {
+	store, err := fss3.New(context.Background(), fss3.Options{
+		Bucket:          "files",
+		Region:          "us-east-1",
+		Prefix:          "p/",
+		Endpoint:        "http://localhost:9000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("s3.New: %v", err)
+	}
+	file := &filestore.File{ID: "file_1", Filename: "report.pdf", MimeType: "application/pdf"}
+
+	tests := []struct {
+		name        string
+		expires     time.Duration
+		wantExpires string
+	}{
+		{"default", 0, "X-Amz-Expires=900"},
+		{"explicit", time.Hour, "X-Amz-Expires=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawURL, expiresAt, err := store.PresignContentURL(context.Background(), file, tt.expires)
+			if err != nil {
+				t.Fatalf("PresignContentURL: %v", err)
+			}
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				t.Fatalf("parse %q: %v", rawURL, err)
+			}
+			if u.Path != "/files/p/file_1/content" {
+				t.Errorf("path = %q", u.Path)
+			}
+			if !strings.Contains(rawURL, tt.wantExpires) {
+				t.Errorf("URL %q does not contain %s", rawURL, tt.wantExpires)
+			}
+			if got := u.Query().Get("response-content-disposition"); got != "attachment; filename=report.pdf" {
+				t.Errorf("content disposition = %q", got)
+			}
+			if time.Until(expiresAt) <= 0 {
+				t.Errorf("expiresAt %v is in the past", expiresAt)
+			}
+		})
+	}
+
+	if _, _, err := store.PresignContentURL(context.Background(), file, 8*24*time.Hour); err == nil {
+		t.Error("expected error for expiry beyond 7 days")
+	}
+}
+
+func TestNewOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts fss3.Options
+	}{
+		{"unknown encryption", fss3.Options{ServerSideEncryption: "rot13"}},
+		{"kms key without kms", fss3.Options{ServerSideEncryption: "AES256", KMSKeyID: "k"}},
+		{"part too small", fss3.Options{PartSize: 1 << 20}},
+		{"expiry too long", fss3.Options{PresignExpiry: 8 * 24 * time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Bucket = "files"
+			tt.opts.Region = "us-east-1"
+			if _, err := fss3.New(context.Background(), tt.opts); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// fakeS3 records the requests of a store and answers them like S3 would,
+// for single and multipart uploads.
+type fakeS3 struct {
+	mu       sync.Mutex
+	requests []string // "METHOD path?query"
+	headers  []http.Header
+	parts    int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+	f.headers = append(f.headers, r.Header.Clone())
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>files</Bucket><Key>k</Key><UploadId>up1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		f.parts++
+		w.Header().Set("ETag", fmt.Sprintf(`"etag%s"`, q.Get("partNumber")))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"final"</ETag></CompleteMultipartUploadResult>`)
+	default:
+		w.Header().Set("ETag", `"single"`)
+	}
+	f.mu.Unlock()
+}
+
+func TestCreateFileMultipartAndEncryption(t *testing.T) {
+	fake := &fakeS3{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := fss3.New(context.Background(), fss3.Options{
+		Bucket:               "files",
+		Region:               "us-east-1",
+		Endpoint:             srv.URL,
+		AccessKeyID:          "key",
+		SecretAccessKey:      "secret",
+		ServerSideEncryption: "aws:kms",
+		KMSKeyID:             "alias/files",
+		MultipartThreshold:   6 << 20,
+		PartSize:             5 << 20,
+	})
+	if err != nil {
+		t.Fatalf("s3.New: %v", err)
+	}
+
+	err = store.CreateFile(context.Background(), &filestore.File{
+		ID:        "file_big",
+		Filename:  "big.bin",
+		MimeType:  "application/octet-stream",
+		Content:   bytes.Repeat([]byte("x"), 11<<20),
+		Bytes:     11 << 20,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	// Content: create, three parts, complete. Metadata: one PUT.
+	if len(fake.requests) != 6 {
+		t.Fatalf("requests = %q, want 6", fake.requests)
+	}
+	if fake.parts != 3 {
+		t.Errorf("parts = %d, want 3", fake.parts)
+	}
+	if !strings.HasPrefix(fake.requests[0], "POST /files/file_big/content?uploads") {
+		t.Errorf("first request = %q, want multipart create", fake.requests[0])
+	}
+	if !strings.HasPrefix(fake.requests[5], "PUT /files/file_big/metadata.json") {
+		t.Errorf("last request = %q, want metadata PUT", fake.requests[5])
+	}
+	for _, i := range []int{0, 5} {
+		h := fake.headers[i]
+		if h.Get("X-Amz-Server-Side-Encryption") != "aws:kms" || h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "alias/files" {
+			t.Errorf("request %q missing encryption headers: %v", fake.requests[i], h)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/services"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+)
+
+// SetGarbageCollector enables the /admin/gc endpoint. defaults supplies the
+// min age and include-files settings used when a request leaves them unset.
+func (h *Handler) SetGarbageCollector(gc *services.GarbageCollector, defaults services.GCOptions) {
+	h.gc = gc
+	h.gcDefaults = defaults
+}
+
+// handleGarbageCollect handles POST /admin/gc
+//
+//	@Summary		Collect orphaned objects
+//	@Description	Cross-reference the file store, vector store metadata and vector store backend, and report or remove orphans. Runs as a dry run unless dry_run is false.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schema.GarbageCollectRequest	false	"Garbage collection options"
+//	@Success		200		{object}	schema.GarbageCollectResponse
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Failure		501		{object}	map[string]interface{}
+//	@Router			/admin/gc [post]
+func (h *Handler) handleGarbageCollect(w http.ResponseWriter, r *http.Request) {
+	if h.gc == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Garbage collection is not configured")
+		return
+	}
+
+	var req schema.GarbageCollectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+
+	opts := h.gcDefaults
+	opts.DryRun = true
+	if req.DryRun != nil {
+		opts.DryRun = *req.DryRun
+	}
+	if req.MinAgeSeconds != nil {
+		if *req.MinAgeSeconds < 0 {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "min_age_seconds must not be negative")
+			return
+		}
+		opts.MinAge = time.Duration(*req.MinAgeSeconds) * time.Second
+	}
+	if req.IncludeFiles != nil {
+		opts.IncludeFiles = *req.IncludeFiles
+	}
+
+	report, err := h.gc.Run(r.Context(), opts)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	resp := schema.GarbageCollectResponse{
+		Object:  "garbage_collection",
+		DryRun:  report.DryRun,
+		Orphans: make([]schema.GarbageCollectOrphan, 0, len(report.Orphans)),
+		Found:   len(report.Orphans),
+		Removed: report.Removed(),
+	}
+	for _, o := range report.Orphans {
+		resp.Orphans = append(resp.Orphans, schema.GarbageCollectOrphan{
+			Kind:          o.Kind,
+			VectorStoreID: o.VectorStoreID,
+			FileID:        o.FileID,
+			Removed:       o.Removed,
+			Error:         o.Error,
+		})
+	}
+
+	h.logger.InfoContext(r.Context(), "Garbage collection completed", "dry_run", resp.DryRun, "found", resp.Found, "removed", resp.Removed)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetDataEraser enables the /admin/data_deletion endpoint.
+func (h *Handler) SetDataEraser(eraser *services.DataEraser) {
+	h.eraser = eraser
+}
+
+// handleDataDeletion handles POST /admin/data_deletion
+//
+//	@Summary		Delete data by tenant or metadata
+//	@Description	Delete every response, conversation (with its items), file (with its vector store chunks) and vector store created by a tenant or carrying the given metadata. Runs as a dry run unless dry_run is false. Files have no metadata, so they are only matched by tenant alone.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schema.DataDeletionRequest	true	"Data selector"
+//	@Success		200		{object}	schema.DataDeletionResponse
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Failure		501		{object}	map[string]interface{}
+//	@Router			/admin/data_deletion [post]
+func (h *Handler) handleDataDeletion(w http.ResponseWriter, r *http.Request) {
+	if h.eraser == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Data deletion is not configured")
+		return
+	}
+
+	var req schema.DataDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+	dryRun := true
+	if req.DryRun != nil {
+		dryRun = *req.DryRun
+	}
+
+	sel := services.ErasureSelector{Tenant: req.Tenant, Metadata: req.Metadata}
+	report, err := h.eraser.Erase(r.Context(), sel, dryRun)
+	if errors.Is(err, services.ErrEmptySelector) {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "tenant or metadata is required")
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	resp := schema.DataDeletionResponse{
+		Object: "data_deletion",
+		DryRun: report.DryRun,
+		Counts: schema.DataDeletionCounts{
+			Responses:     report.Count(services.ErasedResponse),
+			Conversations: report.Count(services.ErasedConversation),
+			Files:         report.Count(services.ErasedFile),
+			VectorStores:  report.Count(services.ErasedVectorStore),
+		},
+		Objects: make([]schema.DataDeletionObject, 0, len(report.Objects)),
+		Found:   len(report.Objects),
+		Removed: report.Removed(),
+	}
+	for _, o := range report.Objects {
+		resp.Counts.ConversationItems += o.Items
+		resp.Objects = append(resp.Objects, schema.DataDeletionObject{
+			Kind:           o.Kind,
+			ID:             o.ID,
+			Items:          o.Items,
+			VectorStoreIDs: o.VectorStoreIDs,
+			Removed:        o.Removed,
+			Error:          o.Error,
+		})
+	}
+
+	h.logger.InfoContext(r.Context(), "Data deletion completed", "tenant", req.Tenant, "metadata_keys", len(req.Metadata),
+		"dry_run", resp.DryRun, "found", resp.Found, "removed", resp.Removed)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetSessionReaper enables the /admin/session_retention endpoint.
+func (h *Handler) SetSessionReaper(r *services.SessionReaper) {
+	h.sessionReaper = r
+}
+
+// handleSessionRetention handles GET /admin/session_retention
+//
+//	@Summary		Get session store retention statistics
+//	@Description	Report the rows deleted by the session store reaper since the gateway started.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	schema.SessionRetentionResponse
+//	@Failure		501	{object}	map[string]interface{}
+//	@Router			/admin/session_retention [get]
+func (h *Handler) handleSessionRetention(w http.ResponseWriter, r *http.Request) {
+	if h.sessionReaper == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "The session store does not support retention")
+		return
+	}
+
+	stats := h.sessionReaper.Stats()
+	resp := schema.SessionRetentionResponse{
+		Object: "session_retention",
+		Runs:   stats.Runs,
+		Reclaimed: schema.SessionRetentionCounts{
+			Sessions:      stats.Reclaimed[state.ExpiredSessions],
+			Responses:     stats.Reclaimed[state.ExpiredResponses],
+			Conversations: stats.Reclaimed[state.ExpiredConversations],
+		},
+		LastError: stats.LastError,
+	}
+	if !stats.LastRun.IsZero() {
+		resp.LastRunAt = stats.LastRun.Unix()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetFeatureFlags enables the /admin/feature_flags endpoints. tenantHeader
+// names the request header whose value is used as the tenant when flags are
+// evaluated.
+func (h *Handler) SetFeatureFlags(f *featureflags.Flags, tenantHeader string) {
+	h.features = f
+	h.tenantHeader = tenantHeader
+}
+
+// handleListFeatureFlags handles GET /admin/feature_flags
+//
+//	@Summary	List feature flags
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{object}	schema.ListFeatureFlagsResponse
+//	@Failure	501	{object}	map[string]interface{}
+//	@Router		/admin/feature_flags [get]
+func (h *Handler) handleListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	if h.features == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Feature flags are not configured")
+		return
+	}
+
+	flags := h.features.List()
+	resp := schema.ListFeatureFlagsResponse{
+		Object: "list",
+		Data:   make([]schema.FeatureFlag, 0, len(flags)),
+	}
+	for _, f := range flags {
+		resp.Data = append(resp.Data, featureFlagToSchema(f))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleUpdateFeatureFlag handles PUT /admin/feature_flags/{name}
+//
+//	@Summary		Override feature flag
+//	@Description	Replace the rollout rule of a feature flag at runtime. The override is kept in memory until it is deleted or the gateway restarts.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string							true	"Flag name"
+//	@Param			request	body		schema.UpdateFeatureFlagRequest	true	"Rollout rule"
+//	@Success		200		{object}	schema.FeatureFlag
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Failure		501		{object}	map[string]interface{}
+//	@Router			/admin/feature_flags/{name} [put]
+func (h *Handler) handleUpdateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	if h.features == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Feature flags are not configured")
+		return
+	}
+	name := r.PathValue("name")
+
+	var req schema.UpdateFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+
+	rule := featureflags.Rule{Enabled: req.Enabled, Percentage: req.Percentage, Tenants: req.Tenants}
+	if err := h.features.Override(name, rule); err != nil {
+		h.writeFeatureFlagError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Feature flag overridden", "flag", name, "enabled", rule.Enabled, "percentage", rule.Percentage, "tenants", rule.Tenants)
+
+	h.writeFeatureFlag(w, name)
+}
+
+// handleResetFeatureFlag handles DELETE /admin/feature_flags/{name}
+//
+//	@Summary		Remove feature flag override
+//	@Description	Remove the runtime override of a feature flag, restoring the configured rollout rule.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			name	path		string	true	"Flag name"
+//	@Success		200		{object}	schema.FeatureFlag
+//	@Failure		404		{object}	map[string]interface{}
+//	@Failure		501		{object}	map[string]interface{}
+//	@Router			/admin/feature_flags/{name} [delete]
+func (h *Handler) handleResetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	if h.features == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Feature flags are not configured")
+		return
+	}
+	name := r.PathValue("name")
+
+	if err := h.features.Reset(name); err != nil {
+		h.writeFeatureFlagError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Feature flag override removed", "flag", name)
+
+	h.writeFeatureFlag(w, name)
+}
+
+func (h *Handler) writeFeatureFlag(w http.ResponseWriter, name string) {
+	f, err := h.features.Get(name)
+	if err != nil {
+		h.writeFeatureFlagError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(featureFlagToSchema(f))
+}
+
+func (h *Handler) writeFeatureFlagError(w http.ResponseWriter, err error) {
+	var unknown *featureflags.UnknownFlagError
+	if errors.As(err, &unknown) {
+		h.writeError(w, http.StatusNotFound, "feature_flag_not_found", err.Error())
+		return
+	}
+	h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+}
+
+func featureFlagToSchema(f featureflags.Flag) schema.FeatureFlag {
+	tenants := f.Rule.Tenants
+	if tenants == nil {
+		tenants = []string{}
+	}
+	return schema.FeatureFlag{
+		Object:     "feature_flag",
+		Name:       f.Name,
+		Enabled:    f.Rule.Enabled,
+		Percentage: f.Rule.Percentage,
+		Tenants:    tenants,
+		Overridden: f.Overridden,
+	}
+}
+
+// handleCompactConversation handles POST /admin/conversations/{id}/compact
+//
+//	@Summary		Compact conversation items
+//	@Description	Merge consecutive assistant text fragments, drop empty items and normalize legacy item formats. Runs as a dry run unless dry_run is false. IDs of merged and dropped items are removed.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string								true	"Conversation ID"
+//	@Param			request	body		schema.CompactConversationRequest	false	"Compaction options"
+//	@Success		200		{object}	schema.CompactConversationResponse
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Router			/admin/conversations/{id}/compact [post]
+func (h *Handler) handleCompactConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID := r.PathValue("id")
+	if conversationID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Conversation ID is required")
+		return
+	}
+
+	var req schema.CompactConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+	dryRun := true
+	if req.DryRun != nil {
+		dryRun = *req.DryRun
+	}
+
+	conv, err := h.engine.Store().GetConversation(r.Context(), conversationID)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
+		return
+	}
+
+	report, err := services.NewConversationCompactor(h.engine.Store()).Compact(r.Context(), conv, dryRun)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	resp := schema.CompactConversationResponse{
+		Object:         "conversation.compaction",
+		ConversationID: report.ConversationID,
+		DryRun:         report.DryRun,
+		ItemsBefore:    report.ItemsBefore,
+		ItemsAfter:     report.ItemsAfter,
+		Changes:        make([]schema.ConversationItemChange, 0, len(report.Changes)),
+	}
+	for _, c := range report.Changes {
+		resp.Changes = append(resp.Changes, schema.ConversationItemChange{
+			ItemID: c.ItemID,
+			Action: c.Action,
+			Into:   c.Into,
+		})
+	}
+
+	h.logger.InfoContext(r.Context(), "Conversation compacted", "conversation_id", conversationID, "dry_run", dryRun,
+		"items_before", resp.ItemsBefore, "items_after", resp.ItemsAfter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleStreamStats handles GET /admin/streams
+//
+//	@Summary		Get streaming backpressure statistics
+//	@Description	Report how often streamed responses waited for slow clients, and the deltas merged or dropped by the overflow policy, since the gateway started.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	schema.StreamStatsResponse
+//	@Router			/admin/streams [get]
+func (h *Handler) handleStreamStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.engine.StreamStats())
+}
+
+// handleListModelAliases handles GET /admin/model_aliases
+//
+//	@Summary	List model aliases
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{object}	schema.ListModelAliasesResponse
+//	@Router		/admin/model_aliases [get]
+func (h *Handler) handleListModelAliases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.ListModelAliasesResponse{
+		Object: "list",
+		Data:   h.engine.ModelAliases(),
+	})
+}
+
+// handleUpdateModelAlias handles PUT /admin/model_aliases/{alias}
+//
+//	@Summary		Create or repoint model alias
+//	@Description	Point a model alias to a backend model at runtime. The change is kept in memory until the alias is deleted or the gateway restarts, which restores the configured aliases.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			alias	path		string							true	"Alias"
+//	@Param			request	body		schema.UpdateModelAliasRequest	true	"Target model"
+//	@Success		200		{object}	schema.ModelAlias
+//	@Failure		400		{object}	map[string]interface{}
+//	@Router			/admin/model_aliases/{alias} [put]
+func (h *Handler) handleUpdateModelAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+
+	var req schema.UpdateModelAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+	if err := h.engine.SetModelAlias(alias, req.Model); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Model alias updated", "alias", alias, "model", req.Model)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.ModelAlias{Object: "model_alias", Alias: alias, Model: req.Model})
+}
+
+// handleDeleteModelAlias handles DELETE /admin/model_aliases/{alias}
+//
+//	@Summary	Delete model alias
+//	@Tags		Admin
+//	@Produce	json
+//	@Param		alias	path		string	true	"Alias"
+//	@Success	200		{object}	schema.DeleteModelAliasResponse
+//	@Failure	404		{object}	map[string]interface{}
+//	@Router		/admin/model_aliases/{alias} [delete]
+func (h *Handler) handleDeleteModelAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+
+	if err := h.engine.DeleteModelAlias(alias); err != nil {
+		if errors.Is(err, engine.ErrModelAliasNotFound) {
+			h.writeError(w, http.StatusNotFound, "model_alias_not_found", err.Error())
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Model alias deleted", "alias", alias)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.DeleteModelAliasResponse{Alias: alias, Object: "model_alias.deleted", Deleted: true})
+}
@@ -0,0 +1,188 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+//go:embed admin_ui/index.html
+var adminUIHTML []byte
+
+const (
+	// adminEventsBuffer is the number of live events queued per dashboard
+	// before they are dropped.
+	adminEventsBuffer = 256
+	// adminEventsKeepAlive is the interval of SSE comments keeping idle
+	// event streams open through proxies.
+	adminEventsKeepAlive = 15 * time.Second
+)
+
+// adminUI holds what the admin dashboard reports about the gateway itself.
+type adminUI struct {
+	version   string
+	startedAt time.Time
+	config    schema.AdminConfigResponse
+}
+
+// EnableAdminUI serves the admin dashboard at /admin, with the read-only
+// JSON endpoints behind it: /admin/overview, /admin/config and the live
+// event stream /admin/events. The dashboard lists recent responses,
+// conversations, vector stores and files through the public API. version
+// and config are reported as is; config must not hold credentials. Like the
+// other /admin endpoints, the dashboard has no access control of its own.
+func (h *Handler) EnableAdminUI(version string, config schema.AdminConfigResponse) {
+	config.Object = "admin_config"
+	h.adminUI = &adminUI{version: version, startedAt: time.Now(), config: config}
+}
+
+// handleAdminUI serves the dashboard page, or 404 when it is disabled.
+func (h *Handler) handleAdminUI(w http.ResponseWriter, r *http.Request) {
+	if h.adminUI == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(adminUIHTML)
+}
+
+// handleAdminOverview handles GET /admin/overview
+//
+//	@Summary		Get gateway overview
+//	@Description	Report the gateway version and start time, the number of stored responses, conversations, files and vector stores, and streaming statistics. Served when the admin dashboard is enabled.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	schema.AdminOverviewResponse
+//	@Failure		404	{object}	map[string]interface{}
+//	@Failure		500	{object}	map[string]interface{}
+//	@Router			/admin/overview [get]
+func (h *Handler) handleAdminOverview(w http.ResponseWriter, r *http.Request) {
+	if h.adminUI == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	var counts schema.AdminOverviewCounts
+	var err error
+	if counts.Responses, err = h.engine.CountResponses(ctx, state.ResponseFilter{}); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to count responses", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		return
+	}
+	if counts.Conversations, err = h.engine.Store().CountConversations(ctx); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to count conversations", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
+		return
+	}
+	if counts.Files, err = h.filesStore.CountFiles(ctx, ""); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to count files", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
+		return
+	}
+	if counts.VectorStores, err = h.vectorStoresStore.CountVectorStores(ctx); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to count vector stores", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.AdminOverviewResponse{
+		Object:    "admin_overview",
+		Version:   h.adminUI.version,
+		StartedAt: h.adminUI.startedAt.Unix(),
+		Counts:    counts,
+		Streams:   h.engine.StreamStats(),
+	})
+}
+
+// handleAdminConfig handles GET /admin/config
+//
+//	@Summary		Get configuration summary
+//	@Description	Report the backends, providers and optional features the gateway runs with. Credentials and endpoint paths are left out. Served when the admin dashboard is enabled.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	schema.AdminConfigResponse
+//	@Failure		404	{object}	map[string]interface{}
+//	@Router			/admin/config [get]
+func (h *Handler) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if h.adminUI == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.adminUI.config)
+}
+
+// handleAdminEvents handles GET /admin/events
+//
+//	@Summary		Stream response events
+//	@Description	Stream the lifecycle events of responses (response.created, response.completed, response.incomplete, response.failed, response.usage) as server-sent events while they happen. Events are dropped for clients that fall behind. The stream ends when the gateway shuts down. Served when the admin dashboard is enabled.
+//	@Tags			Admin
+//	@Produce		text/event-stream
+//	@Success		200	{string}	string	"Server-sent events"
+//	@Failure		404	{object}	map[string]interface{}
+//	@Router			/admin/events [get]
+func (h *Handler) handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	if h.adminUI == nil {
+		http.NotFound(w, r)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, http.StatusInternalServerError, "streaming_not_supported", "Streaming not supported")
+		return
+	}
+
+	// The stream outlives the server write timeout; when it cannot be
+	// lifted, the browser reconnects once the stream is cut
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	events, unsubscribe := h.engine.SubscribeActivity(adminEventsBuffer)
+	defer unsubscribe()
+	keepAlive := time.NewTicker(adminEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-h.drain.done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/adapters/chatcompletions"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+)
+
+// auditRoute describes the operation a mutating route performs.
+type auditRoute struct {
+	action       string // "create", "update" or "delete"
+	resourceType string
+	idParam      string // path value holding the resource ID; empty to read it from the response body
+}
+
+// auditRoutes describes the operations of the mutating routes. Every route
+// but GET, HEAD and those in auditExempt is audited; one missing here is
+// recorded as the operation routeAudit derives from its pattern.
+var auditRoutes = map[string]auditRoute{
+	"POST /responses":                                            {"create", "response", ""},
+	"POST /v1/responses":                                         {"create", "response", ""},
+	"DELETE /v1/responses/{id}":                                  {"delete", "response", "id"},
+	"POST /v1/responses/{id}/replay":                             {"create", "response", ""},
+	"POST " + chatcompletions.Path:                               {"create", "response", ""},
+	"POST /v1/conversations":                                     {"create", "conversation", ""},
+	"DELETE /v1/conversations/{id}":                              {"delete", "conversation", "id"},
+	"POST /v1/conversations/{id}/items":                          {"update", "conversation", "id"},
+	"POST /v1/conversations/import":                              {"create", "conversation", ""},
+	"POST /v1/conversations/{id}/fork":                           {"create", "conversation", ""},
+	"POST /v1/prompts":                                           {"create", "prompt", ""},
+	"PUT /v1/prompts/{id}":                                       {"update", "prompt", "id"},
+	"DELETE /v1/prompts/{id}":                                    {"delete", "prompt", "id"},
+	"POST /v1/prompts/{id}/default_version":                      {"update", "prompt", "id"},
+	"POST /v1/files":                                             {"create", "file", ""},
+	"DELETE /v1/files/{id}":                                      {"delete", "file", "id"},
+	"POST /v1/vector_stores":                                     {"create", "vector_store", ""},
+	"PUT /v1/vector_stores/{id}":                                 {"update", "vector_store", "id"},
+	"DELETE /v1/vector_stores/{id}":                              {"delete", "vector_store", "id"},
+	"POST /v1/vector_stores/{id}/reindex":                        {"update", "vector_store", "id"},
+	"POST /v1/vector_stores/{id}/files":                          {"create", "vector_store_file", ""},
+	"POST /v1/vector_stores/{id}/upload":                         {"create", "vector_store_file", ""},
+	"DELETE /v1/vector_stores/{id}/files/{file_id}":              {"delete", "vector_store_file", "file_id"},
+	"POST /v1/vector_stores/{id}/file_batches":                   {"create", "vector_store_file_batch", ""},
+	"POST /v1/vector_stores/{id}/file_batches/{batch_id}/cancel": {"update", "vector_store_file_batch", "batch_id"},
+	"POST /v1/connectors":                                        {"create", "connector", ""},
+	"DELETE /v1/connectors/{connector_id}":                       {"delete", "connector", "connector_id"},
+	"POST /v1/evals/run":                                         {"create", "eval", ""},
+	"PUT /admin/feature_flags/{name}":                            {"update", "feature_flag", "name"},
+	"DELETE /admin/feature_flags/{name}":                         {"delete", "feature_flag", "name"},
+	"POST /admin/conversations/{id}/compact":                     {"update", "conversation", "id"},
+	"POST /admin/gc":                                             {"delete", "orphaned_object", ""},
+	"POST /admin/data_deletion":                                  {"delete", "data", ""},
+	"PUT /admin/model_aliases/{alias}":                           {"update", "model_alias", "alias"},
+	"DELETE /admin/model_aliases/{alias}":                        {"delete", "model_alias", "alias"},
+}
+
+// auditExempt lists the routes that use POST without changing anything, and
+// so are not audited.
+var auditExempt = map[string]bool{
+	"POST /v1/vector_stores/{id}/search": true,
+}
+
+// routeAudit returns the operation the route pattern performs, and whether
+// it is audited. An unlisted mutating route is recorded as the action of its
+// method on the resource named by its first path segment, identified by its
+// {id} path value.
+func routeAudit(pattern string) (auditRoute, bool) {
+	if route, ok := auditRoutes[pattern]; ok {
+		return route, true
+	}
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || method == http.MethodGet || method == http.MethodHead || auditExempt[pattern] {
+		return auditRoute{}, false
+	}
+
+	route := auditRoute{action: "update"}
+	switch method {
+	case http.MethodPost:
+		route.action = "create"
+	case http.MethodDelete:
+		route.action = "delete"
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for len(segments) > 1 && (segments[0] == "v1" || segments[0] == "admin") {
+		segments = segments[1:]
+	}
+	route.resourceType = strings.TrimSuffix(segments[0], "s")
+	if strings.Contains(path, "{id}") {
+		route.idParam = "id"
+	}
+	return route, true
+}
+
+// auditBodyLimit bounds how much of a create response is kept to find the
+// ID of the created resource.
+const auditBodyLimit = 16 << 10
+
+// SetAuditLog enables auditing: every create, update and delete made
+// through the API is recorded in log, and /v1/admin/audit_logs serves it.
+func (h *Handler) SetAuditLog(log state.AuditLog) {
+	h.audit = log
+}
+
+// serveAudited serves a request and records it in the audit log if its
+// route is audited (see routeAudit).
+func (h *Handler) serveAudited(w http.ResponseWriter, r *http.Request) {
+	aw := &auditWriter{ResponseWriter: w}
+	h.mux.ServeHTTP(aw, r)
+
+	// The mux sets the matched pattern on the request it serves
+	route, ok := routeAudit(r.Pattern)
+	if !ok {
+		return
+	}
+	event := &state.AuditEvent{
+		ID:           h.engine.NewID("audit_"),
+		CreatedAt:    time.Now(),
+		Actor:        apiKeyFingerprint(r),
+		Tenant:       featureflags.TenantFromContext(r.Context()),
+		RemoteAddr:   r.RemoteAddr,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Action:       route.action,
+		ResourceType: route.resourceType,
+		Status:       aw.status,
+	}
+	if event.Status == 0 {
+		event.Status = http.StatusOK
+	}
+	if route.idParam != "" {
+		event.ResourceID = r.PathValue(route.idParam)
+	} else if event.Status < 300 {
+		event.ResourceID = createdID(aw.body.Bytes())
+	}
+
+	if err := h.audit.AppendAuditEvent(context.WithoutCancel(r.Context()), event); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to record audit event", "error", err,
+			"action", event.Action, "resource_type", event.ResourceType, "resource_id", event.ResourceID)
+	}
+}
+
+// apiKeyFingerprint identifies the API key of a request without revealing
+// it: "key_" followed by the start of its SHA-256 hash.
+func apiKeyFingerprint(r *http.Request) string {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return state.APIKeyFingerprint(key)
+}
+
+// createdID returns the ID of the resource a create request made, read from
+// the start of its response: the top-level "id" (or "connector_id", the
+// imported conversation's ID, or the replayed response's ID) of a JSON
+// body, or the response ID of the first event of a stream (or first chunk
+// of a chat completion stream).
+func createdID(body []byte) string {
+	if bytes.HasPrefix(body, []byte("event: ")) {
+		_, data, ok := bytes.Cut(body, []byte("\ndata: "))
+		if !ok {
+			return ""
+		}
+		return jsonField(data, "response", "id")
+	}
+	if data, ok := bytes.CutPrefix(body, []byte("data: ")); ok {
+		return jsonField(data, "id")
+	}
+	if id := jsonField(body, "id"); id != "" {
+		return id
+	}
+	if id := jsonField(body, "connector_id"); id != "" {
+		return id
+	}
+	if id := jsonField(body, "conversation", "id"); id != "" {
+		return id
+	}
+	return jsonField(body, "response", "id")
+}
+
+// jsonField returns the string at path in a JSON object, reading no further
+// than needed, so a truncated document still yields the fields at its start.
+func jsonField(data []byte, path ...string) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for _, key := range path {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return ""
+		}
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return ""
+			}
+			name, ok := tok.(string)
+			if !ok {
+				return "" // end of the object
+			}
+			if name == key {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return ""
+			}
+		}
+	}
+	var v string
+	if err := dec.Decode(&v); err != nil {
+		return ""
+	}
+	return v
+}
+
+// auditWriter records the status and the start of the body of a response.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (aw *auditWriter) WriteHeader(status int) {
+	if aw.status == 0 && status >= http.StatusOK {
+		aw.status = status
+	}
+	aw.ResponseWriter.WriteHeader(status)
+}
+
+func (aw *auditWriter) Write(p []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	if n := auditBodyLimit - aw.body.Len(); n > 0 {
+		aw.body.Write(p[:min(n, len(p))])
+	}
+	return aw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, for streamed responses.
+func (aw *auditWriter) Flush() {
+	http.NewResponseController(aw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (aw *auditWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+// handleListAuditLogs handles GET /v1/admin/audit_logs
+//
+//	@Summary		List audit logs
+//	@Description	List the create, update and delete operations made through the API, newest first.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			after			query		string	false	"Cursor for pagination: ID of the last entry of the previous page"
+//	@Param			limit			query		int		false	"Number of entries (1-100, default 20)"
+//	@Param			actor			query		string	false	"Filter by API key fingerprint"
+//	@Param			tenant			query		string	false	"Filter by tenant"
+//	@Param			action			query		string	false	"Filter by action: create, update or delete"
+//	@Param			resource_type	query		string	false	"Filter by resource type"
+//	@Param			resource_id		query		string	false	"Filter by resource ID"
+//	@Param			created_after	query		int		false	"Only entries recorded after this Unix timestamp"
+//	@Param			created_before	query		int		false	"Only entries recorded before this Unix timestamp"
+//	@Success		200				{object}	schema.ListAuditLogsResponse
+//	@Failure		400				{object}	map[string]interface{}
+//	@Failure		500				{object}	map[string]interface{}
+//	@Failure		501				{object}	map[string]interface{}
+//	@Router			/v1/admin/audit_logs [get]
+func (h *Handler) handleListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Audit logging is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := state.AuditFilter{
+		Actor:        query.Get("actor"),
+		Tenant:       query.Get("tenant"),
+		Action:       query.Get("action"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+	}
+	if err := parseCreatedBounds(query, &filter.CreatedAfter, &filter.CreatedBefore); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	limit := 20
+	if parsedLimit, err := parseInt(query.Get("limit")); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+		limit = parsedLimit
+	}
+
+	events, hasMore, err := h.audit.ListAuditEvents(r.Context(), filter, query.Get("after"), limit)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list audit logs", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		return
+	}
+
+	resp := schema.ListAuditLogsResponse{
+		Object:  "list",
+		Data:    make([]schema.AuditLogEntry, 0, len(events)),
+		HasMore: hasMore,
+	}
+	for _, e := range events {
+		resp.Data = append(resp.Data, schema.AuditLogEntry{
+			ID:           e.ID,
+			Object:       "audit_log",
+			CreatedAt:    e.CreatedAt.Unix(),
+			Actor:        e.Actor,
+			Tenant:       e.Tenant,
+			RemoteAddr:   e.RemoteAddr,
+			Method:       e.Method,
+			Path:         e.Path,
+			Action:       e.Action,
+			ResourceType: e.ResourceType,
+			ResourceID:   e.ResourceID,
+			Status:       e.Status,
+		})
+	}
+	if len(resp.Data) > 0 {
+		resp.FirstID = resp.Data[0].ID
+		resp.LastID = resp.Data[len(resp.Data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,59 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestAuditRoutes checks that every mutating route is either described in
+// auditRoutes or exempt, so that none is audited under a derived operation.
+func TestAuditRoutes(t *testing.T) {
+	h := New(nil, nil, nil, nil, nil, nil, nil)
+	registered := make(map[string]bool)
+	for _, pattern := range h.Routes() {
+		registered[pattern] = true
+		method, _, ok := strings.Cut(pattern, " ")
+		if ok && (method == http.MethodGet || method == http.MethodHead) {
+			continue
+		}
+		_, audited := auditRoutes[pattern]
+		if audited == auditExempt[pattern] {
+			t.Errorf("route %q must be in exactly one of auditRoutes and auditExempt", pattern)
+		}
+	}
+	for pattern := range auditRoutes {
+		if !registered[pattern] {
+			t.Errorf("auditRoutes lists unregistered route %q", pattern)
+		}
+	}
+	for pattern := range auditExempt {
+		if !registered[pattern] {
+			t.Errorf("auditExempt lists unregistered route %q", pattern)
+		}
+	}
+}
+
+func TestRouteAudit(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    auditRoute
+		audited bool
+	}{
+		{"DELETE /v1/files/{id}", auditRoute{"delete", "file", "id"}, true},
+		{"GET /v1/files/{id}", auditRoute{}, false},
+		{"POST /v1/vector_stores/{id}/search", auditRoute{}, false},
+		{"POST /v1/widgets", auditRoute{"create", "widget", ""}, true},
+		{"PATCH /admin/widgets/{id}", auditRoute{"update", "widget", "id"}, true},
+		{"DELETE /v1/admin/widgets/{id}/parts", auditRoute{"delete", "widget", "id"}, true},
+	}
+	for _, tt := range tests {
+		got, audited := routeAudit(tt.pattern)
+		if got != tt.want || audited != tt.audited {
+			t.Errorf("routeAudit(%q) = %+v, %v, want %+v, %v", tt.pattern, got, audited, tt.want, tt.audited)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/adapters/chatcompletions"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// handleChatCompletions handles /v1/chat/completions requests
+//
+//	@Summary		Create chat completion
+//	@Description	OpenAI Chat Completions compatible endpoint. The request is run as a response, so it can use conversations, MCP and file_search tools; the chat completion ID is the response ID.
+//	@Tags			Chat Completions
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		chatcompletions.Request	true	"Chat completion request"
+//	@Success		200		{object}	api.ChatCompletionResponse
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		413		{object}	map[string]interface{}
+//	@Failure		429		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Failure		503		{object}	map[string]interface{}
+//	@Router			/v1/chat/completions [post]
+func (h *Handler) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if !h.drain.begin() {
+		h.writeDraining(w)
+		return
+	}
+	defer h.drain.end()
+
+	var in chatcompletions.Request
+	if !h.decodeRequest(w, r, &in) {
+		return
+	}
+	req, err := chatcompletions.ToRequest(&in)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.writeValidationError(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Processing chat completion request",
+		"model", in.Model,
+		"stream", in.Stream)
+
+	if in.Stream {
+		includeUsage := in.StreamOptions != nil && in.StreamOptions.IncludeUsage
+		h.handleStreamingChatCompletion(w, r, chatcompletions.NewStreamConverter(includeUsage), req)
+		return
+	}
+
+	resp, err := h.engine.ProcessRequest(r.Context(), req)
+	if err != nil {
+		h.writeProcessError(w, r, err)
+		return
+	}
+	if resp.Status == "failed" && resp.Error != nil {
+		h.writeAPIError(w, apierror.Failed(resp.Error))
+		return
+	}
+
+	setSessionAffinity(w, resp.Conversation)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(chatcompletions.FromResponse(resp))
+
+	h.logger.InfoContext(r.Context(), "Chat completion sent",
+		"response_id", resp.ID,
+		"status", resp.Status)
+}
+
+// handleStreamingChatCompletion streams the response as chat completion
+// chunks, ending with "data: [DONE]" as OpenAI does.
+func (h *Handler) handleStreamingChatCompletion(w http.ResponseWriter, r *http.Request, conv *chatcompletions.StreamConverter, req *schema.ResponseRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, http.StatusInternalServerError, "streaming_not_supported", "Streaming not supported")
+		return
+	}
+
+	// Errors before the first event are returned with their status, as the
+	// stream has not started yet
+	events, err := h.engine.ProcessRequestStream(r.Context(), req)
+	if err != nil {
+		h.writeProcessError(w, r, err)
+		return
+	}
+	first := <-events
+	if created, ok := first.(*schema.ResponseCreatedStreamingEvent); ok {
+		setSessionAffinity(w, created.Response.Conversation)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event interface{}) bool {
+		chunks, errField := conv.Convert(event)
+		for _, chunk := range chunks {
+			h.writeSSEData(w, r, chunk)
+		}
+		if errField != nil {
+			h.writeSSEData(w, r, map[string]interface{}{"error": errField})
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if first != nil && write(first) {
+		for event := range events {
+			if !write(event) {
+				break
+			}
+		}
+	}
+	// Drain the rest so the engine can finish and save the response
+	for range events {
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+	h.logger.InfoContext(r.Context(), "Chat completion streaming completed")
+}
+
+// writeSSEData writes v as an SSE data line without an event name, the
+// format of Chat Completions streams.
+func (h *Handler) writeSSEData(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if err := writeSSE(w, "", v); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to marshal chunk", "error", err)
+	}
+}
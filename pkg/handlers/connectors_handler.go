@@ -6,10 +6,13 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/mcp"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
 )
 
@@ -28,7 +31,7 @@ func (h *Handler) handleRegisterConnector(w http.ResponseWriter, r *http.Request
 	// Parse request body
 	var req schema.RegisterConnectorRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse connector request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse connector request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -42,45 +45,120 @@ func (h *Handler) handleRegisterConnector(w http.ResponseWriter, r *http.Request
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "connector_type is required")
 		return
 	}
-	if req.ConnectorType != "mcp" {
-		h.writeError(w, http.StatusBadRequest, "invalid_request", "connector_type must be \"mcp\"")
+	var httpTool *mcp.HTTPTool
+	switch req.ConnectorType {
+	case "mcp":
+		if req.HTTPTool != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "http_tool requires connector_type \"http_tool\"")
+			return
+		}
+		if req.URL == "" && req.Command == "" {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "url or command is required")
+			return
+		}
+		if req.URL != "" && req.Command != "" {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "url and command are mutually exclusive")
+			return
+		}
+	case "http_tool":
+		if req.HTTPTool == nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "http_tool is required for http_tool connectors")
+			return
+		}
+		if req.URL != "" || req.Command != "" || len(req.Args) > 0 || len(req.Env) > 0 {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "url, command, args and env are not supported for http_tool connectors; set http_tool.url")
+			return
+		}
+		httpTool = &mcp.HTTPTool{
+			Name:        req.HTTPTool.Name,
+			Description: req.HTTPTool.Description,
+			Parameters:  req.HTTPTool.Parameters,
+			Method:      req.HTTPTool.Method,
+			URL:         req.HTTPTool.URL,
+			Headers:     req.HTTPTool.Headers,
+			Body:        req.HTTPTool.Body,
+		}
+		if err := httpTool.Validate(); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	default:
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "connector_type must be \"mcp\" or \"http_tool\"")
+		return
+	}
+	if req.MaxOutputBytes < 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "max_output_bytes must not be negative")
 		return
 	}
-	if req.URL == "" {
-		h.writeError(w, http.StatusBadRequest, "invalid_request", "url is required")
+
+	var stdio *mcp.StdioConfig
+	if req.Command != "" {
+		if h.stdioServers == nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "stdio connectors are disabled; configure connectors.stdio.allowed_commands to enable them")
+			return
+		}
+		if err := h.stdioServers.CheckCommand(req.Command); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if req.Auth != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "auth is not supported for stdio connectors; pass credentials through env")
+			return
+		}
+		stdio = &mcp.StdioConfig{
+			Command: req.Command,
+			Args:    req.Args,
+			Env:     req.Env,
+		}
+	} else if len(req.Args) > 0 || len(req.Env) > 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "args and env require command")
 		return
 	}
 
+	var auth *mcp.Auth
+	if req.Auth != nil {
+		auth = &mcp.Auth{
+			Type:         req.Auth.Type,
+			Token:        req.Auth.Token,
+			Headers:      req.Auth.Headers,
+			TokenURL:     req.Auth.TokenURL,
+			ClientID:     req.Auth.ClientID,
+			ClientSecret: req.Auth.ClientSecret,
+			Scopes:       req.Auth.Scopes,
+		}
+		if err := auth.Validate(); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	}
+
 	now := time.Now()
 
 	connector := &memory.Connector{
-		ConnectorID:   req.ConnectorID,
-		ConnectorType: req.ConnectorType,
-		URL:           req.URL,
-		ServerLabel:   req.ServerLabel,
-		CreatedAt:     now,
-		Metadata:      convertMetadata(req.Metadata),
+		ConnectorID:    req.ConnectorID,
+		ConnectorType:  req.ConnectorType,
+		URL:            req.URL,
+		Stdio:          stdio,
+		HTTPTool:       httpTool,
+		ServerLabel:    req.ServerLabel,
+		Auth:           auth,
+		DeniedTools:    req.DeniedTools,
+		MaxOutputBytes: req.MaxOutputBytes,
+		CreatedAt:      now,
+		Metadata:       convertMetadata(req.Metadata),
 	}
 
 	err := h.connectorsStore.CreateConnector(r.Context(), connector)
 	if err != nil {
-		h.logger.Error("Failed to register connector", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to register connector", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
-	h.logger.Info("Connector registered", "connector_id", req.ConnectorID)
+	h.logger.InfoContext(r.Context(), "Connector registered", "connector_id", req.ConnectorID)
 
 	// Return connector
-	schemaConnector := schema.Connector{
-		ConnectorID:   connector.ConnectorID,
-		Object:        "connector",
-		ConnectorType: connector.ConnectorType,
-		URL:           connector.URL,
-		ServerLabel:   connector.ServerLabel,
-		CreatedAt:     connector.CreatedAt.Unix(),
-		Metadata:      convertMetadataToInterface(connector.Metadata),
-	}
+	schemaConnector := connectorToSchema(connector)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -96,6 +174,7 @@ func (h *Handler) handleRegisterConnector(w http.ResponseWriter, r *http.Request
 //	@Param		before	query		string	false	"Cursor for pagination (backwards)"
 //	@Param		limit	query		int		false	"Number of items (1-100, default 50)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListConnectorsResponse
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/connectors [get]
@@ -116,31 +195,22 @@ func (h *Handler) handleListConnectors(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.logger.Info("Listing connectors", "after", after, "limit", limit, "order", order)
+	h.logger.InfoContext(r.Context(), "Listing connectors", "after", after, "limit", limit, "order", order)
 
 	// Get connectors from storage
 	connectors, hasMore, err := h.connectorsStore.ListConnectorsPaginated(
 		r.Context(), after, before, limit, order,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list connectors", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list connectors", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
 	// Convert to schema
 	schemaConnectors := make([]schema.Connector, 0, len(connectors))
 	for _, connector := range connectors {
-		c := schema.Connector{
-			ConnectorID:   connector.ConnectorID,
-			Object:        "connector",
-			ConnectorType: connector.ConnectorType,
-			URL:           connector.URL,
-			ServerLabel:   connector.ServerLabel,
-			CreatedAt:     connector.CreatedAt.Unix(),
-			Metadata:      convertMetadataToInterface(connector.Metadata),
-		}
-		schemaConnectors = append(schemaConnectors, c)
+		schemaConnectors = append(schemaConnectors, connectorToSchema(connector))
 	}
 
 	// Build response
@@ -155,6 +225,16 @@ func (h *Handler) handleListConnectors(w http.ResponseWriter, r *http.Request) {
 		listResp.LastID = schemaConnectors[len(schemaConnectors)-1].ConnectorID
 	}
 
+	if includeTotal(query) {
+		total, err := h.connectorsStore.CountConnectors(r.Context())
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count connectors", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -178,26 +258,18 @@ func (h *Handler) handleGetConnector(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting connector", "connector_id", connectorID)
+	h.logger.InfoContext(r.Context(), "Getting connector", "connector_id", connectorID)
 
 	// Get connector from storage
 	connector, err := h.connectorsStore.GetConnector(r.Context(), connectorID)
 	if err != nil {
-		h.logger.Error("Failed to get connector", "error", err, "connector_id", connectorID)
+		h.logger.ErrorContext(r.Context(), "Failed to get connector", "error", err, "connector_id", connectorID)
 		h.writeError(w, http.StatusNotFound, "connector_not_found", err.Error())
 		return
 	}
 
 	// Convert to schema
-	schemaConnector := schema.Connector{
-		ConnectorID:   connector.ConnectorID,
-		Object:        "connector",
-		ConnectorType: connector.ConnectorType,
-		URL:           connector.URL,
-		ServerLabel:   connector.ServerLabel,
-		CreatedAt:     connector.CreatedAt.Unix(),
-		Metadata:      convertMetadataToInterface(connector.Metadata),
-	}
+	schemaConnector := connectorToSchema(connector)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -222,15 +294,18 @@ func (h *Handler) handleDeleteConnector(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.logger.Info("Deleting connector", "connector_id", connectorID)
+	h.logger.InfoContext(r.Context(), "Deleting connector", "connector_id", connectorID)
 
 	// Delete connector from storage
 	err := h.connectorsStore.DeleteConnector(r.Context(), connectorID)
 	if err != nil {
-		h.logger.Error("Failed to delete connector", "error", err, "connector_id", connectorID)
+		h.logger.ErrorContext(r.Context(), "Failed to delete connector", "error", err, "connector_id", connectorID)
 		h.writeError(w, http.StatusNotFound, "connector_not_found", err.Error())
 		return
 	}
+	if h.stdioServers != nil {
+		h.stdioServers.Remove(connectorID)
+	}
 
 	// Return deletion confirmation
 	deleteResp := schema.DeleteConnectorResponse{
@@ -243,3 +318,52 @@ func (h *Handler) handleDeleteConnector(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(deleteResp)
 }
+
+// connectorToSchema converts a stored connector to its API representation.
+// Credentials are never included; only the non-secret auth settings are.
+func connectorToSchema(connector *memory.Connector) schema.Connector {
+	c := schema.Connector{
+		ConnectorID:    connector.ConnectorID,
+		Object:         "connector",
+		ConnectorType:  connector.ConnectorType,
+		URL:            connector.URL,
+		ServerLabel:    connector.ServerLabel,
+		DeniedTools:    connector.DeniedTools,
+		MaxOutputBytes: connector.MaxOutputBytes,
+		CreatedAt:      connector.CreatedAt.Unix(),
+		Metadata:       convertMetadataToInterface(connector.Metadata),
+	}
+	if st := connector.Stdio; st != nil {
+		c.Command = st.Command
+		c.Args = st.Args
+		for name := range st.Env {
+			c.EnvNames = append(c.EnvNames, name)
+		}
+		sort.Strings(c.EnvNames)
+	}
+	if t := connector.HTTPTool; t != nil {
+		c.HTTPTool = &schema.HTTPTool{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+			Method:      t.Method,
+			URL:         t.URL,
+			Headers:     t.Headers,
+			Body:        t.Body,
+		}
+	}
+	if a := connector.Auth; a != nil {
+		info := &schema.ConnectorAuthInfo{
+			Type:     a.Type,
+			TokenURL: a.TokenURL,
+			ClientID: a.ClientID,
+			Scopes:   a.Scopes,
+		}
+		for name := range a.Headers {
+			info.HeaderNames = append(info.HeaderNames, name)
+		}
+		sort.Strings(info.HeaderNames)
+		c.Auth = info
+	}
+	return c
+}
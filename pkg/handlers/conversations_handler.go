@@ -5,13 +5,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/services"
 	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
 )
 
 // handleCreateConversation handles POST /v1/conversations
@@ -23,50 +28,50 @@ import (
 //	@Param		request	body		schema.CreateConversationRequest	true	"Create conversation request"
 //	@Success	200		{object}	schema.Conversation
 //	@Failure	400		{object}	map[string]interface{}
+//	@Failure	413		{object}	map[string]interface{}
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/conversations [post]
 func (h *Handler) handleCreateConversation(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req schema.CreateConversationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse conversation request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse conversation request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
 
+	metadata := convertMetadata(req.Metadata)
+	if err := schema.ValidateMetadata("metadata", metadata); err != nil {
+		h.writeValidationError(w, r, err)
+		return
+	}
+
 	// Create conversation
-	convID := generateID("conv_")
+	convID := h.engine.NewID("conv_")
 	now := time.Now()
 
 	stateConv := &state.Conversation{
 		ID:        convID,
 		SessionID: "", // Not associated with a session for now
 		Messages:  []state.Message{},
-		Metadata:  convertMetadata(req.Metadata),
+		Metadata:  metadata,
+		Tenant:    featureflags.TenantFromContext(r.Context()),
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
 	err := h.engine.Store().CreateConversation(r.Context(), stateConv)
 	if err != nil {
-		h.logger.Error("Failed to create conversation", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to create conversation", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
 		return
 	}
 
-	h.logger.Info("Conversation created", "conversation_id", convID)
-
-	// Return conversation
-	conv := schema.Conversation{
-		ID:        convID,
-		Object:    "conversation",
-		CreatedAt: now.Unix(),
-		Metadata:  req.Metadata,
-	}
+	h.logger.InfoContext(r.Context(), "Conversation created", "conversation_id", convID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(conv)
+	json.NewEncoder(w).Encode(convertToSchemaConversation(stateConv))
 }
 
 // handleListConversations handles GET /v1/conversations
@@ -78,6 +83,7 @@ func (h *Handler) handleCreateConversation(w http.ResponseWriter, r *http.Reques
 //	@Param		before	query		string	false	"Cursor for pagination (backwards)"
 //	@Param		limit	query		int		false	"Number of items (1-100, default 50)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListConversationsResponse
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/conversations [get]
@@ -98,28 +104,22 @@ func (h *Handler) handleListConversations(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	h.logger.Info("Listing conversations", "after", after, "limit", limit, "order", order)
+	h.logger.InfoContext(r.Context(), "Listing conversations", "after", after, "limit", limit, "order", order)
 
 	// Get conversations from storage
 	stateConvs, hasMore, err := h.engine.Store().ListConversationsPaginated(
 		r.Context(), after, before, limit, order,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list conversations", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list conversations", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
 		return
 	}
 
 	// Convert to schema
 	conversations := make([]schema.Conversation, 0, len(stateConvs))
 	for _, stateConv := range stateConvs {
-		conv := schema.Conversation{
-			ID:        stateConv.ID,
-			Object:    "conversation",
-			CreatedAt: stateConv.CreatedAt.Unix(),
-			Metadata:  convertMetadataToInterface(stateConv.Metadata),
-		}
-		conversations = append(conversations, conv)
+		conversations = append(conversations, convertToSchemaConversation(stateConv))
 	}
 
 	// Build response
@@ -134,6 +134,16 @@ func (h *Handler) handleListConversations(w http.ResponseWriter, r *http.Request
 		listResp.LastID = conversations[len(conversations)-1].ID
 	}
 
+	if includeTotal(query) {
+		total, err := h.engine.Store().CountConversations(r.Context())
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count conversations", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -157,27 +167,68 @@ func (h *Handler) handleGetConversation(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.logger.Info("Getting conversation", "conversation_id", conversationID)
+	h.logger.InfoContext(r.Context(), "Getting conversation", "conversation_id", conversationID)
 
 	// Get conversation from storage
 	stateConv, err := h.engine.Store().GetConversation(r.Context(), conversationID)
 	if err != nil {
-		h.logger.Error("Failed to get conversation", "error", err, "conversation_id", conversationID)
+		h.logger.ErrorContext(r.Context(), "Failed to get conversation", "error", err, "conversation_id", conversationID)
 		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
 		return
 	}
 
-	// Convert to schema
-	conv := schema.Conversation{
-		ID:        stateConv.ID,
-		Object:    "conversation",
-		CreatedAt: stateConv.CreatedAt.Unix(),
-		Metadata:  convertMetadataToInterface(stateConv.Metadata),
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convertToSchemaConversation(stateConv))
+}
+
+// handleForkConversation handles POST /v1/conversations/{id}/fork
+//
+//	@Summary		Fork conversation
+//	@Description	Creates a conversation that branches this one at an earlier response. The fork continues from the history up to that response and gets a copy of the items added until then.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Param			id					path		string	true	"Conversation ID"
+//	@Param			from_response_id	query		string	true	"Response of the conversation to fork from"
+//	@Success		200					{object}	schema.Conversation
+//	@Failure		400					{object}	map[string]interface{}
+//	@Failure		404					{object}	map[string]interface{}
+//	@Failure		500					{object}	map[string]interface{}
+//	@Router			/v1/conversations/{id}/fork [post]
+func (h *Handler) handleForkConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID := r.PathValue("id")
+	if conversationID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Conversation ID is required")
+		return
 	}
+	fromResponseID := r.URL.Query().Get("from_response_id")
+	if fromResponseID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "from_response_id is required")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Forking conversation", "conversation_id", conversationID, "from_response_id", fromResponseID)
+
+	if _, err := h.engine.Store().GetConversation(r.Context(), conversationID); err != nil {
+		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
+		return
+	}
+	fork, err := services.ForkConversation(r.Context(), h.engine.Store(), conversationID, fromResponseID)
+	if err != nil {
+		if errors.Is(err, services.ErrForkPoint) {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to fork conversation", "error", err, "conversation_id", conversationID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Conversation forked", "conversation_id", fork.ID, "source_id", conversationID, "items", len(fork.Messages))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(conv)
+	json.NewEncoder(w).Encode(convertToSchemaConversation(fork))
 }
 
 // handleDeleteConversation handles DELETE /v1/conversations/{id}
@@ -198,12 +249,12 @@ func (h *Handler) handleDeleteConversation(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	h.logger.Info("Deleting conversation", "conversation_id", conversationID)
+	h.logger.InfoContext(r.Context(), "Deleting conversation", "conversation_id", conversationID)
 
 	// Delete conversation from storage
 	err := h.engine.Store().DeleteConversation(r.Context(), conversationID)
 	if err != nil {
-		h.logger.Error("Failed to delete conversation", "error", err, "conversation_id", conversationID)
+		h.logger.ErrorContext(r.Context(), "Failed to delete conversation", "error", err, "conversation_id", conversationID)
 		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
 		return
 	}
@@ -243,7 +294,7 @@ func (h *Handler) handleAddConversationItems(w http.ResponseWriter, r *http.Requ
 	// Parse request body
 	var req schema.AddConversationItemsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse items request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse items request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -254,7 +305,7 @@ func (h *Handler) handleAddConversationItems(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.logger.Info("Adding conversation items", "conversation_id", conversationID, "count", len(req.Items))
+	h.logger.InfoContext(r.Context(), "Adding conversation items", "conversation_id", conversationID, "count", len(req.Items))
 
 	// Convert schema items to state messages
 	now := time.Now()
@@ -278,7 +329,7 @@ func (h *Handler) handleAddConversationItems(w http.ResponseWriter, r *http.Requ
 	// Add items to conversation
 	err := h.engine.Store().AddConversationItems(r.Context(), conversationID, messages)
 	if err != nil {
-		h.logger.Error("Failed to add items", "error", err, "conversation_id", conversationID)
+		h.logger.ErrorContext(r.Context(), "Failed to add items", "error", err, "conversation_id", conversationID)
 		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
 		return
 	}
@@ -347,14 +398,14 @@ func (h *Handler) handleListConversationItems(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	h.logger.Info("Listing conversation items", "conversation_id", conversationID, "limit", limit)
+	h.logger.InfoContext(r.Context(), "Listing conversation items", "conversation_id", conversationID, "limit", limit)
 
 	// Get items from storage
 	messages, hasMore, err := h.engine.Store().ListConversationItems(
 		r.Context(), conversationID, after, before, limit, order,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list items", "error", err, "conversation_id", conversationID)
+		h.logger.ErrorContext(r.Context(), "Failed to list items", "error", err, "conversation_id", conversationID)
 		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
 		return
 	}
@@ -391,6 +442,147 @@ func (h *Handler) handleListConversationItems(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(listResp)
 }
 
+// handleExportConversation handles GET /v1/conversations/{id}/export
+//
+//	@Summary		Export conversation
+//	@Description	Returns a portable bundle of the conversation, its items and the responses made in it, which POST /v1/conversations/import recreates in another gateway.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Param			id	path		string	true	"Conversation ID"
+//	@Success		200	{object}	schema.ConversationExport
+//	@Failure		400	{object}	map[string]interface{}
+//	@Failure		404	{object}	map[string]interface{}
+//	@Failure		500	{object}	map[string]interface{}
+//	@Router			/v1/conversations/{id}/export [get]
+func (h *Handler) handleExportConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID := r.PathValue("id")
+	if conversationID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Conversation ID is required")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Exporting conversation", "conversation_id", conversationID)
+
+	if _, err := h.engine.Store().GetConversation(r.Context(), conversationID); err != nil {
+		h.writeError(w, http.StatusNotFound, "conversation_not_found", err.Error())
+		return
+	}
+	bundle, err := services.ExportConversation(r.Context(), h.engine.Store(), conversationID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to export conversation", "error", err, "conversation_id", conversationID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
+		return
+	}
+
+	export := schema.ConversationExport{
+		Object:       "conversation.export",
+		Version:      conversationExportVersion,
+		ExportedAt:   time.Now().Unix(),
+		Conversation: convertToSchemaConversation(bundle.Conversation),
+		Items:        make([]schema.ConversationItem, 0, len(bundle.Conversation.Messages)),
+		Responses:    make([]schema.ExportedResponse, 0, len(bundle.Responses)),
+	}
+	for _, msg := range bundle.Conversation.Messages {
+		export.Items = append(export.Items, convertToSchemaItem(msg))
+	}
+	for _, resp := range bundle.Responses {
+		export.Responses = append(export.Responses, convertToExportedResponse(resp))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+conversationID+`.json"`)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(export)
+}
+
+// handleImportConversation handles POST /v1/conversations/import
+//
+//	@Summary		Import conversation
+//	@Description	Recreates a conversation from a bundle returned by GET /v1/conversations/{id}/export. Conversation and response IDs are regenerated unless preserve_ids is true, in which case importing over existing IDs fails with 409.
+//	@Tags			Conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			preserve_ids	query		bool						false	"Keep the bundle's conversation and response IDs"
+//	@Param			request			body		schema.ConversationExport	true	"Exported conversation"
+//	@Success		200				{object}	schema.ImportConversationResponse
+//	@Failure		400				{object}	map[string]interface{}
+//	@Failure		409				{object}	map[string]interface{}
+//	@Failure		500				{object}	map[string]interface{}
+//	@Router			/v1/conversations/import [post]
+func (h *Handler) handleImportConversation(w http.ResponseWriter, r *http.Request) {
+	var export schema.ConversationExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON: "+err.Error())
+		return
+	}
+	if export.Object != "conversation.export" || export.Conversation.ID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Body must be a conversation export")
+		return
+	}
+	if export.Version != conversationExportVersion {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Unsupported export version %d", export.Version))
+		return
+	}
+	preserveIDs := r.URL.Query().Get("preserve_ids") == "true"
+
+	tenant := featureflags.TenantFromContext(r.Context())
+	bundle := &services.ConversationBundle{
+		Conversation: &state.Conversation{
+			ID:        export.Conversation.ID,
+			Metadata:  convertMetadata(export.Conversation.Metadata),
+			Tenant:    tenant,
+			CreatedAt: time.Unix(export.Conversation.CreatedAt, 0),
+			Messages:  make([]state.Message, 0, len(export.Items)),
+		},
+	}
+	for i, item := range export.Items {
+		if item.ID == "" {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("items[%d].id is required", i))
+			return
+		}
+		bundle.Conversation.Messages = append(bundle.Conversation.Messages, state.Message{
+			ID:        item.ID,
+			Role:      item.Role,
+			Content:   item.Content,
+			Metadata:  convertMetadata(item.Metadata),
+			CreatedAt: time.Unix(item.CreatedAt, 0),
+		})
+	}
+	for i, resp := range export.Responses {
+		if resp.ID == "" {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("responses[%d].id is required", i))
+			return
+		}
+		stateResp := convertFromExportedResponse(resp)
+		stateResp.Tenant = tenant
+		bundle.Responses = append(bundle.Responses, stateResp)
+	}
+
+	result, err := services.ImportConversation(r.Context(), h.engine.Store(), bundle, preserveIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrImportConflict) {
+			h.writeError(w, http.StatusConflict, "conflict", err.Error())
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to import conversation", "error", err, "conversation_id", export.Conversation.ID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeConversationError, err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Conversation imported", "conversation_id", result.Conversation.ID, "source_id", export.Conversation.ID,
+		"items", len(result.Conversation.Messages), "responses", result.Responses)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.ImportConversationResponse{
+		Object:       "conversation.import",
+		Conversation: convertToSchemaConversation(result.Conversation),
+		Items:        len(result.Conversation.Messages),
+		Responses:    result.Responses,
+		IDs:          result.IDs,
+	})
+}
+
 // Helper functions
 
 func convertMetadata(m map[string]interface{}) map[string]string {
@@ -415,3 +607,95 @@ func convertMetadataToInterface(m map[string]string) map[string]interface{} {
 	}
 	return result
 }
+
+// conversationExportVersion is the version of the conversation export
+// format written by export and accepted by import.
+const conversationExportVersion = 1
+
+func convertToSchemaConversation(c *state.Conversation) schema.Conversation {
+	conv := schema.Conversation{
+		ID:        c.ID,
+		Object:    "conversation",
+		CreatedAt: c.CreatedAt.Unix(),
+		Metadata:  convertMetadataToInterface(c.Metadata),
+	}
+	if title := c.Metadata[engine.TitleMetadataKey]; title != "" {
+		conv.Title = &title
+	}
+	if c.ForkedFrom != "" {
+		conv.ForkedFrom = &c.ForkedFrom
+	}
+	return conv
+}
+
+func convertToSchemaItem(msg state.Message) schema.ConversationItem {
+	return schema.ConversationItem{
+		ID:        msg.ID,
+		Object:    "conversation.item",
+		Type:      "message",
+		CreatedAt: msg.CreatedAt.Unix(),
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Metadata:  convertMetadataToInterface(msg.Metadata),
+	}
+}
+
+func convertToExportedResponse(r *state.Response) schema.ExportedResponse {
+	out := schema.ExportedResponse{
+		ID:                 r.ID,
+		PreviousResponseID: r.PreviousResponseID,
+		Status:             r.Status,
+		CreatedAt:          r.CreatedAt.Unix(),
+		ExternalID:         r.ExternalID,
+		Request:            r.Request,
+		Output:             r.Output,
+		Error:              r.Error,
+		Usage:              r.Usage,
+		Messages:           make([]schema.ExportedMessage, 0, len(r.Messages)),
+	}
+	if r.CompletedAt != nil {
+		completed := r.CompletedAt.Unix()
+		out.CompletedAt = &completed
+	}
+	for _, m := range r.Messages {
+		msg := schema.ExportedMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, schema.ExportedToolCall{ID: tc.ID, Type: tc.Type, Name: tc.Name, Arguments: tc.Arguments})
+		}
+		if r := m.Reasoning; r != nil {
+			msg.Reasoning = &schema.ExportedReasoning{ID: r.ID, Summary: r.Summary, Text: r.Text, EncryptedContent: r.EncryptedContent}
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	return out
+}
+
+func convertFromExportedResponse(r schema.ExportedResponse) *state.Response {
+	out := &state.Response{
+		ID:                 r.ID,
+		PreviousResponseID: r.PreviousResponseID,
+		Status:             r.Status,
+		CreatedAt:          time.Unix(r.CreatedAt, 0),
+		ExternalID:         r.ExternalID,
+		Request:            r.Request,
+		Output:             r.Output,
+		Error:              r.Error,
+		Usage:              r.Usage,
+		Messages:           make([]state.ConversationMessage, 0, len(r.Messages)),
+	}
+	if r.CompletedAt != nil {
+		completed := time.Unix(*r.CompletedAt, 0)
+		out.CompletedAt = &completed
+	}
+	for _, m := range r.Messages {
+		msg := state.ConversationMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, state.ToolCallRecord{ID: tc.ID, Type: tc.Type, Name: tc.Name, Arguments: tc.Arguments})
+		}
+		if r := m.Reasoning; r != nil {
+			msg.Reasoning = &state.ReasoningRecord{ID: r.ID, Summary: r.Summary, Text: r.Text, EncryptedContent: r.EncryptedContent}
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+)
+
+// interruptTimeout bounds how long Drain waits for interrupted responses to
+// be saved once the grace period has expired.
+const interruptTimeout = 5 * time.Second
+
+// drainer tracks in-flight /v1/responses requests so that shutdown can wait
+// for them.
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+	closing  chan struct{} // closed when draining starts; see done
+}
+
+// begin registers a request, or returns false once draining has started.
+func (d *drainer) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inflight.Add(1)
+	return true
+}
+
+func (d *drainer) end() {
+	d.inflight.Done()
+}
+
+func (d *drainer) isDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// done returns a channel closed once draining has started. Long-lived
+// streams that are not responses end on it, so they do not hold up the
+// shutdown of the server.
+func (d *drainer) done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closing == nil {
+		d.closing = make(chan struct{})
+	}
+	return d.closing
+}
+
+// wait waits for in-flight requests, or until ctx is done.
+func (d *drainer) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain prepares the handler for shutdown. New /v1/responses requests are
+// rejected with 503 and /readyz starts failing, while in-flight responses,
+// including SSE streams, are allowed to finish until ctx is done. Responses
+// still running then are interrupted: they end as "incomplete" with reason
+// "interrupted" and their partial output is saved. Drain returns once no
+// response is in flight, or an error if interrupted responses did not finish
+// in time.
+func (h *Handler) Drain(ctx context.Context) error {
+	h.drain.mu.Lock()
+	if !h.drain.draining {
+		h.drain.draining = true
+		if h.drain.closing == nil {
+			h.drain.closing = make(chan struct{})
+		}
+		close(h.drain.closing)
+	}
+	h.drain.mu.Unlock()
+
+	if err := h.drain.wait(ctx); err == nil {
+		return nil
+	}
+
+	h.logger.Warn("Shutdown grace period expired, interrupting in-flight responses")
+	h.engine.Interrupt()
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), interruptTimeout)
+	defer cancel()
+	if err := h.drain.wait(waitCtx); err != nil {
+		return fmt.Errorf("interrupted responses still running after %s", interruptTimeout)
+	}
+	return nil
+}
+
+// writeDraining rejects a request received while the handler is draining.
+// Connection: close makes clients reconnect, to another replica if any.
+func (h *Handler) writeDraining(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	w.Header().Set("Retry-After", "1")
+	h.writeError(w, http.StatusServiceUnavailable, apierror.CodeServerShuttingDown, "Server is shutting down, retry the request")
+}
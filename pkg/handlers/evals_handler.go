@@ -0,0 +1,190 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/services"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+// handleRunEval handles POST /v1/evals/run
+//
+//	@Summary		Run eval
+//	@Description	Compare two model configurations: every prompt of the set is run through both, and the paired outputs are recorded on the eval run as they come. The run is processed in the background; poll GET /v1/evals/{id} until its status is completed.
+//	@Tags			Evals
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		schema.EvalRunRequest	true	"Eval run request"
+//	@Success		200		{object}	schema.EvalRun
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Failure		503		{object}	map[string]interface{}
+//	@Router			/v1/evals/run [post]
+func (h *Handler) handleRunEval(w http.ResponseWriter, r *http.Request) {
+	// Runs are not waited for on shutdown
+	if h.drain.isDraining() {
+		h.writeDraining(w)
+		return
+	}
+
+	var req schema.EvalRunRequest
+	if !h.decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.A) == 0 || len(req.B) == 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Both configurations, a and b, are required")
+		return
+	}
+	if (len(req.Prompts) > 0) == (req.FileID != "") {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Exactly one of prompts and file_id is required")
+		return
+	}
+	if err := schema.ValidateMetadata("metadata", req.Metadata); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	prompts := make([]services.EvalPrompt, len(req.Prompts))
+	for i, p := range req.Prompts {
+		prompts[i] = services.EvalPrompt{ID: p.ID, Input: p.Input}
+	}
+	if req.FileID != "" {
+		if _, err := h.filesStore.GetFile(r.Context(), req.FileID); err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to get file", "error", err, "file_id", req.FileID)
+			h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
+			return
+		}
+		content, err := h.filesStore.GetFileContent(r.Context(), req.FileID)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to get file content", "error", err, "file_id", req.FileID)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
+			return
+		}
+		if prompts, err = services.ParseEvalPrompts(content); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	}
+
+	run := &memory.EvalRun{
+		ID:        h.engine.NewID("eval_"),
+		Name:      req.Name,
+		FileID:    req.FileID,
+		A:         req.A,
+		B:         req.B,
+		Metadata:  req.Metadata,
+		CreatedAt: time.Now(),
+	}
+	if err := h.evalRunner.Start(r.Context(), run, prompts); err != nil {
+		if errors.Is(err, services.ErrInvalidEval) {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to start eval run", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Started eval run", "eval_id", run.ID, "prompts", len(run.Results))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convertToSchemaEvalRun(run))
+}
+
+// handleGetEval handles GET /v1/evals/{id}
+//
+//	@Summary	Get eval run
+//	@Tags		Evals
+//	@Produce	json
+//	@Param		id	path		string	true	"Eval run ID"
+//	@Success	200	{object}	schema.EvalRun
+//	@Failure	404	{object}	map[string]interface{}
+//	@Router		/v1/evals/{id} [get]
+func (h *Handler) handleGetEval(w http.ResponseWriter, r *http.Request) {
+	run, err := h.evals.GetEvalRun(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "eval_not_found", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convertToSchemaEvalRun(run))
+}
+
+// convertToSchemaEvalRun converts an internal eval run to schema
+func convertToSchemaEvalRun(run *memory.EvalRun) schema.EvalRun {
+	summary := services.SummarizeEval(run)
+	out := schema.EvalRun{
+		ID:     run.ID,
+		Object: "eval.run",
+		Name:   run.Name,
+		Status: run.Status,
+		FileID: run.FileID,
+		A:      run.A,
+		B:      run.B,
+		Summary: schema.EvalSummary{
+			Prompts:   summary.Prompts,
+			Completed: summary.Completed,
+			Identical: summary.Identical,
+			A:         convertToSchemaEvalVariantSummary(summary.A),
+			B:         convertToSchemaEvalVariantSummary(summary.B),
+		},
+		Results:   make([]schema.EvalResult, len(run.Results)),
+		Metadata:  run.Metadata,
+		CreatedAt: run.CreatedAt.Unix(),
+	}
+	if run.CompletedAt != nil {
+		completedAt := run.CompletedAt.Unix()
+		out.CompletedAt = &completedAt
+	}
+	for i, result := range run.Results {
+		out.Results[i] = schema.EvalResult{
+			PromptID:  result.PromptID,
+			Input:     result.Input,
+			A:         convertToSchemaEvalOutput(result.A),
+			B:         convertToSchemaEvalOutput(result.B),
+			Identical: services.EvalIdentical(result),
+		}
+	}
+	return out
+}
+
+func convertToSchemaEvalVariantSummary(s services.EvalVariantSummary) schema.EvalVariantSummary {
+	return schema.EvalVariantSummary{
+		Completed:        s.Completed,
+		Failed:           s.Failed,
+		InputTokens:      s.InputTokens,
+		OutputTokens:     s.OutputTokens,
+		AverageLatencyMs: s.AverageLatency.Milliseconds(),
+	}
+}
+
+func convertToSchemaEvalOutput(o *memory.EvalOutput) *schema.EvalOutput {
+	if o == nil {
+		return nil
+	}
+	out := &schema.EvalOutput{
+		ResponseID:   o.ResponseID,
+		Model:        o.Model,
+		Status:       o.Status,
+		OutputText:   o.OutputText,
+		InputTokens:  o.InputTokens,
+		OutputTokens: o.OutputTokens,
+		LatencyMs:    o.Latency.Milliseconds(),
+	}
+	if o.Error != "" {
+		out.Error = &o.Error
+	}
+	return out
+}
@@ -10,14 +10,37 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/services"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
 	"github.com/leseb/openresponses-gw/pkg/filestore"
 )
 
 const (
 	maxFileSize = 512 * 1024 * 1024 // 512 MB
+
+	// maxDownloadURLExpiry caps expires_in at the SigV4 limit of 7 days.
+	maxDownloadURLExpiry = 7 * 24 * 60 * 60
 )
 
+// validFilePurposes lists the accepted values of the "purpose" upload field.
+var validFilePurposes = map[string]bool{
+	"assistants":        true,
+	"assistants_output": true,
+	"batch":             true,
+	"batch_output":      true,
+	"fine-tune":         true,
+	"fine-tune-results": true,
+	"vision":            true,
+}
+
+// SetFileRetention sets the retention policy used to report when files
+// expire.
+func (h *Handler) SetFileRetention(policy services.RetentionPolicy) {
+	h.fileRetention = policy
+}
+
 // handleUploadFile handles POST /v1/files
 //
 //	@Summary	Upload file
@@ -34,7 +57,7 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form
 	err := r.ParseMultipartForm(maxFileSize)
 	if err != nil {
-		h.logger.Error("Failed to parse multipart form", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse multipart form", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse multipart form")
 		return
 	}
@@ -42,7 +65,7 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 	// Get file from form
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		h.logger.Error("Failed to get file from form", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get file from form", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "File is required")
 		return
 	}
@@ -56,16 +79,7 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate purpose
-	validPurposes := map[string]bool{
-		"assistants":        true,
-		"assistants_output": true,
-		"batch":             true,
-		"batch_output":      true,
-		"fine-tune":         true,
-		"fine-tune-results": true,
-		"vision":            true,
-	}
-	if !validPurposes[purpose] {
+	if !validFilePurposes[purpose] {
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid purpose")
 		return
 	}
@@ -73,13 +87,13 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 	// Read file content
 	content, err := io.ReadAll(file)
 	if err != nil {
-		h.logger.Error("Failed to read file content", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "read_error", "Failed to read file content")
+		h.logger.ErrorContext(r.Context(), "Failed to read file content", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, "Failed to read file content")
 		return
 	}
 
 	// Create file
-	fileID := generateID("file_")
+	fileID := h.engine.NewID("file_")
 	now := time.Now()
 
 	storeFile := &filestore.File{
@@ -90,29 +104,21 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 		Bytes:     int64(len(content)),
 		Content:   content,
 		Status:    "uploaded",
+		Tenant:    featureflags.TenantFromContext(r.Context()),
 		CreatedAt: now,
 	}
 
 	err = h.filesStore.CreateFile(r.Context(), storeFile)
 	if err != nil {
-		h.logger.Error("Failed to create file", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to create file", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
 		return
 	}
 
-	h.logger.Info("File uploaded", "file_id", fileID, "filename", header.Filename, "bytes", len(content))
+	h.logger.InfoContext(r.Context(), "File uploaded", "file_id", fileID, "filename", header.Filename, "bytes", len(content))
 
 	// Return file
-	schemaFile := schema.File{
-		ID:        storeFile.ID,
-		Object:    "file",
-		Bytes:     storeFile.Bytes,
-		CreatedAt: storeFile.CreatedAt.Unix(),
-		Filename:  storeFile.Filename,
-		Purpose:   storeFile.Purpose,
-		Status:    storeFile.Status,
-		MimeType:  storeFile.MimeType,
-	}
+	schemaFile := h.convertToSchemaFile(storeFile)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -129,6 +135,7 @@ func (h *Handler) handleUploadFile(w http.ResponseWriter, r *http.Request) {
 //	@Param		limit	query		int		false	"Number of items (1-100, default 50)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
 //	@Param		purpose	query		string	false	"Filter by purpose"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListFilesResponse
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/files [get]
@@ -150,32 +157,22 @@ func (h *Handler) handleListFiles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.logger.Info("Listing files", "after", after, "limit", limit, "order", order, "purpose", purpose)
+	h.logger.InfoContext(r.Context(), "Listing files", "after", after, "limit", limit, "order", order, "purpose", purpose)
 
 	// Get files from storage
 	files, hasMore, err := h.filesStore.ListFilesPaginated(
 		r.Context(), after, before, limit, order, purpose,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list files", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list files", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
 		return
 	}
 
 	// Convert to schema
 	schemaFiles := make([]schema.File, 0, len(files))
 	for _, file := range files {
-		f := schema.File{
-			ID:        file.ID,
-			Object:    "file",
-			Bytes:     file.Bytes,
-			CreatedAt: file.CreatedAt.Unix(),
-			Filename:  file.Filename,
-			Purpose:   file.Purpose,
-			Status:    file.Status,
-			MimeType:  file.MimeType,
-		}
-		schemaFiles = append(schemaFiles, f)
+		schemaFiles = append(schemaFiles, h.convertToSchemaFile(file))
 	}
 
 	// Build response
@@ -190,6 +187,16 @@ func (h *Handler) handleListFiles(w http.ResponseWriter, r *http.Request) {
 		listResp.LastID = schemaFiles[len(schemaFiles)-1].ID
 	}
 
+	if includeTotal(query) {
+		total, err := h.filesStore.CountFiles(r.Context(), purpose)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count files", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -213,27 +220,18 @@ func (h *Handler) handleGetFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting file", "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Getting file", "file_id", fileID)
 
 	// Get file from storage
 	file, err := h.filesStore.GetFile(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to get file", "error", err, "file_id", fileID)
+		h.logger.ErrorContext(r.Context(), "Failed to get file", "error", err, "file_id", fileID)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
 
 	// Convert to schema
-	schemaFile := schema.File{
-		ID:        file.ID,
-		Object:    "file",
-		Bytes:     file.Bytes,
-		CreatedAt: file.CreatedAt.Unix(),
-		Filename:  file.Filename,
-		Purpose:   file.Purpose,
-		Status:    file.Status,
-		MimeType:  file.MimeType,
-	}
+	schemaFile := h.convertToSchemaFile(file)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -259,12 +257,12 @@ func (h *Handler) handleGetFileContent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting file content", "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Getting file content", "file_id", fileID)
 
 	// Get file metadata
 	file, err := h.filesStore.GetFile(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to get file", "error", err, "file_id", fileID)
+		h.logger.ErrorContext(r.Context(), "Failed to get file", "error", err, "file_id", fileID)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
@@ -272,8 +270,8 @@ func (h *Handler) handleGetFileContent(w http.ResponseWriter, r *http.Request) {
 	// Get file content
 	content, err := h.filesStore.GetFileContent(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to get file content", "error", err, "file_id", fileID)
-		h.writeError(w, http.StatusInternalServerError, "read_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to get file content", "error", err, "file_id", fileID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
 		return
 	}
 
@@ -287,6 +285,67 @@ func (h *Handler) handleGetFileContent(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
+// handleGetFileDownloadURL handles GET /v1/files/{id}/download_url
+//
+//	@Summary	Get a file download URL
+//	@Description	Returns a presigned URL from which the file content can be downloaded directly from the file store, without going through the gateway. Only supported by the s3 file store.
+//	@Tags		Files
+//	@Produce	json
+//	@Param		id			path		string	true	"File ID"
+//	@Param		expires_in	query		int		false	"URL lifetime in seconds (default from file_store.s3_presign_expiry, max 604800)"
+//	@Success	200			{object}	schema.FileDownloadURL
+//	@Failure	400			{object}	map[string]interface{}
+//	@Failure	404			{object}	map[string]interface{}
+//	@Failure	500			{object}	map[string]interface{}
+//	@Failure	501			{object}	map[string]interface{}
+//	@Router		/v1/files/{id}/download_url [get]
+func (h *Handler) handleGetFileDownloadURL(w http.ResponseWriter, r *http.Request) {
+	signer, ok := h.filesStore.(filestore.URLSigner)
+	if !ok {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "The configured file store does not support download URLs")
+		return
+	}
+
+	fileID := r.PathValue("id")
+	if fileID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "File ID is required")
+		return
+	}
+
+	var expires time.Duration
+	if v := r.URL.Query().Get("expires_in"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs <= 0 || secs > maxDownloadURLExpiry {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "expires_in must be between 1 and 604800 seconds")
+			return
+		}
+		expires = time.Duration(secs) * time.Second
+	}
+
+	file, err := h.filesStore.GetFile(r.Context(), fileID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to get file", "error", err, "file_id", fileID)
+		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
+		return
+	}
+
+	url, expiresAt, err := signer.PresignContentURL(r.Context(), file, expires)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to presign file URL", "error", err, "file_id", fileID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, "Failed to create download URL")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schema.FileDownloadURL{
+		ID:        fileID,
+		Object:    "file.download_url",
+		URL:       url,
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
+
 // handleDeleteFile handles DELETE /v1/files/{id}
 //
 //	@Summary	Delete file
@@ -305,12 +364,12 @@ func (h *Handler) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Deleting file", "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Deleting file", "file_id", fileID)
 
 	// Delete file from storage
 	err := h.filesStore.DeleteFile(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to delete file", "error", err, "file_id", fileID)
+		h.logger.ErrorContext(r.Context(), "Failed to delete file", "error", err, "file_id", fileID)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
@@ -326,3 +385,23 @@ func (h *Handler) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(deleteResp)
 }
+
+// convertToSchemaFile converts a stored file to its API representation,
+// with the expiry given by the retention policy.
+func (h *Handler) convertToSchemaFile(f *filestore.File) schema.File {
+	file := schema.File{
+		ID:        f.ID,
+		Object:    "file",
+		Bytes:     f.Bytes,
+		CreatedAt: f.CreatedAt.Unix(),
+		Filename:  f.Filename,
+		Purpose:   f.Purpose,
+		Status:    f.Status,
+		MimeType:  f.MimeType,
+	}
+	if expiresAt, ok := h.fileRetention.ExpiresAt(f); ok {
+		unix := expiresAt.Unix()
+		file.ExpiresAt = &unix
+	}
+	return file
+}
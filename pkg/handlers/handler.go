@@ -4,17 +4,34 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/adapters/chatcompletions"
+	"github.com/leseb/openresponses-gw/pkg/core/admission"
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
 	"github.com/leseb/openresponses-gw/pkg/core/services"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
 	"github.com/leseb/openresponses-gw/pkg/filestore"
+	"github.com/leseb/openresponses-gw/pkg/health"
+	"github.com/leseb/openresponses-gw/pkg/mcp"
 	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
 )
 
@@ -30,11 +47,31 @@ type Handler struct {
 	filesStore         filestore.FileStore
 	vectorStoresStore  *memory.VectorStoresStore
 	connectorsStore    *memory.ConnectorsStore
+	evals              *memory.EvalsStore
+	evalRunner         *services.EvalRunner
 	vectorStoreService *services.VectorStoreService // nil when feature is disabled
+	gc                 *services.GarbageCollector   // nil until SetGarbageCollector is called
+	gcDefaults         services.GCOptions
+	fileRetention      services.RetentionPolicy // nil until SetFileRetention is called
+	eraser             *services.DataEraser     // nil until SetDataEraser is called
+	sessionReaper      *services.SessionReaper  // nil until SetSessionReaper is called
+	stdioServers       *mcp.StdioManager        // nil when stdio connectors are disabled
+	features           *featureflags.Flags      // nil until SetFeatureFlags is called
+	tenantHeader       string
+	playground         bool            // serve /playground; see EnablePlayground
+	adminUI            *adminUI        // nil until EnableAdminUI is called
+	health             *health.Checker // nil until SetHealthChecker is called
+	audit              state.AuditLog  // nil until SetAuditLog is called
+	maxRequestBytes    int64           // 0 means unlimited; see SetMaxRequestBytes
+	maxUploadBytes     int64           // bounds vector store file upload bodies
+	strictValidation   bool            // see SetStrictValidation
+	drain              drainer
+	routes             []string // patterns registered by handle
 }
 
 // New creates a new HTTP handler
 func New(eng *engine.Engine, logger *logging.Logger, promptsStore *memory.PromptsStore, filesStore filestore.FileStore, vectorStoresStore *memory.VectorStoresStore, connectorsStore *memory.ConnectorsStore, vectorStoreService *services.VectorStoreService) *Handler {
+	evals := memory.NewEvalsStore()
 	h := &Handler{
 		engine:             eng,
 		logger:             logger,
@@ -43,81 +80,178 @@ func New(eng *engine.Engine, logger *logging.Logger, promptsStore *memory.Prompt
 		filesStore:         filesStore,
 		vectorStoresStore:  vectorStoresStore,
 		connectorsStore:    connectorsStore,
+		evals:              evals,
+		evalRunner:         services.NewEvalRunner(evals, eng),
 		vectorStoreService: vectorStoreService,
+		maxUploadBytes:     maxFileSize + maxUploadFieldSize*3,
 	}
 
 	// Register routes
-	h.mux.HandleFunc("GET /health", h.handleHealth)
-	h.mux.HandleFunc("GET /openapi.json", h.handleOpenAPI)
+	h.handle("GET /health", h.handleHealth)
+	h.handle("GET /healthz", h.handleLiveness)
+	h.handle("GET /readyz", h.handleReadiness)
+	h.handle("GET /openapi.json", h.handleOpenAPI)
+	h.handle("GET /playground", h.handlePlayground)
 
 	// Responses API (Open Responses compliant - single endpoint)
 	// Support both /responses (Open Responses spec) and /v1/responses (OpenAI compatibility)
-	h.mux.HandleFunc("POST /responses", h.handleResponses)
-	h.mux.HandleFunc("POST /v1/responses", h.handleResponses)
-	h.mux.HandleFunc("GET /v1/responses", h.handleListResponses)
-	h.mux.HandleFunc("GET /v1/responses/{id}", h.handleGetResponse)
-	h.mux.HandleFunc("DELETE /v1/responses/{id}", h.handleDeleteResponse)
-	h.mux.HandleFunc("GET /v1/responses/{id}/input_items", h.handleGetResponseInputItems)
+	h.handle("POST /responses", h.handleResponses)
+	h.handle("POST /v1/responses", h.handleResponses)
+	h.handle("GET /v1/responses", h.handleListResponses)
+	h.handle("GET /v1/responses/export", h.handleExportResponses)
+	h.handle("GET /v1/responses/{id}", h.handleGetResponse)
+	h.handle("DELETE /v1/responses/{id}", h.handleDeleteResponse)
+	h.handle("GET /v1/responses/{id}/input_items", h.handleGetResponseInputItems)
+	h.handle("GET /v1/responses/{id}/recording", h.handleGetResponseRecording)
+	h.handle("POST /v1/responses/{id}/replay", h.handleReplayResponse)
+
+	// Chat Completions API, served through the engine
+	h.handle("POST "+chatcompletions.Path, h.handleChatCompletions)
 
 	// Conversations API
-	h.mux.HandleFunc("POST /v1/conversations", h.handleCreateConversation)
-	h.mux.HandleFunc("GET /v1/conversations", h.handleListConversations)
-	h.mux.HandleFunc("GET /v1/conversations/{id}", h.handleGetConversation)
-	h.mux.HandleFunc("DELETE /v1/conversations/{id}", h.handleDeleteConversation)
-	h.mux.HandleFunc("POST /v1/conversations/{id}/items", h.handleAddConversationItems)
-	h.mux.HandleFunc("GET /v1/conversations/{id}/items", h.handleListConversationItems)
+	h.handle("POST /v1/conversations", h.handleCreateConversation)
+	h.handle("GET /v1/conversations", h.handleListConversations)
+	h.handle("POST /v1/conversations/import", h.handleImportConversation)
+	h.handle("GET /v1/conversations/{id}", h.handleGetConversation)
+	h.handle("DELETE /v1/conversations/{id}", h.handleDeleteConversation)
+	h.handle("POST /v1/conversations/{id}/items", h.handleAddConversationItems)
+	h.handle("GET /v1/conversations/{id}/items", h.handleListConversationItems)
+	h.handle("GET /v1/conversations/{id}/export", h.handleExportConversation)
+	h.handle("POST /v1/conversations/{id}/fork", h.handleForkConversation)
 
 	// Prompts API
-	h.mux.HandleFunc("POST /v1/prompts", h.handleCreatePrompt)
-	h.mux.HandleFunc("GET /v1/prompts", h.handleListPrompts)
-	h.mux.HandleFunc("GET /v1/prompts/{id}", h.handleGetPrompt)
-	h.mux.HandleFunc("PUT /v1/prompts/{id}", h.handleUpdatePrompt)
-	h.mux.HandleFunc("DELETE /v1/prompts/{id}", h.handleDeletePrompt)
-	h.mux.HandleFunc("GET /v1/prompts/{id}/versions", h.handleListPromptVersions)
-	h.mux.HandleFunc("POST /v1/prompts/{id}/default_version", h.handleSetDefaultVersion)
+	h.handle("POST /v1/prompts", h.handleCreatePrompt)
+	h.handle("GET /v1/prompts", h.handleListPrompts)
+	h.handle("GET /v1/prompts/{id}", h.handleGetPrompt)
+	h.handle("PUT /v1/prompts/{id}", h.handleUpdatePrompt)
+	h.handle("DELETE /v1/prompts/{id}", h.handleDeletePrompt)
+	h.handle("GET /v1/prompts/{id}/versions", h.handleListPromptVersions)
+	h.handle("POST /v1/prompts/{id}/default_version", h.handleSetDefaultVersion)
 
 	// Files API
-	h.mux.HandleFunc("POST /v1/files", h.handleUploadFile)
-	h.mux.HandleFunc("GET /v1/files", h.handleListFiles)
-	h.mux.HandleFunc("GET /v1/files/{id}", h.handleGetFile)
-	h.mux.HandleFunc("GET /v1/files/{id}/content", h.handleGetFileContent)
-	h.mux.HandleFunc("DELETE /v1/files/{id}", h.handleDeleteFile)
+	h.handle("POST /v1/files", h.handleUploadFile)
+	h.handle("GET /v1/files", h.handleListFiles)
+	h.handle("GET /v1/files/{id}", h.handleGetFile)
+	h.handle("GET /v1/files/{id}/content", h.handleGetFileContent)
+	h.handle("GET /v1/files/{id}/download_url", h.handleGetFileDownloadURL)
+	h.handle("DELETE /v1/files/{id}", h.handleDeleteFile)
 
 	// Vector Stores API
-	h.mux.HandleFunc("POST /v1/vector_stores", h.handleCreateVectorStore)
-	h.mux.HandleFunc("GET /v1/vector_stores", h.handleListVectorStores)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}", h.handleGetVectorStore)
-	h.mux.HandleFunc("PUT /v1/vector_stores/{id}", h.handleUpdateVectorStore)
-	h.mux.HandleFunc("DELETE /v1/vector_stores/{id}", h.handleDeleteVectorStore)
-	h.mux.HandleFunc("POST /v1/vector_stores/{id}/files", h.handleAddVectorStoreFile)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}/files", h.handleListVectorStoreFiles)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}/files/{file_id}", h.handleGetVectorStoreFile)
-	h.mux.HandleFunc("DELETE /v1/vector_stores/{id}/files/{file_id}", h.handleDeleteVectorStoreFile)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}/files/{file_id}/content", h.handleGetVectorStoreFileContent)
-	h.mux.HandleFunc("POST /v1/vector_stores/{id}/search", h.handleSearchVectorStore)
-	h.mux.HandleFunc("POST /v1/vector_stores/{id}/file_batches", h.handleCreateVectorStoreFileBatch)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}/file_batches/{batch_id}", h.handleGetVectorStoreFileBatch)
-	h.mux.HandleFunc("GET /v1/vector_stores/{id}/file_batches/{batch_id}/files", h.handleListVectorStoreFileBatchFiles)
-	h.mux.HandleFunc("POST /v1/vector_stores/{id}/file_batches/{batch_id}/cancel", h.handleCancelVectorStoreFileBatch)
+	h.handle("POST /v1/vector_stores", h.handleCreateVectorStore)
+	h.handle("GET /v1/vector_stores", h.handleListVectorStores)
+	h.handle("GET /v1/vector_stores/{id}", h.handleGetVectorStore)
+	h.handle("PUT /v1/vector_stores/{id}", h.handleUpdateVectorStore)
+	h.handle("DELETE /v1/vector_stores/{id}", h.handleDeleteVectorStore)
+	h.handle("POST /v1/vector_stores/{id}/reindex", h.handleReindexVectorStore)
+	h.handle("POST /v1/vector_stores/{id}/files", h.handleAddVectorStoreFile)
+	h.handle("GET /v1/vector_stores/{id}/files", h.handleListVectorStoreFiles)
+	h.handle("GET /v1/vector_stores/{id}/files/{file_id}", h.handleGetVectorStoreFile)
+	h.handle("DELETE /v1/vector_stores/{id}/files/{file_id}", h.handleDeleteVectorStoreFile)
+	h.handle("GET /v1/vector_stores/{id}/files/{file_id}/content", h.handleGetVectorStoreFileContent)
+	h.handle("GET /v1/vector_stores/{id}/files/{file_id}/chunks", h.handleListVectorStoreFileChunks)
+	h.handle("POST /v1/vector_stores/{id}/upload", h.handleUploadVectorStoreFile)
+	h.handle("POST /v1/vector_stores/{id}/search", h.handleSearchVectorStore)
+	h.handle("POST /v1/vector_stores/{id}/file_batches", h.handleCreateVectorStoreFileBatch)
+	h.handle("GET /v1/vector_stores/{id}/file_batches/{batch_id}", h.handleGetVectorStoreFileBatch)
+	h.handle("GET /v1/vector_stores/{id}/file_batches/{batch_id}/files", h.handleListVectorStoreFileBatchFiles)
+	h.handle("POST /v1/vector_stores/{id}/file_batches/{batch_id}/cancel", h.handleCancelVectorStoreFileBatch)
 
 	// Connectors API (llama-stack pattern)
-	h.mux.HandleFunc("POST /v1/connectors", h.handleRegisterConnector)
-	h.mux.HandleFunc("GET /v1/connectors", h.handleListConnectors)
-	h.mux.HandleFunc("GET /v1/connectors/{connector_id}", h.handleGetConnector)
-	h.mux.HandleFunc("DELETE /v1/connectors/{connector_id}", h.handleDeleteConnector)
+	h.handle("POST /v1/connectors", h.handleRegisterConnector)
+	h.handle("GET /v1/connectors", h.handleListConnectors)
+	h.handle("GET /v1/connectors/{connector_id}", h.handleGetConnector)
+	h.handle("DELETE /v1/connectors/{connector_id}", h.handleDeleteConnector)
+
+	// Evals API
+	h.handle("POST /v1/evals/run", h.handleRunEval)
+	h.handle("GET /v1/evals/{id}", h.handleGetEval)
+
+	// Admin
+	h.handle("GET /admin", h.handleAdminUI)
+	h.handle("GET /admin/overview", h.handleAdminOverview)
+	h.handle("GET /admin/config", h.handleAdminConfig)
+	h.handle("GET /admin/events", h.handleAdminEvents)
+	h.handle("POST /admin/gc", h.handleGarbageCollect)
+	h.handle("POST /admin/data_deletion", h.handleDataDeletion)
+	h.handle("GET /admin/session_retention", h.handleSessionRetention)
+	h.handle("GET /admin/streams", h.handleStreamStats)
+	h.handle("GET /admin/feature_flags", h.handleListFeatureFlags)
+	h.handle("PUT /admin/feature_flags/{name}", h.handleUpdateFeatureFlag)
+	h.handle("DELETE /admin/feature_flags/{name}", h.handleResetFeatureFlag)
+	h.handle("POST /admin/conversations/{id}/compact", h.handleCompactConversation)
+	h.handle("GET /admin/model_aliases", h.handleListModelAliases)
+	h.handle("PUT /admin/model_aliases/{alias}", h.handleUpdateModelAlias)
+	h.handle("DELETE /admin/model_aliases/{alias}", h.handleDeleteModelAlias)
+	h.handle("GET /v1/admin/audit_logs", h.handleListAuditLogs)
+	h.handle("GET /v1/admin/shadow_results", h.handleListShadowResults)
 
 	return h
 }
 
+// handle registers fn for pattern, recording the pattern for Routes.
+func (h *Handler) handle(pattern string, fn http.HandlerFunc) {
+	h.routes = append(h.routes, pattern)
+	h.mux.HandleFunc(pattern, fn)
+}
+
+// Routes returns the patterns of the routes the handler serves, such as
+// "GET /v1/responses/{id}", in registration order.
+func (h *Handler) Routes() []string {
+	return slices.Clone(h.routes)
+}
+
+// SetStdioManager enables registration of stdio connectors. Registrations
+// are checked against the manager's command allowlist, and deleting a
+// connector stops its process.
+func (h *Handler) SetStdioManager(m *mcp.StdioManager) {
+	h.stdioServers = m
+}
+
+// SetMaxRequestBytes bounds the JSON body of the Responses and Chat
+// Completions endpoints. Larger bodies are refused with 413.
+func (h *Handler) SetMaxRequestBytes(n int64) {
+	h.maxRequestBytes = n
+}
+
+// SetStrictValidation makes /v1/responses refuse unknown top-level fields
+// and malformed input items with 400 instead of ignoring them. Clients can
+// override it per request with the StrictValidationHeader header.
+func (h *Handler) SetStrictValidation(strict bool) {
+	h.strictValidation = strict
+}
+
 // ServeHTTP implements http.Handler
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Attach the request ID that correlates the logs, stored response,
+	// events and backend calls of the request
+	r = requestid.Ensure(w, r)
+
 	// Log request
-	h.logger.Info("Request",
+	h.logger.InfoContext(r.Context(), "Request",
 		"method", r.Method,
 		"path", r.URL.Path,
 		"remote_addr", r.RemoteAddr)
 
+	// Attach the tenant used to evaluate feature flags
+	if h.tenantHeader != "" {
+		if tenant := r.Header.Get(h.tenantHeader); tenant != "" {
+			r = r.WithContext(featureflags.WithTenant(r.Context(), tenant))
+		}
+	}
+	// and the API key fingerprint recorded with responses
+	if fingerprint := apiKeyFingerprint(r); fingerprint != "" {
+		r = r.WithContext(state.WithAPIKey(r.Context(), fingerprint))
+	}
+	// and the time the client gives the response to finish
+	if timeout, ok := requestTimeout(r); ok {
+		r = r.WithContext(engine.WithRequestTimeout(r.Context(), timeout))
+	}
+
 	// Serve
+	if h.audit != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.serveAudited(w, r)
+		return
+	}
 	h.mux.ServeHTTP(w, r)
 }
 
@@ -146,25 +280,34 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 //	@Param			request	body		schema.ResponseRequest	true	"Response request"
 //	@Success		200		{object}	schema.Response
 //	@Failure		400		{object}	map[string]interface{}
+//	@Failure		409		{object}	map[string]interface{}
+//	@Failure		413		{object}	map[string]interface{}
+//	@Failure		429		{object}	map[string]interface{}
 //	@Failure		500		{object}	map[string]interface{}
+//	@Failure		503		{object}	map[string]interface{}
 //	@Router			/v1/responses [post]
 func (h *Handler) handleResponses(w http.ResponseWriter, r *http.Request) {
+	// Refuse new work while shutting down; in-flight responses are drained
+	if !h.drain.begin() {
+		h.writeDraining(w)
+		return
+	}
+	defer h.drain.end()
+
 	// Parse request body
 	var req schema.ResponseRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse request", "error", err)
-		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+	if !h.decodeResponseRequest(w, r, &req) {
 		return
 	}
 
 	// Validate request
 	if err := req.Validate(); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		h.writeValidationError(w, r, err)
 		return
 	}
 
 	// Log request
-	h.logger.Info("Processing response request",
+	h.logger.InfoContext(r.Context(), "Processing response request",
 		"model", req.Model,
 		"stream", req.Stream)
 
@@ -177,19 +320,193 @@ func (h *Handler) handleResponses(w http.ResponseWriter, r *http.Request) {
 	// Non-streaming response
 	resp, err := h.engine.ProcessRequest(r.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to process request", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "processing_error", err.Error())
+		h.writeProcessError(w, r, err)
 		return
 	}
 
 	// Write response
+	setSessionAffinity(w, resp.Conversation)
+	if resp.CacheHit {
+		w.Header().Set(ResponseCacheHeader, "hit")
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 
-	h.logger.Info("Response sent",
+	h.logger.InfoContext(r.Context(), "Response sent", append([]any{
 		"response_id", resp.ID,
-		"status", resp.Status)
+		"status", resp.Status}, usageLogAttrs(resp)...)...)
+}
+
+// decodeRequest decodes the JSON body of r into v, within the request size
+// limit. It writes an error to w and returns false if the body cannot be
+// decoded.
+func (h *Handler) decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	body := r.Body
+	if h.maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	}
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.writeBodyTooLarge(w, r, maxErr)
+			return false
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to parse request", "error", err)
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return false
+	}
+	return true
+}
+
+// decodeResponseRequest decodes a Responses API request like
+// decodeRequest, strictly when strict validation is on for r.
+func (h *Handler) decodeResponseRequest(w http.ResponseWriter, r *http.Request, req *schema.ResponseRequest) bool {
+	if !h.strictFor(r) {
+		return h.decodeRequest(w, r, req)
+	}
+	body := r.Body
+	if h.maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.writeBodyTooLarge(w, r, maxErr)
+			return false
+		}
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+		return false
+	}
+	if err := schema.DecodeStrict(data, req); err != nil {
+		h.writeValidationError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// strictFor reports whether r is validated strictly: as requested by its
+// StrictValidationHeader header, else as configured.
+func (h *Handler) strictFor(r *http.Request) bool {
+	if v := r.Header.Get(StrictValidationHeader); v != "" {
+		if strict, err := strconv.ParseBool(v); err == nil {
+			return strict
+		}
+	}
+	return h.strictValidation
+}
+
+// maxTimeoutSeconds is the longest timeout a time.Duration holds.
+var maxTimeoutSeconds = time.Duration(math.MaxInt64).Seconds()
+
+// requestTimeout returns the timeout set by the RequestTimeoutHeader header
+// of r, in seconds. Malformed, non-positive and overlong values are
+// ignored.
+func requestTimeout(r *http.Request) (time.Duration, bool) {
+	v := r.Header.Get(RequestTimeoutHeader)
+	if v == "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(seconds > 0) || seconds > maxTimeoutSeconds {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// writeBodyTooLarge writes the 413 error of a body over the size limit.
+func (h *Handler) writeBodyTooLarge(w http.ResponseWriter, r *http.Request, err *http.MaxBytesError) {
+	h.writeValidationError(w, r, &schema.LimitError{Message: fmt.Sprintf("request body is larger than %d bytes", err.Limit)})
+}
+
+// writeValidationError writes the error of an invalid request: 413 for a
+// request over a size limit, 400 otherwise.
+func (h *Handler) writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr, ok := apierror.From(err)
+	if !ok {
+		apiErr = apierror.Wrap(http.StatusBadRequest, apierror.CodeInvalidRequest, err)
+	}
+	if apiErr.Code == apierror.CodeRequestTooLarge {
+		h.logger.InfoContext(r.Context(), "Request over size limit", "param", apiErr.Param, "error", apiErr.Message)
+	}
+	h.writeAPIError(w, apiErr)
+}
+
+// writeProcessError writes the error returned by the engine for a request,
+// with the status of its apierror mapping: 400 for invalid requests, those
+// refused by a hook or content moderation and those with an invalid
+// prompt, 409 while another request holds the conversation, 413 over a
+// size limit, 429 and 503 for admission refusals, 504 for timeouts, else
+// 500.
+func (h *Handler) writeProcessError(w http.ResponseWriter, r *http.Request, err error) {
+	var promptErr *engine.PromptError
+	if errors.As(err, &promptErr) {
+		h.writeAPIError(w, apierror.Wrap(http.StatusBadRequest, apierror.CodeInvalidRequest, promptErr))
+		return
+	}
+	if errors.Is(err, engine.ErrInputFlagged) {
+		h.logger.InfoContext(r.Context(), "Request input flagged by moderation", "error", err)
+		h.writeAPIError(w, apierror.Wrap(http.StatusBadRequest, "content_filter", err))
+		return
+	}
+	apiErr := apierror.Internal("processing_error", err)
+	var admitErr *admission.Error
+	var rejectErr *hooks.RejectError
+	switch {
+	case errors.As(err, &admitErr):
+		h.logger.InfoContext(r.Context(), "Request refused by admission control", "reason", admitErr.Reason, "queue_depth", admitErr.QueueDepth)
+		w.Header().Set(QueueDepthHeader, strconv.Itoa(admitErr.QueueDepth))
+		w.Header().Set("Retry-After", "1")
+	case errors.As(err, &rejectErr):
+		h.logger.InfoContext(r.Context(), "Request rejected by hook", "hook", rejectErr.Hook)
+	case apiErr.Code == apierror.CodeRequestTooLarge:
+		h.logger.InfoContext(r.Context(), "Request over size limit", "param", apiErr.Param, "error", apiErr.Message)
+	case apiErr.Status >= 500:
+		h.logger.ErrorContext(r.Context(), "Failed to process request", "error", err)
+	}
+	h.writeAPIError(w, apiErr)
+}
+
+// PrepareBackendRequest rewrites a POST /v1/responses request into the
+// request the model backend expects, for the ExtProc passthrough mode. It
+// writes an error to w and returns nil if the request is refused.
+func (h *Handler) PrepareBackendRequest(w http.ResponseWriter, r *http.Request) *http.Request {
+	if !h.drain.begin() {
+		h.writeDraining(w)
+		return nil
+	}
+	defer h.drain.end()
+
+	if h.tenantHeader != "" {
+		if tenant := r.Header.Get(h.tenantHeader); tenant != "" {
+			r = r.WithContext(featureflags.WithTenant(r.Context(), tenant))
+		}
+	}
+
+	var req schema.ResponseRequest
+	if !h.decodeResponseRequest(w, r, &req) {
+		return nil
+	}
+	if err := req.Validate(); err != nil {
+		h.writeValidationError(w, r, err)
+		return nil
+	}
+
+	backendReq, err := h.engine.PrepareBackendRequest(r.Context(), &req)
+	if err != nil {
+		h.writeProcessError(w, r, err)
+		return nil
+	}
+
+	out, err := http.NewRequestWithContext(r.Context(), http.MethodPost, backendReq.Path, bytes.NewReader(backendReq.Body))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "processing_error", err.Error())
+		return nil
+	}
+	out.Header = backendReq.Header
+	h.logger.InfoContext(r.Context(), "Forwarding request to backend", "model", req.Model, "path", backendReq.Path)
+	return out
 }
 
 // handleGetResponse handles GET /v1/responses/{id}
@@ -210,12 +527,12 @@ func (h *Handler) handleGetResponse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting response", "response_id", responseID)
+	h.logger.InfoContext(r.Context(), "Getting response", "response_id", responseID)
 
 	// Get response from session store
 	resp, err := h.engine.GetResponse(r.Context(), responseID)
 	if err != nil {
-		h.logger.Error("Failed to get response", "error", err, "response_id", responseID)
+		h.logger.ErrorContext(r.Context(), "Failed to get response", "error", err, "response_id", responseID)
 		h.writeError(w, http.StatusNotFound, "response_not_found", err.Error())
 		return
 	}
@@ -225,7 +542,7 @@ func (h *Handler) handleGetResponse(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 
-	h.logger.Info("Response retrieved",
+	h.logger.InfoContext(r.Context(), "Response retrieved",
 		"response_id", resp.ID,
 		"status", resp.Status)
 }
@@ -240,7 +557,17 @@ func (h *Handler) handleGetResponse(w http.ResponseWriter, r *http.Request) {
 //	@Param		limit	query		int		false	"Number of items (1-100, default 20)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
 //	@Param		model	query		string	false	"Filter by model"
+//	@Param		external_id	query		string	false	"Filter by client-supplied external ID"
+//	@Param		conversation	query		string	false	"Filter by conversation ID"
+//	@Param		status	query		string	false	"Filter by status"
+//	@Param		api_key	query		string	false	"Filter by the fingerprint of the API key that created the response"
+//	@Param		request_id	query		string	false	"Filter by the X-Request-ID of the request that created the response"
+//	@Param		created_after	query		int		false	"Only responses created after this Unix timestamp"
+//	@Param		created_before	query		int		false	"Only responses created before this Unix timestamp"
+//	@Param		metadata	query		string	false	"Filter by metadata, as metadata[key]=value (repeatable)"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListResponsesResponse
+//	@Failure	400		{object}	map[string]interface{}
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/responses [get]
 func (h *Handler) handleListResponses(w http.ResponseWriter, r *http.Request) {
@@ -249,7 +576,11 @@ func (h *Handler) handleListResponses(w http.ResponseWriter, r *http.Request) {
 	before := r.URL.Query().Get("before")
 	limitStr := r.URL.Query().Get("limit")
 	order := r.URL.Query().Get("order")
-	model := r.URL.Query().Get("model")
+	filter, err := parseResponseFilter(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
 
 	// Default values
 	limit := 20
@@ -263,18 +594,18 @@ func (h *Handler) handleListResponses(w http.ResponseWriter, r *http.Request) {
 		order = "desc"
 	}
 
-	h.logger.Info("Listing responses",
+	h.logger.InfoContext(r.Context(), "Listing responses",
 		"after", after,
 		"before", before,
 		"limit", limit,
 		"order", order,
-		"model", model)
+		"model", filter.Model)
 
 	// Get responses from engine
-	responses, hasMore, err := h.engine.ListResponses(r.Context(), after, before, limit, order, model)
+	responses, hasMore, err := h.engine.ListResponses(r.Context(), after, before, limit, order, filter)
 	if err != nil {
-		h.logger.Error("Failed to list responses", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_failed", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list responses", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
@@ -290,12 +621,96 @@ func (h *Handler) handleListResponses(w http.ResponseWriter, r *http.Request) {
 		result["last_id"] = responses[len(responses)-1].ID
 	}
 
+	if includeTotal(r.URL.Query()) {
+		total, err := h.engine.CountResponses(r.Context(), filter)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count responses", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+			return
+		}
+		result["total_count"] = total
+	}
+
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 
-	h.logger.Info("Responses listed", "count", len(responses), "has_more", hasMore)
+	h.logger.InfoContext(r.Context(), "Responses listed", "count", len(responses), "has_more", hasMore)
+}
+
+// handleExportResponses handles GET /v1/responses/export
+//
+//	@Summary		Export training data
+//	@Description	Streams a JSON Lines file with one fine-tuning example per completed response, oldest first, derived from its stored messages.
+//	@Tags			Responses
+//	@Produce		application/x-ndjson
+//	@Param			format	query		string	false	"Example format: chat (default) or prompt_completion"
+//	@Param			model	query		string	false	"Filter by model"
+//	@Param			external_id	query		string	false	"Filter by client-supplied external ID"
+//	@Param			conversation	query		string	false	"Filter by conversation ID"
+//	@Param			api_key	query		string	false	"Filter by the fingerprint of the API key that created the response"
+//	@Param			request_id	query		string	false	"Filter by the X-Request-ID of the request that created the response"
+//	@Param			created_after	query		int		false	"Only responses created after this Unix timestamp"
+//	@Param			created_before	query		int		false	"Only responses created before this Unix timestamp"
+//	@Param			metadata	query		string	false	"Filter by metadata, as metadata[key]=value (repeatable)"
+//	@Success		200	{string}	string	"JSON Lines file"
+//	@Failure		400	{object}	map[string]interface{}
+//	@Failure		500	{object}	map[string]interface{}
+//	@Router			/v1/responses/export [get]
+func (h *Handler) handleExportResponses(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.TrainingFormatChat
+	}
+	if format != services.TrainingFormatChat && format != services.TrainingFormatPromptCompletion {
+		h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter,
+			fmt.Sprintf("'format' must be %q or %q", services.TrainingFormatChat, services.TrainingFormatPromptCompletion))
+		return
+	}
+	filter, err := parseResponseFilter(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Exporting training data", "format", format, "model", filter.Model, "conversation", filter.ConversationID)
+
+	// The status is sent with the first example, so a store error before
+	// it can still be reported
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="responses.jsonl"`)
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+	n, err := services.ExportTrainingData(r.Context(), h.engine.Store(), filter, format, func(example interface{}) error {
+		if !started {
+			start()
+		}
+		if err := enc.Encode(example); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to export training data", "error", err, "examples", n)
+		if !started {
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		}
+		return
+	}
+	if !started {
+		start()
+	}
+
+	h.logger.InfoContext(r.Context(), "Training data exported", "examples", n)
 }
 
 // handleDeleteResponse handles DELETE /v1/responses/{id}
@@ -316,12 +731,12 @@ func (h *Handler) handleDeleteResponse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Deleting response", "response_id", responseID)
+	h.logger.InfoContext(r.Context(), "Deleting response", "response_id", responseID)
 
 	// Delete response from engine
 	if err := h.engine.DeleteResponse(r.Context(), responseID); err != nil {
-		h.logger.Error("Failed to delete response", "error", err, "response_id", responseID)
-		h.writeError(w, http.StatusInternalServerError, "delete_failed", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to delete response", "error", err, "response_id", responseID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
@@ -334,7 +749,7 @@ func (h *Handler) handleDeleteResponse(w http.ResponseWriter, r *http.Request) {
 		"deleted": true,
 	})
 
-	h.logger.Info("Response deleted", "response_id", responseID)
+	h.logger.InfoContext(r.Context(), "Response deleted", "response_id", responseID)
 }
 
 // handleGetResponseInputItems handles GET /v1/responses/{id}/input_items
@@ -342,10 +757,14 @@ func (h *Handler) handleDeleteResponse(w http.ResponseWriter, r *http.Request) {
 //	@Summary	List response input items
 //	@Tags		Responses
 //	@Produce	json
-//	@Param		id	path		string	true	"Response ID"
-//	@Success	200	{object}	schema.ListInputItemsResponse
-//	@Failure	400	{object}	map[string]interface{}
-//	@Failure	404	{object}	map[string]interface{}
+//	@Param		id		path		string	true	"Response ID"
+//	@Param		after	query		string	false	"Item ID to list after"
+//	@Param		before	query		string	false	"Item ID to list before"
+//	@Param		limit	query		int		false	"Number of items (1-100, default 20)"
+//	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
+//	@Success	200		{object}	schema.ListInputItemsResponse
+//	@Failure	400		{object}	map[string]interface{}
+//	@Failure	404		{object}	map[string]interface{}
 //	@Router		/v1/responses/{id}/input_items [get]
 func (h *Handler) handleGetResponseInputItems(w http.ResponseWriter, r *http.Request) {
 	// Extract response ID from path
@@ -355,25 +774,102 @@ func (h *Handler) handleGetResponseInputItems(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	h.logger.Info("Getting response input items", "response_id", responseID)
+	query := r.URL.Query()
+	after := query.Get("after")
+	before := query.Get("before")
+	order := query.Get("order")
+	if order == "" {
+		order = "desc"
+	}
+	limit := 20
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := parseInt(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	h.logger.InfoContext(r.Context(), "Listing response input items", "response_id", responseID, "after", after, "before", before, "limit", limit, "order", order)
 
-	// Get input items from engine
-	items, err := h.engine.GetResponseInputItems(r.Context(), responseID)
+	items, hasMore, err := h.engine.ListResponseInputItems(r.Context(), responseID, after, before, limit, order)
 	if err != nil {
-		h.logger.Error("Failed to get response input items", "error", err, "response_id", responseID)
+		if errors.Is(err, engine.ErrInputItemNotFound) {
+			h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter, err.Error())
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to get response input items", "error", err, "response_id", responseID)
 		h.writeError(w, http.StatusNotFound, "not_found", err.Error())
 		return
 	}
 
-	// Write response
+	result := schema.ListInputItemsResponse{
+		Object:  "list",
+		Data:    items,
+		HasMore: hasMore,
+	}
+	if len(items) > 0 {
+		result.FirstID = items[0].ID
+		result.LastID = items[len(items)-1].ID
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"object": "list",
-		"data":   items,
-	})
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseResponseFilter reads the list filters from the query string. Metadata
+// pairs are given as metadata[key]=value.
+func parseResponseFilter(query url.Values) (state.ResponseFilter, error) {
+	filter := state.ResponseFilter{
+		Model:          query.Get("model"),
+		ExternalID:     query.Get("external_id"),
+		ConversationID: query.Get("conversation"),
+		Status:         query.Get("status"),
+		APIKey:         query.Get("api_key"),
+		RequestID:      query.Get("request_id"),
+	}
+	if err := parseCreatedBounds(query, &filter.CreatedAfter, &filter.CreatedBefore); err != nil {
+		return filter, err
+	}
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, "metadata[")
+		if !ok || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = make(map[string]string)
+		}
+		filter.Metadata[strings.TrimSuffix(key, "]")] = values[0]
+	}
+	return filter, nil
+}
+
+// parseCreatedBounds parses the created_after and created_before Unix
+// timestamps of a list request.
+func parseCreatedBounds(query url.Values, after, before *time.Time) error {
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"created_after", after},
+		{"created_before", before},
+	} {
+		v := query.Get(bound.name)
+		if v == "" {
+			continue
+		}
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a Unix timestamp", bound.name)
+		}
+		*bound.dst = time.Unix(ts, 0)
+	}
+	return nil
+}
 
-	h.logger.Info("Response input items retrieved", "response_id", responseID)
+// includeTotal reports whether a list request asked for total_count.
+func includeTotal(query url.Values) bool {
+	include, _ := strconv.ParseBool(query.Get("include_total"))
+	return include
 }
 
 // parseInt parses a string to int, returning 0 if failed
@@ -392,56 +888,163 @@ func (h *Handler) handleStreamingResponse(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Get event stream. Errors before the stream starts get the same
+	// status and error envelope as without streaming.
+	events, err := h.engine.ProcessRequestStream(r.Context(), req)
+	if err != nil {
+		h.writeProcessError(w, r, err)
+		return
+	}
+
+	// Peek at response.created so the session affinity header can be set
+	// before the SSE headers are flushed.
+	first := <-events
+	if created, ok := first.(*schema.ResponseCreatedStreamingEvent); ok {
+		setSessionAffinity(w, created.Response.Conversation)
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.WriteHeader(http.StatusOK)
 
-	// Get event stream
-	events, err := h.engine.ProcessRequestStream(r.Context(), req)
-	if err != nil {
-		h.logger.Error("Failed to start streaming", "error", err)
-		fmt.Fprintf(w, "data: {\"error\":\"%s\"}\n\n", err.Error())
-		flusher.Flush()
+	// Stream events, dropping those the client opted out of before they
+	// are serialized
+	if first != nil && req.StreamOptions.Allows(schema.ExtractEventType(first)) {
+		h.writeSSEEvent(w, r, flusher, first)
+	}
+	var final *schema.Response
+	for event := range events {
+		if resp := finalResponse(event); resp != nil {
+			final = resp
+		}
+		if req.StreamOptions.Allows(schema.ExtractEventType(event)) {
+			h.writeSSEEvent(w, r, flusher, event)
+		}
+	}
+
+	if final == nil {
+		h.logger.InfoContext(r.Context(), "Streaming completed")
 		return
 	}
+	h.logger.InfoContext(r.Context(), "Streaming completed", append([]any{
+		"response_id", final.ID,
+		"status", final.Status}, usageLogAttrs(final)...)...)
+}
 
-	// Stream events
-	for event := range events {
-		data, err := json.Marshal(event)
-		if err != nil {
-			h.logger.Error("Failed to marshal event", "error", err)
-			continue
+// sseBuffers holds the buffers SSE events are encoded into. A stream
+// writes one event per token, so the buffers are reused rather than
+// allocated for every event.
+var sseBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledSSEBuffer bounds the buffers kept in sseBuffers, so that the
+// few large events, such as response.completed, do not pin their memory.
+const maxPooledSSEBuffer = 64 << 10
+
+// writeSSE encodes v as the data of an SSE event named eventType, or of an
+// unnamed event if eventType is empty, and writes the whole event at once.
+// It only fails if v cannot be encoded; write errors mean the client went
+// away, which the caller notices from the request context.
+func writeSSE(w io.Writer, eventType string, v interface{}) error {
+	buf := sseBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledSSEBuffer {
+			sseBuffers.Put(buf)
 		}
+	}()
+
+	if eventType != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(eventType)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("data: ")
+	// Encode terminates the data line with a newline
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	w.Write(buf.Bytes())
+	return nil
+}
+
+// writeSSEEvent writes a single event in SSE format and flushes it.
+func (h *Handler) writeSSEEvent(w http.ResponseWriter, r *http.Request, flusher http.Flusher, event interface{}) {
+	if err := writeSSE(w, schema.ExtractEventType(event), event); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to marshal event", "error", err)
+		return
+	}
+	flusher.Flush()
+}
+
+// SessionAffinityHeader carries the conversation ID on /v1/responses
+// replies. Clients echo it on follow-up turns so a proxy such as Envoy can
+// hash on it and keep a conversation on the same replica.
+const SessionAffinityHeader = "X-Session-Affinity"
+
+// ResponseCacheHeader is set to "hit" on /v1/responses replies whose output
+// was served from the response cache instead of the backend.
+const ResponseCacheHeader = "X-Response-Cache"
 
-		// Extract event type for SSE event field
-		eventType := schema.ExtractEventType(event)
+// StrictValidationHeader turns strict validation of a /v1/responses request
+// on ("true") or off ("false"), overriding the server configuration.
+const StrictValidationHeader = "X-Strict-Validation"
 
-		// Write SSE event
-		fmt.Fprintf(w, "event: %s\n", eventType)
-		fmt.Fprintf(w, "data: %s\n\n", data)
-		flusher.Flush()
+// RequestTimeoutHeader sets the number of seconds a response has to
+// finish, lowering the configured engine.loop.request_timeout.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// QueueDepthHeader reports the number of requests waiting for a backend
+// slot when admission control refuses a request.
+const QueueDepthHeader = "X-Queue-Depth"
+
+// ResponseHeaders lists the headers the gateway sets on replies for
+// clients to read. Browsers only let pages from other origins read them
+// when CORS exposes them.
+var ResponseHeaders = []string{requestid.Header, SessionAffinityHeader, ResponseCacheHeader, QueueDepthHeader, "Retry-After", "Content-Disposition"}
+
+// usageLogAttrs returns the log attributes recording the usage of a
+// response against its model, and against the alias it was requested by.
+func usageLogAttrs(resp *schema.Response) []any {
+	attrs := []any{"model", resp.Model}
+	if resp.ModelAlias != nil {
+		attrs = append(attrs, "model_alias", *resp.ModelAlias)
+	}
+	if resp.Usage != nil {
+		attrs = append(attrs, "input_tokens", resp.Usage.InputTokens, "output_tokens", resp.Usage.OutputTokens)
+	}
+	return attrs
+}
+
+// finalResponse returns the response carried by a terminal streaming event.
+func finalResponse(event interface{}) *schema.Response {
+	switch e := event.(type) {
+	case *schema.ResponseCompletedStreamingEvent:
+		return &e.Response
+	case *schema.ResponseIncompleteStreamingEvent:
+		return &e.Response
+	case *schema.ResponseFailedStreamingEvent:
+		return &e.Response
 	}
+	return nil
+}
 
-	h.logger.Info("Streaming completed")
+// setSessionAffinity sets the session affinity header to the conversation ID.
+func setSessionAffinity(w http.ResponseWriter, conversationID *string) {
+	if conversationID != nil && *conversationID != "" {
+		w.Header().Set(SessionAffinityHeader, *conversationID)
+	}
 }
 
-// writeError writes an error response
-func (h *Handler) writeError(w http.ResponseWriter, status int, errType, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": map[string]string{
-			"type":    errType,
-			"message": message,
-		},
-	})
+// writeError writes an error response. Its type follows from the status;
+// code tells it apart from the other errors of the endpoint.
+func (h *Handler) writeError(w http.ResponseWriter, status int, code, message string) {
+	h.writeAPIError(w, apierror.New(status, code, message))
 }
 
-// generateID generates a unique ID with a prefix
-func generateID(prefix string) string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return prefix + hex.EncodeToString(b)
+// writeAPIError writes an error response in the error envelope.
+func (h *Handler) writeAPIError(w http.ResponseWriter, err *apierror.Error) {
+	apierror.Write(w, err)
 }
@@ -0,0 +1,323 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/config"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/hooks"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	filememory "github.com/leseb/openresponses-gw/pkg/filestore/memory"
+	"github.com/leseb/openresponses-gw/pkg/observability/logging"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+	"github.com/leseb/openresponses-gw/pkg/storage/sqlite"
+)
+
+// newChatBackend serves the chat completions API, answering every request
+// with "Hello", streamed as a single delta when asked to.
+func newChatBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		usage := `{"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}`
+		if !req.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": %q, "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}], "usage": %s}`, req.Model, usage)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices": [{"index": 0, "delta": {"role": "assistant", "content": "Hello"}}]}`,
+			`{"choices": [{"index": 0, "delta": {}, "finish_reason": "stop"}]}`,
+			`{"choices": [], "usage": ` + usage + `}`,
+		} {
+			fmt.Fprintf(w, "data: {\"id\": \"chatcmpl-1\", \"object\": \"chat.completion.chunk\", \"created\": 1, \"model\": %q, %s\n\n", req.Model, strings.TrimPrefix(chunk, "{"))
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestHandler returns a handler whose engine stores sessions in SQLite
+// and calls a newChatBackend, with its config changed by opts.
+func newTestHandler(t *testing.T, opts ...func(*config.EngineConfig)) *Handler {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	prompts := memory.NewPromptsStore()
+	cfg := &config.EngineConfig{ModelEndpoint: newChatBackend(t).URL + "/v1"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	eng, err := engine.New(cfg, store, nil, nil, nil, prompts)
+	if err != nil {
+		t.Fatalf("engine.New() error = %v", err)
+	}
+	logger := logging.New(logging.Config{Level: "error", Output: io.Discard})
+	return New(eng, logger, prompts, filememory.New(), memory.NewVectorStoresStore(), memory.NewConnectorsStore(), nil)
+}
+
+// serve sends a request with a JSON body to h and returns the recorded
+// reply.
+func serve(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// errorCode returns the code of the error envelope in the body of w.
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not an error envelope: %s", w.Body.String())
+	}
+	return body.Error.Code
+}
+
+// rejectHook refuses requests for the model "blocked".
+type rejectHook struct{}
+
+func (rejectHook) OnRequest(_ context.Context, req *schema.ResponseRequest) error {
+	if req.Model != nil && *req.Model == "blocked" {
+		return &hooks.RejectError{Hook: "policy", Message: "model not allowed"}
+	}
+	return nil
+}
+
+// flagModerator flags every input containing "forbidden".
+type flagModerator struct{}
+
+func (flagModerator) Check(_ context.Context, text string) ([]string, error) {
+	if strings.Contains(text, "forbidden") {
+		return []string{"violence"}, nil
+	}
+	return nil, nil
+}
+
+// Errors before a stream starts get the status and error envelope the
+// same request gets without streaming
+func TestHandleResponses_Errors(t *testing.T) {
+	h := newTestHandler(t, func(cfg *config.EngineConfig) { cfg.RequestLimits.MaxTools = 1 })
+	chain := hooks.NewChain()
+	chain.Add(rejectHook{})
+	h.engine.SetHooks(chain)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		streamOnly bool
+	}{
+		{
+			name:       "rejected by a hook",
+			body:       `{"model": "blocked", "input": "hi"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "request_rejected",
+		},
+		{
+			name:       "unknown prompt",
+			body:       `{"model": "m", "input": "hi", "prompt": {"id": "pmpt_missing"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "over a size limit",
+			body:       `{"model": "m", "input": "hi", "tools": [{"type": "function", "name": "a"}, {"type": "function", "name": "b"}]}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "request_too_large",
+		},
+		{
+			name:       "background stream",
+			body:       `{"model": "m", "input": "hi", "background": true}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter",
+			streamOnly: true,
+		},
+	}
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			if tt.streamOnly && !stream {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.name, stream), func(t *testing.T) {
+				body := tt.body
+				if stream {
+					body = strings.Replace(body, "{", `{"stream": true, `, 1)
+				}
+				w := serve(h, http.MethodPost, "/v1/responses", body)
+				if w.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+				}
+				if got := w.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", got)
+				}
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("error code = %q, want %q", code, tt.wantCode)
+				}
+			})
+		}
+	}
+}
+
+func TestPrepareBackendRequest_Errors(t *testing.T) {
+	h := newTestHandler(t, func(cfg *config.EngineConfig) { cfg.RequestLimits.MaxTools = 1 })
+	chain := hooks.NewChain()
+	chain.Add(rejectHook{})
+	h.engine.SetHooks(chain)
+	h.engine.SetModerator(flagModerator{}, true, false, "")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "forwarded", body: `{"model": "m", "input": "hi"}`, wantStatus: http.StatusOK},
+		{name: "rejected by a hook", body: `{"model": "blocked", "input": "hi"}`, wantStatus: http.StatusBadRequest, wantCode: "request_rejected"},
+		{name: "unknown prompt", body: `{"model": "m", "input": "hi", "prompt": {"id": "pmpt_missing"}}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request"},
+		{name: "flagged input", body: `{"model": "m", "input": "something forbidden"}`, wantStatus: http.StatusBadRequest, wantCode: "content_filter"},
+		{name: "over a size limit", body: `{"model": "m", "input": "hi", "tools": [{"type": "function", "name": "a"}, {"type": "function", "name": "b"}]}`, wantStatus: http.StatusRequestEntityTooLarge, wantCode: "request_too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			out := h.PrepareBackendRequest(w, r)
+			if tt.wantStatus == http.StatusOK {
+				if out == nil || out.Method != http.MethodPost {
+					t.Fatalf("PrepareBackendRequest() = %v, %s", out, w.Body.String())
+				}
+				return
+			}
+			if out != nil {
+				t.Fatal("PrepareBackendRequest() returned a request")
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if code := errorCode(t, w); code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+// sseEventTypes returns the types of the events of an SSE body.
+func sseEventTypes(t *testing.T, body string) []string {
+	t.Helper()
+	var types []string
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("event %q is not JSON: %v", data, err)
+		}
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestHandleResponses_SessionAffinity(t *testing.T) {
+	h := newTestHandler(t)
+	w := serve(h, http.MethodPost, "/v1/conversations", `{}`)
+	var conv struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil || conv.ID == "" {
+		t.Fatalf("create conversation = %d %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantHeader string
+		wantFirst  string // first streamed event, if streaming
+	}{
+		{
+			name:       "conversation",
+			body:       `{"model": "m", "input": "hi", "conversation": "` + conv.ID + `"}`,
+			wantHeader: conv.ID,
+		},
+		{
+			name:       "conversation streamed",
+			body:       `{"model": "m", "input": "hi", "stream": true, "conversation": "` + conv.ID + `"}`,
+			wantHeader: conv.ID,
+			wantFirst:  "response.created",
+		},
+		// Every response is in a conversation unless it fails first
+		{
+			name: "no conversation",
+			body: `{"model": "m", "input": "hi", "conversation": "conv_missing"}`,
+		},
+		{
+			name:      "no conversation streamed",
+			body:      `{"model": "m", "input": "hi", "stream": true, "conversation": "conv_missing"}`,
+			wantFirst: "error",
+		},
+		{
+			name:       "response.created filtered out",
+			body:       `{"model": "m", "input": "hi", "stream": true, "conversation": "` + conv.ID + `", "stream_options": {"exclude_events": ["response.created"]}}`,
+			wantHeader: conv.ID,
+			wantFirst:  "response.in_progress",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, http.MethodPost, "/v1/responses", tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get(SessionAffinityHeader); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", SessionAffinityHeader, got, tt.wantHeader)
+			}
+			if tt.wantFirst == "" {
+				return
+			}
+			types := sseEventTypes(t, w.Body.String())
+			if len(types) == 0 || types[0] != tt.wantFirst {
+				t.Fatalf("events = %v, want %s first", types, tt.wantFirst)
+			}
+			if n := strings.Count(strings.Join(types, " "), "response.created"); tt.wantFirst == "response.created" && n != 1 {
+				t.Errorf("response.created sent %d times", n)
+			}
+			if tt.wantHeader != "" && types[len(types)-1] != "response.completed" {
+				t.Errorf("events = %v, want response.completed last", types)
+			}
+		})
+	}
+}
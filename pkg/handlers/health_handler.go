@@ -0,0 +1,73 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/health"
+)
+
+// SetHealthChecker sets the dependency checks reported by /healthz and
+// /readyz. Without one, both report ok with no checks.
+func (h *Handler) SetHealthChecker(c *health.Checker) {
+	h.health = c
+}
+
+// handleLiveness handles GET /healthz
+//
+//	@Summary		Liveness check
+//	@Description	Report the status of each dependency (session store, file store, vector backend, embedding and model endpoints). Always returns 200 while the gateway is serving, so that a failing dependency does not get the gateway restarted; use /readyz to take it out of rotation.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	schema.HealthResponse
+//	@Router			/healthz [get]
+func (h *Handler) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, http.StatusOK, h.health.Check(r.Context()))
+}
+
+// handleReadiness handles GET /readyz
+//
+//	@Summary		Readiness check
+//	@Description	Check each dependency (session store, file store, vector backend, embedding and model endpoints). Returns 503 when any check fails, or with status "draining" once the gateway is shutting down. Results are cached for a few seconds.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	schema.HealthResponse
+//	@Failure		503	{object}	schema.HealthResponse
+//	@Router			/readyz [get]
+func (h *Handler) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Check(r.Context())
+	if h.drain.isDraining() {
+		report = &health.Report{Status: health.StatusDraining, Checks: report.Checks, CheckedAt: report.CheckedAt}
+	}
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeHealth(w, status, report)
+}
+
+func (h *Handler) writeHealth(w http.ResponseWriter, status int, report *health.Report) {
+	resp := schema.HealthResponse{
+		Status: report.Status,
+		Checks: make(map[string]schema.HealthCheckResult, len(report.Checks)),
+	}
+	if !report.CheckedAt.IsZero() {
+		resp.CheckedAt = report.CheckedAt.Unix()
+	}
+	for name, res := range report.Checks {
+		resp.Checks[name] = schema.HealthCheckResult{
+			Status:    res.Status,
+			Error:     res.Error,
+			LatencyMS: res.Latency.Milliseconds(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,33 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed playground/index.html
+var playgroundHTML []byte
+
+// EnablePlayground serves the developer playground at /playground. The page
+// sends requests to this gateway from the browser, shows streamed events as
+// they arrive, fetches the stored response and keeps a replayable history in
+// local storage. It has no access control and is meant for local use.
+func (h *Handler) EnablePlayground() {
+	h.playground = true
+}
+
+// handlePlayground serves the playground page, or 404 when it is disabled.
+func (h *Handler) handlePlayground(w http.ResponseWriter, r *http.Request) {
+	if !h.playground {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(playgroundHTML)
+}
@@ -0,0 +1,30 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandlePlayground(t *testing.T) {
+	h := newTestHandler(t)
+	if w := serve(h, http.MethodGet, "/playground", ""); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", w.Code)
+	}
+
+	h.EnablePlayground()
+	w := serve(h, http.MethodGet, "/playground", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("enabled: status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html; charset=utf-8", got)
+	}
+	if !bytes.Equal(w.Body.Bytes(), playgroundHTML) || !strings.Contains(w.Body.String(), "<title>Open Responses Gateway Playground</title>") {
+		t.Errorf("body is not the embedded playground page")
+	}
+}
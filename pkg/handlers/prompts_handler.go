@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
 )
@@ -46,7 +47,7 @@ func (h *Handler) handleCreatePrompt(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req schema.CreatePromptRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse prompt request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse prompt request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -62,7 +63,7 @@ func (h *Handler) handleCreatePrompt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create prompt
-	promptID := generateID("prompt_")
+	promptID := h.engine.NewID("prompt_")
 	now := time.Now()
 
 	prompt := &memory.Prompt{
@@ -77,12 +78,12 @@ func (h *Handler) handleCreatePrompt(w http.ResponseWriter, r *http.Request) {
 
 	err := h.promptsStore.CreatePrompt(r.Context(), prompt)
 	if err != nil {
-		h.logger.Error("Failed to create prompt", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to create prompt", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
-	h.logger.Info("Prompt created", "prompt_id", promptID)
+	h.logger.InfoContext(r.Context(), "Prompt created", "prompt_id", promptID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -98,6 +99,7 @@ func (h *Handler) handleCreatePrompt(w http.ResponseWriter, r *http.Request) {
 //	@Param		before	query		string	false	"Cursor for pagination (backwards)"
 //	@Param		limit	query		int		false	"Number of items (1-100, default 50)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListPromptsResponse
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/prompts [get]
@@ -118,15 +120,15 @@ func (h *Handler) handleListPrompts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.logger.Info("Listing prompts", "after", after, "limit", limit, "order", order)
+	h.logger.InfoContext(r.Context(), "Listing prompts", "after", after, "limit", limit, "order", order)
 
 	// Get prompts from storage
 	prompts, hasMore, err := h.promptsStore.ListPromptsPaginated(
 		r.Context(), after, before, limit, order,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list prompts", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list prompts", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
 		return
 	}
 
@@ -148,6 +150,16 @@ func (h *Handler) handleListPrompts(w http.ResponseWriter, r *http.Request) {
 		listResp.LastID = schemaPrompts[len(schemaPrompts)-1].ID
 	}
 
+	if includeTotal(query) {
+		total, err := h.promptsStore.CountPrompts(r.Context())
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count prompts", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -172,7 +184,7 @@ func (h *Handler) handleGetPrompt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting prompt", "prompt_id", promptID)
+	h.logger.InfoContext(r.Context(), "Getting prompt", "prompt_id", promptID)
 
 	// Check for optional ?version=N query param
 	var prompt *memory.Prompt
@@ -190,7 +202,7 @@ func (h *Handler) handleGetPrompt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to get prompt", "error", err, "prompt_id", promptID)
+		h.logger.ErrorContext(r.Context(), "Failed to get prompt", "error", err, "prompt_id", promptID)
 		h.writeError(w, http.StatusNotFound, "prompt_not_found", err.Error())
 		return
 	}
@@ -224,7 +236,7 @@ func (h *Handler) handleUpdatePrompt(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req schema.UpdatePromptRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse update request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse update request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -235,7 +247,7 @@ func (h *Handler) handleUpdatePrompt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Updating prompt", "prompt_id", promptID, "version", req.Version)
+	h.logger.InfoContext(r.Context(), "Updating prompt", "prompt_id", promptID, "version", req.Version)
 
 	// Build updates
 	updates := &memory.Prompt{}
@@ -255,7 +267,7 @@ func (h *Handler) handleUpdatePrompt(w http.ResponseWriter, r *http.Request) {
 	// Create new version in storage
 	newPrompt, err := h.promptsStore.UpdatePrompt(r.Context(), promptID, req.Version, updates, req.SetAsDefault)
 	if err != nil {
-		h.logger.Error("Failed to update prompt", "error", err, "prompt_id", promptID)
+		h.logger.ErrorContext(r.Context(), "Failed to update prompt", "error", err, "prompt_id", promptID)
 		// Return 409 for version mismatch, 404 for not found
 		var vme *memory.VersionMismatchError
 		if errors.As(err, &vme) {
@@ -289,12 +301,12 @@ func (h *Handler) handleDeletePrompt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Deleting prompt", "prompt_id", promptID)
+	h.logger.InfoContext(r.Context(), "Deleting prompt", "prompt_id", promptID)
 
 	// Delete prompt from storage
 	err := h.promptsStore.DeletePrompt(r.Context(), promptID)
 	if err != nil {
-		h.logger.Error("Failed to delete prompt", "error", err, "prompt_id", promptID)
+		h.logger.ErrorContext(r.Context(), "Failed to delete prompt", "error", err, "prompt_id", promptID)
 		h.writeError(w, http.StatusNotFound, "prompt_not_found", err.Error())
 		return
 	}
@@ -328,11 +340,11 @@ func (h *Handler) handleListPromptVersions(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	h.logger.Info("Listing prompt versions", "prompt_id", promptID)
+	h.logger.InfoContext(r.Context(), "Listing prompt versions", "prompt_id", promptID)
 
 	versions, err := h.promptsStore.ListPromptVersions(r.Context(), promptID)
 	if err != nil {
-		h.logger.Error("Failed to list prompt versions", "error", err, "prompt_id", promptID)
+		h.logger.ErrorContext(r.Context(), "Failed to list prompt versions", "error", err, "prompt_id", promptID)
 		h.writeError(w, http.StatusNotFound, "prompt_not_found", err.Error())
 		return
 	}
@@ -379,7 +391,7 @@ func (h *Handler) handleSetDefaultVersion(w http.ResponseWriter, r *http.Request
 
 	var req schema.SetDefaultVersionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse set default version request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse set default version request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -389,11 +401,11 @@ func (h *Handler) handleSetDefaultVersion(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.logger.Info("Setting default version", "prompt_id", promptID, "version", req.Version)
+	h.logger.InfoContext(r.Context(), "Setting default version", "prompt_id", promptID, "version", req.Version)
 
 	prompt, err := h.promptsStore.SetDefaultVersion(r.Context(), promptID, req.Version)
 	if err != nil {
-		h.logger.Error("Failed to set default version", "error", err, "prompt_id", promptID)
+		h.logger.ErrorContext(r.Context(), "Failed to set default version", "error", err, "prompt_id", promptID)
 		h.writeError(w, http.StatusNotFound, "prompt_not_found", err.Error())
 		return
 	}
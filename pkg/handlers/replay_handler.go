@@ -0,0 +1,99 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+)
+
+// handleGetResponseRecording handles GET /v1/responses/{id}/recording
+//
+//	@Summary		Get response recording
+//	@Description	Get the backend calls, MCP tool listings and server-side tool results recorded for a response made in record mode (engine.recording.enabled). A recording can be kept as a fixture for regression tests of the agentic loop.
+//	@Tags			Responses
+//	@Produce		json
+//	@Param			id	path		string	true	"Response ID"
+//	@Success		200	{object}	schema.ResponseRecording
+//	@Failure		404	{object}	map[string]interface{}
+//	@Failure		500	{object}	map[string]interface{}
+//	@Router			/v1/responses/{id}/recording [get]
+func (h *Handler) handleGetResponseRecording(w http.ResponseWriter, r *http.Request) {
+	recording, ok := h.getRecording(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(recording)
+}
+
+// handleReplayResponse handles POST /v1/responses/{id}/replay
+//
+//	@Summary		Replay response
+//	@Description	Run the agentic loop of a recorded response again against its recording, without calling the backend or the tools. The replayed response is stored as a new response with the metadata replay_of; the points where the loop departed from the recording, such as a backend request that differs from the recorded one, are listed as divergences. Responses that continued a conversation cannot be replayed.
+//	@Tags			Responses
+//	@Produce		json
+//	@Param			id	path		string	true	"Response ID"
+//	@Success		200	{object}	schema.ResponseReplay
+//	@Failure		400	{object}	map[string]interface{}
+//	@Failure		404	{object}	map[string]interface{}
+//	@Failure		500	{object}	map[string]interface{}
+//	@Failure		503	{object}	map[string]interface{}
+//	@Router			/v1/responses/{id}/replay [post]
+func (h *Handler) handleReplayResponse(w http.ResponseWriter, r *http.Request) {
+	if !h.drain.begin() {
+		h.writeDraining(w)
+		return
+	}
+	defer h.drain.end()
+
+	recording, ok := h.getRecording(w, r)
+	if !ok {
+		return
+	}
+
+	replay, err := h.engine.Replay(r.Context(), recording)
+	if err != nil {
+		if errors.Is(err, engine.ErrNotReplayable) {
+			h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		h.writeProcessError(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Response replayed",
+		"replay_of", recording.ID,
+		"response_id", replay.Response.ID,
+		"status", replay.Response.Status,
+		"divergences", len(replay.Divergences))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(replay)
+}
+
+// getRecording returns the recording of the response in the path. It
+// writes an error to w and returns false if there is none.
+func (h *Handler) getRecording(w http.ResponseWriter, r *http.Request) (*schema.ResponseRecording, bool) {
+	responseID := r.PathValue("id")
+	recording, err := h.engine.GetRecording(r.Context(), responseID)
+	if err != nil {
+		if errors.Is(err, engine.ErrRecordingNotFound) {
+			h.writeError(w, http.StatusNotFound, "recording_not_found", err.Error())
+			return nil, false
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to get recording", "error", err, "response_id", responseID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		return nil, false
+	}
+	return recording, true
+}
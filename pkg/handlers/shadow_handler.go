@@ -0,0 +1,73 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
+	"github.com/leseb/openresponses-gw/pkg/core/engine"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// handleListShadowResults handles GET /v1/admin/shadow_results
+//
+//	@Summary		List shadow results
+//	@Description	List the backend calls sent as shadow traffic (engine.shadow), newest first, each with the outputs and latencies of the primary and secondary backends. The secondary backend's output is never returned to clients; it is only kept here for offline comparison.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			after			query		string	false	"Cursor for pagination: ID of the last result of the previous page"
+//	@Param			limit			query		int		false	"Number of results (1-100, default 20)"
+//	@Param			response_id		query		string	false	"Filter by response ID"
+//	@Param			model			query		string	false	"Filter by model of the backend request"
+//	@Param			created_after	query		int		false	"Only results recorded after this Unix timestamp"
+//	@Param			created_before	query		int		false	"Only results recorded before this Unix timestamp"
+//	@Success		200				{object}	schema.ListShadowResultsResponse
+//	@Failure		400				{object}	map[string]interface{}
+//	@Failure		500				{object}	map[string]interface{}
+//	@Failure		501				{object}	map[string]interface{}
+//	@Router			/v1/admin/shadow_results [get]
+func (h *Handler) handleListShadowResults(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := state.ShadowFilter{
+		ResponseID: query.Get("response_id"),
+		Model:      query.Get("model"),
+	}
+	if err := parseCreatedBounds(query, &filter.CreatedAfter, &filter.CreatedBefore); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	limit := 20
+	if parsedLimit, err := parseInt(query.Get("limit")); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+		limit = parsedLimit
+	}
+
+	results, hasMore, err := h.engine.ListShadowResults(r.Context(), filter, query.Get("after"), limit)
+	if err != nil {
+		if errors.Is(err, engine.ErrShadowUnsupported) {
+			h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Shadow traffic is not supported by the session store")
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to list shadow results", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeStoreError, err.Error())
+		return
+	}
+
+	resp := schema.ListShadowResultsResponse{
+		Object:  "list",
+		Data:    results,
+		HasMore: hasMore,
+	}
+	if len(results) > 0 {
+		resp.FirstID = results[0].ID
+		resp.LastID = results[len(results)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -4,13 +4,22 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/leseb/openresponses-gw/pkg/core/apierror"
 	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/core/services"
+	"github.com/leseb/openresponses-gw/pkg/featureflags"
+	"github.com/leseb/openresponses-gw/pkg/filestore"
 	"github.com/leseb/openresponses-gw/pkg/storage/memory"
 	"github.com/leseb/openresponses-gw/pkg/vectorstore"
 )
@@ -30,13 +39,19 @@ func (h *Handler) handleCreateVectorStore(w http.ResponseWriter, r *http.Request
 	// Parse request body
 	var req schema.CreateVectorStoreRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse vector store request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse vector store request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
 
+	embedder, err := h.vectorStoreService.Embedder(req.Embedder)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter, err.Error())
+		return
+	}
+
 	// Create vector store
-	vsID := generateID("vs_")
+	vsID := h.engine.NewID("vs_")
 	now := time.Now()
 
 	var expiresAfter *memory.VectorStoreExpiration
@@ -56,38 +71,42 @@ func (h *Handler) handleCreateVectorStore(w http.ResponseWriter, r *http.Request
 		CreatedAt:    now,
 		ExpiresAfter: expiresAfter,
 		Metadata:     convertMetadata(req.Metadata),
+		Tenant:       featureflags.TenantFromContext(r.Context()),
 		FileIDs:      []string{},
+
+		Embedder:            embedder.Name,
+		EmbeddingModel:      embedder.Model,
+		EmbeddingDimensions: embedder.Dimensions,
 	}
 
-	err := h.vectorStoresStore.CreateVectorStore(r.Context(), vs)
-	if err != nil {
-		h.logger.Error("Failed to create vector store", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+	if err := h.vectorStoresStore.CreateVectorStore(r.Context(), vs); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to create vector store", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
 	// Provision backend storage (e.g. Milvus collection)
 	if h.vectorStoreService != nil {
-		if err := h.vectorStoreService.CreateStore(r.Context(), vsID, 0); err != nil {
-			h.logger.Error("Failed to provision vector store backend", "error", err, "vector_store_id", vsID)
+		if err := h.vectorStoreService.CreateStore(r.Context(), vsID, embedder); err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to provision vector store backend", "error", err, "vector_store_id", vsID)
 			// Continue — metadata is created; backend can be retried
 		}
 	}
 
-	h.logger.Info("Vector store created", "vector_store_id", vsID)
+	h.logger.InfoContext(r.Context(), "Vector store created", "vector_store_id", vsID)
 
 	// Add files if provided
 	if len(req.FileIDs) > 0 {
 		for _, fileID := range req.FileIDs {
 			vsFile := &memory.VectorStoreFile{
-				ID:            generateID("vsf_"),
+				ID:            h.engine.NewID("vsf_"),
 				VectorStoreID: vsID,
 				FileID:        fileID,
 				Status:        "in_progress",
 				CreatedAt:     now,
 			}
 			if addErr := h.vectorStoresStore.AddVectorStoreFile(r.Context(), vsFile); addErr != nil {
-				h.logger.Error("Failed to add file to vector store", "error", addErr)
+				h.logger.ErrorContext(r.Context(), "Failed to add file to vector store", "error", addErr)
 				continue
 			}
 			h.startFileIngestion(vsID, fileID, nil)
@@ -111,6 +130,7 @@ func (h *Handler) handleCreateVectorStore(w http.ResponseWriter, r *http.Request
 //	@Param		before	query		string	false	"Cursor for pagination (backwards)"
 //	@Param		limit	query		int		false	"Number of items (1-100, default 20)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListVectorStoresResponse
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/vector_stores [get]
@@ -131,15 +151,15 @@ func (h *Handler) handleListVectorStores(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	h.logger.Info("Listing vector stores", "after", after, "limit", limit, "order", order)
+	h.logger.InfoContext(r.Context(), "Listing vector stores", "after", after, "limit", limit, "order", order)
 
 	// Get vector stores from storage
 	vectorStores, hasMore, err := h.vectorStoresStore.ListVectorStoresPaginated(
 		r.Context(), after, before, limit, order,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list vector stores", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list vector stores", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -161,6 +181,16 @@ func (h *Handler) handleListVectorStores(w http.ResponseWriter, r *http.Request)
 		listResp.LastID = schemaVS[len(schemaVS)-1].ID
 	}
 
+	if includeTotal(query) {
+		total, err := h.vectorStoresStore.CountVectorStores(r.Context())
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count vector stores", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -184,12 +214,12 @@ func (h *Handler) handleGetVectorStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("Getting vector store", "vector_store_id", vsID)
+	h.logger.InfoContext(r.Context(), "Getting vector store", "vector_store_id", vsID)
 
 	// Get vector store from storage
 	vs, err := h.vectorStoresStore.GetVectorStore(r.Context(), vsID)
 	if err != nil {
-		h.logger.Error("Failed to get vector store", "error", err, "vector_store_id", vsID)
+		h.logger.ErrorContext(r.Context(), "Failed to get vector store", "error", err, "vector_store_id", vsID)
 		h.writeError(w, http.StatusNotFound, "vector_store_not_found", err.Error())
 		return
 	}
@@ -226,17 +256,17 @@ func (h *Handler) handleUpdateVectorStore(w http.ResponseWriter, r *http.Request
 	// Parse request body
 	var req schema.UpdateVectorStoreRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse update request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse update request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
 
-	h.logger.Info("Updating vector store", "vector_store_id", vsID)
+	h.logger.InfoContext(r.Context(), "Updating vector store", "vector_store_id", vsID)
 
 	// Get existing vector store
 	vs, err := h.vectorStoresStore.GetVectorStore(r.Context(), vsID)
 	if err != nil {
-		h.logger.Error("Failed to get vector store", "error", err, "vector_store_id", vsID)
+		h.logger.ErrorContext(r.Context(), "Failed to get vector store", "error", err, "vector_store_id", vsID)
 		h.writeError(w, http.StatusNotFound, "vector_store_not_found", err.Error())
 		return
 	}
@@ -258,8 +288,8 @@ func (h *Handler) handleUpdateVectorStore(w http.ResponseWriter, r *http.Request
 	// Update in storage
 	err = h.vectorStoresStore.UpdateVectorStore(r.Context(), vs)
 	if err != nil {
-		h.logger.Error("Failed to update vector store", "error", err, "vector_store_id", vsID)
-		h.writeError(w, http.StatusInternalServerError, "update_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to update vector store", "error", err, "vector_store_id", vsID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -289,12 +319,12 @@ func (h *Handler) handleDeleteVectorStore(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.logger.Info("Deleting vector store", "vector_store_id", vsID)
+	h.logger.InfoContext(r.Context(), "Deleting vector store", "vector_store_id", vsID)
 
 	// Delete vector store from storage
 	err := h.vectorStoresStore.DeleteVectorStore(r.Context(), vsID)
 	if err != nil {
-		h.logger.Error("Failed to delete vector store", "error", err, "vector_store_id", vsID)
+		h.logger.ErrorContext(r.Context(), "Failed to delete vector store", "error", err, "vector_store_id", vsID)
 		h.writeError(w, http.StatusNotFound, "vector_store_not_found", err.Error())
 		return
 	}
@@ -302,7 +332,7 @@ func (h *Handler) handleDeleteVectorStore(w http.ResponseWriter, r *http.Request
 	// Delete backend storage (e.g. Milvus collection)
 	if h.vectorStoreService != nil {
 		if delErr := h.vectorStoreService.DeleteStore(r.Context(), vsID); delErr != nil {
-			h.logger.Error("Failed to delete vector store backend", "error", delErr, "vector_store_id", vsID)
+			h.logger.ErrorContext(r.Context(), "Failed to delete vector store backend", "error", delErr, "vector_store_id", vsID)
 		}
 	}
 
@@ -318,18 +348,131 @@ func (h *Handler) handleDeleteVectorStore(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(deleteResp)
 }
 
-// handleAddVectorStoreFile handles POST /v1/vector_stores/{id}/files
+// handleReindexVectorStore handles POST /v1/vector_stores/{id}/reindex
 //
-//	@Summary	Add file to vector store
-//	@Tags		Vector Stores
-//	@Accept		json
-//	@Produce	json
-//	@Param		id		path		string								true	"Vector store ID"
-//	@Param		request	body		schema.AddVectorStoreFileRequest	true	"Add file request"
-//	@Success	200		{object}	schema.VectorStoreFile
-//	@Failure	400		{object}	map[string]interface{}
-//	@Failure	500		{object}	map[string]interface{}
-//	@Router		/v1/vector_stores/{id}/files [post]
+//	@Summary		Re-index vector store
+//	@Description	Re-chunks and re-embeds all files of a vector store in the background, with the configured embedding model or the one given. Progress is reported in the vector store's reindex field.
+//	@Tags			Vector Stores
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string								true	"Vector store ID"
+//	@Param			request	body		schema.ReindexVectorStoreRequest	false	"Re-index request"
+//	@Success		200		{object}	schema.VectorStore
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Failure		409		{object}	map[string]interface{}
+//	@Failure		501		{object}	map[string]interface{}
+//	@Router			/v1/vector_stores/{id}/reindex [post]
+func (h *Handler) handleReindexVectorStore(w http.ResponseWriter, r *http.Request) {
+	vsID := r.PathValue("id")
+	if vsID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Vector store ID is required")
+		return
+	}
+
+	// The body is optional
+	var req schema.ReindexVectorStoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return
+	}
+	if req.EmbeddingDimensions < 0 {
+		h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter, "embedding_dimensions must not be negative")
+		return
+	}
+	newModel := req.EmbeddingModel != "" || req.EmbeddingDimensions != 0
+	if req.Embedder != "" && newModel {
+		h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter, "embedder cannot be combined with embedding_model or embedding_dimensions")
+		return
+	}
+
+	if h.vectorStoreService == nil {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, "Vector store search is not configured")
+		return
+	}
+
+	vs, err := h.vectorStoresStore.GetVectorStore(r.Context(), vsID)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "vector_store_not_found", err.Error())
+		return
+	}
+	if vs.Reindex != nil && vs.Reindex.Status == "in_progress" {
+		h.writeError(w, http.StatusConflict, apierror.CodeInvalidRequest, fmt.Sprintf("Vector store %s is already being re-indexed", vsID))
+		return
+	}
+
+	// Without a choice, the store's embedder is taken again as configured now
+	var embedder services.Embedder
+	if newModel {
+		embedder, err = h.vectorStoreService.NewEmbedder(req.EmbeddingModel, req.EmbeddingDimensions)
+	} else {
+		name := req.Embedder
+		if name == "" {
+			name = vs.Embedder
+		}
+		embedder, err = h.vectorStoreService.Embedder(name)
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, apierror.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	files, err := h.allVectorStoreFiles(r.Context(), vsID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list vector store files", "error", err, "vector_store_id", vsID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "Re-indexing vector store", "vector_store_id", vsID, "embedder", embedder.Name, "embedding_model", embedder.Model, "files", len(files))
+
+	progress := memory.VectorStoreReindex{
+		Status:         "in_progress",
+		Embedder:       embedder.Name,
+		EmbeddingModel: embedder.Model,
+		FilesTotal:     len(files),
+		StartedAt:      time.Now(),
+	}
+	vs.Status = "in_progress"
+	vs.Reindex = &progress
+	if err := h.vectorStoresStore.UpdateVectorStore(r.Context(), vs); err != nil {
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+		return
+	}
+	h.startReindex(vsID, embedder, files, progress)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convertToSchemaVectorStore(vs))
+}
+
+// allVectorStoreFiles returns every file of a vector store.
+func (h *Handler) allVectorStoreFiles(ctx context.Context, vsID string) ([]*memory.VectorStoreFile, error) {
+	var files []*memory.VectorStoreFile
+	after := ""
+	for {
+		page, hasMore, err := h.vectorStoresStore.ListVectorStoreFilesPaginated(ctx, vsID, after, "", 100, "asc", "")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, page...)
+		if !hasMore || len(page) == 0 {
+			return files, nil
+		}
+		after = page[len(page)-1].FileID
+	}
+}
+
+// @Summary	Add file to vector store
+// @Tags		Vector Stores
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string								true	"Vector store ID"
+// @Param		request	body		schema.AddVectorStoreFileRequest	true	"Add file request"
+// @Success	200		{object}	schema.VectorStoreFile
+// @Failure	400		{object}	map[string]interface{}
+// @Failure	500		{object}	map[string]interface{}
+// @Router		/v1/vector_stores/{id}/files [post]
 func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Request) {
 	// Extract vector store ID from path
 	vsID := r.PathValue("id")
@@ -341,7 +484,7 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 	// Parse request body
 	var req schema.AddVectorStoreFileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse add file request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse add file request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -351,23 +494,12 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	h.logger.Info("Adding file to vector store", "vector_store_id", vsID, "file_id", req.FileID)
+	h.logger.InfoContext(r.Context(), "Adding file to vector store", "vector_store_id", vsID, "file_id", req.FileID)
 
 	// Create vector store file
 	now := time.Now()
 
-	var chunkingStrategy *memory.ChunkingStrategy
-	if req.ChunkingStrategy != nil {
-		chunkingStrategy = &memory.ChunkingStrategy{
-			Type: req.ChunkingStrategy.Type,
-		}
-		if req.ChunkingStrategy.Static != nil {
-			chunkingStrategy.Static = &memory.StaticChunkingStrategy{
-				MaxChunkSizeTokens: req.ChunkingStrategy.Static.MaxChunkSizeTokens,
-				ChunkOverlapTokens: req.ChunkingStrategy.Static.ChunkOverlapTokens,
-			}
-		}
-	}
+	chunkingStrategy := convertFromSchemaChunkingStrategy(req.ChunkingStrategy)
 
 	// Set initial status based on whether ingestion is possible
 	initialStatus := "completed"
@@ -376,7 +508,7 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 	}
 
 	vsFile := &memory.VectorStoreFile{
-		ID:               generateID("vsf_"),
+		ID:               h.engine.NewID("vsf_"),
 		VectorStoreID:    vsID,
 		FileID:           req.FileID,
 		Status:           initialStatus,
@@ -387,8 +519,8 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 
 	err := h.vectorStoresStore.AddVectorStoreFile(r.Context(), vsFile)
 	if err != nil {
-		h.logger.Error("Failed to add file to vector store", "error", err, "vector_store_id", vsID, "file_id", req.FileID)
-		h.writeError(w, http.StatusInternalServerError, "add_file_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to add file to vector store", "error", err, "vector_store_id", vsID, "file_id", req.FileID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -403,6 +535,201 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(schemaVSFile)
 }
 
+// handleUploadVectorStoreFile handles POST /v1/vector_stores/{id}/upload
+//
+// It stores the uploaded file, adds it to the vector store and starts
+// ingestion in a single call. Multipart parts are read as they arrive rather
+// than buffered to disk first.
+//
+//	@Summary	Upload file to vector store
+//	@Tags		Vector Stores
+//	@Accept		multipart/form-data
+//	@Produce	json
+//	@Param		id					path		string	true	"Vector store ID"
+//	@Param		file				formData	file	true	"File to upload"
+//	@Param		purpose				formData	string	false	"File purpose (default assistants)"
+//	@Param		chunking_strategy	formData	string	false	"Chunking strategy as JSON"
+//	@Param		attributes			formData	string	false	"File attributes as a JSON object"
+//	@Success	200					{object}	schema.VectorStoreFileUploadResponse
+//	@Failure	400					{object}	map[string]interface{}
+//	@Failure	404					{object}	map[string]interface{}
+//	@Failure	413					{object}	map[string]interface{}
+//	@Failure	500					{object}	map[string]interface{}
+//	@Router		/v1/vector_stores/{id}/upload [post]
+func (h *Handler) handleUploadVectorStoreFile(w http.ResponseWriter, r *http.Request) {
+	// Extract vector store ID from path
+	vsID := r.PathValue("id")
+	if vsID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Vector store ID is required")
+		return
+	}
+
+	// Check the vector store before consuming the upload
+	if _, err := h.vectorStoresStore.GetVectorStore(r.Context(), vsID); err != nil {
+		h.writeError(w, http.StatusNotFound, "vector_store_not_found", err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Expected a multipart/form-data body")
+		return
+	}
+
+	var (
+		content          []byte
+		filename         string
+		mimeType         string
+		hasFile          bool
+		purpose          = "assistants"
+		chunkingStrategy *schema.ChunkingStrategy
+		attributes       map[string]interface{}
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				h.writeBodyTooLarge(w, r, maxErr)
+				return
+			}
+			h.logger.ErrorContext(r.Context(), "Failed to read multipart body", "error", err)
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read multipart body")
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			content, err = io.ReadAll(part)
+			filename = part.FileName()
+			mimeType = part.Header.Get("Content-Type")
+			hasFile = true
+		case "purpose":
+			var v []byte
+			if v, err = readUploadField(part); err == nil && len(v) > 0 {
+				purpose = string(v)
+			}
+		case "chunking_strategy":
+			var v []byte
+			if v, err = readUploadField(part); err == nil && len(v) > 0 {
+				chunkingStrategy = &schema.ChunkingStrategy{}
+				if jsonErr := json.Unmarshal(v, chunkingStrategy); jsonErr != nil {
+					h.writeError(w, http.StatusBadRequest, "invalid_request", "chunking_strategy must be a JSON object")
+					return
+				}
+			}
+		case "attributes":
+			var v []byte
+			if v, err = readUploadField(part); err == nil && len(v) > 0 {
+				if jsonErr := json.Unmarshal(v, &attributes); jsonErr != nil {
+					h.writeError(w, http.StatusBadRequest, "invalid_request", "attributes must be a JSON object")
+					return
+				}
+			}
+		default:
+			_, err = io.Copy(io.Discard, part)
+		}
+		part.Close()
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				h.writeBodyTooLarge(w, r, maxErr)
+				return
+			}
+			h.logger.ErrorContext(r.Context(), "Failed to read multipart field", "error", err, "field", part.FormName())
+			h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read multipart field "+part.FormName())
+			return
+		}
+	}
+
+	if !hasFile {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "File is required")
+		return
+	}
+	if !validFilePurposes[purpose] {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid purpose")
+		return
+	}
+
+	// Store the file
+	now := time.Now()
+	storeFile := &filestore.File{
+		ID:        h.engine.NewID("file_"),
+		Filename:  filename,
+		Purpose:   purpose,
+		MimeType:  mimeType,
+		Bytes:     int64(len(content)),
+		Content:   content,
+		Status:    "uploaded",
+		Tenant:    featureflags.TenantFromContext(r.Context()),
+		CreatedAt: now,
+	}
+	if err := h.filesStore.CreateFile(r.Context(), storeFile); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to create file", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeFileStoreError, err.Error())
+		return
+	}
+
+	// Register it with the vector store
+	initialStatus := "completed"
+	if h.vectorStoreService != nil {
+		initialStatus = "in_progress"
+	}
+	memChunking := convertFromSchemaChunkingStrategy(chunkingStrategy)
+	vsFile := &memory.VectorStoreFile{
+		ID:               h.engine.NewID("vsf_"),
+		VectorStoreID:    vsID,
+		FileID:           storeFile.ID,
+		Status:           initialStatus,
+		CreatedAt:        now,
+		ChunkingStrategy: memChunking,
+		Attributes:       attributes,
+	}
+	if err := h.vectorStoresStore.AddVectorStoreFile(r.Context(), vsFile); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to add file to vector store", "error", err, "vector_store_id", vsID, "file_id", storeFile.ID)
+		// Don't leave an orphaned file behind
+		if delErr := h.filesStore.DeleteFile(r.Context(), storeFile.ID); delErr != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to delete file after upload error", "error", delErr, "file_id", storeFile.ID)
+		}
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "File uploaded to vector store", "vector_store_id", vsID, "file_id", storeFile.ID, "filename", filename, "bytes", len(content))
+
+	// Trigger async ingestion
+	h.startFileIngestion(vsID, storeFile.ID, memChunking)
+
+	uploadResp := schema.VectorStoreFileUploadResponse{
+		Object:          "vector_store.file_upload",
+		File:            h.convertToSchemaFile(storeFile),
+		VectorStoreFile: convertToSchemaVectorStoreFile(vsFile),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(uploadResp)
+}
+
+// maxUploadFieldSize bounds the non-file fields of a multipart upload.
+const maxUploadFieldSize = 64 * 1024
+
+// readUploadField reads a small multipart form field.
+func readUploadField(part io.Reader) ([]byte, error) {
+	v, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) > maxUploadFieldSize {
+		return nil, fmt.Errorf("field exceeds %d bytes", maxUploadFieldSize)
+	}
+	return bytes.TrimSpace(v), nil
+}
+
 // handleListVectorStoreFiles handles GET /v1/vector_stores/{id}/files
 //
 //	@Summary	List vector store files
@@ -414,6 +741,7 @@ func (h *Handler) handleAddVectorStoreFile(w http.ResponseWriter, r *http.Reques
 //	@Param		limit	query		int		false	"Number of items (1-100, default 20)"
 //	@Param		order	query		string	false	"Sort order: asc or desc (default desc)"
 //	@Param		filter	query		string	false	"Filter by status: in_progress, completed, failed, cancelled"
+//	@Param		include_total	query		bool	false	"Include total_count in the response"
 //	@Success	200		{object}	schema.ListVectorStoreFilesResponse
 //	@Failure	400		{object}	map[string]interface{}
 //	@Failure	500		{object}	map[string]interface{}
@@ -443,15 +771,15 @@ func (h *Handler) handleListVectorStoreFiles(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	h.logger.Info("Listing vector store files", "vector_store_id", vsID, "limit", limit, "filter", filter)
+	h.logger.InfoContext(r.Context(), "Listing vector store files", "vector_store_id", vsID, "limit", limit, "filter", filter)
 
 	// Get files from storage
 	files, hasMore, err := h.vectorStoresStore.ListVectorStoreFilesPaginated(
 		r.Context(), vsID, after, before, limit, order, filter,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list vector store files", "error", err, "vector_store_id", vsID)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list vector store files", "error", err, "vector_store_id", vsID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -473,6 +801,16 @@ func (h *Handler) handleListVectorStoreFiles(w http.ResponseWriter, r *http.Requ
 		listResp.LastID = schemaFiles[len(schemaFiles)-1].ID
 	}
 
+	if includeTotal(query) {
+		total, err := h.vectorStoresStore.CountVectorStoreFiles(r.Context(), vsID, filter)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to count vector store files", "error", err)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+			return
+		}
+		listResp.TotalCount = &total
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(listResp)
@@ -514,12 +852,57 @@ func convertToSchemaVectorStore(vs *memory.VectorStore) schema.VectorStore {
 			Cancelled:  vs.FileCounts.Cancelled,
 			Total:      vs.FileCounts.Total,
 		},
-		CreatedAt:    vs.CreatedAt.Unix(),
-		ExpiresAt:    expiresAt,
-		ExpiresAfter: expiresAfter,
-		LastActiveAt: lastActiveAt,
-		Metadata:     convertMetadataToInterface(vs.Metadata),
+		CreatedAt:           vs.CreatedAt.Unix(),
+		ExpiresAt:           expiresAt,
+		ExpiresAfter:        expiresAfter,
+		LastActiveAt:        lastActiveAt,
+		Metadata:            convertMetadataToInterface(vs.Metadata),
+		Embedder:            vs.Embedder,
+		EmbeddingModel:      vs.EmbeddingModel,
+		EmbeddingDimensions: vs.EmbeddingDimensions,
+		Reindex:             convertToSchemaReindex(vs.Reindex),
+	}
+}
+
+func convertToSchemaReindex(r *memory.VectorStoreReindex) *schema.VectorStoreReindex {
+	if r == nil {
+		return nil
+	}
+	reindex := &schema.VectorStoreReindex{
+		Status:         r.Status,
+		Embedder:       r.Embedder,
+		EmbeddingModel: r.EmbeddingModel,
+		FileCounts: schema.ReindexFileCounts{
+			Completed: r.FilesCompleted,
+			Failed:    r.FilesFailed,
+			Total:     r.FilesTotal,
+		},
+		StartedAt: r.StartedAt.Unix(),
+	}
+	if r.CompletedAt != nil {
+		ts := r.CompletedAt.Unix()
+		reindex.CompletedAt = &ts
+	}
+	if r.LastError != nil {
+		reindex.LastError = &schema.VectorStoreFileError{Code: r.LastError.Code, Message: r.LastError.Message}
+	}
+	return reindex
+}
+
+func convertFromSchemaChunkingStrategy(cs *schema.ChunkingStrategy) *memory.ChunkingStrategy {
+	if cs == nil {
+		return nil
+	}
+	chunkingStrategy := &memory.ChunkingStrategy{
+		Type: cs.Type,
 	}
+	if cs.Static != nil {
+		chunkingStrategy.Static = &memory.StaticChunkingStrategy{
+			MaxChunkSizeTokens: cs.Static.MaxChunkSizeTokens,
+			ChunkOverlapTokens: cs.Static.ChunkOverlapTokens,
+		}
+	}
+	return chunkingStrategy
 }
 
 func convertToSchemaVectorStoreFile(vsFile *memory.VectorStoreFile) schema.VectorStoreFile {
@@ -577,11 +960,11 @@ func (h *Handler) handleGetVectorStoreFile(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	h.logger.Info("Getting vector store file", "vector_store_id", vsID, "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Getting vector store file", "vector_store_id", vsID, "file_id", fileID)
 
 	vsFile, err := h.vectorStoresStore.GetVectorStoreFile(r.Context(), vsID, fileID)
 	if err != nil {
-		h.logger.Error("Failed to get vector store file", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get vector store file", "error", err)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
@@ -613,11 +996,11 @@ func (h *Handler) handleDeleteVectorStoreFile(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	h.logger.Info("Deleting vector store file", "vector_store_id", vsID, "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Deleting vector store file", "vector_store_id", vsID, "file_id", fileID)
 
 	err := h.vectorStoresStore.DeleteVectorStoreFile(r.Context(), vsID, fileID)
 	if err != nil {
-		h.logger.Error("Failed to delete vector store file", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to delete vector store file", "error", err)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
@@ -625,7 +1008,7 @@ func (h *Handler) handleDeleteVectorStoreFile(w http.ResponseWriter, r *http.Req
 	// Remove chunks from backend
 	if h.vectorStoreService != nil {
 		if rmErr := h.vectorStoreService.RemoveFile(r.Context(), vsID, fileID); rmErr != nil {
-			h.logger.Error("Failed to remove file chunks from backend", "error", rmErr, "vector_store_id", vsID, "file_id", fileID)
+			h.logger.ErrorContext(r.Context(), "Failed to remove file chunks from backend", "error", rmErr, "vector_store_id", vsID, "file_id", fileID)
 		}
 	}
 
@@ -661,12 +1044,12 @@ func (h *Handler) handleGetVectorStoreFileContent(w http.ResponseWriter, r *http
 		return
 	}
 
-	h.logger.Info("Getting vector store file content", "vector_store_id", vsID, "file_id", fileID)
+	h.logger.InfoContext(r.Context(), "Getting vector store file content", "vector_store_id", vsID, "file_id", fileID)
 
 	// Get file metadata from files store (not vector store)
 	file, err := h.filesStore.GetFile(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to get file", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get file", "error", err)
 		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
 		return
 	}
@@ -674,8 +1057,8 @@ func (h *Handler) handleGetVectorStoreFileContent(w http.ResponseWriter, r *http
 	// Get file content
 	content, err := h.filesStore.GetFileContent(r.Context(), fileID)
 	if err != nil {
-		h.logger.Error("Failed to get file content", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "read_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to get file content", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -689,6 +1072,102 @@ func (h *Handler) handleGetVectorStoreFileContent(w http.ResponseWriter, r *http
 	w.Write(content)
 }
 
+// handleListVectorStoreFileChunks handles GET /v1/vector_stores/{id}/files/{file_id}/chunks
+//
+//	@Summary	List vector store file chunks
+//	@Description	Returns the chunks indexed for a file, in file order, to debug retrieval.
+//	@Tags		Vector Stores
+//	@Produce	json
+//	@Param		id		path		string	true	"Vector store ID"
+//	@Param		file_id	path		string	true	"File ID"
+//	@Param		after	query		string	false	"Cursor for pagination: the ID of the last chunk of the previous page"
+//	@Param		limit	query		int		false	"Number of items (1-100, default 20)"
+//	@Success	200		{object}	schema.ListVectorStoreFileChunksResponse
+//	@Failure	400		{object}	map[string]interface{}
+//	@Failure	404		{object}	map[string]interface{}
+//	@Failure	500		{object}	map[string]interface{}
+//	@Failure	501		{object}	map[string]interface{}
+//	@Router		/v1/vector_stores/{id}/files/{file_id}/chunks [get]
+func (h *Handler) handleListVectorStoreFileChunks(w http.ResponseWriter, r *http.Request) {
+	vsID := r.PathValue("id")
+	fileID := r.PathValue("file_id")
+
+	if vsID == "" || fileID == "" {
+		h.writeError(w, http.StatusBadRequest, "invalid_request", "Vector store ID and file ID are required")
+		return
+	}
+
+	query := r.URL.Query()
+	after := query.Get("after")
+	limit := 20
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	h.logger.InfoContext(r.Context(), "Listing vector store file chunks", "vector_store_id", vsID, "file_id", fileID, "limit", limit)
+
+	vsFile, err := h.vectorStoresStore.GetVectorStoreFile(r.Context(), vsID, fileID)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "file_not_found", err.Error())
+		return
+	}
+
+	chunks, err := h.vectorStoreService.ListFileChunks(r.Context(), vsID, fileID)
+	if errors.Is(err, services.ErrChunksUnsupported) {
+		h.writeError(w, http.StatusNotImplemented, apierror.CodeNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list vector store file chunks", "error", err, "vector_store_id", vsID, "file_id", fileID)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
+		return
+	}
+
+	// Page through the chunks in file order; an unknown cursor starts over
+	if after != "" {
+		i := slices.IndexFunc(chunks, func(c vectorstore.StoredChunk) bool { return c.ChunkID == after })
+		chunks = chunks[i+1:]
+	}
+	hasMore := len(chunks) > limit
+	if hasMore {
+		chunks = chunks[:limit]
+	}
+
+	data := make([]schema.VectorStoreFileChunk, 0, len(chunks))
+	for _, c := range chunks {
+		chunk := schema.VectorStoreFileChunk{
+			ID:                  c.ChunkID,
+			Object:              "vector_store.file.chunk",
+			FileID:              c.FileID,
+			Content:             c.Content,
+			StartOffset:         c.Offset,
+			EmbeddingDimensions: c.Dimensions,
+			Attributes:          vsFile.Attributes,
+		}
+		if c.Offset != nil {
+			end := *c.Offset + len(c.Content)
+			chunk.EndOffset = &end
+		}
+		data = append(data, chunk)
+	}
+
+	listResp := schema.ListVectorStoreFileChunksResponse{
+		Object:  "list",
+		Data:    data,
+		HasMore: hasMore,
+	}
+	if len(data) > 0 {
+		listResp.FirstID = data[0].ID
+		listResp.LastID = data[len(data)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listResp)
+}
+
 // handleSearchVectorStore handles POST /v1/vector_stores/{id}/search
 //
 //	@Summary	Search vector store
@@ -699,6 +1178,7 @@ func (h *Handler) handleGetVectorStoreFileContent(w http.ResponseWriter, r *http
 //	@Param		request	body		schema.SearchVectorStoreRequest			true	"Search request"
 //	@Success	200		{object}	schema.SearchVectorStoreResponse
 //	@Failure	400		{object}	map[string]interface{}
+//	@Failure	409		{object}	map[string]interface{}
 //	@Failure	500		{object}	map[string]interface{}
 //	@Router		/v1/vector_stores/{id}/search [post]
 func (h *Handler) handleSearchVectorStore(w http.ResponseWriter, r *http.Request) {
@@ -712,7 +1192,7 @@ func (h *Handler) handleSearchVectorStore(w http.ResponseWriter, r *http.Request
 	// Parse request body
 	var req schema.SearchVectorStoreRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse search request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse search request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
@@ -730,7 +1210,7 @@ func (h *Handler) handleSearchVectorStore(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	h.logger.Info("Searching vector store", "vector_store_id", vsID, "query", queryStr)
+	h.logger.InfoContext(r.Context(), "Searching vector store", "vector_store_id", vsID, "query", queryStr)
 
 	topK := 10
 	if req.MaxNumResults != nil && *req.MaxNumResults > 0 {
@@ -755,8 +1235,8 @@ func (h *Handler) handleSearchVectorStore(w http.ResponseWriter, r *http.Request
 		// List all files in this vector store and evaluate the filter
 		allFiles, _, listErr := h.vectorStoresStore.ListVectorStoreFilesPaginated(r.Context(), vsID, "", "", 10000, "asc", "")
 		if listErr != nil {
-			h.logger.Error("Failed to list files for filter resolution", "error", listErr)
-			h.writeError(w, http.StatusInternalServerError, "filter_error", listErr.Error())
+			h.logger.ErrorContext(r.Context(), "Failed to list files for filter resolution", "error", listErr)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, listErr.Error())
 			return
 		}
 
@@ -791,9 +1271,13 @@ func (h *Handler) handleSearchVectorStore(w http.ResponseWriter, r *http.Request
 	if h.vectorStoreService != nil {
 		var searchErr error
 		results, searchErr = h.vectorStoreService.Search(r.Context(), vsID, queryStr, topK, filterExpr)
+		if errors.Is(searchErr, services.ErrDimensionMismatch) {
+			h.writeError(w, http.StatusConflict, apierror.CodeVectorStoreError, searchErr.Error()+"; re-index the vector store")
+			return
+		}
 		if searchErr != nil {
-			h.logger.Error("Vector store search failed", "error", searchErr, "vector_store_id", vsID)
-			h.writeError(w, http.StatusInternalServerError, "search_error", searchErr.Error())
+			h.logger.ErrorContext(r.Context(), "Vector store search failed", "error", searchErr, "vector_store_id", vsID)
+			h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, searchErr.Error())
 			return
 		}
 	}
@@ -846,15 +1330,15 @@ func (h *Handler) handleCreateVectorStoreFileBatch(w http.ResponseWriter, r *htt
 	// Parse request body
 	var req schema.CreateVectorStoreFileBatchRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse batch request", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to parse batch request", "error", err)
 		h.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
 		return
 	}
 
-	h.logger.Info("Creating file batch", "vector_store_id", vsID, "file_count", len(req.FileIDs))
+	h.logger.InfoContext(r.Context(), "Creating file batch", "vector_store_id", vsID, "file_count", len(req.FileIDs))
 
 	// Create batch
-	batchID := generateID("vsfb_")
+	batchID := h.engine.NewID("vsfb_")
 	now := time.Now()
 
 	batch := &memory.VectorStoreFileBatch{
@@ -870,15 +1354,15 @@ func (h *Handler) handleCreateVectorStoreFileBatch(w http.ResponseWriter, r *htt
 
 	err := h.vectorStoresStore.CreateVectorStoreFileBatch(r.Context(), batch)
 	if err != nil {
-		h.logger.Error("Failed to create batch", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "creation_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to create batch", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
 	// Add files to batch
 	for _, fileID := range req.FileIDs {
 		vsFile := &memory.VectorStoreFile{
-			ID:            generateID("vsf_"),
+			ID:            h.engine.NewID("vsf_"),
 			VectorStoreID: vsID,
 			FileID:        fileID,
 			Status:        "completed",
@@ -915,11 +1399,11 @@ func (h *Handler) handleGetVectorStoreFileBatch(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	h.logger.Info("Getting file batch", "vector_store_id", vsID, "batch_id", batchID)
+	h.logger.InfoContext(r.Context(), "Getting file batch", "vector_store_id", vsID, "batch_id", batchID)
 
 	batch, err := h.vectorStoresStore.GetVectorStoreFileBatch(r.Context(), vsID, batchID)
 	if err != nil {
-		h.logger.Error("Failed to get batch", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get batch", "error", err)
 		h.writeError(w, http.StatusNotFound, "batch_not_found", err.Error())
 		return
 	}
@@ -973,15 +1457,15 @@ func (h *Handler) handleListVectorStoreFileBatchFiles(w http.ResponseWriter, r *
 		}
 	}
 
-	h.logger.Info("Listing batch files", "vector_store_id", vsID, "batch_id", batchID)
+	h.logger.InfoContext(r.Context(), "Listing batch files", "vector_store_id", vsID, "batch_id", batchID)
 
 	// For now, return all files in the vector store (since we don't track batch membership)
 	files, hasMore, err := h.vectorStoresStore.ListVectorStoreFilesPaginated(
 		r.Context(), vsID, after, before, limit, order, filter,
 	)
 	if err != nil {
-		h.logger.Error("Failed to list batch files", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "list_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to list batch files", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -1029,12 +1513,12 @@ func (h *Handler) handleCancelVectorStoreFileBatch(w http.ResponseWriter, r *htt
 		return
 	}
 
-	h.logger.Info("Canceling file batch", "vector_store_id", vsID, "batch_id", batchID)
+	h.logger.InfoContext(r.Context(), "Canceling file batch", "vector_store_id", vsID, "batch_id", batchID)
 
 	// Get batch
 	batch, err := h.vectorStoresStore.GetVectorStoreFileBatch(r.Context(), vsID, batchID)
 	if err != nil {
-		h.logger.Error("Failed to get batch", "error", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get batch", "error", err)
 		h.writeError(w, http.StatusNotFound, "batch_not_found", err.Error())
 		return
 	}
@@ -1043,8 +1527,8 @@ func (h *Handler) handleCancelVectorStoreFileBatch(w http.ResponseWriter, r *htt
 	batch.Status = "cancelled"
 	err = h.vectorStoresStore.UpdateVectorStoreFileBatch(r.Context(), batch)
 	if err != nil {
-		h.logger.Error("Failed to cancel batch", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "cancel_error", err.Error())
+		h.logger.ErrorContext(r.Context(), "Failed to cancel batch", "error", err)
+		h.writeError(w, http.StatusInternalServerError, apierror.CodeVectorStoreError, err.Error())
 		return
 	}
 
@@ -1062,16 +1546,7 @@ func (h *Handler) startFileIngestion(vsID, fileID string, cs *memory.ChunkingStr
 		return
 	}
 
-	chunkSize := vectorstore.DefaultChunkSize
-	overlap := vectorstore.DefaultChunkOverlap
-	if cs != nil && cs.Static != nil {
-		if cs.Static.MaxChunkSizeTokens > 0 {
-			chunkSize = vectorstore.TokensToChars(cs.Static.MaxChunkSizeTokens)
-		}
-		if cs.Static.ChunkOverlapTokens > 0 {
-			overlap = vectorstore.TokensToChars(cs.Static.ChunkOverlapTokens)
-		}
-	}
+	chunkSize, overlap := chunkingParams(cs)
 
 	go func() {
 		ctx := context.Background()
@@ -1098,6 +1573,89 @@ func (h *Handler) startFileIngestion(vsID, fileID string, cs *memory.ChunkingStr
 	}()
 }
 
+// chunkingParams returns the chunk size and overlap, in characters, of a
+// chunking strategy.
+func chunkingParams(cs *memory.ChunkingStrategy) (chunkSize, overlap int) {
+	chunkSize = vectorstore.DefaultChunkSize
+	overlap = vectorstore.DefaultChunkOverlap
+	if cs != nil && cs.Static != nil {
+		if cs.Static.MaxChunkSizeTokens > 0 {
+			chunkSize = vectorstore.TokensToChars(cs.Static.MaxChunkSizeTokens)
+		}
+		if cs.Static.ChunkOverlapTokens > 0 {
+			overlap = vectorstore.TokensToChars(cs.Static.ChunkOverlapTokens)
+		}
+	}
+	return chunkSize, overlap
+}
+
+// startReindex re-indexes a vector store's files in the background,
+// recording progress on the vector store and each file's status.
+func (h *Handler) startReindex(vsID string, embedder services.Embedder, vsFiles []*memory.VectorStoreFile, progress memory.VectorStoreReindex) {
+	files := make([]services.ReindexFile, len(vsFiles))
+	for i, f := range vsFiles {
+		chunkSize, overlap := chunkingParams(f.ChunkingStrategy)
+		files[i] = services.ReindexFile{FileID: f.FileID, ChunkSize: chunkSize, Overlap: overlap}
+	}
+
+	go func() {
+		ctx := context.Background()
+		record := func() {
+			vs, err := h.vectorStoresStore.GetVectorStore(ctx, vsID)
+			if err != nil {
+				return // deleted meanwhile
+			}
+			p := progress
+			vs.Reindex = &p
+			if p.Status != "in_progress" {
+				vs.Status = "completed"
+				if p.Status == "completed" {
+					vs.Embedder = embedder.Name
+					vs.EmbeddingModel = embedder.Model
+					vs.EmbeddingDimensions = embedder.Dimensions
+				}
+			}
+			h.vectorStoresStore.UpdateVectorStore(ctx, vs)
+		}
+
+		bound, err := h.vectorStoreService.Reindex(ctx, vsID, embedder, files, func(fileID string, err error) {
+			vsFile, getErr := h.vectorStoresStore.GetVectorStoreFile(ctx, vsID, fileID)
+			if err != nil {
+				h.logger.Error("File re-indexing failed", "error", err, "vector_store_id", vsID, "file_id", fileID)
+				progress.FilesFailed++
+				progress.LastError = &memory.VectorStoreFileError{Code: "ingestion_failed", Message: err.Error()}
+				if getErr == nil {
+					vsFile.Status = "failed"
+					vsFile.LastError = progress.LastError
+					h.vectorStoresStore.UpdateVectorStoreFile(ctx, vsFile)
+				}
+			} else {
+				progress.FilesCompleted++
+				if getErr == nil && vsFile.Status != "completed" {
+					vsFile.Status = "completed"
+					vsFile.LastError = nil
+					h.vectorStoresStore.UpdateVectorStoreFile(ctx, vsFile)
+				}
+			}
+			record()
+		})
+
+		now := time.Now()
+		progress.CompletedAt = &now
+		if err != nil {
+			h.logger.Error("Vector store re-indexing failed", "error", err, "vector_store_id", vsID)
+			progress.Status = "failed"
+			progress.LastError = &memory.VectorStoreFileError{Code: "server_error", Message: err.Error()}
+		} else {
+			embedder = bound
+			progress.Status = "completed"
+			h.logger.Info("Vector store re-indexing completed", "vector_store_id", vsID,
+				"files_completed", progress.FilesCompleted, "files_failed", progress.FilesFailed)
+		}
+		record()
+	}()
+}
+
 // convertToSchemaFileBatch converts internal batch to schema
 func convertToSchemaFileBatch(batch *memory.VectorStoreFileBatch) schema.VectorStoreFileBatch {
 	return schema.VectorStoreFileBatch{
@@ -0,0 +1,132 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/storage/memory"
+)
+
+// multipartBody encodes fields as a multipart form, with content as its
+// "file" part unless content is nil.
+func multipartBody(t *testing.T, content []byte, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if content != nil {
+		fw, err := mw.CreateFormFile("file", "notes.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf, mw.FormDataContentType()
+}
+
+func TestHandleUploadVectorStoreFile(t *testing.T) {
+	h := newTestHandler(t)
+	h.maxUploadBytes = 1024
+	if err := h.vectorStoresStore.CreateVectorStore(context.Background(), &memory.VectorStore{ID: "vs_1", Status: "completed", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		vsID       string
+		content    []byte
+		fields     map[string]string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "stored and attached",
+			vsID:       "vs_1",
+			content:    []byte("some notes"),
+			fields:     map[string]string{"attributes": `{"topic": "notes"}`},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing file",
+			vsID:       "vs_1",
+			fields:     map[string]string{"purpose": "assistants"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "unknown vector store",
+			vsID:       "vs_missing",
+			content:    []byte("some notes"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "vector_store_not_found",
+		},
+		{
+			name:       "body too large",
+			vsID:       "vs_1",
+			content:    bytes.Repeat([]byte("x"), 2048),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "request_too_large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, tt.content, tt.fields)
+			req := httptest.NewRequest(http.MethodPost, "/v1/vector_stores/"+tt.vsID+"/upload", body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("error code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+
+			var resp schema.VectorStoreFileUploadResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			content, err := h.filesStore.GetFileContent(context.Background(), resp.File.ID)
+			if err != nil {
+				t.Fatalf("GetFileContent() error = %v", err)
+			}
+			if string(content) != string(tt.content) || resp.File.Filename != "notes.txt" {
+				t.Errorf("stored file %q = %q, want %q", resp.File.Filename, content, tt.content)
+			}
+			vsFile, err := h.vectorStoresStore.GetVectorStoreFile(context.Background(), tt.vsID, resp.File.ID)
+			if err != nil {
+				t.Fatalf("GetVectorStoreFile() error = %v", err)
+			}
+			if vsFile.VectorStoreID != tt.vsID || vsFile.Attributes["topic"] != "notes" {
+				t.Errorf("vector store file = %+v, want the uploaded file with its attributes", vsFile)
+			}
+		})
+	}
+
+	// Nothing is stored for refused uploads
+	files, _, err := h.filesStore.ListFilesPaginated(context.Background(), "", "", 100, "asc", "")
+	if err != nil || len(files) != 1 {
+		t.Errorf("stored %d files (%v), want 1", len(files), err)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package health checks the gateway's dependencies (stores, vector backend,
+// embedding and model endpoints) for liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report statuses.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDraining = "draining" // set by the server while shutting down
+	StatusError    = "error"
+)
+
+// Default option values.
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 5 * time.Second
+)
+
+// CheckFunc checks a single dependency. It returns nil when the dependency
+// is usable.
+type CheckFunc func(ctx context.Context) error
+
+// Pinger is implemented by backends that can check their connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping returns a check that pings v. Backends without a Pinger
+// implementation (in-process stores) always report ok.
+func Ping(v any) CheckFunc {
+	if p, ok := v.(Pinger); ok {
+		return p.Ping
+	}
+	return func(context.Context) error { return nil }
+}
+
+// HTTPCheck returns a check that issues GET url, authenticated with apiKey
+// when set. The endpoint is considered up unless the request fails, is
+// rejected as unauthorized, or returns a server error: OpenAI-compatible
+// backends do not all implement the probed path.
+func HTTPCheck(client *http.Client, url, apiKey string) CheckFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("unauthorized (HTTP %d)", resp.StatusCode)
+		case resp.StatusCode >= 500:
+			return fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// ModelsURL returns the /models URL of an OpenAI-compatible base URL.
+func ModelsURL(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/models"
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status  string // "ok" or "error"
+	Error   string // why the check failed
+	Latency time.Duration
+}
+
+// Report is the outcome of all checks.
+type Report struct {
+	Status    string            // "ok", or "degraded" if any check failed
+	Checks    map[string]Result // by dependency name
+	CheckedAt time.Time
+}
+
+// OK reports whether every check passed.
+func (r *Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Options configures a Checker. Zero values select the defaults.
+type Options struct {
+	// Timeout bounds each check.
+	Timeout time.Duration
+	// CacheTTL is how long a report is reused before the checks run again,
+	// so that frequent probes do not load the dependencies.
+	CacheTTL time.Duration
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs dependency checks concurrently and caches the report. A nil
+// *Checker reports ok with no checks.
+type Checker struct {
+	opts   Options
+	checks []namedCheck
+	now    func() time.Time
+
+	mu   sync.Mutex
+	last *Report
+	at   time.Time
+}
+
+// New creates a Checker.
+func New(opts Options) *Checker {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = DefaultCacheTTL
+	}
+	return &Checker{opts: opts, now: time.Now}
+}
+
+// Add registers a check. Checks must be added before the first Check call.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// Check returns the latest report, running the checks again when the cached
+// one has expired. Concurrent callers share a single run.
+func (c *Checker) Check(ctx context.Context) *Report {
+	if c == nil {
+		return &Report{Status: StatusOK, Checks: map[string]Result{}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && c.now().Sub(c.at) < c.opts.CacheTTL {
+		return c.last
+	}
+
+	// The report is shared with later callers: don't let this caller's
+	// cancellation fail the checks.
+	ctx = context.WithoutCancel(ctx)
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check.fn)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range c.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	report.CheckedAt = c.now()
+	c.last, c.at = report, report.CheckedAt
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	// Don't wait for checks that ignore their context
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := Result{Status: StatusOK, Latency: time.Since(start)}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", c.opts.Timeout)
+		}
+		res.Status, res.Error = StatusError, err.Error()
+	}
+	return res
+}
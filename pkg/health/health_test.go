@@ -0,0 +1,133 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Check(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+	stuck := func(context.Context) error { select {} }
+
+	tests := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		wantStatus string
+		wantErrors map[string]string
+	}{
+		{"no checks", nil, StatusOK, nil},
+		{"all ok", map[string]CheckFunc{"a": ok, "b": ok}, StatusOK, nil},
+		{"one failing", map[string]CheckFunc{"a": ok, "b": fail}, StatusDegraded,
+			map[string]string{"b": "connection refused"}},
+		{"timeout", map[string]CheckFunc{"a": hang}, StatusDegraded,
+			map[string]string{"a": "timed out"}},
+		{"ignores context", map[string]CheckFunc{"a": stuck}, StatusDegraded,
+			map[string]string{"a": "timed out"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Options{Timeout: 20 * time.Millisecond})
+			for name, fn := range tt.checks {
+				c.Add(name, fn)
+			}
+			report := c.Check(context.Background())
+
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("got %d results, want %d", len(report.Checks), len(tt.checks))
+			}
+			for name, res := range report.Checks {
+				want, failed := tt.wantErrors[name]
+				if failed != (res.Status == StatusError) || !strings.Contains(res.Error, want) {
+					t.Errorf("%s = %+v, want error %q", name, res, want)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_Cache(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Unix(1000, 0)
+	c := New(Options{CacheTTL: 5 * time.Second})
+	c.now = func() time.Time { return now }
+	c.Add("a", func(context.Context) error { calls.Add(1); return nil })
+
+	c.Check(context.Background())
+	now = now.Add(4 * time.Second)
+	c.Check(context.Background())
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls within TTL = %d, want 1", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	c.Check(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls after TTL = %d, want 2", got)
+	}
+}
+
+func TestChecker_Nil(t *testing.T) {
+	var c *Checker
+	if report := c.Check(context.Background()); !report.OK() {
+		t.Errorf("nil checker status = %q, want ok", report.Status)
+	}
+}
+
+type pinger struct{ err error }
+
+func (p pinger) Ping(context.Context) error { return p.err }
+
+func TestPing(t *testing.T) {
+	if err := Ping(struct{}{})(context.Background()); err != nil {
+		t.Errorf("non-pinger: %v", err)
+	}
+	if err := Ping(pinger{errors.New("down")})(context.Background()); err == nil {
+		t.Error("expected the pinger's error")
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"not found", http.StatusNotFound, false},
+		{"unauthorized", http.StatusUnauthorized, true},
+		{"server error", http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/models" {
+					t.Errorf("path = %q, want /v1/models", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := HTTPCheck(srv.Client(), ModelsURL(srv.URL+"/v1/"), "secret")(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ids generates the identifiers of API objects: a type prefix such
+// as "resp_" followed by a unique suffix.
+//
+// ULID and KSUID suffixes start with their creation time, so IDs of one
+// prefix sort in creation order and break ties between objects created in
+// the same second when listing. ULIDs are monotonic within a process: IDs
+// generated in the same millisecond still sort in generation order and
+// cannot collide. Random suffixes are 32 hex characters and do not sort.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Generator creates IDs.
+type Generator interface {
+	NewID(prefix string) string
+}
+
+// Formats lists the supported ID formats.
+var Formats = []string{"ulid", "ksuid", "random"}
+
+// New returns the generator for a format; an empty name selects "ulid".
+func New(format string) (Generator, error) {
+	switch format {
+	case "", "ulid":
+		return NewULID(), nil
+	case "ksuid":
+		return KSUID{}, nil
+	case "random":
+		return Random{}, nil
+	}
+	return nil, fmt.Errorf("ids: unknown format %q (available: %v)", format, Formats)
+}
+
+// Default is the generator used when none is configured.
+var Default Generator = NewULID()
+
+// Random generates 128-bit random hex suffixes.
+type Random struct{}
+
+// NewID implements Generator.
+func (Random) NewID(prefix string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// Sequence generates deterministic IDs numbered from 1 per prefix, for
+// tests. Numbers are zero-padded so IDs sort in generation order.
+type Sequence struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewSequence creates a Sequence.
+func NewSequence() *Sequence {
+	return &Sequence{next: make(map[string]int)}
+}
+
+// NewID implements Generator.
+func (s *Sequence) NewID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[prefix]++
+	return fmt.Sprintf("%s%08d", prefix, s.next[prefix])
+}
@@ -0,0 +1,82 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package ids
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantLen int
+	}{
+		{"", 26},
+		{"ulid", 26},
+		{"ksuid", 27},
+		{"random", 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			g, err := New(tt.format)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			a, b := g.NewID("resp_"), g.NewID("resp_")
+			if !strings.HasPrefix(a, "resp_") || len(a) != len("resp_")+tt.wantLen {
+				t.Errorf("ID %q, want prefix resp_ and a %d-character suffix", a, tt.wantLen)
+			}
+			if a == b {
+				t.Errorf("duplicate ID %q", a)
+			}
+		})
+	}
+
+	if _, err := New("uuid"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestULID_Monotonic(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	g := &ULID{now: func() time.Time { return now }}
+
+	prev := g.NewID("msg_")
+	for i := range 1000 {
+		if i == 500 {
+			now = now.Add(-time.Second) // the clock goes back
+		}
+		id := g.NewID("msg_")
+		if id <= prev {
+			t.Fatalf("ID %d %q does not sort after %q", i, id, prev)
+		}
+		prev = id
+	}
+
+	now = now.Add(time.Hour)
+	if later := g.NewID("msg_"); later <= prev {
+		t.Errorf("later ID %q does not sort after %q", later, prev)
+	}
+}
+
+func TestULID_Timestamp(t *testing.T) {
+	// 01ARZ3NDEK is the timestamp of the ULID specification's example
+	g := &ULID{now: func() time.Time { return time.UnixMilli(1469922850259) }}
+	if id := g.NewID(""); !strings.HasPrefix(id, "01arz3ndek") {
+		t.Errorf("ID %q does not start with 01arz3ndek", id)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	s := NewSequence()
+	got := []string{s.NewID("resp_"), s.NewID("msg_"), s.NewID("resp_")}
+	want := []string{"resp_00000001", "msg_00000001", "resp_00000002"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ID %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"time"
+)
+
+// ksuidEpoch is the KSUID epoch, 2014-05-13T16:53:20Z.
+const ksuidEpoch = 1400000000
+
+// base62 is the KSUID alphabet, in ASCII order.
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// KSUID generates 27-character KSUID suffixes: a 32-bit timestamp in
+// seconds followed by 128 random bits. IDs of the same second do not sort
+// in generation order.
+type KSUID struct{}
+
+// NewID implements Generator.
+func (KSUID) NewID(prefix string) string {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(time.Now().Unix()-ksuidEpoch))
+	rand.Read(b[4:])
+
+	n := new(big.Int).SetBytes(b[:])
+	out := []byte("000000000000000000000000000")
+	radix := big.NewInt(62)
+	mod := new(big.Int)
+	for i := len(out) - 1; n.Sign() > 0; i-- {
+		n.DivMod(n, radix, mod)
+		out[i] = base62[mod.Int64()]
+	}
+	return prefix + string(out)
+}
@@ -0,0 +1,77 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package ids
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// crockford is the base32 alphabet of ULIDs, lowercased to match the other
+// IDs of the API. It is in ASCII order, so encoded IDs sort like their
+// bytes.
+const crockford = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// ULID generates 26-character ULID suffixes: a 48-bit millisecond
+// timestamp followed by 80 random bits. Within a millisecond, or if the
+// clock goes back, the previous ID is incremented instead, so IDs always
+// sort in generation order.
+type ULID struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	last [16]byte
+	ms   uint64
+}
+
+// NewULID creates a ULID generator.
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+// NewID implements Generator.
+func (g *ULID) NewID(prefix string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms := uint64(g.now().UnixMilli()); ms > g.ms {
+		g.ms = ms
+		rand.Read(g.last[6:])
+	} else if !increment(g.last[6:]) {
+		// The random part overflowed, which 80 random bits make
+		// practically impossible: move on to the next millisecond.
+		g.ms++
+		rand.Read(g.last[6:])
+	}
+	for i := range 6 {
+		g.last[i] = byte(g.ms >> (40 - 8*i))
+	}
+	return prefix + encodeCrockford(g.last)
+}
+
+// increment adds one to the big-endian number b and reports whether it
+// did not overflow.
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCrockford encodes 128 bits as 26 base32 characters, the first of
+// which holds only 3 bits.
+func encodeCrockford(b [16]byte) string {
+	n := new(big.Int).SetBytes(b[:])
+	out := make([]byte, 26)
+	mask := big.NewInt(31)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
@@ -0,0 +1,206 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported authentication types for MCP servers.
+const (
+	AuthTypeBearer                  = "bearer"
+	AuthTypeHeaders                 = "headers"
+	AuthTypeOAuth2ClientCredentials = "oauth2_client_credentials"
+)
+
+// tokenExpiryLeeway is subtracted from the OAuth2 token lifetime so that
+// tokens are refreshed before the server starts rejecting them.
+const tokenExpiryLeeway = 30 * time.Second
+
+// Auth holds the credentials used to authenticate to an MCP server.
+type Auth struct {
+	Type string `json:"type"` // "bearer", "headers", "oauth2_client_credentials"
+
+	// Static bearer token (type "bearer")
+	Token string `json:"token,omitempty"`
+
+	// Extra request headers (type "headers")
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// OAuth2 client credentials grant (type "oauth2_client_credentials")
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// Validate checks that the fields required by the auth type are set.
+func (a *Auth) Validate() error {
+	switch a.Type {
+	case AuthTypeBearer:
+		if a.Token == "" {
+			return fmt.Errorf("auth.token is required for type %q", a.Type)
+		}
+	case AuthTypeHeaders:
+		if len(a.Headers) == 0 {
+			return fmt.Errorf("auth.headers is required for type %q", a.Type)
+		}
+		for name := range a.Headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				return fmt.Errorf("auth.headers: invalid header name %q", name)
+			}
+		}
+	case AuthTypeOAuth2ClientCredentials:
+		if a.TokenURL == "" || a.ClientID == "" || a.ClientSecret == "" {
+			return fmt.Errorf("auth.token_url, auth.client_id and auth.client_secret are required for type %q", a.Type)
+		}
+		if u, err := url.Parse(a.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("auth.token_url must be an http(s) URL")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q (want %q, %q or %q)",
+			a.Type, AuthTypeBearer, AuthTypeHeaders, AuthTypeOAuth2ClientCredentials)
+	}
+	return nil
+}
+
+// applyAuth sets the credentials for auth on an outgoing request.
+func applyAuth(ctx context.Context, httpClient *http.Client, auth *Auth, req *http.Request) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case AuthTypeBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case AuthTypeHeaders:
+		for name, value := range auth.Headers {
+			req.Header.Set(name, value)
+		}
+	case AuthTypeOAuth2ClientCredentials:
+		token, err := tokens.get(ctx, httpClient, auth)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// tokens caches OAuth2 access tokens across clients. The engine creates a
+// client per request, so caching on the client would fetch a new token for
+// every response.
+var tokens = &tokenCache{entries: make(map[string]cachedToken)}
+
+type cachedToken struct {
+	accessToken string
+	expiry      time.Time // zero means no known expiry
+}
+
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedToken
+}
+
+// key identifies a set of client credentials without keeping the secret
+// itself as a map key.
+func (tc *tokenCache) key(auth *Auth) string {
+	h := sha256.New()
+	for _, part := range []string{auth.TokenURL, auth.ClientID, auth.ClientSecret, strings.Join(auth.Scopes, " ")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns a valid access token, fetching a new one if none is cached or
+// the cached one is about to expire.
+func (tc *tokenCache) get(ctx context.Context, httpClient *http.Client, auth *Auth) (string, error) {
+	key := tc.key(auth)
+
+	tc.mu.Lock()
+	entry, ok := tc.entries[key]
+	tc.mu.Unlock()
+	if ok && (entry.expiry.IsZero() || time.Now().Before(entry.expiry)) {
+		return entry.accessToken, nil
+	}
+
+	entry, err := fetchToken(ctx, httpClient, auth)
+	if err != nil {
+		return "", err
+	}
+
+	tc.mu.Lock()
+	tc.entries[key] = entry
+	tc.mu.Unlock()
+	return entry.accessToken, nil
+}
+
+// invalidate drops the cached token, forcing a refresh on the next request.
+func (tc *tokenCache) invalidate(auth *Auth) {
+	tc.mu.Lock()
+	delete(tc.entries, tc.key(auth))
+	tc.mu.Unlock()
+}
+
+// fetchToken performs the OAuth2 client credentials grant (RFC 6749 §4.4).
+func fetchToken(ctx context.Context, httpClient *http.Client, auth *Auth) (cachedToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cachedToken{}, fmt.Errorf("oauth2 token endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("oauth2 token response: missing access_token")
+	}
+
+	entry := cachedToken{accessToken: tokenResp.AccessToken}
+	if tokenResp.ExpiresIn > 0 {
+		lifetime := time.Duration(tokenResp.ExpiresIn) * time.Second
+		if lifetime > 2*tokenExpiryLeeway {
+			lifetime -= tokenExpiryLeeway
+		}
+		entry.expiry = time.Now().Add(lifetime)
+	}
+	return entry, nil
+}
@@ -15,29 +15,67 @@ import (
 	"sync/atomic"
 )
 
-// Client is a stateless MCP client that communicates over HTTP using JSON-RPC 2.0.
+// Client is a stateless MCP client that communicates using JSON-RPC 2.0,
+// either over HTTP or with a local stdio server.
 type Client struct {
+	// Label is the server_label of the mcp tool the client was created
+	// for, reported on the items of its failed calls.
+	Label string
+
+	// MaxOutputBytes bounds the tool output the engine gives the model;
+	// 0 uses the engine's limit.
+	MaxOutputBytes int
+
 	httpClient *http.Client
 	serverURL  string
+	auth       *Auth
 	sessionID  string
 	nextID     atomic.Int64
+	stdio      *StdioServer // set for stdio servers; HTTP fields are unused
+	httpTool   *HTTPTool    // set for HTTP tools, which have no MCP server
 }
 
-// NewClient creates a new MCP client targeting the given server URL.
-func NewClient(serverURL string) *Client {
+// NewClient creates a new MCP client targeting the given server URL. If auth
+// is non-nil, its credentials are attached to every request.
+func NewClient(serverURL string, auth *Auth) *Client {
 	return &Client{
 		httpClient: &http.Client{},
 		serverURL:  serverURL,
+		auth:       auth,
 	}
 }
 
+// NewStdioClient creates a new MCP client for a local stdio server. The
+// server's process is shared with other clients and outlives this one.
+func NewStdioClient(server *StdioServer) *Client {
+	return &Client{stdio: server}
+}
+
+// SetHTTPClient replaces the HTTP client used to reach the server, such as
+// with one enforcing an egress policy. It has no effect on stdio servers.
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.httpClient = hc
+}
+
 // ServerURL returns the server URL for this client.
 func (c *Client) ServerURL() string {
 	return c.serverURL
 }
 
 // Initialize performs the MCP initialize handshake and stores the session ID.
+// For stdio servers the handshake happens once per process, so this only
+// makes sure the process is running; HTTP tools have none.
 func (c *Client) Initialize(ctx context.Context) error {
+	if c.httpTool != nil {
+		return nil
+	}
+	if c.stdio != nil {
+		if err := c.stdio.ensureStarted(ctx); err != nil {
+			return fmt.Errorf("mcp initialize: %w", err)
+		}
+		return nil
+	}
+
 	params := InitializeParams{
 		ProtocolVersion: "2025-03-26",
 		ClientInfo: ClientInfo{
@@ -70,6 +108,9 @@ func (c *Client) Initialize(ctx context.Context) error {
 
 // ListTools returns the tools exposed by the MCP server.
 func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	if c.httpTool != nil {
+		return []ToolInfo{c.httpTool.toolInfo()}, nil
+	}
 	raw, err := c.call(ctx, "tools/list", nil)
 	if err != nil {
 		return nil, fmt.Errorf("mcp tools/list: %w", err)
@@ -84,6 +125,9 @@ func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
 
 // CallTool invokes a tool on the MCP server.
 func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*ToolCallResult, error) {
+	if c.httpTool != nil {
+		return c.callHTTPTool(ctx, name, args)
+	}
 	params := ToolCallParams{
 		Name:      name,
 		Arguments: args,
@@ -109,6 +153,11 @@ func (c *Client) call(ctx context.Context, method string, params any) (json.RawM
 
 // callWithHeaders sends a JSON-RPC request and returns the result along with response headers.
 func (c *Client) callWithHeaders(ctx context.Context, method string, params any) (json.RawMessage, http.Header, error) {
+	if c.stdio != nil {
+		raw, err := c.stdio.call(ctx, method, params)
+		return raw, nil, err
+	}
+
 	id := int(c.nextID.Add(1))
 	reqBody := JSONRPCRequest{
 		JSONRPC: "2.0",
@@ -122,19 +171,9 @@ func (c *Client) callWithHeaders(ctx context.Context, method string, params any)
 		return nil, nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL, bytes.NewReader(body))
+	httpResp, err := c.post(ctx, body)
 	if err != nil {
-		return nil, nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json, text/event-stream")
-	if c.sessionID != "" {
-		httpReq.Header.Set("Mcp-Session-Id", c.sessionID)
-	}
-
-	httpResp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, nil, fmt.Errorf("http request: %w", err)
+		return nil, nil, err
 	}
 	defer httpResp.Body.Close()
 
@@ -198,10 +237,41 @@ func (c *Client) notify(ctx context.Context, method string) error {
 		return err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL, bytes.NewReader(body))
+	resp, err := c.post(ctx, body)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+	io.ReadAll(resp.Body)
+	return nil
+}
+
+// post sends a JSON-RPC message to the server with the session and
+// authentication headers set. When an OAuth2 access token is rejected with
+// 401, the cached token is dropped and the request is retried once with a
+// fresh one.
+func (c *Client) post(ctx context.Context, body []byte) (*http.Response, error) {
+	resp, err := c.doPost(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.auth != nil && c.auth.Type == AuthTypeOAuth2ClientCredentials {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		tokens.invalidate(c.auth)
+		return c.doPost(ctx, body)
+	}
+	return resp, nil
+}
+
+func (c *Client) doPost(ctx context.Context, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if err := applyAuth(ctx, c.httpClient, c.auth, httpReq); err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json, text/event-stream")
 	if c.sessionID != "" {
@@ -210,9 +280,7 @@ func (c *Client) notify(ctx context.Context, method string) error {
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("http request: %w", err)
 	}
-	defer resp.Body.Close()
-	io.ReadAll(resp.Body)
-	return nil
+	return resp, nil
 }
@@ -0,0 +1,219 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// maxHTTPToolResponseBytes bounds the response body of an HTTP tool read
+// by the gateway; the engine truncates it further for the model.
+const maxHTTPToolResponseBytes = 4 << 20
+
+// HTTPTool is the single tool of an "http_tool" connector: instead of
+// calling an MCP server, the client makes an HTTP request built from the
+// tool call's arguments and returns the response body.
+//
+// URL, header values and body are templates where {{name}} stands for the
+// argument of that name: escaped in the URL, as is in headers, and
+// JSON-encoded in the body, so that arguments cannot change the request
+// beyond their value. Without a body template, POST, PUT and PATCH
+// requests send the arguments as a JSON object.
+type HTTPTool struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Parameters  map[string]any    `json:"parameters,omitempty"` // JSON Schema of the arguments
+	Method      string            `json:"method,omitempty"`     // default: GET
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+}
+
+// httpToolName matches the function names backends accept.
+var httpToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// placeholder matches the {{name}} placeholders of HTTP tool templates.
+var placeholder = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.-]+)\s*\}\}`)
+
+// Validate checks the tool definition.
+func (t *HTTPTool) Validate() error {
+	if !httpToolName.MatchString(t.Name) {
+		return fmt.Errorf("http_tool.name must be 1 to 64 letters, digits, underscores or dashes")
+	}
+	if !slices.Contains([]string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}, t.method()) {
+		return fmt.Errorf("http_tool.method %q is not supported (want GET, POST, PUT, PATCH or DELETE)", t.Method)
+	}
+	if u, err := url.Parse(placeholder.ReplaceAllString(t.URL, "x")); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("http_tool.url must be an http(s) URL")
+	}
+	for name := range t.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("http_tool.headers: invalid header name %q", name)
+		}
+	}
+	if t.Body != "" && !t.hasBody() {
+		return fmt.Errorf("http_tool.body requires method POST, PUT or PATCH")
+	}
+	if t.Parameters != nil && t.Parameters["type"] != "object" {
+		return fmt.Errorf("http_tool.parameters must be a JSON Schema of type object")
+	}
+	return nil
+}
+
+func (t *HTTPTool) method() string {
+	if t.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(t.Method)
+}
+
+func (t *HTTPTool) hasBody() bool {
+	switch t.method() {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// toolInfo describes the tool as an MCP server would list it.
+func (t *HTTPTool) toolInfo() ToolInfo {
+	params := t.Parameters
+	if params == nil {
+		params = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return ToolInfo{Name: t.Name, Description: t.Description, InputSchema: params}
+}
+
+// NewHTTPToolClient creates a client serving an HTTP tool. If auth is
+// non-nil, its credentials are attached to every request.
+func NewHTTPToolClient(tool HTTPTool, auth *Auth) *Client {
+	return &Client{
+		httpClient: &http.Client{},
+		auth:       auth,
+		httpTool:   &tool,
+	}
+}
+
+// callHTTPTool makes the HTTP request of a call to the client's HTTP tool.
+// Responses with an error status fail the call.
+func (c *Client) callHTTPTool(ctx context.Context, name string, args map[string]any) (*ToolCallResult, error) {
+	t := c.httpTool
+	if name != t.Name {
+		return nil, fmt.Errorf("http tool %s: unknown tool %q", t.Name, name)
+	}
+	var body []byte
+	switch {
+	case t.Body != "":
+		body = []byte(render(t.Body, args, jsonValue))
+	case t.hasBody():
+		if args == nil {
+			args = map[string]any{}
+		}
+		body, _ = json.Marshal(args)
+	}
+
+	resp, err := c.doHTTPTool(ctx, args, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.auth != nil && c.auth.Type == AuthTypeOAuth2ClientCredentials {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		tokens.invalidate(c.auth)
+		if resp, err = c.doHTTPTool(ctx, args, body); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPToolResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("http tool %s: read response: %w", t.Name, err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("http tool %s: %s: %s", t.Name, resp.Status, truncate(string(data), 512))
+	}
+	return &ToolCallResult{Content: []ContentBlock{{Type: "text", Text: string(data)}}}, nil
+}
+
+func (c *Client) doHTTPTool(ctx context.Context, args map[string]any, body []byte) (*http.Response, error) {
+	t := c.httpTool
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, t.method(), render(t.URL, args, urlValue), reader)
+	if err != nil {
+		return nil, fmt.Errorf("http tool %s: create request: %w", t.Name, err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range t.Headers {
+		value = render(value, args, textValue)
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("http tool %s: header %s: arguments must not contain line breaks", t.Name, name)
+		}
+		httpReq.Header.Set(name, value)
+	}
+	requestid.SetHeader(httpReq)
+	if err := applyAuth(ctx, c.httpClient, c.auth, httpReq); err != nil {
+		return nil, fmt.Errorf("http tool %s: authenticate: %w", t.Name, err)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http tool %s: %w", t.Name, err)
+	}
+	return resp, nil
+}
+
+// render replaces the placeholders of tmpl with the encoded arguments.
+// Missing arguments encode as nil.
+func render(tmpl string, args map[string]any, encode func(any) string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return encode(args[placeholder.FindStringSubmatch(m)[1]])
+	})
+}
+
+// textValue returns strings as is and other values as JSON; nil is "".
+func textValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return jsonValue(v)
+}
+
+// urlValue escapes a value for any part of a URL.
+func urlValue(v any) string {
+	return strings.ReplaceAll(url.QueryEscape(textValue(v)), "+", "%20")
+}
+
+func jsonValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
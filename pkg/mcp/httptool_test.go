@@ -0,0 +1,115 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPTool_Call(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "no such city", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"temp": 21}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		tool     HTTPTool
+		args     map[string]any
+		wantURI  string
+		wantBody string
+	}{
+		{
+			name:    "GET with escaped arguments",
+			tool:    HTTPTool{Name: "weather", URL: srv.URL + "/cities/{{city}}/weather?units={{ units }}&days={{days}}"},
+			args:    map[string]any{"city": "San Francisco/CA", "units": "a&b=c", "days": float64(3)},
+			wantURI: "/cities/San%20Francisco%2FCA/weather?units=a%26b%3Dc&days=3",
+		},
+		{
+			name:     "POST body template",
+			tool:     HTTPTool{Name: "search", Method: "post", URL: srv.URL + "/search", Body: `{"q": {{query}}, "limit": {{limit}}}`},
+			args:     map[string]any{"query": `say "hi"`},
+			wantURI:  "/search",
+			wantBody: `{"q": "say \"hi\"", "limit": null}`,
+		},
+		{
+			name:     "POST arguments",
+			tool:     HTTPTool{Name: "create", Method: http.MethodPost, URL: srv.URL + "/tickets"},
+			args:     map[string]any{"title": "Broken"},
+			wantURI:  "/tickets",
+			wantBody: `{"title":"Broken"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tool.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			c := NewHTTPToolClient(tt.tool, &Auth{Type: AuthTypeBearer, Token: "secret"})
+			tools, err := c.ListTools(context.Background())
+			if err != nil || len(tools) != 1 || tools[0].Name != tt.tool.Name || tools[0].InputSchema["type"] != "object" {
+				t.Fatalf("ListTools = %v, %v", tools, err)
+			}
+			result, err := c.CallTool(context.Background(), tt.tool.Name, tt.args)
+			if err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			if len(result.Content) != 1 || result.Content[0].Text != `{"temp": 21}` {
+				t.Errorf("result = %+v", result)
+			}
+			if got.RequestURI != tt.wantURI || gotBody != tt.wantBody {
+				t.Errorf("request %s %q, body %q; want %q, body %q", got.Method, got.RequestURI, gotBody, tt.wantURI, tt.wantBody)
+			}
+			if auth := got.Header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("Authorization = %q", auth)
+			}
+		})
+	}
+
+	c := NewHTTPToolClient(HTTPTool{Name: "weather", URL: srv.URL + "/fail", Headers: map[string]string{"X-City": "{{city}}"}}, nil)
+	if _, err := c.CallTool(context.Background(), "weather", map[string]any{"city": "Paris"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("error status: err = %v", err)
+	}
+	if got.Header.Get("X-City") != "Paris" {
+		t.Errorf("X-City = %q", got.Header.Get("X-City"))
+	}
+	if _, err := c.CallTool(context.Background(), "weather", map[string]any{"city": "Paris\r\nX-Admin: 1"}); err == nil {
+		t.Error("header injection was not refused")
+	}
+}
+
+func TestHTTPTool_Validate(t *testing.T) {
+	for _, tool := range []HTTPTool{
+		{URL: "https://api.example.com"},
+		{Name: "bad name", URL: "https://api.example.com"},
+		{Name: "t", URL: "ftp://api.example.com"},
+		{Name: "t", URL: "/relative"},
+		{Name: "t", Method: "TRACE", URL: "https://api.example.com"},
+		{Name: "t", URL: "https://api.example.com", Body: "{}"},
+		{Name: "t", URL: "https://api.example.com", Headers: map[string]string{"Bad Header": "x"}},
+		{Name: "t", URL: "https://api.example.com", Parameters: map[string]any{"type": "string"}},
+	} {
+		if err := tool.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", tool)
+		}
+	}
+	ok := HTTPTool{Name: "t", Method: "put", URL: "https://{{host}}.example.com/items/{{id}}", Body: "{{item}}"}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,623 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultStdioConcurrency is the default number of in-flight requests
+	// per stdio server.
+	defaultStdioConcurrency = 4
+
+	// defaultStdioIdleTimeout is how long a stdio server may sit unused
+	// before its process is stopped.
+	defaultStdioIdleTimeout = 5 * time.Minute
+
+	// minHealthyUptime is how long a process has to run before an exit no
+	// longer counts as a crash for restart backoff.
+	minHealthyUptime = 10 * time.Second
+
+	// maxRestartBackoff caps the delay between restarts of a crashing server.
+	maxRestartBackoff = 30 * time.Second
+
+	// stderrTailSize is how much of a server's stderr is kept for error
+	// messages.
+	stderrTailSize = 4096
+
+	// stopGracePeriod is how long a process gets to exit after its stdin is
+	// closed before it is killed.
+	stopGracePeriod = 2 * time.Second
+
+	// initializeTimeout bounds the MCP initialize handshake of a new process.
+	initializeTimeout = 30 * time.Second
+)
+
+// stdioEnvPassthrough lists the gateway environment variables inherited by
+// stdio servers. Everything else, including the gateway's own credentials,
+// must be passed explicitly through StdioConfig.Env.
+var stdioEnvPassthrough = []string{"HOME", "LANG", "PATH", "TMPDIR", "USER"}
+
+// StdioConfig describes a local MCP server spawned as a child process that
+// speaks newline-delimited JSON-RPC over stdin/stdout.
+type StdioConfig struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+}
+
+func (c StdioConfig) equal(o StdioConfig) bool {
+	if c.Command != o.Command || !slices.Equal(c.Args, o.Args) || len(c.Env) != len(o.Env) {
+		return false
+	}
+	for k, v := range c.Env {
+		if ov, ok := o.Env[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// StdioManagerOptions configures a StdioManager.
+type StdioManagerOptions struct {
+	// AllowedCommands lists the commands connectors may spawn. Commands are
+	// matched exactly against the connector's command.
+	AllowedCommands []string
+
+	// MaxConcurrency limits in-flight requests per server (default 4).
+	MaxConcurrency int
+
+	// IdleTimeout stops processes that have not been used for this long
+	// (default 5m). They are restarted on the next request.
+	IdleTimeout time.Duration
+}
+
+// StdioManager owns the stdio MCP server processes, one per connector. It
+// starts processes on first use, restarts them after crashes with
+// exponential backoff, and stops them when idle.
+type StdioManager struct {
+	opts    StdioManagerOptions
+	mu      sync.Mutex
+	servers map[string]*StdioServer
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+// NewStdioManager creates a StdioManager. Call Close to stop all processes.
+func NewStdioManager(opts StdioManagerOptions) *StdioManager {
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = defaultStdioConcurrency
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultStdioIdleTimeout
+	}
+	m := &StdioManager{
+		opts:    opts,
+		servers: make(map[string]*StdioServer),
+		stop:    make(chan struct{}),
+	}
+	go m.reapIdle()
+	return m
+}
+
+// CheckCommand returns an error if command is not in the allowlist.
+func (m *StdioManager) CheckCommand(command string) error {
+	if command == "" {
+		return fmt.Errorf("command is required")
+	}
+	if !slices.Contains(m.opts.AllowedCommands, command) {
+		return fmt.Errorf("command %q is not in the list of allowed stdio commands", command)
+	}
+	return nil
+}
+
+// Get returns the server for key, creating it if needed. If the server for
+// key was created with a different configuration, its process is stopped
+// and replaced.
+func (m *StdioManager) Get(key string, cfg StdioConfig) (*StdioServer, error) {
+	if err := m.CheckCommand(cfg.Command); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	old, ok := m.servers[key]
+	if ok && old.cfg.equal(cfg) {
+		m.mu.Unlock()
+		return old, nil
+	}
+	s := newStdioServer(cfg, m.opts.MaxConcurrency)
+	m.servers[key] = s
+	m.mu.Unlock()
+
+	if ok {
+		old.Close()
+	}
+	return s, nil
+}
+
+// Remove stops and forgets the server for key.
+func (m *StdioManager) Remove(key string) {
+	m.mu.Lock()
+	s, ok := m.servers[key]
+	delete(m.servers, key)
+	m.mu.Unlock()
+	if ok {
+		s.Close()
+	}
+}
+
+// Close stops all server processes.
+func (m *StdioManager) Close() error {
+	m.stopped.Do(func() { close(m.stop) })
+
+	m.mu.Lock()
+	servers := m.servers
+	m.servers = make(map[string]*StdioServer)
+	m.mu.Unlock()
+
+	for _, s := range servers {
+		s.Close()
+	}
+	return nil
+}
+
+// reapIdle periodically stops processes that have been idle for longer than
+// the idle timeout.
+func (m *StdioManager) reapIdle() {
+	ticker := time.NewTicker(m.opts.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			servers := make([]*StdioServer, 0, len(m.servers))
+			for _, s := range m.servers {
+				servers = append(servers, s)
+			}
+			m.mu.Unlock()
+
+			for _, s := range servers {
+				s.stopIfIdle(m.opts.IdleTimeout)
+			}
+		}
+	}
+}
+
+// StdioServer is a local MCP server process. Requests from concurrent
+// clients are multiplexed over the process's stdin/stdout by JSON-RPC id.
+type StdioServer struct {
+	cfg StdioConfig
+	sem chan struct{}
+
+	mu          sync.Mutex
+	proc        *stdioProcess
+	starting    chan struct{}      // closed when the process being started is up or has failed
+	cancelStart context.CancelFunc // cancels the start in progress
+	active      int                // in-flight requests
+	lastUsed    time.Time          // end of the last request
+	failures    int                // consecutive crashes, for backoff
+	nextStart   time.Time          // earliest restart after a crash
+	lastErr     error              // why the last process crashed or failed to start
+	closed      bool
+}
+
+func newStdioServer(cfg StdioConfig, maxConcurrency int) *StdioServer {
+	return &StdioServer{
+		cfg: cfg,
+		sem: make(chan struct{}, maxConcurrency),
+	}
+}
+
+// Close stops the process, or aborts its start. Further requests fail.
+func (s *StdioServer) Close() {
+	s.mu.Lock()
+	s.closed = true
+	p := s.proc
+	s.proc = nil
+	if s.cancelStart != nil {
+		s.cancelStart()
+	}
+	s.mu.Unlock()
+	if p != nil {
+		p.stop()
+	}
+}
+
+// call sends a request to the server, starting the process if needed, and
+// returns the result.
+func (s *StdioServer) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	p, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release()
+
+	return p.call(ctx, method, params)
+}
+
+// ensureStarted starts the process if it is not running.
+func (s *StdioServer) ensureStarted(ctx context.Context) error {
+	if _, err := s.acquire(ctx); err != nil {
+		return err
+	}
+	s.release()
+	return nil
+}
+
+// acquire returns a running, initialized process and marks a request as
+// in flight. It must be paired with release. The process is started in the
+// background, once for all the callers waiting for it, so that the lock is
+// not held during the initialize handshake.
+func (s *StdioServer) acquire(ctx context.Context) (*stdioProcess, error) {
+	s.mu.Lock()
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("stdio server %s is closed", s.cfg.Command)
+		}
+
+		if s.proc != nil {
+			select {
+			case <-s.proc.done:
+				// Crashed: account for it and fall through to restart
+				if s.proc.exited.Sub(s.proc.started) < minHealthyUptime {
+					s.failures++
+				} else {
+					s.failures = 0
+				}
+				s.nextStart = s.proc.exited.Add(restartBackoff(s.failures))
+				s.lastErr = s.proc.err
+				s.proc = nil
+			default:
+				s.active++
+				p := s.proc
+				s.mu.Unlock()
+				return p, nil
+			}
+		}
+
+		if s.starting == nil {
+			if wait := time.Until(s.nextStart); wait > 0 {
+				s.mu.Unlock()
+				return nil, fmt.Errorf("stdio server %s is restarting in %s: %w", s.cfg.Command, wait.Round(time.Second), s.lastErr)
+			}
+			startCtx, cancel := context.WithTimeout(context.Background(), initializeTimeout)
+			s.starting = make(chan struct{})
+			s.cancelStart = cancel
+			go s.start(startCtx, s.starting)
+		}
+
+		starting := s.starting
+		s.mu.Unlock()
+		select {
+		case <-starting:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.mu.Lock()
+	}
+}
+
+// start starts the process for acquire and closes done once it is up or
+// has failed.
+func (s *StdioServer) start(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	p, err := startStdioProcess(ctx, s.cfg)
+
+	s.mu.Lock()
+	s.cancelStart()
+	s.starting = nil
+	s.cancelStart = nil
+	switch {
+	case err != nil:
+		s.failures++
+		s.nextStart = time.Now().Add(restartBackoff(s.failures))
+		s.lastErr = err
+	case s.closed:
+		s.mu.Unlock()
+		p.stop()
+		return
+	default:
+		s.proc = p
+	}
+	s.mu.Unlock()
+}
+
+// restartBackoff returns the delay before restarting a server that has
+// crashed failures times in a row: 1s, 2s, 4s, ... up to maxRestartBackoff.
+func restartBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	if failures > 6 {
+		return maxRestartBackoff
+	}
+	return min(time.Second<<(failures-1), maxRestartBackoff)
+}
+
+func (s *StdioServer) release() {
+	s.mu.Lock()
+	s.active--
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+// stopIfIdle stops the process if no request is in flight and none has
+// completed within idle.
+func (s *StdioServer) stopIfIdle(idle time.Duration) {
+	s.mu.Lock()
+	p := s.proc
+	if p == nil || s.active > 0 || time.Since(s.lastUsed) < idle {
+		s.mu.Unlock()
+		return
+	}
+	s.proc = nil
+	s.failures = 0
+	s.mu.Unlock()
+	p.stop()
+}
+
+// stdioProcess is a single run of a stdio server.
+type stdioProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stderr  *tailBuffer
+	started time.Time
+
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan stdioMessage
+
+	done   chan struct{} // closed when the process has exited
+	exited time.Time     // set before done is closed
+	err    error         // set before done is closed
+}
+
+// stdioMessage is any JSON-RPC message read from a server.
+type stdioMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// startStdioProcess spawns the server and performs the MCP initialize
+// handshake within ctx.
+func startStdioProcess(ctx context.Context, cfg StdioConfig) (*stdioProcess, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = stdioEnv(cfg.Env)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdio server %s: %w", cfg.Command, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdio server %s: %w", cfg.Command, err)
+	}
+	stderr := &tailBuffer{max: stderrTailSize}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("stdio server %s: start: %w", cfg.Command, err)
+	}
+
+	p := &stdioProcess{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  stderr,
+		started: time.Now(),
+		pending: make(map[int64]chan stdioMessage),
+		done:    make(chan struct{}),
+	}
+	go p.readLoop(stdout)
+
+	params := InitializeParams{
+		ProtocolVersion: "2025-03-26",
+		ClientInfo: ClientInfo{
+			Name:    "openresponses-gw",
+			Version: "0.1.0",
+		},
+		Capabilities: map[string]any{},
+	}
+	if _, err := p.call(ctx, "initialize", params); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("stdio server %s: initialize: %w", cfg.Command, err)
+	}
+	if err := p.write(map[string]string{"jsonrpc": "2.0", "method": "notifications/initialized"}); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("stdio server %s: initialize: %w", cfg.Command, err)
+	}
+	return p, nil
+}
+
+// stdioEnv builds the child environment from the passthrough variables and
+// the configured extras.
+func stdioEnv(extra map[string]string) []string {
+	var env []string
+	for _, name := range stdioEnvPassthrough {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	for k, v := range extra {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// call sends a request and waits for the matching response.
+func (p *stdioProcess) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := p.nextID.Add(1)
+	ch := make(chan stdioMessage, 1)
+
+	p.mu.Lock()
+	p.pending[id] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	req := JSONRPCRequest{JSONRPC: "2.0", Method: method, ID: int(id), Params: params}
+	if err := p.write(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return nil, fmt.Errorf("rpc error %d: %s", msg.Error.Code, msg.Error.Message)
+		}
+		return msg.Result, nil
+	case <-p.done:
+		return nil, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// write sends one newline-delimited JSON-RPC message.
+func (p *stdioProcess) write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	data = append(data, '\n')
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if _, err := p.stdin.Write(data); err != nil {
+		select {
+		case <-p.done:
+			return p.err
+		default:
+			return fmt.Errorf("write to stdio server: %w", err)
+		}
+	}
+	return nil
+}
+
+// readLoop dispatches responses to waiting callers until stdout closes,
+// then reaps the process.
+func (p *stdioProcess) readLoop(stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			p.dispatch(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	waitErr := p.cmd.Wait()
+	p.exited = time.Now()
+	msg := "exited"
+	if waitErr != nil {
+		msg = waitErr.Error()
+	}
+	if tail := strings.TrimSpace(p.stderr.String()); tail != "" {
+		msg += ": " + tail
+	}
+	p.err = fmt.Errorf("stdio server %s %s", p.cmd.Path, msg)
+	close(p.done)
+}
+
+func (p *stdioProcess) dispatch(line []byte) {
+	var msg stdioMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		// Servers may log non-JSON lines to stdout; skip them
+		return
+	}
+
+	if msg.Method != "" {
+		// Request or notification from the server. Requests get a
+		// method-not-found error so that the server does not wait forever.
+		if len(msg.ID) > 0 {
+			_ = p.write(map[string]any{
+				"jsonrpc": "2.0",
+				"id":      msg.ID,
+				"error":   JSONRPCError{Code: -32601, Message: "method not found"},
+			})
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	ch, ok := p.pending[id]
+	p.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// stop closes stdin and kills the process if it does not exit within the
+// grace period.
+func (p *stdioProcess) stop() {
+	p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(stopGracePeriod):
+		if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return
+		}
+		<-p.done
+	}
+}
+
+// tailBuffer is an io.Writer that keeps the last max bytes written.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
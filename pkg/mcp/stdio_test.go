@@ -0,0 +1,370 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubModeEnv makes the test binary run as a stub MCP server instead of the
+// tests, in the given mode.
+const stubModeEnv = "MCP_STDIO_STUB_MODE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(stubModeEnv); mode != "" {
+		runStubServer(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runStubServer serves newline-delimited JSON-RPC on stdin/stdout until
+// stdin closes. In "hang" mode it never answers. Otherwise it answers:
+//
+//	initialize  with the protocol version it was sent
+//	state       with whether the handshake completed and its process ID
+//	echo        with params.n, after params.sleep_ms
+//	crash       by exiting with status 3
+func runStubServer(mode string) {
+	var (
+		mu          sync.Mutex
+		out         = json.NewEncoder(os.Stdout)
+		protocol    string
+		initialized bool
+	)
+	reply := func(id json.RawMessage, result any) {
+		mu.Lock()
+		defer mu.Unlock()
+		out.Encode(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				ProtocolVersion string `json:"protocolVersion"`
+				N               int    `json:"n"`
+				SleepMs         int    `json:"sleep_ms"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil || mode == "hang" {
+			continue
+		}
+		switch req.Method {
+		case "initialize":
+			mu.Lock()
+			protocol = req.Params.ProtocolVersion
+			mu.Unlock()
+			reply(req.ID, map[string]any{"protocolVersion": req.Params.ProtocolVersion})
+		case "notifications/initialized":
+			mu.Lock()
+			initialized = true
+			mu.Unlock()
+		case "state":
+			mu.Lock()
+			state := stubState{Protocol: protocol, Initialized: initialized, PID: os.Getpid()}
+			mu.Unlock()
+			reply(req.ID, state)
+		case "echo":
+			go func() {
+				time.Sleep(time.Duration(req.Params.SleepMs) * time.Millisecond)
+				reply(req.ID, map[string]int{"n": req.Params.N})
+			}()
+		case "crash":
+			fmt.Fprintln(os.Stderr, "boom")
+			os.Exit(3)
+		}
+	}
+}
+
+type stubState struct {
+	Protocol    string `json:"protocol"`
+	Initialized bool   `json:"initialized"`
+	PID         int    `json:"pid"`
+}
+
+// stubConfig returns the configuration of a stub server in mode.
+func stubConfig(t *testing.T, mode string) StdioConfig {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return StdioConfig{Command: exe, Env: map[string]string{stubModeEnv: mode}}
+}
+
+func newStubServer(t *testing.T, mode string) *StdioServer {
+	t.Helper()
+	s := newStdioServer(stubConfig(t, mode), 8)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func callState(t *testing.T, s *StdioServer) stubState {
+	t.Helper()
+	raw, err := s.call(context.Background(), "state", nil)
+	if err != nil {
+		t.Fatalf("call(state) error = %v", err)
+	}
+	var state stubState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestStdioServer_Initialize(t *testing.T) {
+	s := newStubServer(t, "serve")
+	state := callState(t, s)
+	if state.Protocol != "2025-03-26" || !state.Initialized {
+		t.Errorf("state = %+v, want the initialize handshake completed", state)
+	}
+	// The process is reused
+	if again := callState(t, s); again.PID != state.PID {
+		t.Errorf("pid = %d, want %d", again.PID, state.PID)
+	}
+}
+
+func TestStdioServer_ConcurrentCalls(t *testing.T) {
+	s := newStubServer(t, "serve")
+	const n = 8
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Later calls are answered first
+			raw, err := s.call(context.Background(), "echo", map[string]int{"n": i, "sleep_ms": (n - i) * 10})
+			if err != nil {
+				t.Errorf("call(echo %d) error = %v", i, err)
+				return
+			}
+			var got struct{ N int }
+			if err := json.Unmarshal(raw, &got); err != nil || got.N != i {
+				t.Errorf("call(echo %d) = %s", i, raw)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStdioServer_CrashBackoff(t *testing.T) {
+	s := newStubServer(t, "serve")
+	pid := callState(t, s).PID
+
+	if _, err := s.call(context.Background(), "crash", nil); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("call(crash) error = %v, want the stderr of the server", err)
+	}
+
+	// Requests fail until the backoff has passed
+	_, err := s.call(context.Background(), "state", nil)
+	if err == nil || !strings.Contains(err.Error(), "restarting in 1s") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("call() after crash error = %v, want the restart backoff", err)
+	}
+	s.mu.Lock()
+	failures := s.failures
+	s.nextStart = time.Now()
+	s.mu.Unlock()
+	if failures != 1 {
+		t.Errorf("failures = %d, want 1", failures)
+	}
+
+	if state := callState(t, s); state.PID == pid || !state.Initialized {
+		t.Errorf("state after restart = %+v, want a new initialized process", state)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		0:  0,
+		1:  time.Second,
+		2:  2 * time.Second,
+		5:  16 * time.Second,
+		6:  maxRestartBackoff,
+		40: maxRestartBackoff,
+	} {
+		if got := restartBackoff(failures); got != want {
+			t.Errorf("restartBackoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestStdioManager_IdleReaping(t *testing.T) {
+	cfg := stubConfig(t, "serve")
+	m := NewStdioManager(StdioManagerOptions{AllowedCommands: []string{cfg.Command}, IdleTimeout: 20 * time.Millisecond})
+	defer m.Close()
+
+	s, err := m.Get("conn", cfg)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	pid := callState(t, s).PID
+	waitFor(t, "the idle process to stop", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.proc == nil
+	})
+
+	// The next request starts it again
+	if state := callState(t, s); state.PID == pid {
+		t.Errorf("pid = %d, want a new process", state.PID)
+	}
+}
+
+func TestStdioServer_CloseInFlight(t *testing.T) {
+	s := newStubServer(t, "serve")
+	if err := s.ensureStarted(context.Background()); err != nil {
+		t.Fatalf("ensureStarted() error = %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.call(context.Background(), "echo", map[string]int{"sleep_ms": 60_000})
+		errc <- err
+	}()
+	waitFor(t, "the call to be in flight", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.active == 1
+	})
+
+	s.Close()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("in-flight call succeeded after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight call did not end after Close")
+	}
+	if _, err := s.call(context.Background(), "state", nil); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("call() after Close error = %v, want closed", err)
+	}
+}
+
+// A server that never answers initialize holds up only the callers
+// waiting for it
+func TestStdioServer_InitializeHangs(t *testing.T) {
+	s := newStubServer(t, "hang")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.call(ctx, "state", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("call() error = %v, want the caller's deadline", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.call(context.Background(), "state", nil)
+		errc <- err
+	}()
+	waitFor(t, "the process to be starting", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.starting != nil
+	})
+	s.stopIfIdle(0)
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the initialize handshake")
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), "closed") {
+			t.Errorf("waiting call() error = %v, want closed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting call did not end after Close")
+	}
+}
+
+func TestStdioManager_CheckCommand(t *testing.T) {
+	m := NewStdioManager(StdioManagerOptions{AllowedCommands: []string{"npx", "/usr/bin/mcp-server"}})
+	defer m.Close()
+
+	tests := []struct {
+		command string
+		wantErr string
+	}{
+		{command: "npx"},
+		{command: "/usr/bin/mcp-server"},
+		{command: "", wantErr: "command is required"},
+		{command: "mcp-server", wantErr: "not in the list of allowed stdio commands"},
+		{command: "npx ", wantErr: "not in the list of allowed stdio commands"},
+		{command: "/bin/sh", wantErr: "not in the list of allowed stdio commands"},
+	}
+	for _, tt := range tests {
+		err := m.CheckCommand(tt.command)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("CheckCommand(%q) error = %v", tt.command, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("CheckCommand(%q) error = %v, want %q", tt.command, err, tt.wantErr)
+		}
+		if _, err := m.Get("conn", StdioConfig{Command: tt.command}); err == nil {
+			t.Errorf("Get(%q) error = nil", tt.command)
+		}
+	}
+}
+
+func TestStdioManager_Get(t *testing.T) {
+	cfg := stubConfig(t, "serve")
+	m := NewStdioManager(StdioManagerOptions{AllowedCommands: []string{cfg.Command}})
+	defer m.Close()
+
+	s, err := m.Get("conn", cfg)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if again, _ := m.Get("conn", cfg); again != s {
+		t.Error("Get() with the same config returned a new server")
+	}
+
+	// A changed config replaces the server and stops the old one
+	changed := cfg
+	changed.Args = []string{"-test.run=none"}
+	replaced, err := m.Get("conn", changed)
+	if err != nil || replaced == s {
+		t.Fatalf("Get() with a changed config = %p, %v", replaced, err)
+	}
+	if _, err := s.call(context.Background(), "state", nil); err == nil {
+		t.Error("replaced server still serves requests")
+	}
+	if state := callState(t, replaced); !state.Initialized {
+		t.Errorf("state = %+v", state)
+	}
+}
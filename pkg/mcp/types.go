@@ -54,9 +54,15 @@ type ToolsListResult struct {
 
 // ToolInfo describes a single tool exposed by an MCP server.
 type ToolInfo struct {
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	InputSchema map[string]any `json:"inputSchema"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema map[string]any   `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are optional hints about a tool's behavior.
+type ToolAnnotations struct {
+	ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
 }
 
 // ToolCallParams is the params for "tools/call".
@@ -0,0 +1,50 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package moderation screens text against a content moderation backend.
+package moderation
+
+import (
+	"context"
+	"sort"
+
+	"github.com/leseb/openresponses-gw/pkg/provider"
+)
+
+// Providers is the registry of moderation provider implementations.
+// The OpenAI-compatible provider is registered automatically via init().
+var Providers = provider.NewRegistry[Provider]("moderation")
+
+// Result is the classification of a single input.
+type Result struct {
+	Flagged    bool
+	Categories map[string]bool    // category → flagged by the provider
+	Scores     map[string]float64 // category → confidence score in [0, 1]
+}
+
+// Provider classifies text against moderation categories.
+type Provider interface {
+	Moderate(ctx context.Context, input string) (*Result, error)
+}
+
+// Violations returns the sorted list of categories that violate policy.
+// A category with a configured threshold is flagged when its score reaches
+// the threshold; all other categories fall back to the provider's verdict.
+func Violations(result *Result, thresholds map[string]float64) []string {
+	if result == nil {
+		return nil
+	}
+	var out []string
+	for category, flagged := range result.Categories {
+		if _, ok := thresholds[category]; !ok && flagged {
+			out = append(out, category)
+		}
+	}
+	for category, threshold := range thresholds {
+		if score, ok := result.Scores[category]; ok && score >= threshold {
+			out = append(out, category)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,97 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestViolations(t *testing.T) {
+	result := &Result{
+		Flagged: true,
+		Categories: map[string]bool{
+			"violence":   true,
+			"harassment": false,
+			"hate":       true,
+		},
+		Scores: map[string]float64{
+			"violence":   0.6,
+			"harassment": 0.4,
+			"hate":       0.9,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		thresholds map[string]float64
+		want       []string
+	}{
+		{
+			name: "provider verdict only",
+			want: []string{"hate", "violence"},
+		},
+		{
+			name:       "threshold relaxes a category",
+			thresholds: map[string]float64{"violence": 0.8},
+			want:       []string{"hate"},
+		},
+		{
+			name:       "threshold tightens a category",
+			thresholds: map[string]float64{"harassment": 0.3},
+			want:       []string{"harassment", "hate", "violence"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Violations(result, tt.thresholds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Violations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := Violations(nil, nil); got != nil {
+		t.Errorf("Violations(nil) = %v, want nil", got)
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/moderations" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["input"] != "hello" || body["model"] != "omni-moderation-latest" {
+			t.Errorf("unexpected request body %v", body)
+		}
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"hate":true},"category_scores":{"hate":0.97}}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := Providers.New(context.Background(), "openai", map[string]string{
+		"base_url": srv.URL + "/v1/",
+		"api_key":  "key",
+		"model":    "omni-moderation-latest",
+	})
+	if err != nil {
+		t.Fatalf("Providers.New: %v", err)
+	}
+
+	result, err := p.Moderate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Moderate: %v", err)
+	}
+	if !result.Flagged || !result.Categories["hate"] || result.Scores["hate"] != 0.97 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
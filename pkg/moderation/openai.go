@@ -0,0 +1,105 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	Providers.Register("openai", func(_ context.Context, params map[string]string) (Provider, error) {
+		return NewOpenAIProvider(params["base_url"], params["api_key"], params["model"]), nil
+	})
+}
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIProvider calls an OpenAI-compatible /v1/moderations endpoint.
+// Local classifiers (e.g. Llama Guard behind a compatible server) work by
+// pointing the base URL at them.
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates a new OpenAI-compatible moderation provider.
+// An empty baseURL defaults to the OpenAI API.
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &OpenAIProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// Moderate classifies input using the /moderations endpoint.
+func (p *OpenAIProvider) Moderate(ctx context.Context, input string) (*Result, error) {
+	payload := map[string]interface{}{"input": input}
+	if p.model != "" {
+		payload["model"] = p.model
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/moderations", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result openAIModerationResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("moderation returned no results")
+	}
+
+	r := result.Results[0]
+	return &Result{
+		Flagged:    r.Flagged,
+		Categories: r.Categories,
+		Scores:     r.CategoryScores,
+	}, nil
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
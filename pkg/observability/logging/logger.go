@@ -4,9 +4,12 @@
 package logging
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
 )
 
 // Config for logger
@@ -19,24 +22,14 @@ type Config struct {
 // Logger wraps slog.Logger
 type Logger struct {
 	*slog.Logger
+	level *slog.LevelVar
 }
 
 // New creates a new logger
 func New(cfg Config) *Logger {
 	// Parse level
-	var level slog.Level
-	switch cfg.Level {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
+	level := new(slog.LevelVar)
+	level.Set(parseLevel(cfg.Level))
 
 	// Set output
 	output := cfg.Output
@@ -55,6 +48,51 @@ func New(cfg Config) *Logger {
 	}
 
 	return &Logger{
-		Logger: slog.New(handler),
+		Logger: slog.New(contextHandler{handler}),
+		level:  level,
+	}
+}
+
+// SetLevel changes the minimum level of the logger, and of the loggers
+// derived from it, while it is in use.
+func (l *Logger) SetLevel(level string) {
+	l.level.Set(parseLevel(level))
+}
+
+// contextHandler adds the request ID of the context to the records logged
+// with one, such as by InfoContext, so that the lines of a request can be
+// found from the X-Request-ID it was answered with.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// parseLevel parses a level name, defaulting to info.
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
 	}
 }
@@ -0,0 +1,333 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/leseb/openresponses-gw/pkg/observability/requestid"
+)
+
+// DefaultPayloadMaxBytes is the default cap on a logged body.
+const DefaultPayloadMaxBytes = 16 << 10
+
+// payloadCaptureLimit bounds how much of a body is buffered for logging.
+// JSON is only redacted when parsed whole, so larger JSON bodies are logged
+// by size only.
+const payloadCaptureLimit = 4 << 20
+
+// redacted replaces the values removed from logged payloads.
+const redacted = "[REDACTED]"
+
+// Values always redacted from logged payloads, in addition to those in
+// PayloadOptions.
+var (
+	DefaultRedactFields  = []string{"file_data", "authorization", "api_key"}
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+)
+
+// PayloadOptions configures payload logging. Zero values select the
+// defaults.
+type PayloadOptions struct {
+	// MaxBytes caps each logged body, after redaction.
+	MaxBytes int
+	// RedactFields are JSON object keys, matched case-insensitively at any
+	// depth, whose values are replaced.
+	RedactFields []string
+	// RedactHeaders are the headers whose values are replaced.
+	RedactHeaders []string
+	// RedactMetadataKeys are path.Match patterns of the keys of "metadata"
+	// objects whose values are replaced, such as "user_*".
+	RedactMetadataKeys []string
+}
+
+// PayloadHandler wraps next to log the headers and bodies of requests and
+// their responses at debug level, for debugging clients that send
+// malformed requests. Credentials, file contents and base64 data URLs
+// (inline images) are redacted, and bodies other than JSON and server-sent
+// events are logged by size only. Nothing is buffered while debug logging
+// is disabled.
+func (l *Logger) PayloadHandler(next http.Handler, opts PayloadOptions) http.Handler {
+	p := &payloadHandler{
+		logger:       l,
+		next:         next,
+		maxBytes:     opts.MaxBytes,
+		fields:       make(map[string]bool),
+		headers:      make(map[string]bool),
+		metadataKeys: opts.RedactMetadataKeys,
+	}
+	if p.maxBytes <= 0 {
+		p.maxBytes = DefaultPayloadMaxBytes
+	}
+	for _, f := range append(DefaultRedactFields, opts.RedactFields...) {
+		p.fields[strings.ToLower(f)] = true
+	}
+	for _, h := range append(DefaultRedactHeaders, opts.RedactHeaders...) {
+		p.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return p
+}
+
+type payloadHandler struct {
+	logger       *Logger
+	next         http.Handler
+	maxBytes     int
+	fields       map[string]bool // lowercased
+	headers      map[string]bool // canonical
+	metadataKeys []string
+}
+
+func (p *payloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.logger.Enabled(r.Context(), slog.LevelDebug) {
+		p.next.ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	// The payloads are logged with the ID the handler will use
+	r = requestid.Ensure(w, r)
+
+	// The request is logged once its body is read, so that it shows up
+	// before a long streamed response ends
+	var once sync.Once
+	reqBody := &payloadReader{ReadCloser: r.Body}
+	logRequest := func() {
+		once.Do(func() {
+			body, truncated := p.body(r.Header.Get("Content-Type"), &reqBody.payloadBuffer)
+			p.logger.DebugContext(r.Context(), "Request payload",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"headers", p.headerAttr(r.Header),
+				"body", body,
+				"body_bytes", reqBody.n,
+				"truncated", truncated)
+		})
+	}
+	reqBody.onEOF = logRequest
+	if r.Body != nil {
+		r = r.WithContext(r.Context())
+		r.Body = reqBody
+	}
+
+	pw := &payloadWriter{ResponseWriter: w}
+	p.next.ServeHTTP(pw, r)
+	logRequest()
+
+	status := pw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body, truncated := p.body(w.Header().Get("Content-Type"), &pw.payloadBuffer)
+	p.logger.DebugContext(r.Context(), "Response payload",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+		"headers", p.headerAttr(w.Header()),
+		"body", body,
+		"body_bytes", pw.n,
+		"truncated", truncated,
+		"duration", time.Since(start))
+}
+
+// headerAttr returns the headers to log, with sensitive values redacted.
+func (p *payloadHandler) headerAttr(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if p.headers[name] {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// body returns the redacted body to log and whether it was cut to
+// maxBytes.
+func (p *payloadHandler) body(contentType string, b *payloadBuffer) (string, bool) {
+	if b.n == 0 {
+		return "", false
+	}
+	complete := b.n == int64(b.buf.Len())
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var out string
+	switch {
+	// JSON sent with another content type is logged too: a wrong header is
+	// a common client bug
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		(complete && json.Valid(b.buf.Bytes())):
+		if !complete {
+			return fmt.Sprintf("[%d bytes, too large to redact]", b.n), false
+		}
+		var ok bool
+		if out, ok = p.redactJSON(b.buf.Bytes()); !ok {
+			return fmt.Sprintf("[%d bytes of invalid JSON]", b.n), false
+		}
+	case mediaType == "text/event-stream":
+		out = p.redactEvents(b.buf.Bytes(), complete)
+	default:
+		if mediaType == "" {
+			mediaType = "unknown content type"
+		}
+		return fmt.Sprintf("[%d bytes of %s]", b.n, mediaType), false
+	}
+
+	if len(out) <= p.maxBytes {
+		return out, false
+	}
+	cut := p.maxBytes
+	for cut > 0 && !utf8.RuneStart(out[cut]) {
+		cut--
+	}
+	return out[:cut], true
+}
+
+// redactJSON returns a JSON document with the sensitive values replaced.
+func (p *payloadHandler) redactJSON(data []byte) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(p.redactValue(v)); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(out.String(), "\n"), true
+}
+
+func (p *payloadHandler) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch {
+			case p.fields[strings.ToLower(key)]:
+				v[key] = redacted
+			case key == "metadata":
+				if m, ok := value.(map[string]any); ok {
+					p.redactMetadata(m)
+				}
+			default:
+				v[key] = p.redactValue(value)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = p.redactValue(v[i])
+		}
+	case string:
+		// Inline images and files, "data:image/png;base64,..."
+		if strings.HasPrefix(v, "data:") && strings.Contains(v, ";base64,") {
+			return fmt.Sprintf("[REDACTED %d bytes]", len(v))
+		}
+	}
+	return v
+}
+
+func (p *payloadHandler) redactMetadata(m map[string]any) {
+	for key := range m {
+		for _, pattern := range p.metadataKeys {
+			if ok, _ := path.Match(pattern, key); ok {
+				m[key] = redacted
+				break
+			}
+		}
+	}
+}
+
+// redactEvents redacts the JSON data lines of a server-sent event stream.
+// A partial last line is dropped.
+func (p *payloadHandler) redactEvents(data []byte, complete bool) string {
+	lines := strings.Split(string(data), "\n")
+	if !complete {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		event, ok := strings.CutPrefix(line, "data: ")
+		if !ok || !json.Valid([]byte(event)) {
+			continue
+		}
+		if out, ok := p.redactJSON([]byte(event)); ok {
+			lines[i] = "data: " + out
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// payloadBuffer keeps the start of a body and counts its length.
+type payloadBuffer struct {
+	buf bytes.Buffer
+	n   int64
+}
+
+func (b *payloadBuffer) capture(data []byte) {
+	b.n += int64(len(data))
+	if room := payloadCaptureLimit - b.buf.Len(); room > 0 {
+		b.buf.Write(data[:min(room, len(data))])
+	}
+}
+
+// payloadReader captures a request body as the handler reads it.
+type payloadReader struct {
+	io.ReadCloser
+	payloadBuffer
+	onEOF func()
+}
+
+func (r *payloadReader) Read(data []byte) (int, error) {
+	n, err := r.ReadCloser.Read(data)
+	r.capture(data[:n])
+	if err == io.EOF {
+		r.onEOF()
+	}
+	return n, err
+}
+
+// payloadWriter captures a response body as the handler writes it.
+type payloadWriter struct {
+	http.ResponseWriter
+	payloadBuffer
+	status int
+}
+
+func (w *payloadWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *payloadWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.capture(data[:n])
+	return n, err
+}
+
+// Flush implements http.Flusher, for streamed responses.
+func (w *payloadWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *payloadWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,170 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// payloadRecord is a line logged by the payload handler.
+type payloadRecord struct {
+	Msg       string            `json:"msg"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+	BodyBytes int               `json:"body_bytes"`
+	Truncated bool              `json:"truncated"`
+}
+
+// servePayload sends a request through the payload handler to a handler
+// answering with respContentType and respBody, and returns the logged
+// request and response payloads.
+func servePayload(t *testing.T, level string, opts PayloadOptions, req *http.Request, respContentType, respBody string) []payloadRecord {
+	t.Helper()
+	var out bytes.Buffer
+	logger := New(Config{Level: level, Format: "json", Output: &out})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", respContentType)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(respBody))
+	})
+	logger.PayloadHandler(next, opts).ServeHTTP(httptest.NewRecorder(), req)
+
+	var records []payloadRecord
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record payloadRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line %s: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestPayloadHandler_RedactsRequest(t *testing.T) {
+	image := "data:image/png;base64," + strings.Repeat("iVBORw0KGgo", 20)
+	body := `{
+		"model": "m",
+		"input": [{"role": "user", "content": [
+			{"type": "input_text", "text": "What is in these?"},
+			{"type": "input_image", "image_url": "` + image + `"},
+			{"type": "input_file", "filename": "a.pdf", "file_data": "JVBERi0xLjQK"}
+		]}],
+		"tools": [{"type": "mcp", "server_label": "s", "headers": {"Authorization": "Bearer mcp-secret"}}],
+		"metadata": {"user_email": "a@example.com", "user_id": "u1", "team": "search"}
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("X-Api-Key", "sk-other")
+	req.Header.Set("X-Trace", "abc")
+
+	records := servePayload(t, "debug", PayloadOptions{RedactMetadataKeys: []string{"user_*"}}, req, "application/json", `{"id":"resp_1"}`)
+	if len(records) != 2 || records[0].Msg != "Request payload" {
+		t.Fatalf("records = %+v, want request and response payloads", records)
+	}
+	logged := records[0]
+	for _, secret := range []string{"sk-secret", "sk-other", "mcp-secret", "JVBERi0xLjQK", "iVBORw0KGgo", "a@example.com", "u1"} {
+		if strings.Contains(logged.Body, secret) || strings.Contains(logged.Headers["Authorization"]+logged.Headers["X-Api-Key"], secret) {
+			t.Errorf("logged request contains %q: %+v", secret, logged)
+		}
+	}
+	for _, kept := range []string{"What is in these?", `"filename":"a.pdf"`, `"team":"search"`, `"file_data":"[REDACTED]"`, `[REDACTED 242 bytes]`} {
+		if !strings.Contains(logged.Body, kept) {
+			t.Errorf("logged request body %s does not contain %s", logged.Body, kept)
+		}
+	}
+	if logged.Headers["Authorization"] != redacted || logged.Headers["X-Trace"] != "abc" {
+		t.Errorf("logged request headers = %v", logged.Headers)
+	}
+	if logged.BodyBytes != len(body) || logged.Truncated {
+		t.Errorf("logged request = %d bytes, truncated %v, want %d bytes", logged.BodyBytes, logged.Truncated, len(body))
+	}
+}
+
+func TestPayloadHandler_RedactsResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"id":"resp_1","metadata":{"user_email":"a@example.com","team":"search"},"api_key":"sk-secret"}`,
+			want:        `{"api_key":"[REDACTED]","id":"resp_1","metadata":{"team":"search","user_email":"[REDACTED]"}}`,
+		},
+		{
+			name:        "json with a wrong content type",
+			contentType: "text/plain",
+			body:        `{"file_data":"JVBERi0xLjQK"}`,
+			want:        `{"file_data":"[REDACTED]"}`,
+		},
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			body:        "event: response.created\ndata: {\"response\":{\"metadata\":{\"user_id\":\"u1\"}}}\n\ndata: [DONE]\n",
+			want:        "event: response.created\ndata: {\"response\":{\"metadata\":{\"user_id\":\"[REDACTED]\"}}}\n\ndata: [DONE]\n",
+		},
+		{
+			name:        "other content",
+			contentType: "application/pdf",
+			body:        "%PDF-1.4",
+			want:        "[8 bytes of application/pdf]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/responses/resp_1", nil)
+			records := servePayload(t, "debug", PayloadOptions{RedactMetadataKeys: []string{"user_*"}}, req, tt.contentType, tt.body)
+			if len(records) != 2 || records[1].Msg != "Response payload" {
+				t.Fatalf("records = %+v, want request and response payloads", records)
+			}
+			if got := records[1]; got.Body != tt.want || got.BodyBytes != len(tt.body) || got.Headers["Set-Cookie"] != redacted {
+				t.Errorf("logged response = %+v, want body %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayloadHandler_MaxBytes(t *testing.T) {
+	// Multi-byte runes must not be split at the cap
+	text := strings.Repeat("é", 100)
+	body := `{"input":"` + text + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	records := servePayload(t, "debug", PayloadOptions{MaxBytes: 64}, req, "application/json", `{"id":"resp_1"}`)
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want request and response payloads", records)
+	}
+	got := records[0]
+	if !got.Truncated || len(got.Body) > 64 || !utf8.ValidString(got.Body) || !strings.HasPrefix(got.Body, `{"input":"éé`) {
+		t.Errorf("logged request = %+v, want a valid prefix of at most 64 bytes", got)
+	}
+	if got.BodyBytes != len(body) {
+		t.Errorf("body_bytes = %d, want %d", got.BodyBytes, len(body))
+	}
+	if records[1].Truncated || records[1].Body != `{"id":"resp_1"}` {
+		t.Errorf("logged response = %+v, want it whole", records[1])
+	}
+}
+
+func TestPayloadHandler_DebugDisabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"m"}`))
+	if records := servePayload(t, "info", PayloadOptions{}, req, "application/json", `{}`); len(records) != 0 {
+		t.Errorf("records = %+v, want none", records)
+	}
+}
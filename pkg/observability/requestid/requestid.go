@@ -0,0 +1,83 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package requestid correlates the work done for a request: its ID is
+// accepted from the client, or generated, then carried by the request
+// context to the log lines, stored responses, published events and backend
+// calls of the request, and returned to the client in the X-Request-ID
+// header and in error bodies.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/leseb/openresponses-gw/pkg/ids"
+)
+
+// Header is the HTTP header carrying request IDs, both ways. gRPC clients
+// use the metadata key of the same name.
+const Header = "X-Request-ID"
+
+// MaxLength bounds the length of the request IDs accepted from clients.
+const MaxLength = 128
+
+// prefix starts generated request IDs.
+const prefix = "req_"
+
+type idKey struct{}
+
+// New returns a new request ID.
+func New() string {
+	return ids.Random{}.NewID(prefix)
+}
+
+// Valid reports whether id may be accepted from a client: 1 to MaxLength
+// printable ASCII characters, without spaces, so that it can be logged and
+// forwarded as is.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a context carrying the request ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the ID set by WithID, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// Ensure returns r with a request ID in its context: the one already
+// there, else the client's X-Request-ID if it is valid, else a new one. It
+// sets the header of the response to the ID.
+func Ensure(w http.ResponseWriter, r *http.Request) *http.Request {
+	if id := FromContext(r.Context()); id != "" {
+		w.Header().Set(Header, id)
+		return r
+	}
+	id := r.Header.Get(Header)
+	if !Valid(id) {
+		id = New()
+	}
+	w.Header().Set(Header, id)
+	return r.WithContext(WithID(r.Context(), id))
+}
+
+// SetHeader sets the X-Request-ID header of an outgoing request to the ID
+// of its context, if any.
+func SetHeader(req *http.Request) {
+	if id := FromContext(req.Context()); id != "" {
+		req.Header.Set(Header, id)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnsure(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string // "" for a generated ID
+	}{
+		{"client ID", "a1b2-c3d4", "a1b2-c3d4"},
+		{"no ID", "", ""},
+		{"spaces", "bad id", ""},
+		{"too long", strings.Repeat("x", MaxLength+1), ""},
+		{"non-ASCII", "réq", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			got := FromContext(Ensure(rec, req).Context())
+			if tt.want != "" && got != tt.want {
+				t.Errorf("ID = %q, want %q", got, tt.want)
+			}
+			if tt.want == "" && (!strings.HasPrefix(got, prefix) || !Valid(got)) {
+				t.Errorf("ID = %q, want a generated one", got)
+			}
+			if h := rec.Header().Get(Header); h != got {
+				t.Errorf("response header = %q, want %q", h, got)
+			}
+		})
+	}
+}
+
+func TestEnsure_KeepsContextID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "from-client")
+	req = req.WithContext(WithID(req.Context(), "from-adapter"))
+	rec := httptest.NewRecorder()
+	if got := FromContext(Ensure(rec, req).Context()); got != "from-adapter" || rec.Header().Get(Header) != got {
+		t.Errorf("ID = %q, header %q, want the context's", got, rec.Header().Get(Header))
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://backend/v1/responses", nil)
+	SetHeader(req)
+	if _, ok := req.Header[Header]; ok {
+		t.Error("header set without an ID")
+	}
+	req = req.WithContext(WithID(req.Context(), "req_1"))
+	SetHeader(req)
+	if got := req.Header.Get(Header); got != "req_1" {
+		t.Errorf("header = %q, want req_1", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package openapi builds the OpenAPI document served at /openapi.json. It
+// starts from the spec generated from the handler annotations
+// (docs/openapi.yaml) and adds what the annotations cannot express: the
+// events of response streams, generated from their Go types so that
+// clients generated from the document decode every event the gateway
+// sends.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leseb/openresponses-gw/docs"
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// StreamEventSchema names the component matching any event of a response
+// stream. Its variants are told apart by their type property.
+const StreamEventSchema = "ResponseStreamEvent"
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// streamingOperations are the operations that answer with a response
+// stream when the request sets stream.
+var streamingOperations = []struct{ path, method string }{
+	{"/v1/responses", "post"},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// JSON returns the OpenAPI document encoded as JSON.
+func JSON() ([]byte, error) {
+	doc, err := Document()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Document returns the OpenAPI document, decoded.
+func Document() (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(docs.OpenAPISpec, &raw); err != nil {
+		return nil, fmt.Errorf("parse embedded OpenAPI spec: %w", err)
+	}
+	doc, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("embedded OpenAPI spec is not an object")
+	}
+	schemas := object(object(doc, "components"), "schemas")
+	g := &generator{schemas: schemas}
+
+	variants := make([]interface{}, 0, len(schema.StreamingEvents))
+	mapping := make(map[string]interface{}, len(schema.StreamingEvents))
+	for _, event := range schema.StreamingEvents {
+		t := reflect.TypeOf(event.Event)
+		name := componentName(t)
+		// Events are always generated: their spec must follow their type
+		delete(schemas, name)
+		ref := g.ref(t)
+		component := schemas[name].(map[string]interface{})
+		component["description"] = fmt.Sprintf("The %s event of a response stream.", event.Type)
+		component["properties"].(map[string]interface{})["type"] = map[string]interface{}{
+			"type":  "string",
+			"const": event.Type,
+		}
+		variants = append(variants, ref)
+		mapping[event.Type] = ref["$ref"]
+	}
+	schemas[StreamEventSchema] = map[string]interface{}{
+		"description": "An event of a response stream, sent as the data of a server-sent event named after its type.",
+		"oneOf":       variants,
+		"discriminator": map[string]interface{}{
+			"propertyName": "type",
+			"mapping":      mapping,
+		},
+	}
+
+	paths := object(doc, "paths")
+	for _, op := range streamingOperations {
+		operation, ok := object(paths, op.path)[op.method].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("OpenAPI spec has no %s %s operation", strings.ToUpper(op.method), op.path)
+		}
+		ok200 := object(object(operation, "responses"), "200")
+		object(ok200, "content")["text/event-stream"] = map[string]interface{}{
+			"schema": map[string]interface{}{"$ref": schemaRefPrefix + StreamEventSchema},
+		}
+	}
+	return doc, nil
+}
+
+// object returns the object under key in m, adding an empty one if needed.
+func object(m map[string]interface{}, key string) map[string]interface{} {
+	if child, ok := m[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := make(map[string]interface{})
+	m[key] = child
+	return child
+}
+
+// normalize converts the YAML-decoded value v to one encoding/json can
+// marshal: mappings with non-string keys become string-keyed maps.
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalize(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = normalize(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalize(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// componentName returns the component name of a named Go type, following
+// the naming of the annotation-generated spec.
+func componentName(t reflect.Type) string {
+	return strings.NewReplacer("/", "_", ".", "_").Replace(t.PkgPath()) + "." + t.Name()
+}
+
+// generator derives JSON schemas from Go types, the way encoding/json
+// marshals them. Named structs become components; those the spec already
+// describes are referenced as they are.
+type generator struct {
+	schemas map[string]interface{}
+}
+
+// ref returns a reference to the component of the named struct t,
+// generating it if the spec lacks it.
+func (g *generator) ref(t reflect.Type) map[string]interface{} {
+	name := componentName(t)
+	if _, ok := g.schemas[name]; !ok {
+		// Reserve the name first, so recursive types terminate
+		g.schemas[name] = map[string]interface{}{}
+		g.schemas[name] = g.object(t)
+	}
+	return map[string]interface{}{"$ref": schemaRefPrefix + name}
+}
+
+// object returns the schema of the fields of the struct t.
+func (g *generator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.fields(t, properties, &required)
+	out := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		out["required"] = required
+	}
+	return out
+}
+
+func (g *generator) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft := deref(f.Type); ft.Kind() == reflect.Struct {
+				g.fields(ft, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+		s := g.schema(f.Type, f.Tag.Get("swaggertype"))
+		if f.Type.Kind() == reflect.Pointer && !omitempty {
+			// nil pointers are sent as null
+			s = map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+		}
+		properties[name] = s
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schema returns the schema of values of type t. swaggerType, the
+// swaggertype tag of the field, describes values of interface types.
+func (g *generator) schema(t reflect.Type, swaggerType string) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem(), swaggerType)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem(), "")}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem(), "")}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	case reflect.Interface:
+		if swaggerType != "" {
+			return map[string]interface{}{"type": swaggerType}
+		}
+	}
+	// Any value
+	return map[string]interface{}{}
+}
+
+func deref(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
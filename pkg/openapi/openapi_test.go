@@ -0,0 +1,109 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package openapi_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/schema"
+	"github.com/leseb/openresponses-gw/pkg/handlers"
+	"github.com/leseb/openresponses-gw/pkg/openapi"
+)
+
+// undocumented are the routes left out of the spec on purpose.
+var undocumented = map[string]bool{
+	"GET /openapi.json": true, // the spec itself
+	"GET /playground":   true, // HTML page
+	"GET /admin":        true, // HTML page
+	"POST /responses":   true, // alias of POST /v1/responses
+}
+
+func TestDocument_StreamingEvents(t *testing.T) {
+	doc, err := openapi.Document()
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	union := schemas[openapi.StreamEventSchema].(map[string]interface{})
+	mapping := union["discriminator"].(map[string]interface{})["mapping"].(map[string]interface{})
+	if len(union["oneOf"].([]interface{})) != len(schema.StreamingEvents) || len(mapping) != len(schema.StreamingEvents) {
+		t.Fatalf("%s has %d variants, want %d", openapi.StreamEventSchema, len(mapping), len(schema.StreamingEvents))
+	}
+	for _, event := range schema.StreamingEvents {
+		ref, _ := mapping[event.Type].(string)
+		component, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]interface{})
+		if !ok {
+			t.Errorf("no component for %s (ref %q)", event.Type, ref)
+			continue
+		}
+		properties := component["properties"].(map[string]interface{})
+		if got := properties["type"].(map[string]interface{})["const"]; got != event.Type {
+			t.Errorf("%s: type const = %v", event.Type, got)
+		}
+		if _, ok := properties["sequence_number"]; !ok {
+			t.Errorf("%s: no sequence_number property", event.Type)
+		}
+	}
+
+	responses := doc["paths"].(map[string]interface{})["/v1/responses"].(map[string]interface{})["post"].(map[string]interface{})["responses"].(map[string]interface{})
+	content := responses["200"].(map[string]interface{})["content"].(map[string]interface{})
+	if _, ok := content["text/event-stream"]; !ok {
+		t.Error("POST /v1/responses does not document its event stream")
+	}
+
+	// Every reference resolves
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := val["$ref"].(string); ok {
+				if _, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, item := range val {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// TestDocument_Routes checks that the spec documents every route of the
+// handler, and nothing else.
+func TestDocument_Routes(t *testing.T) {
+	doc, err := openapi.Document()
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+	params := regexp.MustCompile(`\{[^}]*\}`)
+	documented := make(map[string]bool)
+	for path, ops := range doc["paths"].(map[string]interface{}) {
+		for method := range ops.(map[string]interface{}) {
+			documented[strings.ToUpper(method)+" "+params.ReplaceAllString(path, "{}")] = true
+		}
+	}
+
+	served := make(map[string]bool)
+	h := handlers.New(nil, nil, nil, nil, nil, nil, nil)
+	for _, route := range h.Routes() {
+		key := params.ReplaceAllString(route, "{}")
+		served[key] = true
+		if !documented[key] && !undocumented[route] {
+			t.Errorf("route %s is not in docs/openapi.yaml", route)
+		}
+	}
+	for key := range documented {
+		if !served[key] {
+			t.Errorf("docs/openapi.yaml documents %s, which no route serves", key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pagination implements the cursor pagination shared by the list
+// endpoints.
+//
+// Items are ordered by creation time and then by ID, so that items created
+// in the same instant keep a stable position across pages. The after and
+// before cursors are item IDs and follow the listing order: with order
+// "desc", after selects older items and before selects newer ones.
+package pagination
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sort orders accepted by the list endpoints.
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Key is the position of an item in a listing.
+type Key struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Compare returns -1, 0 or +1 depending on whether k sorts before, with or
+// after other in ascending order.
+func (k Key) Compare(other Key) int {
+	if c := k.CreatedAt.Compare(other.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(k.ID, other.ID)
+}
+
+// Slice returns the page of items selected by the cursors, sorted in order
+// (anything but "asc" is descending), and whether more items follow in the
+// paging direction. With only a before cursor, the page holds the limit
+// items immediately preceding it. A cursor that matches no item selects
+// nothing. items is not modified.
+func Slice[T any](items []T, key func(T) Key, after, before string, limit int, order string) ([]T, bool) {
+	sorted := slices.Clone(items)
+	slices.SortFunc(sorted, func(a, b T) int {
+		if order == OrderAsc {
+			return key(a).Compare(key(b))
+		}
+		return key(b).Compare(key(a))
+	})
+
+	start, end := 0, len(sorted)
+	if after != "" {
+		i := slices.IndexFunc(sorted, func(item T) bool { return key(item).ID == after })
+		if i < 0 {
+			return nil, false
+		}
+		start = i + 1
+	}
+	if before != "" {
+		i := slices.IndexFunc(sorted, func(item T) bool { return key(item).ID == before })
+		if i < 0 || i < start {
+			return nil, false
+		}
+		end = i
+	}
+
+	window := sorted[start:end]
+	if len(window) <= limit {
+		return window, false
+	}
+	if after == "" && before != "" {
+		return window[len(window)-limit:], true
+	}
+	return window[:limit], true
+}
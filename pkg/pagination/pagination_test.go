@@ -0,0 +1,70 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pagination
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type item struct {
+	id      string
+	created time.Time
+}
+
+func itemKey(i item) Key { return Key{CreatedAt: i.created, ID: i.id} }
+
+func TestSlice(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	// b, c and d share a timestamp; the ID breaks the tie
+	items := []item{
+		{"d", t0.Add(time.Second)},
+		{"a", t0},
+		{"e", t0.Add(2 * time.Second)},
+		{"c", t0.Add(time.Second)},
+		{"b", t0.Add(time.Second)},
+	}
+
+	tests := []struct {
+		name        string
+		after       string
+		before      string
+		limit       int
+		order       string
+		want        []string
+		wantHasMore bool
+	}{
+		{"asc first page", "", "", 2, OrderAsc, []string{"a", "b"}, true},
+		{"asc after tie", "b", "", 2, OrderAsc, []string{"c", "d"}, true},
+		{"asc last page", "d", "", 2, OrderAsc, []string{"e"}, false},
+		{"desc first page", "", "", 2, OrderDesc, []string{"e", "d"}, true},
+		{"desc after", "d", "", 2, OrderDesc, []string{"c", "b"}, true},
+		{"desc before", "", "b", 2, OrderDesc, []string{"d", "c"}, true},
+		{"asc before", "", "c", 5, OrderAsc, []string{"a", "b"}, false},
+		{"after and before", "a", "e", 5, OrderAsc, []string{"b", "c", "d"}, false},
+		{"unknown cursor", "zz", "", 5, OrderAsc, nil, false},
+		{"crossed cursors", "d", "b", 5, OrderAsc, nil, false},
+		{"default order is desc", "", "", 1, "", []string{"e"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, hasMore := Slice(items, itemKey, tt.after, tt.before, tt.limit, tt.order)
+			var got []string
+			for _, i := range page {
+				got = append(got, i.id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+			if hasMore != tt.wantHasMore {
+				t.Errorf("hasMore = %v, want %v", hasMore, tt.wantHasMore)
+			}
+		})
+	}
+
+	if items[0].id != "d" {
+		t.Error("Slice reordered its input")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix starts every value sealed by a Keyring. JSON documents never
+// start with it, so sealed and plain values can be told apart.
+const sealedPrefix = "enc:v1:"
+
+// Keyring encrypts with its first key and decrypts with any of its keys, so
+// the key can be rotated while values sealed with previous keys stay
+// readable. Sealed values are text: "enc:v1:", the ID of the key, ":" and
+// the base64 ciphertext.
+type Keyring struct {
+	primary string
+	ciphers map[string]*Cipher
+}
+
+// NewKeyring creates a Keyring from 32-byte keys. The first key encrypts;
+// the others only decrypt.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("secrets: keyring needs at least one key")
+	}
+	k := &Keyring{ciphers: make(map[string]*Cipher, len(keys))}
+	for i, key := range keys {
+		c, err := NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		id := keyID(key)
+		if i == 0 {
+			k.primary = id
+		}
+		k.ciphers[id] = c
+	}
+	return k, nil
+}
+
+// ParseKeyring creates a Keyring from a comma-separated list of keys in any
+// format accepted by ParseKey, primary key first.
+func ParseKeyring(s string) (*Keyring, error) {
+	var keys [][]byte
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, err := ParseKey(part)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(keys...)
+}
+
+// keyID identifies a key without revealing it.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// IsSealed reports whether s was produced by Keyring.Seal.
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+// Seal encrypts plaintext with the primary key.
+func (k *Keyring) Seal(plaintext []byte) (string, error) {
+	data, err := k.ciphers[k.primary].Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + k.primary + ":" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Open decrypts a value produced by Seal with any key of the keyring.
+func (k *Keyring) Open(sealed string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return nil, fmt.Errorf("secrets: value is not sealed")
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, fmt.Errorf("secrets: malformed sealed value")
+	}
+	c, ok := k.ciphers[id]
+	if !ok {
+		return nil, fmt.Errorf("secrets: value sealed with unknown key %s", id)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: malformed sealed value: %w", err)
+	}
+	return c.Decrypt(data)
+}
+
+// IsCurrent reports whether s is sealed with the primary key, so it needs
+// no re-encryption after a rotation.
+func (k *Keyring) IsCurrent(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix+k.primary+":")
+}
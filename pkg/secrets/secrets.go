@@ -0,0 +1,94 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secrets encrypts credentials that the gateway keeps at rest, such
+// as connector authentication settings.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the required key length in bytes (AES-256).
+const KeySize = 32
+
+// Cipher encrypts and decrypts small secrets with AES-256-GCM. Ciphertexts
+// are the random nonce followed by the sealed data.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a Cipher from a 32-byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secrets: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// NewRandomCipher creates a Cipher with a freshly generated key. Data
+// encrypted with it cannot be decrypted after the process exits.
+func NewRandomCipher() *Cipher {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	c, err := NewCipher(key)
+	if err != nil {
+		// Unreachable: the key has the required length.
+		panic(err)
+	}
+	return c
+}
+
+// ParseKey decodes a 32-byte key given as base64 (standard or URL-safe,
+// padded or not) or hex.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil && len(b) == KeySize {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding,
+		base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) == KeySize {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("secrets: key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// Encrypt seals plaintext.
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("secrets: generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens data produced by Encrypt with the same key.
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("secrets: ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
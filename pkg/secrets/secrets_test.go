@@ -0,0 +1,143 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := NewRandomCipher()
+	plaintext := []byte(`{"type":"bearer","token":"secret"}`)
+
+	sealed, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("ciphertext contains plaintext")
+	}
+
+	got, err := c.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt = %q, want %q", got, plaintext)
+	}
+
+	// A different key must not open the ciphertext
+	if _, err := NewRandomCipher().Decrypt(sealed); err == nil {
+		t.Error("expected error decrypting with a different key")
+	}
+
+	// Tampering is detected
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := c.Decrypt(sealed); err == nil {
+		t.Error("expected error decrypting tampered ciphertext")
+	}
+
+	if _, err := c.Decrypt([]byte("short")); err == nil {
+		t.Error("expected error decrypting short ciphertext")
+	}
+}
+
+func TestNewCipher_KeySize(t *testing.T) {
+	if _, err := NewCipher(make([]byte, 16)); err == nil {
+		t.Error("expected error for 16-byte key")
+	}
+	if _, err := NewCipher(make([]byte, KeySize)); err != nil {
+		t.Errorf("NewCipher: %v", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xab}, KeySize)
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"hex", hex.EncodeToString(key), false},
+		{"base64", base64.StdEncoding.EncodeToString(key), false},
+		{"raw base64 url", base64.RawURLEncoding.EncodeToString(key), false},
+		{"surrounding whitespace", " " + base64.StdEncoding.EncodeToString(key) + "\n", false},
+		{"too short", base64.StdEncoding.EncodeToString(key[:16]), true},
+		{"garbage", "not a key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseKey(%q) expected error", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKey(%q): %v", tt.input, err)
+			}
+			if !bytes.Equal(got, key) {
+				t.Errorf("ParseKey(%q) = %x, want %x", tt.input, got, key)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, KeySize)
+	newKey := bytes.Repeat([]byte{2}, KeySize)
+	plaintext := []byte(`{"input":"my card number"}`)
+
+	before, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	sealed, err := before.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || IsSealed(string(plaintext)) {
+		t.Errorf("IsSealed does not tell %q from plaintext", sealed)
+	}
+	if bytes.Contains([]byte(sealed), []byte("card")) {
+		t.Error("sealed value contains plaintext")
+	}
+
+	// After rotation, old values still open but are no longer current
+	after, err := ParseKeyring(hex.EncodeToString(newKey) + "," + base64.StdEncoding.EncodeToString(oldKey))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	got, err := after.Open(sealed)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if after.IsCurrent(sealed) || !before.IsCurrent(sealed) {
+		t.Error("IsCurrent does not track the primary key")
+	}
+	resealed, err := after.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !after.IsCurrent(resealed) {
+		t.Error("value sealed with the new key is not current")
+	}
+
+	// Without the old key, old values cannot be opened
+	only, _ := NewKeyring(newKey)
+	if _, err := only.Open(sealed); err == nil {
+		t.Error("expected error opening a value sealed with a dropped key")
+	}
+	if _, err := only.Open("not sealed"); err == nil {
+		t.Error("expected error opening a plain value")
+	}
+	if _, err := ParseKeyring(" , "); err == nil {
+		t.Error("expected error for an empty keyring")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package servertls builds the TLS configuration of the gateway's HTTP and
+// gRPC listeners, so that deployments without a fronting proxy can
+// terminate TLS and authenticate clients themselves.
+//
+// The certificate, key and client CA files are watched: when one of them
+// changes, as when cert-manager or a sidecar rotates them, the next
+// handshake uses the new files, without a restart. A rotation that leaves
+// the files unusable, such as a key written before its certificate, keeps
+// the previous ones until the files are consistent again.
+package servertls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often the files are checked for changes.
+const DefaultReloadInterval = 30 * time.Second
+
+// Options configures the server TLS. Zero values select the defaults.
+type Options struct {
+	// CertFile and KeyFile are the PEM-encoded certificate chain and
+	// private key of the server.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, when set, requires clients to present a certificate
+	// signed by one of the PEM-encoded CAs it holds (mutual TLS).
+	ClientCAFile string
+	// AllowedSANs, when set, only accepts client certificates with one of
+	// these subject alternative names: DNS names, such as
+	// "client.example.com" or "*.example.com", URIs, such as SPIFFE IDs,
+	// email addresses or IP addresses. It requires ClientCAFile.
+	AllowedSANs []string
+	// ReloadInterval is how often the files are checked for changes, at
+	// most once per interval and only while handshakes happen. Negative
+	// disables reloading.
+	ReloadInterval time.Duration
+	// OnReload, when set, is called after the files changed, with the
+	// error that kept the previous ones if they could not be loaded.
+	OnReload func(error)
+}
+
+// Config returns a TLS server configuration serving the certificate in
+// opts. It fails if the files cannot be loaded.
+func Config(opts Options) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("a certificate and a key are required")
+	}
+	if len(opts.AllowedSANs) > 0 && opts.ClientCAFile == "" {
+		return nil, errors.New("allowed SANs require a client CA")
+	}
+	if opts.ReloadInterval == 0 {
+		opts.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &reloader{opts: opts}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if opts.ClientCAFile != "" {
+		// Client certificates are verified by verifyClient rather than
+		// through ClientCAs, so that a rotated CA applies to the next
+		// handshake
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyPeerCertificate = r.verifyClient
+	}
+	return cfg, nil
+}
+
+// reloader holds the files currently served, and reloads them when they
+// change.
+type reloader struct {
+	opts Options
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	stamps   []fileStamp // of the files loaded, in the order of files()
+	checked  time.Time
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// load reads the files. The caller holds r.mu, or owns r.
+func (r *reloader) load() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA %s holds no PEM certificate", r.opts.ClientCAFile)
+		}
+	}
+	r.cert, r.clientCA, r.stamps = &cert, pool, stamps
+	return nil
+}
+
+func (r *reloader) stat() ([]fileStamp, error) {
+	files := r.files()
+	stamps := make([]fileStamp, len(files))
+	for i, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// current returns the certificate and client CAs to use, reloading them
+// first if the files changed.
+func (r *reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.opts.ReloadInterval > 0 && time.Since(r.checked) >= r.opts.ReloadInterval {
+		r.checked = time.Now()
+		if stamps, err := r.stat(); err != nil || !slices.Equal(stamps, r.stamps) {
+			err := r.load()
+			if r.opts.OnReload != nil {
+				r.opts.OnReload(err)
+			}
+		}
+	}
+	return r.cert, r.clientCA
+}
+
+func (r *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	return cert, nil
+}
+
+// verifyClient verifies the client certificate chain against the client
+// CAs, then checks its SANs.
+func (r *reloader) verifyClient(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("client certificate required")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parse client certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+	_, roots := r.current()
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("verify client certificate: %w", err)
+	}
+	if len(r.opts.AllowedSANs) > 0 && !sanAllowed(certs[0], r.opts.AllowedSANs) {
+		return fmt.Errorf("client certificate %q has no allowed SAN", certs[0].Subject.CommonName)
+	}
+	return nil
+}
+
+// sanAllowed reports whether cert has one of the allowed SANs.
+func sanAllowed(cert *x509.Certificate, allowed []string) bool {
+	for _, want := range allowed {
+		for _, name := range cert.DNSNames {
+			if dnsMatch(want, name) {
+				return true
+			}
+		}
+		for _, uri := range cert.URIs {
+			if uri.String() == want {
+				return true
+			}
+		}
+		for _, email := range cert.EmailAddresses {
+			if strings.EqualFold(email, want) {
+				return true
+			}
+		}
+		if ip := net.ParseIP(want); ip != nil {
+			for _, got := range cert.IPAddresses {
+				if got.Equal(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// dnsMatch reports whether the DNS name matches pattern, which may start
+// with "*." to match any subdomain.
+func dnsMatch(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(name, "."+suffix)
+	}
+	return pattern == name
+}
@@ -0,0 +1,240 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var (
+	serial int64
+	mtime  = time.Now()
+)
+
+// newCert issues a certificate from parent, or a self-signed CA when
+// parent is nil.
+func newCert(t *testing.T, parent *testCert, name string, tmpl x509.Certificate) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := &tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCert(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Distinct modification times, whatever the file system's resolution
+	mtime = mtime.Add(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake connects a client presenting clientCerts to a server using
+// cfg, and returns the certificate the client was served and the server's
+// handshake error.
+func handshake(t *testing.T, cfg *tls.Config, clientCerts ...tls.Certificate) (*x509.Certificate, error) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		errc <- tls.Server(conn, cfg).Handshake()
+	}()
+	clientConn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	client := tls.Client(clientConn, &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       clientCerts,
+	})
+	client.Handshake()
+	var served *x509.Certificate
+	if certs := client.ConnectionState().PeerCertificates; len(certs) > 0 {
+		served = certs[0]
+	}
+	return served, <-errc
+}
+
+func TestConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	ca := newCert(t, nil, "ca", x509.Certificate{})
+	first := newCert(t, ca, "gateway", x509.Certificate{DNSNames: []string{"gateway"}})
+	writeFile(t, certFile, first.certPEM())
+	writeFile(t, keyFile, first.keyPEM(t))
+
+	var reloads []error
+	cfg, err := Config(Options{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: time.Nanosecond,
+		OnReload:       func(err error) { reloads = append(reloads, err) },
+	})
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	if served, err := handshake(t, cfg); err != nil || served.SerialNumber.Cmp(first.cert.SerialNumber) != 0 {
+		t.Fatalf("handshake error %v, served %v", err, served)
+	}
+
+	// A key rotated before its certificate keeps the previous pair
+	second := newCert(t, ca, "gateway", x509.Certificate{DNSNames: []string{"gateway"}})
+	writeFile(t, keyFile, second.keyPEM(t))
+	if served, err := handshake(t, cfg); err != nil || served.SerialNumber.Cmp(first.cert.SerialNumber) != 0 {
+		t.Fatalf("mid-rotation: handshake error %v, served %v", err, served)
+	}
+	if len(reloads) != 1 || reloads[0] == nil {
+		t.Fatalf("reloads = %v, want one error", reloads)
+	}
+
+	writeFile(t, certFile, second.certPEM())
+	if served, err := handshake(t, cfg); err != nil || served.SerialNumber.Cmp(second.cert.SerialNumber) != 0 {
+		t.Fatalf("rotated: handshake error %v, served %v", err, served)
+	}
+	if len(reloads) != 2 || reloads[1] != nil {
+		t.Fatalf("reloads = %v, want a successful reload", reloads)
+	}
+	if served, _ := handshake(t, cfg); served.SerialNumber.Cmp(second.cert.SerialNumber) != 0 || len(reloads) != 2 {
+		t.Errorf("unchanged files were reloaded: %v", reloads)
+	}
+}
+
+func TestConfig_ClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, nil, "ca", x509.Certificate{})
+	server := newCert(t, ca, "gateway", x509.Certificate{DNSNames: []string{"gateway"}})
+	writeFile(t, filepath.Join(dir, "tls.crt"), server.certPEM())
+	writeFile(t, filepath.Join(dir, "tls.key"), server.keyPEM(t))
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM())
+
+	cfg, err := Config(Options{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		AllowedSANs:  []string{"spiffe://cluster.local/ns/prod/sa/envoy", "*.clients.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/prod/sa/envoy")
+	clientAuth := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	other := newCert(t, nil, "other-ca", x509.Certificate{})
+	tests := []struct {
+		name   string
+		certs  []tls.Certificate
+		wantOK bool
+	}{
+		{"no certificate", nil, false},
+		{"allowed URI", []tls.Certificate{newCert(t, ca, "envoy", x509.Certificate{URIs: []*url.URL{spiffe}, ExtKeyUsage: clientAuth}).tlsCert(t)}, true},
+		{"allowed DNS wildcard", []tls.Certificate{newCert(t, ca, "batch", x509.Certificate{DNSNames: []string{"batch.clients.example.com"}, ExtKeyUsage: clientAuth}).tlsCert(t)}, true},
+		{"other SAN", []tls.Certificate{newCert(t, ca, "intruder", x509.Certificate{DNSNames: []string{"clients.example.com"}, ExtKeyUsage: clientAuth}).tlsCert(t)}, false},
+		{"server-only usage", []tls.Certificate{newCert(t, ca, "envoy", x509.Certificate{URIs: []*url.URL{spiffe}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}).tlsCert(t)}, false},
+		{"other CA", []tls.Certificate{newCert(t, other, "envoy", x509.Certificate{URIs: []*url.URL{spiffe}, ExtKeyUsage: clientAuth}).tlsCert(t)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handshake(t, cfg, tt.certs...)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("handshake error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestConfig_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, nil, "ca", x509.Certificate{})
+	writeFile(t, filepath.Join(dir, "tls.crt"), ca.certPEM())
+	writeFile(t, filepath.Join(dir, "tls.key"), ca.keyPEM(t))
+	writeFile(t, filepath.Join(dir, "empty.pem"), nil)
+
+	for _, opts := range []Options{
+		{},
+		{CertFile: filepath.Join(dir, "tls.crt")},
+		{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "missing.key")},
+		{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key"), ClientCAFile: filepath.Join(dir, "empty.pem")},
+		{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key"), AllowedSANs: []string{"client"}},
+	} {
+		if _, err := Config(opts); err == nil {
+			t.Errorf("Config(%+v) succeeded, want an error", opts)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ssecheck checks that a /v1/responses event stream follows the
+// ordering rules the OpenAI SDKs rely on when they accumulate a stream.
+//
+// A stream starts with response.created and response.in_progress, numbers
+// its events with contiguous sequence numbers, and ends with a single
+// response.completed, response.incomplete or response.failed. Every output
+// item is announced with response.output_item.added at the next
+// output_index before its content, its content part is added before the
+// deltas and closed after the text is done, and the item is closed with
+// response.output_item.done exactly once.
+package ssecheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Event is a server-sent event of a response stream.
+type Event struct {
+	Name   string                 // SSE event field
+	Fields map[string]interface{} // JSON data
+}
+
+// Type returns the type field of the event data.
+func (e Event) Type() string {
+	typ, _ := e.Fields["type"].(string)
+	return typ
+}
+
+// Read parses the events of an SSE stream until the end of input or a
+// "data: [DONE]" line.
+func Read(r io.Reader) ([]Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var events []Event
+	var name string
+	var data []string
+	flush := func() error {
+		if len(data) == 0 {
+			name = ""
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+			return fmt.Errorf("event %d: invalid data %q: %w", len(events), payload, err)
+		}
+		events = append(events, Event{Name: name, Fields: fields})
+		name, data = "", nil
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return events, err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, such as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			value := strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			if value == "[DONE]" {
+				return events, nil
+			}
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return events, err
+	}
+	return events, flush()
+}
+
+// Stream sends a streamed request to the /responses endpoint under baseURL
+// and reads the events of the reply. The stream field of body is set.
+func Stream(ctx context.Context, client *http.Client, baseURL, apiKey string, body map[string]interface{}) ([]Event, error) {
+	req := make(map[string]interface{}, len(body)+1)
+	for k, v := range body {
+		req[k] = v
+	}
+	req["stream"] = true
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/responses", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("gateway returned status %d: %s", resp.StatusCode, msg)
+	}
+	return Read(resp.Body)
+}
+
+// Validate checks the ordering rules on a complete stream and returns every
+// violation found, in stream order.
+func Validate(events []Event) []error {
+	v := &validator{}
+	if len(events) < 3 {
+		v.errorf("stream has %d events, want at least response.created, response.in_progress and a terminal event", len(events))
+		return v.errs
+	}
+	if resp, ok := events[0].Fields["response"].(map[string]interface{}); ok {
+		v.respID, _ = resp["id"].(string)
+	}
+
+	for i, event := range events {
+		v.check(i, len(events), event)
+	}
+	for _, it := range v.items {
+		if !it.done {
+			v.errorf("item %s (output_index %d) never done", it.id, it.index)
+		}
+	}
+	return v.errs
+}
+
+// item is the state of an announced output item.
+type item struct {
+	id        string
+	index     int
+	partAdded bool
+	textDone  bool
+	partDone  bool
+	args      bool // function_call_arguments.delta seen
+	argsDone  bool
+	done      bool
+}
+
+type validator struct {
+	respID string
+	items  []*item
+	errs   []error
+}
+
+func (v *validator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// item returns the announced item an event refers to by output_index.
+func (v *validator) item(i int, typ string, event Event) *item {
+	index, ok := event.Fields["output_index"].(float64)
+	if !ok || int(index) < 0 || int(index) >= len(v.items) {
+		v.errorf("event %d (%s): output_index %v was not announced", i, typ, event.Fields["output_index"])
+		return &item{}
+	}
+	it := v.items[int(index)]
+	if id, ok := event.Fields["item_id"]; ok && id != it.id {
+		v.errorf("event %d (%s): item_id %v, want %s", i, typ, id, it.id)
+	}
+	if it.done {
+		v.errorf("event %d (%s): item %s is already done", i, typ, it.id)
+	}
+	return it
+}
+
+func (v *validator) check(i, n int, event Event) {
+	typ := event.Type()
+	if event.Name != "" && event.Name != typ {
+		v.errorf("event %d: SSE event %q carries a %q payload", i, event.Name, typ)
+	}
+	if seq, ok := event.Fields["sequence_number"].(float64); !ok || int(seq) != i {
+		v.errorf("event %d (%s): sequence_number %v, want %d", i, typ, event.Fields["sequence_number"], i)
+	}
+	if id, ok := event.Fields["response_id"]; ok && id != v.respID {
+		v.errorf("event %d (%s): response_id %v, want %s", i, typ, id, v.respID)
+	}
+
+	switch {
+	case i == 0 && typ != "response.created":
+		v.errorf("event 0 is %s, want response.created", typ)
+	case i == 1 && typ != "response.in_progress":
+		v.errorf("event 1 is %s, want response.in_progress", typ)
+	case typ == "response.completed" || typ == "response.failed" || typ == "response.incomplete":
+		if i != n-1 {
+			v.errorf("event %d: %s before the end of the stream", i, typ)
+		}
+	case i == n-1:
+		v.errorf("stream ends with %s, want a terminal event", typ)
+	case typ == "error":
+		v.errorf("event %d: error event %v", i, event.Fields["error"])
+	}
+
+	switch typ {
+	case "response.output_item.added":
+		index, _ := event.Fields["output_index"].(float64)
+		if int(index) != len(v.items) {
+			v.errorf("event %d: output_index %v, want %d", i, event.Fields["output_index"], len(v.items))
+		}
+		// Skipped indexes are filled so that the events of the item
+		// are checked against it rather than reported again
+		for len(v.items) < int(index) {
+			v.items = append(v.items, &item{index: len(v.items), done: true})
+		}
+		fields, _ := event.Fields["item"].(map[string]interface{})
+		id, _ := fields["id"].(string)
+		if id == "" {
+			v.errorf("event %d: item without an id", i)
+		}
+		v.items = append(v.items, &item{id: id, index: len(v.items)})
+	case "response.content_part.added":
+		it := v.item(i, typ, event)
+		if it.partAdded {
+			v.errorf("event %d: second content part for item %s", i, it.id)
+		}
+		it.partAdded = true
+	case "response.output_text.delta", "response.refusal.delta":
+		if it := v.item(i, typ, event); !it.partAdded || it.textDone {
+			v.errorf("event %d: %s outside its content part", i, typ)
+		}
+	case "response.output_text.done", "response.refusal.done":
+		it := v.item(i, typ, event)
+		if !it.partAdded {
+			v.errorf("event %d: %s before content_part.added", i, typ)
+		}
+		it.textDone = true
+	case "response.content_part.done":
+		it := v.item(i, typ, event)
+		if !it.textDone {
+			v.errorf("event %d: content_part.done before the text is done", i)
+		}
+		it.partDone = true
+	case "response.function_call_arguments.delta":
+		it := v.item(i, typ, event)
+		if it.argsDone {
+			v.errorf("event %d: arguments delta after function_call_arguments.done", i)
+		}
+		it.args = true
+	case "response.function_call_arguments.done":
+		v.item(i, typ, event).argsDone = true
+	case "response.reasoning.delta", "response.reasoning.done",
+		"response.reasoning_summary.delta", "response.reasoning_summary.done":
+		v.item(i, typ, event)
+	case "response.output_item.done":
+		it := v.item(i, typ, event)
+		if it.partAdded && !it.partDone {
+			v.errorf("event %d: output_item.done before content_part.done", i)
+		}
+		if it.args && !it.argsDone {
+			v.errorf("event %d: output_item.done before function_call_arguments.done", i)
+		}
+		fields, _ := event.Fields["item"].(map[string]interface{})
+		if id, _ := fields["id"].(string); id != it.id {
+			v.errorf("event %d: output_item.done for %s at the index of %s", i, id, it.id)
+		}
+		it.done = true
+	case "response.output_text_annotation.added":
+		// Annotations are known once the output is complete
+		if index, _ := event.Fields["output_index"].(float64); int(index) >= len(v.items) {
+			v.errorf("event %d: output_index %v was not announced", i, event.Fields["output_index"])
+		}
+	}
+}
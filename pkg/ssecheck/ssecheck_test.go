@@ -0,0 +1,200 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package ssecheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sse builds a stream from event payloads, numbering them in order.
+func sse(payloads ...string) string {
+	var b strings.Builder
+	for i, p := range payloads {
+		typ := p[strings.Index(p, `"type":"`)+8:]
+		typ = typ[:strings.Index(typ, `"`)]
+		fmt.Fprintf(&b, "event: %s\ndata: {\"sequence_number\":%d,%s}\n\n", typ, i, p)
+	}
+	return b.String()
+}
+
+const (
+	created    = `"type":"response.created","response":{"id":"resp_1","status":"in_progress"}`
+	inProgress = `"type":"response.in_progress","response":{"id":"resp_1","status":"in_progress"}`
+	completed  = `"type":"response.completed","response":{"id":"resp_1","status":"completed"}`
+	msgAdded   = `"type":"response.output_item.added","output_index":0,"item":{"type":"message","id":"msg_1"}`
+	partAdded  = `"type":"response.content_part.added","output_index":0,"item_id":"msg_1","part":{"type":"output_text","text":""}`
+	textDelta  = `"type":"response.output_text.delta","output_index":0,"item_id":"msg_1","delta":"hi"`
+	textDone   = `"type":"response.output_text.done","output_index":0,"item_id":"msg_1","text":"hi"`
+	partDone   = `"type":"response.content_part.done","output_index":0,"item_id":"msg_1","part":{"type":"output_text","text":"hi"}`
+	msgDone    = `"type":"response.output_item.done","output_index":0,"item":{"type":"message","id":"msg_1"}`
+	fcAdded    = `"type":"response.output_item.added","output_index":1,"item":{"type":"function_call","id":"fc_1"}`
+	argsDelta  = `"type":"response.function_call_arguments.delta","output_index":1,"item_id":"fc_1","response_id":"resp_1","delta":"{}"`
+	argsDone   = `"type":"response.function_call_arguments.done","output_index":1,"item_id":"fc_1","response_id":"resp_1","arguments":"{}"`
+	fcDone     = `"type":"response.output_item.done","output_index":1,"item":{"type":"function_call","id":"fc_1"}`
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string // substrings of the expected violations, in order
+	}{
+		{
+			name: "conformant",
+			stream: sse(created, inProgress, msgAdded, partAdded, textDelta, textDone, partDone, msgDone,
+				fcAdded, argsDelta, argsDone, fcDone, completed),
+		},
+		{
+			name:   "in_progress before created",
+			stream: sse(inProgress, created, completed),
+			want:   []string{"event 0 is response.in_progress", "event 1 is response.created"},
+		},
+		{
+			name:   "delta before content part",
+			stream: sse(created, inProgress, msgAdded, textDelta, partAdded, textDone, partDone, msgDone, completed),
+			want:   []string{"outside its content part"},
+		},
+		{
+			name:   "item never done",
+			stream: sse(created, inProgress, msgAdded, partAdded, textDelta, textDone, partDone, completed),
+			want:   []string{"item msg_1 (output_index 0) never done"},
+		},
+		{
+			name:   "item done twice",
+			stream: sse(created, inProgress, msgAdded, msgDone, msgDone, completed),
+			want:   []string{"item msg_1 is already done"},
+		},
+		{
+			name:   "item done before its part",
+			stream: sse(created, inProgress, msgAdded, partAdded, textDelta, textDone, msgDone, completed),
+			want:   []string{"output_item.done before content_part.done"},
+		},
+		{
+			name:   "function call done before its arguments",
+			stream: sse(created, inProgress, msgAdded, msgDone, fcAdded, argsDelta, fcDone, completed),
+			want:   []string{"output_item.done before function_call_arguments.done"},
+		},
+		{
+			name:   "skipped output_index",
+			stream: sse(created, inProgress, fcAdded, argsDelta, argsDone, fcDone, completed),
+			want:   []string{"output_index 1, want 0"},
+		},
+		{
+			name:   "events after the terminal event",
+			stream: sse(created, inProgress, completed, msgAdded, msgDone),
+			want:   []string{"response.completed before the end of the stream", "stream ends with response.output_item.done"},
+		},
+		{
+			name: "sequence number gap",
+			stream: "event: response.created\ndata: {\"sequence_number\":0," + created + "}\n\n" +
+				"event: response.in_progress\ndata: {\"sequence_number\":2," + inProgress + "}\n\n" +
+				"event: response.completed\ndata: {\"sequence_number\":3," + completed + "}\n\n",
+			want: []string{"event 1 (response.in_progress): sequence_number 2, want 1", "sequence_number 3, want 2"},
+		},
+		{
+			name: "SSE event name differs from the payload",
+			stream: "event: response.created\ndata: {\"sequence_number\":0," + created + "}\n\n" +
+				"event: message\ndata: {\"sequence_number\":1," + inProgress + "}\n\n" +
+				"event: response.completed\ndata: {\"sequence_number\":2," + completed + "}\n\n",
+			want: []string{`SSE event "message" carries a "response.in_progress" payload`},
+		},
+		{
+			name:   "response_id of another response",
+			stream: sse(created, inProgress, msgAdded, msgDone, fcAdded, strings.Replace(argsDelta, "resp_1", "resp_2", 1), argsDone, fcDone, completed),
+			want:   []string{"response_id resp_2, want resp_1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Read(strings.NewReader(tt.stream))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			errs := Validate(events)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %d violations", errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("violation %d = %q, want it to contain %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"event: response.created\ndata: {\"type\":\"response.created\",\n" +
+		"data: \"sequence_number\":0}\n\n" +
+		"data: {\"type\":\"response.in_progress\"}\n\n" +
+		"data: [DONE]\n\n" +
+		"data: {\"type\":\"ignored\"}\n\n"
+	events, err := Read(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Read() = %d events, want 2", len(events))
+	}
+	if events[0].Name != "response.created" || events[0].Type() != "response.created" {
+		t.Errorf("event 0 = %q/%q, want response.created", events[0].Name, events[0].Type())
+	}
+	if events[1].Name != "" || events[1].Type() != "response.in_progress" {
+		t.Errorf("event 1 = %q/%q, want an unnamed response.in_progress", events[1].Name, events[1].Type())
+	}
+
+	if _, err := Read(strings.NewReader("data: not json\n\n")); err == nil {
+		t.Error("Read() accepted invalid JSON data")
+	}
+}
+
+// TestLiveGateway validates the stream of a running gateway. It is skipped
+// unless OPENRESPONSES_BASE_URL is set, as for the integration tests.
+func TestLiveGateway(t *testing.T) {
+	baseURL := os.Getenv("OPENRESPONSES_BASE_URL")
+	if baseURL == "" {
+		t.Skip("OPENRESPONSES_BASE_URL not set")
+	}
+	model := os.Getenv("OPENRESPONSES_MODEL")
+	if model == "" {
+		model = "Qwen/Qwen3-0.6B"
+	}
+
+	requests := map[string]map[string]interface{}{
+		"text": {"model": model, "input": "Say hello in one word."},
+		"function call": {
+			"model":       model,
+			"input":       "What is the weather in Paris?",
+			"tool_choice": "required",
+			"tools": []interface{}{map[string]interface{}{
+				"type": "function", "name": "get_weather",
+				"parameters": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+					"required":   []string{"city"},
+				},
+			}},
+		},
+	}
+	for name, body := range requests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			events, err := Stream(ctx, http.DefaultClient, baseURL, os.Getenv("OPENRESPONSES_API_KEY"), body)
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			for _, err := range Validate(events) {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -5,40 +5,103 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/mcp"
+	"github.com/leseb/openresponses-gw/pkg/pagination"
+	"github.com/leseb/openresponses-gw/pkg/secrets"
 )
 
-// Connector represents a stored MCP connector
+// Connector represents a stored connector: an MCP server, or an HTTP tool
 type Connector struct {
-	ConnectorID   string
-	ConnectorType string
-	URL           string
-	ServerLabel   string
-	CreatedAt     time.Time
-	Metadata      map[string]string
+	ConnectorID    string
+	ConnectorType  string
+	URL            string           // empty for stdio servers
+	Stdio          *mcp.StdioConfig // local command; nil for HTTP servers
+	HTTPTool       *mcp.HTTPTool    // set for "http_tool" connectors, which have no MCP server
+	ServerLabel    string
+	Auth           *mcp.Auth // nil when the server needs no authentication
+	DeniedTools    []string  // tools never exposed to the model, whatever the request allows
+	MaxOutputBytes int       // limit of the tool output given to the model; 0 uses the engine's
+	CreatedAt      time.Time
+	Metadata       map[string]string
+}
+
+// storedConnector is a connector as held by the store: credentials and the
+// stdio environment are kept encrypted and only decrypted when the
+// connector is read.
+type storedConnector struct {
+	connector  Connector // Auth and Stdio.Env are always nil
+	sealedAuth []byte
+	sealedEnv  []byte
 }
 
 // ConnectorsStore is an in-memory connectors store
 type ConnectorsStore struct {
 	mu         sync.RWMutex
-	connectors map[string]*Connector // keyed by ConnectorID
+	connectors map[string]*storedConnector // keyed by ConnectorID
+	cipher     *secrets.Cipher
 }
 
-// NewConnectorsStore creates a new connectors store
+// NewConnectorsStore creates a new connectors store. Credentials are
+// encrypted with a random key generated for the lifetime of the process.
 func NewConnectorsStore() *ConnectorsStore {
 	return &ConnectorsStore{
-		connectors: make(map[string]*Connector),
+		connectors: make(map[string]*storedConnector),
+		cipher:     secrets.NewRandomCipher(),
 	}
 }
 
+// NewConnectorsStoreWithKey creates a new connectors store that encrypts
+// credentials with the given 32-byte key.
+func NewConnectorsStoreWithKey(key []byte) (*ConnectorsStore, error) {
+	c, err := secrets.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ConnectorsStore{
+		connectors: make(map[string]*storedConnector),
+		cipher:     c,
+	}, nil
+}
+
 // CreateConnector creates or overwrites a connector
 func (s *ConnectorsStore) CreateConnector(ctx context.Context, connector *Connector) error {
+	stored := &storedConnector{connector: *connector}
+	stored.connector.Auth = nil
+	if connector.Auth != nil {
+		data, err := json.Marshal(connector.Auth)
+		if err != nil {
+			return fmt.Errorf("marshal connector auth: %w", err)
+		}
+		stored.sealedAuth, err = s.cipher.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("encrypt connector auth: %w", err)
+		}
+	}
+	if connector.Stdio != nil {
+		stdio := *connector.Stdio
+		stdio.Env = nil
+		stored.connector.Stdio = &stdio
+		if len(connector.Stdio.Env) > 0 {
+			data, err := json.Marshal(connector.Stdio.Env)
+			if err != nil {
+				return fmt.Errorf("marshal connector env: %w", err)
+			}
+			stored.sealedEnv, err = s.cipher.Encrypt(data)
+			if err != nil {
+				return fmt.Errorf("encrypt connector env: %w", err)
+			}
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.connectors[connector.ConnectorID] = connector
+	s.connectors[connector.ConnectorID] = stored
 	return nil
 }
 
@@ -47,12 +110,43 @@ func (s *ConnectorsStore) GetConnector(ctx context.Context, connectorID string)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	connector, exists := s.connectors[connectorID]
+	stored, exists := s.connectors[connectorID]
 	if !exists {
 		return nil, fmt.Errorf("connector %s not found", connectorID)
 	}
 
-	return connector, nil
+	return s.open(stored)
+}
+
+// open returns a copy of the stored connector with its credentials and
+// environment decrypted.
+func (s *ConnectorsStore) open(stored *storedConnector) (*Connector, error) {
+	connector := stored.connector
+	if stored.sealedAuth != nil {
+		data, err := s.cipher.Decrypt(stored.sealedAuth)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt connector %s auth: %w", connector.ConnectorID, err)
+		}
+		var auth mcp.Auth
+		if err := json.Unmarshal(data, &auth); err != nil {
+			return nil, fmt.Errorf("unmarshal connector %s auth: %w", connector.ConnectorID, err)
+		}
+		connector.Auth = &auth
+	}
+	if connector.Stdio != nil {
+		stdio := *connector.Stdio
+		if stored.sealedEnv != nil {
+			data, err := s.cipher.Decrypt(stored.sealedEnv)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt connector %s env: %w", connector.ConnectorID, err)
+			}
+			if err := json.Unmarshal(data, &stdio.Env); err != nil {
+				return nil, fmt.Errorf("unmarshal connector %s env: %w", connector.ConnectorID, err)
+			}
+		}
+		connector.Stdio = &stdio
+	}
+	return &connector, nil
 }
 
 // DeleteConnector deletes a connector
@@ -78,40 +172,31 @@ func (s *ConnectorsStore) ListConnectorsPaginated(ctx context.Context, after, be
 		limit = 50
 	}
 
-	// Collect all connectors
-	var allConnectors []*Connector
-	for _, connector := range s.connectors {
-		allConnectors = append(allConnectors, connector)
+	// Page over the sealed connectors and only decrypt the selected ones
+	stored := make([]*storedConnector, 0, len(s.connectors))
+	for _, c := range s.connectors {
+		stored = append(stored, c)
 	}
+	page, hasMore := pagination.Slice(stored, storedConnectorKey, after, before, limit, order)
 
-	// Apply cursor-based pagination
-	var filtered []*Connector
-	foundAfter := after == ""
-
-	for _, connector := range allConnectors {
-		// Handle after cursor
-		if after != "" && !foundAfter {
-			if connector.ConnectorID == after {
-				foundAfter = true
-			}
-			continue
-		}
-
-		// Handle before cursor
-		if before != "" && connector.ConnectorID == before {
-			break
-		}
-
-		filtered = append(filtered, connector)
-
-		// Limit results
-		if len(filtered) >= limit {
-			break
+	connectors := make([]*Connector, 0, len(page))
+	for _, c := range page {
+		connector, err := s.open(c)
+		if err != nil {
+			return nil, false, err
 		}
+		connectors = append(connectors, connector)
 	}
+	return connectors, hasMore, nil
+}
 
-	// Check if there are more results
-	hasMore := len(allConnectors) > len(filtered) && len(filtered) == limit
+// CountConnectors returns the number of connectors
+func (s *ConnectorsStore) CountConnectors(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.connectors), nil
+}
 
-	return filtered, hasMore, nil
+func storedConnectorKey(c *storedConnector) pagination.Key {
+	return pagination.Key{CreatedAt: c.connector.CreatedAt, ID: c.connector.ConnectorID}
 }
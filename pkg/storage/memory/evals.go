@@ -0,0 +1,105 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EvalRun is a comparison of two model configurations, A and B, over a
+// set of prompts.
+type EvalRun struct {
+	ID          string
+	Name        string
+	Status      string          // "in_progress", "completed"
+	FileID      string          // file the prompts were read from, if any
+	A           json.RawMessage // request parameters of configuration A
+	B           json.RawMessage // request parameters of configuration B
+	Results     []EvalResult    // one per prompt, in prompt order
+	Metadata    map[string]string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// EvalResult holds the outputs of both configurations for one prompt.
+type EvalResult struct {
+	PromptID string
+	Input    json.RawMessage
+	A        *EvalOutput // nil until configuration A has run
+	B        *EvalOutput // nil until configuration B has run
+}
+
+// EvalOutput is the output of one configuration for one prompt.
+type EvalOutput struct {
+	ResponseID   string
+	Model        string
+	Status       string
+	OutputText   string
+	InputTokens  int
+	OutputTokens int
+	Latency      time.Duration
+	Error        string
+}
+
+// clone returns a copy of run that shares no mutable state with it.
+func (run *EvalRun) clone() *EvalRun {
+	c := *run
+	c.Results = slices.Clone(run.Results)
+	c.Metadata = maps.Clone(run.Metadata)
+	return &c
+}
+
+// EvalsStore is an in-memory store of eval runs. Runs are copied in and
+// out, so that they can be updated while being read.
+type EvalsStore struct {
+	mu   sync.RWMutex
+	runs map[string]*EvalRun
+}
+
+// NewEvalsStore creates a new evals store
+func NewEvalsStore() *EvalsStore {
+	return &EvalsStore{runs: make(map[string]*EvalRun)}
+}
+
+// CreateEvalRun stores a new eval run
+func (s *EvalsStore) CreateEvalRun(ctx context.Context, run *EvalRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.runs[run.ID]; exists {
+		return fmt.Errorf("eval run %s already exists", run.ID)
+	}
+	s.runs[run.ID] = run.clone()
+	return nil
+}
+
+// GetEvalRun retrieves an eval run by ID
+func (s *EvalsStore) GetEvalRun(ctx context.Context, id string) (*EvalRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	run, exists := s.runs[id]
+	if !exists {
+		return nil, fmt.Errorf("eval run %s not found", id)
+	}
+	return run.clone(), nil
+}
+
+// UpdateEvalRun replaces a stored eval run
+func (s *EvalsStore) UpdateEvalRun(ctx context.Context, run *EvalRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.runs[run.ID]; !exists {
+		return fmt.Errorf("eval run %s not found", run.ID)
+	}
+	s.runs[run.ID] = run.clone()
+	return nil
+}
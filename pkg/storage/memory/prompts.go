@@ -11,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/pagination"
 )
 
 // VersionMismatchError indicates an optimistic concurrency conflict
@@ -214,45 +216,30 @@ func (s *PromptsStore) ListPromptsPaginated(ctx context.Context, after, before s
 		limit = 50
 	}
 
-	// Collect default version of each prompt
-	var allPrompts []*Prompt
-	for promptID, versionMap := range s.versions {
-		defVer := s.defaultVersion[promptID]
-		if prompt, ok := versionMap[defVer]; ok {
-			allPrompts = append(allPrompts, prompt)
-		}
-	}
-
-	// Apply cursor-based pagination
-	var filtered []*Prompt
-	foundAfter := after == ""
-
-	for _, prompt := range allPrompts {
-		// Handle after cursor
-		if after != "" && !foundAfter {
-			if prompt.ID == after {
-				foundAfter = true
-			}
-			continue
-		}
-
-		// Handle before cursor
-		if before != "" && prompt.ID == before {
-			break
-		}
+	page, hasMore := pagination.Slice(s.defaultPromptsLocked(), promptKey, after, before, limit, order)
+	return page, hasMore, nil
+}
 
-		filtered = append(filtered, prompt)
+// CountPrompts returns the number of prompts
+func (s *PromptsStore) CountPrompts(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.defaultPromptsLocked()), nil
+}
 
-		// Limit results
-		if len(filtered) >= limit {
-			break
+// defaultPromptsLocked returns the default version of each prompt
+func (s *PromptsStore) defaultPromptsLocked() []*Prompt {
+	prompts := make([]*Prompt, 0, len(s.versions))
+	for promptID, versionMap := range s.versions {
+		if prompt, ok := versionMap[s.defaultVersion[promptID]]; ok {
+			prompts = append(prompts, prompt)
 		}
 	}
+	return prompts
+}
 
-	// Check if there are more results
-	hasMore := len(allPrompts) > len(filtered) && len(filtered) == limit
-
-	return filtered, hasMore, nil
+func promptKey(p *Prompt) pagination.Key {
+	return pagination.Key{CreatedAt: p.CreatedAt, ID: p.ID}
 }
 
 // ListPromptVersions returns all versions of a prompt, sorted by version ascending
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/pagination"
 )
 
 // VectorStore represents a stored vector store
@@ -22,7 +24,26 @@ type VectorStore struct {
 	ExpiresAfter *VectorStoreExpiration
 	LastActiveAt *time.Time
 	Metadata     map[string]string
+	Tenant       string   // tenant that created the vector store, if any
 	FileIDs      []string // Track associated files
+
+	Embedder            string // name of the embedder the store is indexed with
+	EmbeddingModel      string
+	EmbeddingDimensions int
+	Reindex             *VectorStoreReindex // latest re-index, if any
+}
+
+// VectorStoreReindex tracks the progress of re-indexing a vector store
+type VectorStoreReindex struct {
+	Status         string // "in_progress", "completed", "failed"
+	Embedder       string
+	EmbeddingModel string
+	FilesTotal     int
+	FilesCompleted int
+	FilesFailed    int
+	StartedAt      time.Time
+	CompletedAt    *time.Time
+	LastError      *VectorStoreFileError
 }
 
 // VectorStoreFileCounts represents file count statistics
@@ -147,6 +168,24 @@ func (s *VectorStoresStore) DeleteVectorStore(ctx context.Context, vsID string)
 	return nil
 }
 
+// ListAll returns every vector store and every vector store file as a
+// consistent snapshot. It is meant for maintenance jobs such as garbage
+// collection that need to cross-reference the whole store.
+func (s *VectorStoresStore) ListAll(ctx context.Context) ([]*VectorStore, []*VectorStoreFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stores := make([]*VectorStore, 0, len(s.vectorStores))
+	for _, vs := range s.vectorStores {
+		stores = append(stores, vs)
+	}
+	files := make([]*VectorStoreFile, 0, len(s.vsFiles))
+	for _, f := range s.vsFiles {
+		files = append(files, f)
+	}
+	return stores, files, nil
+}
+
 // ListVectorStoresPaginated lists vector stores with pagination
 func (s *VectorStoresStore) ListVectorStoresPaginated(ctx context.Context, after, before string, limit int, order string) ([]*VectorStore, bool, error) {
 	s.mu.RLock()
@@ -157,42 +196,23 @@ func (s *VectorStoresStore) ListVectorStoresPaginated(ctx context.Context, after
 		limit = 20
 	}
 
-	// Collect all vector stores
-	var allStores []*VectorStore
+	stores := make([]*VectorStore, 0, len(s.vectorStores))
 	for _, vs := range s.vectorStores {
-		allStores = append(allStores, vs)
-	}
-
-	// Apply cursor-based pagination
-	var filtered []*VectorStore
-	foundAfter := after == ""
-
-	for _, vs := range allStores {
-		// Handle after cursor
-		if after != "" && !foundAfter {
-			if vs.ID == after {
-				foundAfter = true
-			}
-			continue
-		}
-
-		// Handle before cursor
-		if before != "" && vs.ID == before {
-			break
-		}
-
-		filtered = append(filtered, vs)
-
-		// Limit results
-		if len(filtered) >= limit {
-			break
-		}
+		stores = append(stores, vs)
 	}
+	page, hasMore := pagination.Slice(stores, vectorStoreKey, after, before, limit, order)
+	return page, hasMore, nil
+}
 
-	// Check if there are more results
-	hasMore := len(allStores) > len(filtered) && len(filtered) == limit
+// CountVectorStores returns the number of vector stores
+func (s *VectorStoresStore) CountVectorStores(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.vectorStores), nil
+}
 
-	return filtered, hasMore, nil
+func vectorStoreKey(vs *VectorStore) pagination.Key {
+	return pagination.Key{CreatedAt: vs.CreatedAt, ID: vs.ID}
 }
 
 // AddVectorStoreFile adds a file to a vector store
@@ -324,48 +344,38 @@ func (s *VectorStoresStore) ListVectorStoreFilesPaginated(ctx context.Context, v
 		limit = 20
 	}
 
-	// Collect all files for this vector store
-	var allFiles []*VectorStoreFile
-	for _, vsFile := range s.vsFiles {
-		if vsFile.VectorStoreID == vsID {
-			// Apply filter if specified
-			if filter != "" && vsFile.Status != filter {
-				continue
-			}
-			allFiles = append(allFiles, vsFile)
-		}
-	}
+	page, hasMore := pagination.Slice(s.vectorStoreFilesLocked(vsID, filter), vectorStoreFileKey, after, before, limit, order)
+	return page, hasMore, nil
+}
 
-	// Apply cursor-based pagination
-	var filtered []*VectorStoreFile
-	foundAfter := after == ""
+// CountVectorStoreFiles returns the number of files in a vector store,
+// optionally only those with the given status
+func (s *VectorStoresStore) CountVectorStoreFiles(ctx context.Context, vsID, filter string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	for _, vsFile := range allFiles {
-		// Handle after cursor
-		if after != "" && !foundAfter {
-			if vsFile.FileID == after {
-				foundAfter = true
-			}
-			continue
-		}
+	if _, exists := s.vectorStores[vsID]; !exists {
+		return 0, fmt.Errorf("vector store %s not found", vsID)
+	}
+	return len(s.vectorStoreFilesLocked(vsID, filter)), nil
+}
 
-		// Handle before cursor
-		if before != "" && vsFile.FileID == before {
-			break
+func (s *VectorStoresStore) vectorStoreFilesLocked(vsID, filter string) []*VectorStoreFile {
+	var files []*VectorStoreFile
+	for _, vsFile := range s.vsFiles {
+		if vsFile.VectorStoreID != vsID {
+			continue
 		}
-
-		filtered = append(filtered, vsFile)
-
-		// Limit results
-		if len(filtered) >= limit {
-			break
+		if filter != "" && vsFile.Status != filter {
+			continue
 		}
+		files = append(files, vsFile)
 	}
+	return files
+}
 
-	// Check if there are more results
-	hasMore := len(allFiles) > len(filtered) && len(filtered) == limit
-
-	return filtered, hasMore, nil
+func vectorStoreFileKey(f *VectorStoreFile) pagination.Key {
+	return pagination.Key{CreatedAt: f.CreatedAt, ID: f.FileID}
 }
 
 // VectorStoreFileBatch represents a batch of files being processed
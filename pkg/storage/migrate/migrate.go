@@ -0,0 +1,144 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package migrate applies the versioned schema migrations of the SQL session
+// stores. Each database records the migrations applied to it in a
+// schema_version table, so on upgrade only the newer ones run, in order and
+// once each.
+//
+// Migrations are append-only: a released migration is never edited, since
+// databases that already applied it would not see the change. New columns,
+// indexes and backfills are added as a new migration at the end of the
+// store's list.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Migration is one step of a store's schema.
+type Migration struct {
+	Version     int    // greater than the version of the previous migration
+	Description string // what the migration changes, for errors
+
+	// Statements are run in order.
+	Statements []string
+	// Func, if set, runs after Statements, for changes that depend on the
+	// current schema or data.
+	Func func(ctx context.Context, tx *sql.Tx) error
+}
+
+// Run applies the migrations newer than the version of db. Each migration
+// runs in its own transaction with the record of its version, so a failed
+// migration leaves the database at the previous version.
+//
+// lock, if not empty, is run first in each transaction to keep processes
+// sharing the database from applying the same migration concurrently, e.g.
+// a PostgreSQL transaction-level advisory lock. The version is read again
+// once it is held.
+func Run(ctx context.Context, db *sql.DB, lock string, migrations []Migration) error {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return fmt.Errorf("migrate: version %d follows version %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+
+	if err := createVersionTable(ctx, db, lock); err != nil {
+		return fmt.Errorf("migrate: create schema_version: %w", err)
+	}
+
+	current, err := Version(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := apply(ctx, db, lock, m); err != nil {
+			return fmt.Errorf("migrate: version %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return nil
+}
+
+// createVersionTable creates the schema_version table, holding the lock so
+// that concurrent creations do not conflict.
+func createVersionTable(ctx context.Context, db *sql.DB, lock string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if lock != "" {
+		if _, err := tx.ExecContext(ctx, lock); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// apply runs m and records its version, unless another process applied it
+// first.
+func apply(ctx context.Context, db *sql.DB, lock string, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if lock != "" {
+		if _, err := tx.ExecContext(ctx, lock); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+		current, err := Version(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if m.Version <= current {
+			return nil
+		}
+	}
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if m.Func != nil {
+		if err := m.Func(ctx, tx); err != nil {
+			return err
+		}
+	}
+	// The version is an int, so it is formatted rather than bound to avoid
+	// the placeholder syntax differing between databases.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO schema_version (version, applied_at) VALUES (%d, CURRENT_TIMESTAMP)`, m.Version)); err != nil {
+		return fmt.Errorf("record version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Querier is implemented by both *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Version returns the version of the last migration applied to db, or 0
+// if none was.
+func Version(ctx context.Context, db Querier) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("migrate: read schema version: %w", err)
+	}
+	return version, nil
+}
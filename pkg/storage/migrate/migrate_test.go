@@ -0,0 +1,79 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	var funcRuns int
+	migrations := []Migration{
+		{Version: 1, Description: "create", Statements: []string{`CREATE TABLE items (id TEXT PRIMARY KEY)`}},
+		{Version: 2, Description: "seed", Func: func(ctx context.Context, tx *sql.Tx) error {
+			funcRuns++
+			_, err := tx.ExecContext(ctx, `INSERT INTO items (id) VALUES ('a')`)
+			return err
+		}},
+	}
+	if err := Run(ctx, db, "", migrations); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// Applied migrations do not run again
+	if err := Run(ctx, db, "", migrations); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if funcRuns != 1 {
+		t.Errorf("Func ran %d times, want 1", funcRuns)
+	}
+	if v, err := Version(ctx, db); err != nil || v != 2 {
+		t.Errorf("Version = %d, %v; want 2", v, err)
+	}
+
+	// A failed migration is rolled back and leaves the previous version
+	migrations = append(migrations,
+		Migration{Version: 3, Description: "add column", Statements: []string{`ALTER TABLE items ADD COLUMN name TEXT`}},
+		Migration{Version: 4, Description: "broken", Statements: []string{`CREATE INDEX idx_name ON items(name)`}, Func: func(context.Context, *sql.Tx) error {
+			return errors.New("boom")
+		}},
+	)
+	if err := Run(ctx, db, "", migrations); err == nil {
+		t.Fatal("expected error from the broken migration")
+	}
+	if v, _ := Version(ctx, db); v != 3 {
+		t.Errorf("Version after failure = %d, want 3", v)
+	}
+	var indexes int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name='idx_name'`).Scan(&indexes)
+	if indexes != 0 {
+		t.Error("statements of the failed migration were not rolled back")
+	}
+}
+
+func TestRun_Order(t *testing.T) {
+	migrations := []Migration{{Version: 2}, {Version: 1}}
+	if err := Run(context.Background(), openDB(t), "", migrations); err == nil {
+		t.Error("expected error for out-of-order versions")
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/storage/storagetest"
+)
+
+func BenchmarkStore(b *testing.B) {
+	storagetest.RunBenchmarks(b, func(b testing.TB) state.SessionStore {
+		return newTestStore(b)
+	})
+}
@@ -8,22 +8,38 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/secrets"
+	"github.com/leseb/openresponses-gw/pkg/storage/migrate"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func init() {
 	state.Providers.Register("postgres", func(_ context.Context, params map[string]string) (state.SessionStore, error) {
-		return New(params["dsn"])
+		store, err := New(params["dsn"])
+		if err != nil || params["encryption_keys"] == "" {
+			return store, err
+		}
+		keys, err := secrets.ParseKeyring(params["encryption_keys"])
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store.SetKeyring(keys)
+		return store, nil
 	})
 }
 
 // Store is a PostgreSQL-backed implementation of SessionStore.
 type Store struct {
-	db *sql.DB
+	db   *sql.DB
+	keys *secrets.Keyring // nil stores payloads in plain JSON
 }
 
 // New creates a new PostgreSQL store. The dsn is a PostgreSQL connection string,
@@ -40,19 +56,156 @@ func New(dsn string) (*Store, error) {
 	}
 
 	s := &Store{db: db}
-	if err := s.createTables(); err != nil {
+	if err := migrate.Run(context.Background(), db, migrationLock, migrations); err != nil {
 		db.Close()
 		return nil, err
 	}
 	return s, nil
 }
 
+// SetKeyring encrypts the payloads written from now on with the primary key
+// of keys. Payloads written in plain JSON or with the other keys stay
+// readable; ReencryptPayloads rewrites them. Call it before using the store.
+func (s *Store) SetKeyring(keys *secrets.Keyring) {
+	s.keys = keys
+}
+
 // Close closes the underlying database connection.
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-func (s *Store) createTables() error {
+// Ping checks the database connection.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// migrations is the schema of the store; see package migrate. Append new
+// migrations at the end.
+var migrations = []migrate.Migration{
+	{
+		Version:     1,
+		Description: "initial schema",
+		Func:        createTables,
+	},
+	{
+		Version:     2,
+		Description: "index responses by status",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_responses_status ON responses(status)`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "index the expiry of sessions and conversations",
+		Statements: []string{
+			`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_conversations_updated ON conversations(updated_at)`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "record the API key of responses",
+		Statements: []string{
+			`ALTER TABLE responses ADD COLUMN api_key TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_responses_api_key ON responses(api_key)`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "lock conversations during a turn",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS conversation_locks (
+				conversation_id TEXT PRIMARY KEY,
+				holder TEXT NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "record the response a conversation was forked from",
+		Statements: []string{
+			`ALTER TABLE conversations ADD COLUMN forked_from TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "hold response events until they are published",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS event_outbox (
+				seq BIGSERIAL PRIMARY KEY,
+				id TEXT NOT NULL UNIQUE,
+				type TEXT NOT NULL,
+				response_id TEXT NOT NULL,
+				data TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				claimed_until TIMESTAMPTZ NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_event_outbox_claimed ON event_outbox(claimed_until)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "record the request ID of responses",
+		Statements: []string{
+			`ALTER TABLE responses ADD COLUMN request_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_responses_request_id ON responses(request_id)`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "record the backend calls and tool results of responses",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS response_recordings (
+				response_id TEXT PRIMARY KEY,
+				recording TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "keep the results of shadow traffic",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS shadow_results (
+				id TEXT PRIMARY KEY,
+				created_at TIMESTAMPTZ NOT NULL,
+				response_id TEXT NOT NULL,
+				model TEXT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_shadow_results_created ON shadow_results(created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_shadow_results_response ON shadow_results(response_id)`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "keep the provenance of responses",
+		Statements: []string{
+			`ALTER TABLE responses ADD COLUMN provenance TEXT NOT NULL DEFAULT 'null'`,
+		},
+	},
+}
+
+// migrationLock keeps replicas starting together from migrating the same
+// database at once. The key is arbitrary but must not change.
+const migrationLock = `SELECT pg_advisory_xact_lock(7164203548)`
+
+// createTables creates the tables, or brings up to date tables created
+// before migrations were versioned.
+func createTables(ctx context.Context, tx *sql.Tx) error {
+	// The model and metadata of a request have their own columns, so that
+	// listings can filter on them when requests are encrypted. Rows written
+	// before are filled in from their request.
+	var hasModel bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'responses' AND column_name = 'model')`).Scan(&hasModel); err != nil {
+		return fmt.Errorf("postgres create tables: %w", err)
+	}
+
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS sessions (
 			id TEXT PRIMARY KEY,
@@ -91,17 +244,59 @@ func (s *Store) createTables() error {
 			error TEXT NOT NULL DEFAULT 'null',
 			usage TEXT NOT NULL DEFAULT 'null',
 			messages TEXT NOT NULL DEFAULT '[]',
+			messages_base TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMPTZ NOT NULL,
-			completed_at TIMESTAMPTZ
+			completed_at TIMESTAMPTZ,
+			external_id TEXT NOT NULL DEFAULT ''
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_responses_created ON responses(created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_responses_conversation ON responses(conversation_id)`,
+		// Migrations for tables created by earlier versions
+		`ALTER TABLE responses ADD COLUMN IF NOT EXISTS external_id TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_responses_external_id ON responses(external_id)`,
+		`ALTER TABLE responses ADD COLUMN IF NOT EXISTS messages_base TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_responses_messages_base ON responses(messages_base)`,
+		`ALTER TABLE responses ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_responses_tenant ON responses(tenant)`,
+		`ALTER TABLE conversations ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_tenant ON conversations(tenant)`,
+		`ALTER TABLE responses ADD COLUMN IF NOT EXISTS model TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE responses ADD COLUMN IF NOT EXISTS metadata TEXT NOT NULL DEFAULT '{}'`,
+		`CREATE INDEX IF NOT EXISTS idx_responses_model ON responses(model)`,
+		`CREATE TABLE IF NOT EXISTS audit_events (
+			id TEXT PRIMARY KEY,
+			created_at TIMESTAMPTZ NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			tenant TEXT NOT NULL DEFAULT '',
+			remote_addr TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL DEFAULT '',
+			path TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL DEFAULT '',
+			resource_type TEXT NOT NULL DEFAULT '',
+			resource_id TEXT NOT NULL DEFAULT '',
+			status INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events(created_at)`,
+		`CREATE TABLE IF NOT EXISTS response_cache (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_response_cache_expires ON response_cache(expires_at)`,
 	}
 	for _, stmt := range stmts {
-		if _, err := s.db.Exec(stmt); err != nil {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
 			return fmt.Errorf("postgres create tables: %w", err)
 		}
 	}
+	if !hasModel {
+		if _, err := tx.ExecContext(ctx, `UPDATE responses SET
+			model = COALESCE(request::jsonb ->> 'model', ''),
+			metadata = COALESCE(request::jsonb -> 'metadata', '{}'::jsonb)::text
+			WHERE request <> 'null'`); err != nil {
+			return fmt.Errorf("postgres backfill responses.model: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -115,6 +310,21 @@ func marshalJSON(v interface{}) (string, error) {
 	return string(b), nil
 }
 
+// requestIndex returns the model and the metadata JSON of a request, which
+// are stored in their own columns.
+func requestIndex(requestJSON string) (model, metadata string) {
+	var req struct {
+		Model    string          `json:"model"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	_ = json.Unmarshal([]byte(requestJSON), &req)
+	metadata = string(req.Metadata)
+	if metadata == "" || metadata == "null" {
+		metadata = "{}"
+	}
+	return req.Model, metadata
+}
+
 func unmarshalMapStringInterface(data string) (map[string]interface{}, error) {
 	var m map[string]interface{}
 	if err := json.Unmarshal([]byte(data), &m); err != nil {
@@ -239,9 +449,9 @@ func (s *Store) CreateConversation(ctx context.Context, conv *state.Conversation
 	}
 
 	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO conversations (id, session_id, metadata, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5)`,
-		conv.ID, conv.SessionID, metaJSON, conv.CreatedAt, conv.UpdatedAt,
+		`INSERT INTO conversations (id, session_id, metadata, created_at, updated_at, tenant, forked_from)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		conv.ID, conv.SessionID, metaJSON, conv.CreatedAt, conv.UpdatedAt, conv.Tenant, conv.ForkedFrom,
 	)
 	if err != nil {
 		return fmt.Errorf("conversation %s already exists", conv.ID)
@@ -251,14 +461,14 @@ func (s *Store) CreateConversation(ctx context.Context, conv *state.Conversation
 
 func (s *Store) GetConversation(ctx context.Context, conversationID string) (*state.Conversation, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, session_id, metadata, created_at, updated_at
+		`SELECT id, session_id, metadata, created_at, updated_at, tenant, forked_from
 		 FROM conversations WHERE id = $1`, conversationID)
 
 	var (
 		conv    state.Conversation
 		metaStr string
 	)
-	err := row.Scan(&conv.ID, &conv.SessionID, &metaStr, &conv.CreatedAt, &conv.UpdatedAt)
+	err := row.Scan(&conv.ID, &conv.SessionID, &metaStr, &conv.CreatedAt, &conv.UpdatedAt, &conv.Tenant, &conv.ForkedFrom)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("conversation %s not found", conversationID)
 	}
@@ -286,32 +496,41 @@ func (s *Store) SaveConversation(ctx context.Context, conv *state.Conversation)
 		return fmt.Errorf("marshal metadata: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO conversations (id, session_id, metadata, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5)
-		 ON CONFLICT (id) DO UPDATE SET session_id=$2, metadata=$3, created_at=$4, updated_at=$5`,
-		conv.ID, conv.SessionID, metaJSON, conv.CreatedAt, conv.UpdatedAt,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save conversation: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO conversations (id, session_id, metadata, created_at, updated_at, tenant, forked_from)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (id) DO UPDATE SET session_id=$2, metadata=$3, created_at=$4, updated_at=$5, tenant=$6, forked_from=$7`,
+		conv.ID, conv.SessionID, metaJSON, conv.CreatedAt, conv.UpdatedAt, conv.Tenant, conv.ForkedFrom,
 	)
 	if err != nil {
 		return fmt.Errorf("save conversation: %w", err)
 	}
 
 	// Sync messages: delete existing then re-insert to handle updates
-	if _, err := s.db.ExecContext(ctx, `DELETE FROM messages WHERE conversation_id=$1`, conv.ID); err != nil {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE conversation_id=$1`, conv.ID); err != nil {
 		return fmt.Errorf("delete old messages: %w", err)
 	}
 	for i, msg := range conv.Messages {
-		if err := s.insertMessage(ctx, conv.ID, msg, i); err != nil {
+		if err := s.insertMessage(ctx, tx, conv.ID, msg, i); err != nil {
 			return err
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save conversation: %w", err)
+	}
 	return nil
 }
 
 func (s *Store) ListConversations(ctx context.Context, sessionID string) ([]*state.Conversation, error) {
 	convs, err := s.scanConversationRows(ctx,
-		`SELECT id, session_id, metadata, created_at, updated_at
+		`SELECT id, session_id, metadata, created_at, updated_at, tenant, forked_from
 		 FROM conversations WHERE session_id=$1`, sessionID)
 	if err != nil {
 		return nil, err
@@ -333,30 +552,14 @@ func (s *Store) ListConversationsPaginated(ctx context.Context, after, before st
 		order = "desc"
 	}
 
-	query := `SELECT id, session_id, metadata, created_at, updated_at FROM conversations`
-	var args []interface{}
-	var where []string
-	argIdx := 1
-
-	if after != "" {
-		where = append(where, fmt.Sprintf("created_at > (SELECT created_at FROM conversations WHERE id = $%d)", argIdx))
-		args = append(args, after)
-		argIdx++
-	}
-	if before != "" {
-		where = append(where, fmt.Sprintf("created_at < (SELECT created_at FROM conversations WHERE id = $%d)", argIdx))
-		args = append(args, before)
-		argIdx++
-	}
-	if len(where) > 0 {
-		query += " WHERE " + where[0]
-		for _, w := range where[1:] {
-			query += " AND " + w
-		}
+	query := `SELECT id, session_id, metadata, created_at, updated_at, tenant, forked_from FROM conversations`
+	cursor := newCursorQuery("conversations", after, before, order, 1)
+	if len(cursor.where) > 0 {
+		query += " WHERE " + strings.Join(cursor.where, " AND ")
 	}
 
-	query += fmt.Sprintf(" ORDER BY created_at %s LIMIT $%d", order, argIdx)
-	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %[1]s LIMIT $%d", cursor.order, len(cursor.args)+1)
+	args := append(cursor.args, limit+1)
 
 	convs, err := s.scanConversationRows(ctx, query, args...)
 	if err != nil {
@@ -373,9 +576,20 @@ func (s *Store) ListConversationsPaginated(ctx context.Context, after, before st
 	if hasMore {
 		convs = convs[:limit]
 	}
+	if cursor.reversed {
+		slices.Reverse(convs)
+	}
 	return convs, hasMore, nil
 }
 
+func (s *Store) CountConversations(ctx context.Context) (int, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM conversations`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count conversations: %w", err)
+	}
+	return n, nil
+}
+
 func (s *Store) DeleteConversation(ctx context.Context, conversationID string) error {
 	res, err := s.db.ExecContext(ctx, `DELETE FROM conversations WHERE id=$1`, conversationID)
 	if err != nil {
@@ -391,31 +605,7 @@ func (s *Store) DeleteConversation(ctx context.Context, conversationID string) e
 }
 
 func (s *Store) AddConversationItems(ctx context.Context, conversationID string, items []state.Message) error {
-	// Verify conversation exists
-	var exists int
-	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM conversations WHERE id=$1`, conversationID).Scan(&exists)
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("conversation %s not found", conversationID)
-	}
-	if err != nil {
-		return fmt.Errorf("check conversation: %w", err)
-	}
-
-	// Get current max position
-	var maxPos int
-	err = s.db.QueryRowContext(ctx,
-		`SELECT COALESCE(MAX(position), -1) FROM messages WHERE conversation_id=$1`,
-		conversationID).Scan(&maxPos)
-	if err != nil {
-		return fmt.Errorf("get max position: %w", err)
-	}
-
-	for i, msg := range items {
-		if err := s.insertMessage(ctx, conversationID, msg, maxPos+1+i); err != nil {
-			return err
-		}
-	}
-	return nil
+	return s.appendItems(ctx, s.db, conversationID, items)
 }
 
 func (s *Store) ListConversationItems(ctx context.Context, conversationID string, after, before string, limit int, order string) ([]state.Message, bool, error) {
@@ -466,7 +656,7 @@ func (s *Store) ListConversationItems(ctx context.Context, conversationID string
 		if err := rows.Scan(&msg.ID, &msg.Role, &contentStr, &metaStr, &msg.CreatedAt); err != nil {
 			return nil, false, fmt.Errorf("scan message: %w", err)
 		}
-		msg.Content, err = unmarshalInterface(contentStr)
+		msg.Content, err = s.openInterface(contentStr)
 		if err != nil {
 			return nil, false, fmt.Errorf("unmarshal content: %w", err)
 		}
@@ -492,32 +682,122 @@ func (s *Store) ListConversationItems(ctx context.Context, conversationID string
 func (s *Store) GetResponse(ctx context.Context, responseID string) (*state.Response, error) {
 	row := s.db.QueryRowContext(ctx,
 		`SELECT id, conversation_id, previous_response_id, request, output, status,
-		        error, usage, messages, created_at, completed_at
+		        error, usage, messages, messages_base, created_at, completed_at, external_id, tenant, api_key, request_id, model, provenance
 		 FROM responses WHERE id = $1`, responseID)
 
-	return s.scanResponse(row)
+	resp, err := s.scanResponse(row)
+	if err != nil {
+		return nil, err
+	}
+	if resp.MessagesBase != "" {
+		history, _, err := s.loadHistory(ctx, resp.MessagesBase)
+		if err != nil {
+			return nil, fmt.Errorf("load history of response %s: %w", responseID, err)
+		}
+		resp.Messages = append(history, resp.Messages...)
+		resp.MessagesBase = ""
+	}
+	return resp, nil
 }
 
 func (s *Store) SaveResponse(ctx context.Context, resp *state.Response) error {
+	if len(resp.Events) > 0 {
+		return s.SaveResponseWithItems(ctx, resp, nil)
+	}
+	args, err := s.responseArgs(ctx, resp)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, saveResponseQuery, args...); err != nil {
+		return fmt.Errorf("save response: %w", err)
+	}
+	return nil
+}
+
+// SaveResponseWithItems saves a response and appends items to its
+// conversation and its events to the outbox in one transaction, so either
+// all are stored or none is.
+func (s *Store) SaveResponseWithItems(ctx context.Context, resp *state.Response, items []state.Message) error {
+	if resp.ConversationID == "" {
+		items = nil
+	}
+	if len(items) == 0 && len(resp.Events) == 0 {
+		return s.SaveResponse(ctx, resp)
+	}
+	args, err := s.responseArgs(ctx, resp)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save response: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, saveResponseQuery, args...); err != nil {
+		return fmt.Errorf("save response: %w", err)
+	}
+	if len(items) > 0 {
+		if err := s.appendItems(ctx, tx, resp.ConversationID, items); err != nil {
+			return err
+		}
+	}
+	for _, event := range resp.Events {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO event_outbox (id, type, response_id, data, created_at, claimed_until) VALUES ($1, $2, $3, $4, $5, $6)`,
+			event.ID, event.Type, event.ResponseID, string(event.Data), event.CreatedAt.UTC(), time.Time{},
+		); err != nil {
+			return fmt.Errorf("append outbox event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save response: %w", err)
+	}
+	return nil
+}
+
+// saveResponseQuery inserts or replaces a response with the arguments
+// returned by responseArgs.
+const saveResponseQuery = `INSERT INTO responses
+	(id, conversation_id, previous_response_id, request, output, status, error, usage, messages, messages_base, created_at, completed_at, external_id, tenant, model, metadata, api_key, request_id, provenance)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+	ON CONFLICT (id) DO UPDATE SET
+	  conversation_id=$2, previous_response_id=$3, request=$4, output=$5,
+	  status=$6, error=$7, usage=$8, messages=$9, messages_base=$10, created_at=$11,
+	  completed_at=$12, external_id=$13, tenant=$14, model=$15, metadata=$16, api_key=$17, request_id=$18, provenance=$19`
+
+// responseArgs encodes resp as the arguments of saveResponseQuery,
+// compacting its history against its base.
+func (s *Store) responseArgs(ctx context.Context, resp *state.Response) ([]interface{}, error) {
 	requestJSON, err := marshalJSON(resp.Request)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	model, metadata := requestIndex(requestJSON)
+	if requestJSON, err = s.seal(requestJSON); err != nil {
+		return nil, fmt.Errorf("encrypt request: %w", err)
 	}
-	outputJSON, err := marshalJSON(resp.Output)
+	outputJSON, err := s.sealJSON(resp.Output)
 	if err != nil {
-		return fmt.Errorf("marshal output: %w", err)
+		return nil, fmt.Errorf("marshal output: %w", err)
 	}
 	errorJSON, err := marshalJSON(resp.Error)
 	if err != nil {
-		return fmt.Errorf("marshal error: %w", err)
+		return nil, fmt.Errorf("marshal error: %w", err)
 	}
 	usageJSON, err := marshalJSON(resp.Usage)
 	if err != nil {
-		return fmt.Errorf("marshal usage: %w", err)
+		return nil, fmt.Errorf("marshal usage: %w", err)
 	}
-	messagesJSON, err := marshalJSON(resp.Messages)
+	provenanceJSON, err := marshalJSON(resp.Provenance)
 	if err != nil {
-		return fmt.Errorf("marshal messages: %w", err)
+		return nil, fmt.Errorf("marshal provenance: %w", err)
+	}
+	messages, messagesBase := s.compactHistory(ctx, resp)
+	messagesJSON, err := s.sealJSON(messages)
+	if err != nil {
+		return nil, fmt.Errorf("marshal messages: %w", err)
 	}
 
 	var completedAt sql.NullTime
@@ -525,27 +805,18 @@ func (s *Store) SaveResponse(ctx context.Context, resp *state.Response) error {
 		completedAt = sql.NullTime{Time: *resp.CompletedAt, Valid: true}
 	}
 
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO responses
-		 (id, conversation_id, previous_response_id, request, output, status, error, usage, messages, created_at, completed_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		 ON CONFLICT (id) DO UPDATE SET
-		   conversation_id=$2, previous_response_id=$3, request=$4, output=$5,
-		   status=$6, error=$7, usage=$8, messages=$9, created_at=$10, completed_at=$11`,
+	return []interface{}{
 		resp.ID, resp.ConversationID, resp.PreviousResponseID,
-		requestJSON, outputJSON, resp.Status, errorJSON, usageJSON, messagesJSON,
-		resp.CreatedAt, completedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("save response: %w", err)
-	}
-	return nil
+		requestJSON, outputJSON, resp.Status, errorJSON, usageJSON, messagesJSON, messagesBase,
+		resp.CreatedAt, completedAt, resp.ExternalID, resp.Tenant, model, metadata, resp.APIKey, resp.RequestID,
+		provenanceJSON,
+	}, nil
 }
 
 func (s *Store) ListResponses(ctx context.Context, conversationID string) ([]*state.Response, error) {
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT id, conversation_id, previous_response_id, request, output, status,
-		        error, usage, messages, created_at, completed_at
+		        error, usage, messages, messages_base, created_at, completed_at, external_id, tenant, api_key, request_id, model, provenance
 		 FROM responses WHERE conversation_id=$1`, conversationID)
 	if err != nil {
 		return nil, fmt.Errorf("list responses: %w", err)
@@ -580,7 +851,7 @@ func (s *Store) LinkResponses(ctx context.Context, currentID, previousID string)
 	return err
 }
 
-func (s *Store) ListResponsesPaginated(ctx context.Context, after, before string, limit int, order, model string) ([]*state.Response, bool, error) {
+func (s *Store) ListResponsesPaginated(ctx context.Context, after, before string, limit int, order string, filter state.ResponseFilter) ([]*state.Response, bool, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
@@ -589,30 +860,17 @@ func (s *Store) ListResponsesPaginated(ctx context.Context, after, before string
 	}
 
 	query := `SELECT id, conversation_id, previous_response_id, request, output, status,
-	                 error, usage, messages, created_at, completed_at
+	                 error, usage, messages, messages_base, created_at, completed_at, external_id, tenant, api_key, request_id, model, provenance
 	          FROM responses`
-	var args []interface{}
-	var where []string
-	argIdx := 1
-
-	if after != "" {
-		where = append(where, fmt.Sprintf("created_at > (SELECT created_at FROM responses WHERE id = $%d)", argIdx))
-		args = append(args, after)
-		argIdx++
-	}
-	if before != "" {
-		where = append(where, fmt.Sprintf("created_at < (SELECT created_at FROM responses WHERE id = $%d)", argIdx))
-		args = append(args, before)
-		argIdx++
-	}
+	cursor := newCursorQuery("responses", after, before, order, 1)
+	where, args := responseFilterClauses(filter, len(cursor.args)+1)
+	where = append(cursor.where, where...)
+	args = append(cursor.args, args...)
 	if len(where) > 0 {
-		query += " WHERE " + where[0]
-		for _, w := range where[1:] {
-			query += " AND " + w
-		}
+		query += " WHERE " + strings.Join(where, " AND ")
 	}
 
-	query += fmt.Sprintf(" ORDER BY created_at %s LIMIT $%d", order, argIdx)
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %[1]s LIMIT $%d", cursor.order, len(args)+1)
 	args = append(args, limit+1)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -630,19 +888,189 @@ func (s *Store) ListResponsesPaginated(ctx context.Context, after, before string
 	if hasMore {
 		resps = resps[:limit]
 	}
+	if cursor.reversed {
+		slices.Reverse(resps)
+	}
 	return resps, hasMore, nil
 }
 
+func (s *Store) CountResponses(ctx context.Context, filter state.ResponseFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM responses`
+	where, args := responseFilterClauses(filter, 1)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count responses: %w", err)
+	}
+	return n, nil
+}
+
+// responseFilterClauses returns the WHERE conditions and arguments that
+// apply filter, numbering placeholders from argIdx.
+func responseFilterClauses(filter state.ResponseFilter, argIdx int) ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if filter.Model != "" {
+		where = append(where, fmt.Sprintf("model = $%d", argIdx))
+		args = append(args, filter.Model)
+		argIdx++
+	}
+	if filter.ExternalID != "" {
+		where = append(where, fmt.Sprintf("external_id = $%d", argIdx))
+		args = append(args, filter.ExternalID)
+		argIdx++
+	}
+	if filter.ConversationID != "" {
+		where = append(where, fmt.Sprintf("conversation_id = $%d", argIdx))
+		args = append(args, filter.ConversationID)
+		argIdx++
+	}
+	if filter.Tenant != "" {
+		where = append(where, fmt.Sprintf("tenant = $%d", argIdx))
+		args = append(args, filter.Tenant)
+		argIdx++
+	}
+	if filter.APIKey != "" {
+		where = append(where, fmt.Sprintf("api_key = $%d", argIdx))
+		args = append(args, filter.APIKey)
+		argIdx++
+	}
+	if filter.RequestID != "" {
+		where = append(where, fmt.Sprintf("request_id = $%d", argIdx))
+		args = append(args, filter.RequestID)
+		argIdx++
+	}
+	if filter.Status != "" {
+		where = append(where, fmt.Sprintf("status = $%d", argIdx))
+		args = append(args, filter.Status)
+		argIdx++
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, fmt.Sprintf("created_at > $%d", argIdx))
+		args = append(args, filter.CreatedAfter)
+		argIdx++
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, filter.CreatedBefore)
+		argIdx++
+	}
+	for _, key := range slices.Sorted(maps.Keys(filter.Metadata)) {
+		where = append(where, fmt.Sprintf("metadata::jsonb ->> $%d::text = $%d", argIdx, argIdx+1))
+		args = append(args, key, filter.Metadata[key])
+		argIdx += 2
+	}
+	return where, args
+}
+
+// cursorQuery holds the conditions selecting the rows between the after and
+// before cursors of a listing. Rows are compared on (created_at, id) so that
+// rows created in the same instant keep their position across pages.
+type cursorQuery struct {
+	where []string
+	args  []interface{}
+	// order is the order to query in. With only a before cursor the rows
+	// nearest to it are wanted, so the query runs in reverse and reversed is
+	// set for the caller to flip the page back.
+	order    string
+	reversed bool
+}
+
+// newCursorQuery builds the cursor conditions for table, numbering
+// placeholders from argIdx.
+func newCursorQuery(table, after, before, order string, argIdx int) cursorQuery {
+	next, prev, reverse := ">", "<", "desc"
+	if order == "desc" {
+		next, prev, reverse = "<", ">", "asc"
+	}
+
+	q := cursorQuery{order: order}
+	if after != "" {
+		q.where = append(q.where, fmt.Sprintf("(created_at, id) %s (SELECT created_at, id FROM %s WHERE id = $%d)", next, table, argIdx))
+		q.args = append(q.args, after)
+		argIdx++
+	}
+	if before != "" {
+		q.where = append(q.where, fmt.Sprintf("(created_at, id) %s (SELECT created_at, id FROM %s WHERE id = $%d)", prev, table, argIdx))
+		q.args = append(q.args, before)
+		if after == "" {
+			q.order, q.reversed = reverse, true
+		}
+	}
+	return q
+}
+
 func (s *Store) DeleteResponse(ctx context.Context, responseID string) error {
-	res, err := s.db.ExecContext(ctx, `DELETE FROM responses WHERE id=$1`, responseID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("delete response: %w", err)
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
+	defer tx.Rollback()
+
+	// Responses storing only their delta against this one take over its
+	// messages, so that their history survives the delete.
+	var messagesStr, messagesBase string
+	err = tx.QueryRowContext(ctx,
+		`SELECT messages, messages_base FROM responses WHERE id=$1 FOR UPDATE`, responseID).Scan(&messagesStr, &messagesBase)
+	if err == sql.ErrNoRows {
 		return fmt.Errorf("response %s not found", responseID)
 	}
-	return nil
+	if err != nil {
+		return fmt.Errorf("delete response: %w", err)
+	}
+	var messages []state.ConversationMessage
+	if err := s.openJSON(messagesStr, &messages); err != nil {
+		return fmt.Errorf("unmarshal messages: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, messages FROM responses WHERE messages_base=$1`, responseID)
+	if err != nil {
+		return fmt.Errorf("delete response: %w", err)
+	}
+	children := make(map[string][]state.ConversationMessage)
+	for rows.Next() {
+		var id, childStr string
+		if err := rows.Scan(&id, &childStr); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan response: %w", err)
+		}
+		var delta []state.ConversationMessage
+		if err := s.openJSON(childStr, &delta); err != nil {
+			rows.Close()
+			return fmt.Errorf("unmarshal messages: %w", err)
+		}
+		children[id] = delta
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("delete response: %w", err)
+	}
+
+	for id, delta := range children {
+		merged, err := s.sealJSON(append(slices.Clone(messages), delta...))
+		if err != nil {
+			return fmt.Errorf("marshal messages: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE responses SET messages=$1, messages_base=$2 WHERE id=$3`,
+			merged, messagesBase, id); err != nil {
+			return fmt.Errorf("rebase response %s: %w", id, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM responses WHERE id=$1`, responseID); err != nil {
+		return fmt.Errorf("delete response: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM response_recordings WHERE response_id=$1`, responseID); err != nil {
+		return fmt.Errorf("delete response recording: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM shadow_results WHERE response_id=$1`, responseID); err != nil {
+		return fmt.Errorf("delete shadow results: %w", err)
+	}
+	return tx.Commit()
 }
 
 func (s *Store) GetResponseInputItems(ctx context.Context, responseID string) (interface{}, error) {
@@ -655,13 +1083,122 @@ func (s *Store) GetResponseInputItems(ctx context.Context, responseID string) (i
 	if err != nil {
 		return nil, fmt.Errorf("get response input items: %w", err)
 	}
-	return unmarshalInterface(requestStr)
+	return s.openInterface(requestStr)
 }
 
 // --- internal helpers ---
 
-func (s *Store) insertMessage(ctx context.Context, conversationID string, msg state.Message, position int) error {
-	contentJSON, err := marshalJSON(msg.Content)
+// maxHistoryDepth bounds the walk over messages_base links. Chains are
+// acyclic by construction; the limit only guards against corrupt rows.
+const maxHistoryDepth = 100000
+
+// loadHistory returns the full message history of a response by following
+// its messages_base chain, along with the IDs of the responses on the chain.
+func (s *Store) loadHistory(ctx context.Context, responseID string) ([]state.ConversationMessage, []string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`WITH RECURSIVE chain(id, messages, messages_base, depth) AS (
+		     SELECT id, messages, messages_base, 0 FROM responses WHERE id = $1
+		     UNION ALL
+		     SELECT r.id, r.messages, r.messages_base, c.depth + 1
+		     FROM responses r JOIN chain c ON r.id = c.messages_base
+		     WHERE c.depth < $2
+		 )
+		 SELECT id, messages, messages_base FROM chain ORDER BY depth DESC`,
+		responseID, maxHistoryDepth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		history []state.ConversationMessage
+		ids     []string
+	)
+	for rows.Next() {
+		var id, messagesStr, messagesBase string
+		if err := rows.Scan(&id, &messagesStr, &messagesBase); err != nil {
+			return nil, nil, fmt.Errorf("scan history: %w", err)
+		}
+		if len(ids) == 0 && messagesBase != "" {
+			return nil, nil, fmt.Errorf("history is incomplete: response %s not found", messagesBase)
+		}
+		var delta []state.ConversationMessage
+		if err := s.openJSON(messagesStr, &delta); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal messages: %w", err)
+		}
+		history = append(history, delta...)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("query history: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("response %s not found", responseID)
+	}
+	return history, ids, nil
+}
+
+// compactHistory returns the messages to store for resp and the response
+// they are relative to. Only the messages added since resp.MessagesBase are
+// kept; the full history is stored when the base cannot be loaded or is not
+// a prefix of resp.Messages.
+func (s *Store) compactHistory(ctx context.Context, resp *state.Response) ([]state.ConversationMessage, string) {
+	if resp.MessagesBase == "" {
+		return resp.Messages, ""
+	}
+	base, chain, err := s.loadHistory(ctx, resp.MessagesBase)
+	if err != nil || slices.Contains(chain, resp.ID) {
+		return resp.Messages, ""
+	}
+	delta, ok := state.SplitHistory(base, resp.Messages)
+	if !ok {
+		return resp.Messages, ""
+	}
+	return delta, resp.MessagesBase
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryExecer is implemented by both *sql.DB and *sql.Tx.
+type queryExecer interface {
+	execer
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// appendItems adds items after the last item of a conversation.
+func (s *Store) appendItems(ctx context.Context, db queryExecer, conversationID string, items []state.Message) error {
+	// Verify conversation exists
+	var exists int
+	err := db.QueryRowContext(ctx, `SELECT 1 FROM conversations WHERE id=$1`, conversationID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("conversation %s not found", conversationID)
+	}
+	if err != nil {
+		return fmt.Errorf("check conversation: %w", err)
+	}
+
+	// Get current max position
+	var maxPos int
+	err = db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(position), -1) FROM messages WHERE conversation_id=$1`,
+		conversationID).Scan(&maxPos)
+	if err != nil {
+		return fmt.Errorf("get max position: %w", err)
+	}
+
+	for i, msg := range items {
+		if err := s.insertMessage(ctx, db, conversationID, msg, maxPos+1+i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) insertMessage(ctx context.Context, db execer, conversationID string, msg state.Message, position int) error {
+	contentJSON, err := s.sealJSON(msg.Content)
 	if err != nil {
 		return fmt.Errorf("marshal content: %w", err)
 	}
@@ -670,7 +1207,7 @@ func (s *Store) insertMessage(ctx context.Context, conversationID string, msg st
 		return fmt.Errorf("marshal metadata: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx,
+	_, err = db.ExecContext(ctx,
 		`INSERT INTO messages (id, conversation_id, role, content, metadata, created_at, position)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7)
 		 ON CONFLICT (conversation_id, id) DO UPDATE SET role=$3, content=$4, metadata=$5, created_at=$6, position=$7`,
@@ -700,7 +1237,7 @@ func (s *Store) loadMessages(ctx context.Context, conversationID string) ([]stat
 		if err := rows.Scan(&msg.ID, &msg.Role, &contentStr, &metaStr, &msg.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
-		msg.Content, err = unmarshalInterface(contentStr)
+		msg.Content, err = s.openInterface(contentStr)
 		if err != nil {
 			return nil, fmt.Errorf("unmarshal content: %w", err)
 		}
@@ -721,11 +1258,12 @@ func (s *Store) scanResponse(row scannable) (*state.Response, error) {
 	var (
 		resp                                                   state.Response
 		requestStr, outputStr, errorStr, usageStr, messagesStr string
+		provenanceStr                                          string
 		completedAt                                            sql.NullTime
 	)
 	err := row.Scan(&resp.ID, &resp.ConversationID, &resp.PreviousResponseID,
-		&requestStr, &outputStr, &resp.Status, &errorStr, &usageStr, &messagesStr,
-		&resp.CreatedAt, &completedAt)
+		&requestStr, &outputStr, &resp.Status, &errorStr, &usageStr, &messagesStr, &resp.MessagesBase,
+		&resp.CreatedAt, &completedAt, &resp.ExternalID, &resp.Tenant, &resp.APIKey, &resp.RequestID, &resp.Model, &provenanceStr)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("response %s not found", resp.ID)
 	}
@@ -735,11 +1273,11 @@ func (s *Store) scanResponse(row scannable) (*state.Response, error) {
 
 	resp.CompletedAt = nullTimeToPtr(completedAt)
 
-	resp.Request, err = unmarshalInterface(requestStr)
+	resp.Request, err = s.openInterface(requestStr)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal request: %w", err)
 	}
-	resp.Output, err = unmarshalInterface(outputStr)
+	resp.Output, err = s.openInterface(outputStr)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal output: %w", err)
 	}
@@ -751,7 +1289,11 @@ func (s *Store) scanResponse(row scannable) (*state.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal usage: %w", err)
 	}
-	if err := json.Unmarshal([]byte(messagesStr), &resp.Messages); err != nil {
+	resp.Provenance, err = unmarshalInterface(provenanceStr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal provenance: %w", err)
+	}
+	if err := s.openJSON(messagesStr, &resp.Messages); err != nil {
 		return nil, fmt.Errorf("unmarshal messages: %w", err)
 	}
 	return &resp, nil
@@ -770,7 +1312,7 @@ func (s *Store) scanConversationRows(ctx context.Context, query string, args ...
 			conv    state.Conversation
 			metaStr string
 		)
-		if err := rows.Scan(&conv.ID, &conv.SessionID, &metaStr, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
+		if err := rows.Scan(&conv.ID, &conv.SessionID, &metaStr, &conv.CreatedAt, &conv.UpdatedAt, &conv.Tenant, &conv.ForkedFrom); err != nil {
 			return nil, fmt.Errorf("scan conversation: %w", err)
 		}
 		conv.Metadata, err = unmarshalMapStringString(metaStr)
@@ -793,3 +1335,655 @@ func (s *Store) scanResponses(rows *sql.Rows) ([]*state.Response, error) {
 	}
 	return resps, rows.Err()
 }
+
+// AppendAuditEvent implements state.AuditLog.
+func (s *Store) AppendAuditEvent(ctx context.Context, event *state.AuditEvent) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_events
+		 (id, created_at, actor, tenant, remote_addr, method, path, action, resource_type, resource_id, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		event.ID, event.CreatedAt, event.Actor, event.Tenant, event.RemoteAddr, event.Method, event.Path,
+		event.Action, event.ResourceType, event.ResourceID, event.Status,
+	)
+	if err != nil {
+		return fmt.Errorf("append audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents implements state.AuditLog.
+func (s *Store) ListAuditEvents(ctx context.Context, filter state.AuditFilter, after string, limit int) ([]*state.AuditEvent, bool, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	query := `SELECT id, created_at, actor, tenant, remote_addr, method, path, action, resource_type, resource_id, status
+	          FROM audit_events`
+	cursor := newCursorQuery("audit_events", after, "", "desc", 1)
+	where, args := auditFilterClauses(filter, len(cursor.args)+1)
+	where = append(cursor.where, where...)
+	args = append(cursor.args, args...)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*state.AuditEvent
+	for rows.Next() {
+		var e state.AuditEvent
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Actor, &e.Tenant, &e.RemoteAddr, &e.Method, &e.Path,
+			&e.Action, &e.ResourceType, &e.ResourceID, &e.Status); err != nil {
+			return nil, false, fmt.Errorf("scan audit event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("list audit events: %w", err)
+	}
+
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+	return events, hasMore, nil
+}
+
+func auditFilterClauses(filter state.AuditFilter, argIdx int) ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	for _, c := range []struct{ column, value string }{
+		{"actor", filter.Actor},
+		{"tenant", filter.Tenant},
+		{"action", filter.Action},
+		{"resource_type", filter.ResourceType},
+		{"resource_id", filter.ResourceID},
+	} {
+		if c.value != "" {
+			where = append(where, fmt.Sprintf("%s = $%d", c.column, argIdx))
+			args = append(args, c.value)
+			argIdx++
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, fmt.Sprintf("created_at > $%d", argIdx))
+		args = append(args, filter.CreatedAfter)
+		argIdx++
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, filter.CreatedBefore)
+	}
+	return where, args
+}
+
+// --- Response cache ---
+
+// GetCachedResponse implements state.ResponseCache.
+func (s *Store) GetCachedResponse(ctx context.Context, key string) ([]byte, bool, error) {
+	var (
+		value     string
+		expiresAt time.Time
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT value, expires_at FROM response_cache WHERE key=$1`, key).Scan(&value, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get cached response: %w", err)
+	}
+	if !time.Now().Before(expiresAt) {
+		return nil, false, nil
+	}
+	if value, err = s.open(value); err != nil {
+		return nil, false, fmt.Errorf("get cached response: %w", err)
+	}
+	return []byte(value), true, nil
+}
+
+// PutCachedResponse implements state.ResponseCache. Expired entries are
+// removed as new ones are added.
+func (s *Store) PutCachedResponse(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	sealed, err := s.seal(string(value))
+	if err != nil {
+		return fmt.Errorf("put cached response: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO response_cache (key, value, expires_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (key) DO UPDATE SET value=EXCLUDED.value, expires_at=EXCLUDED.expires_at`,
+		key, sealed, expiresAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("put cached response: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM response_cache WHERE expires_at <= $1`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("prune response cache: %w", err)
+	}
+	return nil
+}
+
+// --- Response recordings ---
+
+// SaveRecording implements state.RecordingStore.
+func (s *Store) SaveRecording(ctx context.Context, responseID string, recording []byte) error {
+	sealed, err := s.seal(string(recording))
+	if err != nil {
+		return fmt.Errorf("save recording: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO response_recordings (response_id, recording, created_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (response_id) DO UPDATE SET recording=EXCLUDED.recording, created_at=EXCLUDED.created_at`,
+		responseID, sealed, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("save recording: %w", err)
+	}
+	return nil
+}
+
+// GetRecording implements state.RecordingStore.
+func (s *Store) GetRecording(ctx context.Context, responseID string) ([]byte, bool, error) {
+	var recording string
+	err := s.db.QueryRowContext(ctx, `SELECT recording FROM response_recordings WHERE response_id=$1`, responseID).Scan(&recording)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get recording: %w", err)
+	}
+	if recording, err = s.open(recording); err != nil {
+		return nil, false, fmt.Errorf("get recording: %w", err)
+	}
+	return []byte(recording), true, nil
+}
+
+// --- Shadow results ---
+
+// SaveShadowResult implements state.ShadowStore.
+func (s *Store) SaveShadowResult(ctx context.Context, result *state.ShadowResult) error {
+	sealed, err := s.seal(string(result.Data))
+	if err != nil {
+		return fmt.Errorf("save shadow result: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO shadow_results (id, created_at, response_id, model, data) VALUES ($1, $2, $3, $4, $5)`,
+		result.ID, result.CreatedAt, result.ResponseID, result.Model, sealed,
+	); err != nil {
+		return fmt.Errorf("save shadow result: %w", err)
+	}
+	return nil
+}
+
+// ListShadowResults implements state.ShadowStore.
+func (s *Store) ListShadowResults(ctx context.Context, filter state.ShadowFilter, after string, limit int) ([]*state.ShadowResult, bool, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	query := `SELECT id, created_at, response_id, model, data FROM shadow_results`
+	cursor := newCursorQuery("shadow_results", after, "", "desc", 1)
+	where, args := shadowFilterClauses(filter, len(cursor.args)+1)
+	where = append(cursor.where, where...)
+	args = append(cursor.args, args...)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("list shadow results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*state.ShadowResult
+	for rows.Next() {
+		var r state.ShadowResult
+		var data string
+		if err := rows.Scan(&r.ID, &r.CreatedAt, &r.ResponseID, &r.Model, &data); err != nil {
+			return nil, false, fmt.Errorf("scan shadow result: %w", err)
+		}
+		if data, err = s.open(data); err != nil {
+			return nil, false, fmt.Errorf("open shadow result: %w", err)
+		}
+		r.Data = []byte(data)
+		results = append(results, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("list shadow results: %w", err)
+	}
+
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	return results, hasMore, nil
+}
+
+func shadowFilterClauses(filter state.ShadowFilter, argIdx int) ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	for _, c := range []struct{ column, value string }{
+		{"response_id", filter.ResponseID},
+		{"model", filter.Model},
+	} {
+		if c.value != "" {
+			where = append(where, fmt.Sprintf("%s = $%d", c.column, argIdx))
+			args = append(args, c.value)
+			argIdx++
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, fmt.Sprintf("created_at > $%d", argIdx))
+		args = append(args, filter.CreatedAfter)
+		argIdx++
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, filter.CreatedBefore)
+	}
+	return where, args
+}
+
+// --- Event outbox ---
+
+// ClaimOutboxEvents implements state.EventOutbox. Rows claimed by a
+// concurrent replica are skipped rather than waited for.
+func (s *Store) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*state.OutboxEvent, error) {
+	now := time.Now().UTC()
+	rows, err := s.db.QueryContext(ctx,
+		`WITH claimed AS (
+		     UPDATE event_outbox SET claimed_until=$1, attempts=attempts+1
+		     WHERE seq IN (SELECT seq FROM event_outbox WHERE claimed_until <= $2
+		                   ORDER BY seq LIMIT $3 FOR UPDATE SKIP LOCKED)
+		     RETURNING seq, id, type, response_id, data, created_at, attempts)
+		 SELECT id, type, response_id, data, created_at, attempts FROM claimed ORDER BY seq`,
+		now.Add(lease), now, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*state.OutboxEvent
+	for rows.Next() {
+		var (
+			event state.OutboxEvent
+			data  string
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &event.ResponseID, &data, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		event.Data = []byte(data)
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteOutboxEvents implements state.EventOutbox.
+func (s *Store) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM event_outbox WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("delete outbox events: %w", err)
+	}
+	return nil
+}
+
+// --- Conversation metadata ---
+
+// AddConversationMetadata implements state.ConversationMetadataAdder.
+func (s *Store) AddConversationMetadata(ctx context.Context, conversationID, key, value string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("add conversation metadata: %w", err)
+	}
+	defer tx.Rollback()
+
+	var metaStr string
+	err = tx.QueryRowContext(ctx, `SELECT metadata FROM conversations WHERE id = $1 FOR UPDATE`, conversationID).Scan(&metaStr)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("conversation %s not found", conversationID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("add conversation metadata: %w", err)
+	}
+	metadata, err := unmarshalMapStringString(metaStr)
+	if err != nil {
+		return false, fmt.Errorf("unmarshal metadata: %w", err)
+	}
+	if _, ok := metadata[key]; ok {
+		return false, nil
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[key] = value
+	metaJSON, err := marshalJSON(metadata)
+	if err != nil {
+		return false, fmt.Errorf("marshal metadata: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE conversations SET metadata=$1 WHERE id=$2`, metaJSON, conversationID); err != nil {
+		return false, fmt.Errorf("add conversation metadata: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("add conversation metadata: %w", err)
+	}
+	return true, nil
+}
+
+// --- Conversation locks ---
+
+// LockConversation implements state.ConversationLocker. An expired lock is
+// taken over in the same statement, so two requests cannot both get it.
+func (s *Store) LockConversation(ctx context.Context, conversationID, holder string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO conversation_locks (conversation_id, holder, expires_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (conversation_id) DO UPDATE SET holder=EXCLUDED.holder, expires_at=EXCLUDED.expires_at
+		 WHERE conversation_locks.expires_at <= $4`,
+		conversationID, holder, now.Add(ttl), now,
+	)
+	if err != nil {
+		return fmt.Errorf("lock conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return state.ErrConversationLocked
+	}
+	return nil
+}
+
+// UnlockConversation implements state.ConversationLocker.
+func (s *Store) UnlockConversation(ctx context.Context, conversationID, holder string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM conversation_locks WHERE conversation_id=$1 AND holder=$2`, conversationID, holder,
+	); err != nil {
+		return fmt.Errorf("unlock conversation: %w", err)
+	}
+	return nil
+}
+
+// --- Retention ---
+
+// DeleteExpired implements state.Expirer. Rows locked by another replica's
+// cleanup are skipped.
+func (s *Store) DeleteExpired(ctx context.Context, kind string, cutoff time.Time, limit int) (int, error) {
+	switch kind {
+	case state.ExpiredSessions:
+		res, err := s.db.ExecContext(ctx,
+			`DELETE FROM sessions WHERE id IN (
+			     SELECT id FROM sessions WHERE expires_at <= $1 ORDER BY expires_at LIMIT $2
+			     FOR UPDATE SKIP LOCKED)`,
+			cutoff, limit)
+		if err != nil {
+			return 0, fmt.Errorf("delete expired sessions: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		return int(n), nil
+
+	case state.ExpiredConversations:
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, fmt.Errorf("delete expired conversations: %w", err)
+		}
+		defer tx.Rollback()
+		ids, err := queryIDs(ctx, tx,
+			`DELETE FROM conversations WHERE id IN (
+			     SELECT id FROM conversations WHERE updated_at < $1 ORDER BY updated_at LIMIT $2
+			     FOR UPDATE SKIP LOCKED)
+			 RETURNING id`,
+			cutoff, limit)
+		if err != nil || len(ids) == 0 {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE conversation_id = ANY($1)`, ids); err != nil {
+			return 0, fmt.Errorf("delete expired conversation items: %w", err)
+		}
+		return len(ids), tx.Commit()
+
+	case state.ExpiredResponses:
+		// Deleted one by one so that newer responses can take over the
+		// messages of those they store their history against.
+		ids, err := queryIDs(ctx, s.db,
+			`SELECT id FROM responses WHERE created_at < $1 ORDER BY created_at, id LIMIT $2`, cutoff, limit)
+		if err != nil {
+			return 0, err
+		}
+		deleted := 0
+		for _, id := range ids {
+			if err := s.DeleteResponse(ctx, id); err != nil {
+				// Another replica may have deleted it meanwhile
+				var exists int
+				if s.db.QueryRowContext(ctx, `SELECT 1 FROM responses WHERE id=$1`, id).Scan(&exists) == sql.ErrNoRows {
+					continue
+				}
+				return deleted, err
+			}
+			deleted++
+		}
+		return deleted, nil
+	}
+	return 0, fmt.Errorf("unknown kind of expired data %q", kind)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryIDs returns the IDs returned by query.
+func queryIDs(ctx context.Context, db querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query ids: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// --- Payload encryption ---
+
+// seal encrypts a payload for storage when a keyring is set.
+func (s *Store) seal(data string) (string, error) {
+	if s.keys == nil {
+		return data, nil
+	}
+	return s.keys.Seal([]byte(data))
+}
+
+// sealJSON marshals v and seals it.
+func (s *Store) sealJSON(v interface{}) (string, error) {
+	data, err := marshalJSON(v)
+	if err != nil {
+		return "", err
+	}
+	return s.seal(data)
+}
+
+// open returns the JSON of a stored payload, decrypting it if it is sealed.
+// Payloads written before encryption was turned on are plain JSON.
+func (s *Store) open(data string) (string, error) {
+	if !secrets.IsSealed(data) {
+		return data, nil
+	}
+	if s.keys == nil {
+		return "", fmt.Errorf("payload is encrypted but no encryption key is configured")
+	}
+	plaintext, err := s.keys.Open(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// openJSON opens a stored payload and unmarshals it into v.
+func (s *Store) openJSON(data string, v interface{}) error {
+	data, err := s.open(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+// openInterface opens a stored payload and unmarshals it.
+func (s *Store) openInterface(data string) (interface{}, error) {
+	data, err := s.open(data)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalInterface(data)
+}
+
+// payloadColumns lists the columns holding payloads, by table, with the
+// primary key of the table.
+var payloadColumns = []struct {
+	table   string
+	key     []string
+	columns []string
+}{
+	{"responses", []string{"id"}, []string{"request", "output", "messages"}},
+	{"messages", []string{"conversation_id", "id"}, []string{"content"}},
+	{"response_cache", []string{"key"}, []string{"value"}},
+	{"response_recordings", []string{"response_id"}, []string{"recording"}},
+	{"shadow_results", []string{"id"}, []string{"data"}},
+}
+
+// reencryptPageSize is the number of rows read at a time by
+// ReencryptPayloads.
+const reencryptPageSize = 100
+
+// ReencryptPayloads implements state.PayloadEncrypter. Rows are rewritten
+// only if they did not change since they were read, so concurrent writes
+// are not lost.
+func (s *Store) ReencryptPayloads(ctx context.Context) (int, error) {
+	if s.keys == nil {
+		return 0, fmt.Errorf("no encryption key is configured")
+	}
+	total := 0
+	for _, t := range payloadColumns {
+		n, err := s.reencryptTable(ctx, t.table, t.key, t.columns)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("reencrypt %s: %w", t.table, err)
+		}
+	}
+	return total, nil
+}
+
+func (s *Store) reencryptTable(ctx context.Context, table string, key, columns []string) (int, error) {
+	// Rows are paged on their primary key: (k1, k2) > ($1, $2)
+	keyList := strings.Join(key, ", ")
+	var cursor []string
+	for i := range key {
+		cursor = append(cursor, fmt.Sprintf("$%d", i+1))
+	}
+	first := fmt.Sprintf(`SELECT %[1]s, %[2]s FROM %[3]s ORDER BY %[1]s LIMIT %[4]d`,
+		keyList, strings.Join(columns, ", "), table, reencryptPageSize)
+	next := fmt.Sprintf(`SELECT %[1]s, %[2]s FROM %[3]s WHERE (%[1]s) > (%[5]s) ORDER BY %[1]s LIMIT %[4]d`,
+		keyList, strings.Join(columns, ", "), table, reencryptPageSize, strings.Join(cursor, ", "))
+
+	var set, where []string
+	for i, c := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", c, i+1))
+	}
+	for i, k := range key {
+		where = append(where, fmt.Sprintf("%s = $%d", k, len(columns)+i+1))
+	}
+	for i, c := range columns {
+		where = append(where, fmt.Sprintf("%s = $%d", c, len(columns)+len(key)+i+1))
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s WHERE %s`, table, strings.Join(set, ", "), strings.Join(where, " AND "))
+
+	rewritten := 0
+	var after []interface{}
+	for {
+		query := first
+		if after != nil {
+			query = next
+		}
+		rows, err := s.db.QueryContext(ctx, query, after...)
+		if err != nil {
+			return rewritten, err
+		}
+		var page [][]string
+		for rows.Next() {
+			values := make([]string, len(key)+len(columns))
+			dest := make([]interface{}, len(values))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return rewritten, err
+			}
+			page = append(page, values)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rewritten, err
+		}
+
+		for _, values := range page {
+			keys, old := values[:len(key)], values[len(key):]
+			args := make([]interface{}, 0, len(values)+len(columns))
+			stale := false
+			for _, v := range old {
+				if s.keys.IsCurrent(v) {
+					args = append(args, v)
+					continue
+				}
+				stale = true
+				plain, err := s.open(v)
+				if err != nil {
+					return rewritten, fmt.Errorf("row %v: %w", keys, err)
+				}
+				sealed, err := s.seal(plain)
+				if err != nil {
+					return rewritten, err
+				}
+				args = append(args, sealed)
+			}
+			if !stale {
+				continue
+			}
+			for _, v := range keys {
+				args = append(args, v)
+			}
+			for _, v := range old {
+				args = append(args, v)
+			}
+			res, err := s.db.ExecContext(ctx, update, args...)
+			if err != nil {
+				return rewritten, err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				rewritten++
+			}
+		}
+
+		if len(page) < reencryptPageSize {
+			return rewritten, nil
+		}
+		after = nil
+		for _, v := range page[len(page)-1][:len(key)] {
+			after = append(after, v)
+		}
+	}
+}
@@ -4,15 +4,21 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/leseb/openresponses-gw/pkg/core/state"
+	"github.com/leseb/openresponses-gw/pkg/secrets"
 )
 
-func newTestStore(t *testing.T) *Store {
+func newTestStore(t testing.TB) *Store {
 	t.Helper()
 	dsn := os.Getenv("POSTGRES_DSN")
 	if dsn == "" {
@@ -28,6 +34,9 @@ func newTestStore(t *testing.T) *Store {
 		s.db.Exec("DELETE FROM responses")
 		s.db.Exec("DELETE FROM conversations")
 		s.db.Exec("DELETE FROM sessions")
+		s.db.Exec("DELETE FROM audit_events")
+		s.db.Exec("DELETE FROM response_cache")
+		s.db.Exec("DELETE FROM conversation_locks")
 		s.Close()
 	})
 	// Clean tables before test to ensure isolation
@@ -35,6 +44,9 @@ func newTestStore(t *testing.T) *Store {
 	s.db.Exec("DELETE FROM responses")
 	s.db.Exec("DELETE FROM conversations")
 	s.db.Exec("DELETE FROM sessions")
+	s.db.Exec("DELETE FROM audit_events")
+	s.db.Exec("DELETE FROM response_cache")
+	s.db.Exec("DELETE FROM conversation_locks")
 	return s
 }
 
@@ -416,6 +428,105 @@ func TestDeleteResponse_NotFound(t *testing.T) {
 		t.Error("expected error for missing response, got nil")
 	}
 }
+func makeHistory(turns int) []state.ConversationMessage {
+	var msgs []state.ConversationMessage
+	for i := 0; i < turns; i++ {
+		msgs = append(msgs,
+			state.ConversationMessage{Role: "user", Content: fmt.Sprintf("question %d", i)},
+			state.ConversationMessage{Role: "assistant", Content: fmt.Sprintf("answer %d", i)},
+		)
+	}
+	return msgs
+}
+
+func TestResponseHistoryDeltas(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	// resp-1 <- resp-2 <- resp-3, each adding one turn
+	for i, id := range []string{"resp-1", "resp-2", "resp-3"} {
+		resp := makeResponse(id, "conv-1")
+		resp.Messages = makeHistory(i + 1)
+		if i > 0 {
+			resp.MessagesBase = fmt.Sprintf("resp-%d", i)
+		}
+		if err := s.SaveResponse(ctx, resp); err != nil {
+			t.Fatalf("SaveResponse(%s): %v", id, err)
+		}
+	}
+
+	// Only the new turn is stored
+	resps, err := s.ListResponses(ctx, "conv-1")
+	if err != nil {
+		t.Fatalf("ListResponses: %v", err)
+	}
+	for _, r := range resps {
+		if len(r.Messages) != 2 {
+			t.Errorf("%s stores %d messages, want 2", r.ID, len(r.Messages))
+		}
+	}
+
+	got, err := s.GetResponse(ctx, "resp-3")
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if !reflect.DeepEqual(got.Messages, makeHistory(3)) {
+		t.Errorf("resp-3 history = %+v, want %+v", got.Messages, makeHistory(3))
+	}
+	if got.MessagesBase != "" {
+		t.Errorf("MessagesBase = %q, want empty for the full history", got.MessagesBase)
+	}
+
+	// Deleting a base keeps the history of the responses built on it
+	if err := s.DeleteResponse(ctx, "resp-2"); err != nil {
+		t.Fatalf("DeleteResponse: %v", err)
+	}
+	got, err = s.GetResponse(ctx, "resp-3")
+	if err != nil {
+		t.Fatalf("GetResponse after delete: %v", err)
+	}
+	if !reflect.DeepEqual(got.Messages, makeHistory(3)) {
+		t.Errorf("resp-3 history after delete = %+v, want %+v", got.Messages, makeHistory(3))
+	}
+}
+
+func TestResponseHistoryDeltas_RewrittenHistory(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	base := makeResponse("resp-1", "conv-1")
+	base.Messages = makeHistory(1)
+	if err := s.SaveResponse(ctx, base); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+
+	// A system message prepended to the history is not a delta of resp-1
+	full := append([]state.ConversationMessage{{Role: "system", Content: "be brief"}}, makeHistory(2)...)
+	resp := makeResponse("resp-2", "conv-1")
+	resp.Messages = full
+	resp.MessagesBase = "resp-1"
+	if err := s.SaveResponse(ctx, resp); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+
+	resps, err := s.ListResponses(ctx, "conv-1")
+	if err != nil {
+		t.Fatalf("ListResponses: %v", err)
+	}
+	for _, r := range resps {
+		if r.ID == "resp-2" && (r.MessagesBase != "" || len(r.Messages) != len(full)) {
+			t.Errorf("resp-2 stored %d messages on base %q, want full history", len(r.Messages), r.MessagesBase)
+		}
+	}
+
+	got, err := s.GetResponse(ctx, "resp-2")
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if !reflect.DeepEqual(got.Messages, full) {
+		t.Errorf("resp-2 history = %+v, want %+v", got.Messages, full)
+	}
+}
 
 func TestLinkResponses(t *testing.T) {
 	s := newTestStore(t)
@@ -504,7 +615,7 @@ func TestListResponsesPaginated(t *testing.T) {
 	}
 
 	// Limit to 2
-	resps, hasMore, err := s.ListResponsesPaginated(ctx, "", "", 2, "asc", "")
+	resps, hasMore, err := s.ListResponsesPaginated(ctx, "", "", 2, "asc", state.ResponseFilter{})
 	if err != nil {
 		t.Fatalf("ListResponsesPaginated: %v", err)
 	}
@@ -516,7 +627,7 @@ func TestListResponsesPaginated(t *testing.T) {
 	}
 
 	// Default limit (0 -> 50)
-	resps2, _, err := s.ListResponsesPaginated(ctx, "", "", 0, "", "")
+	resps2, _, err := s.ListResponsesPaginated(ctx, "", "", 0, "", state.ResponseFilter{})
 	if err != nil {
 		t.Fatalf("ListResponsesPaginated default: %v", err)
 	}
@@ -525,6 +636,159 @@ func TestListResponsesPaginated(t *testing.T) {
 	}
 }
 
+func TestListResponsesPaginated_ExternalID(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for i, extID := range []string{"order-1", "order-2", "order-1", ""} {
+		resp := makeResponse("resp-x-"+string(rune('a'+i)), "conv-1")
+		resp.ExternalID = extID
+		resp.CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		_ = s.SaveResponse(ctx, resp)
+	}
+
+	resps, _, err := s.ListResponsesPaginated(ctx, "", "", 0, "asc", state.ResponseFilter{ExternalID: "order-1"})
+	if err != nil {
+		t.Fatalf("ListResponsesPaginated: %v", err)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("expected 2 responses for external_id=order-1, got %d", len(resps))
+	}
+	for _, r := range resps {
+		if r.ExternalID != "order-1" {
+			t.Errorf("ExternalID = %q, want %q", r.ExternalID, "order-1")
+		}
+	}
+
+	got, err := s.GetResponse(ctx, "resp-x-b")
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if got.ExternalID != "order-2" {
+		t.Errorf("ExternalID = %q, want %q", got.ExternalID, "order-2")
+	}
+}
+
+func TestListResponsesPaginated_Filters(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	fixtures := []struct {
+		id       string
+		conv     string
+		status   string
+		model    string
+		metadata map[string]interface{}
+	}{
+		{"resp-f-a", "conv-1", "completed", "gpt", map[string]interface{}{"customer": "cus_1", "tier": "gold"}},
+		{"resp-f-b", "conv-1", "failed", "gpt", map[string]interface{}{"customer": "cus_2"}},
+		{"resp-f-c", "conv-2", "completed", "llama", map[string]interface{}{"customer": "cus_1"}},
+		{"resp-f-d", "conv-2", "completed", "gpt", nil},
+	}
+	for i, f := range fixtures {
+		resp := makeResponse(f.id, f.conv)
+		resp.Status = f.status
+		resp.Request = map[string]interface{}{"model": f.model, "metadata": f.metadata}
+		resp.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := s.SaveResponse(ctx, resp); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter state.ResponseFilter
+		want   []string
+	}{
+		{"no filter", state.ResponseFilter{}, []string{"resp-f-a", "resp-f-b", "resp-f-c", "resp-f-d"}},
+		{"model", state.ResponseFilter{Model: "llama"}, []string{"resp-f-c"}},
+		{"conversation", state.ResponseFilter{ConversationID: "conv-2"}, []string{"resp-f-c", "resp-f-d"}},
+		{"status", state.ResponseFilter{Status: "failed"}, []string{"resp-f-b"}},
+		{"metadata", state.ResponseFilter{Metadata: map[string]string{"customer": "cus_1"}}, []string{"resp-f-a", "resp-f-c"}},
+		{"metadata pairs", state.ResponseFilter{Metadata: map[string]string{"customer": "cus_1", "tier": "gold"}}, []string{"resp-f-a"}},
+		{"created range", state.ResponseFilter{CreatedAfter: base, CreatedBefore: base.Add(3 * time.Minute)}, []string{"resp-f-b", "resp-f-c"}},
+		{"combined", state.ResponseFilter{ConversationID: "conv-1", Metadata: map[string]string{"customer": "cus_2"}}, []string{"resp-f-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resps, _, err := s.ListResponsesPaginated(ctx, "", "", 0, "asc", tt.filter)
+			if err != nil {
+				t.Fatalf("ListResponsesPaginated: %v", err)
+			}
+			var got []string
+			for _, r := range resps {
+				got = append(got, r.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			count, err := s.CountResponses(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("CountResponses: %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("CountResponses = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestListResponsesPaginated_SameCreatedAt(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	// Responses created in the same instant are ordered by ID
+	createdAt := time.Now().Truncate(time.Second)
+	for _, id := range []string{"resp-t-c", "resp-t-a", "resp-t-e", "resp-t-b", "resp-t-d"} {
+		resp := makeResponse(id, "conv-1")
+		resp.CreatedAt = createdAt
+		if err := s.SaveResponse(ctx, resp); err != nil {
+			t.Fatalf("SaveResponse: %v", err)
+		}
+	}
+
+	ids := func(resps []*state.Response) []string {
+		var got []string
+		for _, r := range resps {
+			got = append(got, r.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name        string
+		after       string
+		before      string
+		order       string
+		want        []string
+		wantHasMore bool
+	}{
+		{"desc first page", "", "", "desc", []string{"resp-t-e", "resp-t-d"}, true},
+		{"desc after", "resp-t-d", "", "desc", []string{"resp-t-c", "resp-t-b"}, true},
+		{"desc last page", "resp-t-b", "", "desc", []string{"resp-t-a"}, false},
+		{"desc before", "", "resp-t-b", "desc", []string{"resp-t-d", "resp-t-c"}, true},
+		{"asc after", "resp-t-b", "", "asc", []string{"resp-t-c", "resp-t-d"}, true},
+		{"asc before", "", "resp-t-b", "asc", []string{"resp-t-a"}, false},
+		{"after and before", "resp-t-a", "resp-t-d", "asc", []string{"resp-t-b", "resp-t-c"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resps, hasMore, err := s.ListResponsesPaginated(ctx, tt.after, tt.before, 2, tt.order, state.ResponseFilter{})
+			if err != nil {
+				t.Fatalf("ListResponsesPaginated: %v", err)
+			}
+			if got := ids(resps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if hasMore != tt.wantHasMore {
+				t.Errorf("hasMore = %v, want %v", hasMore, tt.wantHasMore)
+			}
+		})
+	}
+}
+
 func TestListConversationsPaginated(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -555,6 +819,26 @@ func TestListConversationsPaginated(t *testing.T) {
 	if len(convs2) != 5 {
 		t.Errorf("expected 5 conversations with default limit, got %d", len(convs2))
 	}
+
+	// Paging backwards from the oldest conversation returns the newest first
+	convs3, hasMore, err := s.ListConversationsPaginated(ctx, "", "conv-p-a", 2, "desc")
+	if err != nil {
+		t.Fatalf("ListConversationsPaginated before: %v", err)
+	}
+	if len(convs3) != 2 || convs3[0].ID != "conv-p-c" || convs3[1].ID != "conv-p-b" {
+		t.Errorf("expected [conv-p-c conv-p-b] before conv-p-a, got %d conversations", len(convs3))
+	}
+	if !hasMore {
+		t.Error("expected hasMore=true before conv-p-a")
+	}
+
+	count, err := s.CountConversations(ctx)
+	if err != nil {
+		t.Fatalf("CountConversations: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("CountConversations = %d, want 5", count)
+	}
 }
 
 func TestDeleteConversation_NotFound(t *testing.T) {
@@ -581,3 +865,294 @@ func TestCreateConversation_Duplicate(t *testing.T) {
 		t.Error("expected error on duplicate conversation, got nil")
 	}
 }
+
+func TestAuditEvents(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	events := []*state.AuditEvent{
+		{ID: "audit_1", CreatedAt: base, Actor: "key_a", Tenant: "acme", Method: "POST", Path: "/v1/files", Action: "create", ResourceType: "file", ResourceID: "file_1", Status: 200},
+		{ID: "audit_2", CreatedAt: base.Add(time.Minute), Actor: "key_b", Method: "DELETE", Path: "/v1/files/file_1", Action: "delete", ResourceType: "file", ResourceID: "file_1", Status: 200},
+		{ID: "audit_3", CreatedAt: base.Add(2 * time.Minute), Actor: "key_a", Tenant: "acme", Method: "POST", Path: "/v1/prompts", Action: "create", ResourceType: "prompt", ResourceID: "pmpt_1", Status: 200},
+	}
+	for _, e := range events {
+		if err := s.AppendAuditEvent(ctx, e); err != nil {
+			t.Fatalf("AppendAuditEvent(%s): %v", e.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  state.AuditFilter
+		after   string
+		limit   int
+		want    []string
+		hasMore bool
+	}{
+		{name: "all newest first", want: []string{"audit_3", "audit_2", "audit_1"}},
+		{name: "by actor", filter: state.AuditFilter{Actor: "key_a"}, want: []string{"audit_3", "audit_1"}},
+		{name: "by resource", filter: state.AuditFilter{ResourceType: "file", ResourceID: "file_1"}, want: []string{"audit_2", "audit_1"}},
+		{name: "by action and tenant", filter: state.AuditFilter{Action: "create", Tenant: "acme"}, want: []string{"audit_3", "audit_1"}},
+		{name: "created after", filter: state.AuditFilter{CreatedAfter: base.Add(30 * time.Second)}, want: []string{"audit_3", "audit_2"}},
+		{name: "first page", limit: 2, want: []string{"audit_3", "audit_2"}, hasMore: true},
+		{name: "next page", after: "audit_2", limit: 2, want: []string{"audit_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasMore, err := s.ListAuditEvents(ctx, tt.filter, tt.after, tt.limit)
+			if err != nil {
+				t.Fatalf("ListAuditEvents: %v", err)
+			}
+			var ids []string
+			for _, e := range got {
+				ids = append(ids, e.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) || hasMore != tt.hasMore {
+				t.Errorf("got %v (has_more %v), want %v (has_more %v)", ids, hasMore, tt.want, tt.hasMore)
+			}
+		})
+	}
+
+	got, _, err := s.ListAuditEvents(ctx, state.AuditFilter{ResourceID: "pmpt_1"}, "", 0)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ListAuditEvents = %v, %v", got, err)
+	}
+	if e := got[0]; e.Actor != "key_a" || e.Path != "/v1/prompts" || e.Status != 200 || !e.CreatedAt.Equal(events[2].CreatedAt) {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := s.GetCachedResponse(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Get(missing) = %v, %v, want miss", ok, err)
+	}
+
+	if err := s.PutCachedResponse(ctx, "k1", []byte(`{"a":1}`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.PutCachedResponse(ctx, "k1", []byte(`{"a":2}`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put(replace): %v", err)
+	}
+	value, ok, err := s.GetCachedResponse(ctx, "k1")
+	if err != nil || !ok || string(value) != `{"a":2}` {
+		t.Errorf("Get(k1) = %s, %v, %v, want replaced value", value, ok, err)
+	}
+
+	if err := s.PutCachedResponse(ctx, "k2", []byte(`{}`), time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Put(expired): %v", err)
+	}
+	if _, ok, err := s.GetCachedResponse(ctx, "k2"); err != nil || ok {
+		t.Errorf("Get(expired) = %v, %v, want miss", ok, err)
+	}
+}
+
+func TestRecordings(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := s.GetRecording(ctx, "resp-rec"); err != nil || ok {
+		t.Fatalf("GetRecording(missing) = %v, %v, want none", ok, err)
+	}
+
+	_ = s.SaveResponse(ctx, makeResponse("resp-rec", "conv-1"))
+	if err := s.SaveRecording(ctx, "resp-rec", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("SaveRecording: %v", err)
+	}
+	if err := s.SaveRecording(ctx, "resp-rec", []byte(`{"a":2}`)); err != nil {
+		t.Fatalf("SaveRecording(replace): %v", err)
+	}
+	recording, ok, err := s.GetRecording(ctx, "resp-rec")
+	if err != nil || !ok || string(recording) != `{"a":2}` {
+		t.Errorf("GetRecording = %s, %v, %v, want replaced recording", recording, ok, err)
+	}
+
+	if err := s.DeleteResponse(ctx, "resp-rec"); err != nil {
+		t.Fatalf("DeleteResponse: %v", err)
+	}
+	if _, ok, err := s.GetRecording(ctx, "resp-rec"); err != nil || ok {
+		t.Errorf("GetRecording after delete = %v, %v, want none", ok, err)
+	}
+}
+
+func TestShadowResults(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	_ = s.SaveResponse(ctx, makeResponse("resp-shadow-1", "conv-1"))
+	_ = s.SaveResponse(ctx, makeResponse("resp-shadow-2", "conv-1"))
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, r := range []struct{ id, responseID, model string }{
+		{"shadow-1", "resp-shadow-1", "m1"},
+		{"shadow-2", "resp-shadow-1", "m1"},
+		{"shadow-3", "resp-shadow-2", "m2"},
+	} {
+		if err := s.SaveShadowResult(ctx, &state.ShadowResult{
+			ID:         r.id,
+			CreatedAt:  base.Add(time.Duration(i) * time.Second),
+			ResponseID: r.responseID,
+			Model:      r.model,
+			Data:       []byte(`{"call":` + fmt.Sprint(i) + `}`),
+		}); err != nil {
+			t.Fatalf("SaveShadowResult(%s): %v", r.id, err)
+		}
+	}
+
+	results, hasMore, err := s.ListShadowResults(ctx, state.ShadowFilter{}, "", 2)
+	if err != nil || !hasMore || len(results) != 2 || results[0].ID != "shadow-3" || string(results[0].Data) != `{"call":2}` {
+		t.Fatalf("ListShadowResults = %+v, %v, %v, want the newest two", results, hasMore, err)
+	}
+	results, hasMore, err = s.ListShadowResults(ctx, state.ShadowFilter{}, results[1].ID, 2)
+	if err != nil || hasMore || len(results) != 1 || results[0].ID != "shadow-1" {
+		t.Errorf("ListShadowResults(after) = %+v, %v, %v, want shadow-1", results, hasMore, err)
+	}
+	results, _, err = s.ListShadowResults(ctx, state.ShadowFilter{Model: "m2"}, "", 10)
+	if err != nil || len(results) != 1 || results[0].ResponseID != "resp-shadow-2" {
+		t.Errorf("ListShadowResults(model) = %+v, %v", results, err)
+	}
+
+	if err := s.DeleteResponse(ctx, "resp-shadow-1"); err != nil {
+		t.Fatalf("DeleteResponse: %v", err)
+	}
+	results, _, err = s.ListShadowResults(ctx, state.ShadowFilter{ResponseID: "resp-shadow-1"}, "", 10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("ListShadowResults after delete = %+v, %v, want none", results, err)
+	}
+}
+
+func TestConversationLock(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if err := s.LockConversation(ctx, "conv-1", "resp-a", time.Hour); err != nil {
+		t.Fatalf("Lock(resp-a): %v", err)
+	}
+	if err := s.LockConversation(ctx, "conv-1", "resp-b", time.Hour); !errors.Is(err, state.ErrConversationLocked) {
+		t.Fatalf("Lock(resp-b) = %v, want ErrConversationLocked", err)
+	}
+	if err := s.LockConversation(ctx, "conv-2", "resp-b", time.Hour); err != nil {
+		t.Fatalf("Lock(conv-2): %v", err)
+	}
+
+	// Only the holder can unlock
+	if err := s.UnlockConversation(ctx, "conv-1", "resp-b"); err != nil {
+		t.Fatalf("Unlock(resp-b): %v", err)
+	}
+	if err := s.LockConversation(ctx, "conv-1", "resp-b", time.Hour); !errors.Is(err, state.ErrConversationLocked) {
+		t.Fatalf("Lock(resp-b) after a foreign unlock = %v, want ErrConversationLocked", err)
+	}
+	if err := s.UnlockConversation(ctx, "conv-1", "resp-a"); err != nil {
+		t.Fatalf("Unlock(resp-a): %v", err)
+	}
+	if err := s.LockConversation(ctx, "conv-1", "resp-b", -time.Second); err != nil {
+		t.Fatalf("Lock(resp-b) after unlock: %v", err)
+	}
+
+	// An expired lock is taken over
+	if err := s.LockConversation(ctx, "conv-1", "resp-c", time.Hour); err != nil {
+		t.Errorf("Lock(resp-c) over an expired lock: %v", err)
+	}
+}
+
+func TestPayloadEncryption(t *testing.T) {
+	ctx := context.Background()
+	oldKey := bytes.Repeat([]byte{1}, secrets.KeySize)
+	newKey := bytes.Repeat([]byte{2}, secrets.KeySize)
+
+	s := newTestStore(t)
+
+	// Written before encryption is turned on
+	plain := makeResponse("resp-plain", "")
+	plain.Request = map[string]interface{}{"model": "gpt", "input": "plain secret"}
+	if err := s.SaveResponse(ctx, plain); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+
+	keys, _ := secrets.NewKeyring(oldKey)
+	s.SetKeyring(keys)
+	if err := s.CreateConversation(ctx, makeConversation("conv-1", "")); err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	if err := s.AddConversationItems(ctx, "conv-1", []state.Message{{ID: "msg-1", Role: "user", Content: "item secret", CreatedAt: time.Now()}}); err != nil {
+		t.Fatalf("AddConversationItems: %v", err)
+	}
+	sealed := makeResponse("resp-sealed", "conv-1")
+	sealed.Request = map[string]interface{}{"model": "gpt", "input": "request secret", "metadata": map[string]interface{}{"user": "u1"}}
+	sealed.Output = []interface{}{"output secret"}
+	sealed.Messages = []state.ConversationMessage{{Role: "user", Content: "history secret"}}
+	if err := s.SaveResponse(ctx, sealed); err != nil {
+		t.Fatalf("SaveResponse: %v", err)
+	}
+	if err := s.PutCachedResponse(ctx, "key-1", []byte(`"cache secret"`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PutCachedResponse: %v", err)
+	}
+
+	// Nothing written with the key is readable in the database
+	for _, q := range []string{
+		`SELECT request || output || messages FROM responses WHERE id = 'resp-sealed'`,
+		`SELECT content FROM messages WHERE id = 'msg-1'`,
+		`SELECT value FROM response_cache WHERE key = 'key-1'`,
+	} {
+		var raw string
+		if err := s.db.QueryRow(q).Scan(&raw); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if strings.Contains(raw, "secret") {
+			t.Errorf("%s returned plaintext %q", q, raw)
+		}
+	}
+
+	// Reads decrypt, and filters still work on encrypted requests
+	got, err := s.GetResponse(ctx, "resp-sealed")
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if got.Request.(map[string]interface{})["input"] != "request secret" || len(got.Messages) != 1 {
+		t.Errorf("GetResponse = %+v", got)
+	}
+	resps, _, err := s.ListResponsesPaginated(ctx, "", "", 0, "", state.ResponseFilter{Model: "gpt", Metadata: map[string]string{"user": "u1"}})
+	if err != nil || len(resps) != 1 || resps[0].ID != "resp-sealed" {
+		t.Errorf("ListResponsesPaginated = %d responses, %v", len(resps), err)
+	}
+	if _, err := s.GetResponse(ctx, "resp-plain"); err != nil {
+		t.Errorf("plain response unreadable: %v", err)
+	}
+
+	// After a rotation, every payload is rewritten with the new key
+	rotated, _ := secrets.NewKeyring(newKey, oldKey)
+	s.SetKeyring(rotated)
+	n, err := s.ReencryptPayloads(ctx)
+	if err != nil {
+		t.Fatalf("ReencryptPayloads: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("ReencryptPayloads rewrote %d rows, want 4", n)
+	}
+	if n, _ := s.ReencryptPayloads(ctx); n != 0 {
+		t.Errorf("second ReencryptPayloads rewrote %d rows", n)
+	}
+
+	only, _ := secrets.NewKeyring(newKey)
+	s.SetKeyring(only)
+	for _, id := range []string{"resp-plain", "resp-sealed"} {
+		if _, err := s.GetResponse(ctx, id); err != nil {
+			t.Errorf("GetResponse(%s) with the new key only: %v", id, err)
+		}
+	}
+	items, _, err := s.ListConversationItems(ctx, "conv-1", "", "", 0, "asc")
+	if err != nil || len(items) != 1 || items[0].Content != "item secret" {
+		t.Errorf("ListConversationItems = %+v, %v", items, err)
+	}
+	if value, ok, err := s.GetCachedResponse(ctx, "key-1"); err != nil || !ok || string(value) != `"cache secret"` {
+		t.Errorf("GetCachedResponse = %q, %v, %v", value, ok, err)
+	}
+
+	// Without a key, encrypted payloads cannot be read
+	s.SetKeyring(nil)
+	if _, err := s.GetResponse(ctx, "resp-sealed"); err == nil {
+		t.Error("expected error reading an encrypted response without a key")
+	}
+}
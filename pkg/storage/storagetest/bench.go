@@ -0,0 +1,133 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package storagetest provides shared benchmarks for state.SessionStore
+// implementations. Each backend should call RunBenchmarks from its own
+// _test.go file.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leseb/openresponses-gw/pkg/core/state"
+)
+
+// StoredResponses are the store sizes the listing benchmarks run at.
+var StoredResponses = []int{1000, 10000}
+
+// RunBenchmarks measures SaveResponse and the paginated response listing
+// of a SessionStore. The newStore function is called once per benchmark to
+// provide an empty store.
+func RunBenchmarks(b *testing.B, newStore func(b testing.TB) state.SessionStore) {
+	b.Run("SaveResponse", func(b *testing.B) {
+		s := newStore(b)
+		ctx := context.Background()
+		base := time.Now()
+		durations := make([]time.Duration, 0, b.N)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			resp := makeResponse(i, base)
+			start := time.Now()
+			if err := s.SaveResponse(ctx, resp); err != nil {
+				b.Fatalf("SaveResponse: %v", err)
+			}
+			durations = append(durations, time.Since(start))
+		}
+		b.StopTimer()
+		ReportLatency(b, durations)
+	})
+
+	for _, n := range StoredResponses {
+		b.Run(fmt.Sprintf("ListResponsesPaginated/stored=%d", n), func(b *testing.B) {
+			s := newStore(b)
+			ctx := context.Background()
+			base := time.Now().Add(-time.Duration(n) * time.Second)
+			for i := 0; i < n; i++ {
+				if err := s.SaveResponse(ctx, makeResponse(i, base)); err != nil {
+					b.Fatalf("SaveResponse: %v", err)
+				}
+			}
+
+			// The cursor of the middle page, as a client walking the
+			// listing would send it
+			middle := fmt.Sprintf("resp_%08d", n/2)
+			queries := []struct {
+				name   string
+				after  string
+				filter state.ResponseFilter
+			}{
+				{name: "first_page"},
+				{name: "middle_page", after: middle},
+				{name: "filtered", filter: state.ResponseFilter{Model: "model-b"}},
+			}
+			for _, q := range queries {
+				b.Run(q.name, func(b *testing.B) {
+					durations := make([]time.Duration, 0, b.N)
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						start := time.Now()
+						page, _, err := s.ListResponsesPaginated(ctx, q.after, "", 20, "desc", q.filter)
+						if err != nil {
+							b.Fatalf("ListResponsesPaginated: %v", err)
+						}
+						if len(page) != 20 {
+							b.Fatalf("ListResponsesPaginated returned %d responses, want 20", len(page))
+						}
+						durations = append(durations, time.Since(start))
+					}
+					b.StopTimer()
+					ReportLatency(b, durations)
+				})
+			}
+		})
+	}
+}
+
+// makeResponse returns the i-th stored response of a benchmark, with an
+// output and history of typical size. Responses alternate between two
+// models so that filters select half of them.
+func makeResponse(i int, base time.Time) *state.Response {
+	model := "model-a"
+	if i%2 == 1 {
+		model = "model-b"
+	}
+	completed := base.Add(time.Duration(i)*time.Second + 500*time.Millisecond)
+	return &state.Response{
+		ID:             fmt.Sprintf("resp_%08d", i),
+		ConversationID: fmt.Sprintf("conv_%08d", i/10),
+		Status:         "completed",
+		Request:        map[string]interface{}{"model": model, "input": "What are the opening hours of the store?"},
+		Output: []interface{}{map[string]interface{}{
+			"type": "message", "id": fmt.Sprintf("msg_%08d", i), "role": "assistant", "status": "completed",
+			"content": []interface{}{map[string]interface{}{
+				"type": "output_text", "text": "The store is open from 9am to 6pm, Monday to Saturday.",
+			}},
+		}},
+		Usage: map[string]interface{}{"input_tokens": 12, "output_tokens": 16, "total_tokens": 28},
+		Messages: []state.ConversationMessage{
+			{Role: "user", Content: "What are the opening hours of the store?"},
+			{Role: "assistant", Content: "The store is open from 9am to 6pm, Monday to Saturday."},
+		},
+		CreatedAt:   base.Add(time.Duration(i) * time.Second),
+		CompletedAt: &completed,
+	}
+}
+
+// ReportLatency reports the p50 and p99 of the durations of the operations
+// of a benchmark.
+func ReportLatency(b *testing.B, durations []time.Duration) {
+	b.Helper()
+	if len(durations) == 0 {
+		return
+	}
+	slices.Sort(durations)
+	b.ReportMetric(float64(durations[len(durations)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(durations[len(durations)*99/100].Nanoseconds()), "p99-ns")
+}
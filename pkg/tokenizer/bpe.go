@@ -0,0 +1,124 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// BPE is a byte-pair encoding tokenizer. It is safe for concurrent use.
+type BPE struct {
+	ranks    map[string]int
+	splitter splitter
+}
+
+// LoadBPE loads the ranks of a tiktoken encoding from a vocabulary file in
+// tiktoken's format: one token per line, base64-encoded, followed by a
+// space and its rank.
+func LoadBPE(encoding, path string) (*BPE, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: %w", err)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("tokenizer: %s:%d: expected \"<base64 token> <rank>\"", path, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: %s:%d: %w", path, line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: %s:%d: invalid rank: %w", path, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tokenizer: %w", err)
+	}
+	return NewBPE(encoding, ranks)
+}
+
+// NewBPE creates a tokenizer from the ranks of its tokens, which must
+// include every single byte. The encoding selects how text is split before
+// it is encoded.
+func NewBPE(encoding string, ranks map[string]int) (*BPE, error) {
+	var s splitter
+	switch encoding {
+	case "cl100k_base":
+		s = cl100kSplitter
+	case "o200k_base":
+		s = o200kSplitter
+	case "p50k_base", "r50k_base":
+		s = gpt2Splitter
+	default:
+		return nil, fmt.Errorf("tokenizer: unknown BPE encoding %q", encoding)
+	}
+	for b := range 256 {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("tokenizer: vocabulary has no token for byte 0x%02x", b)
+		}
+	}
+	return &BPE{ranks: ranks, splitter: s}, nil
+}
+
+// Encode returns the tokens of text. Special tokens such as <|endoftext|>
+// are encoded as ordinary text.
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	b.splitter.split(text, func(piece string) {
+		tokens = b.encodePiece([]byte(piece), tokens)
+	})
+	return tokens
+}
+
+// CountTokens implements TokenCounter.
+func (b *BPE) CountTokens(text string) int {
+	return len(b.Encode(text))
+}
+
+// encodePiece appends the tokens of a piece to tokens, repeatedly merging
+// the adjacent pair of parts with the lowest rank, as tiktoken does.
+func (b *BPE) encodePiece(piece []byte, tokens []int) []int {
+	if rank, ok := b.ranks[string(piece)]; ok {
+		return append(tokens, rank)
+	}
+
+	// bounds[i] is where the i-th part starts; the last entry is the end
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = slices.Delete(bounds, best+1, best+2)
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		tokens = append(tokens, b.ranks[string(piece[bounds[i]:bounds[i+1]])])
+	}
+	return tokens
+}
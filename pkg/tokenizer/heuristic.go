@@ -0,0 +1,30 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tokenizer
+
+import "unicode/utf8"
+
+// Heuristic estimates token counts without a vocabulary. Text is split like
+// cl100k_base, then each piece counts one token per four ASCII characters
+// (at least one) plus one per other character. Common English words are
+// single tokens in real vocabularies, so the estimate errs high, which is
+// the safe side for budgets and context limits.
+type Heuristic struct{}
+
+// CountTokens implements TokenCounter.
+func (Heuristic) CountTokens(text string) int {
+	n := 0
+	cl100kSplitter.split(text, func(piece string) {
+		ascii := 0
+		for _, r := range piece {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				n++
+			}
+		}
+		n += (ascii + 3) / 4
+	})
+	return n
+}
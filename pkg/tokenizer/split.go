@@ -0,0 +1,78 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tokenizer
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// The pre-tokenization patterns of the tiktoken encodings, which split text
+// into the pieces that are then byte-pair encoded.
+//
+// tiktoken's patterns end with `\s+(?!\S)|\s+`, and their \s is Unicode
+// whitespace. RE2 has no lookahead, so the final `\s+` is captured and
+// splitter trims it as the lookahead would; ws spells out Unicode
+// whitespace, since RE2's \s is ASCII only.
+const (
+	ws = `\s\v\x{85}\p{Z}`
+
+	contractions = `'s|'t|'re|'ve|'m|'ll|'d`
+
+	cl100kPattern = `(?i:` + contractions + `)` +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + ws + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + ws + `]*[\r\n]+` +
+		`|([` + ws + `]+)`
+
+	o200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:` + contractions + `)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:` + contractions + `)?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + ws + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + ws + `]*[\r\n]+` +
+		`|([` + ws + `]+)`
+
+	gpt2Pattern = contractions +
+		`| ?\p{L}+` +
+		`| ?\p{N}+` +
+		`| ?[^` + ws + `\p{L}\p{N}]+` +
+		`|([` + ws + `]+)`
+)
+
+// splitter splits text into pre-tokenization pieces.
+type splitter struct {
+	re *regexp.Regexp
+}
+
+var (
+	cl100kSplitter = splitter{regexp.MustCompile(cl100kPattern)}
+	o200kSplitter  = splitter{regexp.MustCompile(o200kPattern)}
+	gpt2Splitter   = splitter{regexp.MustCompile(gpt2Pattern)}
+)
+
+// split calls fn with each piece of text, in order.
+func (s splitter) split(text string, fn func(piece string)) {
+	for len(text) > 0 {
+		m := s.re.FindStringSubmatchIndex(text)
+		if m == nil {
+			// Unreachable: every character matches some alternative
+			fn(text)
+			return
+		}
+		start, end := m[0], m[1]
+		if start > 0 {
+			fn(text[:start])
+		}
+		// `\s+(?!\S)`: a whitespace run followed by text leaves its last
+		// character to the next piece
+		if m[2] >= 0 && end < len(text) {
+			if _, size := utf8.DecodeLastRuneInString(text[start:end]); end-start > size {
+				end -= size
+			}
+		}
+		fn(text[start:end])
+		text = text[end:]
+	}
+}
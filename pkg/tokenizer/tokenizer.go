@@ -0,0 +1,37 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tokenizer counts the tokens of a text, so the gateway can reason
+// about context size before calling a backend.
+//
+// BPE implements the byte-pair encodings used by OpenAI models and is
+// compatible with tiktoken: given the same vocabulary file, it produces the
+// same tokens. The vocabulary files (cl100k_base.tiktoken,
+// o200k_base.tiktoken, ...) are not bundled. Heuristic estimates counts
+// without a vocabulary, for backends whose tokenizer is unknown.
+package tokenizer
+
+import "fmt"
+
+// TokenCounter counts the tokens of a text.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// Encodings lists the supported encoding names.
+var Encodings = []string{"heuristic", "cl100k_base", "o200k_base", "p50k_base", "r50k_base"}
+
+// New returns the counter for an encoding. BPE encodings load their ranks
+// from vocabFile; "heuristic", or an empty name, needs no vocabulary.
+func New(encoding, vocabFile string) (TokenCounter, error) {
+	switch encoding {
+	case "", "heuristic":
+		return Heuristic{}, nil
+	case "cl100k_base", "o200k_base", "p50k_base", "r50k_base":
+		if vocabFile == "" {
+			return nil, fmt.Errorf("tokenizer: encoding %s needs a vocabulary file", encoding)
+		}
+		return LoadBPE(encoding, vocabFile)
+	}
+	return nil, fmt.Errorf("tokenizer: unknown encoding %q (available: %v)", encoding, Encodings)
+}
@@ -0,0 +1,159 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		splitter splitter
+		text     string
+		want     []string
+	}{
+		{"cl100k words", cl100kSplitter, "Hello world", []string{"Hello", " world"}},
+		{"cl100k contraction", cl100kSplitter, "I'm here", []string{"I", "'m", " here"}},
+		{"cl100k numbers", cl100kSplitter, "12345", []string{"123", "45"}},
+		{"cl100k punctuation", cl100kSplitter, "hi!!\nok", []string{"hi", "!!\n", "ok"}},
+		{"cl100k whitespace before word", cl100kSplitter, "a   b", []string{"a", "  ", " b"}},
+		{"cl100k trailing whitespace", cl100kSplitter, "a   ", []string{"a", "   "}},
+		{"cl100k newlines", cl100kSplitter, "a\n\n  b", []string{"a", "\n\n", " ", " b"}},
+		{"cl100k unicode whitespace", cl100kSplitter, "a  b", []string{"a", " ", " b"}},
+		{"o200k camel case", o200kSplitter, "HelloWorld", []string{"Hello", "World"}},
+		{"o200k contraction", o200kSplitter, "I'm here", []string{"I'm", " here"}},
+		{"o200k path", o200kSplitter, "a/b", []string{"a", "/b"}},
+		{"gpt2 numbers", gpt2Splitter, "x 12345", []string{"x", " 12345"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			tt.splitter.split(tt.text, func(piece string) { got = append(got, piece) })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// testRanks returns a vocabulary of every byte, ranked by value, plus a few
+// merges.
+func testRanks() map[string]int {
+	ranks := make(map[string]int)
+	for b := range 256 {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	for i, token := range []string{"ab", "cd", "abcd", " a"} {
+		ranks[token] = 256 + i
+	}
+	return ranks
+}
+
+func TestBPE_Encode(t *testing.T) {
+	bpe, err := NewBPE("cl100k_base", testRanks())
+	if err != nil {
+		t.Fatalf("NewBPE: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"", nil},
+		{"abcd", []int{258}},            // whole piece is a token
+		{"abcde", []int{258, 'e'}},      // ab, then cd, then abcd
+		{"xabd", []int{'x', 256, 'd'}},  // no merge for "bd"
+		{"ab ab", []int{256, ' ', 256}}, // "ab" outranks " a"
+		{"é", []int{0xc3, 0xa9}},        // bytes of a multi-byte rune
+		{"cdcd", []int{257, 257}},       // equal ranks merge left to right
+		{"abab", []int{256, 256}},       // no "abab" token
+		{" a", []int{259}},
+	}
+	for _, tt := range tests {
+		got := bpe.Encode(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+	if n := bpe.CountTokens("abcde"); n != 2 {
+		t.Errorf("CountTokens = %d, want 2", n)
+	}
+}
+
+func TestNewBPE_Errors(t *testing.T) {
+	if _, err := NewBPE("unknown", testRanks()); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+	ranks := testRanks()
+	delete(ranks, "z")
+	if _, err := NewBPE("cl100k_base", ranks); err == nil {
+		t.Error("expected error for vocabulary missing a byte")
+	}
+}
+
+func TestLoadBPE(t *testing.T) {
+	var sb strings.Builder
+	for token, rank := range testRanks() {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	counter, err := New("cl100k_base", path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := counter.(*BPE).Encode("abcde"); !reflect.DeepEqual(got, []int{258, 'e'}) {
+		t.Errorf("Encode = %v", got)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.tiktoken")
+	os.WriteFile(bad, []byte("YWI= notarank\n"), 0o600)
+	if _, err := LoadBPE("cl100k_base", bad); err == nil {
+		t.Error("expected error for invalid rank")
+	}
+	if _, err := New("cl100k_base", ""); err == nil {
+		t.Error("expected error for missing vocabulary file")
+	}
+	if _, err := New("gpt-5", ""); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello world", 4},  // "Hello" + " world"
+		{"The cat sat.", 4}, // "The" + " cat" + " sat" + "."
+		{"information", 3},  // one long word
+		{"你好", 2},           // one per non-ASCII character
+		{"{\"a\": 1}", 6},   // "{\"", "a", "\":", " ", "1", "}"
+		{strings.Repeat("a", 400), 100},
+	}
+	for _, tt := range tests {
+		if got := (Heuristic{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+
+	counter, err := New("", "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, ok := counter.(Heuristic); !ok {
+		t.Errorf("New(\"\") = %T, want Heuristic", counter)
+	}
+}
@@ -21,6 +21,7 @@ type Chunk struct {
 	FileID        string
 	VectorStoreID string
 	Content       string
+	Offset        int // byte offset of Content in the text extracted from the file
 	Vector        []float32
 }
 
@@ -53,3 +54,31 @@ type Backend interface {
 	// Close releases any resources held by the backend.
 	Close(ctx context.Context) error
 }
+
+// StoredChunk is a chunk as stored in a backend, for inspection.
+type StoredChunk struct {
+	ChunkID    string
+	FileID     string
+	Content    string
+	Offset     *int // nil when the backend did not record it
+	Dimensions int  // of the chunk's embedding
+}
+
+// ChunkLister is implemented by backends that can list the chunks they
+// store, so that users can see what was indexed for a file.
+type ChunkLister interface {
+	// ListFileChunks returns the chunks of a file in a vector store, in
+	// file order.
+	ListFileChunks(ctx context.Context, vectorStoreID, fileID string) ([]StoredChunk, error)
+}
+
+// Inventory is implemented by backends that can enumerate their contents.
+// The garbage collector uses it to find data that no vector store or file
+// refers to anymore; backends without it are skipped.
+type Inventory interface {
+	// ListStores returns the IDs of all vector stores provisioned in the backend.
+	ListStores(ctx context.Context) ([]string, error)
+
+	// ListFileIDs returns the distinct file IDs that have chunks in a vector store.
+	ListFileIDs(ctx context.Context, vectorStoreID string) ([]string, error)
+}
@@ -9,10 +9,27 @@ const DefaultChunkSize = 800
 // DefaultChunkOverlap is the default overlap between chunks in characters.
 const DefaultChunkOverlap = 200
 
+// TextChunk is a chunk of text and its byte offset in the text it was cut
+// from.
+type TextChunk struct {
+	Text   string
+	Offset int
+}
+
 // ChunkText splits text into fixed-size chunks with configurable overlap.
 // chunkSize and overlap are in characters. If chunkSize <= 0, DefaultChunkSize is used.
 // If overlap < 0 or >= chunkSize, DefaultChunkOverlap is used (clamped to < chunkSize).
 func ChunkText(text string, chunkSize, overlap int) []string {
+	var chunks []string
+	for _, c := range SplitText(text, chunkSize, overlap) {
+		chunks = append(chunks, c.Text)
+	}
+	return chunks
+}
+
+// SplitText splits text like ChunkText and also returns the offset of
+// each chunk.
+func SplitText(text string, chunkSize, overlap int) []TextChunk {
 	if chunkSize <= 0 {
 		chunkSize = DefaultChunkSize
 	}
@@ -27,7 +44,7 @@ func ChunkText(text string, chunkSize, overlap int) []string {
 		return nil
 	}
 
-	var chunks []string
+	var chunks []TextChunk
 	step := chunkSize - overlap
 	if step <= 0 {
 		step = 1
@@ -38,7 +55,7 @@ func ChunkText(text string, chunkSize, overlap int) []string {
 		if end > len(text) {
 			end = len(text)
 		}
-		chunks = append(chunks, text[start:end])
+		chunks = append(chunks, TextChunk{Text: text[start:end], Offset: start})
 		if end == len(text) {
 			break
 		}
@@ -223,3 +223,19 @@ func TestTokensToChars(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitText_Offsets(t *testing.T) {
+	text := "abcdefghijklmnopqrstuvwxyz"
+	chunks := SplitText(text, 10, 4)
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Offset != i*6 {
+			t.Errorf("chunk %d offset = %d, want %d", i, c.Offset, i*6)
+		}
+		if text[c.Offset:c.Offset+len(c.Text)] != c.Text {
+			t.Errorf("chunk %d text %q is not at its offset", i, c.Text)
+		}
+	}
+}
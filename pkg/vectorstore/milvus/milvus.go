@@ -4,8 +4,13 @@
 package milvus
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/leseb/openresponses-gw/pkg/vectorstore"
@@ -24,10 +29,22 @@ const (
 	fieldFileID    = "file_id"
 	fieldContent   = "content"
 	fieldEmbedding = "embedding"
+	fieldOffset    = "chunk_offset" // absent from collections created before it was added
 
 	maxContentLength = 65535
 	maxChunkIDLength = 256
 	maxFileIDLength  = 256
+
+	// vectorStorePrefix is the prefix of gateway-generated vector store IDs.
+	// Collections without it are not managed by the gateway.
+	vectorStorePrefix = "vs_"
+
+	inventoryBatchSize = 1000
+)
+
+var (
+	_ vectorstore.Inventory   = (*Backend)(nil)
+	_ vectorstore.ChunkLister = (*Backend)(nil)
 )
 
 // Backend implements vectorstore.Backend using Milvus.
@@ -73,6 +90,9 @@ func (b *Backend) CreateStore(ctx context.Context, vectorStoreID string, dimensi
 			WithName(fieldContent).
 			WithDataType(entity.FieldTypeVarChar).
 			WithMaxLength(int64(maxContentLength))).
+		WithField(entity.NewField().
+			WithName(fieldOffset).
+			WithDataType(entity.FieldTypeInt64)).
 		WithField(entity.NewField().
 			WithName(fieldEmbedding).
 			WithDataType(entity.FieldTypeFloatVector).
@@ -124,10 +144,15 @@ func (b *Backend) InsertChunks(ctx context.Context, chunks []vectorstore.Chunk)
 	}
 
 	coll := collectionName(chunks[0].VectorStoreID)
+	layout, err := b.describe(ctx, coll)
+	if err != nil {
+		return err
+	}
 
 	chunkIDs := make([]string, len(chunks))
 	fileIDs := make([]string, len(chunks))
 	contents := make([]string, len(chunks))
+	offsets := make([]int64, len(chunks))
 	vectors := make([][]float32, len(chunks))
 
 	for i, c := range chunks {
@@ -138,16 +163,21 @@ func (b *Backend) InsertChunks(ctx context.Context, chunks []vectorstore.Chunk)
 			content = content[:maxContentLength]
 		}
 		contents[i] = content
+		offsets[i] = int64(c.Offset)
 		vectors[i] = c.Vector
 	}
 
 	dim := len(vectors[0])
-	_, err := b.client.Insert(ctx, coll, "",
+	columns := []entity.Column{
 		entity.NewColumnVarChar(fieldChunkID, chunkIDs),
 		entity.NewColumnVarChar(fieldFileID, fileIDs),
 		entity.NewColumnVarChar(fieldContent, contents),
 		entity.NewColumnFloatVector(fieldEmbedding, dim, vectors),
-	)
+	}
+	if layout.offsets {
+		columns = append(columns, entity.NewColumnInt64(fieldOffset, offsets))
+	}
+	_, err = b.client.Insert(ctx, coll, "", columns...)
 	if err != nil {
 		return fmt.Errorf("insert into %s: %w", coll, err)
 	}
@@ -245,6 +275,173 @@ func (b *Backend) Search(ctx context.Context, vectorStoreID string, queryVector
 	return out, nil
 }
 
+// Ping checks that Milvus is reachable and reports itself healthy.
+func (b *Backend) Ping(ctx context.Context) error {
+	state, err := b.client.CheckHealth(ctx)
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+	if !state.IsHealthy {
+		return fmt.Errorf("milvus unhealthy: %s", strings.Join(state.Reasons, "; "))
+	}
+	return nil
+}
+
+// ListStores returns the vector store IDs of all gateway-managed collections.
+func (b *Backend) ListStores(ctx context.Context) ([]string, error) {
+	colls, err := b.client.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list collections: %w", err)
+	}
+
+	var ids []string
+	for _, c := range colls {
+		if strings.HasPrefix(c.Name, vectorStorePrefix) {
+			ids = append(ids, c.Name)
+		}
+	}
+	return ids, nil
+}
+
+// ListFileIDs returns the distinct file IDs that have chunks in the
+// collection for the given vector store.
+func (b *Backend) ListFileIDs(ctx context.Context, vectorStoreID string) ([]string, error) {
+	coll := collectionName(vectorStoreID)
+
+	exists, err := b.client.HasCollection(ctx, coll)
+	if err != nil {
+		return nil, fmt.Errorf("check collection %s: %w", coll, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	itr, err := b.client.QueryIterator(ctx, milvusclient.NewQueryIteratorOption(coll).
+		WithOutputFields(fieldFileID).
+		WithBatchSize(inventoryBatchSize))
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", coll, err)
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for {
+		rs, err := itr.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", coll, err)
+		}
+		col := rs.GetColumn(fieldFileID)
+		if col == nil {
+			continue
+		}
+		for i := 0; i < col.Len(); i++ {
+			id, _ := col.GetAsString(i)
+			if id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// ListFileChunks returns the chunks of a file in the collection for the
+// given vector store, ordered by offset. Collections created before offsets
+// were recorded are ordered by chunk ID and have no offsets.
+func (b *Backend) ListFileChunks(ctx context.Context, vectorStoreID, fileID string) ([]vectorstore.StoredChunk, error) {
+	coll := collectionName(vectorStoreID)
+
+	exists, err := b.client.HasCollection(ctx, coll)
+	if err != nil {
+		return nil, fmt.Errorf("check collection %s: %w", coll, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	layout, err := b.describe(ctx, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{fieldChunkID, fieldContent}
+	if layout.offsets {
+		fields = append(fields, fieldOffset)
+	}
+	itr, err := b.client.QueryIterator(ctx, milvusclient.NewQueryIteratorOption(coll).
+		WithExpr(fmt.Sprintf(`%s == "%s"`, fieldFileID, escapeExpr(fileID))).
+		WithOutputFields(fields...).
+		WithBatchSize(inventoryBatchSize))
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", coll, err)
+	}
+
+	var chunks []vectorstore.StoredChunk
+	for {
+		rs, err := itr.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", coll, err)
+		}
+		chunkIDCol := rs.GetColumn(fieldChunkID)
+		contentCol := rs.GetColumn(fieldContent)
+		offsetCol := rs.GetColumn(fieldOffset)
+		if chunkIDCol == nil || contentCol == nil {
+			continue
+		}
+		for i := 0; i < chunkIDCol.Len(); i++ {
+			chunk := vectorstore.StoredChunk{FileID: fileID, Dimensions: layout.dimensions}
+			chunk.ChunkID, _ = chunkIDCol.GetAsString(i)
+			chunk.Content, _ = contentCol.GetAsString(i)
+			if offsetCol != nil {
+				if offset, err := offsetCol.GetAsInt64(i); err == nil {
+					o := int(offset)
+					chunk.Offset = &o
+				}
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	slices.SortFunc(chunks, func(a, c vectorstore.StoredChunk) int {
+		if a.Offset != nil && c.Offset != nil {
+			return cmp.Compare(*a.Offset, *c.Offset)
+		}
+		// Chunk IDs end with the chunk's index: shorter ones come first
+		return cmp.Or(cmp.Compare(len(a.ChunkID), len(c.ChunkID)), strings.Compare(a.ChunkID, c.ChunkID))
+	})
+	return chunks, nil
+}
+
+// collectionLayout describes the fields of a collection that vary between
+// collections.
+type collectionLayout struct {
+	offsets    bool // the collection records chunk offsets
+	dimensions int  // of the embeddings
+}
+
+// describe returns the layout of a collection.
+func (b *Backend) describe(ctx context.Context, coll string) (collectionLayout, error) {
+	c, err := b.client.DescribeCollection(ctx, coll)
+	if err != nil {
+		return collectionLayout{}, fmt.Errorf("describe collection %s: %w", coll, err)
+	}
+	var layout collectionLayout
+	for _, f := range c.Schema.Fields {
+		switch f.Name {
+		case fieldOffset:
+			layout.offsets = true
+		case fieldEmbedding:
+			layout.dimensions, _ = strconv.Atoi(f.TypeParams[entity.TypeParamDim])
+		}
+	}
+	return layout, nil
+}
+
 // Close releases the Milvus client connection.
 func (b *Backend) Close(ctx context.Context) error {
 	return b.client.Close()
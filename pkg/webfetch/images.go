@@ -0,0 +1,140 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of ImageOptions.
+const (
+	DefaultImageMaxBytes   = 20 << 20
+	DefaultImageCacheBytes = 64 << 20
+	DefaultImageCacheTTL   = time.Hour
+)
+
+// ImageOptions configures an ImageFetcher. Zero values use the defaults.
+type ImageOptions struct {
+	Options // MaxBytes bounds each image; robots.txt is not checked
+
+	CacheBytes int64         // total size of the cached images; negative disables the cache
+	CacheTTL   time.Duration // how long an image is reused
+}
+
+// ImageFetcher fetches remote images and returns them as base64 data URLs,
+// for backends that cannot reach the internet. Images are cached by URL.
+// It is safe for concurrent use.
+type ImageFetcher struct {
+	fetcher *Fetcher
+	ttl     time.Duration
+	limit   int64
+	now     func() time.Time
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List               // most recently used first
+	entries map[string]*list.Element // URL → *cachedImage element of order
+}
+
+// cachedImage is an image of the cache.
+type cachedImage struct {
+	url     string
+	dataURL string
+	expires time.Time
+}
+
+// NewImageFetcher creates an image fetcher.
+func NewImageFetcher(opts ImageOptions) *ImageFetcher {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultImageMaxBytes
+	}
+	if opts.CacheBytes == 0 {
+		opts.CacheBytes = DefaultImageCacheBytes
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = DefaultImageCacheTTL
+	}
+	opts.IgnoreRobots = true
+	return &ImageFetcher{
+		fetcher: New(opts.Options),
+		ttl:     opts.CacheTTL,
+		limit:   opts.CacheBytes,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// DataURL returns the image at rawURL as a data URL. The response must be
+// an image, by its Content-Type or, when it has none, by its content.
+func (p *ImageFetcher) DataURL(ctx context.Context, rawURL string) (string, error) {
+	if dataURL, ok := p.cached(rawURL); ok {
+		return dataURL, nil
+	}
+
+	body, mediaType, err := p.fetcher.Download(ctx, rawURL, "image/*")
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = strings.Cut(http.DetectContentType(body), ";")
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("fetch %s: %s is not an image", rawURL, mediaType)
+	}
+
+	dataURL := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(body)
+	p.store(rawURL, dataURL)
+	return dataURL, nil
+}
+
+// cached returns the cached data URL of an image, if fresh.
+func (p *ImageFetcher) cached(rawURL string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elem, ok := p.entries[rawURL]
+	if !ok {
+		return "", false
+	}
+	img := elem.Value.(*cachedImage)
+	if p.now().After(img.expires) {
+		p.remove(elem)
+		return "", false
+	}
+	p.order.MoveToFront(elem)
+	return img.dataURL, true
+}
+
+// store caches an image, evicting the least recently used ones to stay
+// within the cache size. Images larger than the cache are not kept.
+func (p *ImageFetcher) store(rawURL, dataURL string) {
+	size := int64(len(dataURL))
+	if size > p.limit {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if elem, ok := p.entries[rawURL]; ok {
+		p.remove(elem)
+	}
+	for p.size+size > p.limit {
+		p.remove(p.order.Back())
+	}
+	p.entries[rawURL] = p.order.PushFront(&cachedImage{url: rawURL, dataURL: dataURL, expires: p.now().Add(p.ttl)})
+	p.size += size
+}
+
+// remove drops an image from the cache. The caller holds p.mu.
+func (p *ImageFetcher) remove(elem *list.Element) {
+	img := p.order.Remove(elem).(*cachedImage)
+	delete(p.entries, img.url)
+	p.size -= int64(len(img.dataURL))
+}
@@ -0,0 +1,93 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestImageFetcher(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "png")
+		case "/sniffed":
+			// No Content-Type: the GIF signature identifies the image
+			w.Header()["Content-Type"] = nil
+			fmt.Fprint(w, "GIF89a")
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+		case "/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, strings.Repeat("x", 100))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	p := NewImageFetcher(ImageOptions{Options: Options{AllowPrivateNetworks: true, MaxBytes: 50}})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	got, err := p.DataURL(ctx, srv.URL+"/cat.png")
+	if err != nil || got != "data:image/png;base64,cG5n" {
+		t.Fatalf("DataURL(cat.png) = %q, %v", got, err)
+	}
+	if got, err := p.DataURL(ctx, srv.URL+"/sniffed"); err != nil || got != "data:image/gif;base64,R0lGODlh" {
+		t.Errorf("DataURL(sniffed) = %q, %v", got, err)
+	}
+	for _, path := range []string{"/page", "/big.png", "/missing"} {
+		if _, err := p.DataURL(ctx, srv.URL+path); err == nil {
+			t.Errorf("DataURL(%s) succeeded, want an error", path)
+		}
+	}
+
+	// Cached images are reused until they expire
+	requests.Store(0)
+	if _, err := p.DataURL(ctx, srv.URL+"/cat.png"); err != nil || requests.Load() != 0 {
+		t.Errorf("cached DataURL made %d requests, err = %v", requests.Load(), err)
+	}
+	now = now.Add(2 * DefaultImageCacheTTL)
+	if _, err := p.DataURL(ctx, srv.URL+"/cat.png"); err != nil || requests.Load() != 1 {
+		t.Errorf("expired DataURL made %d requests, err = %v", requests.Load(), err)
+	}
+
+	// Private addresses are refused unless allowed
+	blocked := NewImageFetcher(ImageOptions{})
+	if _, err := blocked.DataURL(ctx, srv.URL+"/cat.png"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("DataURL(private) error = %v, want ErrBlocked", err)
+	}
+}
+
+func TestImageFetcher_Eviction(t *testing.T) {
+	p := NewImageFetcher(ImageOptions{CacheBytes: 10})
+	p.store("a", "12345")
+	p.store("b", "12345")
+	p.cached("a") // a is now the most recently used
+	p.store("c", "12345")
+	if _, ok := p.cached("b"); ok {
+		t.Error("b still cached, want it evicted")
+	}
+	if _, ok := p.cached("a"); !ok {
+		t.Error("a evicted, want it kept")
+	}
+	p.store("big", strings.Repeat("x", 11))
+	if _, ok := p.cached("big"); ok || p.size != 10 {
+		t.Errorf("cache holds an image larger than itself, size = %d", p.size)
+	}
+}
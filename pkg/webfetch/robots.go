@@ -0,0 +1,181 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsTTL is how long a site's robots.txt is reused.
+	robotsTTL = time.Hour
+	// maxRobotsBytes bounds the robots.txt read, as RFC 9309 allows.
+	maxRobotsBytes = 500 << 10
+	// maxRobotsSites bounds the cached robots.txt files.
+	maxRobotsSites = 1000
+)
+
+// robotsRule allows or disallows the paths matching a pattern.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the rules of a robots.txt applying to the fetcher.
+type robotsRules struct {
+	rules []robotsRule
+}
+
+var (
+	allowAll    = &robotsRules{}
+	disallowAll = &robotsRules{rules: []robotsRule{{pattern: "/"}}}
+)
+
+// allows reports whether path may be fetched: the longest matching rule
+// decides, allow winning ties, and paths no rule matches are allowed.
+func (r *robotsRules) allows(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if len(rule.pattern) < best || !matchPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || rule.allow {
+			best, allowed = len(rule.pattern), rule.allow
+		}
+	}
+	return allowed
+}
+
+// matchPattern matches path against a robots.txt path pattern, where "*"
+// matches any characters and a trailing "$" anchors the end.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if !anchored || rest == "" {
+		return true
+	}
+	// The last part must then end the path
+	last := parts[len(parts)-1]
+	return len(parts) > 1 && strings.HasSuffix(path, last)
+}
+
+// parseRobots returns the rules of a robots.txt for userAgent: those of
+// the groups naming its product token, or of the "*" groups if none does.
+func parseRobots(body []byte, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var own, wildcard []robotsRule
+	var foundOwn bool
+	var agents []string
+	inRules := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			if agent == token {
+				foundOwn = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			for _, agent := range agents {
+				switch agent {
+				case token:
+					own = append(own, rule)
+				case "*":
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+	if foundOwn {
+		return &robotsRules{rules: own}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// robotsCache keeps the robots.txt rules of recently fetched sites.
+type robotsCache struct {
+	mu    sync.Mutex
+	sites map[string]robotsEntry
+	now   func() time.Time
+}
+
+type robotsEntry struct {
+	rules   *robotsRules
+	expires time.Time
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{sites: make(map[string]robotsEntry), now: time.Now}
+}
+
+// get returns the cached rules of site, calling fetch when they are
+// missing or stale. Errors are not cached.
+func (c *robotsCache) get(site string, fetch func() (*robotsRules, error)) (*robotsRules, error) {
+	c.mu.Lock()
+	entry, ok := c.sites[site]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.rules, nil
+	}
+
+	rules, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.sites) >= maxRobotsSites {
+		now := c.now()
+		for s, e := range c.sites {
+			if !now.Before(e.expires) {
+				delete(c.sites, s)
+			}
+		}
+		if len(c.sites) >= maxRobotsSites {
+			clear(c.sites)
+		}
+	}
+	c.sites[site] = robotsEntry{rules: rules, expires: c.now().Add(robotsTTL)}
+	return rules, nil
+}
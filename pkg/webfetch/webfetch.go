@@ -0,0 +1,323 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webfetch reads web pages for the model. Fetches honor robots.txt,
+// are bounded in time and size, refuse private network addresses unless
+// allowed, follow the gateway's egress policy and return the readable text
+// of the page.
+package webfetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/leseb/openresponses-gw/pkg/egress"
+	"github.com/leseb/openresponses-gw/pkg/filestore/extractor"
+)
+
+// Defaults of Options.
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultMaxBytes  = 2 << 20
+	DefaultMaxChars  = 20000
+	DefaultUserAgent = "openresponses-gw"
+)
+
+// maxRedirects bounds the redirects followed by a fetch.
+const maxRedirects = 5
+
+var (
+	// ErrBlocked is returned for URLs the fetcher is not allowed to read.
+	// It is egress.ErrBlocked, so that the egress policy's refusals match.
+	ErrBlocked = egress.ErrBlocked
+	// ErrDisallowed is returned for URLs the site's robots.txt disallows.
+	ErrDisallowed = errors.New("disallowed by robots.txt")
+)
+
+// Options configures a Fetcher. Zero values use the defaults.
+type Options struct {
+	Timeout              time.Duration // per fetch, robots.txt included
+	MaxBytes             int64         // response body read; the rest is dropped
+	MaxChars             int           // text returned; the rest is dropped
+	UserAgent            string        // also the robots.txt user agent
+	IgnoreRobots         bool          // do not check robots.txt
+	AllowPrivateNetworks bool          // allow loopback, private and link-local addresses
+
+	// AllowURL, when set, rejects the URLs, redirects included, for which
+	// it returns false.
+	AllowURL func(rawURL string) bool
+
+	// Egress, when set, is the outbound policy fetches follow. Its private
+	// network blocking adds to AllowPrivateNetworks, and its size and time
+	// limits lower MaxBytes and Timeout.
+	Egress *egress.Policy
+}
+
+// Page is the text of a fetched page.
+type Page struct {
+	URL       string // after redirects
+	Title     string
+	Text      string
+	Truncated bool // the page was cut to the size limits
+}
+
+// Fetcher fetches web pages. It is safe for concurrent use.
+type Fetcher struct {
+	opts   Options
+	client *http.Client
+	robots *robotsCache
+}
+
+// New creates a fetcher.
+func New(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = DefaultMaxChars
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+
+	var policy egress.Policy
+	if opts.Egress != nil {
+		policy = *opts.Egress
+	}
+	policy.BlockPrivateNetworks = policy.BlockPrivateNetworks || !opts.AllowPrivateNetworks
+	if policy.MaxResponseBytes > 0 && policy.MaxResponseBytes < opts.MaxBytes {
+		opts.MaxBytes = policy.MaxResponseBytes
+	}
+	if policy.Timeout > 0 && policy.Timeout < opts.Timeout {
+		opts.Timeout = policy.Timeout
+	}
+	// Fetch truncates bodies past MaxBytes rather than failing, and bounds
+	// each fetch with Timeout itself
+	policy.MaxResponseBytes, policy.Timeout = 0, 0
+
+	f := &Fetcher{opts: opts, robots: newRobotsCache()}
+	f.client = &http.Client{
+		Transport: policy.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.check(req.Context(), req.URL)
+		},
+	}
+	return f
+}
+
+// Fetch reads the page at rawURL and returns its text. HTML, plain text,
+// JSON and PDF pages are supported.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+	if err := f.check(ctx, u); err != nil {
+		return nil, err
+	}
+
+	resp, err := f.get(ctx, u, "text/html, text/plain;q=0.9, application/json;q=0.8, application/pdf;q=0.7")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	page := &Page{URL: resp.Request.URL.String()}
+	if int64(len(body)) > f.opts.MaxBytes {
+		body = body[:f.opts.MaxBytes]
+		page.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title = htmlTitle(body)
+		page.Text, err = extractor.ExtractText(body, "page.html")
+		page.Text = strings.Join(strings.Fields(page.Text), " ")
+	case mediaType == "application/pdf":
+		page.Text, err = extractor.ExtractText(body, "page.pdf")
+	case mediaType == "application/json":
+		page.Text, err = extractor.ExtractText(body, "page.json")
+	case strings.HasPrefix(mediaType, "text/"):
+		page.Text = string(body)
+	default:
+		return nil, fmt.Errorf("fetch %s: unsupported content type %s", u, mediaType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", u, err)
+	}
+
+	if text, cut := truncate(strings.TrimSpace(page.Text), f.opts.MaxChars); cut {
+		page.Text = text
+		page.Truncated = true
+	}
+	return page, nil
+}
+
+// Download reads the resource at rawURL whole and returns it with its
+// media type. Unlike Fetch, it fails for resources larger than MaxBytes.
+func (f *Fetcher) Download(ctx context.Context, rawURL, accept string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+	if err := f.check(ctx, u); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := f.get(ctx, u, accept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read %s: %w", u, err)
+	}
+	if int64(len(body)) > f.opts.MaxBytes {
+		return nil, "", fmt.Errorf("fetch %s: larger than %d bytes", u, f.opts.MaxBytes)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return body, mediaType, nil
+}
+
+// get sends a GET request for u and returns the response if it succeeded.
+func (f *Fetcher) get(ctx context.Context, u *url.URL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept", accept)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: status %d", u, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// check returns an error if u may not be fetched.
+func (f *Fetcher) check(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlocked, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrBlocked)
+	}
+	if f.opts.AllowURL != nil && !f.opts.AllowURL(u.String()) {
+		return fmt.Errorf("%w: %s", ErrBlocked, u.Hostname())
+	}
+	if f.opts.IgnoreRobots {
+		return nil
+	}
+	rules, err := f.robots.get(u.Scheme+"://"+u.Host, func() (*robotsRules, error) {
+		return f.fetchRobots(ctx, u)
+	})
+	if err != nil {
+		return err
+	}
+	if !rules.allows(u.EscapedPath()) {
+		return fmt.Errorf("%w: %s", ErrDisallowed, u)
+	}
+	return nil
+}
+
+// fetchRobots fetches and parses the robots.txt of u's site. Following
+// RFC 9309, a missing file allows everything and an unreachable one
+// disallows everything. Only blocked addresses are errors.
+func (f *Fetcher) fetchRobots(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	robotsURL := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return disallowAll, nil
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	// robots.txt redirects are followed without being checked themselves
+	resp, err := (&http.Client{Transport: f.client.Transport}).Do(req)
+	if errors.Is(err, ErrBlocked) {
+		return nil, err
+	}
+	if err != nil {
+		return disallowAll, nil
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return disallowAll, nil
+	case resp.StatusCode >= 400:
+		return allowAll, nil
+	case resp.StatusCode != http.StatusOK:
+		return disallowAll, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return disallowAll, nil
+	}
+	return parseRobots(body, f.opts.UserAgent), nil
+}
+
+// htmlTitle returns the content of the <title> element of an HTML page.
+func htmlTitle(body []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "title" {
+				if z.Next() == html.TextToken {
+					return strings.Join(strings.Fields(string(z.Text())), " ")
+				}
+				return ""
+			}
+		}
+	}
+}
+
+// truncate cuts s to at most n characters.
+func truncate(s string, n int) (string, bool) {
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos], true
+		}
+		i++
+	}
+	return s, false
+}
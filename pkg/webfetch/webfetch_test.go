@@ -0,0 +1,149 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package webfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+User-agent: otherbot
+Disallow: /
+
+# comment
+User-agent: *
+User-agent: openresponses-gw
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow:
+`
+	tests := []struct {
+		name      string
+		body      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{name: "no rule", body: robots, userAgent: "openresponses-gw", path: "/docs", want: true},
+		{name: "disallowed", body: robots, userAgent: "openresponses-gw", path: "/private/x", want: false},
+		{name: "longer allow wins", body: robots, userAgent: "openresponses-gw", path: "/private/public/x", want: true},
+		{name: "wildcard and anchor", body: robots, userAgent: "openresponses-gw", path: "/a/b.pdf", want: false},
+		{name: "anchor does not match a longer path", body: robots, userAgent: "openresponses-gw", path: "/a/b.pdf.html", want: true},
+		{name: "own group", body: robots, userAgent: "OtherBot/1.0", path: "/docs", want: false},
+		{name: "wildcard group", body: robots, userAgent: "somebot", path: "/private", want: false},
+		{name: "empty file", body: "", userAgent: "somebot", path: "/private", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRobots([]byte(tt.body), tt.userAgent).allows(tt.path); got != tt.want {
+				t.Errorf("allows(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /secret\n")
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><head><title> Hello
+				page </title><script>var x;</script></head><body><p>Some text</p><p>more text here</p></body></html>`)
+		case "/moved":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/to-secret":
+			http.Redirect(w, r, "/secret", http.StatusFound)
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, strings.Repeat("a", 100))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		opts      Options
+		path      string
+		wantPage  Page
+		wantErrIs error
+		wantErr   bool
+	}{
+		{
+			name:     "html",
+			path:     "/page",
+			wantPage: Page{URL: srv.URL + "/page", Title: "Hello page", Text: "Hello page Some text more text here"},
+		},
+		{
+			name:     "redirect",
+			path:     "/moved",
+			wantPage: Page{URL: srv.URL + "/page", Title: "Hello page", Text: "Hello page Some text more text here"},
+		},
+		{
+			name:     "truncated by characters",
+			opts:     Options{MaxChars: 10},
+			path:     "/plain",
+			wantPage: Page{URL: srv.URL + "/plain", Text: strings.Repeat("a", 10), Truncated: true},
+		},
+		{
+			name:     "truncated by bytes",
+			opts:     Options{MaxBytes: 20},
+			path:     "/plain",
+			wantPage: Page{URL: srv.URL + "/plain", Text: strings.Repeat("a", 20), Truncated: true},
+		},
+		{name: "disallowed by robots", path: "/secret", wantErrIs: ErrDisallowed},
+		{name: "redirect to a disallowed page", path: "/to-secret", wantErrIs: ErrDisallowed},
+		{name: "robots ignored", opts: Options{IgnoreRobots: true}, path: "/secret", wantErr: true},
+		{name: "blocked url", opts: Options{AllowURL: func(string) bool { return false }}, path: "/page", wantErrIs: ErrBlocked},
+		{name: "unsupported content type", path: "/image", wantErr: true},
+		{name: "not found", path: "/missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.AllowPrivateNetworks = true
+			page, err := New(tt.opts).Fetch(context.Background(), srv.URL+tt.path)
+			if tt.wantErrIs != nil || tt.wantErr {
+				if err == nil || (tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs)) {
+					t.Fatalf("Fetch() error = %v, want %v", err, tt.wantErrIs)
+				}
+				if tt.opts.IgnoreRobots && errors.Is(err, ErrDisallowed) {
+					t.Errorf("Fetch() error = %v with robots ignored", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if *page != tt.wantPage {
+				t.Errorf("Fetch() = %+v, want %+v", *page, tt.wantPage)
+			}
+		})
+	}
+}
+
+func TestFetch_PrivateNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	for _, rawURL := range []string{srv.URL + "/page", "ftp://example.com/file", "http:///page"} {
+		if _, err := New(Options{}).Fetch(context.Background(), rawURL); !errors.Is(err, ErrBlocked) {
+			t.Errorf("Fetch(%s) error = %v, want ErrBlocked", rawURL, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const bingDefaultURL = "https://api.bing.microsoft.com/v7.0/search"
+
+func init() {
+	Providers.Register("bing", func(_ context.Context, params map[string]string) (Provider, error) {
+		apiKey := params["api_key"]
+		if apiKey == "" {
+			return nil, fmt.Errorf("bing: api_key parameter is required")
+		}
+		p := NewBingProvider(apiKey)
+		if params["base_url"] != "" {
+			p.endpoint = params["base_url"]
+		}
+		p.defaults = optionsFromParams(params)
+		return p, nil
+	})
+}
+
+// BingProvider performs web searches using the Bing Web Search API.
+type BingProvider struct {
+	apiKey     string
+	endpoint   string
+	defaults   Options
+	httpClient *http.Client
+}
+
+// NewBingProvider creates a new Bing Web Search provider.
+func NewBingProvider(apiKey string) *BingProvider {
+	return &BingProvider{
+		apiKey:     apiKey,
+		endpoint:   bingDefaultURL,
+		httpClient: &http.Client{},
+	}
+}
+
+// bingFreshness maps freshness options to Bing's values. Bing has no
+// yearly filter.
+var bingFreshness = map[string]string{"day": "Day", "week": "Week", "month": "Month"}
+
+// SetHTTPClient replaces the HTTP client used to call the API.
+func (b *BingProvider) SetHTTPClient(hc *http.Client) {
+	b.httpClient = hc
+}
+
+// Search queries the Bing Web Search API and returns results.
+func (b *BingProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	opts := resolveOptions(ctx, b.defaults)
+
+	q := url.Values{}
+	q.Set("q", siteQuery(query, opts.Sites))
+	q.Set("count", strconv.Itoa(maxResults))
+	q.Set("responseFilter", "Webpages")
+	if opts.Language != "" && opts.Country != "" {
+		q.Set("mkt", opts.locale())
+	} else if opts.Country != "" {
+		q.Set("cc", strings.ToUpper(opts.Country))
+	}
+	if f, ok := bingFreshness[opts.Freshness]; ok {
+		q.Set("freshness", f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.apiKey)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bing search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bing search returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result bingSearchResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	var results []SearchResult
+	for _, r := range result.WebPages.Value {
+		results = append(results, SearchResult{
+			Title:   r.Name,
+			URL:     r.URL,
+			Snippet: r.Snippet,
+		})
+	}
+
+	return results, nil
+}
+
+type bingSearchResponse struct {
+	WebPages struct {
+		Value []struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"value"`
+	} `json:"webPages"`
+}
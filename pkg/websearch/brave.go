@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 func init() {
@@ -19,13 +20,16 @@ func init() {
 		if apiKey == "" {
 			return nil, fmt.Errorf("brave: api_key parameter is required")
 		}
-		return NewBraveProvider(apiKey), nil
+		p := NewBraveProvider(apiKey)
+		p.defaults = optionsFromParams(params)
+		return p, nil
 	})
 }
 
 // BraveProvider performs web searches using the Brave Search API.
 type BraveProvider struct {
 	apiKey     string
+	defaults   Options
 	httpClient *http.Client
 }
 
@@ -37,12 +41,31 @@ func NewBraveProvider(apiKey string) *BraveProvider {
 	}
 }
 
+// braveFreshness maps freshness options to Brave's values.
+var braveFreshness = map[string]string{"day": "pd", "week": "pw", "month": "pm", "year": "py"}
+
+// SetHTTPClient replaces the HTTP client used to call the API.
+func (b *BraveProvider) SetHTTPClient(hc *http.Client) {
+	b.httpClient = hc
+}
+
 // Search queries the Brave Web Search API and returns results.
 func (b *BraveProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	opts := resolveOptions(ctx, b.defaults)
+
 	u, _ := url.Parse("https://api.search.brave.com/res/v1/web/search")
 	q := u.Query()
-	q.Set("q", query)
+	q.Set("q", siteQuery(query, opts.Sites))
 	q.Set("count", strconv.Itoa(maxResults))
+	if opts.Country != "" {
+		q.Set("country", strings.ToUpper(opts.Country))
+	}
+	if opts.Language != "" {
+		q.Set("search_lang", strings.ToLower(opts.Language))
+	}
+	if f, ok := braveFreshness[opts.Freshness]; ok {
+		q.Set("freshness", f)
+	}
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
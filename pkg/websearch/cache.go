@@ -0,0 +1,109 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websearch
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of searches a cache keeps when no size is
+// given.
+const DefaultCacheSize = 1000
+
+// CachingProvider reuses the results of identical searches for a while,
+// saving provider calls when the model repeats a query. Failed searches are
+// not cached. It is safe for concurrent use.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	size     int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	results []SearchResult
+	expires time.Time
+}
+
+// NewCachingProvider returns a provider caching the results of p for ttl,
+// keeping at most size searches (DefaultCacheSize if size is not
+// positive).
+func NewCachingProvider(p Provider, ttl time.Duration, size int) *CachingProvider {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &CachingProvider{
+		provider: p,
+		ttl:      ttl,
+		size:     size,
+		now:      time.Now,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// Search returns the cached results of an identical search, or searches
+// with the underlying provider. Searches are identical when their query,
+// result count and context options match.
+func (c *CachingProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	key := cacheKey(query, maxResults, OptionsFromContext(ctx))
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return slices.Clone(entry.results), nil
+	}
+
+	results, err := c.provider.Search(ctx, query, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+	c.entries[key] = cacheEntry{results: slices.Clone(results), expires: c.now().Add(c.ttl)}
+	return results, nil
+}
+
+// evict makes room for an entry: it drops the expired entries, or the one
+// expiring first if none has. c.mu must be held.
+func (c *CachingProvider) evict() {
+	now := c.now()
+	var oldest string
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.size {
+		delete(c.entries, oldest)
+	}
+}
+
+// cacheKey identifies a search.
+func cacheKey(query string, maxResults int, opts Options) string {
+	return strings.Join([]string{
+		query,
+		strconv.Itoa(maxResults),
+		opts.Freshness,
+		strings.Join(opts.Sites, ","),
+		opts.Language,
+		opts.Country,
+	}, "\x00")
+}
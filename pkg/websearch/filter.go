@@ -0,0 +1,95 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websearch
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// DomainFilter drops the results of a provider whose domain is not
+// allowed, before they reach the model. A domain matches its subdomains
+// too: "example.com" matches "docs.example.com".
+type DomainFilter struct {
+	provider Provider
+	allowed  []string
+	blocked  []string
+}
+
+// NewDomainFilter returns a provider keeping the results of p from the
+// allowed domains, or from any domain if allowed is empty, and dropping
+// those from the blocked domains. p may be nil when only Allows is used.
+func NewDomainFilter(p Provider, allowed, blocked []string) *DomainFilter {
+	return &DomainFilter{
+		provider: p,
+		allowed:  normalizeDomains(allowed),
+		blocked:  normalizeDomains(blocked),
+	}
+}
+
+// Search searches with the underlying provider and filters the results.
+func (f *DomainFilter) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	results, err := f.provider.Search(ctx, query, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	kept := results[:0]
+	for _, r := range results {
+		if f.Allows(r.URL) {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// Allows reports whether a URL passes the filter. URLs without a
+// host are dropped when an allowlist is set.
+func (f *DomainFilter) Allows(rawURL string) bool {
+	var host string
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	}
+	if matchesDomain(host, f.blocked) {
+		return false
+	}
+	return len(f.allowed) == 0 || matchesDomain(host, f.allowed)
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of
+// one.
+func matchesDomain(host string, domains []string) bool {
+	if host == "" {
+		return false
+	}
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDomains lowercases domains and strips any scheme, path or
+// leading "*." or "www.", so that "https://www.Example.com/" becomes
+// "example.com".
+func normalizeDomains(domains []string) []string {
+	var out []string
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if i := strings.Index(d, "://"); i >= 0 {
+			d = d[i+3:]
+		}
+		if i := strings.IndexAny(d, "/?#"); i >= 0 {
+			d = d[:i]
+		}
+		d = strings.TrimPrefix(d, "*.")
+		d = strings.TrimPrefix(d, "www.")
+		d = strings.TrimSuffix(d, ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
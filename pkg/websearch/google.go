@@ -0,0 +1,128 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const googleDefaultURL = "https://www.googleapis.com/customsearch/v1"
+
+// googleMaxResults is the most results Custom Search returns per request.
+const googleMaxResults = 10
+
+func init() {
+	Providers.Register("google", func(_ context.Context, params map[string]string) (Provider, error) {
+		apiKey, engineID := params["api_key"], params["engine_id"]
+		if apiKey == "" || engineID == "" {
+			return nil, fmt.Errorf("google: api_key and engine_id parameters are required")
+		}
+		p := NewGoogleProvider(apiKey, engineID)
+		if params["base_url"] != "" {
+			p.endpoint = params["base_url"]
+		}
+		p.defaults = optionsFromParams(params)
+		return p, nil
+	})
+}
+
+// GoogleProvider performs web searches using the Google Custom Search JSON
+// API with a programmable search engine.
+type GoogleProvider struct {
+	apiKey     string
+	engineID   string // the "cx" of the search engine
+	endpoint   string
+	defaults   Options
+	httpClient *http.Client
+}
+
+// NewGoogleProvider creates a new Google Custom Search provider.
+func NewGoogleProvider(apiKey, engineID string) *GoogleProvider {
+	return &GoogleProvider{
+		apiKey:     apiKey,
+		engineID:   engineID,
+		endpoint:   googleDefaultURL,
+		httpClient: &http.Client{},
+	}
+}
+
+// googleDateRestrict maps freshness options to Google's dateRestrict values.
+var googleDateRestrict = map[string]string{"day": "d1", "week": "w1", "month": "m1", "year": "y1"}
+
+// SetHTTPClient replaces the HTTP client used to call the API.
+func (g *GoogleProvider) SetHTTPClient(hc *http.Client) {
+	g.httpClient = hc
+}
+
+// Search queries the Google Custom Search API and returns results.
+func (g *GoogleProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	opts := resolveOptions(ctx, g.defaults)
+
+	q := url.Values{}
+	q.Set("key", g.apiKey)
+	q.Set("cx", g.engineID)
+	q.Set("q", siteQuery(query, opts.Sites))
+	q.Set("num", strconv.Itoa(min(maxResults, googleMaxResults)))
+	if opts.Country != "" {
+		q.Set("gl", strings.ToLower(opts.Country))
+	}
+	if opts.Language != "" {
+		q.Set("hl", strings.ToLower(opts.Language))
+	}
+	if d, ok := googleDateRestrict[opts.Freshness]; ok {
+		q.Set("dateRestrict", d)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("google search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google search returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result googleSearchResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	var results []SearchResult
+	for _, r := range result.Items {
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.Link,
+			Snippet: r.Snippet,
+		})
+	}
+
+	return results, nil
+}
+
+type googleSearchResponse struct {
+	Items []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+	} `json:"items"`
+}
@@ -0,0 +1,117 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Providers.Register("searxng", func(_ context.Context, params map[string]string) (Provider, error) {
+		baseURL := params["base_url"]
+		if baseURL == "" {
+			return nil, fmt.Errorf("searxng: base_url parameter is required")
+		}
+		p := NewSearxNGProvider(baseURL, params["api_key"])
+		p.defaults = optionsFromParams(params)
+		return p, nil
+	})
+}
+
+// SearxNGProvider performs web searches against a self-hosted SearxNG
+// instance, which must have the JSON output format enabled.
+type SearxNGProvider struct {
+	baseURL    string
+	apiKey     string // optional, sent as a bearer token to instances behind a proxy
+	defaults   Options
+	httpClient *http.Client
+}
+
+// NewSearxNGProvider creates a new SearxNG provider for the instance at
+// baseURL.
+func NewSearxNGProvider(baseURL, apiKey string) *SearxNGProvider {
+	return &SearxNGProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used to call the API.
+func (s *SearxNGProvider) SetHTTPClient(hc *http.Client) {
+	s.httpClient = hc
+}
+
+// Search queries the SearxNG search endpoint and returns results.
+func (s *SearxNGProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	opts := resolveOptions(ctx, s.defaults)
+
+	q := url.Values{}
+	q.Set("q", siteQuery(query, opts.Sites))
+	q.Set("format", "json")
+	if locale := opts.locale(); locale != "" {
+		q.Set("language", locale)
+	}
+	if opts.Freshness != "" {
+		q.Set("time_range", opts.Freshness)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("searxng search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("searxng search returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result searxngSearchResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	// SearxNG has no result count parameter
+	var results []SearchResult
+	for _, r := range result.Results {
+		if len(results) == maxResults {
+			break
+		}
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Content,
+		})
+	}
+
+	return results, nil
+}
+
+type searxngSearchResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+	} `json:"results"`
+}
@@ -18,13 +18,16 @@ func init() {
 		if apiKey == "" {
 			return nil, fmt.Errorf("tavily: api_key parameter is required")
 		}
-		return NewTavilyProvider(apiKey), nil
+		p := NewTavilyProvider(apiKey)
+		p.defaults = optionsFromParams(params)
+		return p, nil
 	})
 }
 
 // TavilyProvider performs web searches using the Tavily Search API.
 type TavilyProvider struct {
 	apiKey     string
+	defaults   Options
 	httpClient *http.Client
 }
 
@@ -36,12 +39,20 @@ func NewTavilyProvider(apiKey string) *TavilyProvider {
 	}
 }
 
+// SetHTTPClient replaces the HTTP client used to call the API.
+func (t *TavilyProvider) SetHTTPClient(hc *http.Client) {
+	t.httpClient = hc
+}
+
 // Search queries the Tavily Search API and returns results.
 func (t *TavilyProvider) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	opts := resolveOptions(ctx, t.defaults)
 	reqBody := tavilySearchRequest{
-		APIKey:     t.apiKey,
-		Query:      query,
-		MaxResults: maxResults,
+		APIKey:         t.apiKey,
+		Query:          query,
+		MaxResults:     maxResults,
+		TimeRange:      opts.Freshness,
+		IncludeDomains: opts.Sites,
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -88,9 +99,11 @@ func (t *TavilyProvider) Search(ctx context.Context, query string, maxResults in
 }
 
 type tavilySearchRequest struct {
-	APIKey     string `json:"api_key"`
-	Query      string `json:"query"`
-	MaxResults int    `json:"max_results"`
+	APIKey         string   `json:"api_key"`
+	Query          string   `json:"query"`
+	MaxResults     int      `json:"max_results"`
+	TimeRange      string   `json:"time_range,omitempty"`
+	IncludeDomains []string `json:"include_domains,omitempty"`
 }
 
 type tavilySearchResponse struct {
@@ -5,12 +5,15 @@ package websearch
 
 import (
 	"context"
+	"net/http"
+	"strings"
 
 	"github.com/leseb/openresponses-gw/pkg/provider"
 )
 
 // Providers is the registry of web search provider implementations.
-// Brave and Tavily are registered automatically via init().
+// Brave, Tavily, SearxNG, Bing and Google Custom Search are registered
+// automatically via init().
 var Providers = provider.NewRegistry[Provider]("web_search")
 
 // SearchResult represents a single web search result.
@@ -24,3 +27,97 @@ type SearchResult struct {
 type Provider interface {
 	Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error)
 }
+
+// HTTPClientSetter is implemented by the providers whose HTTP client can be
+// replaced, such as with one enforcing an egress policy. All the built-in
+// providers implement it.
+type HTTPClientSetter interface {
+	SetHTTPClient(hc *http.Client)
+}
+
+// Options narrow a search. Providers apply the options their API supports
+// and ignore the others. Options set on the context with WithOptions take
+// precedence over those a provider was configured with.
+type Options struct {
+	Freshness string   // "day", "week", "month" or "year"
+	Sites     []string // restrict results to these domains
+	Language  string   // ISO 639-1 code, e.g. "en"
+	Country   string   // ISO 3166-1 alpha-2 code, e.g. "US"
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a context carrying search options for the providers.
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// OptionsFromContext returns the options set by WithOptions.
+func OptionsFromContext(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+// optionsFromParams returns the default options of a provider from its
+// registry parameters.
+func optionsFromParams(params map[string]string) Options {
+	opts := Options{
+		Freshness: params["freshness"],
+		Language:  params["language"],
+		Country:   params["country"],
+	}
+	for _, site := range strings.Split(params["sites"], ",") {
+		if site = strings.TrimSpace(site); site != "" {
+			opts.Sites = append(opts.Sites, site)
+		}
+	}
+	return opts
+}
+
+// resolveOptions returns the options of a search: those of ctx, falling
+// back to the provider's defaults.
+func resolveOptions(ctx context.Context, defaults Options) Options {
+	opts := OptionsFromContext(ctx)
+	if opts.Freshness == "" {
+		opts.Freshness = defaults.Freshness
+	}
+	if len(opts.Sites) == 0 {
+		opts.Sites = defaults.Sites
+	}
+	if opts.Language == "" {
+		opts.Language = defaults.Language
+	}
+	if opts.Country == "" {
+		opts.Country = defaults.Country
+	}
+	return opts
+}
+
+// siteQuery restricts query to sites with site: operators, for providers
+// without a domain filter.
+func siteQuery(query string, sites []string) string {
+	if len(sites) == 0 {
+		return query
+	}
+	ops := make([]string, len(sites))
+	for i, site := range sites {
+		ops[i] = "site:" + site
+	}
+	if len(ops) == 1 {
+		return query + " " + ops[0]
+	}
+	return query + " (" + strings.Join(ops, " OR ") + ")"
+}
+
+// locale returns the "language-COUNTRY" locale of opts, e.g. "en-US", or
+// whichever part is set.
+func (o Options) locale() string {
+	switch {
+	case o.Language != "" && o.Country != "":
+		return strings.ToLower(o.Language) + "-" + strings.ToUpper(o.Country)
+	case o.Language != "":
+		return strings.ToLower(o.Language)
+	default:
+		return ""
+	}
+}
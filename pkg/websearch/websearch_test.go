@@ -6,9 +6,12 @@ package websearch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestBraveProvider_Search(t *testing.T) {
@@ -112,7 +115,208 @@ func TestTavilyProvider_Search(t *testing.T) {
 	}
 }
 
+func TestProviders_Options(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		params    map[string]string
+		body      string
+		wantQuery map[string]string
+		wantTitle string
+	}{
+		{
+			name:     "searxng",
+			provider: "searxng",
+			params:   map[string]string{"freshness": "week", "language": "en"},
+			body:     `{"results":[{"title":"A","url":"https://a","content":"a"},{"title":"B","url":"https://b","content":"b"}]}`,
+			wantQuery: map[string]string{
+				"q": "golang (site:go.dev OR site:pkg.go.dev)", "format": "json", "language": "en-US", "time_range": "week",
+			},
+			wantTitle: "A",
+		},
+		{
+			name:     "bing",
+			provider: "bing",
+			params:   map[string]string{"api_key": "test-key", "freshness": "day", "language": "en"},
+			body:     `{"webPages":{"value":[{"name":"A","url":"https://a","snippet":"a"}]}}`,
+			wantQuery: map[string]string{
+				"q": "golang (site:go.dev OR site:pkg.go.dev)", "count": "1", "mkt": "en-US", "freshness": "Day",
+			},
+			wantTitle: "A",
+		},
+		{
+			name:     "google",
+			provider: "google",
+			params:   map[string]string{"api_key": "test-key", "engine_id": "cx1", "freshness": "year"},
+			body:     `{"items":[{"title":"A","link":"https://a","snippet":"a"}]}`,
+			wantQuery: map[string]string{
+				"key": "test-key", "cx": "cx1", "num": "1", "gl": "us", "dateRestrict": "y1",
+			},
+			wantTitle: "A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.provider == "bing" && r.Header.Get("Ocp-Apim-Subscription-Key") != "test-key" {
+					t.Errorf("expected API key header, got %q", r.Header.Get("Ocp-Apim-Subscription-Key"))
+				}
+				for k, want := range tt.wantQuery {
+					if got := r.URL.Query().Get(k); got != want {
+						t.Errorf("query %s = %q, want %q", k, got, want)
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			tt.params["base_url"] = server.URL
+			provider, err := Providers.New(context.Background(), tt.provider, tt.params)
+			if err != nil {
+				t.Fatalf("create provider: %v", err)
+			}
+
+			// Request options are merged over the configured defaults
+			ctx := WithOptions(context.Background(), Options{Country: "US", Sites: []string{"go.dev", "pkg.go.dev"}})
+			results, err := provider.Search(ctx, "golang", 1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != 1 || results[0].Title != tt.wantTitle {
+				t.Errorf("results = %+v", results)
+			}
+		})
+	}
+}
+
+func TestProviders_RequiredParams(t *testing.T) {
+	tests := []struct {
+		provider string
+		params   map[string]string
+	}{
+		{"searxng", map[string]string{}},
+		{"bing", map[string]string{}},
+		{"google", map[string]string{"api_key": "k"}},
+	}
+	for _, tt := range tests {
+		if _, err := Providers.New(context.Background(), tt.provider, tt.params); err == nil {
+			t.Errorf("%s: expected error for params %v", tt.provider, tt.params)
+		}
+	}
+}
+
 // rewriteTransport rewrites requests to point at a test server.
+// fakeProvider returns one result per URL and counts its searches.
+type fakeProvider struct {
+	urls     []string
+	searches int
+}
+
+func (f *fakeProvider) Search(_ context.Context, query string, _ int) ([]SearchResult, error) {
+	f.searches++
+	if query == "fail" {
+		return nil, errors.New("search failed")
+	}
+	results := make([]SearchResult, len(f.urls))
+	for i, u := range f.urls {
+		results[i] = SearchResult{Title: query, URL: u}
+	}
+	return results, nil
+}
+
+func TestCachingProvider(t *testing.T) {
+	fake := &fakeProvider{urls: []string{"https://example.com"}}
+	now := time.Unix(0, 0)
+	c := NewCachingProvider(fake, time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	steps := []struct {
+		name         string
+		ctx          context.Context
+		query        string
+		advance      time.Duration
+		wantSearches int
+	}{
+		{name: "miss", ctx: ctx, query: "go", wantSearches: 1},
+		{name: "hit", ctx: ctx, query: "go", wantSearches: 1},
+		{name: "other options", ctx: WithOptions(ctx, Options{Freshness: "day"}), query: "go", wantSearches: 2},
+		{name: "errors are not cached", ctx: ctx, query: "fail", wantSearches: 3},
+		{name: "errors are retried", ctx: ctx, query: "fail", wantSearches: 4},
+		{name: "expired", ctx: ctx, query: "go", advance: time.Minute, wantSearches: 5},
+		{name: "evicts when full", ctx: ctx, query: "rust", wantSearches: 6},
+		{name: "newest entries kept", ctx: ctx, query: "go", wantSearches: 6},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		_, err := c.Search(step.ctx, step.query, 5)
+		if (err != nil) != (step.query == "fail") {
+			t.Fatalf("%s: Search() error = %v", step.name, err)
+		}
+		if fake.searches != step.wantSearches {
+			t.Errorf("%s: searches = %d, want %d", step.name, fake.searches, step.wantSearches)
+		}
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("cached %d searches, want 2", len(c.entries))
+	}
+}
+
+func TestDomainFilter(t *testing.T) {
+	urls := []string{
+		"https://example.com/a",
+		"https://docs.example.com/b",
+		"https://ads.example.com/c",
+		"https://notexample.com/d",
+		"https://other.org/e",
+		"not a url",
+	}
+	tests := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		want    []string
+	}{
+		{
+			name: "no lists",
+			want: urls,
+		},
+		{
+			name:    "allowlist matches subdomains",
+			allowed: []string{"https://www.Example.com/"},
+			want:    urls[:3],
+		},
+		{
+			name:    "denylist wins",
+			allowed: []string{"example.com"},
+			blocked: []string{"ads.example.com"},
+			want:    urls[:2],
+		},
+		{
+			name:    "denylist only",
+			blocked: []string{"*.example.com", "other.org"},
+			want:    []string{urls[3], urls[5]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewDomainFilter(&fakeProvider{urls: urls}, tt.allowed, tt.blocked)
+			results, err := f.Search(context.Background(), "q", 10)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.URL)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 type rewriteTransport struct {
 	base      http.RoundTripper
 	targetURL string
@@ -5,6 +5,7 @@ Applies several fixes that swag v2 cannot express natively:
   1. Nullable fields — pointer-typed Go fields become anyOf with {type: "null"}.
   2. Files API — fix POST /v1/files multipart schema and remove spurious
      ``type: object`` from the File schema so oasdiff sees full conformance.
+     POST /v1/vector_stores/{id}/upload gets the same multipart fix.
 
 Usage:
     python scripts/fix-openapi-nullable.py docs/openapi.yaml
@@ -40,6 +41,9 @@ NULLABLE_FIELDS: list[tuple[str, str, str | None]] = [
     ("schema.Response", "reasoning", None),
     ("schema.Response", "max_output_tokens", None),
     ("schema.Response", "max_tool_calls", None),
+    # schema.VectorStoreFileChunk — offsets are null for chunks indexed before they were recorded
+    ("schema.VectorStoreFileChunk", "start_offset", None),
+    ("schema.VectorStoreFileChunk", "end_offset", None),
 ]
 
 
@@ -502,6 +506,123 @@ def fix_search_request(spec: dict) -> dict:
     return spec
 
 
+def fix_vector_store_upload(spec: dict) -> dict:
+    """Fix POST /v1/vector_stores/{id}/upload multipart schema.
+
+    Same swag issue as POST /v1/files: formData params land under
+    application/x-www-form-urlencoded.  ``chunking_strategy`` and
+    ``attributes`` are JSON-encoded form fields.
+    """
+    post = spec.get("paths", {}).get("/v1/vector_stores/{id}/upload", {}).get("post", {})
+    rb_content = post.get("requestBody", {}).get("content", {})
+    if not rb_content:
+        return spec
+
+    rb_content["multipart/form-data"] = {
+        "schema": {
+            "type": "object",
+            "required": ["file"],
+            "properties": {
+                "file": {
+                    "type": "string",
+                    "format": "binary",
+                    "description": "File to upload",
+                },
+                "purpose": {
+                    "type": "string",
+                    "description": "File purpose (default assistants)",
+                },
+                "chunking_strategy": {
+                    "type": "string",
+                    "description": "Chunking strategy as JSON",
+                },
+                "attributes": {
+                    "type": "string",
+                    "description": "File attributes as a JSON object",
+                },
+            },
+        }
+    }
+    rb_content.pop("application/x-www-form-urlencoded", None)
+
+    return spec
+
+
+def fix_prompt_variables(spec: dict) -> dict:
+    """Allow prompt variables to be a string or a content part object.
+
+    ``PromptVariable`` decodes from either form via a custom UnmarshalJSON,
+    but swag only sees the struct.  Rewrite ``PromptReference.variables``
+    so each value is ``anyOf`` string / PromptVariable.
+    """
+    schemas = spec.get("components", {}).get("schemas", {})
+
+    var_key = None
+    for key in schemas:
+        if key.endswith("schema.PromptVariable"):
+            var_key = key
+            break
+    if var_key is None:
+        return spec
+
+    for key in schemas:
+        if not key.endswith("schema.PromptReference"):
+            continue
+        variables = schemas[key].get("properties", {}).get("variables")
+        if variables is None:
+            continue
+        variables["additionalProperties"] = {
+            "anyOf": [
+                {"type": "string"},
+                {"$ref": f"#/components/schemas/{var_key}"},
+            ]
+        }
+        break
+
+    return spec
+
+
+def fix_mcp_allowed_tools(spec: dict) -> dict:
+    """Allow MCP ``allowed_tools`` to be a list of names or a filter object.
+
+    ``MCPAllowedTools`` decodes from either form via a custom UnmarshalJSON,
+    but swag only sees the struct.  Rewrite ``allowed_tools`` on the tool
+    schemas as ``anyOf`` string array / MCPAllowedTools.
+    """
+    schemas = spec.get("components", {}).get("schemas", {})
+
+    allowed_key = None
+    for key in schemas:
+        if key.endswith("schema.MCPAllowedTools"):
+            allowed_key = key
+            break
+    if allowed_key is None:
+        return spec
+
+    for key in schemas:
+        if not (key.endswith("schema.ResponsesToolParam") or key.endswith("schema.ResponsesTool")):
+            continue
+        props = schemas[key].get("properties", {})
+        field = props.get("allowed_tools")
+        if field is None:
+            continue
+        description = None
+        for part in field.get("allOf", []):
+            description = part.get("description", description)
+        description = field.get("description", description)
+        new_field = {
+            "anyOf": [
+                {"items": {"type": "string"}, "type": "array"},
+                {"$ref": f"#/components/schemas/{allowed_key}"},
+            ]
+        }
+        if description:
+            new_field["description"] = description
+        props["allowed_tools"] = new_field
+
+    return spec
+
+
 def main():
     if len(sys.argv) != 2:
         print(f"Usage: {sys.argv[0]} <openapi.yaml>", file=sys.stderr)
@@ -517,10 +638,13 @@ def main():
 
     fix_nullable(spec)
     fix_files_api(spec)
+    fix_vector_store_upload(spec)
     fix_request_body_oneof(spec)
     fix_chunking_strategy_union(spec)
     fix_request_chunking_strategy(spec)
     fix_search_request(spec)
+    fix_prompt_variables(spec)
+    fix_mcp_allowed_tools(spec)
 
     # Tag null types for proper YAML quoting
     _tag_null_types(spec)
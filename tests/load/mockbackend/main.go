@@ -0,0 +1,110 @@
+// Copyright Open Responses Gateway Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command mockbackend is a /v1/responses backend answering every request
+// with the same text, for load tests that measure the gateway rather than
+// a model. Latency is simulated with a fixed delay per token.
+//
+//	go run ./tests/load/mockbackend -port 8000 -tokens 100 -token-delay 1ms
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func main() {
+	port := flag.Int("port", 8000, "HTTP port to listen on")
+	tokens := flag.Int("tokens", 100, "Number of tokens of every answer")
+	tokenDelay := flag.Duration("token-delay", 0, "Delay before each token")
+	flag.Parse()
+
+	b := &backend{tokens: *tokens, tokenDelay: *tokenDelay}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", b.models)
+	mux.HandleFunc("POST /v1/responses", b.responses)
+
+	addr := fmt.Sprintf(":%d", *port)
+	log.Printf("mock backend listening on %s (%d tokens, %s per token)", addr, b.tokens, b.tokenDelay)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+type backend struct {
+	tokens     int
+	tokenDelay time.Duration
+}
+
+func (b *backend) models(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"object": "list",
+		"data":   []interface{}{map[string]interface{}{"id": "mock", "object": "model"}},
+	})
+}
+
+func (b *backend) responses(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !req.Stream {
+		time.Sleep(time.Duration(b.tokens) * b.tokenDelay)
+		writeJSON(w, b.response(req.Model))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	send := func(evt map[string]interface{}) {
+		data, _ := json.Marshal(evt)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt["type"], data)
+		flusher.Flush()
+	}
+
+	for i := 0; i < b.tokens; i++ {
+		if r.Context().Err() != nil {
+			return
+		}
+		time.Sleep(b.tokenDelay)
+		send(map[string]interface{}{
+			"type": "response.output_text.delta", "output_index": 0, "content_index": 0,
+			"item_id": "msg_mock", "delta": "token ",
+		})
+	}
+	send(map[string]interface{}{"type": "response.completed", "response": b.response(req.Model)})
+}
+
+// response returns the completed backend response.
+func (b *backend) response(model string) map[string]interface{} {
+	text := strings.Repeat("token ", b.tokens)
+	return map[string]interface{}{
+		"id":         "resp_mock",
+		"object":     "response",
+		"status":     "completed",
+		"model":      model,
+		"created_at": time.Now().Unix(),
+		"output": []interface{}{map[string]interface{}{
+			"type": "message", "id": "msg_mock", "role": "assistant", "status": "completed",
+			"content": []interface{}{map[string]interface{}{"type": "output_text", "text": text}},
+		}},
+		"usage": map[string]interface{}{"input_tokens": 10, "output_tokens": b.tokens, "total_tokens": 10 + b.tokens},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,84 @@
+// Load test for the /v1/responses endpoints of the gateway.
+//
+// Run against a gateway backed by tests/load/mockbackend so that the
+// numbers measure the gateway rather than a model:
+//
+//   k6 run tests/load/responses.js
+//   k6 run -e BASE_URL=http://localhost:8080/v1 -e VUS=50 -e DURATION=2m tests/load/responses.js
+//
+// Each scenario reports its own p50/p99 through the http_req_duration
+// tags, and the thresholds fail the run on regressions.
+
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080/v1';
+const MODEL = __ENV.MODEL || 'mock';
+const VUS = parseInt(__ENV.VUS || '20', 10);
+const DURATION = __ENV.DURATION || '1m';
+// Responses stored before the run, so that listings page through a
+// realistic history (e.g. SEED=10000)
+const SEED = parseInt(__ENV.SEED || '0', 10);
+
+const headers = { 'Content-Type': 'application/json' };
+if (__ENV.API_KEY) {
+  headers.Authorization = `Bearer ${__ENV.API_KEY}`;
+}
+
+export const options = {
+  summaryTrendStats: ['avg', 'p(50)', 'p(90)', 'p(99)', 'max'],
+  scenarios: {
+    create: {
+      executor: 'constant-vus', exec: 'create', vus: VUS, duration: DURATION,
+      tags: { scenario: 'create' },
+    },
+    stream: {
+      executor: 'constant-vus', exec: 'stream', vus: VUS, duration: DURATION,
+      tags: { scenario: 'stream' },
+    },
+    list: {
+      executor: 'constant-vus', exec: 'list', vus: Math.max(1, Math.floor(VUS / 4)), duration: DURATION,
+      tags: { scenario: 'list' },
+    },
+  },
+  setupTimeout: '10m',
+  thresholds: {
+    'http_req_failed': ['rate<0.01'],
+    'http_req_duration{scenario:create}': ['p(99)<500'],
+    'http_req_duration{scenario:stream}': ['p(99)<1000'],
+    'http_req_duration{scenario:list}': ['p(99)<250'],
+  },
+};
+
+function body(stream) {
+  return JSON.stringify({ model: MODEL, input: 'What are the opening hours of the store?', stream });
+}
+
+export function setup() {
+  for (let done = 0; done < SEED; done += 50) {
+    const batch = [];
+    for (let i = done; i < Math.min(SEED, done + 50); i++) {
+      batch.push(['POST', `${BASE_URL}/responses`, body(false), { headers, tags: { scenario: 'seed' } }]);
+    }
+    http.batch(batch);
+  }
+}
+
+export function create() {
+  const res = http.post(`${BASE_URL}/responses`, body(false), { headers });
+  check(res, { 'create: 200': (r) => r.status === 200 });
+}
+
+export function stream() {
+  // k6 reads the whole event stream, so the duration covers every event
+  const res = http.post(`${BASE_URL}/responses`, body(true), { headers });
+  check(res, {
+    'stream: 200': (r) => r.status === 200,
+    'stream: completed': (r) => r.body.includes('event: response.completed'),
+  });
+}
+
+export function list() {
+  const res = http.get(`${BASE_URL}/responses?limit=20&order=desc`, { headers });
+  check(res, { 'list: 200': (r) => r.status === 200 });
+}
@@ -0,0 +1,46 @@
+#!/bin/bash
+# Start the mock backend and the gateway, then run the k6 load test
+# Usage: ./tests/load/run.sh [k6 args...]
+# Environment: PORT (8080), BACKEND_PORT (8000), TOKENS (100),
+# TOKEN_DELAY (0s), SESSION_STORE_TYPE (sqlite), SESSION_STORE_DSN, plus
+# the VUS, DURATION and SEED variables of responses.js
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+PROJECT_ROOT="$(cd "$SCRIPT_DIR/../.." && pwd)"
+
+PORT="${PORT:-8080}"
+BACKEND_PORT="${BACKEND_PORT:-8000}"
+WORK_DIR="$(mktemp -d)"
+
+command -v k6 >/dev/null || { echo "k6 not installed. See https://grafana.com/docs/k6/latest/set-up/install-k6/"; exit 1; }
+
+cleanup() {
+    [ -n "$GATEWAY_PID" ] && kill "$GATEWAY_PID" 2>/dev/null || true
+    [ -n "$BACKEND_PID" ] && kill "$BACKEND_PID" 2>/dev/null || true
+    rm -rf "$WORK_DIR"
+}
+trap cleanup EXIT
+
+cd "$PROJECT_ROOT"
+go build -o "$WORK_DIR/mockbackend" ./tests/load/mockbackend
+go build -o "$WORK_DIR/gateway" ./cmd/server
+
+"$WORK_DIR/mockbackend" -port "$BACKEND_PORT" -tokens "${TOKENS:-100}" -token-delay "${TOKEN_DELAY:-0s}" \
+    > "$WORK_DIR/backend.log" 2>&1 &
+BACKEND_PID=$!
+
+OPENAI_API_ENDPOINT="http://localhost:$BACKEND_PORT/v1" \
+BACKEND_API=responses \
+SESSION_STORE_TYPE="${SESSION_STORE_TYPE:-sqlite}" \
+SESSION_STORE_DSN="${SESSION_STORE_DSN:-$WORK_DIR/responses.db}" \
+    "$WORK_DIR/gateway" -config "$WORK_DIR/defaults.yaml" -port "$PORT" > "$WORK_DIR/gateway.log" 2>&1 &
+GATEWAY_PID=$!
+
+for _ in $(seq 1 30); do
+    curl -sf "http://localhost:$PORT/health" >/dev/null && break
+    sleep 1
+done
+curl -sf "http://localhost:$PORT/health" >/dev/null || { cat "$WORK_DIR/gateway.log"; exit 1; }
+
+k6 run -e BASE_URL="http://localhost:$PORT/v1" "$@" "$SCRIPT_DIR/responses.js"